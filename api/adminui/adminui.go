@@ -0,0 +1,48 @@
+// api/adminui/adminui.go
+
+// Package adminui serves the admin console, a small single-page app embedded in the
+// binary, at /admin. The page and its assets hold no data: the console signs in with
+// /auth/login and reads everything through the admin API (/api/v2/admin), which only
+// answers admins.
+package adminui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed static
+var static embed.FS
+
+// contentSecurityPolicy keeps the console to its own scripts, styles and API.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; connect-src 'self'; img-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// RegisterRoutes serves the console at /admin and its assets under /admin/.
+func RegisterRoutes(router *gin.Engine) {
+	assets, _ := fs.Sub(static, "static")
+	fileServer := http.StripPrefix("/admin/", http.FileServer(http.FS(assets)))
+	index, _ := fs.ReadFile(assets, "index.html")
+
+	router.GET("/admin", func(c *gin.Context) {
+		setSecurityHeaders(c)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	})
+	router.GET("/admin/*filepath", func(c *gin.Context) {
+		if c.Param("filepath") == "/" || c.Param("filepath") == "/index.html" {
+			c.Redirect(http.StatusMovedPermanently, "/admin")
+			return
+		}
+		setSecurityHeaders(c)
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+func setSecurityHeaders(c *gin.Context) {
+	c.Header("Content-Security-Policy", contentSecurityPolicy)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Cache-Control", "no-cache")
+}
@@ -0,0 +1,42 @@
+package adminui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router)
+
+	cases := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/admin", http.StatusOK, "text/html"},
+		{"/admin/app.js", http.StatusOK, "javascript"},
+		{"/admin/app.css", http.StatusOK, "text/css"},
+		{"/admin/", http.StatusMovedPermanently, ""},
+		{"/admin/missing.js", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.status {
+			t.Errorf("GET %s = %d; want %d", tc.path, w.Code, tc.status)
+			continue
+		}
+		if !strings.Contains(w.Header().Get("Content-Type"), tc.contentType) {
+			t.Errorf("GET %s: Content-Type = %q; want %s", tc.path, w.Header().Get("Content-Type"), tc.contentType)
+		}
+		if tc.status == http.StatusOK && !strings.Contains(w.Header().Get("Content-Security-Policy"), "script-src 'self'") {
+			t.Errorf("GET %s: missing Content-Security-Policy", tc.path)
+		}
+	}
+}
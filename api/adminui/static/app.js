@@ -0,0 +1,348 @@
+// Nebula admin console. Reads everything through /api/v2/admin with the admin's JWT,
+// kept in sessionStorage so it is gone when the tab closes. All values are rendered
+// with textContent; nothing from the API is parsed as HTML.
+(function () {
+  "use strict";
+
+  var API = "/api/v2/admin";
+  var PAGE_SIZE = 50;
+  var TOKEN_KEY = "nebula.admin.token";
+  var USER_KEY = "nebula.admin.user";
+
+  var view = document.getElementById("view");
+  var errorBox = document.getElementById("error");
+
+  // --- API ---
+
+  function token() {
+    return sessionStorage.getItem(TOKEN_KEY);
+  }
+
+  function signOut() {
+    sessionStorage.removeItem(TOKEN_KEY);
+    sessionStorage.removeItem(USER_KEY);
+    route();
+  }
+
+  function request(method, path, body) {
+    var headers = { Accept: "application/json" };
+    if (token()) headers.Authorization = "Bearer " + token();
+    if (body !== undefined) headers["Content-Type"] = "application/json";
+    return fetch(path, {
+      method: method,
+      headers: headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    }).then(function (res) {
+      return res.text().then(function (text) {
+        var data = text ? JSON.parse(text) : null;
+        if (res.status === 401 && path.indexOf("/auth/") !== 0) {
+          signOut();
+          throw new Error("Session expired, sign in again.");
+        }
+        if (!res.ok) {
+          throw new Error((data && (data.message || data.error)) || res.status + " " + res.statusText);
+        }
+        return data;
+      });
+    });
+  }
+
+  function get(path) {
+    return request("GET", API + path);
+  }
+
+  function userPath(userId, db) {
+    var path = "/users/" + encodeURIComponent(userId);
+    if (db !== undefined) path += "/databases/" + encodeURIComponent(db);
+    return path;
+  }
+
+  // --- DOM helpers ---
+
+  function el(tag, attrs, children) {
+    var node = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (name) {
+      if (name === "text") node.textContent = attrs[name];
+      else if (name === "className") node.className = attrs[name];
+      else if (name === "onclick") node.addEventListener("click", attrs[name]);
+      else node.setAttribute(name, attrs[name]);
+    });
+    (children || []).forEach(function (child) {
+      node.appendChild(typeof child === "string" ? document.createTextNode(child) : child);
+    });
+    return node;
+  }
+
+  function link(text, hash) {
+    return el("a", { href: hash, text: text });
+  }
+
+  function hash(parts) {
+    return "#/" + parts.map(encodeURIComponent).join("/");
+  }
+
+  function formatValue(value) {
+    if (value === null || value === undefined) return null;
+    if (typeof value === "object") return JSON.stringify(value);
+    return String(value);
+  }
+
+  function formatDate(value) {
+    if (!value) return "never";
+    var date = new Date(value);
+    return isNaN(date) ? String(value) : date.toLocaleString();
+  }
+
+  function formatBytes(n) {
+    if (!n) return "0 B";
+    var units = ["B", "KB", "MB", "GB", "TB"];
+    var i = Math.min(Math.floor(Math.log(n) / Math.log(1024)), units.length - 1);
+    return (n / Math.pow(1024, i)).toFixed(i ? 1 : 0) + " " + units[i];
+  }
+
+  // table renders rows as an HTML table. columns are [header, value(row)] pairs;
+  // value may return a DOM node, a string or null.
+  function table(columns, rows) {
+    var head = el("tr", {}, columns.map(function (col) {
+      return el("th", { text: col[0] });
+    }));
+    var body = rows.map(function (row) {
+      return el("tr", {}, columns.map(function (col) {
+        var value = col[1](row);
+        if (value instanceof Node) return el("td", {}, [value]);
+        if (value === null) return el("td", { className: "null", text: "null" });
+        return el("td", { text: value });
+      }));
+    });
+    if (!rows.length) {
+      body = [el("tr", {}, [el("td", { className: "muted", colspan: columns.length, text: "None" })])];
+    }
+    return el("table", {}, [el("thead", {}, [head]), el("tbody", {}, body)]);
+  }
+
+  function cards(items) {
+    return el("div", { className: "cards" }, items.map(function (item) {
+      return el("div", { className: "card" }, [
+        el("div", { className: "value", text: String(item[1]) }),
+        el("div", { className: "label", text: item[0] }),
+      ]);
+    }));
+  }
+
+  function crumbs(parts) {
+    var nodes = [];
+    parts.forEach(function (part, i) {
+      if (i) nodes.push(" / ");
+      nodes.push(part[1] ? link(part[0], part[1]) : part[0]);
+    });
+    return el("div", { className: "crumbs" }, nodes);
+  }
+
+  function render(nodes) {
+    view.replaceChildren.apply(view, nodes);
+  }
+
+  function showError(err) {
+    errorBox.textContent = err ? err.message || String(err) : "";
+    errorBox.hidden = !err;
+  }
+
+  // --- Views ---
+
+  function databasesView() {
+    return get("/databases").then(function (data) {
+      render([
+        el("h2", { text: "Databases" }),
+        table([
+          ["Database", function (db) { return link(db.db_name, hash(["users", db.user_id, "databases", db.db_name])); }],
+          ["Owner", function (db) { return db.owner_email || db.user_id; }],
+          ["Username", function (db) { return db.owner_username || ""; }],
+          ["Created", function (db) { return formatDate(db.created_at); }],
+        ], data.databases || []),
+      ]);
+    });
+  }
+
+  function databaseView(userId, db) {
+    var base = userPath(userId, db);
+    return Promise.all([
+      get(base + "/tables"),
+      get(base + "/usage"),
+      get(base + "/apikey").catch(function () { return null; }),
+    ]).then(function (results) {
+      var tables = results[0].tables || [];
+      var usage = results[1];
+      var key = results[2];
+      var totals = usage.totals || {};
+      var nodes = [
+        crumbs([["Databases", "#/"], [db]]),
+        el("h2", { text: db }),
+        el("h3", { text: "Tables" }),
+        table([
+          ["Table", function (t) { return link(t.name, hash(["users", userId, "databases", db, "tables", t.name])); }],
+          ["Columns", function (t) {
+            return (t.columns || []).map(function (col) {
+              return col.name + " " + col.type + (col.pk ? " (pk)" : "");
+            }).join(", ");
+          }],
+        ], tables),
+        el("h3", { text: "Usage since " + formatDate(usage.since) }),
+        cards([
+          ["Requests", totals.requests || 0],
+          ["Errors", totals.errors || 0],
+          ["Bytes in", formatBytes(totals.bytes_in)],
+          ["Bytes out", formatBytes(totals.bytes_out)],
+        ]),
+        table([
+          ["Day", function (d) { return d.day; }],
+          ["Requests", function (d) { return String(d.requests); }],
+          ["Errors", function (d) { return String(d.errors); }],
+          ["Error rate", function (d) { return (d.error_rate * 100).toFixed(1) + "%"; }],
+          ["Bytes in", function (d) { return formatBytes(d.bytes_in); }],
+          ["Bytes out", function (d) { return formatBytes(d.bytes_out); }],
+        ], usage.days || []),
+        el("h3", { text: "API key" }),
+      ];
+      if (!key) {
+        nodes.push(el("p", { className: "muted", text: "No API key." }));
+        render(nodes);
+        return;
+      }
+      nodes.push(el("p", {}, [el("code", { text: maskKey(key.key) })]));
+      render(nodes);
+      return get(base + "/apikeys/" + encodeURIComponent(key.id) + "/usage").then(function (keyUsage) {
+        view.appendChild(el("p", { className: "muted", text: "Last used " + formatDate(keyUsage.last_used_at) }));
+        view.appendChild(table([
+          ["Endpoint", function (e) { return e.method + " " + e.route; }],
+          ["Requests", function (e) { return String(e.requests); }],
+          ["Errors", function (e) { return String(e.errors); }],
+        ], keyUsage.top_endpoints || []));
+      });
+    });
+  }
+
+  function maskKey(key) {
+    if (!key) return "";
+    return key.length > 12 ? key.slice(0, 8) + "…" + key.slice(-4) : "…";
+  }
+
+  function recordsView(userId, db, tableName, offset) {
+    var path = userPath(userId, db) + "/tables/" + encodeURIComponent(tableName) + "/records?limit=" + PAGE_SIZE + "&offset=" + offset;
+    return get(path).then(function (page) {
+      var rows = page.data || [];
+      var info = page.pagination || {};
+      var columns = [];
+      rows.forEach(function (row) {
+        Object.keys(row).forEach(function (name) {
+          if (columns.indexOf(name) < 0) columns.push(name);
+        });
+      });
+      var tableHash = ["users", userId, "databases", db, "tables", tableName];
+      var pager = el("div", { className: "pager" }, [
+        el("span", { className: "muted", text: rows.length ? (offset + 1) + "–" + (offset + rows.length) + " of " + info.total : "No records" }),
+      ]);
+      if (offset > 0) pager.appendChild(link("Previous", hash(tableHash.concat(String(Math.max(0, offset - PAGE_SIZE))))));
+      if (info.has_more) pager.appendChild(link("Next", hash(tableHash.concat(String(info.next_offset)))));
+      render([
+        crumbs([["Databases", "#/"], [db, hash(["users", userId, "databases", db])], [tableName]]),
+        el("h2", { text: tableName }),
+        table(columns.map(function (name) {
+          return [name, function (row) { return formatValue(row[name]); }];
+        }), rows),
+        pager,
+      ]);
+    });
+  }
+
+  function instanceView() {
+    return Promise.all([
+      get("/mode"),
+      get("/log-level"),
+      get("/stats/latency"),
+      get("/audit-log?limit=" + PAGE_SIZE),
+    ]).then(function (results) {
+      var mode = results[0];
+      var latency = results[2];
+      var audit = results[3];
+      render([
+        el("h2", { text: "Instance" }),
+        cards([
+          ["Mode since " + formatDate(mode.changedAt), mode.mode],
+          ["Log level", results[1].level],
+        ]),
+        el("h3", { text: "Latency since " + formatDate(latency.since) }),
+        table([
+          ["Route", function (r) { return r.method + " " + r.route; }],
+          ["Count", function (r) { return String(r.count); }],
+          ["Mean ms", function (r) { return r.mean_ms.toFixed(1); }],
+          ["p50 ms", function (r) { return r.p50_ms.toFixed(1); }],
+          ["p95 ms", function (r) { return r.p95_ms.toFixed(1); }],
+          ["p99 ms", function (r) { return r.p99_ms.toFixed(1); }],
+        ], latency.routes || []),
+        el("h3", { text: "Audit log" }),
+        table(auditColumns(audit.data || []), audit.data || []),
+      ]);
+    });
+  }
+
+  function auditColumns(entries) {
+    var names = entries.length ? Object.keys(entries[0]) : ["entries"];
+    return names.map(function (name) {
+      return [name, function (entry) { return formatValue(entry[name]); }];
+    });
+  }
+
+  // --- Sign-in and routing ---
+
+  function showLogin() {
+    document.getElementById("nav").hidden = true;
+    document.getElementById("login").hidden = false;
+    render([]);
+  }
+
+  document.getElementById("login-form").addEventListener("submit", function (event) {
+    event.preventDefault();
+    var form = event.target;
+    showError(null);
+    request("POST", "/auth/login", { email: form.email.value, password: form.password.value })
+      .then(function (data) {
+        if (!data.user || data.user.role !== "admin") {
+          throw new Error("This account is not an admin.");
+        }
+        sessionStorage.setItem(TOKEN_KEY, data.token);
+        sessionStorage.setItem(USER_KEY, data.user.email || data.user.userId);
+        form.reset();
+        route();
+      })
+      .catch(showError);
+  });
+
+  document.getElementById("logout").addEventListener("click", signOut);
+
+  function route() {
+    showError(null);
+    if (!token()) {
+      showLogin();
+      return;
+    }
+    document.getElementById("login").hidden = true;
+    document.getElementById("nav").hidden = false;
+    document.getElementById("whoami").textContent = sessionStorage.getItem(USER_KEY) || "";
+
+    var parts = location.hash.replace(/^#\/?/, "").split("/").filter(Boolean).map(decodeURIComponent);
+    var pending;
+    if (parts[0] === "instance") {
+      pending = instanceView();
+    } else if (parts[0] === "users" && parts[2] === "databases" && parts[4] === "tables" && parts[5]) {
+      pending = recordsView(parts[1], parts[3], parts[5], parseInt(parts[6], 10) || 0);
+    } else if (parts[0] === "users" && parts[2] === "databases" && parts[3]) {
+      pending = databaseView(parts[1], parts[3]);
+    } else {
+      pending = databasesView();
+    }
+    pending.catch(showError);
+  }
+
+  window.addEventListener("hashchange", route);
+  route();
+})();
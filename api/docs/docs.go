@@ -0,0 +1,45 @@
+// api/docs/docs.go
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// swaggerUIVersion pins the Swagger UI assets loaded by the explorer page.
+const swaggerUIVersion = "5.17.14"
+
+// explorerPage renders Swagger UI against the embedded spec.
+const explorerPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>Nebula Backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/docs/openapi.yaml", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>`
+
+// RegisterRoutes serves the interactive API explorer at /docs and the raw OpenAPI
+// spec at /docs/openapi.yaml.
+func RegisterRoutes(router *gin.Engine) {
+	router.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(explorerPage))
+	})
+	router.GET("/docs/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", openAPISpec)
+	})
+}
@@ -0,0 +1,174 @@
+// api/grpcapi/auth.go
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/anomaly"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+const apiKeyPrefix = "neb_" // nolint:gosec // Not a credential, just a prefix
+
+// caller is the authenticated identity of a request.
+type caller struct {
+	UserID     string
+	DatabaseID *int64 // Set for DB-scoped API keys, nil for JWTs
+}
+
+type callerKey struct{}
+
+func callerFrom(ctx context.Context) caller {
+	c, _ := ctx.Value(callerKey{}).(caller)
+	return c
+}
+
+// authInterceptor authenticates every call outside AuthService using the "authorization"
+// metadata entry, which takes the same "Bearer <jwt>" / "ApiKey <key>" values as the
+// HTTP Authorization header.
+func authInterceptor(metaDB storage.MetadataStore, cfg *config.Config) grpc.UnaryServerInterceptor {
+	publicPrefix := "/" + nebulav1.AuthService_ServiceDesc.ServiceName + "/"
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		// Correlate log lines the same way as X-Request-ID on the HTTP API
+		requestID := uuid.New().String()
+		if ids := md.Get("x-request-id"); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= 128 {
+			requestID = ids[0]
+		}
+		ctx = logger.NewContext(ctx, requestID)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+		if !serviceModeAllows(info.FullMethod) {
+			code := codes.Unavailable
+			if servicemode.Current().Mode == servicemode.Normal {
+				code = codes.ResourceExhausted // Writes are off to protect the disk
+			}
+			return nil, status.Error(code, servicemode.RejectionMessage())
+		}
+
+		if strings.HasPrefix(info.FullMethod, publicPrefix) {
+			return handler(ctx, req)
+		}
+
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+		}
+		scheme, credentials, ok := strings.Cut(values[0], " ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "authorization must be 'Bearer {token}' or 'ApiKey {key}'")
+		}
+
+		var who caller
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			userID, err := auth.ValidateJWT(credentials, cfg.JWTVerificationSecrets()...)
+			if err != nil {
+				customLog.Ctx(ctx).Printf("gRPC: Token validation failed for %s: %v", info.FullMethod, err)
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			who.UserID = userID
+		case "apikey":
+			if !strings.HasPrefix(credentials, apiKeyPrefix) {
+				return nil, status.Error(codes.Unauthenticated, "invalid API key")
+			}
+			databaseID, userID, err := metaDB.FindAPIKeyOwner(ctx, credentials)
+			if err != nil {
+				if errors.Is(err, storage.ErrAPIKeyNotFound) {
+					anomaly.UnknownAPIKey(ctx, credentials, clientIP(ctx))
+					return nil, status.Error(codes.Unauthenticated, "invalid API key")
+				}
+				return nil, toStatus(err)
+			}
+			country := anomaly.Country(func(name string) string {
+				if values := md.Get(name); len(values) > 0 {
+					return values[0]
+				}
+				return ""
+			})
+			anomaly.APIKeyUsed(ctx, credentials, userID, databaseID, country, clientIP(ctx))
+			who.UserID, who.DatabaseID = userID, &databaseID
+		default:
+			return nil, status.Errorf(codes.Unauthenticated, "unsupported authorization scheme '%s'", scheme)
+		}
+
+		logger.SetUserID(ctx, who.UserID)
+		return handler(context.WithValue(ctx, callerKey{}, who), req)
+	}
+}
+
+// clientIP returns the address of the calling peer, without its port.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// serviceModeAllows applies read-only and maintenance mode: in read-only mode, and
+// while writes are off to protect the disk, only calls that do not modify data
+// (List/Get and Login) are served.
+func serviceModeAllows(fullMethod string) bool {
+	switch s := servicemode.Current(); {
+	case s.Mode == servicemode.Normal && s.WriteProtection == "":
+		return true
+	case s.Mode == servicemode.Maintenance:
+		return false
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	return strings.HasPrefix(method, "List") || strings.HasPrefix(method, "Get") || method == "Login"
+}
+
+// openDatabase resolves dbName for the caller, enforcing API key scope, and connects to
+// it. The caller must release the handle with Release.
+func openDatabase(ctx context.Context, metaDB storage.MetadataStore, dbName string) (storage.UserDataStore, error) {
+	who := callerFrom(ctx)
+	if !core.IsValidIdentifier(dbName) {
+		return nil, status.Error(codes.InvalidArgument, "invalid database name")
+	}
+	logger.SetDatabase(ctx, dbName)
+
+	if who.DatabaseID != nil {
+		databaseID, err := metaDB.FindDatabaseIDByNameAndUser(ctx, who.UserID, dbName)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		if databaseID != *who.DatabaseID {
+			customLog.Ctx(ctx).Warnf("gRPC: FORBIDDEN - User %s API key for DBID %d attempted access to DB '%s'", who.UserID, *who.DatabaseID, dbName)
+			return nil, status.Errorf(codes.PermissionDenied, "API key not valid for database '%s'", dbName)
+		}
+	}
+
+	dbFilePath, err := metaDB.FindDatabasePath(ctx, who.UserID, dbName)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	userDB, err := storage.OpenUserData(ctx, dbFilePath)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return userDB, nil
+}
@@ -0,0 +1,85 @@
+// api/grpcapi/auth_service.go
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/anomaly"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// authService implements nebula.v1.AuthService.
+type authService struct {
+	nebulav1.UnimplementedAuthServiceServer
+	metaDB storage.MetadataStore
+	cfg    *config.Config
+}
+
+// Signup registers a user. Input is validated with the same rules as the HTTP request.
+func (s *authService) Signup(ctx context.Context, req *nebulav1.SignupRequest) (*nebulav1.SignupResponse, error) {
+	signup := models.SignupRequest{Email: req.GetEmail(), Username: req.GetUsername(), Password: req.GetPassword()}
+	if err := binding.Validator.ValidateStruct(&signup); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	hashedPassword, err := auth.HashPassword(req.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	userID, err := s.metaDB.CreateUser(ctx, uuid.New().String(), req.GetUsername(), req.GetEmail(), hashedPassword)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	customLog.Ctx(ctx).Printf("gRPC: Successfully registered user with email %s", req.GetEmail())
+	return &nebulav1.SignupResponse{UserId: userID}, nil
+}
+
+// Login checks credentials and issues a JWT.
+func (s *authService) Login(ctx context.Context, req *nebulav1.LoginRequest) (*nebulav1.LoginResponse, error) {
+	login := models.LoginRequest{Email: req.GetEmail(), Password: req.GetPassword()}
+	if err := binding.Validator.ValidateStruct(&login); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user, err := s.metaDB.FindUserByEmail(ctx, req.GetEmail())
+	if err != nil || user == nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			anomaly.LoginFailed(ctx, req.GetEmail(), "", clientIP(ctx))
+		}
+		return nil, toStatus(storage.ErrInvalidCredentials)
+	}
+	if !auth.CheckPasswordHash(req.GetPassword(), user.PasswordHash) {
+		anomaly.LoginFailed(ctx, req.GetEmail(), user.UserId, clientIP(ctx))
+		return nil, toStatus(storage.ErrInvalidCredentials)
+	}
+	// Hashes made with older settings are upgraded, as for HTTP logins
+	if auth.NeedsRehash(user.PasswordHash) {
+		if rehashed, err := auth.HashPassword(req.GetPassword()); err != nil {
+			customLog.Ctx(ctx).Warnf("gRPC: Failed to rehash password of user %s: %v", user.UserId, err)
+		} else if _, err := s.metaDB.ReplacePasswordHash(ctx, user.UserId, user.PasswordHash, rehashed); err != nil {
+			customLog.Ctx(ctx).Warnf("gRPC: Failed to store rehashed password of user %s: %v", user.UserId, err)
+		}
+	}
+
+	token, err := auth.GenerateJWT(user.UserId, s.cfg.JWTSigningSecret(), s.cfg.JWTExpiration)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &nebulav1.LoginResponse{
+		UserId:   user.UserId,
+		Username: user.Username,
+		Email:    user.Email,
+		Token:    token,
+	}, nil
+}
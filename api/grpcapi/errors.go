@@ -0,0 +1,65 @@
+// api/grpcapi/errors.go
+package grpcapi
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// toStatus maps storage and auth errors to gRPC status codes, mirroring the HTTP
+// ErrorHandler middleware. Unknown errors become Internal without exposing details.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var code codes.Code
+	message := err.Error()
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound),
+		errors.Is(err, storage.ErrDatabaseNotFound),
+		errors.Is(err, storage.ErrRecordNotFound),
+		errors.Is(err, storage.ErrTableNotFound):
+		code = codes.NotFound
+	case errors.Is(err, storage.ErrInvalidCredentials):
+		code, message = codes.Unauthenticated, "invalid email or password"
+	case errors.Is(err, storage.ErrEmailExists),
+		errors.Is(err, storage.ErrDatabaseExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, storage.ErrConstraintViolation),
+		errors.Is(err, storage.ErrDatabaseQuarantined):
+		code = codes.FailedPrecondition
+	case errors.Is(err, storage.ErrColumnNotFound),
+		errors.Is(err, storage.ErrTypeMismatch),
+		errors.Is(err, storage.ErrInvalidFilterValue),
+		errors.Is(err, storage.ErrInvalidSortColumn),
+		errors.Is(err, storage.ErrInvalidFieldColumn),
+		errors.Is(err, auth.ErrBadRequest),
+		errors.Is(err, recordwrite.ErrValidationFailed),
+		errors.Is(err, recordwrite.ErrEmptyHookRecord),
+		errors.Is(err, scripting.ErrScriptTimeout),
+		errors.As(err, new(*scripting.RejectError)):
+		code = codes.InvalidArgument
+	case errors.Is(err, auth.ErrForbidden):
+		code = codes.PermissionDenied
+	case errors.Is(err, storage.ErrRowLimitReached):
+		code = codes.ResourceExhausted
+	case errors.Is(err, storage.ErrUserDataUnsupported):
+		code = codes.Unimplemented
+	case errors.Is(err, storage.ErrDatabaseBusy):
+		code, message = codes.Unavailable, "database is busy, please retry shortly"
+	case errors.Is(err, storage.ErrMetadataUnavailable):
+		code, message = codes.Unavailable, "metadata database is unavailable, please retry shortly"
+	default:
+		customLog.Warnf("gRPC: Internal error: %v", err)
+		code, message = codes.Internal, "internal server error"
+	}
+	return status.Error(code, message)
+}
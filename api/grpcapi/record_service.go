@@ -0,0 +1,248 @@
+// api/grpcapi/record_service.go
+package grpcapi
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// recordService implements nebula.v1.RecordService.
+type recordService struct {
+	nebulav1.UnimplementedRecordServiceServer
+	metaDB storage.MetadataStore
+	cfg    *config.Config
+}
+
+// openTable validates the table name and connects to the caller's database.
+func (s *recordService) openTable(ctx context.Context, dbName, tableName string) (storage.UserDataStore, error) {
+	if !core.IsValidIdentifier(tableName) {
+		return nil, status.Error(codes.InvalidArgument, "invalid table name")
+	}
+	return openDatabase(ctx, s.metaDB, dbName)
+}
+
+// databaseID returns the ID of the caller's database dbName.
+func (s *recordService) databaseID(ctx context.Context, dbName string) (int64, error) {
+	return s.metaDB.FindDatabaseIDByNameAndUser(ctx, callerFrom(ctx).UserID, dbName)
+}
+
+// assignments validates record data against the table's JSON Schema, its column types
+// and its column rules, like the HTTP record handlers. Updates are partial records.
+func (s *recordService) assignments(ctx context.Context, userDB storage.UserDataStore, databaseID int64, tableName string, record map[string]any, partial bool) ([]string, []any, error) {
+	columnTypes, err := userDB.ColumnTypes(ctx, tableName)
+	if err != nil {
+		return nil, nil, toStatus(err)
+	}
+	if err := recordwrite.CheckJSONSchema(ctx, s.metaDB, databaseID, tableName, record, partial); err != nil {
+		return nil, nil, toStatus(err)
+	}
+	if err := core.EncodeJSONValues(columnTypes, record); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	columns, values, err := core.RecordAssignments(columnTypes, record)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(columns) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "no valid columns provided")
+	}
+	if err := recordwrite.CheckColumnRules(ctx, s.metaDB, databaseID, tableName, record); err != nil {
+		return nil, nil, toStatus(err)
+	}
+	return columns, values, nil
+}
+
+// CreateRecord inserts a record and returns its id, running the table's script hooks
+// around the insert.
+func (s *recordService) CreateRecord(ctx context.Context, req *nebulav1.CreateRecordRequest) (*nebulav1.CreateRecordResponse, error) {
+	userDB, err := s.openTable(ctx, req.GetDbName(), req.GetTableName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	databaseID, err := s.databaseID(ctx, req.GetDbName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	script, err := recordwrite.LoadTableScript(ctx, s.metaDB, s.cfg, databaseID, req.GetTableName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	record, err := recordwrite.RunBeforeHook(ctx, s.cfg, script, scripting.BeforeCreate, req.GetTableName(), 0, req.GetData().AsMap())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	columns, values, err := s.assignments(ctx, userDB, databaseID, req.GetTableName(), record, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRowLimit(ctx, userDB, databaseID, req.GetTableName()); err != nil {
+		return nil, toStatus(err)
+	}
+	lastID, err := userDB.InsertRecord(ctx, req.GetTableName(), columns, values)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	recordwrite.RunAfterHook(ctx, s.cfg, script, scripting.AfterCreate, userDB, req.GetTableName(), lastID)
+	return &nebulav1.CreateRecordResponse{RecordId: lastID}, nil
+}
+
+// checkRowLimit fails when the table already holds as many rows as its limit allows.
+func (s *recordService) checkRowLimit(ctx context.Context, userDB storage.UserDataStore, databaseID int64, tableName string) error {
+	options, err := s.metaDB.GetTableOptions(ctx, databaseID, tableName)
+	if err != nil {
+		return err
+	}
+	limit, configured := storage.RowLimit(s.cfg.TableMaxRows, options)
+	return storage.CheckRowLimit(ctx, userDB, tableName, limit, configured, 1)
+}
+
+// GetRecord reads a single record by id.
+func (s *recordService) GetRecord(ctx context.Context, req *nebulav1.GetRecordRequest) (*nebulav1.Record, error) {
+	userDB, err := s.openTable(ctx, req.GetDbName(), req.GetTableName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	record, err := userDB.GetRecord(ctx, req.GetTableName(), req.GetRecordId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return recordToProto(record)
+}
+
+// ListRecords lists records with the same pagination, sorting, field selection and
+// filtering rules as the HTTP endpoint.
+func (s *recordService) ListRecords(ctx context.Context, req *nebulav1.ListRecordsRequest) (*nebulav1.ListRecordsResponse, error) {
+	params := url.Values{}
+	for column, value := range req.GetFilters() {
+		if core.IsReservedParam(column) {
+			return nil, status.Errorf(codes.InvalidArgument, "cannot filter on reserved name '%s'", column)
+		}
+		params.Set(column, value)
+	}
+	if req.GetLimit() != 0 {
+		params.Set("limit", strconv.Itoa(int(req.GetLimit())))
+	}
+	if req.GetOffset() != 0 {
+		params.Set("offset", strconv.Itoa(int(req.GetOffset())))
+	}
+	if req.GetSort() != "" {
+		params.Set("sort", req.GetSort())
+	}
+	if req.GetOrder() != "" {
+		params.Set("order", req.GetOrder())
+	}
+	if len(req.GetFields()) > 0 {
+		params.Set("fields", strings.Join(req.GetFields(), ","))
+	}
+
+	opts, err := core.ParseListQueryOptions(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	userDB, err := s.openTable(ctx, req.GetDbName(), req.GetTableName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	result, err := userDB.ListRecords(ctx, req.GetTableName(), params, opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &nebulav1.ListRecordsResponse{
+		Records: make([]*nebulav1.Record, 0, len(result.Records)),
+		Total:   int32(result.Pagination.Total),  //nolint:gosec // Bounded by table size
+		Limit:   int32(result.Pagination.Limit),  //nolint:gosec // Bounded by MaxLimit
+		Offset:  int32(result.Pagination.Offset), //nolint:gosec // Parsed from an int32
+	}
+	for _, record := range result.Records {
+		r, err := recordToProto(record)
+		if err != nil {
+			return nil, err
+		}
+		resp.Records = append(resp.Records, r)
+	}
+	return resp, nil
+}
+
+// UpdateRecord updates the given columns of a record, running the table's script hooks
+// around the update.
+func (s *recordService) UpdateRecord(ctx context.Context, req *nebulav1.UpdateRecordRequest) (*nebulav1.UpdateRecordResponse, error) {
+	userDB, err := s.openTable(ctx, req.GetDbName(), req.GetTableName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	databaseID, err := s.databaseID(ctx, req.GetDbName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	script, err := recordwrite.LoadTableScript(ctx, s.metaDB, s.cfg, databaseID, req.GetTableName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	record, err := recordwrite.RunBeforeHook(ctx, s.cfg, script, scripting.BeforeUpdate, req.GetTableName(), req.GetRecordId(), req.GetData().AsMap())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	columns, values, err := s.assignments(ctx, userDB, databaseID, req.GetTableName(), record, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := userDB.UpdateRecord(ctx, req.GetTableName(), req.GetRecordId(), columns, values); err != nil {
+		return nil, toStatus(err)
+	}
+	recordwrite.RunAfterHook(ctx, s.cfg, script, scripting.AfterUpdate, userDB, req.GetTableName(), req.GetRecordId())
+	return &nebulav1.UpdateRecordResponse{}, nil
+}
+
+// DeleteRecord deletes a record by id.
+func (s *recordService) DeleteRecord(ctx context.Context, req *nebulav1.DeleteRecordRequest) (*nebulav1.DeleteRecordResponse, error) {
+	userDB, err := s.openTable(ctx, req.GetDbName(), req.GetTableName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	if _, err := userDB.DeleteRecord(ctx, req.GetTableName(), req.GetRecordId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &nebulav1.DeleteRecordResponse{}, nil
+}
+
+// recordToProto converts a scanned row into a Struct. Timestamps become RFC 3339 strings.
+func recordToProto(record map[string]any) (*nebulav1.Record, error) {
+	fields := make(map[string]any, len(record))
+	for name, value := range record {
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339)
+		}
+		fields[name] = value
+	}
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &nebulav1.Record{Fields: s}, nil
+}
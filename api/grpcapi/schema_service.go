@@ -0,0 +1,89 @@
+// api/grpcapi/schema_service.go
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// schemaService implements nebula.v1.SchemaService.
+type schemaService struct {
+	nebulav1.UnimplementedSchemaServiceServer
+	metaDB storage.MetadataStore
+}
+
+// ListTables lists the tables of a database with their columns.
+func (s *schemaService) ListTables(ctx context.Context, req *nebulav1.ListTablesRequest) (*nebulav1.ListTablesResponse, error) {
+	userDB, err := openDatabase(ctx, s.metaDB, req.GetDbName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	tables, err := userDB.ListTables(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &nebulav1.ListTablesResponse{Tables: make([]*nebulav1.Table, 0, len(tables))}
+	for _, table := range tables {
+		t := &nebulav1.Table{Name: table.Name}
+		for _, col := range table.Columns {
+			t.Columns = append(t.Columns, &nebulav1.Column{
+				Name:       col.Name,
+				Type:       col.Type,
+				NotNull:    col.NotNull != 0,
+				PrimaryKey: col.PK != 0,
+			})
+		}
+		resp.Tables = append(resp.Tables, t)
+	}
+	return resp, nil
+}
+
+// CreateTable creates a table with the same implicit columns as the HTTP API.
+func (s *schemaService) CreateTable(ctx context.Context, req *nebulav1.CreateTableRequest) (*nebulav1.CreateTableResponse, error) {
+	specs := make([]core.ColumnSpec, len(req.GetColumns()))
+	for i, col := range req.GetColumns() {
+		specs[i] = core.ColumnSpec{Name: col.GetName(), Type: col.GetType()}
+	}
+	specs, err := core.ValidateTableDefinition(req.GetTableName(), specs)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	userDB, err := openDatabase(ctx, s.metaDB, req.GetDbName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	if err := userDB.CreateTable(ctx, req.GetTableName(), specs); err != nil {
+		return nil, toStatus(err)
+	}
+	return &nebulav1.CreateTableResponse{}, nil
+}
+
+// DropTable drops a table if it exists.
+func (s *schemaService) DropTable(ctx context.Context, req *nebulav1.DropTableRequest) (*nebulav1.DropTableResponse, error) {
+	if !core.IsValidIdentifier(req.GetTableName()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid table name")
+	}
+
+	userDB, err := openDatabase(ctx, s.metaDB, req.GetDbName())
+	if err != nil {
+		return nil, err
+	}
+	defer userDB.Release()
+
+	if err := userDB.DropTable(ctx, req.GetTableName()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &nebulav1.DropTableResponse{}, nil
+}
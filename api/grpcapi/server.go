@@ -0,0 +1,27 @@
+// api/grpcapi/server.go
+package grpcapi
+
+import (
+	"google.golang.org/grpc"
+
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var (
+	customLog = logger.NewLogger()
+)
+
+// NewServer creates a gRPC server exposing the auth, schema and record services defined
+// in api/proto/nebula/v1/nebula.proto. It shares the metadata DB and storage layer with
+// the HTTP API and accepts the same JWTs and API keys.
+func NewServer(metaDB storage.MetadataStore, cfg *config.Config) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(authInterceptor(metaDB, cfg)))
+
+	nebulav1.RegisterAuthServiceServer(server, &authService{metaDB: metaDB, cfg: cfg})
+	nebulav1.RegisterSchemaServiceServer(server, &schemaService{metaDB: metaDB})
+	nebulav1.RegisterRecordServiceServer(server, &recordService{metaDB: metaDB, cfg: cfg})
+	return server
+}
@@ -0,0 +1,157 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	nebulav1 "github.com/Annany2002/nebula-backend/api/proto/nebula/v1"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := &config.Config{MetadataDbDir: dir, MetadataDbFile: "meta.db", JWTSecret: "test-secret", JWTExpiration: time.Hour, ScriptTimeout: time.Second, ScriptMaxCallStackSize: 64}
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer metaDB.Close()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(metaDB, cfg)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer conn.Close()
+	authClient := nebulav1.NewAuthServiceClient(conn)
+	schemaClient := nebulav1.NewSchemaServiceClient(conn)
+	recordClient := nebulav1.NewRecordServiceClient(conn)
+
+	// Calls outside AuthService need credentials
+	_, err = schemaClient.ListTables(ctx, &nebulav1.ListTablesRequest{DbName: "app"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("ListTables without credentials: got %v, want Unauthenticated", err)
+	}
+
+	signup, err := authClient.Signup(ctx, &nebulav1.SignupRequest{Email: "grpc@example.com", Username: "grpcuser", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Signup: %v", err)
+	}
+	login, err := authClient.Login(ctx, &nebulav1.LoginRequest{Email: "grpc@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+login.GetToken())
+
+	dbPath := filepath.Join(dir, "app.db")
+	defer storage.InvalidateUserDB(dbPath)
+	if err := metaDB.RegisterDatabase(ctx, signup.GetUserId(), "app", dbPath); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+
+	_, err = schemaClient.CreateTable(authCtx, &nebulav1.CreateTableRequest{
+		DbName:    "app",
+		TableName: "notes",
+		Columns:   []*nebulav1.Column{{Name: "body", Type: "text"}, {Name: "stars", Type: "integer"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	data, _ := structpb.NewStruct(map[string]any{"body": "hello", "stars": 4})
+	created, err := recordClient.CreateRecord(authCtx, &nebulav1.CreateRecordRequest{DbName: "app", TableName: "notes", Data: data})
+	if err != nil {
+		t.Fatalf("CreateRecord: %v", err)
+	}
+
+	list, err := recordClient.ListRecords(authCtx, &nebulav1.ListRecordsRequest{DbName: "app", TableName: "notes", Filters: map[string]string{"stars": "4"}})
+	if err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+	if list.GetTotal() != 1 || list.GetRecords()[0].GetFields().AsMap()["body"] != "hello" {
+		t.Fatalf("ListRecords = %v, want the created record", list)
+	}
+
+	bad, _ := structpb.NewStruct(map[string]any{"stars": "many"})
+	_, err = recordClient.UpdateRecord(authCtx, &nebulav1.UpdateRecordRequest{DbName: "app", TableName: "notes", RecordId: created.GetRecordId(), Data: bad})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("UpdateRecord with wrong type: got %v, want InvalidArgument", err)
+	}
+
+	// Writes run the same table checks as over HTTP
+	databaseID, _ := metaDB.FindDatabaseIDByNameAndUser(ctx, signup.GetUserId(), "app")
+	minStars := 1.0
+	if err := metaDB.ReplaceColumnRules(ctx, databaseID, "notes", []domain.ColumnRule{{Column: "stars", Min: &minStars}}); err != nil {
+		t.Fatalf("ReplaceColumnRules: %v", err)
+	}
+	noStars, _ := structpb.NewStruct(map[string]any{"body": "meh", "stars": 0})
+	_, err = recordClient.CreateRecord(authCtx, &nebulav1.CreateRecordRequest{DbName: "app", TableName: "notes", Data: noStars})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateRecord breaking a column rule: got %v, want InvalidArgument", err)
+	}
+	_, err = recordClient.UpdateRecord(authCtx, &nebulav1.UpdateRecordRequest{DbName: "app", TableName: "notes", RecordId: created.GetRecordId(), Data: noStars})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateRecord breaking a column rule: got %v, want InvalidArgument", err)
+	}
+	if err := metaDB.SetTableJSONSchema(ctx, databaseID, "notes", `{"type":"object","properties":{"body":{"type":"string","maxLength":5}}}`); err != nil {
+		t.Fatalf("SetTableJSONSchema: %v", err)
+	}
+	longBody, _ := structpb.NewStruct(map[string]any{"body": "far too long", "stars": 3})
+	_, err = recordClient.CreateRecord(authCtx, &nebulav1.CreateRecordRequest{DbName: "app", TableName: "notes", Data: longBody})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateRecord not matching the JSON schema: got %v, want InvalidArgument", err)
+	}
+	_, err = recordClient.UpdateRecord(authCtx, &nebulav1.UpdateRecordRequest{DbName: "app", TableName: "notes", RecordId: created.GetRecordId(), Data: longBody})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateRecord not matching the JSON schema: got %v, want InvalidArgument", err)
+	}
+	script := `function beforeCreate(record) {
+		if (record.body === "nope") throw new Error("no nopes");
+		record.body = record.body.toUpperCase();
+	}`
+	if err := metaDB.SetTableScript(ctx, databaseID, "notes", script); err != nil {
+		t.Fatalf("SetTableScript: %v", err)
+	}
+	hooked, _ := structpb.NewStruct(map[string]any{"body": "hi", "stars": 2})
+	shouted, err := recordClient.CreateRecord(authCtx, &nebulav1.CreateRecordRequest{DbName: "app", TableName: "notes", Data: hooked})
+	if err != nil {
+		t.Fatalf("CreateRecord with a script: %v", err)
+	}
+	stored, err := recordClient.GetRecord(authCtx, &nebulav1.GetRecordRequest{DbName: "app", TableName: "notes", RecordId: shouted.GetRecordId()})
+	if err != nil || stored.GetFields().AsMap()["body"] != "HI" {
+		t.Errorf("record written through beforeCreate = %v, %v; want body HI", stored, err)
+	}
+	rejected, _ := structpb.NewStruct(map[string]any{"body": "nope", "stars": 2})
+	_, err = recordClient.CreateRecord(authCtx, &nebulav1.CreateRecordRequest{DbName: "app", TableName: "notes", Data: rejected})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateRecord rejected by beforeCreate: got %v, want InvalidArgument", err)
+	}
+
+	if _, err := recordClient.DeleteRecord(authCtx, &nebulav1.DeleteRecordRequest{DbName: "app", TableName: "notes", RecordId: created.GetRecordId()}); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	_, err = recordClient.GetRecord(authCtx, &nebulav1.GetRecordRequest{DbName: "app", TableName: "notes", RecordId: created.GetRecordId()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("GetRecord after delete: got %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,101 @@
+// api/handlers/account_export_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
+	"github.com/Annany2002/nebula-backend/internal/mail"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// AccountExportHandler holds dependencies for the self-service data export handlers.
+type AccountExportHandler struct {
+	MetaDB  storage.MetadataStore // Metadata DB pool
+	Cfg     *config.Config        // App configuration
+	Jobs    *jobs.Manager         // Background job runner writing the archives
+	running sync.Map              // User IDs with an export in progress
+}
+
+// NewAccountExportHandler creates a new AccountExportHandler.
+func NewAccountExportHandler(metaDB storage.MetadataStore, cfg *config.Config, jobManager *jobs.Manager) *AccountExportHandler {
+	return &AccountExportHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+		Jobs:   jobManager,
+	}
+}
+
+// StartAccountExport bundles everything stored about the caller's account into a zip
+// archive in a background job, emailing the caller when it is ready to download.
+func (h *AccountExportHandler) StartAccountExport(c *gin.Context) {
+	userId, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	if _, busy := h.running.LoadOrStore(userId, struct{}{}); busy {
+		abortWithError(c, http.StatusConflict, "An export of this account is already in progress.")
+		return
+	}
+	// Download path of the archive under the API version of this request
+	exportsPath := strings.TrimSuffix(c.FullPath(), "/export") + "/exports/"
+
+	job := h.Jobs.Submit(userId, "account_export", func(ctx context.Context) (any, error) {
+		defer h.running.Delete(userId)
+		exportDir := storage.AccountExportDirectory(h.Cfg.AccountExportDir, userId)
+		export, err := storage.CreateAccountExport(ctx, h.MetaDB, exportDir, userId, h.Cfg.AccountExportRetention)
+		if err != nil {
+			return nil, err
+		}
+		if h.Cfg.MailProvider != "" {
+			if user, err := h.MetaDB.FindUserByUserId(ctx, userId); err != nil {
+				customLog.Ctx(ctx).Warnf("Handler: Failed to find UserID %s to email export %s: %v", userId, export.ExportID, err)
+			} else if err := mail.Enqueue(ctx, h.MetaDB, user.Email, mail.TemplateAccountExport, mail.AccountExportData{
+				Username:     user.Username,
+				DownloadPath: exportsPath + export.ExportID,
+				ExpiresAt:    export.ExpiresAt.Format(time.RFC1123),
+			}); err != nil {
+				customLog.Ctx(ctx).Warnf("Handler: Failed to queue export email for UserID %s: %v", userId, err)
+			}
+		}
+		return gin.H{"export": export}, nil
+	})
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Export of account UserID %s queued as job %s", userId, job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Account export job queued", "job": job})
+}
+
+// ListAccountExports returns the caller's exports that can still be downloaded.
+func (h *AccountExportHandler) ListAccountExports(c *gin.Context) {
+	userId, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	exports, err := storage.ListAccountExports(storage.AccountExportDirectory(h.Cfg.AccountExportDir, userId), h.Cfg.AccountExportRetention)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// DownloadAccountExport sends one of the caller's export archives.
+func (h *AccountExportHandler) DownloadAccountExport(c *gin.Context) {
+	userId, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	exportPath, err := storage.FindAccountExport(storage.AccountExportDirectory(h.Cfg.AccountExportDir, userId), c.Param("export_id"), h.Cfg.AccountExportRetention)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.FileAttachment(exportPath, fmt.Sprintf("nebula-export-%s.zip", time.Now().UTC().Format("2006-01-02")))
+}
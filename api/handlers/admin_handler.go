@@ -0,0 +1,124 @@
+// api/handlers/admin_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/coordination"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// AdminHandler holds dependencies for instance administration handlers.
+type AdminHandler struct {
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
+	Jobs   *jobs.Manager         // Background job runner for rebalances
+}
+
+// NewAdminHandler creates a new AdminHandler.
+func NewAdminHandler(metaDB storage.MetadataStore, cfg *config.Config, jobManager *jobs.Manager) *AdminHandler {
+	return &AdminHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+		Jobs:   jobManager,
+	}
+}
+
+// GetLogLevel returns the current minimum log level.
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": logger.Level()})
+}
+
+// SetLogLevel changes the minimum log level without a restart. The change is not
+// persisted; LOG_LEVEL applies again on the next start.
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var req models.LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'level' must be one of debug, info, warn, error.")
+		return
+	}
+
+	previous := logger.Level()
+	if err := logger.SetLevel(req.Level); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// Logged at warn so the change is recorded whatever the new level is
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Log level changed from %s to %s by UserID %s", previous, req.Level, userId)
+	c.JSON(http.StatusOK, gin.H{"level": req.Level, "previous": previous})
+}
+
+// GetServiceMode returns the current service mode.
+func (h *AdminHandler) GetServiceMode(c *gin.Context) {
+	c.JSON(http.StatusOK, servicemode.Current())
+}
+
+// GetInstances returns this instance and the other instances sharing its data
+// directory, with the warnings their setup raises. 404 when instance heartbeats are
+// disabled.
+func (h *AdminHandler) GetInstances(c *gin.Context) {
+	status, ok := coordination.CurrentStatus()
+	if !ok {
+		abortWithError(c, http.StatusNotFound, "Instance heartbeats are disabled (INSTANCE_HEARTBEAT_SECONDS=0).")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SetServiceMode switches between normal, read-only and maintenance mode. Like the
+// log level, the change lasts until the next restart, where SERVICE_MODE applies.
+func (h *AdminHandler) SetServiceMode(c *gin.Context) {
+	var req models.ServiceModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'mode' must be one of normal, read_only, maintenance.")
+		return
+	}
+
+	state := servicemode.Set(servicemode.Mode(req.Mode), req.Message)
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Service mode set to %s by UserID %s", state.Mode, userId)
+	c.JSON(http.StatusOK, state)
+}
+
+// ListDatabases returns the databases of every account with their owner, for the admin
+// console.
+func (h *AdminHandler) ListDatabases(c *gin.Context) {
+	databases, err := h.MetaDB.ListAllDatabases(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	owners := make(map[string]*domain.UserMetadata)
+	items := make([]models.AdminDatabase, 0, len(databases))
+	for _, db := range databases {
+		owner, ok := owners[db.UserID]
+		if !ok {
+			if owner, err = h.MetaDB.FindUserByUserId(c.Request.Context(), db.UserID); err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+				_ = c.Error(err)
+				return
+			}
+			owners[db.UserID] = owner
+		}
+		item := models.AdminDatabase{DatabaseID: db.DatabaseID, DBName: db.DBName, UserID: db.UserID, CreatedAt: db.CreatedAt, Quarantine: db.Quarantine}
+		if owner != nil {
+			item.OwnerEmail, item.OwnerUsername = owner.Email, owner.Username
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"databases": items})
+}
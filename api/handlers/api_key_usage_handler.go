@@ -0,0 +1,150 @@
+// api/handlers/api_key_usage_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Number of endpoints and client addresses in API key usage statistics.
+const (
+	apiKeyTopEndpoints = 10
+	apiKeyLastClients  = 10
+)
+
+// GetAPIKeyUsage returns the traffic of a database's API key over the last ?days UTC
+// days: requests and error rates per day and in total, the most used endpoints, and
+// the addresses the key was last used from, so leaked or abandoned keys stand out.
+func (h *DatabaseHandler) GetAPIKeyUsage(c *gin.Context) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	dbName := c.Param("db_name")
+	if !core.IsValidIdentifier(dbName) {
+		err := errors.New("invalid database name in URL path")
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	keyId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || keyId < 1 {
+		_ = c.Error(fmt.Errorf("invalid api key id '%s'", c.Param("id")))
+		abortWithError(c, http.StatusBadRequest, "Invalid API key ID.")
+		return
+	}
+	since, ok := usageSince(c)
+	if !ok {
+		return
+	}
+
+	databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Database '%s' not found for your account.", dbName))
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify database ownership.")
+		}
+		return
+	}
+	currentKeyId, err := h.MetaDB.FindAPIKeyID(c.Request.Context(), databaseID)
+	if err != nil && !errors.Is(err, storage.ErrAPIKeyNotFound) {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to look up API key.")
+		return
+	}
+	if err != nil || currentKeyId != keyId {
+		_ = c.Error(storage.ErrAPIKeyNotFound)
+		abortWithError(c, http.StatusNotFound, fmt.Sprintf("API key %d not found for database '%s'.", keyId, dbName))
+		return
+	}
+
+	// Include the traffic counted since the last periodic flush
+	if err := storage.FlushAPIKeyUsage(c.Request.Context(), h.MetaDB); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Usage of API key %d may miss recent requests: %v", keyId, err)
+	}
+	usage, err := h.MetaDB.ListAPIKeyUsage(c.Request.Context(), keyId, since)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to retrieve API key usage.")
+		return
+	}
+	clients, err := h.MetaDB.ListAPIKeyClients(c.Request.Context(), keyId, apiKeyLastClients)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to retrieve API key usage.")
+		return
+	}
+
+	// Buckets are per day and route: sum them per day (listed in order) and per route
+	days := make([]domain.APIKeyUsage, 0)
+	endpoints := make([]domain.APIKeyUsage, 0)
+	endpointIndex := make(map[string]int)
+	var totals domain.APIKeyUsage
+	for _, u := range usage {
+		if len(days) == 0 || days[len(days)-1].Day != u.Day {
+			days = append(days, domain.APIKeyUsage{Day: u.Day})
+		}
+		days[len(days)-1].Requests += u.Requests
+		days[len(days)-1].Errors += u.Errors
+
+		route := u.Method + " " + u.Route
+		i, ok := endpointIndex[route]
+		if !ok {
+			i = len(endpoints)
+			endpointIndex[route] = i
+			endpoints = append(endpoints, domain.APIKeyUsage{Method: u.Method, Route: u.Route})
+		}
+		endpoints[i].Requests += u.Requests
+		endpoints[i].Errors += u.Errors
+
+		totals.Requests += u.Requests
+		totals.Errors += u.Errors
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool { return endpoints[i].Requests > endpoints[j].Requests })
+	if len(endpoints) > apiKeyTopEndpoints {
+		endpoints = endpoints[:apiKeyTopEndpoints]
+	}
+	for i := range days {
+		days[i].ErrorRate = keyErrorRate(days[i])
+	}
+	for i := range endpoints {
+		endpoints[i].ErrorRate = keyErrorRate(endpoints[i])
+	}
+	totals.ErrorRate = keyErrorRate(totals)
+
+	response := gin.H{
+		"key_id":        keyId,
+		"db_name":       dbName,
+		"since":         since,
+		"totals":        totals,
+		"days":          days,
+		"top_endpoints": endpoints,
+		"last_ips":      clients,
+		"last_used_at":  nil,
+	}
+	if len(clients) > 0 {
+		response["last_used_at"] = clients[0].LastSeenAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// keyErrorRate returns the share of requests of usage that failed.
+func keyErrorRate(usage domain.APIKeyUsage) float64 {
+	if usage.Requests == 0 {
+		return 0
+	}
+	return float64(usage.Errors) / float64(usage.Requests)
+}
@@ -0,0 +1,54 @@
+// api/handlers/audit_log_handler.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetAuditLog returns the security audit log of the current user (e.g. auth anomaly
+// alerts), newest first. Supports limit and offset.
+func (h *AuthHandler) GetAuditLog(c *gin.Context) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	writeAuditLog(c, h.DB, userId)
+}
+
+// GetAuditLog returns the audit log of every account, including events not tied to an
+// account such as failed logins from one address, newest first. ?user_id narrows it to
+// one account. Supports limit and offset.
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	writeAuditLog(c, h.MetaDB, c.Query("user_id"))
+}
+
+// writeAuditLog responds with a page of the audit log of userId, or of every account
+// when it is empty.
+func writeAuditLog(c *gin.Context, store storage.MetadataStore, userId string) {
+	limit, offset, err := core.ParsePagination(c.Request.URL.Query(), core.MaxLimit)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, pagination, err := store.ListAuditEvents(c.Request.Context(), userId, limit, offset)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to retrieve the audit log.")
+		return
+	}
+
+	if apiVersion(c) >= 2 {
+		c.JSON(http.StatusOK, pageEnvelope{Data: events, Pagination: newPageMeta(pagination)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events, "pagination": newPageMeta(pagination)})
+}
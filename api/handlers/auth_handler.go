@@ -2,15 +2,19 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
 	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/anomaly"
 	"github.com/Annany2002/nebula-backend/internal/auth" // Import internal auth logic
+	"github.com/Annany2002/nebula-backend/internal/domain"
 	"github.com/Annany2002/nebula-backend/internal/logger"
 	"github.com/Annany2002/nebula-backend/internal/storage" // Import storage functions/errors
 )
@@ -21,13 +25,13 @@ var (
 
 // AuthHandler holds dependencies for authentication handlers.
 type AuthHandler struct {
-	DB  *sql.DB        // Metadata DB connection pool
-	Cfg *config.Config // Application configuration
+	DB  storage.MetadataStore // Metadata DB connection pool
+	Cfg *config.Config        // Application configuration
 	// Add AuthService interface later if needed
 }
 
 // NewAuthHandler creates a new AuthHandler with dependencies.
-func NewAuthHandler(db *sql.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db storage.MetadataStore, cfg *config.Config) *AuthHandler {
 	return &AuthHandler{
 		DB:  db,
 		Cfg: cfg,
@@ -40,7 +44,7 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 	uuid := uuid.New().String()
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		customLog.Warnf("Signup binding error: %v", err)
+		customLog.Ctx(c.Request.Context()).Warnf("Signup binding error: %v", err)
 		_ = c.Error(err) // Attach the binding error
 		return
 	}
@@ -48,20 +52,20 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 	// Hash the password using the internal auth function
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
-		customLog.Warnf("Failed to hash password during signup for email %s: %v", req.Email, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to hash password during signup for email %s: %v", req.Email, err)
 		_ = c.Error(err) // Attach internal error
 		return
 	}
 
 	// Create user using the storage function
-	user_id, err := storage.CreateUser(c.Request.Context(), h.DB, uuid, req.Username, req.Email, hashedPassword)
+	user_id, err := h.DB.CreateUser(c.Request.Context(), uuid, req.Username, req.Email, hashedPassword)
 	if err != nil {
-		customLog.Warnf("Failed to create user %s: %v", req.Email, err) // Log context
-		_ = c.Error(err)                                                // Attach storage error (e.g., ErrEmailExists)
-		return                                                          // Let middleware handle response
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to create user %s: %v", req.Email, err) // Log context
+		_ = c.Error(err)                                                                         // Attach storage error (e.g., ErrEmailExists)
+		return                                                                                   // Let middleware handle response
 	}
 
-	customLog.Printf("Successfully registered user with email %s", req.Email)
+	customLog.Ctx(c.Request.Context()).Printf("Successfully registered user with email %s", req.Email)
 	c.JSON(http.StatusCreated, gin.H{"user_id": user_id, "message": "User registered successfully"}) // Success response remains
 }
 
@@ -70,29 +74,34 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		customLog.Warnf("Login binding error: %v", err)
+		customLog.Ctx(c.Request.Context()).Warnf("Login binding error: %v", err)
 		_ = c.Error(err) // Attach binding error
 		return           // Let middleware handle
 	}
 
-	user, err := storage.FindUserByEmail(c.Request.Context(), h.DB, req.Email)
+	user, err := h.DB.FindUserByEmail(c.Request.Context(), req.Email)
 	if err != nil || user == nil {
-		customLog.Warnf("Login failed for email %s: %v", req.Email, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Login failed for email %s: %v", req.Email, err)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			anomaly.LoginFailed(c.Request.Context(), req.Email, "", c.ClientIP())
+		}
 		_ = c.Error(err) // Attach ErrUserNotFound or DB error
 		return
 	}
 
 	if !auth.CheckPasswordHash(req.Password, user.PasswordHash) {
-		customLog.Warnf("Login attempt failed for email %s: invalid password", user.Email)
+		customLog.Ctx(c.Request.Context()).Warnf("Login attempt failed for email %s: invalid password", user.Email)
+		anomaly.LoginFailed(c.Request.Context(), req.Email, user.UserId, c.ClientIP())
 		// *** CHANGED: Use the specific error variable ***
 		_ = c.Error(storage.ErrInvalidCredentials)
 		return // Let middleware handle
 	}
+	upgradePasswordHash(c.Request.Context(), h.DB, user, req.Password)
 
 	// ... (generate JWT and return success) ...
-	tokenString, err := auth.GenerateJWT(user.UserId, h.Cfg.JWTSecret, h.Cfg.JWTExpiration)
+	tokenString, err := auth.GenerateJWT(user.UserId, h.Cfg.JWTSigningSecret(), h.Cfg.JWTExpiration)
 	if err != nil {
-		customLog.Warnf("Failed to generate JWT for user %s: %v", user.UserId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to generate JWT for user %s: %v", user.UserId, err)
 		_ = c.Error(err) // Attach JWT generation error
 		return
 	}
@@ -105,12 +114,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) FindUser(c *gin.Context) {
 	user_id := c.Param("user_id")
 
-	user, err := storage.FindUserByUserId(c.Request.Context(), h.DB, user_id)
+	user, err := h.DB.FindUserByUserId(c.Request.Context(), user_id)
 
 	if err != nil {
-		customLog.Warnf("User with user_id %s not found", user_id)
-		// _ = c.Error(err)
-		c.JSON(http.StatusNotFound, gin.H{"message": "User not found", "user": nil})
+		customLog.Ctx(c.Request.Context()).Warnf("User with user_id %s not found", user_id)
+		abortWithError(c, http.StatusNotFound, "User not found")
 		return
 	}
 
@@ -119,11 +127,15 @@ func (h *AuthHandler) FindUser(c *gin.Context) {
 
 // GetCurrentUser returns the profile of the currently authenticated user.
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	user, err := storage.FindUserByUserId(c.Request.Context(), h.DB, userId)
+	user, err := h.DB.FindUserByUserId(c.Request.Context(), userId)
 	if err != nil {
-		customLog.Warnf("Failed to get current user profile for userId %s: %v", userId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to get current user profile for userId %s: %v", userId, err)
 		_ = c.Error(err)
 		return
 	}
@@ -139,38 +151,42 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 
 // UpdateCurrentUser updates the profile of the currently authenticated user.
 func (h *AuthHandler) UpdateCurrentUser(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
 	var req models.UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		customLog.Warnf("Update profile binding error for userId %s: %v", userId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Update profile binding error for userId %s: %v", userId, err)
 		_ = c.Error(err)
 		return
 	}
 
 	// Check if there's anything to update
 	if req.Username == "" && req.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update. Provide 'username' or 'email'."})
+		abortWithError(c, http.StatusBadRequest, "No fields to update. Provide 'username' or 'email'.")
 		return
 	}
 
 	// Update user profile
-	err := storage.UpdateUser(c.Request.Context(), h.DB, userId, req.Username, req.Email)
+	err = h.DB.UpdateUser(c.Request.Context(), userId, req.Username, req.Email)
 	if err != nil {
-		customLog.Warnf("Failed to update user profile for userId %s: %v", userId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to update user profile for userId %s: %v", userId, err)
 		_ = c.Error(err)
 		return
 	}
 
 	// Fetch updated user to return
-	updatedUser, err := storage.FindUserByUserId(c.Request.Context(), h.DB, userId)
+	updatedUser, err := h.DB.FindUserByUserId(c.Request.Context(), userId)
 	if err != nil {
-		customLog.Warnf("Failed to fetch updated user profile for userId %s: %v", userId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Failed to fetch updated user profile for userId %s: %v", userId, err)
 		_ = c.Error(err)
 		return
 	}
 
-	customLog.Printf("Successfully updated profile for userId %s", userId)
+	customLog.Ctx(c.Request.Context()).Printf("Successfully updated profile for userId %s", userId)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Profile updated successfully",
 		"user": models.UserProfileResponse{
@@ -181,3 +197,25 @@ func (h *AuthHandler) UpdateCurrentUser(c *gin.Context) {
 		},
 	})
 }
+
+// upgradePasswordHash rehashes the password of a user who just logged in when their
+// stored hash was made with older settings, so hashing upgrades roll out without
+// password resets. Failures are logged only: the login succeeds either way.
+func upgradePasswordHash(ctx context.Context, metaDB storage.MetadataStore, user *domain.UserMetadata, password string) {
+	if !auth.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	rehashed, err := auth.HashPassword(password)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Failed to rehash password of user %s: %v", user.UserId, err)
+		return
+	}
+	replaced, err := metaDB.ReplacePasswordHash(ctx, user.UserId, user.PasswordHash, rehashed)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Failed to store rehashed password of user %s: %v", user.UserId, err)
+		return
+	}
+	if replaced {
+		customLog.Ctx(ctx).Printf("Upgraded password hash of user %s", user.UserId)
+	}
+}
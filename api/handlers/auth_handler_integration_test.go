@@ -4,7 +4,6 @@ package handlers_test
 import (
 	"bytes"
 	"context" // Import context package
-	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/Annany2002/nebula-backend/api"
 	"github.com/Annany2002/nebula-backend/api/models"
@@ -26,7 +26,7 @@ import (
 )
 
 // testDBSetup creates a temporary SQLite DB for testing and returns the DB pool and cleanup func.
-func testDBSetup(t *testing.T) (*sql.DB, *config.Config, func()) {
+func testDBSetup(t *testing.T) (storage.MetadataStore, *config.Config, func()) {
 	t.Helper()
 
 	tempDir := t.TempDir()
@@ -57,7 +57,7 @@ func testDBSetup(t *testing.T) (*sql.DB, *config.Config, func()) {
 }
 
 // setupTestServer creates a test server instance with a test DB.
-func setupTestServer(t *testing.T) (*httptest.Server, *sql.DB, func()) {
+func setupTestServer(t *testing.T) (*httptest.Server, storage.MetadataStore, func()) {
 	t.Helper()
 	gin.SetMode(gin.TestMode)
 
@@ -85,7 +85,7 @@ func TestAuthEndpoints(t *testing.T) {
 
 	// --- Test Signup ---
 	t.Run("Signup Success", func(t *testing.T) {
-		signupReqBody := models.SignupRequest{Email: testEmail, Password: testPassword}
+		signupReqBody := models.SignupRequest{Email: testEmail, Username: "integration_user", Password: testPassword}
 		bodyBytes, _ := json.Marshal(signupReqBody)
 
 		res, err := http.Post(server.URL+"/auth/signup", "application/json", bytes.NewReader(bodyBytes))
@@ -101,7 +101,7 @@ func TestAuthEndpoints(t *testing.T) {
 
 		// Verify user created in DB (Direct DB check)
 		// *** FIXED: Use context.Background() ***
-		user, err := storage.FindUserByEmail(context.Background(), db, testEmail)
+		user, err := db.FindUserByEmail(context.Background(), testEmail)
 		assert.NoError(err, "Finding user after signup should not fail")
 		assert.NotNil(user, "User should exist in DB after signup")
 		if user != nil { // Prevent panic on nil pointer if previous assert fails
@@ -112,7 +112,7 @@ func TestAuthEndpoints(t *testing.T) {
 
 	t.Run("Signup Conflict (Duplicate Email)", func(t *testing.T) {
 		// Assumes the previous test ran successfully and created the user
-		signupReqBody := models.SignupRequest{Email: testEmail, Password: "anotherPassword"}
+		signupReqBody := models.SignupRequest{Email: testEmail, Username: "integration_user", Password: "anotherPassword"}
 		bodyBytes, _ := json.Marshal(signupReqBody)
 
 		res, err := http.Post(server.URL+"/auth/signup", "application/json", bytes.NewReader(bodyBytes))
@@ -122,7 +122,7 @@ func TestAuthEndpoints(t *testing.T) {
 	})
 
 	t.Run("Signup Bad Request (Invalid Email Format)", func(t *testing.T) {
-		signupReqBody := models.SignupRequest{Email: "invalid-email-format", Password: testPassword}
+		signupReqBody := models.SignupRequest{Email: "invalid-email-format", Username: "integration_user", Password: testPassword}
 		bodyBytes, _ := json.Marshal(signupReqBody)
 
 		res, err := http.Post(server.URL+"/auth/signup", "application/json", bytes.NewReader(bodyBytes))
@@ -132,7 +132,7 @@ func TestAuthEndpoints(t *testing.T) {
 	})
 
 	t.Run("Signup Bad Request (Short Password)", func(t *testing.T) {
-		signupReqBody := models.SignupRequest{Email: "shortpass@example.com", Password: "short"}
+		signupReqBody := models.SignupRequest{Email: "shortpass@example.com", Username: "integration_user", Password: "short"}
 		bodyBytes, _ := json.Marshal(signupReqBody)
 
 		res, err := http.Post(server.URL+"/auth/signup", "application/json", bytes.NewReader(bodyBytes))
@@ -155,7 +155,7 @@ func TestAuthEndpoints(t *testing.T) {
 		var resBody models.LoginResponse
 		err = json.NewDecoder(res.Body).Decode(&resBody)
 		assert.NoError(err, "Failed to decode login response body")
-		assert.Equal("Login successful", resBody.Message)
+		assert.Equal("Logged in successfully", resBody.Message)
 		assert.NotEmpty(resBody.Token, "Token should not be empty on successful login")
 
 		// Optional: Validate the token structure/claims (basic)
@@ -163,7 +163,7 @@ func TestAuthEndpoints(t *testing.T) {
 		// Using the known test secret from testCfg
 		userID, err := auth.ValidateJWT(resBody.Token, "test_secret_key_for_integration_tests_1234567890")
 		assert.NoError(err, "Returned token should be valid")
-		assert.True(userID == "", "UserID from token should be positive")
+		assert.NotEmpty(userID, "UserID should be set in the token")
 	})
 
 	t.Run("Login Unauthorized (Wrong Password)", func(t *testing.T) {
@@ -187,4 +187,24 @@ func TestAuthEndpoints(t *testing.T) {
 		// *** CHANGED: Expect 404 based on current ErrorHandler logic ***
 		assert.Equal(http.StatusNotFound, res.StatusCode, "Expected status 404 Not Found for non-existent user")
 	})
+
+	t.Run("Login Upgrades Outdated Hash", func(t *testing.T) {
+		// A hash made at a lower bcrypt cost than the configured one
+		oldHash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+		assert.NoError(err)
+		_, err = db.CreateUser(context.Background(), "legacy-user", "legacy_user", "legacy@example.com", string(oldHash))
+		assert.NoError(err)
+
+		bodyBytes, _ := json.Marshal(models.LoginRequest{Email: "legacy@example.com", Password: testPassword})
+		res, err := http.Post(server.URL+"/auth/login", "application/json", bytes.NewReader(bodyBytes))
+		assert.NoError(err)
+		defer res.Body.Close()
+		assert.Equal(http.StatusOK, res.StatusCode, "Expected status 200 OK")
+
+		user, err := db.FindUserByEmail(context.Background(), "legacy@example.com")
+		assert.NoError(err)
+		assert.NotEqual(string(oldHash), user.PasswordHash, "Outdated hash should be replaced")
+		assert.False(auth.NeedsRehash(user.PasswordHash), "Stored hash should use the current settings")
+		assert.True(auth.CheckPasswordHash(testPassword, user.PasswordHash), "Upgraded hash should verify")
+	})
 }
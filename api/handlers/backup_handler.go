@@ -0,0 +1,170 @@
+// api/handlers/backup_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/replication"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// maxRestoreUploadSize caps the size of a .db file uploaded for restore.
+const maxRestoreUploadSize = 512 << 20 // 512 MiB
+
+// BackupHandler holds dependencies for backup and restore handlers.
+type BackupHandler struct {
+	MetaDB     storage.MetadataStore   // Metadata DB pool
+	Cfg        *config.Config          // App configuration (backup location)
+	Replicator *replication.Replicator // Off-site shipping, nil when disabled
+}
+
+// NewBackupHandler creates a new BackupHandler.
+func NewBackupHandler(metaDB storage.MetadataStore, cfg *config.Config) *BackupHandler {
+	return &BackupHandler{
+		MetaDB:     metaDB,
+		Cfg:        cfg,
+		Replicator: replication.NewReplicator(cfg),
+	}
+}
+
+// CreateBackup snapshots a user database into the backup directory.
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userDB, err := storage.ConnectUserDB(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer storage.ReleaseUserDB(userDB)
+
+	backupDir := storage.BackupDirectory(h.Cfg.BackupDir, target.UserID, target.Name)
+	backup, err := storage.CreateBackup(c.Request.Context(), userDB, backupDir, target.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Created backup %s for DB '%s', UserID %s", backup.BackupID, target.Name, target.UserID)
+
+	// Ship the backup off-site in the background; failures are logged by the replicator
+	if h.Replicator != nil {
+		backupPath := filepath.Join(backupDir, backup.BackupID+".db")
+		go func() {
+			_ = h.Replicator.ShipBackup(context.Background(), target.UserID, target.Name, backup.BackupID, backupPath)
+		}()
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Backup created successfully", "backup": backup})
+}
+
+// ListBackups lists stored backups for a user database.
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	backupDir := storage.BackupDirectory(h.Cfg.BackupDir, target.UserID, target.Name)
+	backups, err := storage.ListBackups(backupDir, target.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// RestoreDatabase replaces a user database with a stored backup (JSON body with
+// backup_id) or with an uploaded .db file (multipart field "file").
+func (h *BackupHandler) RestoreDatabase(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var src io.ReadCloser
+	var source string
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreUploadSize)
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			_ = c.Error(fmt.Errorf("restore upload error: %w", err))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				abortWithError(c, http.StatusRequestEntityTooLarge, "Uploaded file is too large.")
+				return
+			}
+			abortWithError(c, http.StatusBadRequest, "Multipart body must contain a 'file' field with the .db file.")
+			return
+		}
+		src, err = fileHeader.Open()
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, "Failed to read uploaded file.")
+			return
+		}
+		source = "upload"
+	} else {
+		var req models.RestoreDatabaseRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			_ = c.Error(fmt.Errorf("binding error: %w", err))
+			abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+
+		backupDir := storage.BackupDirectory(h.Cfg.BackupDir, target.UserID, target.Name)
+		backupPath, err := storage.FindBackupPath(backupDir, req.BackupID)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		src, err = os.Open(backupPath) // #nosec G304 -- path resolved by storage from a validated backup ID
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		source = req.BackupID
+	}
+	defer src.Close()
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Restoring DB '%s' for UserID %s from %s", target.Name, target.UserID, source)
+	if err := storage.RestoreDatabase(c.Request.Context(), src, target.FilePath); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully restored DB '%s' for UserID %s", target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Database restored successfully",
+		"db_name": target.Name,
+		"source":  source,
+	})
+}
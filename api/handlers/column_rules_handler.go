@@ -0,0 +1,124 @@
+// api/handlers/column_rules_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetColumnRules returns the validation rules of a table.
+func (h *TableHandler) GetColumnRules(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	rules, err := h.MetaDB.ListColumnRules(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "rules": rules})
+}
+
+// SetColumnRules replaces the validation rules of a table. Rules must name existing
+// columns; they are enforced by CreateRecord and UpdateRecord.
+func (h *TableHandler) SetColumnRules(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.SetColumnRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		}
+		return
+	}
+	if err := core.ValidateColumnRules(req.Rules, columnTypes); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.MetaDB.ReplaceColumnRules(c.Request.Context(), target.ID, tableName, req.Rules); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set %d column rule(s) on table '%s' in DB '%s' for UserID %s", len(req.Rules), tableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "rules": req.Rules})
+}
+
+// enforceColumnRules rejects record with 400 validation_failed, listing the failed
+// fields, when it breaks a column rule of the table addressed by the request.
+func (h *RecordHandler) enforceColumnRules(c *gin.Context, tableName string, record map[string]any) bool {
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	err = recordwrite.CheckColumnRules(c.Request.Context(), h.MetaDB, databaseID, tableName, record)
+	if err == nil {
+		return true
+	}
+	var invalid *recordwrite.ValidationError
+	if !errors.As(err, &invalid) {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load validation rules.")
+		return false
+	}
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Record for table '%s' failed %d column rule(s)", tableName, len(invalid.Fields))
+	abortValidationFailed(c, invalid)
+	return false
+}
+
+// abortValidationFailed rejects the request with 400 validation_failed, listing the
+// fields of invalid.
+func abortValidationFailed(c *gin.Context, invalid *recordwrite.ValidationError) {
+	_ = c.Error(&models.APIError{
+		Status:  http.StatusBadRequest,
+		Code:    models.ErrCodeValidationFailed,
+		Message: invalid.Message,
+		Details: invalid.Fields,
+	})
+	c.Abort()
+}
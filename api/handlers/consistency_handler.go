@@ -0,0 +1,55 @@
+// api/handlers/consistency_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// CheckConsistency cross-checks every database registration against its file and
+// returns the issues found. It repairs what it can and quarantines databases that stay
+// broken when asked to.
+func (h *AdminHandler) CheckConsistency(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	var req models.ConsistencyCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+
+	report, err := storage.CheckConsistency(c.Request.Context(), h.MetaDB, storage.ConsistencyOptions{Repair: req.Repair, Quarantine: req.Quarantine})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Consistency check by UserID %s: %d database(s), %d issue(s), %d repaired, %d quarantined",
+		userId, report.Checked, len(report.Issues), report.Repaired, report.Quarantined)
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// ReleaseQuarantine lets requests reach a quarantined database again, e.g. after its
+// file was restored.
+func (h *AdminHandler) ReleaseQuarantine(c *gin.Context) {
+	databaseId, ok := databaseIDParam(c)
+	if !ok {
+		return
+	}
+	if err := h.MetaDB.SetDatabaseQuarantine(c.Request.Context(), databaseId, ""); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Quarantine of DBID %d released by UserID %s", databaseId, userId)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+// api/handlers/data_root_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// defaultRebalanceMoves caps the databases one rebalance moves unless told otherwise.
+const defaultRebalanceMoves = 20
+
+// ListDataRoots returns the data roots user database files are placed on, with their
+// free space and the databases on each.
+func (h *AdminHandler) ListDataRoots(c *gin.Context) {
+	databases, err := h.MetaDB.ListAllDatabases(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"placement": h.Cfg.DataPlacement, "data_roots": storage.DataRootUsages(databases)})
+}
+
+// RebalanceDataRoots moves databases from fuller data roots to emptier ones in a
+// background job. A dry run returns the planned moves right away instead.
+func (h *AdminHandler) RebalanceDataRoots(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	var req models.RebalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'max_moves' must be between 0 and 1000.")
+		return
+	}
+	if req.MaxMoves == 0 {
+		req.MaxMoves = defaultRebalanceMoves
+	}
+
+	if req.DryRun {
+		result, err := storage.Rebalance(c.Request.Context(), h.MetaDB, req.MaxMoves, true)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": result})
+		return
+	}
+
+	userId, _ := requestctx.UserID(c)
+	job := h.Jobs.Submit(userId, "rebalance", func(ctx context.Context) (any, error) {
+		return storage.Rebalance(ctx, h.MetaDB, req.MaxMoves, false)
+	})
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Rebalance of data roots (up to %d moves) queued as job %s by UserID %s", req.MaxMoves, job.ID, userId)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Rebalance job queued", "job": job})
+}
+
+// RelocateUserDatabases moves the databases of an account, or one of them, to another
+// data root in a background job. They stay readable while they move.
+func (h *AdminHandler) RelocateUserDatabases(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	var req models.RelocateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'data_root' is required.")
+		return
+	}
+	if !storage.IsDataRoot(req.DataRoot) {
+		_ = c.Error(fmt.Errorf("%w: '%s'", storage.ErrUnknownDataRoot, req.DataRoot))
+		abortWithError(c, http.StatusBadRequest, "'data_root' must be one of the configured DATA_ROOTS.")
+		return
+	}
+	ownerId := c.Param("user_id")
+	if _, err := h.MetaDB.FindUserByUserId(c.Request.Context(), ownerId); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userId, _ := requestctx.UserID(c)
+	job := h.Jobs.Submit(userId, "relocate", func(ctx context.Context) (any, error) {
+		relocations, err := storage.RelocateUserDatabases(ctx, h.MetaDB, ownerId, req.DBName, req.DataRoot)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"relocations": relocations}, nil
+	})
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Relocation of databases of UserID %s to '%s' queued as job %s by UserID %s", ownerId, req.DataRoot, job.ID, userId)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Relocation job queued", "job": job})
+}
+
+// CollectOrphanedFiles removes database files on the data roots that no database is
+// registered at and that are older than the grace period. A dry run only lists them.
+func (h *AdminHandler) CollectOrphanedFiles(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	var req models.CollectOrphansRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body.")
+		return
+	}
+
+	result, err := storage.CollectOrphanedFiles(c.Request.Context(), h.MetaDB, h.Cfg.OrphanFileGracePeriod, req.DryRun)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !req.DryRun {
+		userId, _ := requestctx.UserID(c)
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Orphaned file collection by UserID %s found %d file(s)", userId, len(result.Orphaned))
+	}
+	c.JSON(http.StatusOK, gin.H{"result": result})
+}
@@ -2,17 +2,19 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
 	"github.com/Annany2002/nebula-backend/internal/core"    // For validation
 	"github.com/Annany2002/nebula-backend/internal/storage" // For DB operations
@@ -20,13 +22,13 @@ import (
 
 // DatabaseHandler holds dependencies for DB/Schema management handlers.
 type DatabaseHandler struct {
-	MetaDB *sql.DB        // Metadata DB pool
-	Cfg    *config.Config // App configuration
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
 	// UserRepo *storage.UserDBRepo // Could inject repo struct later
 }
 
 // NewDatabaseHandler creates a new DatabaseHandler.
-func NewDatabaseHandler(metaDB *sql.DB, cfg *config.Config) *DatabaseHandler {
+func NewDatabaseHandler(metaDB storage.MetadataStore, cfg *config.Config) *DatabaseHandler {
 	return &DatabaseHandler{
 		MetaDB: metaDB,
 		Cfg:    cfg,
@@ -35,152 +37,323 @@ func NewDatabaseHandler(metaDB *sql.DB, cfg *config.Config) *DatabaseHandler {
 
 // CreateDatabase handles requests to register a new user database.
 func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
-	userId := c.MustGet("userId").(string) // From CombinedAuthMiddleware
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
 	var req models.CreateDatabaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		_ = c.Error(fmt.Errorf("binding error: %w", err))                                                    // Use c.Error
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()}) // Temporary direct response
+		_ = c.Error(fmt.Errorf("binding error: %w", err)) // Use c.Error
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	if !core.IsValidIdentifier(req.DBName) {
 		_ = c.Error(errors.New("invalid database name format"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid database name. Use only alphanumeric characters and underscores (a-z, A-Z, 0-9, _), max length 64."})
+		abortWithError(c, http.StatusBadRequest, "Invalid database name. Use only alphanumeric characters and underscores (a-z, A-Z, 0-9, _), max length 64.")
+		return
+	}
+	if err := core.ValidateLabels(req.Labels); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	var expiresAt time.Time
+	if req.Ephemeral || req.TTLHours > 0 {
+		ttl := h.Cfg.EphemeralDatabaseTTL
+		if req.TTLHours > 0 {
+			ttl = time.Duration(req.TTLHours) * time.Hour
+		}
+		if ttl > h.Cfg.EphemeralDatabaseMaxTTL {
+			_ = c.Error(errors.New("ephemeral database lifetime too long"))
+			abortWithError(c, http.StatusBadRequest, fmt.Sprintf("ttl_hours may be at most %d.", int(h.Cfg.EphemeralDatabaseMaxTTL.Hours())))
+			return
+		}
+		expiresAt = time.Now().Add(ttl)
+	}
 
-	// Construct file path
-	userDbDir := filepath.Join(h.Cfg.MetadataDbDir, userId)
-	dbFilePath := filepath.Join(userDbDir, req.DBName+".db")
+	// Construct storage location (file path on one of the data roots, or tenant schema)
+	dataRoot, placement := storage.PlaceUserData(h.Cfg.MetadataDbDir, userId)
+	dbFilePath := storage.UserDataLocation(dataRoot, userId, req.DBName)
 
-	// Ensure user directory exists (moved from handler to make it more reusable?)
-	// Or keep it here as it's tied to the registration action. Let's keep it here.
-	if err := os.MkdirAll(userDbDir, 0o750); err != nil {
-		customLog.Warnf("Create DB: Error creating user DB directory '%s': %v", userDbDir, err)
+	// Ensure the user directory (or schema) exists before registering
+	if err := storage.PrepareUserData(c.Request.Context(), dbFilePath); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Create DB: Error preparing storage '%s': %v", dbFilePath, err)
 		_ = c.Error(fmt.Errorf("storage setup error: %w", err))
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create database storage location"})
+		abortWithError(c, http.StatusInternalServerError, "Failed to create database storage location")
 		return
 	}
 
 	// Register in metadata DB using storage function
-	err := storage.RegisterDatabase(c.Request.Context(), h.MetaDB, userId, req.DBName, dbFilePath)
+	err = h.MetaDB.RegisterDatabase(c.Request.Context(), userId, req.DBName, dbFilePath)
 	if err != nil {
 		_ = c.Error(err) // Pass storage error to context
 		if errors.Is(err, storage.ErrDatabaseExists) {
-			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A database with this name already exists."})
+			abortWithError(c, http.StatusConflict, "A database with this name already exists.")
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to register database."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to register database.")
 		}
 		return
 	}
-
-	customLog.Printf("Handler: Successfully registered database '%s' for UserID %s", req.DBName, userId)
-	c.JSON(http.StatusCreated, gin.H{
+	if placement != "" {
+		// Without it the root is worked out from the path, so a failure only gets logged
+		databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, req.DBName)
+		if err == nil {
+			err = h.MetaDB.SetDatabaseLocation(c.Request.Context(), databaseID, dbFilePath, dataRoot, placement)
+		}
+		if err != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Create DB: Failed to record placement of '%s' on '%s': %v", req.DBName, dataRoot, err)
+		}
+	}
+	if len(req.Labels) > 0 {
+		databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, req.DBName)
+		if err == nil {
+			err = h.MetaDB.ReplaceDatabaseLabels(c.Request.Context(), databaseID, req.Labels)
+		}
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, fmt.Sprintf("Database '%s' was registered, but storing its labels failed.", req.DBName))
+			return
+		}
+	}
+	if req.Labels == nil {
+		req.Labels = make(map[string]string)
+	}
+	response := gin.H{
 		"message": "Database registered successfully",
 		"db_name": req.DBName,
-	})
+		"labels":  req.Labels,
+	}
+	if !expiresAt.IsZero() {
+		databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, req.DBName)
+		if err == nil {
+			err = h.MetaDB.SetDatabaseExpiry(c.Request.Context(), databaseID, expiresAt)
+		}
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, fmt.Sprintf("Database '%s' was registered, but storing its expiry failed; it is not ephemeral.", req.DBName))
+			return
+		}
+		response["expires_at"] = expiresAt.UTC().Truncate(time.Second)
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully registered database '%s' for UserID %s", req.DBName, userId)
+	c.JSON(http.StatusCreated, response)
 }
 
-// ListDatabases handles requests to list registered databases for the user.
+// ListDatabases handles requests to list registered databases for the user, a page at
+// a time (?limit, ?offset). ?search keeps databases whose name contains the term, and
+// each ?label=key or ?label=key:value those carrying that label.
 func (h *DatabaseHandler) ListDatabases(c *gin.Context) {
-	userId := c.MustGet("userId").(string) // From AuthMiddleware
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	userDb, err := storage.ListUserDatabases(c.Request.Context(), h.MetaDB, userId)
+	opts := storage.DatabaseListOptions{Search: c.Query("search")}
+	if opts.Limit, opts.Offset, err = core.ParsePagination(c.Request.URL.Query(), core.MaxLimit); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, raw := range c.QueryArray("label") {
+		selector, err := core.ParseLabelSelector(raw)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.Labels = append(opts.Labels, selector)
+	}
+
+	userDb, pagination, err := h.MetaDB.ListUserDatabases(c.Request.Context(), userId, opts)
 	if err != nil {
-		customLog.Warnf("Handler: Error listing databases for UserID %s: %v", userId, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Error listing databases for UserID %s: %v", userId, err)
 		_ = c.Error(err) // Attach storage error
 		// Let middleware handle response (likely 500)
 		return
 	}
 
-	customLog.Printf("Handler: Retrieved %d database(s) for UserID %s", len(userDb), userId)
-	c.JSON(http.StatusOK, gin.H{"databases": userDb})
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Retrieved %d database(s) (total: %d) for UserID %s", len(userDb), pagination.Total, userId)
+	if apiVersion(c) >= 2 {
+		c.JSON(http.StatusOK, pageEnvelope{Data: userDb, Pagination: newPageMeta(pagination)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"databases": userDb, "pagination": newPageMeta(pagination)})
 }
 
 // DeleteDatabase handles requests to delete a database registration and its file.
 func (h *DatabaseHandler) DeleteDatabase(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name")
 
 	if !core.IsValidIdentifier(dbName) {
 		err := errors.New("invalid database name in URL path")
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()}) // Temp direct response
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// 1. Find the file path *before* deleting the registration
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, userId, dbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
-		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve database information."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if !errors.Is(err, storage.ErrDatabaseQuarantined) {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve database information.")
+		}
+		return
+	}
+
+	// With a retention window, the database goes to the trash instead
+	if h.Cfg.TrashRetention > 0 {
+		if h.trashDatabase(c, userId, dbName, dbFilePath) {
+			c.Status(http.StatusNoContent)
 		}
 		return
 	}
 
 	// 2. Delete the registration entry from metadata.db
-	customLog.Printf("Handler: Attempting to delete registration for DB '%s', UserID %s", dbName, userId)
-	err = storage.DeleteDatabaseRegistration(c.Request.Context(), h.MetaDB, userId, dbName)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Attempting to delete registration for DB '%s', UserID %s", dbName, userId)
+	err = h.MetaDB.DeleteDatabaseRegistration(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		// ErrDatabaseNotFound here means it was already gone somehow, treat as success? Or specific conflict?
 		// Let's treat not found as success (idempotent), other errors as 500.
 		if !errors.Is(err, storage.ErrDatabaseNotFound) {
-			customLog.Warnf("Handler: Failed to delete DB registration for UserID %s, DB '%s': %v", userId, dbName, err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete database registration."})
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to delete DB registration for UserID %s, DB '%s': %v", userId, dbName, err)
+			abortWithError(c, http.StatusInternalServerError, "Failed to delete database registration.")
 			return
 		}
 		// If ErrDatabaseNotFound, log it but proceed to file deletion attempt anyway
-		customLog.Printf("Handler: DB registration for UserID %s, DB '%s' was already deleted or not found, proceeding to file check.", userId, dbName)
+		customLog.Ctx(c.Request.Context()).Printf("Handler: DB registration for UserID %s, DB '%s' was already deleted or not found, proceeding to file check.", userId, dbName)
 	}
 
-	// 3. Attempt to delete the associated database file
+	// 3. Attempt to delete the associated database file (or schema)
 	// This is best-effort. Log errors but return success if registration was deleted.
-	customLog.Printf("Handler: Attempting to delete database file: %s", dbFilePath)
-	err = os.Remove(dbFilePath)
+	// Missing storage is not an error (idempotency).
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Attempting to delete database file: %s", dbFilePath)
+	err = storage.DeleteUserData(c.Request.Context(), dbFilePath)
 	if err != nil {
 		// Log error but don't fail the request if registration was deleted
-		// Ignore "not found" errors for the file itself (idempotency)
-		if !errors.Is(err, os.ErrNotExist) {
-			customLog.Warnf("Handler: WARN - Failed to delete database file '%s' for UserID %s, DB '%s': %v", dbFilePath, userId, dbName, err)
-			// You could potentially schedule a retry or flag for cleanup later
-		} else {
-			customLog.Warnf("Handler: Database file '%s' already deleted or did not exist.", dbFilePath)
-		}
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: WARN - Failed to delete database file '%s' for UserID %s, DB '%s': %v", dbFilePath, userId, dbName, err)
+		// The orphan file collector picks the file up later
 	} else {
-		customLog.Printf("Handler: Successfully deleted database file '%s'", dbFilePath)
+		customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully deleted database file '%s'", dbFilePath)
 		// Optional: Try to remove the parent directory if empty, but adds complexity/risk
 		// userDbDir := filepath.Dir(dbFilePath)
 		// if entries, _ := os.ReadDir(userDbDir); len(entries) == 0 { os.Remove(userDbDir) }
 	}
 
-	customLog.Printf("Handler: Completed delete request for DB '%s', UserID %s", dbName, userId)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Completed delete request for DB '%s', UserID %s", dbName, userId)
 	c.Status(http.StatusNoContent) // Return 204 No Content on success
 }
 
+// CloneDatabase registers a new database and copies the schema, and optionally the
+// data, of an existing database into it.
+func (h *DatabaseHandler) CloneDatabase(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	source, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CloneDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !core.IsValidIdentifier(req.TargetDBName) {
+		_ = c.Error(errors.New("invalid target database name format"))
+		abortWithError(c, http.StatusBadRequest, "Invalid target database name. Use only alphanumeric characters and underscores (a-z, A-Z, 0-9, _), max length 64.")
+		return
+	}
+	includeData := req.IncludeData == nil || *req.IncludeData
+
+	// Clones stay on the data root of their source
+	userDbDir := filepath.Dir(source.FilePath)
+	dstFilePath := filepath.Join(userDbDir, req.TargetDBName+".db")
+	if err := os.MkdirAll(userDbDir, 0o750); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Clone DB: Error creating user DB directory '%s': %v", userDbDir, err)
+		_ = c.Error(fmt.Errorf("storage setup error: %w", err))
+		abortWithError(c, http.StatusInternalServerError, "Failed to create database storage location")
+		return
+	}
+
+	// Register first so a concurrent create of the same name fails cleanly
+	if err := h.MetaDB.RegisterDatabase(c.Request.Context(), source.UserID, req.TargetDBName, dstFilePath); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseExists) {
+			abortWithError(c, http.StatusConflict, "A database with this name already exists.")
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to register database.")
+		}
+		return
+	}
+
+	srcDB, err := storage.ConnectUserDB(c.Request.Context(), source.FilePath)
+	if err == nil {
+		err = storage.CloneDatabase(c.Request.Context(), srcDB, dstFilePath, includeData)
+		storage.ReleaseUserDB(srcDB)
+	}
+	if err != nil {
+		// Roll back the registration and any partially written file, even when the
+		// clone failed because the request was cancelled
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Clone of DB '%s' into '%s' failed, rolling back: %v", source.Name, req.TargetDBName, err)
+		if delErr := h.MetaDB.DeleteDatabaseRegistration(context.WithoutCancel(c.Request.Context()), source.UserID, req.TargetDBName); delErr != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to roll back registration of '%s': %v", req.TargetDBName, delErr)
+		}
+		storage.InvalidateUserDB(dstFilePath)
+		_ = os.Remove(dstFilePath)
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to clone database.")
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Cloned DB '%s' into '%s' (data: %v) for UserID %s", source.Name, req.TargetDBName, includeData, source.UserID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Database cloned successfully",
+		"db_name":      req.TargetDBName,
+		"source":       source.Name,
+		"include_data": includeData,
+	})
+}
+
 // CreateSchema handles requests to define a table schema.
 func (h *DatabaseHandler) CreateSchema(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name")
 
 	if !core.IsValidIdentifier(dbName) {
 		_ = c.Error(errors.New("invalid db_name in path"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid database name in URL path."})
+		abortWithError(c, http.StatusBadRequest, "Invalid database name in URL path.")
 		return
 	}
 
 	// Look up path via storage function
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, userId, dbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
-		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve database information."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if !errors.Is(err, storage.ErrDatabaseQuarantined) {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve database information.")
 		}
 		return
 	}
@@ -188,13 +361,13 @@ func (h *DatabaseHandler) CreateSchema(c *gin.Context) {
 	var req models.CreateSchemaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		_ = c.Error(fmt.Errorf("binding error: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	if !core.IsValidIdentifier(req.TableName) {
 		_ = c.Error(errors.New("invalid table name format"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid table name format."})
+		abortWithError(c, http.StatusBadRequest, "Invalid table name format.")
 		return
 	}
 
@@ -206,63 +379,66 @@ func (h *DatabaseHandler) CreateSchema(c *gin.Context) {
 
 	if len(columns) == 0 {
 		_ = c.Error(errors.New("no columns provided"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No columns provided in 'columns' or 'schema' field."})
+		abortWithError(c, http.StatusBadRequest, "No columns provided in 'columns' or 'schema' field.")
 		return
 	}
 
-	var columnDefs []string
-	columnNames := make(map[string]bool) // Check for duplicate column names
-
-	for _, col := range columns {
-		colNameLower := strings.ToLower(col.Name)
-		if !core.IsValidIdentifier(col.Name) || colNameLower == "id" {
-			_ = c.Error(fmt.Errorf("invalid column name: %s", col.Name))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid column name '%s'. Use valid identifiers, cannot be 'id'.", col.Name)})
-			return
-		}
-		if columnNames[colNameLower] {
-			_ = c.Error(fmt.Errorf("duplicate column name: %s", col.Name))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Duplicate column name '%s'.", col.Name)})
-			return
-		}
-		columnNames[colNameLower] = true
-
-		normalizedType, ok := core.NormalizeAndValidateType(col.Type)
-		if !ok {
-			_ = c.Error(fmt.Errorf("invalid column type: %s", col.Type))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid type '%s' for column '%s'.", col.Type, col.Name)})
+	specs := make([]core.ColumnSpec, len(columns))
+	for i, col := range columns {
+		specs[i] = core.ColumnSpec{Name: col.Name, Type: col.Type, Default: col.Default, Collate: col.Collate}
+	}
+	specs, err = core.ValidateTableDefinition(req.TableName, specs)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Strict {
+		if err := core.ValidateStrictColumns(specs); err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", col.Name, normalizedType)) // Use original name case
+	}
+	description, err := requestedTableDescription(&req, specs)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	// Connect to the user DB using storage function
-	userDB, err := storage.ConnectUserDB(c.Request.Context(), dbFilePath)
+	userDB, err := storage.OpenUserData(c.Request.Context(), dbFilePath)
 	if err != nil {
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
-	// Construct CREATE TABLE SQL
-	// Use validated table name and column definitions
-	createTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s , created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);",
-		req.TableName, // Already validated
-		strings.Join(columnDefs, ", "),
-	)
-	customLog.Printf("Handler: Executing Schema SQL for UserID %s, DB '%s': %s", userId, dbName, createTableSQL)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Creating table '%s' for UserID %s, DB '%s'", req.TableName, userId, dbName)
 
 	// Execute via storage function
-	err = storage.CreateTable(c.Request.Context(), userDB, createTableSQL)
+	if req.Strict {
+		err = userDB.CreateStrictTable(c.Request.Context(), req.TableName, specs)
+	} else {
+		err = userDB.CreateTable(c.Request.Context(), req.TableName, specs)
+	}
 	if err != nil {
 		_ = c.Error(err)
 		// Could inspect err further if CreateTable returned more specific errors
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create table."})
+		if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+			return
+		}
+		abortWithError(c, http.StatusInternalServerError, "Failed to create table.")
+		return
+	}
+	if !storeCreatedTableDescription(c, h.MetaDB, userId, dbName, req.TableName, description) {
 		return
 	}
 
-	customLog.Printf("Handler: Successfully ensured table '%s' in DB '%s' for UserID %s", req.TableName, dbName, userId)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully ensured table '%s' in DB '%s' for UserID %s", req.TableName, dbName, userId)
 	c.JSON(http.StatusCreated, gin.H{
 		"message":    fmt.Sprintf("Table '%s' created or already exists.", req.TableName),
 		"db_name":    dbName,
@@ -272,24 +448,31 @@ func (h *DatabaseHandler) CreateSchema(c *gin.Context) {
 
 // GetSchema returns the schema for a table
 func (h *DatabaseHandler) GetSchema(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name")
 	tableName := c.Param("table_name")
 
 	if !core.IsValidIdentifier(dbName) {
 		_ = c.Error(errors.New("invalid db_name in path"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid database name in URL path."})
+		abortWithError(c, http.StatusBadRequest, "Invalid database name in URL path.")
 		return
 	}
 
 	// Look up path via storage function
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, userId, dbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
-		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve database information."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if !errors.Is(err, storage.ErrDatabaseQuarantined) {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve database information.")
 		}
 		return
 	}
@@ -301,56 +484,80 @@ func (h *DatabaseHandler) GetSchema(c *gin.Context) {
 		return
 	}
 
-	defer userDB.Close()
+	defer storage.ReleaseUserDB(userDB)
 	tableSchema, err := storage.ListUserTableSchema(c.Request.Context(), userDB, tableName)
 
 	if err != nil {
-		c.AbortWithStatusJSON(404, gin.H{"error": fmt.Sprintf("Table %s within %s database not found", tableName, dbName)})
+		abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table %s within %s database not found", tableName, dbName))
 		return
 	}
 
-	c.JSON(200, gin.H{"schema": tableSchema})
+	databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	description, err := h.MetaDB.GetTableDescription(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	for i := range tableSchema {
+		tableSchema[i].Description = description.Columns[strings.ToLower(tableSchema[i].Name)]
+	}
+
+	c.JSON(200, gin.H{"schema": tableSchema, "description": description.Description})
 }
 
 // CreateAPIKey generates a new API key scoped to a specific database for the user.
 func (h *DatabaseHandler) CreateAPIKey(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name") // Get target DB name from path
 
 	// Validate dbName from URL param
 	if !core.IsValidIdentifier(dbName) {
 		err := errors.New("invalid database name in URL path")
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Find the database ID belonging to the user for the given dbName
-	databaseID, err := storage.FindDatabaseIDByNameAndUser(c.Request.Context(), h.MetaDB, userId, dbName)
+	databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
 			// Check if it's the user/db combo specifically
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Database '%s' not found for your account.", dbName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Database '%s' not found for your account.", dbName))
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify database ownership."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify database ownership.")
 		}
 		return
 	}
 
 	// Call storage function to generate and store the key
-	APIKey, err := storage.StoreAPIKey(c.Request.Context(), h.MetaDB, userId, databaseID)
+	APIKey, err := h.MetaDB.StoreAPIKey(c.Request.Context(), userId, databaseID)
 	if err != nil {
 		_ = c.Error(err)
 		// Handle specific errors from StoreAPIKey if needed (e.g., ErrConflict)
-		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%v", err)})
+		abortWithError(c, http.StatusConflict, fmt.Sprintf("%v", err))
 		return
 	}
 
-	customLog.Printf("Handler: Generated API key for UserID %s, DB '%s'", userId, dbName)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Generated API key for UserID %s, DB '%s'", userId, dbName)
+
+	keyId, err := h.MetaDB.FindAPIKeyID(c.Request.Context(), databaseID)
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to look up ID of new API key for DB '%s': %v", dbName, err)
+	}
 
 	// Return the generated key ONCE
 	c.JSON(http.StatusCreated, models.CreateAPIKeyResponse{
+		ID:      keyId,
 		APIKey:  APIKey,
 		Message: "API Key generated successfully. Store it securely - it will not be shown again.",
 	})
@@ -358,65 +565,78 @@ func (h *DatabaseHandler) CreateAPIKey(c *gin.Context) {
 
 // GetAPIKeys fetches all the API keys of the user
 func (h *DatabaseHandler) GetAPIKey(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name") // Get target DB name from path
 
 	// Validate dbName from URL param
 	if !core.IsValidIdentifier(dbName) {
 		err := errors.New("invalid database name in URL path")
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Find the database ID belonging to the user for the given dbName
-	databaseID, err := storage.FindDatabaseIDByNameAndUser(c.Request.Context(), h.MetaDB, userId, dbName)
+	databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
 			// Check if it's the user/db combo specifically
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Database '%s' not found for your account.", dbName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Database '%s' not found for your account.", dbName))
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify database ownership."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify database ownership.")
 		}
 		return
 	}
 
-	api_key, err := storage.FindAPIKeyByDatabaseId(c.Request.Context(), h.MetaDB, databaseID)
+	api_key, err := h.MetaDB.FindAPIKeyByDatabaseId(c.Request.Context(), databaseID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err})
+		_ = c.Error(err) // Mapped by the error handler
+		c.Abort()
 		return
 	}
 
-	c.JSON(200, gin.H{"key": api_key})
+	response := gin.H{"key": api_key}
+	if keyId, err := h.MetaDB.FindAPIKeyID(c.Request.Context(), databaseID); err == nil {
+		response["id"] = keyId
+	}
+	c.JSON(200, response)
 }
 
 func (h *DatabaseHandler) DeleteAPIKey(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name") // Get target DB name from path
 
 	// Validate dbName from URL param
 	if !core.IsValidIdentifier(dbName) {
 		err := errors.New("invalid database name in URL path")
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// Find the database ID belonging to the user for the given dbName
-	databaseId, err := storage.FindDatabaseIDByNameAndUser(c.Request.Context(), h.MetaDB, userId, dbName)
+	databaseId, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
 			// Check if it's the user/db combo specifically
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Database '%s' not found for your account.", dbName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Database '%s' not found for your account.", dbName))
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify database ownership."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to verify database ownership.")
 		}
 		return
 	}
 
-	key, err := storage.FindAPIKeyByDatabaseId(c.Request.Context(), h.MetaDB, databaseId)
+	key, err := h.MetaDB.FindAPIKeyByDatabaseId(c.Request.Context(), databaseId)
 
 	if err != nil {
 		c.AbortWithStatusJSON(401, gin.H{"message": err})
@@ -427,7 +647,7 @@ func (h *DatabaseHandler) DeleteAPIKey(c *gin.Context) {
 		return
 	}
 
-	err = storage.DeleteAPIKey(c.Request.Context(), h.MetaDB, key)
+	err = h.MetaDB.DeleteAPIKey(c.Request.Context(), key)
 	if err != nil {
 		c.AbortWithStatusJSON(400, err)
 		return
@@ -0,0 +1,59 @@
+// api/handlers/database_label_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+// GetDatabaseLabels returns the labels of a database.
+func (h *DatabaseHandler) GetDatabaseLabels(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	labels, err := h.MetaDB.GetDatabaseLabels(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"db_name": target.Name, "labels": labels})
+}
+
+// SetDatabaseLabels replaces the labels of a database; labels left out are removed.
+func (h *DatabaseHandler) SetDatabaseLabels(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.SetDatabaseLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := core.ValidateLabels(req.Labels); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Labels == nil {
+		req.Labels = make(map[string]string)
+	}
+
+	if err := h.MetaDB.ReplaceDatabaseLabels(c.Request.Context(), target.ID, req.Labels); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set %d label(s) on DB '%s' for UserID %s", len(req.Labels), target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{"db_name": target.Name, "labels": req.Labels})
+}
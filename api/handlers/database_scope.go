@@ -0,0 +1,65 @@
+// api/handlers/database_scope.go
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// targetDatabase identifies the user database addressed by the :db_name path parameter.
+type targetDatabase struct {
+	UserID   string
+	Name     string
+	ID       int64
+	FilePath string
+}
+
+// resolveTargetDatabase validates :db_name, confirms the caller owns it and, for
+// DB-scoped API keys, that the key was issued for this database.
+// Errors are suitable for c.Error and mapped by the ErrorHandler middleware.
+func resolveTargetDatabase(c *gin.Context, metaDB storage.MetadataStore) (*targetDatabase, error) {
+	authUserID, err := requestctx.UserID(c)
+	if err != nil {
+		return nil, err
+	}
+	authDatabaseID, scoped, err := requestctx.ScopedDatabaseID(c) // Not scoped for JWTs and user keys
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("ERROR: Invalid databaseID in context for UserID %s: %v", authUserID, err)
+		return nil, err
+	}
+	dbName := c.Param("db_name")
+
+	if !core.IsValidIdentifier(dbName) {
+		return nil, fmt.Errorf("%w: invalid database name in URL path", nebulaErrors.ErrBadRequest)
+	}
+	logger.SetDatabase(c.Request.Context(), dbName)
+
+	databaseID, err := metaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), authUserID, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	if scoped && authDatabaseID != databaseID {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: FORBIDDEN - User %s API key for DBID %d attempted access to DB '%s' (ID %d)", authUserID, authDatabaseID, dbName, databaseID)
+		return nil, fmt.Errorf("%w: API key not valid for database '%s'", nebulaErrors.ErrForbidden, dbName)
+	}
+
+	dbFilePath, err := metaDB.FindDatabasePath(c.Request.Context(), authUserID, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &targetDatabase{
+		UserID:   authUserID,
+		Name:     dbName,
+		ID:       databaseID,
+		FilePath: dbFilePath,
+	}, nil
+}
@@ -0,0 +1,135 @@
+// api/handlers/duplicate_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of duplicate detection.
+const (
+	maxDuplicateColumns   = 10
+	defaultDuplicateLimit = 20
+	maxDuplicateLimit     = 500
+)
+
+// FindDuplicates lists groups of records sharing the values of the given columns,
+// largest groups first. Records with NULL in any of the columns are not considered.
+// Query parameters: columns=<col>[,<col>...] (required), limit=<groups> (default 20, max 500).
+func (h *RecordHandler) FindDuplicates(c *gin.Context) {
+	var columns []string
+	for _, name := range strings.Split(c.Query("columns"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			columns = append(columns, name)
+		}
+	}
+	limit, ok := boundedQueryInt(c, "limit", defaultDuplicateLimit, maxDuplicateLimit)
+	if !ok {
+		return
+	}
+
+	userDB, tableName, ok := h.openDuplicateTarget(c, columns)
+	if !ok {
+		return
+	}
+	defer userDB.Release()
+
+	groups, err := userDB.FindDuplicates(c.Request.Context(), tableName, columns, limit)
+	if err != nil {
+		abortDuplicatesError(c, tableName, err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Found %d duplicate group(s) on %v in table '%s'", len(groups), columns, tableName)
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "columns": columns, "groups": groups})
+}
+
+// DedupeRecords deletes the records that duplicate another record's values in the
+// given columns, keeping the first (lowest id) or last record of each group.
+func (h *RecordHandler) DedupeRecords(c *gin.Context) {
+	var req models.DedupeRecordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userDB, tableName, ok := h.openDuplicateTarget(c, req.Columns)
+	if !ok {
+		return
+	}
+	defer userDB.Release()
+
+	keepLast := req.Keep == "last"
+	deleted, err := userDB.DeleteDuplicates(c.Request.Context(), tableName, req.Columns, keepLast)
+	if err != nil {
+		abortDuplicatesError(c, tableName, err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Removed %d duplicate record(s) on %v from table '%s'", len(deleted), req.Columns, tableName)
+	keep := "first"
+	if keepLast {
+		keep = "last"
+	}
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "columns": req.Columns, "keep": keep, "deleted": len(deleted)})
+}
+
+// openDuplicateTarget opens the table addressed by the request and checks that columns
+// name between one and maxDuplicateColumns of its columns. On failure the request is
+// aborted and ok is false; otherwise the caller must release the store.
+func (h *RecordHandler) openDuplicateTarget(c *gin.Context, columns []string) (storage.UserDataStore, string, bool) {
+	if len(columns) == 0 || len(columns) > maxDuplicateColumns {
+		_ = c.Error(fmt.Errorf("invalid duplicate column count %d", len(columns)))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Between 1 and %d columns are required.", maxDuplicateColumns))
+		return nil, "", false
+	}
+
+	userDB, tableName, _, err := h.getUserDBConn(c)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if strings.Contains(err.Error(), "invalid database or table name") {
+			abortWithError(c, http.StatusBadRequest, err.Error())
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		}
+		return nil, "", false
+	}
+
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		userDB.Release()
+		abortDuplicatesError(c, tableName, err)
+		return nil, "", false
+	}
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		_, exists := columnTypes[column]
+		if !core.IsValidIdentifier(column) || !exists || seen[column] {
+			userDB.Release()
+			_ = c.Error(fmt.Errorf("%w: '%s'", storage.ErrColumnNotFound, column))
+			abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid or repeated column '%s'.", column))
+			return nil, "", false
+		}
+		seen[column] = true
+	}
+	return userDB, tableName, true
+}
+
+// abortDuplicatesError maps errors from duplicate detection to a response.
+func abortDuplicatesError(c *gin.Context, tableName string, err error) {
+	_ = c.Error(err)
+	if errors.Is(err, storage.ErrTableNotFound) {
+		abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+	} else if errors.Is(err, storage.ErrDatabaseBusy) {
+		abortDatabaseBusy(c)
+	}
+}
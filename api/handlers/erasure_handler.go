@@ -0,0 +1,114 @@
+// api/handlers/erasure_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/erasure"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// ErasureHandler holds dependencies for the right-to-erasure handlers.
+type ErasureHandler struct {
+	MetaDB  storage.MetadataStore // Metadata DB pool
+	Cfg     *config.Config        // App configuration
+	Jobs    *jobs.Manager         // Background job runner erasing accounts
+	running sync.Map              // User IDs being erased
+}
+
+// NewErasureHandler creates a new ErasureHandler.
+func NewErasureHandler(metaDB storage.MetadataStore, cfg *config.Config, jobManager *jobs.Manager) *ErasureHandler {
+	return &ErasureHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+		Jobs:   jobManager,
+	}
+}
+
+// EraseCurrentUser erases the caller's account and all its data in a background job,
+// once the caller confirmed with their password.
+func (h *ErasureHandler) EraseCurrentUser(c *gin.Context) {
+	var req models.EraseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'password' is required.")
+		return
+	}
+	userId, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	user, err := h.MetaDB.FindUserByUserId(c.Request.Context(), userId)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !auth.CheckPasswordHash(req.Password, user.PasswordHash) {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Erasure of UserID %s refused: invalid password", userId)
+		_ = c.Error(storage.ErrInvalidCredentials)
+		return
+	}
+	h.startErasure(c, userId, userId)
+}
+
+// EraseUser erases any account and all its data in a background job.
+func (h *ErasureHandler) EraseUser(c *gin.Context) {
+	adminId, ok := requireUserID(c)
+	if !ok {
+		return
+	}
+	userId := c.Param("user_id")
+	if _, err := h.MetaDB.FindUserByUserId(c.Request.Context(), userId); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	h.startErasure(c, userId, adminId)
+}
+
+// startErasure queues the erasure of userId requested by requestedBy, refusing a second
+// one while it runs. The job result is the compliance report.
+func (h *ErasureHandler) startErasure(c *gin.Context, userId, requestedBy string) {
+	if _, busy := h.running.LoadOrStore(userId, struct{}{}); busy {
+		abortWithError(c, http.StatusConflict, "This account is already being erased.")
+		return
+	}
+	job := h.Jobs.Submit(requestedBy, "erasure", func(ctx context.Context) (any, error) {
+		defer h.running.Delete(userId)
+		report, err := erasure.Erase(ctx, h.MetaDB, h.Cfg, userId, requestedBy)
+		if err != nil {
+			return nil, err
+		}
+		return gin.H{"report": report}, nil
+	})
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Erasure of UserID %s queued as job %s by UserID %s", userId, job.ID, requestedBy)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Erasure job queued", "job": job})
+}
+
+// ListErasures returns the compliance reports of every erased account.
+func (h *ErasureHandler) ListErasures(c *gin.Context) {
+	reports, err := h.MetaDB.ListErasures(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"erasures": reports})
+}
+
+// GetErasure returns the compliance report of one erasure.
+func (h *ErasureHandler) GetErasure(c *gin.Context) {
+	report, err := h.MetaDB.FindErasure(c.Request.Context(), c.Param("erasure_id"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"erasure": report})
+}
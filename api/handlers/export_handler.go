@@ -0,0 +1,243 @@
+// api/handlers/export_handler.go
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/config"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/codegen"
+	"github.com/Annany2002/nebula-backend/internal/parquet"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+	"github.com/Annany2002/nebula-backend/internal/xlsx"
+)
+
+// ExportHandler holds dependencies for database export handlers.
+type ExportHandler struct {
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
+}
+
+// NewExportHandler creates a new ExportHandler.
+func NewExportHandler(metaDB storage.MetadataStore, cfg *config.Config) *ExportHandler {
+	return &ExportHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+	}
+}
+
+// ExportDatabase streams a full export of a user database in the requested format.
+// Supported formats: sql (default), parquet and xlsx. Parquet exports the table named
+// by the table query parameter, or every table as a zip archive of Parquet files; xlsx
+// writes a workbook with one sheet per exported table.
+func (h *ExportHandler) ExportDatabase(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "sql"))
+	if format != "sql" && format != "parquet" && format != "xlsx" {
+		_ = c.Error(fmt.Errorf("%w: unsupported export format '%s'", nebulaErrors.ErrBadRequest, format))
+		return
+	}
+
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userDB, err := storage.ConnectUserDB(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer storage.ReleaseUserDB(userDB)
+
+	switch format {
+	case "parquet":
+		h.exportParquet(c, target, userDB)
+		return
+	case "xlsx":
+		h.exportWorkbook(c, target, userDB)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Exporting DB '%s' as %s for UserID %s", target.Name, format, target.UserID)
+	c.Header("Content-Type", "application/sql; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.sql"`, target.Name))
+	c.Status(http.StatusOK)
+
+	// The status line is already sent, so a failure here can only be logged
+	if err := storage.DumpSQL(c.Request.Context(), userDB, c.Writer); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Export of DB '%s' aborted: %v", target.Name, err)
+	}
+}
+
+// exportParquet writes one table as a Parquet file, or all tables as a zip of them.
+func (h *ExportHandler) exportParquet(c *gin.Context, target *targetDatabase, userDB *sql.DB) {
+	tables, err := exportTableNames(c, userDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Exporting %d table(s) of DB '%s' as parquet for UserID %s", len(tables), target.Name, target.UserID)
+	if c.Query("table") != "" {
+		c.Header("Content-Type", parquet.ContentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.parquet"`, tables[0]))
+		c.Status(http.StatusOK)
+		if err := writeParquetTable(c.Request.Context(), userDB, tables[0], c.Writer); err != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Parquet export of table '%s' aborted: %v", tables[0], err)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-parquet.zip"`, target.Name))
+	c.Status(http.StatusOK)
+	archive := zip.NewWriter(c.Writer)
+	for _, table := range tables {
+		entry, err := archive.Create(table + ".parquet")
+		if err == nil {
+			err = writeParquetTable(c.Request.Context(), userDB, table, entry)
+		}
+		if err != nil {
+			// The archive is left without its central directory, so clients see it is broken
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Parquet export of DB '%s' aborted at table '%s': %v", target.Name, table, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Parquet export of DB '%s' aborted: %v", target.Name, err)
+	}
+}
+
+// exportWorkbook writes the exported tables as the sheets of an Excel workbook.
+func (h *ExportHandler) exportWorkbook(c *gin.Context, target *targetDatabase, userDB *sql.DB) {
+	tables, err := exportTableNames(c, userDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Exporting %d table(s) of DB '%s' as xlsx for UserID %s", len(tables), target.Name, target.UserID)
+	filename := target.Name
+	if c.Query("table") != "" {
+		filename = tables[0]
+	}
+	c.Header("Content-Type", xlsx.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, filename))
+	c.Status(http.StatusOK)
+
+	workbook := xlsx.NewWriter(c.Writer)
+	for _, table := range tables {
+		err := storage.ScanTableRows(c.Request.Context(), userDB, table, func(columns []storage.ExportColumn) error {
+			header := make([]string, len(columns))
+			for i, col := range columns {
+				header[i] = col.Name
+			}
+			return workbook.AddSheet(table, header)
+		}, workbook.WriteRow)
+		if err != nil {
+			// The workbook is left incomplete, so clients see it is broken
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Excel export of DB '%s' aborted at table '%s': %v", target.Name, table, err)
+			return
+		}
+	}
+	if err := workbook.Close(); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Excel export of DB '%s' aborted: %v", target.Name, err)
+	}
+}
+
+// exportTableNames returns the table named by the table query parameter, or every
+// table of the database when it is not set.
+func exportTableNames(c *gin.Context, userDB *sql.DB) ([]string, error) {
+	tables, err := storage.ListTables(c.Request.Context(), userDB)
+	if err != nil {
+		return nil, err
+	}
+	requested := c.Query("table")
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		if requested == "" || table.Name == requested {
+			names = append(names, table.Name)
+		}
+	}
+	if requested != "" && len(names) == 0 {
+		return nil, storage.ErrTableNotFound
+	}
+	return names, nil
+}
+
+func writeParquetTable(ctx context.Context, userDB *sql.DB, tableName string, w io.Writer) error {
+	var pw *parquet.Writer
+	err := storage.ScanTableRows(ctx, userDB, tableName, func(columns []storage.ExportColumn) error {
+		specs := make([]parquet.Column, len(columns))
+		for i, col := range columns {
+			specs[i] = parquet.Column{Name: col.Name, Type: col.Type}
+		}
+		var err error
+		pw, err = parquet.NewWriter(w, specs)
+		return err
+	}, func(row []any) error {
+		return pw.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// ExportModels generates client model types from the database's table schemas.
+// Query parameters: lang=go|typescript (default go), package=<Go package name>.
+func (h *ExportHandler) ExportModels(c *gin.Context) {
+	lang := strings.ToLower(c.DefaultQuery("lang", codegen.LangGo))
+	if lang == "ts" {
+		lang = codegen.LangTypeScript
+	}
+	if lang != codegen.LangGo && lang != codegen.LangTypeScript {
+		_ = c.Error(fmt.Errorf("%w: unsupported language '%s'", nebulaErrors.ErrBadRequest, lang))
+		return
+	}
+
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer userDB.Release()
+
+	tables, err := userDB.ListTables(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	source, err := codegen.Generate(lang, target.Name, c.Query("package"), tables)
+	if err != nil {
+		_ = c.Error(fmt.Errorf("%w: %v", nebulaErrors.ErrBadRequest, err))
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Generated %s models for %d table(s) of DB '%s' for UserID %s", lang, len(tables), target.Name, target.UserID)
+	filename, contentType := target.Name+".go", "text/x-go; charset=utf-8"
+	if lang == codegen.LangTypeScript {
+		filename, contentType = target.Name+".ts", "application/typescript; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Data(http.StatusOK, contentType, []byte(source))
+}
@@ -0,0 +1,451 @@
+// api/handlers/form_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/forms"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// maxFormsPerDatabase bounds the public endpoints a database can expose.
+const maxFormsPerDatabase = 20
+
+// maxFormBodyBytes bounds the body of a public form submission.
+const maxFormBodyBytes = 64 << 10
+
+// ListForms returns the forms of a database.
+func (h *DatabaseHandler) ListForms(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	list, err := h.MetaDB.ListForms(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"forms": list})
+}
+
+// CreateForm creates a public submission form for a table of a database.
+func (h *DatabaseHandler) CreateForm(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreateFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !core.IsValidIdentifier(req.Table) {
+		_ = c.Error(errors.New("invalid table name"))
+		abortWithError(c, http.StatusBadRequest, "Invalid table name.")
+		return
+	}
+	form := domain.Form{
+		ID:              uuid.New().String(),
+		DatabaseID:      target.ID,
+		Table:           req.Table,
+		Description:     req.Description,
+		Columns:         req.Columns,
+		AllowedOrigins:  req.AllowedOrigins,
+		CaptchaProvider: req.CaptchaProvider,
+		CaptchaSecret:   req.CaptchaSecret,
+		RedirectURL:     req.RedirectURL,
+		RateLimit:       forms.DefaultRateLimit,
+		Enabled:         req.Enabled == nil || *req.Enabled,
+	}
+	if req.RateLimit != nil {
+		form.RateLimit = *req.RateLimit
+	}
+	if !h.validateForm(c, target, &form) {
+		return
+	}
+
+	existing, err := h.MetaDB.ListForms(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if len(existing) >= maxFormsPerDatabase {
+		_ = c.Error(errors.New("form limit reached"))
+		abortWithError(c, http.StatusConflict, fmt.Sprintf("Database '%s' already has %d forms, the maximum.", target.Name, maxFormsPerDatabase))
+		return
+	}
+
+	if err := h.MetaDB.CreateForm(c.Request.Context(), form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	stored, err := h.MetaDB.GetForm(c.Request.Context(), target.ID, form.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Created form %s for table '%s' in DB '%s' for UserID %s", form.ID, form.Table, target.Name, target.UserID)
+	c.JSON(http.StatusCreated, stored)
+}
+
+// GetForm returns one form of a database.
+func (h *DatabaseHandler) GetForm(c *gin.Context) {
+	_, form, ok := h.loadForm(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, form)
+}
+
+// UpdateForm changes the settings of a form; fields left out are kept. The table of a
+// form cannot be changed.
+func (h *DatabaseHandler) UpdateForm(c *gin.Context) {
+	target, form, ok := h.loadForm(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFormRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Description != nil {
+		form.Description = *req.Description
+	}
+	if req.Columns != nil {
+		form.Columns = *req.Columns
+	}
+	if req.AllowedOrigins != nil {
+		form.AllowedOrigins = *req.AllowedOrigins
+	}
+	if req.CaptchaProvider != nil {
+		form.CaptchaProvider = *req.CaptchaProvider
+		if form.CaptchaProvider == "" {
+			form.CaptchaSecret = ""
+		}
+	}
+	if req.CaptchaSecret != nil {
+		form.CaptchaSecret = *req.CaptchaSecret
+	}
+	if req.RedirectURL != nil {
+		form.RedirectURL = *req.RedirectURL
+	}
+	if req.RateLimit != nil {
+		form.RateLimit = *req.RateLimit
+	}
+	if req.Enabled != nil {
+		form.Enabled = *req.Enabled
+	}
+	if !h.validateForm(c, target, form) {
+		return
+	}
+
+	if err := h.MetaDB.UpdateForm(c.Request.Context(), *form); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrFormNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Form '%s' not found.", form.ID))
+		}
+		return
+	}
+	stored, err := h.MetaDB.GetForm(c.Request.Context(), target.ID, form.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Updated form %s on DB '%s' (enabled: %t)", form.ID, target.Name, stored.Enabled)
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteForm removes a form; its URL stops accepting submissions.
+func (h *DatabaseHandler) DeleteForm(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	formId := c.Param("form_id")
+	if err := h.MetaDB.DeleteForm(c.Request.Context(), target.ID, formId); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrFormNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Form '%s' not found.", formId))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Deleted form %s on DB '%s' for UserID %s", formId, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// loadForm resolves the database and the form addressed by the request, aborting it
+// when either does not exist.
+func (h *DatabaseHandler) loadForm(c *gin.Context) (*targetDatabase, *domain.Form, bool) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, nil, false
+	}
+	formId := c.Param("form_id")
+	form, err := h.MetaDB.GetForm(c.Request.Context(), target.ID, formId)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrFormNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Form '%s' not found.", formId))
+		}
+		return nil, nil, false
+	}
+	return target, form, true
+}
+
+// validateForm checks the settings of form against its table, normalizing its columns
+// and origins. On failure the request is aborted with 400.
+func (h *DatabaseHandler) validateForm(c *gin.Context, target *targetDatabase, form *domain.Form) bool {
+	fail := func(message string) bool {
+		_ = c.Error(errors.New(message))
+		abortWithError(c, http.StatusBadRequest, message)
+		return false
+	}
+
+	if len(form.Columns) == 0 || len(form.Columns) > forms.MaxColumns {
+		return fail(fmt.Sprintf("A form must accept between 1 and %d columns.", forms.MaxColumns))
+	}
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return false
+	}
+	defer userDB.Release()
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), form.Table)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", form.Table))
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
+		}
+		return false
+	}
+	columns := make([]string, 0, len(form.Columns))
+	for _, column := range form.Columns {
+		column = strings.ToLower(column)
+		if _, exists := columnTypes[column]; !exists || column == "id" {
+			return fail(fmt.Sprintf("Column '%s' is not a writable column of table '%s'.", column, form.Table))
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	form.Columns = columns
+
+	if err := forms.ValidateOrigins(form.AllowedOrigins); err != nil {
+		return fail(err.Error())
+	}
+	form.AllowedOrigins = forms.NormalizeOrigins(form.AllowedOrigins)
+	if form.RedirectURL != "" {
+		if err := forms.ValidateRedirectURL(form.RedirectURL); err != nil {
+			return fail(err.Error())
+		}
+	}
+	if form.RateLimit < 1 || form.RateLimit > forms.MaxRateLimit {
+		return fail(fmt.Sprintf("rate_limit must be between 1 and %d submissions per hour.", forms.MaxRateLimit))
+	}
+	if form.CaptchaProvider != "" {
+		if !slices.Contains(forms.CaptchaProviders, form.CaptchaProvider) {
+			return fail(fmt.Sprintf("Unsupported captcha_provider '%s' (use %s).", form.CaptchaProvider, strings.Join(forms.CaptchaProviders, ", ")))
+		}
+		if form.CaptchaSecret == "" {
+			return fail("captcha_secret is required with a captcha_provider.")
+		}
+	}
+	return true
+}
+
+// SubmitFormPreflight answers the CORS preflight of a form submission from a browser.
+func (h *RecordHandler) SubmitFormPreflight(c *gin.Context) {
+	form, err := h.MetaDB.FindForm(c.Request.Context(), c.Param("form_id"))
+	if err != nil || !form.Enabled || !forms.OriginAllowed(form.AllowedOrigins, c.GetHeader("Origin")) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	setFormCORSHeaders(c)
+	c.Header("Access-Control-Allow-Methods", "POST, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Content-Type")
+	c.Header("Access-Control-Max-Age", "600")
+	c.Status(http.StatusNoContent)
+}
+
+// SubmitForm writes a public form submission into the form's table. It takes JSON or
+// HTML form posts (urlencoded or multipart, without files); only the form's columns
+// may be submitted, and the CAPTCHA response is verified when the form requires one.
+// HTML form posts are redirected to the form's redirect URL when it has one.
+func (h *RecordHandler) SubmitForm(c *gin.Context) {
+	formId := c.Param("form_id")
+	form, err := h.MetaDB.FindForm(c.Request.Context(), formId)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrFormNotFound) {
+			abortWithError(c, http.StatusNotFound, "Form not found.")
+		}
+		return
+	}
+	if !form.Enabled {
+		_ = c.Error(errors.New("form disabled"))
+		abortWithError(c, http.StatusForbidden, "This form is not accepting submissions.")
+		return
+	}
+	if !forms.OriginAllowed(form.AllowedOrigins, c.GetHeader("Origin")) {
+		_ = c.Error(fmt.Errorf("origin '%s' not allowed", c.GetHeader("Origin")))
+		abortWithError(c, http.StatusForbidden, "Submissions from this origin are not allowed.")
+		return
+	}
+	setFormCORSHeaders(c)
+	if !h.formLimiter.Allow(form.ID, c.ClientIP(), form.RateLimit) {
+		_ = c.Error(errors.New("form rate limit exceeded"))
+		abortWithError(c, http.StatusTooManyRequests, "Too many submissions. Please try again later.")
+		return
+	}
+
+	db, err := h.MetaDB.FindDatabaseByID(c.Request.Context(), form.DatabaseID)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Form not found.")
+		}
+		return
+	}
+	userDB, err := storage.OpenUserData(c.Request.Context(), db.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), form.Table)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, "Form not found.")
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
+		}
+		return
+	}
+
+	record, htmlPost, ok := bindFormSubmission(c, columnTypes)
+	if !ok {
+		return
+	}
+	var captchaToken string
+	for _, field := range forms.CaptchaFields {
+		if token, ok := record[field].(string); ok && captchaToken == "" {
+			captchaToken = token
+		}
+		delete(record, field)
+	}
+	if form.CaptchaProvider != "" {
+		if err := forms.VerifyCaptcha(c.Request.Context(), form.CaptchaProvider, form.CaptchaSecret, captchaToken, c.ClientIP()); err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, forms.ErrCaptchaFailed) {
+				abortWithError(c, http.StatusBadRequest, "CAPTCHA verification failed.")
+			} else {
+				abortWithError(c, http.StatusBadGateway, "Could not verify the CAPTCHA response, please retry.")
+			}
+			return
+		}
+	}
+	if len(record) == 0 {
+		_ = c.Error(errors.New("empty submission"))
+		abortWithError(c, http.StatusBadRequest, "Submission cannot be empty.")
+		return
+	}
+	for field := range record {
+		if !slices.Contains(form.Columns, strings.ToLower(field)) {
+			_ = c.Error(fmt.Errorf("field '%s' not accepted by form", field))
+			abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Field '%s' is not accepted by this form.", field))
+			return
+		}
+	}
+
+	// The submission is written as the database owner would write it, so table
+	// scripts, validation and change notifications apply unchanged
+	requestctx.SetUserID(c, db.UserID)
+	requestctx.SetTargetDatabaseID(c, db.DatabaseID)
+	c.Params = append(c.Params, gin.Param{Key: "db_name", Value: db.DBName}, gin.Param{Key: "table_name", Value: form.Table})
+	recordID, ok := h.insertRecord(c, userDB, form.Table, db.FilePath, columnTypes, record)
+	if !ok {
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Form %s accepted a submission as record ID %d", form.ID, recordID)
+
+	if htmlPost && form.RedirectURL != "" {
+		c.Redirect(http.StatusSeeOther, form.RedirectURL)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Submission received"})
+}
+
+// bindFormSubmission reads a submission from a JSON body or an HTML form post, and
+// reports which it was. On failure the request is aborted with 400.
+func bindFormSubmission(c *gin.Context, columnTypes map[string]string) (map[string]any, bool, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFormBodyBytes)
+	switch contentType := c.ContentType(); {
+	case contentType == "application/x-www-form-urlencoded" || strings.HasPrefix(contentType, "multipart/form-data"):
+		var err error
+		if contentType == "application/x-www-form-urlencoded" {
+			err = c.Request.ParseForm()
+		} else {
+			err = c.Request.ParseMultipartForm(maxFormBodyBytes)
+		}
+		if err == nil && c.Request.MultipartForm != nil && len(c.Request.MultipartForm.File) > 0 {
+			err = errors.New("file uploads are not supported")
+		}
+		if err != nil {
+			_ = c.Error(fmt.Errorf("binding error: %w", err))
+			abortWithError(c, http.StatusBadRequest, "Invalid form submission: "+err.Error())
+			return nil, false, false
+		}
+		record, err := core.FormRecord(c.Request.PostForm, columnTypes)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, "Invalid form submission: "+err.Error())
+			return nil, false, false
+		}
+		return record, true, true
+	default:
+		var record map[string]any
+		if err := c.ShouldBindJSON(&record); err != nil {
+			_ = c.Error(fmt.Errorf("binding error: %w", err))
+			abortWithError(c, http.StatusBadRequest, "Invalid JSON request body: "+err.Error())
+			return nil, false, false
+		}
+		return record, false, true
+	}
+}
+
+// setFormCORSHeaders lets the page that sent a form submission read the response.
+func setFormCORSHeaders(c *gin.Context) {
+	if origin := c.GetHeader("Origin"); origin != "" {
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Vary", "Origin")
+	}
+}
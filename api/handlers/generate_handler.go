@@ -0,0 +1,127 @@
+// api/handlers/generate_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/fakedata"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// defaultGenerateCount is the number of records generated when ?count is not given.
+const defaultGenerateCount = 100
+
+// GenerateRecords fills a table with ?count (default 100, at most 10000) fake records
+// chosen from the names and types of its columns (see fakedata.Generator), so frontend
+// developers can prototype against a populated table. ?seed makes the records
+// repeatable. Generated records pass the same checks as imports; table scripts are not
+// run.
+func (h *RecordHandler) GenerateRecords(c *gin.Context) {
+	count := defaultGenerateCount
+	if raw := c.Query("count"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxImportRows {
+			_ = c.Error(fmt.Errorf("invalid generate count '%s'", raw))
+			abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid count: must be between 1 and %d.", maxImportRows))
+			return
+		}
+		count = parsed
+	}
+	seed := time.Now().UnixNano()
+	if raw := c.Query("seed"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			_ = c.Error(fmt.Errorf("invalid generate seed '%s'", raw))
+			abortWithError(c, http.StatusBadRequest, "Invalid seed: must be an integer.")
+			return
+		}
+		seed = parsed
+	}
+
+	userDB, tableName, _, err := h.getUserDBConn(c)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if strings.Contains(err.Error(), "invalid database or table name") {
+			abortWithError(c, http.StatusBadRequest, err.Error())
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		}
+		return
+	}
+	defer userDB.Release()
+
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		_ = c.Error(err)
+		switch {
+		case errors.Is(err, storage.ErrTableNotFound):
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		case errors.Is(err, storage.ErrDatabaseBusy):
+			abortDatabaseBusy(c)
+		default:
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
+		}
+		return
+	}
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	rules, err := h.MetaDB.ListColumnRules(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load validation rules.")
+		return
+	}
+
+	generator := fakedata.New(seed)
+	records := make([]map[string]any, count)
+	for i := range records {
+		records[i] = generator.Record(columnTypes, rules)
+	}
+	// A JSON schema or a rule the generator cannot satisfy rejects the records here
+	if !h.validateImportRecords(c, tableName, columnTypes, records) {
+		return
+	}
+	if !h.enforceRowLimit(c, userDB, tableName, len(records)) {
+		return
+	}
+
+	generated := 0
+	for _, record := range records {
+		names, values, _ := core.RecordAssignments(columnTypes, record) // Checked by validateImportRecords
+		if _, err := userDB.InsertRecord(c.Request.Context(), tableName, names, values); err != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Generating records for table '%s' stopped after %d row(s): %v", tableName, generated, err)
+			_ = c.Error(err)
+			message := fmt.Sprintf("Failed to insert row %d; %d row(s) were generated before it.", generated+1, generated)
+			if errors.Is(err, storage.ErrConstraintViolation) {
+				abortWithError(c, http.StatusConflict, "Constraint violation: "+message)
+			} else if errors.Is(err, storage.ErrDatabaseBusy) {
+				abortDatabaseBusy(c)
+			} else {
+				abortWithError(c, http.StatusInternalServerError, message)
+			}
+			return
+		}
+		generated++
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Generated %d fake row(s) in table '%s'", generated, tableName)
+	c.JSON(http.StatusOK, gin.H{
+		"table_name": tableName,
+		"generated":  generated,
+		"seed":       seed,
+	})
+}
@@ -0,0 +1,173 @@
+// api/handlers/graphql_handler.go
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/config"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/graphql"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// maxGraphQLBodySize bounds the JSON body of a GraphQL request.
+const maxGraphQLBodySize = 1 << 20 // 1 MiB
+
+// GraphQLHandler serves a GraphQL API generated from the tables of a user database.
+type GraphQLHandler struct {
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler.
+func NewGraphQLHandler(metaDB storage.MetadataStore, cfg *config.Config) *GraphQLHandler {
+	return &GraphQLHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+	}
+}
+
+// graphQLRequestError responds with a request-level GraphQL error (no data).
+func graphQLRequestError(c *gin.Context, status int, err error) {
+	_ = c.Error(err)
+	c.AbortWithStatusJSON(status, graphql.Response{Errors: []graphql.Error{{Message: err.Error()}}})
+}
+
+// loadSchema resolves :db_name and introspects its tables. The caller must release userDB.
+func (h *GraphQLHandler) loadSchema(c *gin.Context) (*sql.DB, *graphql.Schema, *targetDatabase, bool) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, nil, nil, false
+	}
+
+	userDB, err := storage.ConnectUserDB(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, nil, nil, false
+	}
+
+	schema, err := graphql.LoadSchema(c.Request.Context(), userDB)
+	if err != nil {
+		storage.ReleaseUserDB(userDB)
+		_ = c.Error(err)
+		return nil, nil, nil, false
+	}
+	return userDB, schema, target, true
+}
+
+// loadRowLimits sets the row limit of every table of schema, checked by insert mutations.
+func (h *GraphQLHandler) loadRowLimits(c *gin.Context, target *targetDatabase, schema *graphql.Schema) bool {
+	for _, table := range schema.Tables {
+		options, err := h.MetaDB.GetTableOptions(c.Request.Context(), target.ID, table.Name)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, "Failed to load table options.")
+			return false
+		}
+		table.MaxRows, table.MaxRowsConfigured = storage.RowLimit(h.Cfg.TableMaxRows, options)
+	}
+	return true
+}
+
+// Execute runs a GraphQL query or mutation. POST takes a JSON body
+// {"query", "operationName", "variables"}; GET takes the same as query parameters
+// but only runs queries.
+func (h *GraphQLHandler) Execute(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	var req graphql.Request
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxGraphQLBodySize)
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if vars := c.Query("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				graphQLRequestError(c, http.StatusBadRequest, fmt.Errorf("invalid variables: %w", err))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			graphQLRequestError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: request bodies are limited to %d bytes", nebulaErrors.ErrBadRequest, tooLarge.Limit))
+			return
+		}
+		graphQLRequestError(c, http.StatusBadRequest, fmt.Errorf("%w: invalid JSON request body: %v", nebulaErrors.ErrBadRequest, err))
+		return
+	}
+	if req.Query == "" {
+		graphQLRequestError(c, http.StatusBadRequest, fmt.Errorf("%w: query is required", nebulaErrors.ErrBadRequest))
+		return
+	}
+
+	doc, err := graphql.Parse(req.Query)
+	if err != nil {
+		graphQLRequestError(c, http.StatusBadRequest, err)
+		return
+	}
+	op, err := doc.Operation(req.OperationName)
+	if err != nil {
+		graphQLRequestError(c, http.StatusBadRequest, err)
+		return
+	}
+	if op.Type == "mutation" && c.Request.Method == http.MethodGet {
+		c.Header("Allow", http.MethodPost)
+		graphQLRequestError(c, http.StatusMethodNotAllowed, fmt.Errorf("mutations must be sent with POST"))
+		return
+	}
+	if op.Type == "mutation" && !servicemode.AllowsWrites() {
+		if servicemode.Current().Mode == servicemode.Normal {
+			// Writes are off to protect the disk
+			graphQLRequestError(c, http.StatusInsufficientStorage, errors.New(servicemode.RejectionMessage()))
+			return
+		}
+		c.Header("Retry-After", "120")
+		graphQLRequestError(c, http.StatusServiceUnavailable, errors.New(servicemode.RejectionMessage()))
+		return
+	}
+
+	userDB, schema, target, ok := h.loadSchema(c)
+	if !ok {
+		return
+	}
+	defer storage.ReleaseUserDB(userDB)
+	if op.Type == "mutation" {
+		if !h.loadRowLimits(c, target, schema) {
+			return
+		}
+		schema.Writes = &graphql.Writes{Store: h.MetaDB, Cfg: h.Cfg, DatabaseID: target.ID, Location: target.FilePath}
+	}
+
+	resp, err := graphql.Execute(c.Request.Context(), userDB, schema, doc, op, req.Variables)
+	if err != nil {
+		graphQLRequestError(c, http.StatusBadRequest, err)
+		return
+	}
+	if len(resp.Errors) > 0 {
+		customLog.Ctx(c.Request.Context()).Printf("Handler: GraphQL %s on DB '%s' for UserID %s finished with %d error(s)", op.Type, target.Name, target.UserID, len(resp.Errors))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Schema returns the generated schema in GraphQL SDL.
+func (h *GraphQLHandler) Schema(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	userDB, schema, _, ok := h.loadSchema(c)
+	if !ok {
+		return
+	}
+	defer storage.ReleaseUserDB(userDB)
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(schema.SDL()))
+}
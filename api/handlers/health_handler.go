@@ -0,0 +1,93 @@
+// api/handlers/health_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/health"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+	"path/filepath"
+)
+
+// readinessTimeout bounds each dependency check so a hung disk or lock cannot stall
+// the probe past the orchestrator's own timeout.
+const readinessTimeout = 2 * time.Second
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(metaDB storage.MetadataStore, cfg *config.Config) *HealthHandler {
+	return &HealthHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+	}
+}
+
+// Livez reports that the process is up and serving requests. It checks no
+// dependencies, so a failing disk does not get the pod restarted in a loop.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz checks the metadata DB, writability and free space of the data directory and
+// data roots, the shared user data DB (Postgres backend only) and background workers,
+// responding 503 if any component fails.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	components := []health.Component{
+		check("metadata_db", h.MetaDB.Ping(ctx)),
+		check("data_directory", checkWritable(h.Cfg.MetadataDbDir)),
+	}
+	for _, root := range h.Cfg.DataRoots {
+		if root != filepath.Clean(h.Cfg.MetadataDbDir) {
+			components = append(components, check("data_root:"+root, checkWritable(root)))
+		}
+	}
+	if h.Cfg.DiskSpaceCheckInterval > 0 {
+		components = append(components, check("disk_space", storage.LowDiskSpaceError()))
+	}
+	if storage.UserDataBackend() == storage.BackendPostgres {
+		components = append(components, check("user_data_db", storage.PingUserData(ctx)))
+	}
+	components = append(components, health.Workers()...)
+
+	status, code := health.StatusOK, http.StatusOK
+	for _, component := range components {
+		if component.Status != health.StatusOK {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Readiness check '%s' failed: %s", component.Name, component.Message)
+		}
+	}
+	c.JSON(code, gin.H{"status": status, "components": components})
+}
+
+func check(name string, err error) health.Component {
+	if err != nil {
+		return health.Component{Name: name, Status: health.StatusFail, Message: err.Error()}
+	}
+	return health.Component{Name: name, Status: health.StatusOK}
+}
+
+// checkWritable creates and removes a temporary file in dir.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("not writable: %w", err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
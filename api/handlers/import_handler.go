@@ -0,0 +1,330 @@
+// api/handlers/import_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/jsonschema"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of data imports.
+const (
+	maxImportUploadSize = 32 << 20 // 32 MiB
+	maxImportRows       = 10000
+	importPreviewRows   = 5
+)
+
+// ImportRecords inserts the rows of an uploaded CSV or JSON document into a table. The
+// body is the document itself, or a multipart form with a "file" field; the format
+// comes from ?format=csv|json, else from the content type or file extension. When the
+// table does not exist and create=true is set, its columns are inferred from the data
+// and it is created first. With preview=true the inferred or matched columns and the
+// first converted records are returned without writing anything, so clients can
+// confirm the import. Rows are all validated before the first one is inserted; table
+// scripts are not run.
+func (h *RecordHandler) ImportRecords(c *gin.Context) {
+	create := c.Query("create") == "true"
+	preview := c.Query("preview") == "true"
+	data, ok := readImportData(c)
+	if !ok {
+		return
+	}
+
+	userDB, tableName, _, err := h.getUserDBConn(c)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if strings.Contains(err.Error(), "invalid database or table name") {
+			abortWithError(c, http.StatusBadRequest, err.Error())
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		}
+		return
+	}
+	defer userDB.Release()
+
+	var columns []core.ImportColumn
+	var specs []core.ColumnSpec
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	switch {
+	case err == nil:
+		if columns, err = matchImportColumns(data, columnTypes); err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	case errors.Is(err, storage.ErrTableNotFound) && create:
+		columns = core.InferImportColumns(data)
+		for _, col := range columns {
+			if col.Name != "" {
+				specs = append(specs, core.ColumnSpec{Name: col.Name, Type: col.Type})
+			}
+		}
+		if specs, err = core.ValidateTableDefinition(tableName, specs); err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, "Cannot create table from the data: "+err.Error())
+			return
+		}
+		columnTypes = make(map[string]string, len(specs))
+		for _, spec := range specs {
+			columnTypes[spec.Name] = spec.Type
+		}
+	case errors.Is(err, storage.ErrTableNotFound):
+		_ = c.Error(err)
+		abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found. Set create=true to create it from the data.", tableName))
+		return
+	default:
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
+		}
+		return
+	}
+
+	records, err := core.ImportRecords(data, columns)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.validateImportRecords(c, tableName, columnTypes, records) {
+		return
+	}
+
+	if preview {
+		sample := records
+		if len(sample) > importPreviewRows {
+			sample = sample[:importPreviewRows]
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"table_name": tableName,
+			"create":     specs != nil,
+			"columns":    columns,
+			"rows":       len(records),
+			"sample":     sample,
+		})
+		return
+	}
+
+	// Imports that would cross the row limit are rejected before anything is written
+	countFrom := userDB
+	if specs != nil {
+		countFrom = nil
+	}
+	if !h.enforceRowLimit(c, countFrom, tableName, len(records)) {
+		return
+	}
+
+	if specs != nil {
+		if err := userDB.CreateTable(c.Request.Context(), tableName, specs); err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrDatabaseBusy) {
+				abortDatabaseBusy(c)
+				return
+			}
+			abortWithError(c, http.StatusInternalServerError, "Failed to create table.")
+			return
+		}
+		customLog.Ctx(c.Request.Context()).Printf("Handler: Created table '%s' with %d inferred column(s) for import", tableName, len(specs))
+	}
+
+	imported := 0
+	for _, record := range records {
+		names, values, _ := core.RecordAssignments(columnTypes, record) // Checked by validateImportRecords
+		if _, err := userDB.InsertRecord(c.Request.Context(), tableName, names, values); err != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Import into table '%s' stopped after %d row(s): %v", tableName, imported, err)
+			_ = c.Error(err)
+			message := fmt.Sprintf("Failed to insert row %d; %d row(s) were imported before it.", imported+1, imported)
+			if errors.Is(err, storage.ErrConstraintViolation) {
+				abortWithError(c, http.StatusConflict, "Constraint violation: "+message)
+			} else if errors.Is(err, storage.ErrDatabaseBusy) {
+				abortDatabaseBusy(c)
+			} else {
+				abortWithError(c, http.StatusInternalServerError, message)
+			}
+			return
+		}
+		imported++
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Imported %d row(s) into table '%s'", imported, tableName)
+	status := http.StatusOK
+	if specs != nil {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"table_name": tableName,
+		"created":    specs != nil,
+		"columns":    columns,
+		"imported":   imported,
+	})
+}
+
+// readImportData parses the uploaded document of an import request, aborting with 400
+// (or 413 when it is too large) on failure.
+func readImportData(c *gin.Context) (*core.ImportData, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadSize)
+	format := strings.ToLower(c.Query("format"))
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			abortImportUpload(c, err, "Multipart body must contain a 'file' field with the CSV or JSON document.")
+			return nil, false
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			abortImportUpload(c, err, "Failed to read uploaded file.")
+			return nil, false
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	} else if format == "" && (c.ContentType() == "text/csv" || c.ContentType() == "application/csv") {
+		format = "csv"
+	}
+
+	var data *core.ImportData
+	var err error
+	switch format {
+	case "csv":
+		data, err = core.ParseCSVData(body, maxImportRows)
+	case "json", "":
+		data, err = core.ParseJSONData(body, maxImportRows)
+	default:
+		_ = c.Error(fmt.Errorf("unsupported import format '%s'", format))
+		abortWithError(c, http.StatusBadRequest, "Invalid format: use 'csv' or 'json'.")
+		return nil, false
+	}
+	if err != nil {
+		abortImportUpload(c, err, err.Error())
+		return nil, false
+	}
+	if len(data.Fields) == 0 || len(data.Rows) == 0 {
+		_ = c.Error(errors.New("empty import"))
+		abortWithError(c, http.StatusBadRequest, "The data contains no rows to import.")
+		return nil, false
+	}
+	return data, true
+}
+
+// abortImportUpload rejects an unreadable upload, with 413 when it hit the size limit.
+func abortImportUpload(c *gin.Context, err error, message string) {
+	_ = c.Error(fmt.Errorf("import upload error: %w", err))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		abortWithError(c, http.StatusRequestEntityTooLarge, "Uploaded data is too large.")
+		return
+	}
+	abortWithError(c, http.StatusBadRequest, message)
+}
+
+// matchImportColumns maps the fields of data onto the columns of an existing table,
+// matching names case-insensitively. Fields for the built-in id and created_at columns
+// are skipped.
+func matchImportColumns(data *core.ImportData, columnTypes map[string]string) ([]core.ImportColumn, error) {
+	columns := make([]core.ImportColumn, 0, len(data.Fields))
+	matched := false
+	for _, field := range data.Fields {
+		name := strings.ToLower(field)
+		if name == "id" || name == "created_at" {
+			columns = append(columns, core.ImportColumn{Field: field})
+			continue
+		}
+		columnType, exists := columnTypes[name]
+		if !core.IsValidIdentifier(field) || !exists {
+			return nil, fmt.Errorf("field '%s' does not match a column of the table", field)
+		}
+		columns = append(columns, core.ImportColumn{Field: field, Name: name, Type: columnType})
+		matched = true
+	}
+	if !matched {
+		return nil, errors.New("no field of the data matches a column of the table")
+	}
+	return columns, nil
+}
+
+// validateImportRecords checks every record against the JSON schema, column types and
+// column rules of the table, aborting with 400 and the number of the first failing row
+// (counted from 1). These are the checks of a single-record write without its table
+// script hooks, which bulk writes skip. Nested values bound for TEXT columns are
+// JSON-encoded in place.
+func (h *RecordHandler) validateImportRecords(c *gin.Context, tableName string, columnTypes map[string]string, records []map[string]any) bool {
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	rules, err := h.MetaDB.ListColumnRules(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load validation rules.")
+		return false
+	}
+	var schema *jsonschema.Schema
+	if document, err := h.MetaDB.GetTableJSONSchema(c.Request.Context(), databaseID, tableName); err == nil {
+		schema, err = jsonschema.Compile([]byte(document))
+		if err != nil { // Only stored after compiling
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, "Failed to load JSON schema.")
+			return false
+		}
+	} else if !errors.Is(err, storage.ErrJSONSchemaNotFound) {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load JSON schema.")
+		return false
+	}
+
+	for i, record := range records {
+		var details any
+		if schema != nil {
+			if failures := schema.Validate(record); len(failures) > 0 {
+				details = failures
+			}
+		}
+		if details == nil {
+			if err := core.EncodeJSONValues(columnTypes, record); err != nil {
+				_ = c.Error(err)
+				abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Row %d: %s", i+1, err.Error()))
+				return false
+			}
+			if _, _, err := core.RecordAssignments(columnTypes, record); err != nil {
+				_ = c.Error(err)
+				abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Row %d: %s", i+1, err.Error()))
+				return false
+			}
+			if fieldErrors := core.CheckColumnRules(rules, record); len(fieldErrors) > 0 {
+				details = fieldErrors
+			}
+		}
+		if details != nil {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Row %d imported into table '%s' failed validation", i+1, tableName)
+			_ = c.Error(&models.APIError{
+				Status:  http.StatusBadRequest,
+				Code:    models.ErrCodeValidationFailed,
+				Message: fmt.Sprintf("Row %d failed validation.", i+1),
+				Details: details,
+			})
+			c.Abort()
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestImportSkipsTableScripts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := &config.Config{MetadataDbDir: dir, MetadataDbFile: "meta.db"}
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer metaDB.Close()
+	if _, err := metaDB.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	dbPath := filepath.Join(dir, "app.db")
+	defer storage.InvalidateUserDB(dbPath)
+	if err := metaDB.RegisterDatabase(ctx, "u1", "app", dbPath); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, _ := metaDB.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+
+	userDB, err := storage.OpenUserData(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer userDB.Release()
+	if err := userDB.CreateTable(ctx, "notes", []core.ColumnSpec{{Name: "body", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	script := `function beforeCreate(record) { throw new Error("no writes"); }`
+	if err := metaDB.SetTableScript(ctx, databaseID, "notes", script); err != nil {
+		t.Fatalf("SetTableScript: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/databases/:db_name/tables/:table_name/import", func(c *gin.Context) {
+		requestctx.SetUserID(c, "u1")
+	}, NewRecordHandler(metaDB, cfg).ImportRecords)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/databases/app/tables/notes/import?format=csv", strings.NewReader("body\nhello\n"))
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"imported":1`) {
+		t.Fatalf("import = %d %s, want the row imported without running beforeCreate", w.Code, w.Body.String())
+	}
+
+	stored, err := userDB.GetRecord(ctx, "notes", 1)
+	if err != nil || stored["body"] != "hello" {
+		t.Errorf("imported record = %v, %v; want body hello", stored, err)
+	}
+}
@@ -0,0 +1,38 @@
+// api/handlers/job_handler.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
+)
+
+// JobHandler exposes the status of background jobs.
+type JobHandler struct {
+	Jobs *jobs.Manager
+}
+
+// NewJobHandler creates a new JobHandler.
+func NewJobHandler(jobManager *jobs.Manager) *JobHandler {
+	return &JobHandler{Jobs: jobManager}
+}
+
+// GetJob returns the state of a background job owned by the caller.
+func (h *JobHandler) GetJob(c *gin.Context) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	job, ok := h.Jobs.Get(c.Param("job_id"))
+	if !ok || job.OwnerID != userId {
+		abortWithError(c, http.StatusNotFound, "Job not found.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -0,0 +1,153 @@
+// api/handlers/json_schema_handler.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/jsonschema"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetJSONSchema returns the JSON Schema record writes to a table are validated against.
+func (h *TableHandler) GetJSONSchema(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	schema, err := h.MetaDB.GetTableJSONSchema(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrJSONSchemaNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' has no JSON schema.", tableName))
+		}
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(schema))
+}
+
+// SetJSONSchema stores the JSON Schema sent as the request body for a table. It must
+// compile; it is enforced by CreateRecord and UpdateRecord from then on.
+func (h *TableHandler) SetJSONSchema(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, "Failed to read request body.")
+		return
+	}
+	if _, err := jsonschema.Compile(body); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil { // Cannot fail once compiled
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, "Invalid JSON request body.")
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+	if _, err := userDB.ColumnTypes(c.Request.Context(), tableName); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		}
+		return
+	}
+
+	if err := h.MetaDB.SetTableJSONSchema(c.Request.Context(), target.ID, tableName, compact.String()); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set JSON schema on table '%s' in DB '%s' for UserID %s", tableName, target.Name, target.UserID)
+	c.Data(http.StatusOK, "application/json; charset=utf-8", compact.Bytes())
+}
+
+// DeleteJSONSchema stops validating record writes to a table against a JSON Schema.
+func (h *TableHandler) DeleteJSONSchema(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.MetaDB.DeleteTableJSONSchema(c.Request.Context(), target.ID, tableName); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrJSONSchemaNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' has no JSON schema.", tableName))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Removed JSON schema of table '%s' in DB '%s' for UserID %s", tableName, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// enforceJSONSchema validates record against the JSON Schema of the table addressed by
+// the request, if it has one, rejecting it with 400 validation_failed. Partial records
+// (updates) are checked property by property. Object and array values for TEXT columns
+// are then JSON-encoded so they can be stored.
+func (h *RecordHandler) enforceJSONSchema(c *gin.Context, tableName string, columnTypes map[string]string, record map[string]any, partial bool) bool {
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	err = recordwrite.CheckJSONSchema(c.Request.Context(), h.MetaDB, databaseID, tableName, record, partial)
+	var invalid *recordwrite.ValidationError
+	if errors.As(err, &invalid) {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Record for table '%s' failed its JSON schema in %d place(s)", tableName, len(invalid.Fields))
+		abortValidationFailed(c, invalid)
+		return false
+	}
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load JSON schema.")
+		return false
+	}
+
+	if err := core.EncodeJSONValues(columnTypes, record); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+// api/handlers/maintenance_handler.go
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// MaintenanceHandler holds dependencies for database maintenance handlers.
+type MaintenanceHandler struct {
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
+	Jobs   *jobs.Manager         // Background job runner for async operations
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler.
+func NewMaintenanceHandler(metaDB storage.MetadataStore, cfg *config.Config, jobManager *jobs.Manager) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		MetaDB: metaDB,
+		Cfg:    cfg,
+		Jobs:   jobManager,
+	}
+}
+
+// RunMaintenance runs VACUUM, ANALYZE or a WAL checkpoint on a user database,
+// either inline or as a background job when "async" is set.
+func (h *MaintenanceHandler) RunMaintenance(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'operation' must be one of vacuum, analyze, wal_checkpoint.")
+		return
+	}
+
+	run := func(ctx context.Context) (*storage.MaintenanceResult, error) {
+		userDB, err := storage.ConnectUserDB(ctx, target.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer storage.ReleaseUserDB(userDB)
+		return storage.RunMaintenance(ctx, userDB, target.FilePath, req.Operation)
+	}
+
+	if req.Async {
+		job := h.Jobs.Submit(target.UserID, "maintenance:"+req.Operation, func(ctx context.Context) (any, error) {
+			return run(ctx)
+		})
+		customLog.Ctx(c.Request.Context()).Printf("Handler: Queued %s job %s for DB '%s', UserID %s", req.Operation, job.ID, target.Name, target.UserID)
+		c.JSON(http.StatusAccepted, gin.H{"message": "Maintenance job queued", "job": job})
+		return
+	}
+
+	result, err := run(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Ran %s on DB '%s' for UserID %s (%d -> %d bytes)", req.Operation, target.Name, target.UserID, result.SizeBeforeBytes, result.SizeAfterBytes)
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance completed", "db_name": target.Name, "result": result})
+}
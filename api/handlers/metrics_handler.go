@@ -0,0 +1,66 @@
+// api/handlers/metrics_handler.go
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+// Metrics serves the request latency histograms, the count of shed requests, the state
+// of the metadata circuit breaker and the capacity gauges in the Prometheus text format. When
+// METRICS_TOKEN is set, scrapes must send it as a bearer token.
+func (h *HealthHandler) Metrics(c *gin.Context) {
+	if h.Cfg.MetricsToken != "" {
+		token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.Cfg.MetricsToken)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, "A valid metrics token is required.")
+			return
+		}
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	err := metrics.Requests.WritePrometheus(c.Writer)
+	if err == nil {
+		err = metrics.ShedRequests.WritePrometheus(c.Writer)
+	}
+	if err == nil {
+		err = metrics.MetadataBreakerState.WritePrometheus(c.Writer)
+	}
+	if err == nil {
+		err = metrics.MetadataBreakerTransitions.WritePrometheus(c.Writer)
+	}
+	if err == nil {
+		err = metrics.WriteGauges(c.Writer)
+	}
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to write metrics: %v", err)
+	}
+}
+
+// GetLatencyStats returns the p50/p95/p99 latencies per route and per handler since
+// the last reset (or start), slowest first.
+func (h *AdminHandler) GetLatencyStats(c *gin.Context) {
+	c.JSON(http.StatusOK, metrics.Requests.Summarize())
+}
+
+// GetResourceStats returns the current values of the capacity gauges (open user DB
+// handles, cached schemas, background workers, rate limiter clients and goroutines),
+// the same values /metrics exports, for operators without a Prometheus server.
+func (h *AdminHandler) GetResourceStats(c *gin.Context) {
+	c.JSON(http.StatusOK, metrics.Snapshot())
+}
+
+// ResetLatencyStats drops the recorded latencies, e.g. right after a deploy, so the
+// stats only reflect the new release. Prometheus sees the histograms restart.
+func (h *AdminHandler) ResetLatencyStats(c *gin.Context) {
+	metrics.Requests.Reset()
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Warnf("Handler: Latency stats reset by UserID %s", userId)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+// api/handlers/publishable_key_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/forms"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of publishable keys.
+const (
+	maxPublishableKeysPerDatabase = 10
+	maxPublishableKeyTables       = 50
+)
+
+// ListPublishableKeys returns the publishable keys of a database. They are meant to be
+// embedded in frontend apps, so their values are listed too.
+func (h *DatabaseHandler) ListPublishableKeys(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	keys, err := h.MetaDB.ListPublishableKeys(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publishable_keys": keys})
+}
+
+// CreatePublishableKey creates a browser-safe key of a database: used as
+// "Authorization: ApiKey <key>", it only reads the listed tables, and only from
+// requests whose Origin (or Referer) is one of the allowed origins.
+func (h *DatabaseHandler) CreatePublishableKey(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreatePublishableKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	fail := func(message string) {
+		_ = c.Error(errors.New(message))
+		abortWithError(c, http.StatusBadRequest, message)
+	}
+	if len(req.Tables) == 0 || len(req.Tables) > maxPublishableKeyTables {
+		fail(fmt.Sprintf("A publishable key must grant between 1 and %d tables.", maxPublishableKeyTables))
+		return
+	}
+	if len(req.AllowedOrigins) == 0 {
+		fail("A publishable key needs at least one allowed origin.")
+		return
+	}
+	if err := forms.ValidateOrigins(req.AllowedOrigins); err != nil {
+		fail(err.Error())
+		return
+	}
+	tables := make([]string, 0, len(req.Tables))
+	for _, table := range req.Tables {
+		table = strings.ToLower(table)
+		if slices.Contains(tables, table) {
+			continue
+		}
+		if !h.checkShareTarget(c, target, table, "") {
+			return
+		}
+		tables = append(tables, table)
+	}
+
+	existing, err := h.MetaDB.ListPublishableKeys(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if len(existing) >= maxPublishableKeysPerDatabase {
+		_ = c.Error(errors.New("publishable key limit reached"))
+		abortWithError(c, http.StatusConflict, fmt.Sprintf("Database '%s' already has %d publishable keys, the maximum.", target.Name, maxPublishableKeysPerDatabase))
+		return
+	}
+
+	key := domain.PublishableKey{
+		ID:             uuid.New().String(),
+		DatabaseID:     target.ID,
+		Tables:         tables,
+		AllowedOrigins: forms.NormalizeOrigins(req.AllowedOrigins),
+		Description:    req.Description,
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+	}
+	if key.Key, err = h.MetaDB.CreatePublishableKey(c.Request.Context(), key); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Created publishable key %s for tables %v of DB '%s' for UserID %s", key.ID, key.Tables, target.Name, target.UserID)
+	c.JSON(http.StatusCreated, key)
+}
+
+// DeletePublishableKey revokes a publishable key; it stops working right away.
+func (h *DatabaseHandler) DeletePublishableKey(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	keyId := c.Param("key_id")
+	if err := h.MetaDB.DeletePublishableKey(c.Request.Context(), target.ID, keyId); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrPublishableKeyNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Publishable key '%s' not found.", keyId))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Revoked publishable key %s of DB '%s' for UserID %s", keyId, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
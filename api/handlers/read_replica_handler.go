@@ -0,0 +1,99 @@
+// api/handlers/read_replica_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// ListReadReplicas returns the databases designated to have a read replica, with the
+// state of each replica on this instance.
+func (h *AdminHandler) ListReadReplicas(c *gin.Context) {
+	replicas, err := h.MetaDB.ListReadReplicas(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	items := make([]models.AdminReadReplica, 0, len(replicas))
+	for _, replica := range replicas {
+		item := models.AdminReadReplica{ReadReplica: replica}
+		if status, ok := storage.ReadReplicaStatusOf(replica.FilePath); ok {
+			item.Status = &models.ReadReplicaStatus{InSync: status.InSync, SyncedAt: status.SyncedAt, Reads: status.Reads, LastError: status.LastError}
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"replicas": items, "enabled": h.readReplicasEnabled()})
+}
+
+// SetReadReplica designates a read replica for a database, or changes how stale its
+// reads may be. The replica is created by the refresher within one interval.
+func (h *AdminHandler) SetReadReplica(c *gin.Context) {
+	databaseId, ok := databaseIDParam(c)
+	if !ok {
+		return
+	}
+	if !h.readReplicasEnabled() {
+		abortWithError(c, http.StatusBadRequest, "Read replicas need the SQLite user data backend and READ_REPLICA_REFRESH_INTERVAL_SECONDS > 0.")
+		return
+	}
+	var req models.ReadReplicaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body. 'max_lag_seconds' must be between 0 and 86400.")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.MetaDB.FindDatabaseByID(ctx, databaseId); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := h.MetaDB.SetReadReplica(ctx, databaseId, req.MaxLagSeconds); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(ctx).Printf("Handler: Read replica of DBID %d set (max lag %ds) by UserID %s", databaseId, req.MaxLagSeconds, userId)
+	c.JSON(http.StatusOK, gin.H{"database_id": databaseId, "max_lag_seconds": req.MaxLagSeconds})
+}
+
+// DeleteReadReplica removes the read replica of a database; reads go to the primary
+// again and the replica file is deleted by the refresher.
+func (h *AdminHandler) DeleteReadReplica(c *gin.Context) {
+	databaseId, ok := databaseIDParam(c)
+	if !ok {
+		return
+	}
+	if err := h.MetaDB.DeleteReadReplica(c.Request.Context(), databaseId); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userId, _ := requestctx.UserID(c)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Read replica of DBID %d removed by UserID %s", databaseId, userId)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) readReplicasEnabled() bool {
+	return h.Cfg.UserDataBackend == storage.BackendSQLite && h.Cfg.ReadReplicaRefreshInterval > 0
+}
+
+// databaseIDParam parses the :database_id path parameter, aborting with 400 if it is
+// invalid.
+func databaseIDParam(c *gin.Context) (int64, bool) {
+	databaseId, err := strconv.ParseInt(c.Param("database_id"), 10, 64)
+	if err != nil || databaseId < 1 {
+		_ = c.Error(fmt.Errorf("invalid database id '%s'", c.Param("database_id")))
+		abortWithError(c, http.StatusBadRequest, "Invalid database ID.")
+		return 0, false
+	}
+	return databaseId, true
+}
@@ -2,41 +2,50 @@
 package handlers
 
 import (
-	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
-	// "nebula-backend/api/models" // Not using specific models here yet
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
-	"github.com/Annany2002/nebula-backend/internal/core"    // For validation
+	"github.com/Annany2002/nebula-backend/internal/core" // For validation
+	"github.com/Annany2002/nebula-backend/internal/forms"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
 	"github.com/Annany2002/nebula-backend/internal/storage" // For DB operations
+	"github.com/Annany2002/nebula-backend/internal/xlsx"
 )
 
 // RecordHandler holds dependencies for record CRUD handlers.
 type RecordHandler struct {
-	MetaDB *sql.DB        // Metadata DB pool
-	Cfg    *config.Config // App configuration
+	MetaDB storage.MetadataStore // Metadata DB pool
+	Cfg    *config.Config        // App configuration
 	// UserRepo *storage.UserDBRepo // Could inject repo struct later
+
+	formLimiter *forms.Limiter // Public form submissions per client
 }
 
 // NewRecordHandler creates a new RecordHandler.
-func NewRecordHandler(metaDB *sql.DB, cfg *config.Config) *RecordHandler {
+func NewRecordHandler(metaDB storage.MetadataStore, cfg *config.Config) *RecordHandler {
 	return &RecordHandler{
-		MetaDB: metaDB,
-		Cfg:    cfg,
+		MetaDB:      metaDB,
+		Cfg:         cfg,
+		formLimiter: forms.NewLimiter(),
 	}
 }
 
 // --- Helper to get User DB connection ---
 // Avoids repeating lookup/connect logic in every handler
-func (h *RecordHandler) getUserDBConn(c *gin.Context) (*sql.DB, string, string, error) {
-	userId := c.MustGet("userId").(string)
+func (h *RecordHandler) getUserDBConn(c *gin.Context) (storage.UserDataStore, string, string, error) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		return nil, "", "", err
+	}
 	dbName := c.Param("db_name")
 	tableName := c.Param("table_name")
 
@@ -44,12 +53,12 @@ func (h *RecordHandler) getUserDBConn(c *gin.Context) (*sql.DB, string, string,
 		return nil, "", "", errors.New("invalid database or table name in URL path") // Return error
 	}
 
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, userId, dbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), userId, dbName)
 	if err != nil {
 		return nil, "", "", err // Return storage error (e.g., ErrDatabaseNotFound)
 	}
 
-	userDB, err := storage.ConnectUserDB(c.Request.Context(), dbFilePath)
+	userDB, err := storage.OpenUserData(c.Request.Context(), dbFilePath)
 	if err != nil {
 		return nil, "", "", err // Return connection error
 	}
@@ -58,30 +67,55 @@ func (h *RecordHandler) getUserDBConn(c *gin.Context) (*sql.DB, string, string,
 	return userDB, tableName, dbFilePath, nil
 }
 
+// requestDatabaseID returns the ID of the database addressed by the request, looked up
+// once per request.
+func (h *RecordHandler) requestDatabaseID(c *gin.Context) (int64, error) {
+	if id, ok, err := requestctx.TargetDatabaseID(c); err != nil || ok {
+		return id, err
+	}
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		return 0, err
+	}
+	id, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, c.Param("db_name"))
+	if err != nil {
+		return 0, err
+	}
+	requestctx.SetTargetDatabaseID(c, id)
+	return id, nil
+}
+
 // CreateRecord handles inserting a new record.
 func (h *RecordHandler) CreateRecord(c *gin.Context) {
+	representation, ok := wantsRepresentation(c, true)
+	if !ok {
+		return
+	}
+
 	userDB, tableName, dbFilePath, err := h.getUserDBConn(c)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
 		} else if strings.Contains(err.Error(), "invalid database or table name") {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, err.Error())
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		}
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
 	// Fetch schema for validation
-	columnTypes, err := storage.PragmaTableInfo(c.Request.Context(), userDB, tableName)
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Table '%s' not found.", tableName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve table schema."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
 		}
 		return
 	}
@@ -90,123 +124,95 @@ func (h *RecordHandler) CreateRecord(c *gin.Context) {
 	var recordData map[string]any
 	if err := c.ShouldBindJSON(&recordData); err != nil {
 		_ = c.Error(fmt.Errorf("binding error: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON request body: " + err.Error()})
+		abortWithError(c, http.StatusBadRequest, "Invalid JSON request body: "+err.Error())
 		return
 	}
 	if len(recordData) == 0 {
 		_ = c.Error(errors.New("empty request body"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Request body cannot be empty."})
+		abortWithError(c, http.StatusBadRequest, "Request body cannot be empty.")
 		return
 	}
+	lastID, ok := h.insertRecord(c, userDB, tableName, dbFilePath, columnTypes, recordData)
+	if !ok {
+		return
+	}
+	response := gin.H{
+		"message":   "Record created successfully",
+		"record_id": lastID,
+	}
+	if representation {
+		h.addStoredRecord(c, userDB, tableName, lastID, response)
+	}
+	c.JSON(http.StatusCreated, response)
+}
 
-	// Prepare SQL parts and validate types
-	var columns []string
-	var placeholders []string
-	var values []any
-
-	for key, val := range recordData {
-		lowerKey := strings.ToLower(key)
-		if !core.IsValidIdentifier(key) || lowerKey == "id" {
-			continue
-		} // Skip invalid/id
-
-		expectedType, exists := columnTypes[lowerKey]
-		if !exists {
-			err := fmt.Errorf("column '%s' does not exist", key)
-			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		// Perform type validation (copied logic from corrected update handler)
-		isValidValue := false
-		switch expectedType {
-		case "INTEGER":
-			switch v := val.(type) {
-			case float64:
-				if math.Floor(v) == v {
-					isValidValue = true
-				}
-			case int, int64:
-				isValidValue = true
-			case nil:
-				isValidValue = true
-			}
-		case "REAL":
-			switch val.(type) {
-			case float64, int, int64, nil:
-				isValidValue = true
-			}
-		case "TEXT":
-			switch val.(type) {
-			case string, nil:
-				isValidValue = true
-			}
-		case "BLOB":
-			switch val.(type) {
-			case string, nil:
-				isValidValue = true
-			} // Lenient
-		case "BOOLEAN":
-			switch v := val.(type) {
-			case bool:
-				isValidValue = true
-			case float64:
-				if v == 0 || v == 1 {
-					isValidValue = true
-				}
-			case nil:
-				isValidValue = true
-			}
-		default:
-			isValidValue = true // Lenient
-		}
-
-		if !isValidValue {
-			err := fmt.Errorf("invalid data type for column '%s'. Expected compatible with %s", key, expectedType)
-			_ = c.Error(err)
-			customLog.Warnf("Create Record Type Error: Key: %s, Expected: %s, Got Type: %T, Got Value: %v", key, expectedType, val, val)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		columns = append(columns, key)
-		placeholders = append(placeholders, "?")
-		values = append(values, val)
-	} // End validation loop
+// insertRecord writes recordData as a new record of tableName, running the table's
+// script hooks and validation around the insert, and publishes the change. On failure
+// the request is aborted and false is returned.
+func (h *RecordHandler) insertRecord(c *gin.Context, userDB storage.UserDataStore, tableName, dbFilePath string, columnTypes map[string]string, recordData map[string]any) (int64, bool) {
+	script, ok := h.loadTableScript(c, tableName)
+	if !ok {
+		return 0, false
+	}
+	if recordData, ok = h.runBeforeHook(c, script, scripting.BeforeCreate, tableName, 0, recordData); !ok {
+		return 0, false
+	}
+	if !h.enforceJSONSchema(c, tableName, columnTypes, recordData, false) {
+		return 0, false
+	}
+	autoColumns, ok := h.planAutoColumns(c, tableName, columnTypes, recordData)
+	if !ok {
+		return 0, false
+	}
 
+	// Prepare SQL parts and validate types
+	columns, values, err := core.RecordAssignments(columnTypes, recordData)
+	if err != nil {
+		_ = c.Error(err)
+		customLog.Ctx(c.Request.Context()).Warnf("Create Record Validation Error: %v", err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
 	if len(columns) == 0 {
 		_ = c.Error(errors.New("no valid columns provided"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No valid columns found in request body."})
-		return
+		abortWithError(c, http.StatusBadRequest, "No valid columns found in request body.")
+		return 0, false
+	}
+	if !h.enforceColumnRules(c, tableName, recordData) {
+		return 0, false
+	}
+	if !h.enforceRowLimit(c, userDB, tableName, 1) {
+		return 0, false
+	}
+	if !h.addAutoColumns(c, userDB, tableName, autoColumns) {
+		return 0, false
 	}
 
-	// Construct and execute INSERT via storage function
-	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
-	customLog.Printf("Handler: Executing Create Record SQL for DB '%s': %s", dbFilePath, insertSQL)
+	// Execute INSERT via the user data store
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Creating record in DB '%s', Table '%s' with columns %v", dbFilePath, tableName, columns)
 
-	lastID, err := storage.InsertRecord(c.Request.Context(), userDB, insertSQL, values...)
+	lastID, err := userDB.InsertRecord(c.Request.Context(), tableName, columns, values)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Table not found."})
+			abortWithError(c, http.StatusNotFound, "Table not found.")
 		} else if errors.Is(err, storage.ErrColumnNotFound) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Column not found."})
+			abortWithError(c, http.StatusBadRequest, "Column not found.")
 		} else if errors.Is(err, storage.ErrTypeMismatch) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Data type mismatch."})
+			abortWithError(c, http.StatusBadRequest, "Data type mismatch.")
 		} else if errors.Is(err, storage.ErrConstraintViolation) {
-			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Constraint violation."})
+			abortWithError(c, http.StatusConflict, "Constraint violation.")
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert record."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to insert record.")
 		}
-		return
+		return 0, false
 	}
 
-	customLog.Printf("Handler: Successfully inserted record ID %d into DB '%s', Table '%s'", lastID, dbFilePath, tableName)
-	c.JSON(http.StatusCreated, gin.H{
-		"message":   "Record created successfully",
-		"record_id": lastID,
-	})
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully inserted record ID %d into DB '%s', Table '%s'", lastID, dbFilePath, tableName)
+	h.runAfterHook(c, script, scripting.AfterCreate, userDB, tableName, lastID)
+	return lastID, true
 }
 
 // ListRecords handles retrieving records with pagination, sorting, filtering, and field selection.
@@ -215,16 +221,16 @@ func (h *RecordHandler) ListRecords(c *gin.Context) {
 	if err != nil {
 		errToSet := err
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
 		} else if strings.Contains(err.Error(), "invalid database or table name") {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, err.Error())
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		}
 		_ = c.Error(errToSet)
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
 	// Parse query parameters
 	queryParams := c.Request.URL.Query()
@@ -233,34 +239,198 @@ func (h *RecordHandler) ListRecords(c *gin.Context) {
 	queryOpts, err := core.ParseListQueryOptions(queryParams)
 	if err != nil {
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.applyDefaultSort(c, userDB, tableName, queryParams, queryOpts) {
 		return
 	}
 
-	customLog.Printf("Handler: Listing Records for DB '%s', Table '%s' with options: limit=%d, offset=%d, sort=%s, order=%s, fields=%v",
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Listing Records for DB '%s', Table '%s' with options: limit=%d, offset=%d, sort=%s, order=%s, fields=%v",
 		dbFilePath, tableName, queryOpts.Limit, queryOpts.Offset, queryOpts.SortBy, queryOpts.SortOrder, queryOpts.Fields)
 
+	// Large result sets can be streamed instead of buffered
+	if queryOpts.Stream == core.StreamXLSX {
+		h.streamWorkbook(c, userDB, tableName, dbFilePath, queryOpts)
+		return
+	}
+	if queryOpts.Stream != "" {
+		h.streamRecords(c, userDB, tableName, dbFilePath, queryOpts)
+		return
+	}
+
 	// Call the updated storage function with query options
-	result, err := storage.ListRecords(c.Request.Context(), userDB, tableName, queryParams, queryOpts)
+	result, err := userDB.ListRecords(c.Request.Context(), tableName, queryParams, queryOpts)
 	if err != nil {
-		_ = c.Error(err)
-		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Table '%s' not found.", tableName)})
-		} else if errors.Is(err, storage.ErrInvalidFilterValue) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		} else if errors.Is(err, storage.ErrInvalidSortColumn) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		} else if errors.Is(err, storage.ErrInvalidFieldColumn) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortListRecordsError(c, tableName, err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully retrieved %d records (total: %d) from DB '%s', Table '%s'",
+		len(result.Records), result.Pagination.Total, dbFilePath, tableName)
+	if apiVersion(c) >= 2 {
+		records := result.Records
+		if records == nil {
+			records = []map[string]any{}
+		}
+		jsonWithETag(c, pageEnvelope{Data: records, Pagination: newPageMeta(result.Pagination)})
+		return
+	}
+	jsonWithETag(c, result)
+}
+
+// streamFlushEvery is how many streamed records are written between flushes.
+const streamFlushEvery = 100
+
+// streamRecords writes the list response while rows are scanned, so memory use stays
+// bounded regardless of the page size. Errors after the first byte was sent can only
+// be logged; the client sees a truncated body.
+func (h *RecordHandler) streamRecords(c *gin.Context, userDB storage.UserDataStore, tableName, dbFilePath string, opts *core.ListQueryOptions) {
+	w := c.Writer
+	count := 0
+	started := false
+
+	start := func(pagination storage.PaginationMeta) error {
+		started = true
+		if opts.Stream == core.StreamNDJSON {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("X-Total-Count", strconv.Itoa(pagination.Total))
+			w.WriteHeader(http.StatusOK)
+			return nil
+		}
+		// v1 streams {"pagination":...,"records":[...]}, v2 the {"pagination":...,"data":[...]} envelope
+		var meta []byte
+		var err error
+		itemsKey := "records"
+		if apiVersion(c) >= 2 {
+			meta, err = json.Marshal(newPageMeta(pagination))
+			itemsKey = "data"
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to query records."})
+			meta, err = json.Marshal(pagination)
+		}
+		if err != nil {
+			return err
+		}
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, err = fmt.Fprintf(w, `{"pagination":%s,"%s":[`, meta, itemsKey)
+		return err
+	}
+
+	emit := func(record map[string]any) error {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if opts.Stream == core.StreamNDJSON {
+			encoded = append(encoded, '\n')
+		} else if count > 0 {
+			encoded = append([]byte{','}, encoded...)
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			w.Flush()
 		}
+		return nil
+	}
+
+	err := userDB.StreamRecords(c.Request.Context(), tableName, c.Request.URL.Query(), opts, start, emit)
+	if err != nil {
+		if !started {
+			abortListRecordsError(c, tableName, err)
+			return
+		}
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Streaming records from DB '%s', Table '%s' aborted after %d records: %v", dbFilePath, tableName, count, err)
+		_ = c.Error(err)
 		return
 	}
 
-	customLog.Printf("Handler: Successfully retrieved %d records (total: %d) from DB '%s', Table '%s'",
-		len(result.Records), result.Pagination.Total, dbFilePath, tableName)
-	c.JSON(http.StatusOK, result)
+	if opts.Stream == core.StreamJSON {
+		_, _ = w.WriteString("]}")
+	}
+	w.Flush()
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Streamed %d records from DB '%s', Table '%s'", count, dbFilePath, tableName)
+}
+
+// streamWorkbook writes the matching records as an Excel workbook with one sheet,
+// whose columns are the selected fields or else the table's columns.
+func (h *RecordHandler) streamWorkbook(c *gin.Context, userDB storage.UserDataStore, tableName, dbFilePath string, opts *core.ListQueryOptions) {
+	columns := opts.Fields
+	if len(columns) == 0 {
+		tables, err := userDB.ListTables(c.Request.Context())
+		if err != nil {
+			abortListRecordsError(c, tableName, err)
+			return
+		}
+		for _, table := range tables {
+			if table.Name == tableName {
+				for _, col := range table.Columns {
+					columns = append(columns, col.Name)
+				}
+			}
+		}
+		if len(columns) == 0 {
+			abortListRecordsError(c, tableName, storage.ErrTableNotFound)
+			return
+		}
+	}
+
+	workbook := xlsx.NewWriter(c.Writer)
+	row := make([]any, len(columns))
+	count := 0
+	started := false
+
+	start := func(pagination storage.PaginationMeta) error {
+		started = true
+		c.Header("Content-Type", xlsx.ContentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, tableName))
+		c.Header("X-Total-Count", strconv.Itoa(pagination.Total))
+		c.Writer.WriteHeader(http.StatusOK)
+		return workbook.AddSheet(tableName, columns)
+	}
+	emit := func(record map[string]any) error {
+		for i, col := range columns {
+			row[i] = record[col]
+		}
+		count++
+		return workbook.WriteRow(row)
+	}
+
+	err := userDB.StreamRecords(c.Request.Context(), tableName, c.Request.URL.Query(), opts, start, emit)
+	if err == nil {
+		err = workbook.Close()
+	}
+	if err != nil {
+		if !started {
+			abortListRecordsError(c, tableName, err)
+			return
+		}
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Excel export from DB '%s', Table '%s' aborted after %d records: %v", dbFilePath, tableName, count, err)
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Exported %d records from DB '%s', Table '%s' as xlsx", count, dbFilePath, tableName)
+}
+
+// abortListRecordsError maps errors from listing records to a response.
+func abortListRecordsError(c *gin.Context, tableName string, err error) {
+	_ = c.Error(err)
+	if errors.Is(err, storage.ErrTableNotFound) {
+		abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+	} else if errors.Is(err, storage.ErrInvalidFilterValue) {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, storage.ErrInvalidSortColumn) {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, storage.ErrInvalidFieldColumn) {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+	} else if errors.Is(err, storage.ErrDatabaseBusy) {
+		abortDatabaseBusy(c)
+	} else {
+		abortWithError(c, http.StatusInternalServerError, "Failed to query records.")
+	}
 }
 
 // GetRecord handles retrieving a single record by ID.
@@ -269,7 +439,7 @@ func (h *RecordHandler) GetRecord(c *gin.Context) {
 	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
 	if err != nil {
 		_ = c.Error(fmt.Errorf("invalid record_id format: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID format."})
+		abortWithError(c, http.StatusBadRequest, "Invalid record ID format.")
 		return
 	}
 
@@ -277,34 +447,92 @@ func (h *RecordHandler) GetRecord(c *gin.Context) {
 	if err != nil { /* ... handle getUserDBConn error (400, 404, 500) ... */
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
 		} else if strings.Contains(err.Error(), "invalid database or table name") {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, err.Error())
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		}
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
-	selectSQL := fmt.Sprintf("SELECT * FROM %s WHERE id = ? LIMIT 1;", tableName)
-	customLog.Printf("Handler: Executing Get Record SQL for DB '%s', ID %d: %s", dbFilePath, recordID, selectSQL)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Getting record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
 
-	recordData, err := storage.GetRecord(c.Request.Context(), userDB, selectSQL, recordID)
+	recordData, err := userDB.GetRecord(c.Request.Context(), tableName, recordID)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Table '%s' not found.", tableName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
 		} else if errors.Is(err, storage.ErrRecordNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Record not found."})
+			abortWithError(c, http.StatusNotFound, "Record not found.")
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve record."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve record.")
 		}
 		return
 	}
 
-	customLog.Printf("Handler: Successfully retrieved record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
-	c.JSON(http.StatusOK, recordData)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully retrieved record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
+	jsonWithETag(c, recordData)
+}
+
+// BatchGetRecords returns the records with the requested IDs in request order, in one
+// round trip. IDs without a record are listed under "missing" instead of failing the
+// request; repeated IDs are returned once.
+func (h *RecordHandler) BatchGetRecords(c *gin.Context) {
+	var req models.BatchGetRecordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	userDB, tableName, dbFilePath, err := h.getUserDBConn(c)
+	if err != nil { /* ... handle getUserDBConn error (400, 404, 500) ... */
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if strings.Contains(err.Error(), "invalid database or table name") {
+			abortWithError(c, http.StatusBadRequest, err.Error())
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		}
+		return
+	}
+	defer userDB.Release()
+
+	records := make([]map[string]any, 0, len(req.IDs))
+	missing := make([]int64, 0)
+	seen := make(map[int64]bool, len(req.IDs))
+	for _, recordID := range req.IDs {
+		if seen[recordID] {
+			continue
+		}
+		seen[recordID] = true
+
+		recordData, err := userDB.GetRecord(c.Request.Context(), tableName, recordID)
+		switch {
+		case err == nil:
+			records = append(records, recordData)
+		case errors.Is(err, storage.ErrRecordNotFound):
+			missing = append(missing, recordID)
+		default:
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrTableNotFound) {
+				abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+			} else if errors.Is(err, storage.ErrDatabaseBusy) {
+				abortDatabaseBusy(c)
+			} else {
+				abortWithError(c, http.StatusInternalServerError, "Failed to retrieve records.")
+			}
+			return
+		}
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Batch get of %d ID(s) from DB '%s', Table '%s': %d found, %d missing", len(seen), dbFilePath, tableName, len(records), len(missing))
+	jsonWithETag(c, gin.H{"records": records, "missing": missing})
 }
 
 // UpdateRecord handles updating an existing record.
@@ -313,7 +541,11 @@ func (h *RecordHandler) UpdateRecord(c *gin.Context) {
 	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
 	if err != nil {
 		_ = c.Error(fmt.Errorf("invalid record_id format: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID format."})
+		abortWithError(c, http.StatusBadRequest, "Invalid record ID format.")
+		return
+	}
+	representation, ok := wantsRepresentation(c, false)
+	if !ok {
 		return
 	}
 
@@ -321,24 +553,26 @@ func (h *RecordHandler) UpdateRecord(c *gin.Context) {
 	if err != nil { /* ... handle getUserDBConn error (400, 404, 500) ... */
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
 		} else if strings.Contains(err.Error(), "invalid database or table name") {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, err.Error())
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		}
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
 	// Fetch schema for validation
-	columnTypes, err := storage.PragmaTableInfo(c.Request.Context(), userDB, tableName)
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
 	if err != nil { /* ... handle Pragma error (404, 500) ... */
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Table '%s' not found.", tableName)})
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve table schema."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
 		}
 		return
 	}
@@ -347,125 +581,92 @@ func (h *RecordHandler) UpdateRecord(c *gin.Context) {
 	var updateData map[string]interface{}
 	if err := c.ShouldBindJSON(&updateData); err != nil { /* ... handle binding error (400) ... */
 		_ = c.Error(fmt.Errorf("binding error: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON request body: " + err.Error()})
+		abortWithError(c, http.StatusBadRequest, "Invalid JSON request body: "+err.Error())
 		return
 	}
 	if len(updateData) == 0 { /* ... handle empty body (400) ... */
 		_ = c.Error(errors.New("empty request body for update"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Request body cannot be empty for update."})
+		abortWithError(c, http.StatusBadRequest, "Request body cannot be empty for update.")
+		return
+	}
+	script, ok := h.loadTableScript(c, tableName)
+	if !ok {
+		return
+	}
+	if updateData, ok = h.runBeforeHook(c, script, scripting.BeforeUpdate, tableName, recordID, updateData); !ok {
+		return
+	}
+	if !h.enforceJSONSchema(c, tableName, columnTypes, updateData, true) {
 		return
 	}
 
 	// Prepare SQL parts and validate types
-	var setClauses []string
-	var values []any
-
-	for key, val := range updateData {
-		lowerKey := strings.ToLower(key)
-		if !core.IsValidIdentifier(key) || lowerKey == "id" {
-			continue
-		} // Skip
-
-		expectedType, exists := columnTypes[lowerKey]
-		if !exists { /* ... handle column not exists (400) ... */
-			err := fmt.Errorf("column '%s' does not exist", key)
-			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		// Type validation logic (same as create)
-		isValidValue := false
-		switch expectedType { /* ... same validation switch as CreateRecord ... */
-		case "INTEGER":
-			switch v := val.(type) {
-			case float64:
-				if math.Floor(v) == v {
-					isValidValue = true
-				}
-			case int, int64, nil:
-				isValidValue = true
-			}
-		case "REAL":
-			switch val.(type) {
-			case float64, int, int64, nil:
-				isValidValue = true
-			}
-		case "TEXT":
-			switch val.(type) {
-			case string, nil:
-				isValidValue = true
-			}
-		case "BLOB":
-			switch val.(type) {
-			case string, nil:
-				isValidValue = true
-			} // Lenient
-		case "BOOLEAN":
-			switch v := val.(type) {
-			case bool, nil:
-				isValidValue = true
-			case float64:
-				if v == 0 || v == 1 {
-					isValidValue = true
-				}
-			}
-		default:
-			isValidValue = true // Lenient
-		}
-
-		if !isValidValue { /* ... handle type mismatch (400) ... */
-			err := fmt.Errorf("invalid data type for column '%s'. Expected compatible with %s", key, expectedType)
-			_ = c.Error(err)
-			customLog.Warnf("Update Record Type Error: Key: %s, Expected: %s, Got Type: %T, Got Value: %v", key, expectedType, val, val)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", key))
-		values = append(values, val)
-	} // End validation loop
-
-	if len(setClauses) == 0 { /* ... handle no valid fields (400) ... */
+	columns, values, err := core.RecordAssignments(columnTypes, updateData)
+	if err != nil {
+		_ = c.Error(err)
+		customLog.Ctx(c.Request.Context()).Warnf("Update Record Validation Error: %v", err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(columns) == 0 { /* ... handle no valid fields (400) ... */
 		_ = c.Error(errors.New("no valid fields provided for update"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No valid fields provided for update."})
+		abortWithError(c, http.StatusBadRequest, "No valid fields provided for update.")
+		return
+	}
+	if !h.enforceColumnRules(c, tableName, updateData) {
 		return
 	}
 
-	values = append(values, recordID) // Add ID for WHERE clause
-
-	// Construct and execute UPDATE via storage function
-	updateSQL := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?",
-		tableName, strings.Join(setClauses, ", "))
-	customLog.Printf("Handler: Executing Update Record SQL for DB '%s', ID %d: %s", dbFilePath, recordID, updateSQL)
+	// Execute UPDATE via the user data store
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Updating record ID %d in DB '%s', Table '%s' with columns %v", recordID, dbFilePath, tableName, columns)
 
-	_, err = storage.UpdateRecord(c.Request.Context(), userDB, updateSQL, values...)
+	_, err = userDB.UpdateRecord(c.Request.Context(), tableName, recordID, columns, values)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrTableNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Table not found."})
+			abortWithError(c, http.StatusNotFound, "Table not found.")
 		} else // Should have been caught by PRAGMA
 		if errors.Is(err, storage.ErrColumnNotFound) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Column not found."})
+			abortWithError(c, http.StatusBadRequest, "Column not found.")
 		} else // Should have been caught by PRAGMA/validation
 		if errors.Is(err, storage.ErrTypeMismatch) {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Data type mismatch."})
+			abortWithError(c, http.StatusBadRequest, "Data type mismatch.")
 		} else // Should have been caught by validation
 		if errors.Is(err, storage.ErrRecordNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Record not found for update."})
+			abortWithError(c, http.StatusNotFound, "Record not found for update.")
 		} else // From RowsAffected check in repo
 		if errors.Is(err, storage.ErrConstraintViolation) {
-			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Constraint violation."})
+			abortWithError(c, http.StatusConflict, "Constraint violation.")
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to update record."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to update record.")
 		}
 		return
 	}
 
-	customLog.Printf("Handler: Successfully updated record ID %d in DB '%s', Table '%s'", recordID, dbFilePath, tableName)
-	c.JSON(http.StatusOK, gin.H{
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully updated record ID %d in DB '%s', Table '%s'", recordID, dbFilePath, tableName)
+	h.runAfterHook(c, script, scripting.AfterUpdate, userDB, tableName, recordID)
+	response := gin.H{
 		"message":   "Record updated successfully",
 		"record_id": recordID,
-	})
+	}
+	if representation {
+		h.addStoredRecord(c, userDB, tableName, recordID, response)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// addStoredRecord adds the record as stored after a write, with column defaults and
+// timestamps filled in, to response under "record". The write already succeeded, so a
+// failed read is only logged.
+func (h *RecordHandler) addStoredRecord(c *gin.Context, userDB storage.UserDataStore, tableName string, recordID int64, response gin.H) {
+	record, err := userDB.GetRecord(c.Request.Context(), tableName, recordID)
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to read back record ID %d in Table '%s': %v", recordID, tableName, err)
+		return
+	}
+	response["record"] = record
 }
 
 // DeleteRecord handles deleting a specific record by ID.
@@ -474,7 +675,7 @@ func (h *RecordHandler) DeleteRecord(c *gin.Context) {
 	recordID, err := strconv.ParseInt(recordIDStr, 10, 64)
 	if err != nil { /* ... handle invalid ID (400) ... */
 		_ = c.Error(fmt.Errorf("invalid record_id format: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID format."})
+		abortWithError(c, http.StatusBadRequest, "Invalid record ID format.")
 		return
 	}
 
@@ -482,33 +683,34 @@ func (h *RecordHandler) DeleteRecord(c *gin.Context) {
 	if err != nil { /* ... handle getUserDBConn error (400, 404, 500) ... */
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
 		} else if strings.Contains(err.Error(), "invalid database or table name") {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusBadRequest, err.Error())
 		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
 		}
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
-	// Construct and execute DELETE via storage function
-	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id = ?", tableName)
-	customLog.Printf("Handler: Executing Delete Record SQL for DB '%s', ID %d: %s", dbFilePath, recordID, deleteSQL)
+	// Execute DELETE via the user data store
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Deleting record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
 
-	_, err = storage.DeleteRecord(c.Request.Context(), userDB, deleteSQL, recordID)
+	_, err = userDB.DeleteRecord(c.Request.Context(), tableName, recordID)
 	if err != nil {
 		_ = c.Error(err)
 		// ErrTableNotFound might occur if race condition, but unlikely
 		if errors.Is(err, storage.ErrRecordNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Record not found for deletion."})
+			abortWithError(c, http.StatusNotFound, "Record not found for deletion.")
 		} else // From RowsAffected check in repo
-		{
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete record."})
+		if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to delete record.")
 		}
 		return
 	}
 
-	customLog.Printf("Handler: Successfully deleted record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully deleted record ID %d from DB '%s', Table '%s'", recordID, dbFilePath, tableName)
 	c.Status(http.StatusNoContent) // Use 204 No Content
 }
@@ -0,0 +1,148 @@
+// api/handlers/responses.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// busyRetryAfterSeconds is the Retry-After hint sent when a user database stays locked.
+const busyRetryAfterSeconds = 1
+
+// abortWithError ends the request with status and message and the status's default
+// error code. middleware.ErrorHandler writes the response.
+func abortWithError(c *gin.Context, status int, message string) {
+	_ = c.Error(models.NewAPIError(status, message))
+	c.Abort()
+}
+
+// requireUserID returns the ID of the authenticated user, aborting with 401 when the
+// request has none.
+func requireUserID(c *gin.Context) (string, bool) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusUnauthorized, "Authentication required")
+		return "", false
+	}
+	return userId, true
+}
+
+// abortDatabaseBusy answers with 503 when a user DB stayed locked past the retry budget.
+func abortDatabaseBusy(c *gin.Context) {
+	c.Header("Retry-After", strconv.Itoa(busyRetryAfterSeconds))
+	_ = c.Error(&models.APIError{Status: http.StatusServiceUnavailable, Code: models.ErrCodeDatabaseBusy, Message: "Database is busy, please retry shortly."})
+	c.Abort()
+}
+
+// apiVersion returns the API version of the route group serving the request (see
+// middleware.APIVersion), 1 when the route is unversioned.
+func apiVersion(c *gin.Context) int {
+	if version := requestctx.APIVersion(c); version > 0 {
+		return version
+	}
+	return 1
+}
+
+// pageEnvelope is the v2 shape of list responses: items under "data" next to the
+// pagination, which also tells whether more items follow.
+type pageEnvelope struct {
+	Data       any      `json:"data"`
+	Pagination pageMeta `json:"pagination"`
+}
+
+type pageMeta struct {
+	storage.PaginationMeta
+	HasMore    bool `json:"has_more"`
+	NextOffset *int `json:"next_offset"` // Null on the last page
+}
+
+func newPageMeta(pagination storage.PaginationMeta) pageMeta {
+	meta := pageMeta{PaginationMeta: pagination}
+	if next := pagination.Offset + pagination.Limit; next < pagination.Total {
+		meta.HasMore = true
+		meta.NextOffset = &next
+	}
+	return meta
+}
+
+// Values of the return query parameter of record writes.
+const (
+	returnRepresentation = "representation" // Include the stored record in the response
+	returnMinimal        = "minimal"        // Only acknowledge the write
+)
+
+// wantsRepresentation reads the return query parameter of a record write, falling back
+// to fallback when it is absent. Unknown values abort the request with 400.
+func wantsRepresentation(c *gin.Context, fallback bool) (bool, bool) {
+	switch c.Query("return") {
+	case "":
+		return fallback, true
+	case returnRepresentation:
+		return true, true
+	case returnMinimal:
+		return false, true
+	}
+	_ = c.Error(errors.New("invalid return parameter"))
+	abortWithError(c, http.StatusBadRequest, "Invalid 'return' parameter: use 'representation' or 'minimal'.")
+	return false, false
+}
+
+// requireSQLiteUserData rejects features that work on SQLite database files directly
+// (backups, maintenance, dumps...) when tenant data lives in another backend.
+func requireSQLiteUserData(c *gin.Context) bool {
+	if storage.UserDataBackend() == storage.BackendSQLite {
+		return true
+	}
+	_ = c.Error(storage.ErrUserDataUnsupported)
+	abortWithError(c, http.StatusNotImplemented, "This operation is only available when databases are stored as SQLite files.")
+	return false
+}
+
+// jsonWithETag writes obj as a 200 JSON response tagged with a weak ETag derived from
+// the encoded body, answering 304 Not Modified when If-None-Match already matches.
+func jsonWithETag(c *gin.Context, obj any) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to encode response.")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache") // Always revalidate
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches implements the weak comparison If-None-Match requires.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestJSONWithETagConditionalGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := gin.H{"id": 1, "body": "x"}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	jsonWithETag(c, body)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first GET = %d with ETag %q; want 200 and an ETag", w.Code, etag)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", `"other", `+etag)
+	jsonWithETag(c, body)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("conditional GET = %d with %d body bytes; want 304 and no body", w.Code, w.Body.Len())
+	}
+}
+
+func TestNewPageMeta(t *testing.T) {
+	meta := newPageMeta(storage.PaginationMeta{Total: 250, Limit: 100, Offset: 100})
+	if !meta.HasMore || meta.NextOffset == nil || *meta.NextOffset != 200 {
+		t.Errorf("middle page = %+v; want has_more and next_offset 200", meta)
+	}
+	meta = newPageMeta(storage.PaginationMeta{Total: 250, Limit: 100, Offset: 200})
+	if meta.HasMore || meta.NextOffset != nil {
+		t.Errorf("last page = %+v; want no next page", meta)
+	}
+}
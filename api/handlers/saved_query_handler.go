@@ -0,0 +1,147 @@
+// api/handlers/saved_query_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// ListSavedQueries returns the saved queries of a database.
+func (h *RecordHandler) ListSavedQueries(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	queries, err := h.MetaDB.ListSavedQueries(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"queries": queries})
+}
+
+// PutSavedQuery creates or replaces the saved query named in the path.
+func (h *RecordHandler) PutSavedQuery(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.PutSavedQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !core.IsValidIdentifier(req.TableName) {
+		_ = c.Error(errors.New("invalid table name format"))
+		abortWithError(c, http.StatusBadRequest, "Invalid table name format.")
+		return
+	}
+	query := domain.SavedQuery{
+		Name:        c.Param("query_name"),
+		TableName:   req.TableName,
+		Description: req.Description,
+		Query:       req.Query,
+		Params:      req.Params,
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), query.TableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", query.TableName))
+		}
+		return
+	}
+	if err := core.ValidateSavedQuery(query, columnTypes); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.MetaDB.PutSavedQuery(c.Request.Context(), target.ID, query); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	stored, err := h.MetaDB.GetSavedQuery(c.Request.Context(), target.ID, query.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Saved query '%s' on table '%s' in DB '%s' for UserID %s", query.Name, query.TableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteSavedQuery removes the saved query named in the path.
+func (h *RecordHandler) DeleteSavedQuery(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	name := c.Param("query_name")
+	if err := h.MetaDB.DeleteSavedQuery(c.Request.Context(), target.ID, name); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrSavedQueryNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Saved query '%s' not found.", name))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Deleted saved query '%s' in DB '%s' for UserID %s", name, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// RunSavedQuery runs the saved query named in the path with the request's query string
+// as arguments. The response is the same as listing the query's table with the bound
+// parameters.
+func (h *RecordHandler) RunSavedQuery(c *gin.Context) {
+	name := c.Param("query_name")
+	if !core.IsValidIdentifier(name) {
+		_ = c.Error(fmt.Errorf("%w: invalid query name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	query, err := h.MetaDB.GetSavedQuery(c.Request.Context(), target.ID, name)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrSavedQueryNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Saved query '%s' not found.", name))
+		}
+		return
+	}
+	values, err := core.BindSavedQuery(*query, c.Request.URL.Query())
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Running saved query '%s' on table '%s' in DB '%s'", name, query.TableName, target.Name)
+	c.Request.URL.RawQuery = values.Encode()
+	c.Params = append(c.Params, gin.Param{Key: "table_name", Value: query.TableName})
+	h.ListRecords(c)
+}
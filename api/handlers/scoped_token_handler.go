@@ -0,0 +1,69 @@
+// api/handlers/scoped_token_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+// Lifetimes of scoped access tokens.
+const (
+	defaultScopedTokenMinutes = 15
+	maxScopedTokenMinutes     = 60
+)
+
+// CreateScopedToken exchanges the caller's JWT or API key for a short-lived bearer token
+// limited to the database, optionally one of its tables, and read-only unless asked
+// otherwise. It can be handed to browsers or third parties without exposing the parent
+// credential. Tokens cannot be revoked; they expire after at most an hour.
+func (h *DatabaseHandler) CreateScopedToken(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreateScopedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	minutes := defaultScopedTokenMinutes
+	if req.ExpiresInMinutes != nil {
+		minutes = *req.ExpiresInMinutes
+	}
+	if minutes < 1 || minutes > maxScopedTokenMinutes {
+		_ = c.Error(fmt.Errorf("invalid scoped token expiry %d", minutes))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("'expires_in_minutes' must be between 1 and %d.", maxScopedTokenMinutes))
+		return
+	}
+	if req.Table != "" && !h.checkShareTarget(c, target, req.Table, "") {
+		return
+	}
+
+	scope := auth.TokenScope{UserID: target.UserID, DatabaseID: target.ID, Table: req.Table, ReadOnly: req.ReadOnly == nil || *req.ReadOnly}
+	expiresAt := time.Now().Add(time.Duration(minutes) * time.Minute).UTC().Truncate(time.Second)
+	token, err := auth.GenerateScopedToken(scope, h.Cfg.JWTSigningSecret(), expiresAt)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to generate token.")
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Minted scoped token for DB '%s' (table '%s', read-only %t) for UserID %s, expiring %s",
+		target.Name, req.Table, scope.ReadOnly, target.UserID, expiresAt.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"db_name":    target.Name,
+		"table":      req.Table,
+		"read_only":  scope.ReadOnly,
+		"expires_at": expiresAt,
+	})
+}
@@ -0,0 +1,110 @@
+// api/handlers/search_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+const (
+	defaultSearchLimit = 10  // Matches returned per table
+	maxSearchLimit     = 100 // Upper bound of the limit parameter
+	maxSearchTermRunes = 256
+)
+
+// tableMatches is the search result of one table.
+type tableMatches struct {
+	Table   string           `json:"table"`
+	Columns []string         `json:"columns"` // TEXT columns searched
+	Records []map[string]any `json:"records"`
+}
+
+// SearchDatabase searches the TEXT columns of every table of a database for the q
+// parameter, a case-insensitive substring, and returns matching records grouped by table.
+// tables restricts the search to a comma-separated list of tables.
+func (h *RecordHandler) SearchDatabase(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" || utf8.RuneCountInString(term) > maxSearchTermRunes {
+		_ = c.Error(fmt.Errorf("invalid search term length %d", len(term)))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Query parameter 'q' is required and may be at most %d characters.", maxSearchTermRunes))
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			_ = c.Error(fmt.Errorf("invalid search limit '%s'", raw))
+			abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid limit: must be between 1 and %d.", maxSearchLimit))
+			return
+		}
+		limit = parsed
+	}
+	var only map[string]bool
+	if raw := c.Query("tables"); raw != "" {
+		only = make(map[string]bool)
+		for _, name := range strings.Split(raw, ",") {
+			only[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+
+	tables, err := userDB.ListTables(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	results := make([]tableMatches, 0)
+	for _, table := range tables {
+		if !core.IsValidIdentifier(table.Name) || (only != nil && !only[strings.ToLower(table.Name)]) {
+			continue
+		}
+		columnTypes, err := userDB.ColumnTypes(c.Request.Context(), table.Name)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		var columns []string
+		for column, columnType := range columnTypes {
+			if columnType == "TEXT" {
+				columns = append(columns, column)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		sort.Strings(columns)
+
+		records, err := userDB.SearchText(c.Request.Context(), table.Name, columns, term, limit)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		if len(records) > 0 {
+			results = append(results, tableMatches{Table: table.Name, Columns: columns, Records: records})
+		}
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Search in DB '%s' matched %d table(s)", target.Name, len(results))
+	c.JSON(http.StatusOK, gin.H{"query": term, "results": results})
+}
@@ -0,0 +1,78 @@
+// api/handlers/seed_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// maxSeedUploadSize bounds seed documents.
+const maxSeedUploadSize = 32 << 20 // 32 MiB
+
+// SeedDatabase loads a seed document (see core.Seed) into a database: it creates the
+// tables the database lacks and inserts the rows. With reset=true every table is
+// dropped first, so integration tests can bring a database back to a known state
+// between runs. Only routed when SEED_API_ENABLED is set.
+func (h *DatabaseHandler) SeedDatabase(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	reset := c.Query("reset") == "true"
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSeedUploadSize))
+	if err != nil {
+		_ = c.Error(err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortWithError(c, http.StatusRequestEntityTooLarge, "Seed document is larger than 32 MiB.")
+		} else {
+			abortWithError(c, http.StatusBadRequest, "Failed to read seed document.")
+		}
+		return
+	}
+	seed, err := core.ParseSeed(data)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+
+	forget := func(ctx context.Context, tableName string) {
+		storage.ForgetTableMetadata(ctx, h.MetaDB, target.ID, tableName)
+	}
+	result, err := storage.LoadSeed(c.Request.Context(), userDB, seed, reset, forget)
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Failed to seed DB '%s' for UserID %s: %v", target.Name, target.UserID, err)
+		_ = c.Error(err)
+		switch {
+		case errors.Is(err, storage.ErrInvalidSeed):
+		case errors.Is(err, storage.ErrDatabaseBusy):
+			abortDatabaseBusy(c)
+		case errors.Is(err, storage.ErrConstraintViolation):
+			abortWithError(c, http.StatusConflict, "Constraint violation: "+err.Error())
+		default:
+			abortWithError(c, http.StatusInternalServerError, "Failed to load seed; the database may be partially seeded.")
+		}
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Seeded DB '%s' for UserID %s (reset %t)", target.Name, target.UserID, reset)
+	c.JSON(http.StatusOK, gin.H{"db_name": target.Name, "reset": reset, "result": result})
+}
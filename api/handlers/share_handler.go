@@ -0,0 +1,246 @@
+// api/handlers/share_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of share links.
+const (
+	maxShareLinksPerDatabase = 50
+	defaultShareHours        = 7 * 24
+	maxShareHours            = 365 * 24
+)
+
+// createdShareLink is a new share link with its token, which is only returned once.
+type createdShareLink struct {
+	domain.ShareLink
+	Token string `json:"token"`
+	Path  string `json:"path"` // Where the shared data is read, relative to the server
+}
+
+// ListShareLinks returns the unexpired share links of a database, without their tokens.
+func (h *DatabaseHandler) ListShareLinks(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	links, ok := h.activeShareLinks(c, target)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": links})
+}
+
+// CreateShareLink creates a read-only share link of a table or saved query and returns
+// its signed token.
+func (h *DatabaseHandler) CreateShareLink(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if (req.Table == "") == (req.Query == "") {
+		_ = c.Error(errors.New("share link needs a table or a query"))
+		abortWithError(c, http.StatusBadRequest, "Set exactly one of 'table' and 'query'.")
+		return
+	}
+	hours := defaultShareHours
+	if req.ExpiresInHours != nil {
+		hours = *req.ExpiresInHours
+	}
+	if hours < 1 || hours > maxShareHours {
+		_ = c.Error(fmt.Errorf("invalid share expiry %d", hours))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("'expires_in_hours' must be between 1 and %d.", maxShareHours))
+		return
+	}
+	if !h.checkShareTarget(c, target, req.Table, req.Query) {
+		return
+	}
+
+	existing, ok := h.activeShareLinks(c, target)
+	if !ok {
+		return
+	}
+	if len(existing) >= maxShareLinksPerDatabase {
+		_ = c.Error(errors.New("share link limit reached"))
+		abortWithError(c, http.StatusConflict, fmt.Sprintf("Database '%s' already has %d share links, the maximum.", target.Name, maxShareLinksPerDatabase))
+		return
+	}
+
+	link := domain.ShareLink{
+		ID:          uuid.New().String(),
+		DatabaseID:  target.ID,
+		Table:       req.Table,
+		Query:       req.Query,
+		Description: req.Description,
+		ExpiresAt:   time.Now().Add(time.Duration(hours) * time.Hour).UTC().Truncate(time.Second),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	token, err := auth.GenerateShareToken(link.ID, h.Cfg.JWTSigningSecret(), link.ExpiresAt)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to generate share token.")
+		return
+	}
+	if err := h.MetaDB.CreateShareLink(c.Request.Context(), link); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Created share link %s of %s in DB '%s' for UserID %s, expiring %s",
+		link.ID, shareSubject(link), target.Name, target.UserID, link.ExpiresAt.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, createdShareLink{ShareLink: link, Token: token, Path: "/share/" + token})
+}
+
+// DeleteShareLink revokes a share link; its token stops working right away.
+func (h *DatabaseHandler) DeleteShareLink(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	shareId := c.Param("share_id")
+	if err := h.MetaDB.DeleteShareLink(c.Request.Context(), target.ID, shareId); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Share link '%s' not found.", shareId))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Revoked share link %s in DB '%s' for UserID %s", shareId, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// activeShareLinks drops the expired share links of the target database and returns
+// the others.
+func (h *DatabaseHandler) activeShareLinks(c *gin.Context, target *targetDatabase) ([]domain.ShareLink, bool) {
+	if err := h.MetaDB.DeleteExpiredShareLinks(c.Request.Context(), target.ID, time.Now()); err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	links, err := h.MetaDB.ListShareLinks(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	return links, true
+}
+
+// checkShareTarget checks that the table or saved query to share (or to scope a token
+// to) exists.
+func (h *DatabaseHandler) checkShareTarget(c *gin.Context, target *targetDatabase, table, query string) bool {
+	if query != "" {
+		if !core.IsValidIdentifier(query) {
+			_ = c.Error(errors.New("invalid query name"))
+			abortWithError(c, http.StatusBadRequest, "Invalid query name.")
+			return false
+		}
+		if _, err := h.MetaDB.GetSavedQuery(c.Request.Context(), target.ID, query); err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrSavedQueryNotFound) {
+				abortWithError(c, http.StatusNotFound, fmt.Sprintf("Saved query '%s' not found.", query))
+			}
+			return false
+		}
+		return true
+	}
+
+	if !core.IsValidIdentifier(table) {
+		_ = c.Error(errors.New("invalid table name"))
+		abortWithError(c, http.StatusBadRequest, "Invalid table name.")
+		return false
+	}
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return false
+	}
+	defer userDB.Release()
+	if _, err := userDB.ColumnTypes(c.Request.Context(), table); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", table))
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve table schema.")
+		}
+		return false
+	}
+	return true
+}
+
+// shareSubject describes what a share link shares, for logs.
+func shareSubject(link domain.ShareLink) string {
+	if link.Query != "" {
+		return fmt.Sprintf("saved query '%s'", link.Query)
+	}
+	return fmt.Sprintf("table '%s'", link.Table)
+}
+
+// ViewShare serves the records of a share link to anyone holding its token. Shared
+// tables are listed with the request's list parameters (filters, sort, paging), and
+// shared saved queries run with the request's query string as arguments, exactly like
+// the authenticated endpoints; nothing can be written.
+func (h *RecordHandler) ViewShare(c *gin.Context) {
+	shareId, err := auth.ValidateShareToken(c.Param("token"), h.Cfg.JWTVerificationSecrets()...)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			abortWithError(c, http.StatusGone, "This share link has expired.")
+		} else {
+			abortWithError(c, http.StatusNotFound, "Share link not found.")
+		}
+		return
+	}
+	link, err := h.MetaDB.FindShareLink(c.Request.Context(), shareId)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrShareLinkNotFound) {
+			abortWithError(c, http.StatusNotFound, "Share link not found.")
+		}
+		return
+	}
+	db, err := h.MetaDB.FindDatabaseByID(c.Request.Context(), link.DatabaseID)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, "Share link not found.")
+		}
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Serving share link %s of %s in DB '%s'", link.ID, shareSubject(*link), db.DBName)
+	// The data is read as the database owner would read it, scoped to the database
+	requestctx.SetUserID(c, db.UserID)
+	requestctx.SetScopedDatabaseID(c, db.DatabaseID)
+	requestctx.SetTargetDatabaseID(c, db.DatabaseID)
+	c.Params = append(c.Params, gin.Param{Key: "db_name", Value: db.DBName})
+	if link.Query != "" {
+		c.Params = append(c.Params, gin.Param{Key: "query_name", Value: link.Query})
+		h.RunSavedQuery(c)
+		return
+	}
+	c.Params = append(c.Params, gin.Param{Key: "table_name", Value: link.Table})
+	h.ListRecords(c)
+}
@@ -0,0 +1,100 @@
+// api/handlers/signed_url_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+// Lifetimes of signed URLs.
+const (
+	defaultSignedURLMinutes = 60
+	maxSignedURLMinutes     = 7 * 24 * 60
+)
+
+// CreateSignedURL signs a URL that GETs a record, the records of a table or an export
+// of the database without an Authorization header, until it expires; e.g. for links in
+// emails or embedded downloads. The path is relative to the database and may carry
+// query parameters, which are signed with it.
+func (h *DatabaseHandler) CreateSignedURL(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreateSignedURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	minutes := defaultSignedURLMinutes
+	if req.ExpiresInMinutes != nil {
+		minutes = *req.ExpiresInMinutes
+	}
+	if minutes < 1 || minutes > maxSignedURLMinutes {
+		_ = c.Error(fmt.Errorf("invalid signed URL expiry %d", minutes))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("'expires_in_minutes' must be between 1 and %d.", maxSignedURLMinutes))
+		return
+	}
+
+	relative, err := url.Parse(strings.TrimPrefix(req.Path, "/"))
+	if err != nil || relative.Scheme != "" || relative.Host != "" {
+		_ = c.Error(fmt.Errorf("invalid signed URL path '%s'", req.Path))
+		abortWithError(c, http.StatusBadRequest, "Invalid path: give a path relative to the database.")
+		return
+	}
+	table, ok := signablePath(relative.Path)
+	if !ok {
+		_ = c.Error(fmt.Errorf("unsignable path '%s'", req.Path))
+		abortWithError(c, http.StatusBadRequest, "Only 'tables/{table}/records', 'tables/{table}/records/{record_id}' and 'export' can be signed.")
+		return
+	}
+	if table != "" && !h.checkShareTarget(c, target, table, "") {
+		return
+	}
+
+	path := fmt.Sprintf("/api/v%d/databases/%s/%s", requestctx.APIVersion(c), target.Name, relative.Path)
+	expiresAt := time.Now().Add(time.Duration(minutes) * time.Minute).UTC().Truncate(time.Second)
+	query := auth.SignURL(path, relative.Query(), target.UserID, h.Cfg.JWTSigningSecret(), expiresAt)
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Signed URL for '%s' in DB '%s' for UserID %s, expiring %s",
+		relative.Path, target.Name, target.UserID, expiresAt.Format(time.RFC3339))
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        path + "?" + query.Encode(),
+		"expires_at": expiresAt,
+	})
+}
+
+// signablePath reports whether a path relative to a database can be signed, and the
+// table it reads from, if any.
+func signablePath(path string) (table string, ok bool) {
+	segments := strings.Split(path, "/")
+	switch {
+	case len(segments) == 1 && segments[0] == "export":
+		return "", true
+	case (len(segments) == 3 || len(segments) == 4) && segments[0] == "tables" && segments[2] == "records":
+		if !core.IsValidIdentifier(segments[1]) {
+			return "", false
+		}
+		if len(segments) == 4 {
+			if _, err := strconv.ParseInt(segments[3], 10, 64); err != nil {
+				return "", false
+			}
+		}
+		return segments[1], true
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+// api/handlers/slow_query_handler.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetSlowQueries returns the slowest statement shapes run against a database since the
+// server started (or the insights were reset), slowest first. Only record operations
+// taking SLOW_QUERY_THRESHOLD_MS or more are captured, by the instance serving them.
+func (h *DatabaseHandler) GetSlowQueries(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"db_name":      target.Name,
+		"threshold_ms": h.Cfg.SlowQueryThreshold.Milliseconds(),
+		"slow_queries": storage.SlowQueries(target.FilePath),
+	})
+}
+
+// ResetSlowQueries forgets the slow statements captured for a database, e.g. after
+// adding an index, so that the insights only show what is still slow.
+func (h *DatabaseHandler) ResetSlowQueries(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	storage.ResetSlowQueries(target.FilePath)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Reset slow query insights of DB '%s' for UserID %s", target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
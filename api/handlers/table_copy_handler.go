@@ -0,0 +1,105 @@
+// api/handlers/table_copy_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// CopyTable recreates a table, and by default its rows, in another database of the
+// caller or under another name in the same one. Validation rules, JSON Schema and
+// script are not copied.
+func (h *TableHandler) CopyTable(c *gin.Context) {
+	if !requireSQLiteUserData(c) {
+		return
+	}
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	source, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CopyTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	targetTable := req.TargetTableName
+	if targetTable == "" {
+		targetTable = tableName
+	}
+	if !core.IsValidIdentifier(req.TargetDBName) || !core.IsValidIdentifier(targetTable) ||
+		strings.HasPrefix(strings.ToLower(targetTable), core.TrashedTablePrefix) {
+		_ = c.Error(errors.New("invalid target name format"))
+		abortWithError(c, http.StatusBadRequest, "Invalid target database or table name.")
+		return
+	}
+	if req.TargetDBName == source.Name && targetTable == tableName {
+		_ = c.Error(errors.New("table copy onto itself"))
+		abortWithError(c, http.StatusBadRequest, "Copying a table within its database needs a different target_table_name.")
+		return
+	}
+	// A DB-scoped API key only reaches its own database
+	if _, scoped, _ := requestctx.ScopedDatabaseID(c); scoped && req.TargetDBName != source.Name {
+		_ = c.Error(fmt.Errorf("%w: API key not valid for database '%s'", nebulaErrors.ErrForbidden, req.TargetDBName))
+		return
+	}
+	targetPath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), source.UserID, req.TargetDBName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrDatabaseNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Target database '%s' not found.", req.TargetDBName))
+		}
+		return
+	}
+	includeData := req.IncludeData == nil || *req.IncludeData
+
+	dstDB, err := storage.ConnectUserDB(c.Request.Context(), targetPath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer storage.ReleaseUserDB(dstDB)
+
+	copied, err := storage.CopyTable(c.Request.Context(), source.FilePath, tableName, dstDB, targetTable, includeData)
+	if err != nil {
+		_ = c.Error(err)
+		switch {
+		case errors.Is(err, storage.ErrTableNotFound):
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		case errors.Is(err, storage.ErrTableExists):
+			abortWithError(c, http.StatusConflict, fmt.Sprintf("Table '%s' already exists in database '%s'.", targetTable, req.TargetDBName))
+		case errors.Is(err, storage.ErrDatabaseBusy):
+			abortDatabaseBusy(c)
+		default:
+			abortWithError(c, http.StatusInternalServerError, "Failed to copy table.")
+		}
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Copied table '%s' of DB '%s' to '%s.%s' (%d rows) for UserID %s", tableName, source.Name, req.TargetDBName, targetTable, copied, source.UserID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Table copied successfully",
+		"db_name":      req.TargetDBName,
+		"table_name":   targetTable,
+		"rows_copied":  copied,
+		"include_data": includeData,
+	})
+}
@@ -0,0 +1,131 @@
+// api/handlers/table_description_handler.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetTableDescription returns the descriptions of a table and its columns.
+func (h *TableHandler) GetTableDescription(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if _, ok := h.requireTable(c, target, tableName); !ok {
+		return
+	}
+
+	description, err := h.MetaDB.GetTableDescription(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "description": description.Description, "columns": description.Columns})
+}
+
+// SetTableDescription replaces the descriptions of a table and its columns. Columns
+// left out or given an empty description lose theirs.
+func (h *TableHandler) SetTableDescription(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.SetTableDescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	columnTypes, ok := h.requireTable(c, target, tableName)
+	if !ok {
+		return
+	}
+	description, err := core.ValidateTableDescription(domain.TableDescription{Description: req.Description, Columns: req.Columns}, columnTypes)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.MetaDB.ReplaceTableDescription(c.Request.Context(), target.ID, tableName, description); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set descriptions of table '%s' in DB '%s' for UserID %s", tableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{"table_name": tableName, "description": description.Description, "columns": description.Columns})
+}
+
+// requestedTableDescription validates the descriptions given in a request creating a
+// table with the validated columns specs.
+func requestedTableDescription(req *models.CreateSchemaRequest, specs []core.ColumnSpec) (domain.TableDescription, error) {
+	columns := req.Columns
+	if len(columns) == 0 {
+		columns = req.Schema
+	}
+	description := domain.TableDescription{Description: req.Description, Columns: make(map[string]string)}
+	for _, col := range columns {
+		if col.Description != "" {
+			description.Columns[col.Name] = col.Description
+		}
+	}
+	columnTypes := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		columnTypes[strings.ToLower(spec.Name)] = spec.Type
+	}
+	return core.ValidateTableDescription(description, columnTypes)
+}
+
+// storeCreatedTableDescription stores the descriptions given for a table just created,
+// if there are any. On failure the request is aborted.
+func storeCreatedTableDescription(c *gin.Context, metaDB storage.MetadataStore, userId, dbName, tableName string, description domain.TableDescription) bool {
+	if description.Description == "" && len(description.Columns) == 0 {
+		return true
+	}
+	databaseID, err := metaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
+	if err == nil {
+		err = metaDB.ReplaceTableDescription(c.Request.Context(), databaseID, tableName, description)
+	}
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, fmt.Sprintf("Table '%s' was created, but storing its descriptions failed.", tableName))
+		return false
+	}
+	return true
+}
+
+// describeTables adds the stored descriptions of a database to its listed tables.
+func describeTables(tables []domain.TableMetadata, descriptions map[string]domain.TableDescription) {
+	for i := range tables {
+		description, ok := descriptions[tables[i].Name]
+		if !ok {
+			continue
+		}
+		tables[i].Description = description.Description
+		for j := range tables[i].Columns {
+			tables[i].Columns[j].Description = description.Columns[strings.ToLower(tables[i].Columns[j].Name)]
+		}
+	}
+}
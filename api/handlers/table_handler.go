@@ -2,15 +2,14 @@
 package handlers
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
 	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
 	"github.com/Annany2002/nebula-backend/internal/core"
@@ -19,12 +18,12 @@ import (
 
 // TableHandler holds dependencies for table management handlers.
 type TableHandler struct {
-	MetaDB *sql.DB        // Metadata DB pool (needed for path lookup)
-	Cfg    *config.Config // App configuration (needed for?) - maybe not needed here directly
+	MetaDB storage.MetadataStore // Metadata DB pool (needed for path lookup)
+	Cfg    *config.Config        // App configuration (needed for?) - maybe not needed here directly
 }
 
 // NewTableHandler creates a new TableHandler.
-func NewTableHandler(metaDB *sql.DB, cfg *config.Config) *TableHandler {
+func NewTableHandler(metaDB storage.MetadataStore, cfg *config.Config) *TableHandler {
 	return &TableHandler{
 		MetaDB: metaDB,
 		Cfg:    cfg, // Pass config if needed later
@@ -33,9 +32,16 @@ func NewTableHandler(metaDB *sql.DB, cfg *config.Config) *TableHandler {
 
 // --- Helper for common auth check and user DB connection ---
 // Similar to RecordHandler's helper
-func (h *TableHandler) checkScopeAndGetUserDB(c *gin.Context) (*sql.DB, string, error) {
-	authUserID := c.MustGet("userId").(string)
-	authDatabaseIDValue, _ := c.Get("databaseId") // nil if JWT/user-key
+func (h *TableHandler) checkScopeAndGetUserDB(c *gin.Context) (storage.UserDataStore, string, error) {
+	authUserID, err := requestctx.UserID(c)
+	if err != nil {
+		return nil, "", err
+	}
+	authDatabaseID, scoped, err := requestctx.ScopedDatabaseID(c) // Not scoped for JWTs and user keys
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("ERROR: Invalid databaseID in context for UserID %s: %v", authUserID, err)
+		return nil, "", err
+	}
 	targetDbName := c.Param("db_name")
 
 	if !core.IsValidIdentifier(targetDbName) {
@@ -43,35 +49,28 @@ func (h *TableHandler) checkScopeAndGetUserDB(c *gin.Context) (*sql.DB, string,
 	}
 
 	// Verify user owns the target DB and get its actual ID
-	targetDatabaseID, err := storage.FindDatabaseIDByNameAndUser(c.Request.Context(), h.MetaDB, authUserID, targetDbName)
+	targetDatabaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), authUserID, targetDbName)
 	if err != nil {
 		// Propagate ErrDatabaseNotFound or other DB errors
 		return nil, "", err
 	}
 
 	// If using a DB-scoped key, ensure it matches the target DB
-	if authDatabaseIDValue != nil {
-		authDatabaseID, ok := authDatabaseIDValue.(int64)
-		if !ok { // Should not happen
-			customLog.Warnf("ERROR: Invalid databaseID type in context for UserID %s", authUserID)
-			return nil, "", fmt.Errorf("%w: internal authorization error", nebulaErrors.ErrInternalServer)
-		}
-		if authDatabaseID != targetDatabaseID {
-			customLog.Warnf("Handler: FORBIDDEN - User %s API key for DBID %d attempted table operation on DB '%s' (ID %d)", authUserID, authDatabaseID, targetDbName, targetDatabaseID)
-			return nil, "", fmt.Errorf("%w: API key not valid for database '%s'", nebulaErrors.ErrForbidden, targetDbName)
-		}
+	if scoped && authDatabaseID != targetDatabaseID {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: FORBIDDEN - User %s API key for DBID %d attempted table operation on DB '%s' (ID %d)", authUserID, authDatabaseID, targetDbName, targetDatabaseID)
+		return nil, "", fmt.Errorf("%w: API key not valid for database '%s'", nebulaErrors.ErrForbidden, targetDbName)
 	}
 	// If JWT/user-key OR if DB-scoped key matches target, proceed
 
 	// Get the file path using the confirmed user/dbName combo
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, authUserID, targetDbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), authUserID, targetDbName)
 	if err != nil {
 		// Should generally not happen if FindDatabaseIDByNameAndUser succeeded, but check anyway
 		return nil, "", err
 	}
 
-	// Connect to the user's DB file
-	userDB, err := storage.ConnectUserDB(c.Request.Context(), dbFilePath)
+	// Connect to the user's DB
+	userDB, err := storage.OpenUserData(c.Request.Context(), dbFilePath)
 	if err != nil {
 		return nil, "", err
 	}
@@ -82,17 +81,17 @@ func (h *TableHandler) checkScopeAndGetUserDB(c *gin.Context) (*sql.DB, string,
 }
 
 // processSchemaRequest common logic for CreateSchema and CreateTable
-func (h *TableHandler) processSchemaRequest(c *gin.Context, dbName, dbFilePath string) {
+func (h *TableHandler) processSchemaRequest(c *gin.Context, userId, dbName, dbFilePath string) {
 	var req models.CreateSchemaRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		_ = c.Error(fmt.Errorf("binding error: %w", err))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	if !core.IsValidIdentifier(req.TableName) {
 		_ = c.Error(errors.New("invalid table name format"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid table name format."})
+		abortWithError(c, http.StatusBadRequest, "Invalid table name format.")
 		return
 	}
 
@@ -104,53 +103,57 @@ func (h *TableHandler) processSchemaRequest(c *gin.Context, dbName, dbFilePath s
 
 	if len(columns) == 0 {
 		_ = c.Error(errors.New("no columns provided"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "No columns provided in 'columns' or 'schema' field."})
+		abortWithError(c, http.StatusBadRequest, "No columns provided in 'columns' or 'schema' field.")
 		return
 	}
 
-	var columnDefs []string
-	columnNames := make(map[string]bool)
-
-	for _, col := range columns {
-		colNameLower := strings.ToLower(col.Name)
-		if !core.IsValidIdentifier(col.Name) || colNameLower == "id" {
-			_ = c.Error(fmt.Errorf("invalid column name: %s", col.Name))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid column name '%s'. Use valid identifiers, cannot be 'id'.", col.Name)})
-			return
-		}
-		if columnNames[colNameLower] {
-			_ = c.Error(fmt.Errorf("duplicate column name: %s", col.Name))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Duplicate column name '%s'.", col.Name)})
-			return
-		}
-		columnNames[colNameLower] = true
-
-		normalizedType, ok := core.NormalizeAndValidateType(col.Type)
-		if !ok {
-			_ = c.Error(fmt.Errorf("invalid column type: %s", col.Type))
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid type '%s' for column '%s'.", col.Type, col.Name)})
+	specs := make([]core.ColumnSpec, len(columns))
+	for i, col := range columns {
+		specs[i] = core.ColumnSpec{Name: col.Name, Type: col.Type, Default: col.Default, Collate: col.Collate}
+	}
+	specs, err := core.ValidateTableDefinition(req.TableName, specs)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Strict {
+		if err := core.ValidateStrictColumns(specs); err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", col.Name, normalizedType))
 	}
-
-	userDB, err := storage.ConnectUserDB(c.Request.Context(), dbFilePath)
+	description, err := requestedTableDescription(&req, specs)
 	if err != nil {
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to access database storage."})
+		abortWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer userDB.Close()
 
-	createTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s , created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);",
-		req.TableName,
-		strings.Join(columnDefs, ", "),
-	)
+	userDB, err := storage.OpenUserData(c.Request.Context(), dbFilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
 
-	err = storage.CreateTable(c.Request.Context(), userDB, createTableSQL)
+	if req.Strict {
+		err = userDB.CreateStrictTable(c.Request.Context(), req.TableName, specs)
+	} else {
+		err = userDB.CreateTable(c.Request.Context(), req.TableName, specs)
+	}
 	if err != nil {
 		_ = c.Error(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create table."})
+		if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+			return
+		}
+		abortWithError(c, http.StatusInternalServerError, "Failed to create table.")
+		return
+	}
+	if !storeCreatedTableDescription(c, h.MetaDB, userId, dbName, req.TableName, description) {
 		return
 	}
 
@@ -163,27 +166,31 @@ func (h *TableHandler) processSchemaRequest(c *gin.Context, dbName, dbFilePath s
 
 // CreateTable handles requests to create a new table.
 func (h *TableHandler) CreateTable(c *gin.Context) {
-	userId := c.MustGet("userId").(string)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	dbName := c.Param("db_name")
 
 	if !core.IsValidIdentifier(dbName) {
 		_ = c.Error(errors.New("invalid db_name in path"))
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid database name in URL path."})
+		abortWithError(c, http.StatusBadRequest, "Invalid database name in URL path.")
 		return
 	}
 
-	dbFilePath, err := storage.FindDatabasePath(c.Request.Context(), h.MetaDB, userId, dbName)
+	dbFilePath, err := h.MetaDB.FindDatabasePath(c.Request.Context(), userId, dbName)
 	if err != nil {
 		_ = c.Error(err)
 		if errors.Is(err, storage.ErrDatabaseNotFound) {
-			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found or not registered."})
-		} else {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve database information."})
+			abortWithError(c, http.StatusNotFound, "Database not found or not registered.")
+		} else if !errors.Is(err, storage.ErrDatabaseQuarantined) {
+			abortWithError(c, http.StatusInternalServerError, "Failed to retrieve database information.")
 		}
 		return
 	}
 
-	h.processSchemaRequest(c, dbName, dbFilePath)
+	h.processSchemaRequest(c, userId, dbName, dbFilePath)
 }
 
 // ListTables handles requests to list tables within a specific user database.
@@ -193,16 +200,33 @@ func (h *TableHandler) ListTablesFn(c *gin.Context) {
 		_ = c.Error(err) // Let middleware handle response mapping
 		return
 	}
-	defer userDb.Close()
+	defer userDb.Release()
 
-	tables, err := storage.ListTables(c.Request.Context(), userDb)
+	tables, err := userDb.ListTables(c.Request.Context())
 	if err != nil {
-		customLog.Warnf("Handler: Error listing tables for DB %s: %v", dbName, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Error listing tables for DB %s: %v", dbName, err)
 		_ = c.Error(err)
 		return
 	}
 
-	customLog.Printf("Handler: Retrieved %d table(s) for DB %s", len(tables), dbName)
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	descriptions, err := h.MetaDB.ListTableDescriptions(c.Request.Context(), databaseID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	describeTables(tables, descriptions)
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Retrieved %d table(s) for DB %s", len(tables), dbName)
 	c.JSON(http.StatusOK, gin.H{"tables": tables})
 }
 
@@ -222,18 +246,29 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 		_ = c.Error(err)
 		return
 	}
-	defer userDB.Close()
+	defer userDB.Release()
 
-	customLog.Printf("Handler: Attempting to drop table '%s' in DB '%s'", targetTableName, dbName)
-	err = storage.DropTable(c.Request.Context(), userDB, targetTableName)
-	if err != nil {
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Attempting to drop table '%s' in DB '%s'", targetTableName, dbName)
+	if h.Cfg.TrashRetention > 0 {
+		// Kept restorable in the trash until the retention window ends
+		if !h.trashTable(c, userDB, dbName, targetTableName) {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Error moving table '%s' in DB '%s' to the trash", targetTableName, dbName)
+			return
+		}
+	} else if err = userDB.DropTable(c.Request.Context(), targetTableName); err != nil {
 		// DropTable uses DROP IF EXISTS, so errors are likely more serious
-		customLog.Warnf("Handler: Error dropping table '%s' in DB '%s': %v", targetTableName, dbName, err)
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Error dropping table '%s' in DB '%s': %v", targetTableName, dbName, err)
 		_ = c.Error(err)
 		return
 	}
 
-	customLog.Printf("Handler: Successfully dropped table '%s' in DB '%s'", targetTableName, dbName)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Successfully dropped table '%s' in DB '%s'", targetTableName, dbName)
+
+	// A table created later under the same name starts without rules, JSON schema, script, options or descriptions
+	userId, _ := requestctx.UserID(c) // Checked by checkScopeAndGetUserDB
+	if databaseID, err := h.MetaDB.FindDatabaseIDByNameAndUser(c.Request.Context(), userId, dbName); err == nil {
+		storage.ForgetTableMetadata(c.Request.Context(), h.MetaDB, databaseID, targetTableName)
+	}
 
 	c.Status(http.StatusNoContent) // Return 204 No Content on success
 }
@@ -0,0 +1,308 @@
+// api/handlers/table_options_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// maxAutoColumns caps the columns a table can grow to through auto-columns.
+const maxAutoColumns = 200
+
+// GetTableOptions returns the behaviour switches of a table.
+func (h *TableHandler) GetTableOptions(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if _, ok := h.requireTable(c, target, tableName); !ok {
+		return
+	}
+
+	options, err := h.MetaDB.GetTableOptions(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, h.tableOptionsResponse(tableName, options))
+}
+
+// SetTableOptions changes the behaviour switches of a table given in the request.
+func (h *TableHandler) SetTableOptions(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.SetTableOptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	columnTypes, ok := h.requireTable(c, target, tableName)
+	if !ok {
+		return
+	}
+
+	options, err := h.MetaDB.GetTableOptions(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if req.AutoColumns != nil {
+		options.AutoColumns = *req.AutoColumns
+	}
+	if req.DefaultSort != nil {
+		options.DefaultSort = *req.DefaultSort
+	}
+	if req.DefaultOrder != nil {
+		options.DefaultOrder = strings.ToLower(*req.DefaultOrder)
+	}
+	if req.MaxRows != nil {
+		options.MaxRows = *req.MaxRows
+	}
+	if err := validateDefaultSort(&options, columnTypes); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateMaxRows(options.MaxRows, h.Cfg.TableMaxRows); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.MetaDB.SetTableOptions(c.Request.Context(), target.ID, tableName, options); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set options %+v on table '%s' in DB '%s' for UserID %s", options, tableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, h.tableOptionsResponse(tableName, options))
+}
+
+// tableOptionsResponse returns options with the row limit that applies to the table.
+func (h *TableHandler) tableOptionsResponse(tableName string, options domain.TableOptions) models.TableOptionsResponse {
+	limit, _ := storage.RowLimit(h.Cfg.TableMaxRows, options)
+	return models.TableOptionsResponse{TableName: tableName, TableOptions: options, EffectiveMaxRows: limit}
+}
+
+// validateDefaultSort checks the default sort of options against the columns of the
+// table, normalizing the order: "asc" unless given with a sort column, "" without one.
+func validateDefaultSort(options *domain.TableOptions, columnTypes map[string]string) error {
+	if options.DefaultSort == "" {
+		options.DefaultOrder = ""
+		return nil
+	}
+	if _, exists := columnTypes[strings.ToLower(options.DefaultSort)]; !exists || !core.IsValidIdentifier(options.DefaultSort) {
+		return fmt.Errorf("default_sort: column '%s' not found in table schema", options.DefaultSort)
+	}
+	switch options.DefaultOrder {
+	case "":
+		options.DefaultOrder = core.DefaultOrder
+	case "asc", "desc":
+	default:
+		return errors.New("default_order: must be 'asc' or 'desc'")
+	}
+	return nil
+}
+
+// validateMaxRows checks the row limit of a table against the server-wide one, which
+// it can only lower.
+func validateMaxRows(maxRows, serverMax int64) error {
+	if maxRows < 0 {
+		return errors.New("max_rows: must be 0 or more")
+	}
+	if serverMax > 0 && maxRows > serverMax {
+		return fmt.Errorf("max_rows: cannot exceed the server's limit of %d rows", serverMax)
+	}
+	return nil
+}
+
+// requireTable aborts with 404 unless the target database has the table, and returns
+// the table's column types.
+func (h *TableHandler) requireTable(c *gin.Context, target *targetDatabase, tableName string) (map[string]string, bool) {
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return nil, false
+	}
+	defer userDB.Release()
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		}
+		return nil, false
+	}
+	return columnTypes, true
+}
+
+// planAutoColumns returns a column for every key of record that the table lacks when
+// the table has auto-columns on, with the type inferred from the key's value, and
+// records the planned columns in columnTypes so the record validates against them.
+// Tables in strict mode (the default) get none, so unknown keys are rejected later.
+// Call it after the JSON schema check; nothing is altered until addAutoColumns. On
+// failure the request is aborted.
+func (h *RecordHandler) planAutoColumns(c *gin.Context, tableName string, columnTypes map[string]string, record map[string]any) ([]core.ColumnSpec, bool) {
+	var added []core.ColumnSpec
+	for key, value := range record {
+		name := strings.ToLower(key)
+		if _, exists := columnTypes[name]; exists || !core.IsValidIdentifier(key) || name == "id" {
+			continue
+		}
+		added = append(added, core.ColumnSpec{Name: name, Type: core.InferValueType(value)})
+	}
+	if len(added) == 0 {
+		return nil, true
+	}
+
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return nil, false
+	}
+	options, err := h.MetaDB.GetTableOptions(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load table options.")
+		return nil, false
+	}
+	if !options.AutoColumns {
+		return nil, true
+	}
+	if len(columnTypes)+len(added) > maxAutoColumns {
+		_ = c.Error(fmt.Errorf("auto-columns would grow table '%s' past %d columns", tableName, maxAutoColumns))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Cannot add columns: tables are limited to %d columns.", maxAutoColumns))
+		return nil, false
+	}
+	for _, col := range added {
+		columnTypes[col.Name] = col.Type
+	}
+
+	// Objects and arrays for the new TEXT columns are stored as JSON, like for existing ones
+	if err := core.EncodeJSONValues(columnTypes, record); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return added, true
+}
+
+// addAutoColumns adds the columns planned by planAutoColumns to the table. Call it
+// once the record passed every check, right before it is written, so a rejected
+// record leaves the schema unchanged. On failure the request is aborted.
+func (h *RecordHandler) addAutoColumns(c *gin.Context, userDB storage.UserDataStore, tableName string, added []core.ColumnSpec) bool {
+	if len(added) == 0 {
+		return true
+	}
+	if err := userDB.AddColumns(c.Request.Context(), tableName, added); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		} else if errors.Is(err, storage.ErrTypeMismatch) {
+			abortWithError(c, http.StatusBadRequest, "Cannot add columns: "+err.Error())
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to add columns.")
+		}
+		return false
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Added %d auto-column(s) to table '%s'", len(added), tableName)
+	return true
+}
+
+// enforceRowLimit aborts the request when adding rows to tableName would grow it past
+// its row limit (see storage.RowLimit). userDB is nil for a table about to be created.
+func (h *RecordHandler) enforceRowLimit(c *gin.Context, userDB storage.UserDataStore, tableName string, adding int) bool {
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	options, err := h.MetaDB.GetTableOptions(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load table options.")
+		return false
+	}
+	limit, configured := storage.RowLimit(h.Cfg.TableMaxRows, options)
+	if err := storage.CheckRowLimit(c.Request.Context(), userDB, tableName, limit, configured, int64(adding)); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrRowLimitReached) {
+			customLog.Ctx(c.Request.Context()).Warnf("Handler: Rejected %d row(s) for table '%s': %v", adding, tableName, err)
+			c.Abort()
+		} else if errors.Is(err, storage.ErrDatabaseBusy) {
+			abortDatabaseBusy(c)
+		} else {
+			abortWithError(c, http.StatusInternalServerError, "Failed to count table rows.")
+		}
+		return false
+	}
+	return true
+}
+
+// applyDefaultSort sorts a listing by the table's default sort when the request names
+// no sort column; an order given in the request still applies. A default whose column
+// was dropped since is ignored. On failure the request is aborted.
+func (h *RecordHandler) applyDefaultSort(c *gin.Context, userDB storage.UserDataStore, tableName string, queryParams url.Values, opts *core.ListQueryOptions) bool {
+	if opts.SortBy != "" {
+		return true
+	}
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return false
+	}
+	options, err := h.MetaDB.GetTableOptions(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load table options.")
+		return false
+	}
+	if options.DefaultSort == "" {
+		return true
+	}
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		abortListRecordsError(c, tableName, err)
+		return false
+	}
+	if _, exists := columnTypes[strings.ToLower(options.DefaultSort)]; !exists {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Ignoring default sort of table '%s' on missing column '%s'", tableName, options.DefaultSort)
+		return true
+	}
+	opts.SortBy = options.DefaultSort
+	if queryParams.Get("order") == "" {
+		opts.SortOrder = options.DefaultOrder
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+// api/handlers/table_profile_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of the profile query parameters.
+const (
+	defaultProfileSample = 1000
+	maxProfileSample     = 10000
+	defaultProfileTop    = 5
+	maxProfileTop        = 50
+)
+
+// ProfileTable samples the first rows of a table (by id) and reports, per column, the
+// fill rate, how consistently values match the declared type, the number of distinct
+// values and the most frequent ones.
+// Query parameters: sample=<rows> (default 1000, max 10000), top=<values> (default 5, max 50).
+func (h *TableHandler) ProfileTable(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	sample, ok := boundedQueryInt(c, "sample", defaultProfileSample, maxProfileSample)
+	if !ok {
+		return
+	}
+	top, ok := boundedQueryInt(c, "top", defaultProfileTop, maxProfileTop)
+	if !ok {
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+
+	columnTypes, err := userDB.ColumnTypes(c.Request.Context(), tableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		}
+		return
+	}
+
+	profiler := core.NewProfiler(columnTypes)
+	totalRows := 0
+	opts := &core.ListQueryOptions{Limit: sample, SortOrder: core.DefaultOrder}
+	err = userDB.StreamRecords(c.Request.Context(), tableName, url.Values{}, opts,
+		func(pagination storage.PaginationMeta) error {
+			totalRows = pagination.Total
+			return nil
+		},
+		func(record map[string]any) error {
+			profiler.Add(record)
+			return nil
+		})
+	if err != nil {
+		abortListRecordsError(c, tableName, err)
+		return
+	}
+
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Profiled %d row(s) of table '%s' in DB '%s' for UserID %s", profiler.Rows(), tableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"table_name":   tableName,
+		"total_rows":   totalRows,
+		"sampled_rows": profiler.Rows(),
+		"columns":      profiler.Profiles(top),
+	})
+}
+
+// boundedQueryInt parses an optional integer query parameter between 1 and max,
+// aborting with 400 when it is invalid.
+func boundedQueryInt(c *gin.Context, name string, fallback, max int) (int, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return fallback, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 || value > max {
+		_ = c.Error(fmt.Errorf("invalid %s '%s'", name, raw))
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s: must be between 1 and %d.", name, max))
+		return 0, false
+	}
+	return value, true
+}
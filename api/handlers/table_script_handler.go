@@ -0,0 +1,186 @@
+// api/handlers/table_script_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	nebulaErrors "github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// GetTableScript returns the script run around record writes to a table.
+func (h *TableHandler) GetTableScript(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	source, err := h.MetaDB.GetTableScript(c.Request.Context(), target.ID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableScriptNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' has no script.", tableName))
+		}
+		return
+	}
+	script, err := scripting.Compile(source, recordwrite.ScriptLimits(h.Cfg))
+	if err != nil { // Limits may have been lowered since it was stored
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Stored script of table '%s' no longer compiles: %v", tableName, err)
+	}
+	c.JSON(http.StatusOK, models.TableScriptResponse{TableName: tableName, Source: source, Hooks: definedHooks(script)})
+}
+
+// SetTableScript stores a table's script after checking that it compiles and that its
+// top-level code runs within the limits.
+func (h *TableHandler) SetTableScript(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.SetTableScriptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	script, err := scripting.Compile(req.Source, recordwrite.ScriptLimits(h.Cfg))
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userDB, err := storage.OpenUserData(c.Request.Context(), target.FilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+		return
+	}
+	defer userDB.Release()
+	if _, err := userDB.ColumnTypes(c.Request.Context(), tableName); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' not found.", tableName))
+		}
+		return
+	}
+
+	if err := h.MetaDB.SetTableScript(c.Request.Context(), target.ID, tableName, req.Source); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Set script on table '%s' in DB '%s' for UserID %s", tableName, target.Name, target.UserID)
+	c.JSON(http.StatusOK, models.TableScriptResponse{TableName: tableName, Source: req.Source, Hooks: definedHooks(script)})
+}
+
+// DeleteTableScript stops running a script around record writes to a table.
+func (h *TableHandler) DeleteTableScript(c *gin.Context) {
+	tableName := c.Param("table_name")
+	if !core.IsValidIdentifier(tableName) {
+		_ = c.Error(fmt.Errorf("%w: invalid table name in URL path", nebulaErrors.ErrBadRequest))
+		return
+	}
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.MetaDB.DeleteTableScript(c.Request.Context(), target.ID, tableName); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrTableScriptNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Table '%s' has no script.", tableName))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Removed script of table '%s' in DB '%s' for UserID %s", tableName, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+func definedHooks(script *scripting.Script) []string {
+	hooks := []string{}
+	if script == nil {
+		return hooks
+	}
+	for _, hook := range scripting.Hooks {
+		if script.Defines(hook) {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// loadTableScript returns the compiled script of the table addressed by the request, nil
+// when it has none. On failure the request is aborted and ok is false.
+func (h *RecordHandler) loadTableScript(c *gin.Context, tableName string) (script *scripting.Script, ok bool) {
+	databaseID, err := h.requestDatabaseID(c)
+	if err != nil {
+		_ = c.Error(err)
+		c.Abort()
+		return nil, false
+	}
+	script, err = recordwrite.LoadTableScript(c.Request.Context(), h.MetaDB, h.Cfg, databaseID, tableName)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to load table script.")
+		return nil, false
+	}
+	return script, true
+}
+
+// runBeforeHook passes record through a before hook of script (which may be nil) and
+// returns the record to write. A rejection or failure aborts the request.
+func (h *RecordHandler) runBeforeHook(c *gin.Context, script *scripting.Script, hook, tableName string, recordID int64, record map[string]any) (map[string]any, bool) {
+	out, err := recordwrite.RunBeforeHook(c.Request.Context(), h.Cfg, script, hook, tableName, recordID, record)
+	if err == nil {
+		return out, true
+	}
+
+	_ = c.Error(err)
+	var reject *scripting.RejectError
+	switch {
+	case errors.As(err, &reject):
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Table script hook %s rejected a write to table '%s': %s", hook, tableName, reject.Message)
+		_ = c.Error(&models.APIError{
+			Status:  http.StatusBadRequest,
+			Code:    models.ErrCodeValidationFailed,
+			Message: reject.Message,
+			Details: gin.H{"hook": hook},
+		})
+		c.Abort()
+	case errors.Is(err, recordwrite.ErrEmptyHookRecord):
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Table script hook %s returned an empty record.", hook))
+	case errors.Is(err, scripting.ErrScriptTimeout):
+		abortWithError(c, http.StatusBadRequest, fmt.Sprintf("Table script hook %s exceeded its time limit.", hook))
+	default:
+		c.Abort() // Request cancelled; ErrorHandler maps the context error
+	}
+	return nil, false
+}
+
+// runAfterHook runs an after hook of script (which may be nil) with the stored record.
+// The write is already committed, so failures are only logged.
+func (h *RecordHandler) runAfterHook(c *gin.Context, script *scripting.Script, hook string, userDB storage.UserDataStore, tableName string, recordID int64) {
+	recordwrite.RunAfterHook(c.Request.Context(), h.Cfg, script, hook, userDB, tableName, recordID)
+}
@@ -0,0 +1,218 @@
+// api/handlers/trash_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// trashDatabase moves a database being deleted to the trash and removes its
+// registration. Registration-scoped settings (API key, labels, table settings, saved
+// queries) are not kept. Returns false after aborting the request on failure.
+func (h *DatabaseHandler) trashDatabase(c *gin.Context, userId, dbName, dbFilePath string) bool {
+	ctx := c.Request.Context()
+	trashed, err := storage.TrashUserData(ctx, dbFilePath)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to move database to the trash.")
+		return false
+	}
+	restore := func() {
+		if err := storage.RestoreUserData(ctx, trashed, dbFilePath); err != nil {
+			customLog.Ctx(ctx).Warnf("Handler: Failed to move DB '%s' of UserID %s back from the trash at '%s': %v", dbName, userId, trashed, err)
+		}
+	}
+
+	trashID, err := h.MetaDB.AddTrashItem(ctx, domain.TrashItem{
+		UserID:        userId,
+		DBName:        dbName,
+		Location:      dbFilePath,
+		TrashLocation: trashed,
+		ExpiresAt:     time.Now().Add(h.Cfg.TrashRetention),
+	})
+	if err != nil {
+		restore()
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to move database to the trash.")
+		return false
+	}
+
+	if err := h.MetaDB.DeleteDatabaseRegistration(ctx, userId, dbName); err != nil && !errors.Is(err, storage.ErrDatabaseNotFound) {
+		if err := h.MetaDB.DeleteTrashItem(ctx, trashID); err != nil {
+			customLog.Ctx(ctx).Warnf("Handler: Failed to remove trash item %d: %v", trashID, err)
+		}
+		restore()
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to delete database registration.")
+		return false
+	}
+	customLog.Ctx(ctx).Printf("Handler: Moved DB '%s' of UserID %s to the trash (item %d)", dbName, userId, trashID)
+	return true
+}
+
+// trashTable renames a table being dropped out of sight and records it in the trash.
+// A table that does not exist is not an error, as with DROP TABLE IF EXISTS. Returns
+// false after aborting the request on failure.
+func (h *TableHandler) trashTable(c *gin.Context, userDB storage.UserDataStore, dbName, tableName string) bool {
+	ctx := c.Request.Context()
+	trashName := core.TrashedTablePrefix + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := userDB.RenameTable(ctx, tableName, trashName); err != nil {
+		if errors.Is(err, storage.ErrTableNotFound) {
+			return true
+		}
+		_ = c.Error(err)
+		return false
+	}
+
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return false
+	}
+	location, err := h.MetaDB.FindDatabasePath(ctx, userId, dbName)
+	if err == nil {
+		_, err = h.MetaDB.AddTrashItem(ctx, domain.TrashItem{
+			UserID:        userId,
+			DBName:        dbName,
+			TableName:     tableName,
+			Location:      location,
+			TrashLocation: trashName,
+			ExpiresAt:     time.Now().Add(h.Cfg.TrashRetention),
+		})
+	}
+	if err != nil {
+		if err := userDB.RenameTable(ctx, trashName, tableName); err != nil {
+			customLog.Ctx(ctx).Warnf("Handler: Failed to rename trashed table '%s' back to '%s' in DB '%s': %v", trashName, tableName, dbName, err)
+		}
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to move table to the trash.")
+		return false
+	}
+	return true
+}
+
+// ListTrash returns the caller's deleted databases and dropped tables that can still
+// be restored.
+func (h *DatabaseHandler) ListTrash(c *gin.Context) {
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	items, err := h.MetaDB.ListTrashItems(c.Request.Context(), userId)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"trash": items})
+}
+
+// RestoreTrashItem brings a deleted database or dropped table back under its name.
+func (h *DatabaseHandler) RestoreTrashItem(c *gin.Context) {
+	item, ok := h.trashItem(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	if item.TableName == "" {
+		if err := h.MetaDB.RegisterDatabase(ctx, item.UserID, item.DBName, item.Location); err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrDatabaseExists) {
+				abortWithError(c, http.StatusConflict, fmt.Sprintf("A database named '%s' already exists; delete it before restoring.", item.DBName))
+			}
+			return
+		}
+		if err := storage.RestoreUserData(ctx, item.TrashLocation, item.Location); err != nil {
+			if err := h.MetaDB.DeleteDatabaseRegistration(ctx, item.UserID, item.DBName); err != nil {
+				customLog.Ctx(ctx).Warnf("Handler: Failed to remove registration of unrestored DB '%s': %v", item.DBName, err)
+			}
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, "Failed to restore database from the trash.")
+			return
+		}
+	} else {
+		location, err := h.MetaDB.FindDatabasePath(ctx, item.UserID, item.DBName)
+		if err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrDatabaseNotFound) {
+				abortWithError(c, http.StatusConflict, fmt.Sprintf("Database '%s' does not exist; restore it before its tables.", item.DBName))
+			}
+			return
+		}
+		userDB, err := storage.OpenUserData(ctx, location)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusInternalServerError, "Failed to access database storage.")
+			return
+		}
+		err = userDB.RenameTable(ctx, item.TrashLocation, item.TableName)
+		userDB.Release()
+		if err != nil {
+			_ = c.Error(err)
+			switch {
+			case errors.Is(err, storage.ErrTableExists):
+				abortWithError(c, http.StatusConflict, fmt.Sprintf("A table named '%s' already exists; drop it before restoring.", item.TableName))
+			case errors.Is(err, storage.ErrTableNotFound):
+				abortWithError(c, http.StatusNotFound, fmt.Sprintf("The trashed table '%s' no longer exists in database '%s'.", item.TableName, item.DBName))
+			}
+			return
+		}
+	}
+
+	if err := h.MetaDB.DeleteTrashItem(ctx, item.TrashID); err != nil {
+		customLog.Ctx(ctx).Warnf("Handler: Failed to remove restored trash item %d: %v", item.TrashID, err)
+	}
+	customLog.Ctx(ctx).Printf("Handler: Restored trash item %d (DB '%s', table '%s') for UserID %s", item.TrashID, item.DBName, item.TableName, item.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Restored successfully",
+		"db_name":    item.DBName,
+		"table_name": item.TableName,
+	})
+}
+
+// PurgeTrashItem permanently deletes a trashed database or table ahead of its expiry.
+func (h *DatabaseHandler) PurgeTrashItem(c *gin.Context) {
+	item, ok := h.trashItem(c)
+	if !ok {
+		return
+	}
+	if err := storage.PurgeTrashItem(c.Request.Context(), h.MetaDB, *item); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Purged trash item %d (DB '%s', table '%s') for UserID %s", item.TrashID, item.DBName, item.TableName, item.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// trashItem loads the caller's trash item addressed by :trash_id. Returns false after
+// aborting the request on failure.
+func (h *DatabaseHandler) trashItem(c *gin.Context) (*domain.TrashItem, bool) {
+	trashID, err := strconv.ParseInt(c.Param("trash_id"), 10, 64)
+	if err != nil {
+		_ = c.Error(fmt.Errorf("invalid trash_id format: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid trash item ID format.")
+		return nil, false
+	}
+	userId, err := requestctx.UserID(c)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	item, err := h.MetaDB.GetTrashItem(c.Request.Context(), userId, trashID)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, false
+	}
+	return item, true
+}
@@ -0,0 +1,79 @@
+// api/handlers/usage_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Bounds of the ?days window of usage statistics.
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+// GetDatabaseUsage returns the daily API usage of a database (requests, errors and
+// bytes transferred) over the last ?days UTC days, today included, with their totals.
+func (h *DatabaseHandler) GetDatabaseUsage(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	since, ok := usageSince(c)
+	if !ok {
+		return
+	}
+
+	// Include the traffic counted since the last periodic flush
+	if err := storage.FlushDatabaseUsage(c.Request.Context(), h.MetaDB); err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("Handler: Usage of DB '%s' may miss recent requests: %v", target.Name, err)
+	}
+	usage, err := h.MetaDB.ListDatabaseUsage(c.Request.Context(), target.ID, since)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var totals domain.DatabaseUsage
+	for i := range usage {
+		usage[i].ErrorRate = errorRate(usage[i])
+		totals.Requests += usage[i].Requests
+		totals.Errors += usage[i].Errors
+		totals.BytesIn += usage[i].BytesIn
+		totals.BytesOut += usage[i].BytesOut
+	}
+	totals.ErrorRate = errorRate(totals)
+	c.JSON(http.StatusOK, gin.H{"db_name": target.Name, "since": since, "days": usage, "totals": totals})
+}
+
+// usageSince returns the first UTC day (YYYY-MM-DD) of the last ?days days, today
+// included, aborting with 400 when days is invalid.
+func usageSince(c *gin.Context) (string, bool) {
+	days := defaultUsageDays
+	if raw := c.Query("days"); raw != "" {
+		var err error
+		if days, err = strconv.Atoi(raw); err != nil || days < 1 || days > maxUsageDays {
+			_ = c.Error(errors.New("invalid days parameter"))
+			abortWithError(c, http.StatusBadRequest, "Invalid 'days' parameter: must be between 1 and 366.")
+			return "", false
+		}
+	}
+	return time.Now().UTC().AddDate(0, 0, 1-days).Format(storage.UsageDayLayout), true
+}
+
+// errorRate returns the share of requests of usage that failed.
+func errorRate(usage domain.DatabaseUsage) float64 {
+	if usage.Requests == 0 {
+		return 0
+	}
+	return float64(usage.Errors) / float64(usage.Requests)
+}
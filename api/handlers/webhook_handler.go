@@ -0,0 +1,244 @@
+// api/handlers/webhook_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+	"github.com/Annany2002/nebula-backend/internal/webhooks"
+)
+
+// maxWebhooksPerDatabase bounds the deliveries a single write can cause.
+const maxWebhooksPerDatabase = 20
+
+// ListWebhooks returns the webhooks of a database, without their secrets.
+func (h *DatabaseHandler) ListWebhooks(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	hooks, err := h.MetaDB.ListWebhooks(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks, "events": webhooks.Events})
+}
+
+// CreateWebhook registers a webhook for a database. The response holds the secret that
+// signs its deliveries; it is not shown again.
+func (h *DatabaseHandler) CreateWebhook(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	events, err := webhooks.NormalizeEvents(req.Events)
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, err := h.MetaDB.ListWebhooks(c.Request.Context(), target.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if len(existing) >= maxWebhooksPerDatabase {
+		_ = c.Error(errors.New("webhook limit reached"))
+		abortWithError(c, http.StatusConflict, fmt.Sprintf("Database '%s' already has %d webhooks, the maximum.", target.Name, maxWebhooksPerDatabase))
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to generate webhook secret.")
+		return
+	}
+	hook := domain.Webhook{
+		ID:          uuid.New().String(),
+		DatabaseID:  target.ID,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      events,
+		Secret:      secret,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if err := h.MetaDB.CreateWebhook(c.Request.Context(), hook); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	stored, err := h.MetaDB.GetWebhook(c.Request.Context(), target.ID, hook.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Created webhook %s on DB '%s' for UserID %s", hook.ID, target.Name, target.UserID)
+	c.JSON(http.StatusCreated, stored)
+}
+
+// GetWebhook returns one webhook of a database, without its secret.
+func (h *DatabaseHandler) GetWebhook(c *gin.Context) {
+	_, hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook changes the URL, description, events or enabled state of a webhook;
+// fields left out are kept. Disabled webhooks receive no deliveries but can still be
+// test-fired.
+func (h *DatabaseHandler) UpdateWebhook(c *gin.Context) {
+	target, hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(fmt.Errorf("binding error: %w", err))
+		abortWithError(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if req.URL != nil {
+		if err := webhooks.ValidateURL(*req.URL); err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		events, err := webhooks.NormalizeEvents(*req.Events)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		hook.Events = events
+	}
+	if req.Description != nil {
+		hook.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+
+	if err := h.MetaDB.UpdateWebhook(c.Request.Context(), *hook); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Webhook '%s' not found.", hook.ID))
+		}
+		return
+	}
+	stored, err := h.MetaDB.GetWebhook(c.Request.Context(), target.ID, hook.ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Updated webhook %s on DB '%s' (enabled: %t)", hook.ID, target.Name, stored.Enabled)
+	stored.Secret = ""
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteWebhook removes a webhook.
+func (h *DatabaseHandler) DeleteWebhook(c *gin.Context) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	webhookId := c.Param("webhook_id")
+	if err := h.MetaDB.DeleteWebhook(c.Request.Context(), target.ID, webhookId); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Webhook '%s' not found.", webhookId))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Deleted webhook %s on DB '%s' for UserID %s", webhookId, target.Name, target.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook sends a webhook.test event to a webhook right away and reports how it
+// answered. Disabled webhooks can be tested too.
+func (h *DatabaseHandler) TestWebhook(c *gin.Context) {
+	target, hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	result := webhooks.Test(c.Request.Context(), h.MetaDB, *hook, target.Name)
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Test-fired webhook %s on DB '%s': delivered %t, status %d", hook.ID, target.Name, result.Delivered, result.StatusCode)
+	c.JSON(http.StatusOK, result)
+}
+
+// RotateWebhookSecret replaces the signing secret of a webhook and returns the new one.
+// Deliveries are signed with it from then on.
+func (h *DatabaseHandler) RotateWebhookSecret(c *gin.Context) {
+	target, hook, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		_ = c.Error(err)
+		abortWithError(c, http.StatusInternalServerError, "Failed to generate webhook secret.")
+		return
+	}
+	if err := h.MetaDB.SetWebhookSecret(c.Request.Context(), target.ID, hook.ID, secret); err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Webhook '%s' not found.", hook.ID))
+		}
+		return
+	}
+	customLog.Ctx(c.Request.Context()).Printf("Handler: Rotated secret of webhook %s on DB '%s' for UserID %s", hook.ID, target.Name, target.UserID)
+	c.JSON(http.StatusOK, gin.H{"id": hook.ID, "secret": secret})
+}
+
+// loadWebhook resolves the database and the webhook addressed by the request,
+// aborting it when either does not exist.
+func (h *DatabaseHandler) loadWebhook(c *gin.Context) (*targetDatabase, *domain.Webhook, bool) {
+	target, err := resolveTargetDatabase(c, h.MetaDB)
+	if err != nil {
+		_ = c.Error(err)
+		return nil, nil, false
+	}
+	webhookId := c.Param("webhook_id")
+	hook, err := h.MetaDB.GetWebhook(c.Request.Context(), target.ID, webhookId)
+	if err != nil {
+		_ = c.Error(err)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			abortWithError(c, http.StatusNotFound, fmt.Sprintf("Webhook '%s' not found.", webhookId))
+		}
+		return nil, nil, false
+	}
+	return target, hook, true
+}
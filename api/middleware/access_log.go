@@ -0,0 +1,143 @@
+// api/middleware/access_log.go
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+const redacted = "[REDACTED]"
+
+// maxLoggedBodyBytes is the largest request body copied into the access log.
+const maxLoggedBodyBytes = 64 << 10
+
+// AccessLogOptions configures the AccessLog middleware.
+type AccessLogOptions struct {
+	Logger        *logger.Logger
+	LogBodies     bool     // Include JSON request bodies (up to 64KB)
+	RedactHeaders []string // Header names whose values are replaced, case-insensitive
+	RedactFields  []string // JSON body keys whose values are replaced, case-insensitive, at any depth
+}
+
+// AccessLog writes one line per request with method, path, status, latency, user,
+// request ID and body sizes, plus the request headers (and optionally the body) with
+// sensitive values redacted.
+func AccessLog(opts AccessLogOptions) gin.HandlerFunc {
+	redactHeaders := lowerSet(opts.RedactHeaders)
+	redactFields := lowerSet(opts.RedactFields)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		counter := &countingReader{r: c.Request.Body}
+		var body any
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			if opts.LogBodies && isJSON(c.ContentType()) && c.Request.ContentLength <= maxLoggedBodyBytes {
+				raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBodyBytes+1))
+				if err == nil && len(raw) <= maxLoggedBodyBytes && json.Unmarshal(raw, &body) == nil {
+					body = redactJSON(body, redactFields)
+				}
+				counter.r = io.NopCloser(io.MultiReader(bytes.NewReader(raw), c.Request.Body))
+			}
+			c.Request.Body = counter
+		}
+
+		c.Next()
+
+		// Handlers that ignore the body still received it over the wire
+		bytesIn := counter.n
+		if c.Request.ContentLength > bytesIn {
+			bytesIn = c.Request.ContentLength
+		}
+
+		userId, _ := requestctx.UserID(c) // Empty for unauthenticated requests
+		fields := logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip":  c.ClientIP(),
+			"user_id":    userId,
+			"request_id": requestctx.RequestID(c),
+			"bytes_in":   bytesIn,
+			"bytes_out":  max(c.Writer.Size(), 0),
+			"headers":    redactHeaderValues(c.Request.Header, redactHeaders),
+		}
+		if c.Request.URL.RawQuery != "" {
+			fields["query"] = c.Request.URL.RawQuery
+		}
+		if body != nil {
+			fields["body"] = body
+		}
+		opts.Logger.WithFields(fields).Info("request")
+	}
+}
+
+// countingReader counts the request body bytes read by handlers.
+type countingReader struct {
+	r io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) Close() error { return cr.r.Close() }
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+func redactHeaderValues(header http.Header, redact map[string]bool) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		if redact[strings.ToLower(name)] {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// redactJSON replaces the values of sensitive keys in a decoded JSON document.
+func redactJSON(v any, redact map[string]bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for key, value := range t {
+			if redact[strings.ToLower(key)] {
+				t[key] = redacted
+			} else {
+				t[key] = redactJSON(value, redact)
+			}
+		}
+	case []any:
+		for i, value := range t {
+			t[i] = redactJSON(value, redact)
+		}
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+// api/middleware/admin_middleware.go
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// AdminMiddleware allows the request only if the authenticated user has the admin
+// role. It must run after AuthMiddleware, which sets "userId".
+func AdminMiddleware(metaDB storage.MetadataStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := requestctx.UserID(c)
+		if err != nil {
+			_ = c.Error(err)
+			abortWithError(c, http.StatusForbidden, "admin access required")
+			return
+		}
+		user, err := metaDB.FindUserByUserId(c.Request.Context(), userId)
+		if err != nil {
+			_ = c.Error(err)
+			if errors.Is(err, storage.ErrMetadataUnavailable) {
+				c.Abort() // Answered with 503, not as a missing admin role
+				return
+			}
+			abortWithError(c, http.StatusForbidden, "admin access required")
+			return
+		}
+		if user.Role != domain.RoleAdmin {
+			customLog.Ctx(c.Request.Context()).Warnf("AdminMiddleware: FORBIDDEN - UserID %s (role %s) attempted %s %s", userId, user.Role, c.Request.Method, c.Request.URL.Path)
+			abortWithError(c, http.StatusForbidden, "admin access required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminActAsUser lets an admin read the data of the account in the :user_id parameter
+// through the regular handlers: the rest of the request runs as that account. The
+// admin's ID is kept for logs, and the requests are left out of the account's usage
+// statistics. It must run after AdminMiddleware.
+func AdminActAsUser(metaDB storage.MetadataStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminId, err := requestctx.UserID(c)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		userId := c.Param("user_id")
+		if _, err := metaDB.FindUserByUserId(c.Request.Context(), userId); err != nil {
+			_ = c.Error(err) // ErrUserNotFound is answered with 404
+			c.Abort()
+			return
+		}
+		customLog.Ctx(c.Request.Context()).Printf("AdminActAsUser: Admin %s reading %s %s as UserID %s", adminId, c.Request.Method, c.Request.URL.Path, userId)
+		requestctx.SetActingAdmin(c, adminId)
+		requestctx.SetUserID(c, userId)
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+// api/middleware/api_version.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+)
+
+// APIVersionPolicy describes one mounted API version (/api/v<Version>).
+type APIVersionPolicy struct {
+	Version    int
+	Deprecated time.Time // When the version was deprecated; zero while it is supported
+	Sunset     time.Time // When the version will be removed; zero when no date is announced
+	Successor  int       // Version linked from deprecated responses (0 for none)
+}
+
+// APIVersion stores the route group's version in the context under "apiVersion", so
+// handlers shared between versions can pick the response shape. Responses of a
+// deprecated version carry Deprecation (RFC 9745), Sunset (RFC 8594) and a Link to
+// the same path in the successor version.
+func APIVersion(policy APIVersionPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestctx.SetAPIVersion(c, policy.Version)
+		if !policy.Deprecated.IsZero() {
+			c.Header("Deprecation", "@"+strconv.FormatInt(policy.Deprecated.Unix(), 10))
+			if policy.Successor > 0 {
+				successor := successorPath(c.Request.URL.Path, policy.Version, policy.Successor)
+				c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			}
+		}
+		if !policy.Sunset.IsZero() {
+			c.Header("Sunset", policy.Sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
+
+// successorPath rewrites /api/v<from>/... to /api/v<to>/...
+func successorPath(path string, from, to int) string {
+	prefix := fmt.Sprintf("/api/v%d", from)
+	return fmt.Sprintf("/api/v%d", to) + strings.TrimPrefix(path, prefix)
+}
+
+// isAdminPath reports whether path is under /api/v<N>/admin/ for any version.
+func isAdminPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/v")
+	if !ok {
+		return false
+	}
+	_, rest, ok = strings.Cut(rest, "/")
+	return ok && strings.HasPrefix(rest, "admin/")
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	deprecated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	versions := []APIVersionPolicy{
+		{Version: 1, Deprecated: deprecated, Sunset: sunset, Successor: 2},
+		{Version: 2},
+	}
+	for _, policy := range versions {
+		router.Group(fmt.Sprintf("/api/v%d", policy.Version), APIVersion(policy)).GET("/databases/:db_name", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"version": c.GetInt("apiVersion")})
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/databases/app", nil))
+	if got := w.Header().Get("Deprecation"); got != "@1767225600" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `</api/v2/databases/app>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+	if w.Body.String() != `{"version":1}` {
+		t.Errorf("v1 body = %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/databases/app", nil))
+	if w.Header().Get("Deprecation") != "" || w.Header().Get("Sunset") != "" || w.Body.String() != `{"version":2}` {
+		t.Errorf("v2 response = %v %s; want no lifecycle headers", w.Header(), w.Body.String())
+	}
+}
+
+func TestIsAdminPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/api/v1/admin/mode":      true,
+		"/api/v2/admin/log-level": true,
+		"/api/v1/databases":       false,
+		"/api/v1/administrators":  false,
+		"/admin/mode":             false,
+	} {
+		if got := isAdminPath(path); got != want {
+			t.Errorf("isAdminPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -8,19 +8,30 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
 	"github.com/Annany2002/nebula-backend/internal/auth" // Import internal auth logic and errors
+	"github.com/Annany2002/nebula-backend/internal/logger"
 )
 
 // AuthMiddleware creates a gin middleware for checking JWT authentication.
-// It depends on the application configuration for the JWT secret.
+// It depends on the application configuration for the JWT secret. Requests without an
+// Authorization header may instead authenticate with a mapped client certificate.
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if userId := requestctx.CertificateUser(c); authHeader == "" && userId != "" {
+			// Client certificates mapped to a service account (see ClientCertificate)
+			customLog.Ctx(c.Request.Context()).Printf("AuthMiddleware: Client certificate accepted for UserID: %s", userId)
+			requestctx.SetUserID(c, userId)
+			logger.SetUserID(c.Request.Context(), userId)
+			c.Next()
+			return
+		}
 		if authHeader == "" {
 			err := errors.New("authorization header required")
 			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusUnauthorized, err.Error())
 			return
 		}
 
@@ -28,33 +39,25 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 		if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
 			err := errors.New("authorization header format must be Bearer {token}")
 			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			abortWithError(c, http.StatusUnauthorized, err.Error())
 			return
 		}
 		tokenString := parts[1]
 
 		// Validate JWT using the internal auth function
-		userId, err := auth.ValidateJWT(tokenString, cfg.JWTSecret)
+		userId, err := auth.ValidateJWT(tokenString, cfg.JWTVerificationSecrets()...)
 
 		if err != nil {
-			customLog.Printf("AuthMiddleware: Token validation failed: %v", err)
-			statusCode := http.StatusUnauthorized
-			errMsg := "Invalid token"
-			switch {
-			case errors.Is(err, auth.ErrTokenMalformed):
-				errMsg = err.Error()
-			case errors.Is(err, auth.ErrTokenExpired):
-				errMsg = err.Error()
-			}
-
+			customLog.Ctx(c.Request.Context()).Printf("AuthMiddleware: Token validation failed: %v", err)
 			_ = c.Error(err)
-			c.AbortWithStatusJSON(statusCode, gin.H{"error": errMsg})
+			abortWithAPIError(c, tokenError(err))
 			return
 		}
 
 		// Token is valid! Set the userID in the context
-		customLog.Printf("AuthMiddleware: Token validated successfully for UserID: %s", userId)
-		c.Set("userId", userId) // Use consistent key
+		customLog.Ctx(c.Request.Context()).Printf("AuthMiddleware: Token validated successfully for UserID: %s", userId)
+		requestctx.SetUserID(c, userId)
+		logger.SetUserID(c.Request.Context(), userId)
 
 		c.Next() // Continue to the next handler
 	}
@@ -0,0 +1,70 @@
+// api/middleware/client_cert.go
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+// ClientCertificate rejects requests to cfg.TLSClientAuthRoutes that came without a
+// verified client certificate (the TLS handshake already refuses them when no routes
+// are listed). A certificate whose identity is mapped in cfg.TLSClientIdentities
+// authenticates the request as that service account when it has no Authorization
+// header (see CombinedAuthMiddleware and AuthMiddleware).
+func ClientCertificate(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var cert *x509.Certificate
+		if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 {
+			cert = c.Request.TLS.VerifiedChains[0][0]
+		}
+		if cert == nil {
+			if requiresClientCertificate(c.Request.URL.Path, cfg.TLSClientAuthRoutes) {
+				_ = c.Error(auth.ErrUnauthorized)
+				abortWithError(c, http.StatusUnauthorized, "Client certificate required")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if userID, identity, ok := certificateUser(cert, cfg.TLSClientIdentities); ok {
+			customLog.Ctx(c.Request.Context()).Printf("ClientCertificate: Certificate '%s' maps to UserID %s", identity, userID)
+			requestctx.SetCertificateUser(c, userID)
+		}
+		c.Next()
+	}
+}
+
+// requiresClientCertificate reports whether path is under one of routes.
+func requiresClientCertificate(path string, routes []string) bool {
+	for _, route := range routes {
+		if path == route || strings.HasPrefix(path, strings.TrimSuffix(route, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateUser returns the user ID mapped to the first identity of cert found in
+// identities, trying its subject common name, then its DNS, URI and email SANs.
+func certificateUser(cert *x509.Certificate, identities map[string]string) (userID, identity string, ok bool) {
+	candidates := []string{cert.Subject.CommonName}
+	candidates = append(candidates, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		candidates = append(candidates, uri.String())
+	}
+	candidates = append(candidates, cert.EmailAddresses...)
+	for _, candidate := range candidates {
+		if userID, ok := identities[candidate]; ok && candidate != "" {
+			return userID, candidate, true
+		}
+	}
+	return "", "", false
+}
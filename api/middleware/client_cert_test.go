@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestClientCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		JWTSecret:           "test-secret",
+		TLSClientCAFile:     "ca.pem",
+		TLSClientAuthRoutes: []string{"/api/v1/admin"},
+		TLSClientIdentities: map[string]string{"spiffe://internal/billing": "svc-billing", "reporting": "svc-reporting"},
+	}
+	router := gin.New()
+	router.Use(ErrorHandler(), ClientCertificate(cfg))
+	ok := func(c *gin.Context) {
+		userID, _ := requestctx.UserID(c)
+		c.String(http.StatusOK, userID)
+	}
+	router.GET("/api/v1/admin/users", AuthMiddleware(cfg), ok)
+	router.GET("/api/v1/databases", CombinedAuthMiddleware(nil, cfg), ok)
+
+	billing := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "internal", Path: "/billing"}}}
+	reporting := &x509.Certificate{Subject: pkix.Name{CommonName: "reporting"}}
+	unmapped := &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}, DNSNames: []string{"unknown.internal"}}
+	cases := []struct {
+		name string
+		cert *x509.Certificate
+		path string
+		want int
+		user string
+	}{
+		{"required route without certificate", nil, "/api/v1/admin/users", http.StatusUnauthorized, ""},
+		{"other route without certificate", nil, "/api/v1/databases", http.StatusUnauthorized, ""},
+		{"URI SAN", billing, "/api/v1/admin/users", http.StatusOK, "svc-billing"},
+		{"common name", reporting, "/api/v1/databases", http.StatusOK, "svc-reporting"},
+		{"unmapped certificate", unmapped, "/api/v1/admin/users", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Fatalf("GET %s = %d; want %d (%s)", tc.path, rec.Code, tc.want, rec.Body.String())
+			}
+			if tc.want == http.StatusOK && rec.Body.String() != tc.user {
+				t.Errorf("authenticated as %q; want %q", rec.Body.String(), tc.user)
+			}
+		})
+	}
+
+	// A mapped certificate does not override an explicit Authorization header
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/users", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{billing}}}
+	req.Header.Set("Authorization", "Bearer invalid")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid bearer token with certificate = %d; want 401", rec.Code)
+	}
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,7 +8,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/anomaly"
 	"github.com/Annany2002/nebula-backend/internal/auth"
 	"github.com/Annany2002/nebula-backend/internal/logger"
 	"github.com/Annany2002/nebula-backend/internal/storage"
@@ -22,14 +23,34 @@ var (
 
 // This middleware checks requests coming using either from the bearer or the api key token
 // within the Authorization Header
-func CombinedAuthMiddleware(db *sql.DB, cfg *config.Config) gin.HandlerFunc {
+func CombinedAuthMiddleware(db storage.MetadataStore, cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && c.Query(auth.SignedURLSignature) != "" {
+			// Signed URLs carry their credentials in the query string
+			userId, ok := authenticateSignedURL(c, cfg)
+			if !ok {
+				return
+			}
+			requestctx.SetUserID(c, userId)
+			logger.SetUserID(c.Request.Context(), userId)
+			customLog.Ctx(c.Request.Context()).Printf("CombinedAuthMiddleware: Auth success. UserID: %s (Signed URL)", userId)
+			c.Next()
+			return
+		}
+		if userId := requestctx.CertificateUser(c); authHeader == "" && userId != "" {
+			// Client certificates mapped to a service account (see ClientCertificate)
+			requestctx.SetUserID(c, userId)
+			logger.SetUserID(c.Request.Context(), userId)
+			customLog.Ctx(c.Request.Context()).Printf("CombinedAuthMiddleware: Auth success. UserID: %s (Client certificate)", userId)
+			c.Next()
+			return
+		}
 		if authHeader == "" {
 			// No Authorization header provided at all
 			err := auth.ErrUnauthorized
 			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			abortWithError(c, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
@@ -38,7 +59,7 @@ func CombinedAuthMiddleware(db *sql.DB, cfg *config.Config) gin.HandlerFunc {
 			// Invalid header format (not "Scheme Credentials")
 			err := fmt.Errorf("%w: invalid header format", auth.ErrTokenMalformed)
 			_ = c.Error(err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be 'Bearer {token}' or 'ApiKey {key}'"})
+			abortWithError(c, http.StatusUnauthorized, "Authorization header format must be 'Bearer {token}', 'ApiKey {key}' or '"+auth.RequestSigningScheme+" {signature}'")
 			return
 		}
 
@@ -46,86 +67,108 @@ func CombinedAuthMiddleware(db *sql.DB, cfg *config.Config) gin.HandlerFunc {
 		credentials := parts[1]
 
 		var userId string
-		var databaseId any
-		var isApiKeyAuth bool
+		var databaseId *int64 // Set for DB-scoped API keys
 
 		// --- Try Different Authentication Schemes ---
 		switch scheme {
 		case "apikey":
-			customLog.Println("CombinedAuthMiddleware: Attempting ApiKey authentication...")
+			customLog.Ctx(c.Request.Context()).Println("CombinedAuthMiddleware: Attempting ApiKey authentication...")
+			if strings.HasPrefix(credentials, storage.PublishableKeyPrefix) {
+				key, ok := authenticatePublishableKey(c, db, credentials)
+				if !ok {
+					return
+				}
+				databaseId, userId = &key.DatabaseID, key.OwnerID
+				break
+			}
 			if !strings.HasPrefix(credentials, authKeyPrefix) {
 				_ = c.Error(fmt.Errorf("%w: invalid key prefix", auth.ErrTokenMalformed))
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				abortWithError(c, http.StatusUnauthorized, "Invalid API key")
 				return
 			}
 
 			// Find database ID from the API key
-			apiKeyQuery := `SELECT api_database_id, api_owner_id FROM api_keys WHERE key = ?` //nolint:gosec // G101 false positive - not credentials
-			row := db.QueryRow(apiKeyQuery, credentials)
-
-			err := row.Scan(&databaseId, &userId)
+			keyDatabaseId, keyUserId, err := db.FindAPIKeyOwner(c.Request.Context(), credentials)
 			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
+				if errors.Is(err, storage.ErrAPIKeyNotFound) {
+					anomaly.UnknownAPIKey(c.Request.Context(), credentials, c.ClientIP())
 					_ = c.Error(fmt.Errorf("%w: invalid API key", auth.ErrTokenMalformed))
-					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+					abortWithError(c, http.StatusUnauthorized, "Invalid API key")
 					return
 				}
-				customLog.Warnf("error scanning databaseId: %v", err)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key format"})
+				if errors.Is(err, storage.ErrMetadataUnavailable) {
+					_ = c.Error(err)
+					c.Abort()
+					return
+				}
+				customLog.Ctx(c.Request.Context()).Warnf("error scanning databaseId: %v", err)
+				abortWithError(c, http.StatusUnauthorized, "Invalid API key format")
 				return
 			}
+			// The key column is unique: this single indexed lookup authenticates the key
+			databaseId, userId = &keyDatabaseId, keyUserId
 
-			apiKey, err := storage.FindAPIKeyByDatabaseId(c.Request.Context(), db, databaseId.(int64))
-			if err != nil {
-				customLog.Warnf("CombinedAuthMiddleware: DB error looking up ApiKey for database ID '%d': %v", databaseId.(int64), err)
-				_ = c.Error(fmt.Errorf("internal error during auth: %w", err))
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key format"})
-				return
-			}
-			if apiKey == "" {
-				_ = c.Error(auth.ErrUnauthorized)
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			anomaly.APIKeyUsed(c.Request.Context(), credentials, keyUserId, keyDatabaseId, anomaly.Country(c.GetHeader), c.ClientIP())
+
+			requestctx.SetAPIKey(c)
+
+		case strings.ToLower(auth.RequestSigningScheme):
+			customLog.Ctx(c.Request.Context()).Println("CombinedAuthMiddleware: Attempting signed request authentication...")
+			key, keyDatabaseId, keyUserId, ok := authenticateSignedRequest(c, db, cfg, credentials)
+			if !ok {
 				return
 			}
-
-			isApiKeyAuth = true
-			c.Set("isApiKey", isApiKeyAuth)
+			databaseId, userId = &keyDatabaseId, keyUserId
+			anomaly.APIKeyUsed(c.Request.Context(), key, keyUserId, keyDatabaseId, anomaly.Country(c.GetHeader), c.ClientIP())
+			requestctx.SetAPIKey(c)
 
 		case "bearer":
-			customLog.Println("CombinedAuthMiddleware: Attempting Bearer token authentication...")
-			jwtUserID, jwtErr := auth.ValidateJWT(credentials, cfg.JWTSecret)
-			if jwtErr != nil {
-				customLog.Printf("AuthMiddleware: Token validation failed: %v", jwtErr)
-				statusCode := http.StatusUnauthorized
-				errMsg := "Invalid token"
-				switch {
-				case errors.Is(jwtErr, auth.ErrTokenMalformed):
-					errMsg = jwtErr.Error()
-				case errors.Is(jwtErr, auth.ErrTokenExpired):
-					errMsg = jwtErr.Error()
+			customLog.Ctx(c.Request.Context()).Println("CombinedAuthMiddleware: Attempting Bearer token authentication...")
+			// Scoped access tokens are bearer tokens too, limited to what they were minted for
+			scope, scopeErr := auth.ValidateScopedToken(credentials, cfg.JWTVerificationSecrets()...)
+			if scopeErr == nil {
+				if apiErr := checkTokenScope(c, scope); apiErr != nil {
+					customLog.Ctx(c.Request.Context()).Warnf("CombinedAuthMiddleware: Scoped token of UserID %s refused: %s", scope.UserID, apiErr.Message)
+					abortWithAPIError(c, apiErr)
+					return
 				}
+				userId, databaseId = scope.UserID, &scope.DatabaseID
+				break
+			}
+			if errors.Is(scopeErr, auth.ErrTokenExpired) {
+				_ = c.Error(scopeErr)
+				abortWithAPIError(c, tokenError(scopeErr))
+				return
+			}
 
+			jwtUserID, jwtErr := auth.ValidateJWT(credentials, cfg.JWTVerificationSecrets()...)
+			if jwtErr != nil {
+				customLog.Ctx(c.Request.Context()).Printf("AuthMiddleware: Token validation failed: %v", jwtErr)
 				_ = c.Error(jwtErr)
-				c.AbortWithStatusJSON(statusCode, gin.H{"error": errMsg})
+				abortWithAPIError(c, tokenError(jwtErr))
 				return
 			}
 
 			userId = jwtUserID
-			databaseId = nil // Explicitly set databaseID to nil for JWT/user scope
 
 		default:
 			// Unsupported authentication scheme
 			defaultErr := fmt.Errorf("%w: unsupported scheme '%s'", auth.ErrTokenMalformed, parts[0])
-			customLog.Warnf("CombinedAuthMiddleware: Authentication failed (Scheme: %s): %v", scheme, defaultErr)
+			customLog.Ctx(c.Request.Context()).Warnf("CombinedAuthMiddleware: Authentication failed (Scheme: %s): %v", scheme, defaultErr)
 			_ = c.Error(defaultErr)
 			c.Abort()
 			return
 		}
 
 		// --- Authentication Success ---
-		customLog.Printf("CombinedAuthMiddleware: Auth success. UserID: %s, DatabaseID: %v (Scheme: %s)\n", userId, databaseId, scheme)
-		c.Set("userId", userId)
-		c.Set("databaseId", databaseId) // Will be int64 for DB-scoped ApiKey, nil for JWT
+		requestctx.SetUserID(c, userId)
+		logger.SetUserID(c.Request.Context(), userId)
+		if databaseId != nil {
+			customLog.Ctx(c.Request.Context()).Printf("CombinedAuthMiddleware: Auth success. UserID: %s, DatabaseID: %d (Scheme: %s)", userId, *databaseId, scheme)
+			requestctx.SetScopedDatabaseID(c, *databaseId)
+		} else {
+			customLog.Ctx(c.Request.Context()).Printf("CombinedAuthMiddleware: Auth success. UserID: %s (Scheme: %s)", userId, scheme)
+		}
 
 		c.Next() // Proceed to the next handler
 
@@ -0,0 +1,74 @@
+// api/middleware/cors.go
+package middleware
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+// CORS applies the cross-origin policy of cfg. Origins were validated by
+// config.LoadConfig; an empty list allows no cross-origin requests. Public form
+// submissions are exempt: each form has its own allowed origins.
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	corsConfig := cors.DefaultConfig()
+	if len(cfg.CORSAllowedOrigins) == 1 && cfg.CORSAllowedOrigins[0] == "*" {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOriginFunc = originMatcher(cfg.CORSAllowedOrigins)
+	}
+	corsConfig.AllowCredentials = cfg.CORSAllowCredentials
+	corsConfig.AllowMethods = []string{"POST", "OPTIONS", "GET", "PUT", "DELETE"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", RequestIDHeader}
+	corsConfig.ExposeHeaders = []string{RequestIDHeader}
+	policy := cors.New(corsConfig)
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/forms/") {
+			c.Next()
+			return
+		}
+		policy(c)
+	}
+}
+
+// originMatcher reports whether a request origin is one of allowed, where an entry like
+// https://*.example.com matches any subdomain of example.com (but not example.com itself)
+// with the same scheme and port.
+func originMatcher(allowed []string) func(origin string) bool {
+	exact := make(map[string]bool)
+	var wildcards []*url.URL
+	for _, origin := range allowed {
+		origin = strings.ToLower(origin)
+		if !strings.Contains(origin, "://*.") {
+			exact[origin] = true
+			continue
+		}
+		if u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1)); err == nil {
+			wildcards = append(wildcards, u)
+		}
+	}
+
+	return func(origin string) bool {
+		origin = strings.ToLower(origin)
+		if exact[origin] {
+			return true
+		}
+		if len(wildcards) == 0 {
+			return false
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Path != "" {
+			return false
+		}
+		for _, w := range wildcards {
+			if u.Scheme == w.Scheme && u.Port() == w.Port() && strings.HasSuffix(u.Hostname(), "."+w.Hostname()) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import "testing"
+
+func TestOriginMatcher(t *testing.T) {
+	match := originMatcher([]string{"https://app.example.com", "https://*.tenant.io", "http://*.local.test:8080"})
+	for origin, want := range map[string]bool{
+		"https://app.example.com":      true,
+		"HTTPS://APP.EXAMPLE.COM":      true,
+		"http://app.example.com":       false,
+		"https://a.tenant.io":          true,
+		"https://a.b.tenant.io":        true,
+		"https://tenant.io":            false,
+		"https://eviltenant.io":        false,
+		"https://a.tenant.io:8443":     false,
+		"http://x.local.test:8080":     true,
+		"http://x.local.test":          false,
+		"https://a.tenant.io.evil.com": false,
+		"https://other.example.com":    false,
+	} {
+		if got := match(origin); got != want {
+			t.Errorf("match(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
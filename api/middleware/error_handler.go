@@ -2,18 +2,30 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"math"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/errortracking"
 	"github.com/Annany2002/nebula-backend/internal/storage"
 )
 
-// ErrorHandler creates a Gin middleware for centralized error handling.
+// ErrorHandler creates a Gin middleware for centralized error handling. It is the only
+// place error responses are written: handlers and middleware record an error with
+// c.Error and abort, and the response is chosen from the most recent *models.APIError,
+// or else by mapping the last error's type.
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next() // Process request
@@ -23,61 +35,229 @@ func ErrorHandler() gin.HandlerFunc {
 		} // No errors
 
 		err := c.Errors.Last().Err
-		customLog.Warnf("[ErrorHandler] Detected error: %v | Type: %T", err, err)
-
-		var statusCode int
-		var userMessage string
-
-		// --- Map error to HTTP status code and user message ---
-		if errors.Is(err, storage.ErrUserNotFound) ||
-			errors.Is(err, storage.ErrDatabaseNotFound) ||
-			errors.Is(err, storage.ErrRecordNotFound) ||
-			errors.Is(err, storage.ErrTableNotFound) {
-			statusCode = http.StatusNotFound
-			userMessage = err.Error()
-			// *** NEW: Check for Invalid Credentials ***
-		} else if errors.Is(err, storage.ErrInvalidCredentials) {
-			statusCode = http.StatusUnauthorized       // Map to 401
-			userMessage = "Invalid email or password." // Generic message
-			// *** END NEW ***
-		} else if errors.Is(err, storage.ErrEmailExists) ||
-			errors.Is(err, storage.ErrDatabaseExists) ||
-			errors.Is(err, storage.ErrConstraintViolation) {
-			statusCode = http.StatusConflict
-			userMessage = err.Error()
-		} else if errors.Is(err, auth.ErrTokenMalformed) ||
-			errors.Is(err, auth.ErrTokenInvalid) ||
-			errors.Is(err, auth.ErrTokenClaimsInvalid) ||
-			errors.Is(err, auth.ErrUnexpectedSigningMethod) {
-			statusCode = http.StatusUnauthorized // Keep as 401
-			userMessage = "Invalid or malformed authentication token."
-		} else if errors.Is(err, auth.ErrTokenExpired) {
-			statusCode = http.StatusUnauthorized // Keep as 401
-			userMessage = "Authentication token has expired."
-		} else if validationErrs, ok := err.(validator.ValidationErrors); ok {
-			statusCode = http.StatusBadRequest
-			userMessage = "Validation failed. Please check your input."
-			// Log details optional
-			for _, fe := range validationErrs {
-				customLog.Warnf("Validation Error: Field %s failed on %s", fe.Field(), fe.Tag())
-			}
-		} else if errors.Is(err, storage.ErrColumnNotFound) ||
-			errors.Is(err, storage.ErrTypeMismatch) ||
-			errors.Is(err, storage.ErrInvalidFilterValue) { // Include filter value error
-			statusCode = http.StatusBadRequest
-			userMessage = err.Error()
-		} else {
-			// --- Default/Fallback ---
-			statusCode = http.StatusInternalServerError
-			userMessage = "An unexpected internal server error occurred."
-			customLog.Warnf("Unhandled error type: %T, Error: %v", err, err)
+		customLog.Ctx(c.Request.Context()).Warnf("[ErrorHandler] Detected error: %v | Type: %T", err, err)
+
+		apiErr := selectAPIError(c)
+		if apiErr == nil {
+			apiErr = mapError(c, err)
+		}
+		if trackedStatus(apiErr.Status) && errortracking.Enabled() {
+			errortracking.CaptureError(c.Request.Context(), trackedError(c), apiErr.Status, trackedRequest(c))
 		}
 
 		// Abort and send JSON response if not already sent
 		if !c.Writer.Written() {
-			c.AbortWithStatusJSON(statusCode, gin.H{"error": userMessage})
+			writeError(c, apiErr)
 		} else {
-			log.Printf("[ErrorHandler] Warning: Response already written before handling error.")
+			customLog.Ctx(c.Request.Context()).Warnln("[ErrorHandler] Response already written before handling error.")
+		}
+	}
+}
+
+// writeError sends apiErr as application/problem+json (RFC 7807) when the client
+// prefers it, and in the ErrorResponse format otherwise.
+func writeError(c *gin.Context, apiErr *models.APIError) {
+	requestID := requestctx.RequestID(c)
+	if !prefersProblemJSON(c.GetHeader("Accept")) {
+		c.AbortWithStatusJSON(apiErr.Status, models.ErrorResponse{Error: models.ErrorBody{
+			Code:      apiErr.Code,
+			Message:   apiErr.Message,
+			Details:   apiErr.Details,
+			RequestID: requestID,
+		}})
+		return
+	}
+
+	body, err := json.Marshal(models.Problem{
+		Type:      models.ProblemType(apiErr.Code),
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      apiErr.Code,
+		Details:   apiErr.Details,
+		RequestID: requestID,
+	})
+	if err != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("[ErrorHandler] Failed to encode problem details: %v", err)
+		c.AbortWithStatus(apiErr.Status)
+		return
+	}
+	c.Abort()
+	c.Data(apiErr.Status, models.ProblemContentType, body)
+}
+
+// prefersProblemJSON reports whether an Accept header ranks application/problem+json
+// above application/json. Wildcards count for neither, so the default stays JSON.
+func prefersProblemJSON(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	problemQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case models.ProblemContentType:
+			problemQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+// selectAPIError returns the most recent *models.APIError recorded for the request.
+func selectAPIError(c *gin.Context) *models.APIError {
+	for i := len(c.Errors) - 1; i >= 0; i-- {
+		var apiErr *models.APIError
+		if errors.As(c.Errors[i].Err, &apiErr) {
+			return apiErr
+		}
+	}
+	return nil
+}
+
+// trackedStatus reports whether responses with status are sent to error tracking:
+// 5xx except 503 and 504, which report load (busy databases, maintenance, timeouts)
+// rather than faults.
+func trackedStatus(status int) bool {
+	return status >= 500 && status != http.StatusServiceUnavailable && status != http.StatusGatewayTimeout
+}
+
+// trackedError returns the most recent error recorded for the request that is not a
+// response, i.e. the cause of the failure, or the last error when all are responses.
+func trackedError(c *gin.Context) error {
+	for i := len(c.Errors) - 1; i >= 0; i-- {
+		var apiErr *models.APIError
+		if !errors.As(c.Errors[i].Err, &apiErr) {
+			return c.Errors[i].Err
+		}
+	}
+	return c.Errors.Last().Err
+}
+
+// mapError maps an error without an explicit response to a status, code and message.
+func mapError(c *gin.Context, err error) *models.APIError {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound) ||
+		errors.Is(err, storage.ErrDatabaseNotFound) ||
+		errors.Is(err, storage.ErrRecordNotFound) ||
+		errors.Is(err, storage.ErrTableNotFound) ||
+		errors.Is(err, storage.ErrBackupNotFound) ||
+		errors.Is(err, storage.ErrJSONSchemaNotFound) ||
+		errors.Is(err, storage.ErrTableScriptNotFound) ||
+		errors.Is(err, storage.ErrSavedQueryNotFound) ||
+		errors.Is(err, storage.ErrTrashItemNotFound) ||
+		errors.Is(err, storage.ErrReadReplicaNotFound) ||
+		errors.Is(err, storage.ErrAccountExportNotFound) ||
+		errors.Is(err, storage.ErrErasureNotFound):
+		return &models.APIError{Status: http.StatusNotFound, Code: models.ErrCodeNotFound, Message: err.Error()}
+	case errors.Is(err, storage.ErrInvalidCredentials):
+		return &models.APIError{Status: http.StatusUnauthorized, Code: models.ErrCodeInvalidCredentials, Message: "Invalid email or password."}
+	case errors.Is(err, storage.ErrEmailExists) ||
+		errors.Is(err, storage.ErrDatabaseExists) ||
+		errors.Is(err, storage.ErrTableExists) ||
+		errors.Is(err, storage.ErrConstraintViolation):
+		return &models.APIError{Status: http.StatusConflict, Code: models.ErrCodeConflict, Message: err.Error()}
+	case errors.Is(err, auth.ErrTokenMalformed) ||
+		errors.Is(err, auth.ErrTokenInvalid) ||
+		errors.Is(err, auth.ErrTokenClaimsInvalid) ||
+		errors.Is(err, auth.ErrUnexpectedSigningMethod):
+		return &models.APIError{Status: http.StatusUnauthorized, Code: models.ErrCodeTokenInvalid, Message: "Invalid or malformed authentication token."}
+	case errors.Is(err, auth.ErrTokenExpired):
+		return &models.APIError{Status: http.StatusUnauthorized, Code: models.ErrCodeTokenExpired, Message: "Authentication token has expired."}
+	case errors.Is(err, auth.ErrForbidden):
+		return &models.APIError{Status: http.StatusForbidden, Code: models.ErrCodeForbidden, Message: err.Error()}
+	case errors.Is(err, storage.ErrColumnNotFound) ||
+		errors.Is(err, storage.ErrTypeMismatch) ||
+		errors.Is(err, storage.ErrInvalidFilterValue) || // Include filter value error
+		errors.Is(err, storage.ErrInvalidDatabaseFile) ||
+		errors.Is(err, storage.ErrBackupKeyUnavailable) ||
+		errors.Is(err, storage.ErrUnknownDataRoot) ||
+		errors.Is(err, storage.ErrInvalidSeed) ||
+		errors.Is(err, auth.ErrBadRequest):
+		return &models.APIError{Status: http.StatusBadRequest, Code: models.ErrCodeBadRequest, Message: err.Error()}
+	case errors.Is(err, storage.ErrUserDataUnsupported):
+		return &models.APIError{Status: http.StatusNotImplemented, Code: models.ErrCodeNotImplemented, Message: "This operation is not supported by the configured storage backend."}
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded):
+		// RequestTimeout cancelled the request; the storage error may be a plain SQLite interrupt
+		return &models.APIError{Status: http.StatusGatewayTimeout, Code: models.ErrCodeTimeout, Message: requestTimeoutMessage}
+	case errors.Is(err, requestctx.ErrMissing) || errors.Is(err, requestctx.ErrWrongType):
+		// A route is missing the middleware that sets the value (or sets it wrongly)
+		customLog.Ctx(c.Request.Context()).Errorf("Request context error on %s: %v", c.FullPath(), err)
+		return &models.APIError{Status: http.StatusInternalServerError, Code: models.ErrCodeInternal, Message: "An unexpected internal server error occurred."}
+	case errors.Is(err, storage.ErrDatabaseQuarantined):
+		return &models.APIError{Status: http.StatusLocked, Code: models.ErrCodeQuarantined, Message: "Database is quarantined after a consistency check; contact an administrator."}
+	case errors.Is(err, storage.ErrMetadataUnavailable):
+		retryAfter := 1
+		var unavailableErr *storage.MetadataUnavailableError
+		if errors.As(err, &unavailableErr) {
+			retryAfter = max(int(math.Ceil(unavailableErr.RetryAfter.Seconds())), 1)
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		return &models.APIError{Status: http.StatusServiceUnavailable, Code: models.ErrCodeMetadataUnavailable, Message: "The server cannot reach its metadata database, please retry shortly."}
+	case errors.Is(err, storage.ErrDatabaseBusy):
+		c.Header("Retry-After", "1")
+		return &models.APIError{Status: http.StatusServiceUnavailable, Code: models.ErrCodeDatabaseBusy, Message: "Database is busy, please retry shortly."}
+	}
+
+	// A table's own limit can be raised by its owner; the server-wide one cannot
+	var rowLimitErr *storage.RowLimitError
+	if errors.As(err, &rowLimitErr) {
+		status := http.StatusInsufficientStorage
+		if rowLimitErr.Configured {
+			status = http.StatusForbidden
 		}
+		return &models.APIError{Status: status, Code: models.ErrCodeRowLimitReached,
+			Message: fmt.Sprintf("Table '%s' has reached its limit of %d rows.", rowLimitErr.Table, rowLimitErr.Limit),
+			Details: gin.H{"table": rowLimitErr.Table, "max_rows": rowLimitErr.Limit}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]gin.H, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			customLog.Ctx(c.Request.Context()).Warnf("Validation Error: Field %s failed on %s", fe.Field(), fe.Tag())
+			details = append(details, gin.H{"field": fe.Field(), "rule": fe.Tag()})
+		}
+		return &models.APIError{Status: http.StatusBadRequest, Code: models.ErrCodeValidationFailed,
+			Message: "Validation failed. Please check your input.", Details: details}
+	}
+
+	// --- Default/Fallback ---
+	customLog.Ctx(c.Request.Context()).Warnf("Unhandled error type: %T, Error: %v", err, err)
+	return &models.APIError{Status: http.StatusInternalServerError, Code: models.ErrCodeInternal, Message: "An unexpected internal server error occurred."}
+}
+
+// abortWithError ends the request with status and message and the status's default
+// code; ErrorHandler writes the response.
+func abortWithError(c *gin.Context, status int, message string) {
+	abortWithAPIError(c, models.NewAPIError(status, message))
+}
+
+// abortWithAPIError ends the request with apiErr; ErrorHandler writes the response.
+func abortWithAPIError(c *gin.Context, apiErr *models.APIError) {
+	_ = c.Error(apiErr)
+	c.Abort()
+}
+
+// tokenError is the response to a JWT that failed validation.
+func tokenError(err error) *models.APIError {
+	if errors.Is(err, auth.ErrTokenExpired) {
+		return &models.APIError{Status: http.StatusUnauthorized, Code: models.ErrCodeTokenExpired, Message: err.Error()}
+	}
+	message := "Invalid token"
+	if errors.Is(err, auth.ErrTokenMalformed) {
+		message = err.Error()
 	}
+	return &models.APIError{Status: http.StatusUnauthorized, Code: models.ErrCodeTokenInvalid, Message: message}
 }
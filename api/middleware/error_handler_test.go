@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), ErrorHandler())
+	router.GET("/explicit", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("lookup failed: %w", storage.ErrTableNotFound))
+		abortWithError(c, http.StatusNotFound, "Table 'users' not found.")
+	})
+	router.GET("/mapped", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("insert: %w", storage.ErrConstraintViolation))
+	})
+	router.GET("/unknown", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("disk on fire"))
+	})
+
+	cases := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+	}{
+		{"/explicit", http.StatusNotFound, models.ErrCodeNotFound, "Table 'users' not found."},
+		{"/mapped", http.StatusConflict, models.ErrCodeConflict, "insert: constraint violation"},
+		{"/unknown", http.StatusInternalServerError, models.ErrCodeInternal, "An unexpected internal server error occurred."},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		var body models.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid body %s: %v", tc.path, w.Body.String(), err)
+		}
+		if w.Code != tc.status || body.Error.Code != tc.code || body.Error.Message != tc.message {
+			t.Errorf("%s = %d %+v; want %d %s %q", tc.path, w.Code, body.Error, tc.status, tc.code, tc.message)
+		}
+		if body.Error.RequestID == "" || body.Error.RequestID != w.Header().Get(RequestIDHeader) {
+			t.Errorf("%s: request_id %q does not match header %q", tc.path, body.Error.RequestID, w.Header().Get(RequestIDHeader))
+		}
+	}
+}
+
+func TestErrorHandlerProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), ErrorHandler())
+	router.GET("/api/v1/databases/:db_name", func(c *gin.Context) {
+		abortWithError(c, http.StatusNotFound, "Database 'app' not found for your account.")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/databases/app", nil)
+	req.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Type"); got != models.ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q", got, models.ProblemContentType)
+	}
+	var problem models.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid body %s: %v", w.Body.String(), err)
+	}
+	want := models.Problem{
+		Type:      "urn:nebula:problem:not_found",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "Database 'app' not found for your account.",
+		Instance:  "/api/v1/databases/app",
+		Code:      models.ErrCodeNotFound,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	if problem != want {
+		t.Errorf("problem = %+v\nwant %+v", problem, want)
+	}
+}
+
+func TestPrefersProblemJSON(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                         false,
+		"*/*":                      false,
+		"application/json":         false,
+		"application/problem+json": true,
+		"application/json, application/problem+json":         true,
+		"application/json, application/problem+json;q=0.9":   false,
+		"application/problem+json;q=0, application/json;q=0": false,
+	} {
+		if got := prefersProblemJSON(accept); got != want {
+			t.Errorf("prefersProblemJSON(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
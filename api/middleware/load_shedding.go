@@ -0,0 +1,137 @@
+// api/middleware/load_shedding.go
+package middleware
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+// loadSheddingRetryAfter is the Retry-After hint, in seconds, sent with shed requests.
+const loadSheddingRetryAfter = "1"
+
+// Limits a shed request reached, as reported in its details and metrics.
+const (
+	shedLimitServer = "server"
+	shedLimitTenant = "tenant"
+)
+
+// ConcurrencyLimiter caps how many requests are served at once, overall and per
+// tenant, so a tenant sending a burst of requests cannot take every slot.
+type ConcurrencyLimiter struct {
+	mu        sync.Mutex
+	max       int
+	perTenant int
+	inFlight  int
+	tenants   map[string]int
+}
+
+// NewConcurrencyLimiter returns a limiter of maxInFlight requests in flight, at most
+// perTenant of them from one tenant. perTenant <= 0 allows a tenant a quarter.
+func NewConcurrencyLimiter(maxInFlight, perTenant int) *ConcurrencyLimiter {
+	if perTenant <= 0 {
+		perTenant = (maxInFlight + 3) / 4
+	}
+	return &ConcurrencyLimiter{max: maxInFlight, perTenant: min(perTenant, maxInFlight), tenants: make(map[string]int)}
+}
+
+// acquireServer takes one of the server's slots, or returns the limit that was reached.
+func (l *ConcurrencyLimiter) acquireServer() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight >= l.max {
+		return shedLimitServer, false
+	}
+	l.inFlight++
+	return "", true
+}
+
+func (l *ConcurrencyLimiter) releaseServer() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+}
+
+// acquireTenant takes one of tenant's slots, or returns the limit that was reached.
+func (l *ConcurrencyLimiter) acquireTenant(tenant string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tenants[tenant] >= l.perTenant {
+		return shedLimitTenant, false
+	}
+	l.tenants[tenant]++
+	return "", true
+}
+
+func (l *ConcurrencyLimiter) releaseTenant(tenant string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tenants[tenant]--; l.tenants[tenant] <= 0 {
+		delete(l.tenants, tenant)
+	}
+}
+
+// LoadShedding rejects requests with 503 and a Retry-After hint while the server's
+// slots are taken, instead of queueing them until every request is slow. Admin routes
+// and the legacy health checks are exempt so the server stays manageable under load.
+// The tenant's share is enforced by TenantShedding once the request is authenticated.
+func LoadShedding(l *ConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if alwaysServed(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		if limit, ok := l.acquireServer(); !ok {
+			shed(c, limit)
+			return
+		}
+		defer l.releaseServer()
+		c.Next()
+	}
+}
+
+// TenantShedding rejects requests with 503 while their tenant has its share of the
+// limiter's slots. It runs after authentication, so every credential of an account
+// (JWTs, API keys, signed requests) counts towards the same share; public routes fall
+// back to the client IP. A nil limiter sheds nothing.
+func TenantShedding(l *ConcurrencyLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil || alwaysServed(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		tenant := tenantKey(c)
+		if limit, ok := l.acquireTenant(tenant); !ok {
+			shed(c, limit)
+			return
+		}
+		defer l.releaseTenant(tenant)
+		c.Next()
+	}
+}
+
+// shed aborts c with 503 as shed by limit.
+func shed(c *gin.Context, limit string) {
+	metrics.ShedRequests.Inc(limit)
+	c.Header("Retry-After", loadSheddingRetryAfter)
+	abortWithAPIError(c, &models.APIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    models.ErrCodeOverloaded,
+		Message: "The server is handling too many requests, please retry shortly.",
+		Details: gin.H{"limit": limit},
+	})
+}
+
+// tenantKey identifies who a request is from: the authenticated account, or its client
+// IP on public routes.
+func tenantKey(c *gin.Context) string {
+	if userId, err := requestctx.UserID(c); err == nil {
+		return "user:" + userId
+	}
+	return "ip:" + getIP(c)
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	l := NewConcurrencyLimiter(3, 2)
+	for i := 0; i < 3; i++ {
+		if limit, ok := l.acquireServer(); !ok {
+			t.Fatalf("slot %d shed by %q", i, limit)
+		}
+	}
+	if limit, _ := l.acquireServer(); limit != shedLimitServer {
+		t.Errorf("fourth request shed by %q; want %q", limit, shedLimitServer)
+	}
+	l.releaseServer()
+	if limit, ok := l.acquireServer(); !ok {
+		t.Errorf("after a release: shed by %q", limit)
+	}
+
+	steps := []struct {
+		tenant string
+		limit  string // "" when admitted
+	}{
+		{"a", ""},
+		{"a", ""},
+		{"a", shedLimitTenant},
+		{"b", ""},
+	}
+	for i, step := range steps {
+		if limit, _ := l.acquireTenant(step.tenant); limit != step.limit {
+			t.Errorf("step %d: acquireTenant(%s) shed by %q; want %q", i, step.tenant, limit, step.limit)
+		}
+	}
+	l.releaseTenant("a")
+	if limit, ok := l.acquireTenant("a"); !ok {
+		t.Errorf("after a release: acquireTenant(a) shed by %q", limit)
+	}
+	l.releaseTenant("b")
+	if len(l.tenants) != 1 || l.tenants["a"] != 2 {
+		t.Errorf("tenants = %v; want a with 2", l.tenants)
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l := NewConcurrencyLimiter(1, 0)
+	router := gin.New()
+	router.Use(ErrorHandler(), LoadShedding(l))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/databases", ok)
+	router.GET("/api/v1/admin/stats/latency", ok)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer other")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := serve("/api/v1/databases"); w.Code != http.StatusOK {
+		t.Fatalf("idle server: status %d; want 200", w.Code)
+	}
+
+	l.acquireServer() // A request of another tenant takes the only slot
+	shed := metrics.ShedRequests.Counts()[shedLimitServer]
+	w := serve("/api/v1/databases")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != loadSheddingRetryAfter {
+		t.Errorf("saturated server: status %d, Retry-After %q; want 503 with a hint", w.Code, w.Header().Get("Retry-After"))
+	}
+	if got := metrics.ShedRequests.Counts()[shedLimitServer]; got != shed+1 {
+		t.Errorf("shed counter = %d; want %d", got, shed+1)
+	}
+	if w := serve("/api/v1/admin/stats/latency"); w.Code != http.StatusOK {
+		t.Errorf("admin route on a saturated server: status %d; want 200", w.Code)
+	}
+}
+
+func TestTenantShedding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l := NewConcurrencyLimiter(10, 1)
+	router := gin.New()
+	router.Use(ErrorHandler(), func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			requestctx.SetUserID(c, user)
+		}
+	}, TenantShedding(l))
+	router.GET("/api/v1/databases", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(user, credentials string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/databases", nil)
+		req.Header.Set("Authorization", credentials)
+		req.Header.Set("X-Test-User", user)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The account's share is taken, whatever credential the next request carries
+	l.acquireTenant("user:u1")
+	shed := metrics.ShedRequests.Counts()[shedLimitTenant]
+	for _, credentials := range []string{"Bearer jwt-1", "Bearer jwt-2", "NEBULA-HMAC-SHA256 KeyId=7, Timestamp=1, Signature=x"} {
+		if code := serve("u1", credentials); code != http.StatusServiceUnavailable {
+			t.Errorf("u1 with %q: status %d; want 503", credentials, code)
+		}
+	}
+	if got := metrics.ShedRequests.Counts()[shedLimitTenant]; got != shed+3 {
+		t.Errorf("shed counter = %d; want %d", got, shed+3)
+	}
+	if code := serve("u2", "Bearer jwt-1"); code != http.StatusOK {
+		t.Errorf("another account: status %d; want 200", code)
+	}
+	if code := serve("", ""); code != http.StatusOK {
+		t.Errorf("unauthenticated request: status %d; want 200", code)
+	}
+
+	disabled := gin.New()
+	disabled.GET("/api/v1/databases", TenantShedding(nil), func(c *gin.Context) { c.Status(http.StatusOK) })
+	w := httptest.NewRecorder()
+	disabled.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/databases", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("nil limiter: status %d; want 200", w.Code)
+	}
+}
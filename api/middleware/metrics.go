@@ -0,0 +1,42 @@
+// api/middleware/metrics.go
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+// RequestMetrics records the latency of every request to a registered route in
+// metrics.Requests, labelled with the route pattern and the handler serving it. It
+// should wrap ErrorHandler so writing error responses is included.
+func RequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return // Unmatched paths would add a series per probed URL
+		}
+		metrics.Requests.Observe(metrics.RouteKey{
+			Method:  c.Request.Method,
+			Route:   route,
+			Handler: shortHandlerName(c.HandlerName()),
+		}, time.Since(start))
+	}
+}
+
+// shortHandlerName trims the package path and method value suffix of a handler name,
+// e.g. handlers.(*RecordHandler).ListRecords.
+func shortHandlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
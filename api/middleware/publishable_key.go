@@ -0,0 +1,81 @@
+// api/middleware/publishable_key.go
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// authenticatePublishableKey looks up the publishable key of an ApiKey header and checks
+// that the request stays within what it grants, aborting the request otherwise.
+func authenticatePublishableKey(c *gin.Context, db storage.MetadataStore, credentials string) (*domain.PublishableKey, bool) {
+	key, err := db.FindPublishableKey(c.Request.Context(), credentials)
+	if err != nil {
+		if errors.Is(err, storage.ErrPublishableKeyNotFound) {
+			_ = c.Error(fmt.Errorf("%w: invalid publishable key", auth.ErrTokenMalformed))
+			abortWithError(c, http.StatusUnauthorized, "Invalid API key")
+			return nil, false
+		}
+		_ = c.Error(err)
+		c.Abort()
+		return nil, false
+	}
+	if apiErr := checkPublishableKey(c, key); apiErr != nil {
+		customLog.Ctx(c.Request.Context()).Warnf("CombinedAuthMiddleware: Publishable key %s refused: %s", key.ID, apiErr.Message)
+		abortWithAPIError(c, apiErr)
+		return nil, false
+	}
+	return key, true
+}
+
+// publishableKeyRoutes are the routes publishable keys may call, below the API version:
+// the record reads. Table configuration such as scripts and rules stays private.
+var publishableKeyRoutes = []string{
+	"/databases/:db_name/tables/:table_name/records",
+	"/databases/:db_name/tables/:table_name/records/:record_id",
+}
+
+// checkPublishableKey returns the error to answer with when a request goes beyond its
+// publishable key, or nil: the request must come from an allowed origin and read the
+// records of one of the key's tables. The database itself is checked by the handlers,
+// like for API keys.
+func checkPublishableKey(c *gin.Context, key *domain.PublishableKey) *models.APIError {
+	if !originMatcher(key.AllowedOrigins)(requestOrigin(c.Request)) {
+		return models.NewAPIError(http.StatusForbidden, "Publishable key is not allowed from this origin.")
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return models.NewAPIError(http.StatusForbidden, "Publishable keys are read-only.")
+	}
+	route := c.FullPath()
+	if !slices.ContainsFunc(publishableKeyRoutes, func(suffix string) bool { return strings.HasSuffix(route, suffix) }) {
+		return models.NewAPIError(http.StatusForbidden, "Publishable keys can only read records.")
+	}
+	if !slices.Contains(key.Tables, c.Param("table_name")) {
+		return models.NewAPIError(http.StatusForbidden, "Publishable key does not grant access to this table.")
+	}
+	return nil
+}
+
+// requestOrigin returns the origin of the page that made a request: its Origin header,
+// else the scheme and host of its Referer, or "" when it has neither.
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return origin
+	}
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || referer.Scheme == "" || referer.Host == "" {
+		return ""
+	}
+	return referer.Scheme + "://" + referer.Host
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestCheckPublishableKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := &domain.PublishableKey{ID: "k1", Tables: []string{"posts"}, AllowedOrigins: []string{"https://app.example.com", "https://*.example.org"}}
+	router := gin.New()
+	router.Use(ErrorHandler(), func(c *gin.Context) {
+		if apiErr := checkPublishableKey(c, key); apiErr != nil {
+			abortWithAPIError(c, apiErr)
+		}
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/databases", ok)
+	router.GET("/databases/:db_name/tables/:table_name/records", ok)
+	router.POST("/databases/:db_name/tables/:table_name/records", ok)
+	router.GET("/databases/:db_name/tables/:table_name/records/:record_id", ok)
+	router.GET("/databases/:db_name/tables/:table_name/records/duplicates", ok)
+	router.GET("/databases/:db_name/tables/:table_name/script", ok)
+	router.GET("/databases/:db_name/tables/:table_name/rules", ok)
+	router.GET("/databases/:db_name/export", ok)
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"allowed origin", http.MethodGet, "/databases/app/tables/posts/records", map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		{"wildcard origin", http.MethodGet, "/databases/app/tables/posts/records", map[string]string{"Origin": "https://blog.example.org"}, http.StatusOK},
+		{"referer", http.MethodGet, "/databases/app/tables/posts/records", map[string]string{"Referer": "https://app.example.com/posts?page=2"}, http.StatusOK},
+		{"other origin", http.MethodGet, "/databases/app/tables/posts/records", map[string]string{"Origin": "https://evil.example.net"}, http.StatusForbidden},
+		{"no origin", http.MethodGet, "/databases/app/tables/posts/records", nil, http.StatusForbidden},
+		{"write", http.MethodPost, "/databases/app/tables/posts/records", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"other table", http.MethodGet, "/databases/app/tables/users/records", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"record", http.MethodGet, "/databases/app/tables/posts/records/7", map[string]string{"Origin": "https://app.example.com"}, http.StatusOK},
+		{"duplicates", http.MethodGet, "/databases/app/tables/posts/records/duplicates", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"table script", http.MethodGet, "/databases/app/tables/posts/script", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"column rules", http.MethodGet, "/databases/app/tables/posts/rules", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"database route", http.MethodGet, "/databases/app/export", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+		{"no database", http.MethodGet, "/databases", map[string]string{"Origin": "https://app.example.com"}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("%s %s = %d; want %d (%s)", tc.method, tc.path, rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net"
+	"net/http"
 	"sync"
 	"time"
 
@@ -23,6 +24,13 @@ func NewRateLimiter() *RateLimiter {
 	}
 }
 
+// Keys returns the number of clients the limiter tracks.
+func (rl *RateLimiter) Keys() int {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return len(rl.requests)
+}
+
 func (rl *RateLimiter) Allow(ip string) bool {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
@@ -64,8 +72,7 @@ func RateLimitMiddleware(rl *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ip := getIP(c)
 		if !rl.Allow(ip) {
-			c.JSON(429, gin.H{"error": "Too many requests. Please wait."})
-			c.Abort()
+			abortWithError(c, http.StatusTooManyRequests, "Too many requests. Please wait.")
 			return
 		}
 		c.Next()
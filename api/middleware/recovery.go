@@ -0,0 +1,82 @@
+// api/middleware/recovery.go
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/errortracking"
+)
+
+// trackedHeaders are the request headers sent with error reports; others may hold
+// credentials.
+var trackedHeaders = []string{"Accept", "Content-Type", "Content-Length", "Origin", "Referer", "User-Agent"}
+
+// Recovery turns a panic in a later handler into a 500 response in the usual error
+// format, logs it as one structured entry with the route and stack, and reports it to
+// error tracking (see internal/errortracking). It replaces gin.Recovery, which writes
+// an empty 500 and an unstructured dump. Panics with http.ErrAbortHandler are
+// re-raised so net/http aborts the response as intended.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+
+			customLog.Ctx(c.Request.Context()).WithFields(logrus.Fields{
+				"panic":  fmt.Sprint(recovered),
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"route":  c.FullPath(),
+				"stack":  string(debug.Stack()),
+			}).Error("Recovered from panic while handling request")
+			errortracking.CapturePanic(c.Request.Context(), recovered, trackedRequest(c))
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			writeError(c, &models.APIError{Status: http.StatusInternalServerError, Code: models.ErrCodeInternal, Message: "An unexpected internal server error occurred."})
+		}()
+		c.Next()
+	}
+}
+
+// trackedRequest describes the request for error reports, without credentials. Share
+// tokens in the path are redacted.
+func trackedRequest(c *gin.Context) errortracking.Request {
+	path := c.Request.URL.Path
+	if token := c.Param("token"); token != "" {
+		path = strings.Replace(path, token, redacted, 1)
+	}
+	headers := make(map[string]string)
+	for _, name := range trackedHeaders {
+		if value := c.GetHeader(name); value != "" {
+			headers[name] = value
+		}
+	}
+	userId, _ := requestctx.UserID(c) // Empty for unauthenticated requests
+	return errortracking.Request{
+		Method:    c.Request.Method,
+		URL:       path,
+		Query:     c.Request.URL.RawQuery,
+		Route:     c.FullPath(),
+		Headers:   headers,
+		ClientIP:  c.ClientIP(),
+		RequestID: requestctx.RequestID(c),
+		UserID:    userId,
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery(), RequestID(), ErrorHandler())
+	router.GET("/panic", func(c *gin.Context) { panic("nil map write") })
+	router.GET("/missing-user", func(c *gin.Context) {
+		if _, err := requestctx.UserID(c); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/panic", "/missing-user"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body models.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid body %q: %v", path, w.Body.String(), err)
+		}
+		if w.Code != http.StatusInternalServerError || body.Error.Code != models.ErrCodeInternal {
+			t.Errorf("%s = %d %+v; want 500 %s", path, w.Code, body.Error, models.ErrCodeInternal)
+		}
+		if body.Error.RequestID == "" {
+			t.Errorf("%s: response has no request_id", path)
+		}
+	}
+}
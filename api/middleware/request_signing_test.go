@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestSignedRequestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	cfg := &config.Config{JWTSecret: "test-secret", MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db", RequestSigningMaxSkew: time.Minute, RequestSigningMaxBodySize: 64}
+	store, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "app", "app.db"); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, _ := store.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+	key, err := store.StoreAPIKey(ctx, "u1", databaseID)
+	if err != nil {
+		t.Fatalf("StoreAPIKey: %v", err)
+	}
+	keyID, _ := store.FindAPIKeyID(ctx, databaseID)
+
+	router := gin.New()
+	router.Use(ErrorHandler(), CombinedAuthMiddleware(store, cfg))
+	router.POST("/databases/:db_name/tables/:table_name/records", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		if id, scoped, _ := requestctx.ScopedDatabaseID(c); !scoped || id != databaseID || string(body) != `{"name":"a"}` {
+			t.Errorf("scoped database = %d, %t, body %q", id, scoped, body)
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	const path = "/databases/app/tables/items/records"
+	sendSigned := func(signature string, timestamp int64, sentBody string, query url.Values) int {
+		req := httptest.NewRequest(http.MethodPost, path+"?"+query.Encode(), strings.NewReader(sentBody))
+		req.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%d, Timestamp=%d, Signature=%s", auth.RequestSigningScheme, keyID, timestamp, signature))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	send := func(secret string, timestamp int64, signedBody, sentBody string, query url.Values) int {
+		return sendSigned(auth.RequestSignature(secret, http.MethodPost, path, query, timestamp, []byte(signedBody)), timestamp, sentBody, query)
+	}
+	now := time.Now().Unix()
+	body := `{"name":"a"}`
+	if code := send(key, now, body, body, url.Values{"x": {"1"}}); code != http.StatusCreated {
+		t.Errorf("signed request = %d; want 201", code)
+	}
+	if code := send(key, now, body, body, url.Values{"x": {"1"}}); code != http.StatusUnauthorized {
+		t.Errorf("replayed request = %d; want 401", code)
+	}
+	recased := strings.ToUpper(auth.RequestSignature(key, http.MethodPost, path, url.Values{"x": {"1"}}, now, []byte(body)))
+	if code := sendSigned(recased, now, body, url.Values{"x": {"1"}}); code != http.StatusUnauthorized {
+		t.Errorf("replayed request with re-cased signature = %d; want 401", code)
+	}
+	if code := send(key, now, body, `{"name":"b"}`, nil); code != http.StatusUnauthorized {
+		t.Errorf("changed body = %d; want 401", code)
+	}
+	if code := send("neb_wrong", now, body, body, nil); code != http.StatusUnauthorized {
+		t.Errorf("wrong key = %d; want 401", code)
+	}
+	if code := send(key, now-120, body, body, nil); code != http.StatusUnauthorized {
+		t.Errorf("stale request = %d; want 401", code)
+	}
+	large := `{"name":"` + strings.Repeat("a", 64) + `"}`
+	if code := send(key, now, large, large, nil); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body = %d; want 413", code)
+	}
+}
@@ -0,0 +1,64 @@
+// api/middleware/service_mode.go
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+)
+
+// serviceModeRetryAfter is the Retry-After hint, in seconds, sent with 503 responses.
+const serviceModeRetryAfter = "120"
+
+// ServiceMode rejects requests with 503 while the instance is in read-only or
+// maintenance mode (see internal/servicemode). In read-only mode, safe methods, login and GraphQL
+// requests pass; the GraphQL handler rejects mutations itself. Writes are rejected the
+// same way with 507 while the server protects itself from running out of disk space.
+func ServiceMode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := servicemode.Current()
+		if (state.Mode == servicemode.Normal && state.WriteProtection == "") || alwaysServed(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		if state.Mode != servicemode.Maintenance && allowedWhenReadOnly(c.Request) {
+			c.Next()
+			return
+		}
+
+		if state.Mode == servicemode.Normal {
+			abortWithAPIError(c, &models.APIError{
+				Status:  http.StatusInsufficientStorage,
+				Code:    models.ErrCodeInsufficientStorage,
+				Message: servicemode.RejectionMessage(),
+				Details: gin.H{"reason": state.WriteProtection},
+			})
+			return
+		}
+		c.Header("Retry-After", serviceModeRetryAfter)
+		abortWithAPIError(c, &models.APIError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    models.ErrCodeServiceUnavailable,
+			Message: servicemode.RejectionMessage(),
+			Details: gin.H{"mode": state.Mode},
+		})
+	}
+}
+
+// alwaysServed reports paths exempt from every mode: admin routes, so the mode can be
+// switched back, and the legacy health checks, so monitors don't restart the server.
+func alwaysServed(path string) bool {
+	return isAdminPath(path) || path == "/health" || path == "/ping"
+}
+
+func allowedWhenReadOnly(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return r.URL.Path == "/auth/login" || strings.HasSuffix(r.URL.Path, "/graphql")
+}
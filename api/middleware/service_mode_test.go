@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+)
+
+func TestServiceMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), ServiceMode())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/databases", ok)
+	router.POST("/api/v1/databases", ok)
+	router.POST("/auth/login", ok)
+	router.PUT("/api/v1/admin/mode", ok)
+	defer servicemode.Set(servicemode.Normal, "")
+	defer servicemode.SetWriteProtection("")
+
+	const lowDisk = "the server is low on disk space"
+	cases := []struct {
+		mode       servicemode.Mode
+		protection string
+		method     string
+		path       string
+		want       int
+	}{
+		{servicemode.Normal, "", http.MethodPost, "/api/v1/databases", http.StatusOK},
+		{servicemode.ReadOnly, "", http.MethodGet, "/api/v1/databases", http.StatusOK},
+		{servicemode.ReadOnly, "", http.MethodPost, "/api/v1/databases", http.StatusServiceUnavailable},
+		{servicemode.ReadOnly, "", http.MethodPost, "/auth/login", http.StatusOK},
+		{servicemode.Maintenance, "", http.MethodGet, "/api/v1/databases", http.StatusServiceUnavailable},
+		{servicemode.Maintenance, "", http.MethodPut, "/api/v1/admin/mode", http.StatusOK},
+		{servicemode.Normal, lowDisk, http.MethodPost, "/api/v1/databases", http.StatusInsufficientStorage},
+		{servicemode.Normal, lowDisk, http.MethodGet, "/api/v1/databases", http.StatusOK},
+		{servicemode.Normal, lowDisk, http.MethodPut, "/api/v1/admin/mode", http.StatusOK},
+		{servicemode.Maintenance, lowDisk, http.MethodGet, "/api/v1/databases", http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		servicemode.Set(tc.mode, "")
+		servicemode.SetWriteProtection(tc.protection)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s %s in %s mode (protection %q) = %d, want %d", tc.method, tc.path, tc.mode, tc.protection, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+func TestSignedURLAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(ErrorHandler(), CombinedAuthMiddleware(nil, cfg))
+	ok := func(c *gin.Context) {
+		if userID, _ := requestctx.UserID(c); userID != "u1" {
+			t.Errorf("user = %q; want u1", userID)
+		}
+		c.Status(http.StatusOK)
+	}
+	api.GET("/databases/:db_name/tables/:table_name/records/:record_id", ok)
+	api.DELETE("/databases/:db_name/tables/:table_name/records/:record_id", ok)
+	api.GET("/databases/:db_name/tables", ok)
+
+	sign := func(path string, expiresAt time.Time) string {
+		return path + "?" + auth.SignURL(path, url.Values{"fields": {"id"}}, "u1", "test-secret", expiresAt).Encode()
+	}
+	record := "/api/v1/databases/app/tables/orders/records/42"
+	later := time.Now().Add(time.Minute)
+	cases := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{"record", http.MethodGet, sign(record, later), http.StatusOK},
+		{"expired", http.MethodGet, sign(record, time.Now().Add(-time.Minute)), http.StatusUnauthorized},
+		{"other record", http.MethodGet, "/api/v1/databases/app/tables/orders/records/43?" + mustQuery(sign(record, later)).Encode(), http.StatusUnauthorized},
+		{"write", http.MethodDelete, sign(record, later), http.StatusUnauthorized},
+		{"unsignable route", http.MethodGet, sign("/api/v1/databases/app/tables", later), http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+			if rec.Code != tc.want {
+				t.Errorf("%s %s = %d; want %d (%s)", tc.method, tc.target, rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+// mustQuery returns the query of a URL.
+func mustQuery(rawURL string) url.Values {
+	parsed, _ := url.Parse(rawURL)
+	return parsed.Query()
+}
@@ -0,0 +1,58 @@
+// api/middleware/timeout.go
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTimeoutMessage is the error returned with 504 responses.
+const requestTimeoutMessage = "The request took too long and was cancelled."
+
+// RequestTimeout gives each request a context deadline, so storage calls made with
+// c.Request.Context() are cancelled (SQLite queries are interrupted) once it passes.
+// The handler then returns and the request is answered with 504, unless a response was
+// already started. Long-running routes (exports, imports, streams, backups and
+// restores) are exempt. A timeout of 0 disables the middleware.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 || longRunning(c.Request) {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			customLog.Ctx(ctx).Warnf("Request %s %s timed out after %v", c.Request.Method, c.Request.URL.Path, timeout)
+			abortWithError(c, http.StatusGatewayTimeout, requestTimeoutMessage)
+		}
+	}
+}
+
+// longRunning reports requests whose duration depends on the size of the data moved
+// rather than on a stuck operation.
+func longRunning(r *http.Request) bool {
+	path := r.URL.Path
+	if strings.HasSuffix(path, "/export") || strings.HasSuffix(path, "/import") || strings.HasSuffix(path, "/restore") || strings.HasSuffix(path, "/maintenance") {
+		return true
+	}
+	if r.Method == http.MethodPost {
+		// Backups, clones, table copies, generated and seeded records, deduplication
+		for _, suffix := range []string{"/backups", "/clone", "/copy", "/generate", "/seed", "/dedupe"} {
+			if strings.HasSuffix(path, suffix) {
+				return true
+			}
+		}
+	}
+	return r.URL.Query().Get("stream") != ""
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), RequestTimeout(20*time.Millisecond))
+	// Stands in for a query that is interrupted when the request context is cancelled
+	hung := func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		_ = c.Error(fmt.Errorf("database error: %w", errors.New("interrupted")))
+	}
+	router.GET("/api/v1/databases/:db_name/tables", hung)
+	router.GET("/api/v1/databases/:db_name/silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.Status(http.StatusInternalServerError)
+		case <-time.After(50 * time.Millisecond):
+			c.Status(http.StatusOK)
+		}
+	}
+	router.GET("/api/v1/databases/:db_name/export", slow)
+	router.POST("/api/v1/databases/:db_name/clone", slow)
+	router.POST("/api/v1/databases/:db_name/seed", slow)
+	router.POST("/api/v1/databases/:db_name/tables/:table_name/copy", slow)
+	router.POST("/api/v1/databases/:db_name/tables/:table_name/generate", slow)
+	router.POST("/api/v1/databases/:db_name/tables/:table_name/records/dedupe", slow)
+
+	cases := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/databases/app/tables", http.StatusGatewayTimeout},
+		{http.MethodGet, "/api/v1/databases/app/silent", http.StatusGatewayTimeout},
+		{http.MethodGet, "/api/v1/databases/app/export", http.StatusOK},
+		{http.MethodPost, "/api/v1/databases/app/clone", http.StatusOK},
+		{http.MethodPost, "/api/v1/databases/app/seed", http.StatusOK},
+		{http.MethodPost, "/api/v1/databases/app/tables/items/copy", http.StatusOK},
+		{http.MethodPost, "/api/v1/databases/app/tables/items/generate", http.StatusOK},
+		{http.MethodPost, "/api/v1/databases/app/tables/items/records/dedupe", http.StatusOK},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s %s = %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// api/middleware/token_scope.go
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/models"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+// credentialRoutes create or change credentials and subscriptions, by method and route
+// suffix. Anything made with a scoped token this way would outlive the token's expiry.
+var credentialRoutes = []struct{ method, route string }{
+	{http.MethodPost, "/databases/:db_name/tokens"},
+	{http.MethodPost, "/databases/:db_name/signed-urls"},
+	{http.MethodPost, "/databases/:db_name/shares"},
+	{http.MethodPost, "/databases/:db_name/webhooks"},
+	{http.MethodPatch, "/databases/:db_name/webhooks/:webhook_id"},
+	{http.MethodPost, "/databases/:db_name/webhooks/:webhook_id/rotate-secret"},
+	{http.MethodPost, "/databases/:db_name/forms"},
+	{http.MethodPatch, "/databases/:db_name/forms/:form_id"},
+}
+
+// checkTokenScope returns the error to answer with when a request goes beyond the scope
+// of its scoped access token, or nil. The database itself is checked by the handlers,
+// like for DB-scoped API keys; routes that address no database, and the routes creating
+// credentials or subscriptions, are refused here.
+func checkTokenScope(c *gin.Context, scope *auth.TokenScope) *models.APIError {
+	if c.Param("db_name") == "" {
+		return models.NewAPIError(http.StatusForbidden, "Scoped tokens only grant access to a database.")
+	}
+	if isCredentialRoute(c) {
+		return models.NewAPIError(http.StatusForbidden, "Scoped tokens cannot create tokens, signed URLs, share links, webhooks or forms.")
+	}
+	if scope.Table != "" && c.Param("table_name") != scope.Table {
+		return models.NewAPIError(http.StatusForbidden, fmt.Sprintf("Scoped token only grants access to table '%s'.", scope.Table))
+	}
+	if scope.ReadOnly && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return models.NewAPIError(http.StatusForbidden, "Scoped token is read-only.")
+	}
+	return nil
+}
+
+// isCredentialRoute reports whether the request goes to one of credentialRoutes.
+func isCredentialRoute(c *gin.Context) bool {
+	for _, route := range credentialRoutes {
+		if c.Request.Method == route.method && strings.HasSuffix(c.FullPath(), route.route) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+func TestScopedTokenAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	router := gin.New()
+	router.Use(ErrorHandler(), CombinedAuthMiddleware(nil, cfg))
+	ok := func(c *gin.Context) {
+		if id, scoped, _ := requestctx.ScopedDatabaseID(c); !scoped || id != 7 {
+			t.Errorf("scoped database = %d, %t; want 7", id, scoped)
+		}
+		c.Status(http.StatusOK)
+	}
+	router.GET("/databases", ok)
+	router.GET("/databases/:db_name/tables/:table_name/records", ok)
+	router.POST("/databases/:db_name/tables/:table_name/records", ok)
+	router.GET("/databases/:db_name/export", ok)
+	router.POST("/databases/:db_name/tokens", ok)
+	router.POST("/databases/:db_name/shares", ok)
+	router.POST("/databases/:db_name/webhooks", ok)
+	router.POST("/databases/:db_name/forms", ok)
+
+	token := func(table string, readOnly bool, expiresAt time.Time) string {
+		token, err := auth.GenerateScopedToken(auth.TokenScope{UserID: "u1", DatabaseID: 7, Table: table, ReadOnly: readOnly}, "test-secret", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	later := time.Now().Add(time.Minute)
+	cases := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+	}{
+		{"table read", token("items", true, later), http.MethodGet, "/databases/app/tables/items/records", http.StatusOK},
+		{"read-only write", token("items", true, later), http.MethodPost, "/databases/app/tables/items/records", http.StatusForbidden},
+		{"other table", token("items", true, later), http.MethodGet, "/databases/app/tables/users/records", http.StatusForbidden},
+		{"table token on database route", token("items", true, later), http.MethodGet, "/databases/app/export", http.StatusForbidden},
+		{"database token", token("", false, later), http.MethodPost, "/databases/app/tables/users/records", http.StatusOK},
+		{"no database", token("", false, later), http.MethodGet, "/databases", http.StatusForbidden},
+		{"mint token", token("", false, later), http.MethodPost, "/databases/app/tokens", http.StatusForbidden},
+		{"share link", token("", false, later), http.MethodPost, "/databases/app/shares", http.StatusForbidden},
+		{"webhook", token("", false, later), http.MethodPost, "/databases/app/webhooks", http.StatusForbidden},
+		{"form", token("", false, later), http.MethodPost, "/databases/app/forms", http.StatusForbidden},
+		{"expired", token("", true, time.Now().Add(-time.Minute)), http.MethodGet, "/databases/app/export", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("%s %s = %d; want %d (%s)", tc.method, tc.path, rec.Code, tc.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// api/middleware/usage.go
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Annany2002/nebula-backend/api/requestctx"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// DatabaseUsage counts the authenticated requests to routes of a database (those with
+// a :db_name parameter) with their status and body sizes, for the usage statistics of
+// the database, and every request authenticated with an API key, for the statistics
+// of the key. It must wrap ErrorHandler so error responses are counted with their
+// final status.
+func DatabaseUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counter := &countingReader{r: c.Request.Body}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = counter
+		}
+
+		c.Next()
+
+		// Set by the authentication middleware of the route, if it let the request in
+		if requestctx.IsAPIKey(c) {
+			if databaseId, scoped, err := requestctx.ScopedDatabaseID(c); err == nil && scoped {
+				storage.RecordAPIKeyUsage(databaseId, c.Request.Method, c.FullPath(), c.ClientIP(), c.Writer.Status())
+			}
+		}
+		userId, err := requestctx.UserID(c)
+		dbName := c.Param("db_name")
+		// Admins browsing a database are not its traffic
+		if err != nil || !core.IsValidIdentifier(dbName) || requestctx.ActingAdmin(c) != "" {
+			return
+		}
+		// Handlers that ignore the body still received it over the wire
+		bytesIn := max(counter.n, c.Request.ContentLength)
+		storage.RecordDatabaseUsage(userId, dbName, c.Writer.Status(), bytesIn, int64(max(c.Writer.Size(), 0)))
+	}
+}
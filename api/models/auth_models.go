@@ -55,3 +55,8 @@ type CustomClaims struct {
 	UserID string `json:"userId"`
 	jwt.RegisteredClaims
 }
+
+// EraseAccountRequest confirms the erasure of the caller's own account
+type EraseAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
@@ -1,28 +1,271 @@
 // api/models/database_models.go
 package models
 
+import (
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
 // --- Database/Schema Request Structs ---
 
 // CreateDatabaseRequest defines the structure for creating a database registration
 type CreateDatabaseRequest struct {
-	DBName string `json:"db_name" binding:"required"`
+	DBName string            `json:"db_name" binding:"required"`
+	Labels map[string]string `json:"labels"` // Key/value labels organizing the database
+	// Ephemeral databases are deleted once TTLHours (default EPHEMERAL_DATABASE_TTL_HOURS)
+	// have passed; setting TTLHours makes a database ephemeral
+	Ephemeral bool `json:"ephemeral"`
+	TTLHours  int  `json:"ttl_hours" binding:"min=0"`
+}
+
+// SetDatabaseLabelsRequest replaces the labels of a database.
+type SetDatabaseLabelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// CreateWebhookRequest registers a webhook notified of writes to a database.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Description string   `json:"description" binding:"max=200"`
+	Events      []string `json:"events"`  // Omitted or empty for every event
+	Enabled     *bool    `json:"enabled"` // Defaults to true
+}
+
+// UpdateWebhookRequest changes the fields of a webhook that are set.
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url"`
+	Description *string   `json:"description" binding:"omitempty,max=200"`
+	Events      *[]string `json:"events"` // Empty for every event
+	Enabled     *bool     `json:"enabled"`
+}
+
+// CreateFormRequest creates a public submission form for a table.
+type CreateFormRequest struct {
+	Table           string   `json:"table" binding:"required"`
+	Description     string   `json:"description" binding:"max=200"`
+	Columns         []string `json:"columns" binding:"required"` // Writable columns
+	AllowedOrigins  []string `json:"allowed_origins"`            // Omitted or empty for any origin
+	CaptchaProvider string   `json:"captcha_provider"`           // hcaptcha, recaptcha or turnstile; empty for none
+	CaptchaSecret   string   `json:"captcha_secret"`
+	RedirectURL     string   `json:"redirect_url"`
+	RateLimit       *int     `json:"rate_limit"` // Submissions per client IP and hour; defaults to 10
+	Enabled         *bool    `json:"enabled"`    // Defaults to true
+}
+
+// UpdateFormRequest changes the fields of a form that are set.
+type UpdateFormRequest struct {
+	Description     *string   `json:"description" binding:"omitempty,max=200"`
+	Columns         *[]string `json:"columns"`
+	AllowedOrigins  *[]string `json:"allowed_origins"`
+	CaptchaProvider *string   `json:"captcha_provider"` // Empty turns CAPTCHA verification off
+	CaptchaSecret   *string   `json:"captcha_secret"`
+	RedirectURL     *string   `json:"redirect_url"`
+	RateLimit       *int      `json:"rate_limit"`
+	Enabled         *bool     `json:"enabled"`
+}
+
+// CreateShareLinkRequest creates a read-only share link of a table or a saved query.
+type CreateShareLinkRequest struct {
+	Table          string `json:"table"` // Set exactly one of Table and Query
+	Query          string `json:"query"` // Name of a saved query
+	Description    string `json:"description" binding:"max=200"`
+	ExpiresInHours *int   `json:"expires_in_hours"` // Defaults to 168 (7 days)
+}
+
+// CreatePublishableKeyRequest creates a browser-safe key that reads some tables of a
+// database from allowed origins.
+type CreatePublishableKeyRequest struct {
+	Tables         []string `json:"tables" binding:"required"`          // Readable tables
+	AllowedOrigins []string `json:"allowed_origins" binding:"required"` // e.g. https://app.example.com or https://*.example.com
+	Description    string   `json:"description" binding:"max=200"`
+}
+
+// CreateScopedTokenRequest mints a short-lived access token limited to one database,
+// optionally one table of it.
+type CreateScopedTokenRequest struct {
+	Table            string `json:"table"`              // Every table when empty
+	ReadOnly         *bool  `json:"read_only"`          // Defaults to true
+	ExpiresInMinutes *int   `json:"expires_in_minutes"` // Defaults to 15
+}
+
+// CreateSignedURLRequest signs a URL that reads from a database without credentials.
+type CreateSignedURLRequest struct {
+	Path             string `json:"path" binding:"required"` // Relative to the database, e.g. "tables/orders/records/42"
+	ExpiresInMinutes *int   `json:"expires_in_minutes"`      // Defaults to 60
 }
 
 // ColumnDefinition represents a single column in a table schema request
 type ColumnDefinition struct {
-	Name string `json:"name" binding:"required"`
-	Type string `json:"type" binding:"required"` // e.g., "TEXT", "INTEGER", "REAL", "BLOB"
+	Name        string `json:"name" binding:"required"`
+	Type        string `json:"type" binding:"required"` // e.g., "TEXT", "INTEGER", "REAL", "BLOB"
+	Default     any    `json:"default,omitempty"`       // Applied when a new record omits the column
+	Collate     string `json:"collate,omitempty"`       // "nocase" compares TEXT values case-insensitively
+	Description string `json:"description,omitempty"`   // Documents the column
 }
 
 // CreateSchemaRequest defines the structure for the schema creation request body
 type CreateSchemaRequest struct {
-	TableName string             `json:"table_name" binding:"required"`
-	Columns   []ColumnDefinition `json:"columns" binding:"required_without=Schema"`
-	Schema    []ColumnDefinition `json:"schema" binding:"required_without=Columns"`
+	TableName   string             `json:"table_name" binding:"required"`
+	Columns     []ColumnDefinition `json:"columns" binding:"required_without=Schema"`
+	Schema      []ColumnDefinition `json:"schema" binding:"required_without=Columns"`
+	Strict      bool               `json:"strict"`      // Have the database enforce column types (SQLite STRICT table)
+	Description string             `json:"description"` // Documents the table
+}
+
+// SetTableDescriptionRequest replaces the descriptions of a table and its columns.
+type SetTableDescriptionRequest struct {
+	Description string            `json:"description"`
+	Columns     map[string]string `json:"columns"` // Column name to description
+}
+
+// SetColumnRulesRequest replaces the validation rules of a table; an empty list removes them.
+type SetColumnRulesRequest struct {
+	Rules []domain.ColumnRule `json:"rules" binding:"required"`
+}
+
+// SetTableScriptRequest sets the JavaScript hooks run around record writes to a table.
+type SetTableScriptRequest struct {
+	Source string `json:"source" binding:"required"`
+}
+
+// TableScriptResponse returns a table's script and the hooks it defines.
+type TableScriptResponse struct {
+	TableName string   `json:"table_name"`
+	Source    string   `json:"source"`
+	Hooks     []string `json:"hooks"`
+}
+
+// SetTableOptionsRequest sets the behaviour switches of a table; omitted switches keep
+// their current value.
+type SetTableOptionsRequest struct {
+	AutoColumns  *bool   `json:"auto_columns"`
+	DefaultSort  *string `json:"default_sort"`  // "" removes the default sort
+	DefaultOrder *string `json:"default_order"` // "asc" (the default) or "desc"
+	MaxRows      *int64  `json:"max_rows"`      // 0 removes the table's own row limit
+}
+
+// TableOptionsResponse returns the behaviour switches of a table.
+type TableOptionsResponse struct {
+	TableName string `json:"table_name"`
+	domain.TableOptions
+	EffectiveMaxRows int64 `json:"effective_max_rows"` // Row limit that applies, with the server's; 0 for none
+}
+
+// PutSavedQueryRequest defines a saved query; its name comes from the URL path.
+type PutSavedQueryRequest struct {
+	TableName   string              `json:"table_name" binding:"required"`
+	Description string              `json:"description"`
+	Query       map[string]string   `json:"query" binding:"required"`
+	Params      []domain.QueryParam `json:"params"`
+}
+
+// BatchGetRecordsRequest lists the IDs of the records to fetch, in response order.
+type BatchGetRecordsRequest struct {
+	IDs []int64 `json:"ids" binding:"required,min=1,max=1000"`
+}
+
+// DedupeRecordsRequest names the columns whose repeated values make records duplicates,
+// and which record of each group to keep ("first", the default, or "last" by id).
+type DedupeRecordsRequest struct {
+	Columns []string `json:"columns" binding:"required,min=1,max=10"`
+	Keep    string   `json:"keep" binding:"omitempty,oneof=first last"`
+}
+
+// AdminDatabase is a database of any account, as listed to admins.
+type AdminDatabase struct {
+	DatabaseID    int64     `json:"database_id"`
+	DBName        string    `json:"db_name"`
+	UserID        string    `json:"user_id"`
+	OwnerEmail    string    `json:"owner_email"`
+	OwnerUsername string    `json:"owner_username"`
+	CreatedAt     time.Time `json:"created_at"`
+	Quarantine    string    `json:"quarantine,omitempty"` // Why requests to it are refused
 }
 
 // CreateAPIKeyResponse returns the newly generated API key ONCE.
 type CreateAPIKeyResponse struct {
+	ID      int64  `json:"id"`      // Identifies the key, e.g. for its usage statistics
 	APIKey  string `json:"api_key"` // The full key (prefix + secret). Store securely!
 	Message string `json:"message,omitempty"`
 }
+
+// RestoreDatabaseRequest selects a stored backup to restore. Uploaded .db files are
+// sent as multipart form data instead.
+type RestoreDatabaseRequest struct {
+	BackupID string `json:"backup_id" binding:"required"`
+}
+
+// CloneDatabaseRequest defines the structure for cloning a database into a new one.
+type CloneDatabaseRequest struct {
+	TargetDBName string `json:"target_db_name" binding:"required"`
+	IncludeData  *bool  `json:"include_data"` // Defaults to true
+}
+
+// CopyTableRequest defines where a table is copied to.
+type CopyTableRequest struct {
+	TargetDBName    string `json:"target_db_name" binding:"required"`
+	TargetTableName string `json:"target_table_name"` // Defaults to the source table's name
+	IncludeData     *bool  `json:"include_data"`      // Defaults to true
+}
+
+// MaintenanceRequest selects a maintenance operation to run against a database.
+type MaintenanceRequest struct {
+	Operation string `json:"operation" binding:"required,oneof=vacuum analyze wal_checkpoint"`
+	Async     bool   `json:"async"` // Run as a background job and return its ID
+}
+
+// LogLevelRequest changes the server's minimum log level.
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error"`
+}
+
+// ServiceModeRequest switches the instance between normal, read-only and maintenance mode.
+type ServiceModeRequest struct {
+	Mode    string `json:"mode" binding:"required,oneof=normal read_only maintenance"`
+	Message string `json:"message" binding:"max=500"` // Shown to rejected clients
+}
+
+// ReadReplicaRequest designates a read replica for a database.
+type ReadReplicaRequest struct {
+	MaxLagSeconds int `json:"max_lag_seconds" binding:"min=0,max=86400"` // How stale replica reads may be
+}
+
+// AdminReadReplica is a read replica designation with the state of the replica on this
+// instance; Status is nil until the refresher picked the designation up.
+type AdminReadReplica struct {
+	domain.ReadReplica
+	Status *ReadReplicaStatus `json:"status"`
+}
+
+// ReadReplicaStatus describes the state of a read replica.
+type ReadReplicaStatus struct {
+	InSync    bool       `json:"in_sync"`
+	SyncedAt  *time.Time `json:"synced_at"`
+	Reads     int64      `json:"reads"` // Reads served since the server started
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// RebalanceRequest starts moving databases between data roots to even out their free
+// space.
+type RebalanceRequest struct {
+	MaxMoves int  `json:"max_moves" binding:"min=0,max=1000"` // 0 uses the default of 20
+	DryRun   bool `json:"dry_run"`                            // Only plan the moves
+}
+
+// CollectOrphansRequest looks for database files no database is registered at.
+type CollectOrphansRequest struct {
+	DryRun bool `json:"dry_run"` // Only list the files
+}
+
+// ConsistencyCheckRequest cross-checks database registrations against their files.
+type ConsistencyCheckRequest struct {
+	Repair     bool `json:"repair"`     // Fix registrations and permissions where possible
+	Quarantine bool `json:"quarantine"` // Refuse requests to databases left broken
+}
+
+// RelocateRequest moves the databases of an account to another data root.
+type RelocateRequest struct {
+	DataRoot string `json:"data_root" binding:"required"`
+	DBName   string `json:"db_name"` // Only this database; all of the account's when empty
+}
@@ -0,0 +1,116 @@
+// api/models/error_models.go
+package models
+
+import "net/http"
+
+// --- Error Responses ---
+
+// Error codes sent in ErrorBody.Code. Clients branch on these instead of messages,
+// so a code never changes meaning once released.
+const (
+	ErrCodeBadRequest          = "bad_request"
+	ErrCodeValidationFailed    = "validation_failed"
+	ErrCodeUnauthorized        = "unauthorized"
+	ErrCodeInvalidCredentials  = "invalid_credentials"
+	ErrCodeTokenInvalid        = "token_invalid"
+	ErrCodeTokenExpired        = "token_expired"
+	ErrCodeForbidden           = "forbidden"
+	ErrCodeRowLimitReached     = "row_limit_reached"
+	ErrCodeNotFound            = "not_found"
+	ErrCodeConflict            = "conflict"
+	ErrCodePayloadTooLarge     = "payload_too_large"
+	ErrCodeRateLimited         = "rate_limited"
+	ErrCodeDatabaseBusy        = "database_busy"
+	ErrCodeMetadataUnavailable = "metadata_unavailable"
+	ErrCodeQuarantined         = "database_quarantined"
+	ErrCodeServiceUnavailable  = "service_unavailable"
+	ErrCodeInsufficientStorage = "insufficient_storage"
+	ErrCodeOverloaded          = "overloaded"
+	ErrCodeTimeout             = "timeout"
+	ErrCodeNotImplemented      = "not_implemented"
+	ErrCodeInternal            = "internal_error"
+)
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes what went wrong.
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`           // Human-readable; may change between releases
+	Details   any    `json:"details,omitempty"` // Code-specific, e.g. the invalid fields
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProblemContentType is the media type of Problem responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the RFC 7807 form of an error response, sent to clients that accept
+// application/problem+json. Code, Details and RequestID are extension members carrying
+// the same values as ErrorBody.
+type Problem struct {
+	Type      string `json:"type"`     // urn:nebula:problem:<code>
+	Title     string `json:"title"`    // Status text, the same for every occurrence
+	Status    int    `json:"status"`   // Same as the HTTP status
+	Detail    string `json:"detail"`   // Message of this occurrence
+	Instance  string `json:"instance"` // Request path
+	Code      string `json:"code"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProblemType returns the problem type URI of an error code.
+func ProblemType(code string) string {
+	return "urn:nebula:problem:" + code
+}
+
+// APIError selects the error response of a request. Handlers and middleware record it
+// with c.Error and abort; middleware.ErrorHandler renders it. Errors of other types are
+// mapped to a response by the error handler.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details any
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// NewAPIError returns an APIError with the default code for status.
+func NewAPIError(status int, message string) *APIError {
+	return &APIError{Status: status, Code: CodeForStatus(status), Message: message}
+}
+
+// CodeForStatus returns the generic error code of an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrCodeBadRequest
+	case http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrCodeForbidden
+	case http.StatusNotFound:
+		return ErrCodeNotFound
+	case http.StatusConflict:
+		return ErrCodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case http.StatusNotImplemented:
+		return ErrCodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return ErrCodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrCodeTimeout
+	}
+	if status >= 500 {
+		return ErrCodeInternal
+	}
+	return ErrCodeBadRequest
+}
@@ -0,0 +1,144 @@
+// api/requestctx/requestctx.go
+
+// Package requestctx reads and writes the values middleware stores in a request's Gin
+// context. Getters check the stored type and return an error instead of panicking
+// when a value is missing or was stored with another type.
+package requestctx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys. They are kept as plain strings because gin.Context.Keys is also read
+// by code outside this package (logs, tests).
+const (
+	keyUserID           = "userId"
+	keyDatabaseID       = "databaseId"       // Database a DB-scoped API key was issued for
+	keyTargetDatabaseID = "targetDatabaseId" // Database addressed by the request, once looked up
+	keyAPIKey           = "isApiKey"
+	keyCertificateUser  = "certificateUserId" // Service account of a verified client certificate
+	keyRequestID        = "requestId"
+	keyAPIVersion       = "apiVersion"
+	keyActingAdmin      = "actingAdminId" // Admin reading another account's data
+)
+
+var (
+	// ErrMissing is returned when a value the request needs was never set, usually
+	// because the route is not behind the middleware that sets it.
+	ErrMissing = errors.New("request context value missing")
+	// ErrWrongType is returned when a value was stored with an unexpected type.
+	ErrWrongType = errors.New("request context value has an unexpected type")
+)
+
+// UserID returns the ID of the authenticated user.
+func UserID(c *gin.Context) (string, error) {
+	value, ok := c.Get(keyUserID)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrMissing, keyUserID)
+	}
+	userID, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is %T", ErrWrongType, keyUserID, value)
+	}
+	if userID == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrMissing, keyUserID)
+	}
+	return userID, nil
+}
+
+// SetUserID stores the ID of the authenticated user.
+func SetUserID(c *gin.Context, userID string) {
+	c.Set(keyUserID, userID)
+}
+
+// ScopedDatabaseID returns the database a DB-scoped API key was issued for. scoped is
+// false for JWTs and user-wide keys, which may address any database of the user.
+func ScopedDatabaseID(c *gin.Context) (id int64, scoped bool, err error) {
+	return optionalInt64(c, keyDatabaseID)
+}
+
+// SetScopedDatabaseID limits the request to one database of the user.
+func SetScopedDatabaseID(c *gin.Context, id int64) {
+	c.Set(keyDatabaseID, id)
+}
+
+// TargetDatabaseID returns the ID of the database addressed by the request, if it was
+// already looked up.
+func TargetDatabaseID(c *gin.Context) (id int64, ok bool, err error) {
+	return optionalInt64(c, keyTargetDatabaseID)
+}
+
+// SetTargetDatabaseID stores the ID of the database addressed by the request.
+func SetTargetDatabaseID(c *gin.Context, id int64) {
+	c.Set(keyTargetDatabaseID, id)
+}
+
+// IsAPIKey reports whether the request was authenticated with an API key.
+func IsAPIKey(c *gin.Context) bool {
+	return c.GetBool(keyAPIKey)
+}
+
+// SetAPIKey records that the request was authenticated with an API key.
+func SetAPIKey(c *gin.Context) {
+	c.Set(keyAPIKey, true)
+}
+
+// CertificateUser returns the user ID the request's client certificate is mapped to, or
+// "" when it has none.
+func CertificateUser(c *gin.Context) string {
+	return c.GetString(keyCertificateUser)
+}
+
+// SetCertificateUser records the user ID the request's client certificate is mapped to.
+func SetCertificateUser(c *gin.Context, userID string) {
+	c.Set(keyCertificateUser, userID)
+}
+
+// RequestID returns the request's correlation ID, or "" before RequestID ran.
+func RequestID(c *gin.Context) string {
+	return c.GetString(keyRequestID)
+}
+
+// SetRequestID stores the request's correlation ID.
+func SetRequestID(c *gin.Context, requestID string) {
+	c.Set(keyRequestID, requestID)
+}
+
+// APIVersion returns the API version of the route group serving the request, or 0
+// outside versioned groups.
+func APIVersion(c *gin.Context) int {
+	return c.GetInt(keyAPIVersion)
+}
+
+// SetAPIVersion stores the API version of the route group serving the request.
+func SetAPIVersion(c *gin.Context, version int) {
+	c.Set(keyAPIVersion, version)
+}
+
+// ActingAdmin returns the ID of the admin reading another account's data, whose ID
+// UserID then returns, or "" for requests made by the account itself.
+func ActingAdmin(c *gin.Context) string {
+	return c.GetString(keyActingAdmin)
+}
+
+// SetActingAdmin records that the admin adminID makes the request on behalf of the
+// account set with SetUserID.
+func SetActingAdmin(c *gin.Context, adminID string) {
+	c.Set(keyActingAdmin, adminID)
+}
+
+// optionalInt64 reads an int64 that may be absent; nil counts as absent.
+func optionalInt64(c *gin.Context, key string) (int64, bool, error) {
+	value, ok := c.Get(key)
+	if !ok || value == nil {
+		return 0, false, nil
+	}
+	id, ok := value.(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("%w: %s is %T", ErrWrongType, key, value)
+	}
+	return id, true, nil
+}
@@ -0,0 +1,41 @@
+package requestctx
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetters(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if _, err := UserID(c); !errors.Is(err, ErrMissing) {
+		t.Errorf("UserID(unset) error = %v; want ErrMissing", err)
+	}
+	c.Set(keyUserID, 42)
+	if _, err := UserID(c); !errors.Is(err, ErrWrongType) {
+		t.Errorf("UserID(int) error = %v; want ErrWrongType", err)
+	}
+	SetUserID(c, "u1")
+	if userID, err := UserID(c); err != nil || userID != "u1" {
+		t.Errorf("UserID = %q, %v; want u1", userID, err)
+	}
+
+	if _, scoped, err := ScopedDatabaseID(c); scoped || err != nil {
+		t.Errorf("ScopedDatabaseID(unset) = %v, %v; want unscoped", scoped, err)
+	}
+	c.Set(keyDatabaseID, nil)
+	if _, scoped, err := ScopedDatabaseID(c); scoped || err != nil {
+		t.Errorf("ScopedDatabaseID(nil) = %v, %v; want unscoped", scoped, err)
+	}
+	c.Set(keyDatabaseID, 7) // int, not int64
+	if _, _, err := ScopedDatabaseID(c); !errors.Is(err, ErrWrongType) {
+		t.Errorf("ScopedDatabaseID(int) error = %v; want ErrWrongType", err)
+	}
+	SetScopedDatabaseID(c, 7)
+	if id, scoped, err := ScopedDatabaseID(c); id != 7 || !scoped || err != nil {
+		t.Errorf("ScopedDatabaseID = %d, %v, %v; want 7, true", id, scoped, err)
+	}
+}
@@ -2,20 +2,22 @@
 package api
 
 import (
-	"database/sql"
-	"errors"
+	"context"
+	"fmt"
 	"net/http"
-	"os"
-	"strings"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 
+	"github.com/Annany2002/nebula-backend/api/adminui"
+	"github.com/Annany2002/nebula-backend/api/docs"
 	"github.com/Annany2002/nebula-backend/api/handlers"
 	"github.com/Annany2002/nebula-backend/api/middleware" // Import middleware package
+	"github.com/Annany2002/nebula-backend/api/requestctx"
 	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/jobs"
 	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+	"github.com/Annany2002/nebula-backend/internal/storage"
 )
 
 var (
@@ -23,116 +25,343 @@ var (
 )
 
 // SetupRouter initializes the Gin router and sets up all routes.
-func SetupRouter(metaDB *sql.DB, cfg *config.Config) *gin.Engine {
-	router := gin.Default() // Includes Logger and Recovery
-
-	// Configure CORS middleware
-	err := godotenv.Load() // Loads .env file from current directory by default
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		customLog.Warnf("Warning: Error loading .env file: %v", err)
-		// Decide if this should be a fatal error or just a warning
+func SetupRouter(metaDB storage.MetadataStore, cfg *config.Config) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.Recovery(), middleware.RequestID(), middleware.RequestMetrics())
+	if cfg.AccessLogEnabled {
+		router.Use(middleware.AccessLog(middleware.AccessLogOptions{
+			Logger:        logger.NewAccessLogger(cfg.AccessLogFile, cfg.AccessLogMaxSizeMB, cfg.AccessLogMaxBackups, cfg.AccessLogMaxAgeDays),
+			LogBodies:     cfg.AccessLogBodies,
+			RedactHeaders: cfg.AccessLogRedactHeaders,
+			RedactFields:  cfg.AccessLogRedactFields,
+		}))
 	}
 
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
+	// Traffic of database routes feeds GET /databases/:db_name/usage
+	router.Use(middleware.DatabaseUsage())
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = strings.Split(allowedOrigins, " ")
-	config.AllowMethods = []string{"POST", "OPTIONS", "GET", "PUT", "DELETE"} // Allows these methods.
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"} // Allows these headers.
+	// Writes every error response, so it wraps all middleware that can reject a request
+	router.Use(middleware.ErrorHandler())
+
+	// Kubernetes-style probes, registered before the rate limiter so frequent
+	// kubelet checks are never throttled
+	healthHandler := handlers.NewHealthHandler(metaDB, cfg)
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+	// Prometheus scrapes (METRICS_ENABLED, optionally behind METRICS_TOKEN)
+	if cfg.MetricsEnabled {
+		router.GET("/metrics", healthHandler.Metrics)
+	}
 
-	router.Use(cors.New(config))
+	// Mutual TLS: client certificates required on TLS_CLIENT_AUTH_ROUTES, and mapped to
+	// service accounts by TLS_CLIENT_IDENTITIES
+	if cfg.TLSClientCAFile != "" {
+		router.Use(middleware.ClientCertificate(cfg))
+	}
+
+	// Cross-origin policy from the configuration (validated at startup)
+	router.Use(middleware.CORS(cfg))
 
 	// Setting up a rate-limiter
 	ratelimiter := middleware.NewRateLimiter()
 	router.Use(middleware.RateLimitMiddleware(ratelimiter))
+	metrics.RateLimiterKeys.SetSource(func() map[string]float64 {
+		return map[string]float64{"": float64(ratelimiter.Keys())}
+	})
 	// It should run after basic middleware like Logger/Recovery
 	// but before the routing happens, so it wraps the handlers.
 
-	router.Use(middleware.ErrorHandler())
+	// Requests beyond MAX_IN_FLIGHT_REQUESTS are shed here; a tenant's share of them is
+	// enforced by middleware.TenantShedding on each route group, once authenticated
+	var shedder *middleware.ConcurrencyLimiter
+	if cfg.MaxInFlightRequests > 0 {
+		shedder = middleware.NewConcurrencyLimiter(cfg.MaxInFlightRequests, cfg.MaxInFlightPerTenant)
+		router.Use(middleware.LoadShedding(shedder))
+	}
+
+	// Requests are cancelled with 504 when they exceed REQUEST_TIMEOUT_SECONDS
+	router.Use(middleware.RequestTimeout(cfg.RequestTimeout))
+
+	// Read-only and maintenance modes (probes above are unaffected)
+	router.Use(middleware.ServiceMode())
 
-	// Initialize Handlers
-	authHandler := handlers.NewAuthHandler(metaDB, cfg)
-	dbHandler := handlers.NewDatabaseHandler(metaDB, cfg)
-	recordHandler := handlers.NewRecordHandler(metaDB, cfg)
-	tableHandler := handlers.NewTableHandler(metaDB, cfg)
+	// Background jobs started by handlers (maintenance, imports, ...)
+	jobManager := jobs.NewManager(context.Background())
+
+	h := &routeHandlers{
+		authHandler:        handlers.NewAuthHandler(metaDB, cfg),
+		dbHandler:          handlers.NewDatabaseHandler(metaDB, cfg),
+		recordHandler:      handlers.NewRecordHandler(metaDB, cfg),
+		tableHandler:       handlers.NewTableHandler(metaDB, cfg),
+		backupHandler:      handlers.NewBackupHandler(metaDB, cfg),
+		exportHandler:      handlers.NewExportHandler(metaDB, cfg),
+		maintenanceHandler: handlers.NewMaintenanceHandler(metaDB, cfg, jobManager),
+		jobHandler:         handlers.NewJobHandler(jobManager),
+		graphqlHandler:     handlers.NewGraphQLHandler(metaDB, cfg),
+		adminHandler:       handlers.NewAdminHandler(metaDB, cfg, jobManager),
+		exportsHandler:     handlers.NewAccountExportHandler(metaDB, cfg, jobManager),
+		erasureHandler:     handlers.NewErasureHandler(metaDB, cfg, jobManager),
+	}
 
 	// --- Public Routes ---
 	router.GET("/ping", func(c *gin.Context) { c.String(200, "pong") })
-	// Public route for health check
+	// Public route for health check (kept for existing monitors; same as /livez)
 	router.GET("/health", func(c *gin.Context) { c.Status(200) })
+	// Interactive API explorer (disable via API_DOCS_ENABLED in production)
+	if cfg.APIDocsEnabled {
+		docs.RegisterRoutes(router)
+	}
+	// Admin console; its data comes from the admin routes below
+	if cfg.AdminUIEnabled {
+		adminui.RegisterRoutes(router)
+	}
 	// Login, Signup routes
-	authRoutes := router.Group("/auth")
+	authRoutes := router.Group("/auth", middleware.TenantShedding(shedder))
 	{ /* Routes using authHandler */
-		authRoutes.POST("/signup", authHandler.Signup)
-		authRoutes.POST("/login", authHandler.Login)
+		authRoutes.POST("/signup", h.authHandler.Signup)
+		authRoutes.POST("/login", h.authHandler.Login)
 	}
+	// Public form submissions (checked against each form's allowed origins)
+	router.POST("/forms/:form_id", middleware.TenantShedding(shedder), h.recordHandler.SubmitForm)
+	router.OPTIONS("/forms/:form_id", h.recordHandler.SubmitFormPreflight)
+	// Read-only share links (the signed token authorizes the request)
+	router.GET("/share/:token", middleware.TenantShedding(shedder), h.recordHandler.ViewShare)
+
+	// Every API version mounts the same routes and handlers; handlers choose the
+	// response shape from the version middleware.APIVersion stores in the context
+	for _, policy := range apiVersions(cfg) {
+		api := router.Group(fmt.Sprintf("/api/v%d", policy.Version), middleware.APIVersion(policy))
+		mountAPIRoutes(api, h, metaDB, cfg, shedder)
+	}
+
+	return router
+}
 
+// routeHandlers holds the handlers shared by all API versions.
+type routeHandlers struct {
+	authHandler        *handlers.AuthHandler
+	dbHandler          *handlers.DatabaseHandler
+	recordHandler      *handlers.RecordHandler
+	tableHandler       *handlers.TableHandler
+	backupHandler      *handlers.BackupHandler
+	exportHandler      *handlers.ExportHandler
+	maintenanceHandler *handlers.MaintenanceHandler
+	jobHandler         *handlers.JobHandler
+	graphqlHandler     *handlers.GraphQLHandler
+	adminHandler       *handlers.AdminHandler
+	exportsHandler     *handlers.AccountExportHandler
+	erasureHandler     *handlers.ErasureHandler
+}
+
+// apiVersions lists the mounted API versions. v2 changes response shapes only (e.g.
+// the {data, pagination} envelope of record lists); v1 stays stable until its sunset.
+func apiVersions(cfg *config.Config) []middleware.APIVersionPolicy {
+	return []middleware.APIVersionPolicy{
+		{Version: 1, Deprecated: cfg.APIV1DeprecatedAt, Sunset: cfg.APIV1SunsetAt, Successor: 2},
+		{Version: 2},
+	}
+}
+
+// mountAPIRoutes registers the versioned routes on api (/api/v<N>), sharing the
+// tenants' slots of shedder (nil when load shedding is off).
+func mountAPIRoutes(api *gin.RouterGroup, h *routeHandlers, metaDB storage.MetadataStore, cfg *config.Config, shedder *middleware.ConcurrencyLimiter) {
 	// Separate group for JWT-only protected routes ---
 	// Example: Account management, API Key generation
-	accountRoutes := router.Group("/api/v1/account")
-	accountRoutes.Use(middleware.AuthMiddleware(cfg))
+	accountRoutes := api.Group("/account")
+	accountRoutes.Use(middleware.AuthMiddleware(cfg), middleware.TenantShedding(shedder))
 	{
 		// User Profile Management
-		accountRoutes.GET("/user/me", authHandler.GetCurrentUser)
-		accountRoutes.PUT("/user/me", authHandler.UpdateCurrentUser)
+		accountRoutes.GET("/user/me", h.authHandler.GetCurrentUser)
+		accountRoutes.PUT("/user/me", h.authHandler.UpdateCurrentUser)
+		accountRoutes.DELETE("/user/me", h.erasureHandler.EraseCurrentUser)
+		accountRoutes.GET("/audit-log", h.authHandler.GetAuditLog)
+
+		// Self-service export of everything stored about the account
+		accountRoutes.POST("/export", h.exportsHandler.StartAccountExport)
+		accountRoutes.GET("/exports", h.exportsHandler.ListAccountExports)
+		accountRoutes.GET("/exports/:export_id", h.exportsHandler.DownloadAccountExport)
 
 		// API Key Management
-		accountRoutes.GET("/databases/:db_name/apikey", dbHandler.GetAPIKey)
-		accountRoutes.POST("/databases/:db_name/apikey", dbHandler.CreateAPIKey)
-		accountRoutes.DELETE("/databases/:db_name/apikey", dbHandler.DeleteAPIKey)
+		accountRoutes.GET("/databases/:db_name/apikey", h.dbHandler.GetAPIKey)
+		accountRoutes.POST("/databases/:db_name/apikey", h.dbHandler.CreateAPIKey)
+		accountRoutes.DELETE("/databases/:db_name/apikey", h.dbHandler.DeleteAPIKey)
+		accountRoutes.GET("/databases/:db_name/apikeys/:id/usage", h.dbHandler.GetAPIKeyUsage)
+
+		// Browser-safe keys reading some tables from allowed origins
+		accountRoutes.GET("/databases/:db_name/publishable-keys", h.dbHandler.ListPublishableKeys)
+		accountRoutes.POST("/databases/:db_name/publishable-keys", h.dbHandler.CreatePublishableKey)
+		accountRoutes.DELETE("/databases/:db_name/publishable-keys/:key_id", h.dbHandler.DeletePublishableKey)
+	}
+
+	// --- Admin Routes (JWT + admin role) ---
+	adminRoutes := api.Group("/admin")
+	adminRoutes.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware(metaDB))
+	{
+		adminRoutes.GET("/log-level", h.adminHandler.GetLogLevel)
+		adminRoutes.PUT("/log-level", h.adminHandler.SetLogLevel)
+		adminRoutes.GET("/mode", h.adminHandler.GetServiceMode)
+		adminRoutes.PUT("/mode", h.adminHandler.SetServiceMode)
+		adminRoutes.GET("/stats/latency", h.adminHandler.GetLatencyStats)
+		adminRoutes.DELETE("/stats/latency", h.adminHandler.ResetLatencyStats)
+		adminRoutes.GET("/stats/resources", h.adminHandler.GetResourceStats)
+		adminRoutes.GET("/audit-log", h.adminHandler.GetAuditLog)
+		adminRoutes.GET("/instances", h.adminHandler.GetInstances)
+		adminRoutes.GET("/databases", h.adminHandler.ListDatabases)
+		adminRoutes.GET("/replicas", h.adminHandler.ListReadReplicas)
+		adminRoutes.PUT("/databases/:database_id/replica", h.adminHandler.SetReadReplica)
+		adminRoutes.DELETE("/databases/:database_id/replica", h.adminHandler.DeleteReadReplica)
+		adminRoutes.DELETE("/databases/:database_id/quarantine", h.adminHandler.ReleaseQuarantine)
+		adminRoutes.GET("/data-roots", h.adminHandler.ListDataRoots)
+		adminRoutes.POST("/data-roots/rebalance", h.adminHandler.RebalanceDataRoots)
+		adminRoutes.POST("/users/:user_id/relocate", h.adminHandler.RelocateUserDatabases)
+		adminRoutes.POST("/orphaned-files/collect", h.adminHandler.CollectOrphanedFiles)
+		adminRoutes.POST("/consistency-check", h.adminHandler.CheckConsistency)
+		adminRoutes.POST("/users/:user_id/erase", h.erasureHandler.EraseUser)
+		adminRoutes.GET("/erasures", h.erasureHandler.ListErasures)
+		adminRoutes.GET("/erasures/:erasure_id", h.erasureHandler.GetErasure)
+
+		// Read-only views of any account's data for the admin console, served by the
+		// regular handlers running as the account
+		ownerRoutes := adminRoutes.Group("/users/:user_id", middleware.AdminActAsUser(metaDB))
+		ownerRoutes.GET("/databases", h.dbHandler.ListDatabases)
+		ownerRoutes.GET("/databases/:db_name/usage", h.dbHandler.GetDatabaseUsage)
+		ownerRoutes.GET("/databases/:db_name/insights/slow-queries", h.dbHandler.GetSlowQueries)
+		ownerRoutes.GET("/databases/:db_name/apikey", h.dbHandler.GetAPIKey)
+		ownerRoutes.GET("/databases/:db_name/apikeys/:id/usage", h.dbHandler.GetAPIKeyUsage)
+		ownerRoutes.GET("/databases/:db_name/tables", h.tableHandler.ListTablesFn)
+		ownerRoutes.GET("/databases/:db_name/tables/:table_name/schema", h.dbHandler.GetSchema)
+		ownerRoutes.GET("/databases/:db_name/tables/:table_name/records", h.recordHandler.ListRecords)
 	}
 
 	// --- Protected Routes ---
-	apiRoutes := router.Group("/api/v1")
+	apiRoutes := api.Group("")
 
 	// Apply Combined Auth Middleware
-	apiRoutes.Use(middleware.CombinedAuthMiddleware(metaDB, cfg))
+	apiRoutes.Use(middleware.CombinedAuthMiddleware(metaDB, cfg), middleware.TenantShedding(shedder))
 	{ /* Routes using dbHandler and recordHandler */
 
 		// health route to check for protected route health
 		apiRoutes.GET("/health", func(c *gin.Context) {
-			userId, okUserId := c.Get("userId")
-			dbIDValue, _ := c.Get("databaseId")
-			_, okApiAuth := c.Get("isApiKey")
-
-			if !okUserId { // Should not happen if CombinedAuthMiddleware ran successfully
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "UserID not found in context after auth"})
+			userId, err := requestctx.UserID(c)
+			if err != nil { // Should not happen if CombinedAuthMiddleware ran successfully
+				_ = c.Error(err)
 				return
 			}
 
 			// api key auth
-			if okApiAuth {
+			if requestctx.IsAPIKey(c) {
 				c.JSON(http.StatusOK, gin.H{"authenticated_by": "api_key", "status": "ok"})
 				return
 			}
 
-			c.JSON(http.StatusOK, gin.H{"userId": userId, "dbId": dbIDValue})
+			c.JSON(http.StatusOK, gin.H{"userId": userId, "dbId": nil})
 		})
 
-		apiRoutes.GET("/user/:user_id", authHandler.FindUser)
-		// apiRoutes.GET("/user/me", authHandler.GetUser)
+		apiRoutes.GET("/user/:user_id", h.authHandler.FindUser)
+		// apiRoutes.GET("/user/me", h.authHandler.GetUser)
 
 		// Databases Manangement
-		apiRoutes.GET("/databases", dbHandler.ListDatabases)
-		apiRoutes.POST("/databases", dbHandler.CreateDatabase)
-		apiRoutes.DELETE("/databases/:db_name", dbHandler.DeleteDatabase)
+		apiRoutes.GET("/databases", h.dbHandler.ListDatabases)
+		apiRoutes.POST("/databases", h.dbHandler.CreateDatabase)
+		apiRoutes.DELETE("/databases/:db_name", h.dbHandler.DeleteDatabase)
+		apiRoutes.POST("/databases/:db_name/clone", h.dbHandler.CloneDatabase)
+		apiRoutes.GET("/databases/:db_name/labels", h.dbHandler.GetDatabaseLabels)
+		apiRoutes.PUT("/databases/:db_name/labels", h.dbHandler.SetDatabaseLabels)
+		apiRoutes.GET("/databases/:db_name/usage", h.dbHandler.GetDatabaseUsage)
+		apiRoutes.GET("/databases/:db_name/insights/slow-queries", h.dbHandler.GetSlowQueries)
+		apiRoutes.DELETE("/databases/:db_name/insights/slow-queries", h.dbHandler.ResetSlowQueries)
+
+		// Webhooks notified of writes to a database
+		apiRoutes.GET("/databases/:db_name/webhooks", h.dbHandler.ListWebhooks)
+		apiRoutes.POST("/databases/:db_name/webhooks", h.dbHandler.CreateWebhook)
+		apiRoutes.GET("/databases/:db_name/webhooks/:webhook_id", h.dbHandler.GetWebhook)
+		apiRoutes.PATCH("/databases/:db_name/webhooks/:webhook_id", h.dbHandler.UpdateWebhook)
+		apiRoutes.DELETE("/databases/:db_name/webhooks/:webhook_id", h.dbHandler.DeleteWebhook)
+		apiRoutes.POST("/databases/:db_name/webhooks/:webhook_id/test", h.dbHandler.TestWebhook)
+		apiRoutes.POST("/databases/:db_name/webhooks/:webhook_id/rotate-secret", h.dbHandler.RotateWebhookSecret)
+		apiRoutes.POST("/databases/:db_name/maintenance", h.maintenanceHandler.RunMaintenance)
+		// Fixture loading for test environments
+		if cfg.SeedAPIEnabled {
+			apiRoutes.POST("/databases/:db_name/seed", h.dbHandler.SeedDatabase)
+		}
+
+		// Public submission forms of tables
+		apiRoutes.GET("/databases/:db_name/forms", h.dbHandler.ListForms)
+		apiRoutes.POST("/databases/:db_name/forms", h.dbHandler.CreateForm)
+		apiRoutes.GET("/databases/:db_name/forms/:form_id", h.dbHandler.GetForm)
+		apiRoutes.PATCH("/databases/:db_name/forms/:form_id", h.dbHandler.UpdateForm)
+		apiRoutes.DELETE("/databases/:db_name/forms/:form_id", h.dbHandler.DeleteForm)
+
+		// Read-only share links of tables and saved queries
+		apiRoutes.GET("/databases/:db_name/shares", h.dbHandler.ListShareLinks)
+		apiRoutes.POST("/databases/:db_name/shares", h.dbHandler.CreateShareLink)
+		apiRoutes.DELETE("/databases/:db_name/shares/:share_id", h.dbHandler.DeleteShareLink)
+
+		// Short-lived tokens limited to a database or table, and signed URLs of reads, for
+		// browsers, emails and third parties
+		apiRoutes.POST("/databases/:db_name/tokens", h.dbHandler.CreateScopedToken)
+		apiRoutes.POST("/databases/:db_name/signed-urls", h.dbHandler.CreateSignedURL)
+
+		// Trash (deleted databases and dropped tables)
+		apiRoutes.GET("/trash", h.dbHandler.ListTrash)
+		apiRoutes.POST("/trash/:trash_id/restore", h.dbHandler.RestoreTrashItem)
+		apiRoutes.DELETE("/trash/:trash_id", h.dbHandler.PurgeTrashItem)
+
+		// Background Jobs
+		apiRoutes.GET("/jobs/:job_id", h.jobHandler.GetJob)
+
+		// Backup & Restore
+		apiRoutes.GET("/databases/:db_name/backups", h.backupHandler.ListBackups)
+		apiRoutes.POST("/databases/:db_name/backups", h.backupHandler.CreateBackup)
+		apiRoutes.POST("/databases/:db_name/restore", h.backupHandler.RestoreDatabase)
+
+		// Export
+		apiRoutes.GET("/databases/:db_name/export", h.exportHandler.ExportDatabase)
+		apiRoutes.GET("/databases/:db_name/models", h.exportHandler.ExportModels)
 
 		// Schema Management
-		apiRoutes.GET("/databases/:db_name/tables/:table_name/schema", dbHandler.GetSchema)
-		apiRoutes.POST("/databases/:db_name/schema", dbHandler.CreateSchema)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/schema", h.dbHandler.GetSchema)
+		apiRoutes.POST("/databases/:db_name/schema", h.dbHandler.CreateSchema)
 
 		// Table Management
-		apiRoutes.GET("/databases/:db_name/tables", tableHandler.ListTablesFn)
-		apiRoutes.POST("/databases/:db_name/tables", tableHandler.CreateTable)
-		apiRoutes.DELETE("/databases/:db_name/tables/:table_name", tableHandler.DeleteTable)
+		apiRoutes.GET("/databases/:db_name/tables", h.tableHandler.ListTablesFn)
+		apiRoutes.POST("/databases/:db_name/tables", h.tableHandler.CreateTable)
+		apiRoutes.DELETE("/databases/:db_name/tables/:table_name", h.tableHandler.DeleteTable)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/copy", h.tableHandler.CopyTable)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/profile", h.tableHandler.ProfileTable)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/rules", h.tableHandler.GetColumnRules)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/rules", h.tableHandler.SetColumnRules)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/json-schema", h.tableHandler.GetJSONSchema)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/json-schema", h.tableHandler.SetJSONSchema)
+		apiRoutes.DELETE("/databases/:db_name/tables/:table_name/json-schema", h.tableHandler.DeleteJSONSchema)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/script", h.tableHandler.GetTableScript)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/script", h.tableHandler.SetTableScript)
+		apiRoutes.DELETE("/databases/:db_name/tables/:table_name/script", h.tableHandler.DeleteTableScript)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/options", h.tableHandler.GetTableOptions)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/options", h.tableHandler.SetTableOptions)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/description", h.tableHandler.GetTableDescription)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/description", h.tableHandler.SetTableDescription)
+		apiRoutes.GET("/databases/:db_name/search", h.recordHandler.SearchDatabase)
+		apiRoutes.GET("/databases/:db_name/queries", h.recordHandler.ListSavedQueries)
+		apiRoutes.GET("/databases/:db_name/queries/:query_name", h.recordHandler.RunSavedQuery)
+		apiRoutes.PUT("/databases/:db_name/queries/:query_name", h.recordHandler.PutSavedQuery)
+		apiRoutes.DELETE("/databases/:db_name/queries/:query_name", h.recordHandler.DeleteSavedQuery)
 
 		// Record Management
-		apiRoutes.GET("/databases/:db_name/tables/:table_name/records", recordHandler.ListRecords)
-		apiRoutes.POST("/databases/:db_name/tables/:table_name/records", recordHandler.CreateRecord)
-		apiRoutes.GET("/databases/:db_name/tables/:table_name/records/:record_id", recordHandler.GetRecord)
-		apiRoutes.PUT("/databases/:db_name/tables/:table_name/records/:record_id", recordHandler.UpdateRecord)
-		apiRoutes.DELETE("/databases/:db_name/tables/:table_name/records/:record_id", recordHandler.DeleteRecord)
-	}
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/records", h.recordHandler.ListRecords)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/records", h.recordHandler.CreateRecord)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/records/batch-get", h.recordHandler.BatchGetRecords)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/records/duplicates", h.recordHandler.FindDuplicates)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/records/dedupe", h.recordHandler.DedupeRecords)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/import", h.recordHandler.ImportRecords)
+		apiRoutes.POST("/databases/:db_name/tables/:table_name/generate", h.recordHandler.GenerateRecords)
+		apiRoutes.GET("/databases/:db_name/tables/:table_name/records/:record_id", h.recordHandler.GetRecord)
+		apiRoutes.PUT("/databases/:db_name/tables/:table_name/records/:record_id", h.recordHandler.UpdateRecord)
+		apiRoutes.DELETE("/databases/:db_name/tables/:table_name/records/:record_id", h.recordHandler.DeleteRecord)
 
-	return router
+		// GraphQL (generated from the database's tables)
+		apiRoutes.GET("/databases/:db_name/graphql", h.graphqlHandler.Execute)
+		apiRoutes.POST("/databases/:db_name/graphql", h.graphqlHandler.Execute)
+		apiRoutes.GET("/databases/:db_name/graphql/schema", h.graphqlHandler.Schema)
+	}
 }
@@ -0,0 +1,118 @@
+// cmd/server/admin.go
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/auth"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// runAdmin implements the admin subcommand. `admin create` creates a user with a role
+// without going through signup, e.g. the first admin of an automated install. The
+// password is only read from ADMIN_PASSWORD so that it does not show up in the process
+// list. If the email is already registered, only the role is set, so provisioning
+// scripts can run it on every deploy. Returns the exit code.
+func runAdmin(args []string, out io.Writer) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(out, "usage: admin create [-email EMAIL] [-username NAME] [-role admin|user]")
+		return 2
+	}
+	fs := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	email := fs.String("email", "", "Email of the user (default $ADMIN_EMAIL)")
+	username := fs.String("username", "", "Username (default $ADMIN_USERNAME, or administrator)")
+	role := fs.String("role", "", "Role: admin or user (default $ADMIN_ROLE, or admin)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	// Loaded first: the ADMIN_* defaults may come from .env or CONFIG_FILE
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "admin: failed to load configuration: %v\n", err)
+		return 1
+	}
+	if err := logger.Configure(cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(out, "admin: invalid logging configuration: %v\n", err)
+		return 1
+	}
+	if err := configurePasswordHashing(cfg); err != nil {
+		fmt.Fprintf(out, "admin: invalid password hashing configuration: %v\n", err)
+		return 1
+	}
+	*email = cmp.Or(*email, os.Getenv("ADMIN_EMAIL"))
+	*username = cmp.Or(*username, os.Getenv("ADMIN_USERNAME"), "administrator")
+	*role = cmp.Or(*role, os.Getenv("ADMIN_ROLE"), domain.RoleAdmin)
+
+	// The same rules as signup
+	if address, err := mail.ParseAddress(*email); err != nil || address.Address != *email {
+		fmt.Fprintln(out, "admin: -email (or ADMIN_EMAIL) must be an email address")
+		return 2
+	}
+	if len(*username) < 6 {
+		fmt.Fprintln(out, "admin: -username (or ADMIN_USERNAME) must be at least 6 characters")
+		return 2
+	}
+	if *role != domain.RoleAdmin && *role != domain.RoleUser {
+		fmt.Fprintf(out, "admin: unknown role '%s'; use %s or %s\n", *role, domain.RoleAdmin, domain.RoleUser)
+		return 2
+	}
+
+	ctx := context.Background()
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		fmt.Fprintf(out, "admin: failed to initialize metadata database: %v\n", err)
+		return 1
+	}
+	defer metaDB.Close()
+
+	user, err := metaDB.FindUserByEmail(ctx, *email)
+	switch {
+	case err == nil:
+		if err := metaDB.SetUserRole(ctx, user.UserId, *role); err != nil {
+			fmt.Fprintf(out, "admin: failed to set role of '%s': %v\n", *email, err)
+			return 1
+		}
+		fmt.Fprintf(out, "%s already exists (user %s); role set to %s, password unchanged\n", *email, user.UserId, *role)
+		return 0
+	case !errors.Is(err, storage.ErrUserNotFound):
+		fmt.Fprintf(out, "admin: failed to look up '%s': %v\n", *email, err)
+		return 1
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if len(password) < 8 {
+		fmt.Fprintln(out, "admin: set ADMIN_PASSWORD to the password of the new user (at least 8 characters)")
+		return 2
+	}
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		fmt.Fprintf(out, "admin: failed to hash password: %v\n", err)
+		return 1
+	}
+	userID, err := metaDB.CreateUser(ctx, uuid.New().String(), *username, *email, hashedPassword)
+	if err != nil {
+		fmt.Fprintf(out, "admin: failed to create '%s': %v\n", *email, err)
+		return 1
+	}
+	if *role != domain.RoleUser {
+		if err := metaDB.SetUserRole(ctx, userID, *role); err != nil {
+			fmt.Fprintf(out, "admin: created user %s but failed to set its role: %v\n", userID, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(out, "created %s (user %s) with role %s\n", *email, userID, *role)
+	return 0
+}
@@ -0,0 +1,67 @@
+// cmd/server/doctor.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/doctor"
+)
+
+// runDoctor implements the doctor subcommand, which checks the configuration and
+// environment without starting the server and prints what to fix. Returns 1 if any
+// check failed, or with -strict if any warned.
+func runDoctor(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the findings as JSON")
+	strict := fs.Bool("strict", false, "Exit with 1 on warnings too")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "doctor: failed to load configuration: %v\n", err)
+		fmt.Fprintln(out, "    fix: set the variable named above in the environment, .env or CONFIG_FILE")
+		return 1
+	}
+
+	findings := doctor.Run(context.Background(), cfg)
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			return 1
+		}
+	} else {
+		doctor.Print(out, findings)
+	}
+
+	for _, f := range findings {
+		if f.Severity == doctor.SeverityError || (*strict && f.Severity == doctor.SeverityWarning) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// runStartupChecks runs the doctor checks before the server starts, as
+// STARTUP_CHECKS says, logging the problems and exiting on errors in strict mode.
+func runStartupChecks(cfg *config.Config) {
+	if cfg.StartupChecks == config.StartupChecksOff {
+		return
+	}
+	findings := doctor.Run(context.Background(), cfg)
+	for _, f := range findings {
+		if f.Severity != doctor.SeverityOK {
+			customLog.Warnf("Startup check %s (%s): %s. Fix: %s", f.Check, f.Severity, f.Message, f.Fix)
+		}
+	}
+	if cfg.StartupChecks == config.StartupChecksStrict && doctor.HasErrors(findings) {
+		customLog.Fatalf("Startup checks failed; fix the errors above, or set STARTUP_CHECKS=warn to start anyway")
+	}
+}
@@ -0,0 +1,178 @@
+// cmd/server/import.go
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/importer"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// runImport implements the import subcommand, which copies tables of an external
+// Postgres database into a Nebula database of a user, creating it when needed. It
+// prints what was imported and what could not be mapped, and returns the exit code.
+func runImport(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	source := fs.String("source", "", "Source database DSN (postgres://...)")
+	schema := fs.String("schema", "public", "Source schema to import from")
+	tableList := fs.String("tables", "", "Comma-separated tables to import (default: all)")
+	owner := fs.String("owner", "", "Email of the Nebula user receiving the tables")
+	dbName := fs.String("db", "", "Nebula database to import into (created if missing)")
+	schemaOnly := fs.Bool("schema-only", false, "Create the tables without copying rows")
+	dryRun := fs.Bool("dry-run", false, "Print the import plan without changing anything")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *source == "" || *owner == "" || *dbName == "" {
+		fmt.Fprintln(fs.Output(), "import: -source, -owner and -db are required")
+		fs.Usage()
+		return 2
+	}
+	var tables []string
+	for _, name := range strings.Split(*tableList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tables = append(tables, name)
+		}
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "import: failed to load configuration: %v\n", err)
+		return 1
+	}
+	if err := logger.Configure(cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(out, "import: invalid logging configuration: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	src, err := importer.OpenSource(ctx, *source)
+	if err != nil {
+		fmt.Fprintf(out, "import: %v\n", err)
+		return 1
+	}
+	defer src.Close()
+
+	plans, err := importer.Plan(ctx, src, *schema, tables)
+	if err != nil {
+		fmt.Fprintf(out, "import: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		printImportPlan(out, plans)
+		return 0
+	}
+
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		fmt.Fprintf(out, "import: failed to initialize metadata database: %v\n", err)
+		return 1
+	}
+	defer metaDB.Close()
+	if err := storage.ConfigureUserData(cfg); err != nil {
+		fmt.Fprintf(out, "import: failed to initialize user data backend: %v\n", err)
+		return 1
+	}
+	defer storage.CloseUserData()
+	if err := storage.ConfigureEncryption(cfg.UserDBEncryptionKey); err != nil {
+		fmt.Fprintf(out, "import: failed to enable user database encryption: %v\n", err)
+		return 1
+	}
+	defer storage.CloseAllUserDBs()
+	if err := storage.ConfigureDataRoots(cfg.DataRoots, cfg.DataPlacement); err != nil {
+		fmt.Fprintf(out, "import: %v\n", err)
+		return 1
+	}
+
+	location, err := importTarget(ctx, metaDB, cfg, *owner, *dbName)
+	if err != nil {
+		fmt.Fprintf(out, "import: %v\n", err)
+		return 1
+	}
+	dst, err := storage.OpenUserData(ctx, location)
+	if err != nil {
+		fmt.Fprintf(out, "import: failed to open database '%s': %v\n", *dbName, err)
+		return 1
+	}
+	defer dst.Release()
+
+	failed := false
+	for _, plan := range plans {
+		printTablePlan(out, plan)
+		if !plan.Importable() {
+			continue
+		}
+		copied, err := importer.ImportTable(ctx, src, *schema, plan, dst, *schemaOnly)
+		if err != nil {
+			fmt.Fprintf(out, "  FAILED after %d row(s): %v\n", copied, err)
+			failed = true
+			continue
+		}
+		fmt.Fprintf(out, "  imported %d row(s)\n", copied)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// importTarget returns the storage location of the owner's database, registering it
+// first when it does not exist.
+func importTarget(ctx context.Context, metaDB storage.MetadataStore, cfg *config.Config, email, dbName string) (string, error) {
+	if !core.IsValidIdentifier(dbName) {
+		return "", fmt.Errorf("invalid database name '%s'", dbName)
+	}
+	user, err := metaDB.FindUserByEmail(ctx, email)
+	if err != nil {
+		return "", fmt.Errorf("failed to find user '%s': %w", email, err)
+	}
+	location, err := metaDB.FindDatabasePath(ctx, user.UserId, dbName)
+	if err == nil {
+		return location, nil
+	}
+	if !errors.Is(err, storage.ErrDatabaseNotFound) {
+		return "", err
+	}
+
+	dataRoot, _ := storage.PlaceUserData(cfg.MetadataDbDir, user.UserId)
+	location = storage.UserDataLocation(dataRoot, user.UserId, dbName)
+	if err := storage.PrepareUserData(ctx, location); err != nil {
+		return "", fmt.Errorf("failed to create database storage: %w", err)
+	}
+	if err := metaDB.RegisterDatabase(ctx, user.UserId, dbName, location); err != nil {
+		return "", fmt.Errorf("failed to register database '%s': %w", dbName, err)
+	}
+	return location, nil
+}
+
+func printImportPlan(out io.Writer, plans []importer.TablePlan) {
+	for _, plan := range plans {
+		printTablePlan(out, plan)
+		for _, col := range plan.Columns {
+			target := col.Type
+			if target == "" {
+				target = "(built-in)"
+			}
+			fmt.Fprintf(out, "  %s: %s -> %s\n", col.Name, col.SourceType, target)
+		}
+	}
+}
+
+func printTablePlan(out io.Writer, plan importer.TablePlan) {
+	status := "import"
+	if !plan.Importable() {
+		status = "skip"
+	}
+	fmt.Fprintf(out, "%s [%s]\n", plan.Name, status)
+	for _, issue := range plan.Issues {
+		fmt.Fprintf(out, "  ! %s\n", issue)
+	}
+}
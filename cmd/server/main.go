@@ -2,13 +2,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
+	"time"
 
-	"github.com/Annany2002/nebula-backend/api"              // Import router setup
-	"github.com/Annany2002/nebula-backend/config"           // Import config loading
-	"github.com/Annany2002/nebula-backend/internal/logger"  // Import logger
-	"github.com/Annany2002/nebula-backend/internal/storage" // Import DB connection func
+	"github.com/Annany2002/nebula-backend/api"                    // Import router setup
+	"github.com/Annany2002/nebula-backend/api/grpcapi"            // Import gRPC services
+	"github.com/Annany2002/nebula-backend/config"                 // Import config loading
+	"github.com/Annany2002/nebula-backend/internal/anomaly"       // Import auth anomaly alerts
+	"github.com/Annany2002/nebula-backend/internal/auth"          // Import password hashing
+	"github.com/Annany2002/nebula-backend/internal/changefeed"    // Import record change publishing
+	"github.com/Annany2002/nebula-backend/internal/coordination"  // Import multi-instance coordination
+	"github.com/Annany2002/nebula-backend/internal/errortracking" // Import Sentry error reporting
+	"github.com/Annany2002/nebula-backend/internal/eventbridge"   // Import Kafka/NATS change publishing
+	"github.com/Annany2002/nebula-backend/internal/health"        // Import worker health tracking
+	"github.com/Annany2002/nebula-backend/internal/logger"        // Import logger
+	"github.com/Annany2002/nebula-backend/internal/mail"          // Import transactional email
+	"github.com/Annany2002/nebula-backend/internal/replication"   // Import off-site replication
+	"github.com/Annany2002/nebula-backend/internal/servicemode"   // Import read-only/maintenance switch
+	"github.com/Annany2002/nebula-backend/internal/storage"       // Import DB connection func
+	"github.com/Annany2002/nebula-backend/internal/webhooks"      // Import database webhooks
 )
 
 var (
@@ -16,6 +31,23 @@ var (
 )
 
 func main() {
+	// Subcommands share the server's configuration but do not start it
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImport(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "relocate" {
+		os.Exit(runRelocate(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		os.Exit(runSeed(os.Args[2:], os.Stdout))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:], os.Stdout))
+	}
+
 	customLog.Println("Starting Nebula Backend server...")
 
 	// 1. Load Configuration
@@ -24,6 +56,18 @@ func main() {
 		customLog.Fatalf("Failed to load configuration: %v", err)
 		os.Exit(1)
 	}
+	if err := logger.Configure(cfg.LogFormat, cfg.LogLevel); err != nil {
+		customLog.Fatalf("Invalid logging configuration: %v", err)
+	}
+	// The configuration and environment are checked before anything is opened
+	runStartupChecks(cfg)
+	if err := configurePasswordHashing(cfg); err != nil {
+		customLog.Fatalf("Invalid password hashing configuration: %v", err)
+	}
+	if cfg.ServiceMode != string(servicemode.Normal) {
+		servicemode.Set(servicemode.Mode(cfg.ServiceMode), cfg.ServiceModeMessage)
+		customLog.Warnf("Starting in %s mode", cfg.ServiceMode)
+	}
 
 	// 2. Initialize Metadata Database Connection
 	metaDB, err := storage.ConnectMetadataDB(cfg)
@@ -38,12 +82,171 @@ func main() {
 		}
 	}()
 
+	// Background workers stop when main returns
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Tenant databases live in SQLite files or Postgres schemas
+	if err := storage.ConfigureUserData(cfg); err != nil {
+		customLog.Fatalf("Failed to initialize user data backend: %v", err)
+	}
+	defer storage.CloseUserData()
+
+	// At-rest encryption of user DB files (fails fast without SQLCipher)
+	if err := storage.ConfigureEncryption(cfg.UserDBEncryptionKey); err != nil {
+		customLog.Fatalf("Failed to enable user database encryption: %v", err)
+	}
+
+	// Backup files are compressed and encrypted as configured
+	if err := storage.ConfigureBackups(cfg.BackupCompression, cfg.BackupEncryptionKey, cfg.BackupEncryptionPreviousKeys); err != nil {
+		customLog.Fatalf("Failed to configure backups: %v", err)
+	}
+
+	// New user database files are spread over the data roots
+	if err := storage.ConfigureDataRoots(cfg.DataRoots, cfg.DataPlacement); err != nil {
+		customLog.Fatalf("Failed to configure data roots: %v", err)
+	}
+
+	// User database handles are cached between requests
+	storage.ConfigureUserDBPool(cfg.UserDBPoolMaxOpen, cfg.UserDBPoolIdleTimeout)
+	storage.ConfigureReadCache(cfg.ReadCacheMaxEntries, cfg.ReadCacheTTL)
+	storage.ConfigureSlowQueries(cfg.SlowQueryThreshold, cfg.SlowQueriesPerDatabase)
+	defer storage.CloseAllUserDBs()
+	go storage.RunUserDBPoolJanitor(ctx, time.Minute)
+	go storage.RunWALCheckpointer(ctx, cfg.WALCheckpointInterval, cfg.WALCheckpointThreshold)
+	go storage.RunTrashPurger(ctx, metaDB, time.Hour)
+	go storage.RunAccountExportPurger(ctx, cfg.AccountExportDir, cfg.AccountExportRetention, time.Hour)
+	go storage.RunDatabaseExpirer(ctx, metaDB, time.Minute)
+	go storage.RunUsageFlusher(ctx, metaDB, time.Minute)
+
+	// Capacity gauges, served by /metrics and GET /api/v1/admin/stats/resources
+	storage.RegisterGauges()
+	health.RegisterGauges()
+
+	// Designated read-heavy databases are read from replicas (SQLite files only)
+	if cfg.ReadReplicaRefreshInterval > 0 && cfg.UserDataBackend == storage.BackendSQLite {
+		go storage.RunReadReplicaRefresher(ctx, metaDB, cfg.ReadReplicaRefreshInterval)
+	}
+
+	// Database files left without a registration are reported or removed (SQLite files only)
+	if cfg.OrphanFileGCInterval > 0 && cfg.UserDataBackend == storage.BackendSQLite {
+		go storage.RunOrphanFileCollector(ctx, metaDB, cfg.OrphanFileGCInterval, cfg.OrphanFileGracePeriod, cfg.OrphanFileGCRemove)
+	}
+
+	// Off-site snapshot replication (only when an S3 bucket is configured and
+	// databases are SQLite files; Postgres has its own backup tooling)
+	if replicator := replication.NewReplicator(cfg); replicator != nil && cfg.UserDataBackend == storage.BackendSQLite {
+		go replicator.Run(ctx, metaDB)
+	}
+
+	// Queued emails are delivered in the background (only when a provider is configured)
+	mailSender, err := mail.NewSender(cfg)
+	if err != nil {
+		customLog.Fatalf("Failed to configure email delivery: %v", err)
+	}
+	if mailSender != nil {
+		dispatcher := &mail.Dispatcher{Store: metaDB, Sender: mailSender, MaxAttempts: cfg.MailMaxAttempts, PollInterval: cfg.MailPollInterval}
+		go dispatcher.Run(ctx)
+	}
+
+	// Unusual sign-in and API key activity is audited and alerted on
+	anomaly.Configure(metaDB, anomaly.Options{
+		FailedLogins:      cfg.AuthAlertFailedLogins,
+		FailedLoginWindow: cfg.AuthAlertFailedLoginWindow,
+		CountryHeader:     cfg.AuthAlertCountryHeader,
+		WebhookURL:        cfg.AuthAlertWebhookURL,
+		Email:             mailSender != nil,
+	})
+
+	// Writes are rejected, and alerted on, while the data volumes run low on space
+	if cfg.DiskSpaceCheckInterval > 0 {
+		metadataDir := ""
+		if cfg.MetadataBackend != storage.BackendPostgres {
+			metadataDir = cfg.MetadataDbDir
+		}
+		go storage.RunDiskSpaceMonitor(ctx, metadataDir, cfg.DiskSpaceCheckInterval, cfg.DiskSpaceMinFree, anomaly.LowDiskSpace)
+	}
+
+	// Writes to user databases are delivered to their webhooks, whichever API or job
+	// made them
+	webhooks.Configure(metaDB, webhooks.Options{
+		Timeout:           cfg.WebhookTimeout,
+		MaxAttempts:       cfg.WebhookMaxAttempts,
+		AllowPrivateHosts: cfg.WebhookAllowPrivateHosts,
+	})
+
+	// Record changes are published to Kafka or NATS (only when a broker is configured)
+	bridge, err := eventbridge.New(cfg)
+	if err != nil {
+		customLog.Fatalf("Failed to configure the event bridge: %v", err)
+	}
+	if bridge != nil {
+		eventbridge.Configure(bridge)
+		go bridge.Run(ctx)
+	}
+	storage.OnRecordChange(changefeed.New(metaDB).Handle)
+
+	// Panics and 5xx responses are reported to Sentry (only when SENTRY_DSN is set)
+	tracker, err := errortracking.New(cfg)
+	if err != nil {
+		customLog.Fatalf("Failed to configure error tracking: %v", err)
+	}
+	if tracker != nil {
+		errortracking.Configure(tracker)
+		go tracker.Run(ctx)
+	}
+
+	// Instances sharing the data directory detect each other, take turns writing and
+	// exchange cache invalidations (as configured)
+	coordinator, err := coordination.New(cfg)
+	if err != nil {
+		customLog.Fatalf("Failed to configure instance coordination: %v", err)
+	}
+	if coordinator != nil {
+		coordination.Configure(coordinator)
+		go coordinator.Run(ctx)
+	}
+
+	// Settings sourced from secrets managers follow rotations
+	go cfg.WatchSecrets(ctx, func(key, value string) {
+		applyRotatedSecret(cfg, key, value)
+	})
+
 	// 3. Setup Router (passing dependencies)
 	router := api.SetupRouter(metaDB, cfg)
 
-	// 4. Start Server
-	customLog.Printf("Server listening on port %s", cfg.ServerPort)
-	if err := router.Run(fmt.Sprintf(":%s", cfg.ServerPort)); err != nil {
+	// Optional gRPC listener next to the HTTP server
+	if cfg.GRPCPort != "" {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+		if err != nil {
+			customLog.Fatalf("Failed to listen on gRPC port %s: %v", cfg.GRPCPort, err)
+		}
+		grpcServer := grpcapi.NewServer(metaDB, cfg)
+		defer grpcServer.GracefulStop()
+		go func() {
+			customLog.Printf("gRPC server listening on port %s", cfg.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				customLog.Warnf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
+
+	// 4. Start Server (HTTPS when TLS is configured)
+	if err := serve(router, cfg); err != nil {
 		customLog.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// configurePasswordHashing applies the configured password hashing settings to new
+// hashes.
+func configurePasswordHashing(cfg *config.Config) error {
+	return auth.ConfigurePasswordHashing(auth.PasswordHashing{
+		Algorithm:         cfg.PasswordHash,
+		BcryptCost:        cfg.BcryptCost,
+		Argon2Memory:      cfg.Argon2Memory,
+		Argon2Iterations:  cfg.Argon2Iterations,
+		Argon2Parallelism: cfg.Argon2Parallelism,
+		Pepper:            cfg.PasswordPepper,
+		PreviousPeppers:   cfg.PasswordPepperPrevious,
+	})
+}
@@ -0,0 +1,112 @@
+// cmd/server/relocate.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/coordination"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// runRelocate implements the relocate subcommand, which moves the databases of a user
+// to another data root, e.g. to take a disk out of service. A running server would
+// keep using the old files, so it refuses to run next to one; move databases of a live
+// server with POST /api/v1/admin/users/{user_id}/relocate instead. Returns the exit code.
+func runRelocate(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("relocate", flag.ContinueOnError)
+	owner := fs.String("owner", "", "Email of the user whose databases are moved")
+	dbName := fs.String("db", "", "Only move this database (default: all of the user's)")
+	to := fs.String("to", "", "Data root to move to (one of DATA_ROOTS)")
+	force := fs.Bool("force", false, "Run even though server instances seem to be running")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *owner == "" || *to == "" {
+		fmt.Fprintln(fs.Output(), "relocate: -owner and -to are required")
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "relocate: failed to load configuration: %v\n", err)
+		return 1
+	}
+	if err := logger.Configure(cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(out, "relocate: invalid logging configuration: %v\n", err)
+		return 1
+	}
+
+	if cfg.InstanceHeartbeat == 0 {
+		fmt.Fprintln(out, "relocate: instance heartbeats are disabled, so running servers cannot be detected; make sure none is")
+	} else if instances, err := coordination.RunningInstances(cfg.MetadataDbDir, cfg.InstanceHeartbeat); err != nil {
+		fmt.Fprintf(out, "relocate: failed to check for running servers: %v\n", err)
+		return 1
+	} else if len(instances) > 0 && !*force {
+		ids := make([]string, len(instances))
+		for i, instance := range instances {
+			ids[i] = instance.ID
+		}
+		fmt.Fprintf(out, "relocate: server instances are running (%s); they would keep writing to the old files.\n", strings.Join(ids, ", "))
+		fmt.Fprintln(out, "Stop them, or use POST /api/v1/admin/users/{user_id}/relocate on a running server.")
+		return 1
+	}
+
+	ctx := context.Background()
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		fmt.Fprintf(out, "relocate: failed to initialize metadata database: %v\n", err)
+		return 1
+	}
+	defer metaDB.Close()
+	if err := storage.ConfigureUserData(cfg); err != nil {
+		fmt.Fprintf(out, "relocate: failed to initialize user data backend: %v\n", err)
+		return 1
+	}
+	defer storage.CloseUserData()
+	if err := storage.ConfigureEncryption(cfg.UserDBEncryptionKey); err != nil {
+		fmt.Fprintf(out, "relocate: failed to enable user database encryption: %v\n", err)
+		return 1
+	}
+	defer storage.CloseAllUserDBs()
+	if err := storage.ConfigureDataRoots(cfg.DataRoots, cfg.DataPlacement); err != nil {
+		fmt.Fprintf(out, "relocate: %v\n", err)
+		return 1
+	}
+
+	user, err := metaDB.FindUserByEmail(ctx, *owner)
+	if err != nil {
+		fmt.Fprintf(out, "relocate: failed to find user '%s': %v\n", *owner, err)
+		return 1
+	}
+	relocations, err := storage.RelocateUserDatabases(ctx, metaDB, user.UserId, *dbName, *to)
+	if err != nil {
+		fmt.Fprintf(out, "relocate: %v\n", err)
+		return 1
+	}
+
+	failed := false
+	for _, r := range relocations {
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(out, "%s: FAILED: %s\n", r.DBName, r.Error)
+			failed = true
+		case r.Skipped:
+			fmt.Fprintf(out, "%s: already in %s\n", r.DBName, *to)
+		case r.Checksum == "":
+			fmt.Fprintf(out, "%s: %s -> %s (no file yet)\n", r.DBName, r.From, r.To)
+		default:
+			fmt.Fprintf(out, "%s: %s -> %s (sha256 %s)\n", r.DBName, r.From, r.To, r.Checksum)
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+// cmd/server/secrets.go
+package main
+
+import (
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// applyRotatedSecret puts a setting that was rotated in its secrets manager into effect
+// where that can be done safely at runtime. JWT_SECRET is handled by the config itself.
+func applyRotatedSecret(cfg *config.Config, key, value string) {
+	switch key {
+	case "BACKUP_ENCRYPTION_KEY":
+		newKey, err := config.DecodeEncryptionKey(value)
+		if err != nil {
+			customLog.Warnf("Secrets: Ignoring rotated BACKUP_ENCRYPTION_KEY: %v", err)
+			return
+		}
+		// The old key stays available so existing backups can still be restored
+		if cfg.BackupEncryptionKey != nil {
+			cfg.BackupEncryptionPreviousKeys = append(cfg.BackupEncryptionPreviousKeys, cfg.BackupEncryptionKey)
+		}
+		cfg.BackupEncryptionKey = newKey
+		if err := storage.ConfigureBackups(cfg.BackupCompression, cfg.BackupEncryptionKey, cfg.BackupEncryptionPreviousKeys); err != nil {
+			customLog.Warnf("Secrets: Failed to apply rotated BACKUP_ENCRYPTION_KEY: %v", err)
+		}
+	case "PASSWORD_PEPPER":
+		if len(value) < config.MinPepperLength {
+			customLog.Warnf("Secrets: Ignoring rotated PASSWORD_PEPPER: must be at least %d characters", config.MinPepperLength)
+			return
+		}
+		// Hashes made with the old pepper keep verifying and are upgraded on login
+		if cfg.PasswordPepper != "" {
+			cfg.PasswordPepperPrevious = append(cfg.PasswordPepperPrevious, cfg.PasswordPepper)
+		}
+		cfg.PasswordPepper = value
+		if err := configurePasswordHashing(cfg); err != nil {
+			customLog.Warnf("Secrets: Failed to apply rotated PASSWORD_PEPPER: %v", err)
+		}
+	case "USER_DB_ENCRYPTION_KEY":
+		// Files are keyed with the master key; switching it would lock every database out
+		customLog.Warnf("Secrets: USER_DB_ENCRYPTION_KEY changed but user databases must be re-keyed offline; keeping the current key")
+	default:
+		customLog.Warnf("Secrets: %s changed; the new value takes effect after a restart", key)
+	}
+}
@@ -0,0 +1,145 @@
+// cmd/server/seed.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/coordination"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// runSeed implements the seed subcommand, which loads a seed file (see core.Seed) into
+// a database of a user, creating the database when needed, e.g. to prepare an
+// integration-test environment before the server starts. A running server would keep
+// serving cached schemas of the dropped tables, so it refuses to run next to one; seed
+// a live server with POST /api/v1/databases/{db_name}/seed instead. Returns the exit
+// code.
+func runSeed(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	owner := fs.String("owner", "", "Email of the user owning the database")
+	dbName := fs.String("db", "", "Database to seed (created if missing)")
+	file := fs.String("file", "", "Seed file (JSON)")
+	reset := fs.Bool("reset", false, "Drop every table of the database first")
+	force := fs.Bool("force", false, "Run even though server instances seem to be running")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *owner == "" || *dbName == "" || *file == "" {
+		fmt.Fprintln(fs.Output(), "seed: -owner, -db and -file are required")
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+	seed, err := core.ParseSeed(data)
+	if err != nil {
+		fmt.Fprintf(out, "seed: %s: %v\n", *file, err)
+		return 1
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "seed: failed to load configuration: %v\n", err)
+		return 1
+	}
+	if err := logger.Configure(cfg.LogFormat, cfg.LogLevel); err != nil {
+		fmt.Fprintf(out, "seed: invalid logging configuration: %v\n", err)
+		return 1
+	}
+
+	if cfg.InstanceHeartbeat > 0 && !*force {
+		instances, err := coordination.RunningInstances(cfg.MetadataDbDir, cfg.InstanceHeartbeat)
+		if err != nil {
+			fmt.Fprintf(out, "seed: failed to check for running servers: %v\n", err)
+			return 1
+		}
+		if len(instances) > 0 {
+			fmt.Fprintf(out, "seed: %d server instance(s) are running and would not see the new tables.\n", len(instances))
+			fmt.Fprintln(out, "Stop them, or set SEED_API_ENABLED and use POST /api/v1/databases/{db_name}/seed.")
+			return 1
+		}
+	}
+
+	ctx := context.Background()
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		fmt.Fprintf(out, "seed: failed to initialize metadata database: %v\n", err)
+		return 1
+	}
+	defer metaDB.Close()
+	if err := storage.ConfigureUserData(cfg); err != nil {
+		fmt.Fprintf(out, "seed: failed to initialize user data backend: %v\n", err)
+		return 1
+	}
+	defer storage.CloseUserData()
+	if err := storage.ConfigureEncryption(cfg.UserDBEncryptionKey); err != nil {
+		fmt.Fprintf(out, "seed: failed to enable user database encryption: %v\n", err)
+		return 1
+	}
+	defer storage.CloseAllUserDBs()
+	if err := storage.ConfigureDataRoots(cfg.DataRoots, cfg.DataPlacement); err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+
+	location, err := importTarget(ctx, metaDB, cfg, *owner, *dbName)
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+	user, err := metaDB.FindUserByEmail(ctx, *owner)
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+	databaseID, err := metaDB.FindDatabaseIDByNameAndUser(ctx, user.UserId, *dbName)
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+	userDB, err := storage.OpenUserData(ctx, location)
+	if err != nil {
+		fmt.Fprintf(out, "seed: failed to open database '%s': %v\n", *dbName, err)
+		return 1
+	}
+	defer userDB.Release()
+
+	forget := func(ctx context.Context, tableName string) {
+		storage.ForgetTableMetadata(ctx, metaDB, databaseID, tableName)
+	}
+	result, err := storage.LoadSeed(ctx, userDB, seed, *reset, forget)
+	if result != nil {
+		if len(result.Dropped) > 0 {
+			fmt.Fprintf(out, "dropped %s\n", strings.Join(result.Dropped, ", "))
+		}
+		for _, table := range seed.Tables {
+			rows, ok := result.Rows[table.Name]
+			if !ok {
+				continue
+			}
+			verb := "seeded"
+			if slices.Contains(result.Created, table.Name) {
+				verb = "created"
+			}
+			fmt.Fprintf(out, "%s: %s, %d row(s)\n", table.Name, verb, rows)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return 1
+	}
+	return 0
+}
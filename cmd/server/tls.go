@@ -0,0 +1,122 @@
+// cmd/server/tls.go
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+// serve runs the API on cfg.ServerPort, over TLS when a certificate/key pair or
+// autocert domains are configured, and blocks until the listener fails.
+func serve(handler http.Handler, cfg *config.Config) error {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.ServerPort),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var redirect http.Handler = redirectToHTTPS(cfg.ServerPort)
+	switch {
+	case cfg.TLSCertFile != "":
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	case len(cfg.TLSAutocertDomains) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		server.TLSConfig = manager.TLSConfig()
+		server.TLSConfig.MinVersion = tls.VersionTLS12
+		// HTTP-01 challenges arrive on the plain listener; everything else is redirected
+		redirect = manager.HTTPHandler(nil)
+		customLog.Printf("TLS: Obtaining certificates via ACME for %v (cache: %s)", cfg.TLSAutocertDomains, cfg.TLSAutocertCacheDir)
+	default:
+		customLog.Printf("Server listening on port %s", cfg.ServerPort)
+		return server.ListenAndServe()
+	}
+
+	if cfg.TLSClientCAFile != "" {
+		if err := requireClientCertificates(server.TLSConfig, cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.HTTPRedirectPort != "" {
+		redirectServer := &http.Server{
+			Addr:              fmt.Sprintf(":%s", cfg.HTTPRedirectPort),
+			Handler:           redirect,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			customLog.Printf("Redirecting HTTP on port %s to HTTPS", cfg.HTTPRedirectPort)
+			if err := redirectServer.ListenAndServe(); err != nil {
+				customLog.Warnf("HTTP redirect listener stopped: %v", err)
+			}
+		}()
+	}
+
+	customLog.Printf("Server listening with TLS on port %s", cfg.ServerPort)
+	// Empty paths make ListenAndServeTLS use TLSConfig.GetCertificate (autocert)
+	return server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+}
+
+// requireClientCertificates makes tlsConfig verify client certificates against the CAs
+// of cfg.TLSClientCAFile. Connections without one are refused, unless only some routes
+// require it: middleware.ClientCertificate then rejects those requests.
+func requireClientCertificates(tlsConfig *tls.Config, cfg *config.Config) error {
+	pem, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return fmt.Errorf("reading TLS_CLIENT_CA_FILE: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.New("TLS_CLIENT_CA_FILE contains no PEM certificate")
+	}
+	tlsConfig.ClientCAs = pool
+	if len(cfg.TLSClientAuthRoutes) > 0 {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		customLog.Printf("TLS: Client certificates required for %v", cfg.TLSClientAuthRoutes)
+	} else {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		customLog.Printf("TLS: Client certificates required for every connection")
+	}
+
+	// Let's Encrypt's TLS-ALPN-01 challenges come without a client certificate
+	challenge := tlsConfig.Clone()
+	challenge.ClientAuth = tls.NoClientCert
+	tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+			return challenge, nil
+		}
+		return nil, nil
+	}
+	return nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same host and path over HTTPS on
+// tlsPort.
+func redirectToHTTPS(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,23 @@
+// config/account_exports.go
+package config
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// loadAccountExports reads where self-service account exports are written and how long
+// they can be downloaded into cfg. By default they are kept under the data directory
+// for 3 days.
+func loadAccountExports(cfg *Config) {
+	cfg.AccountExportDir = getEnv("ACCOUNT_EXPORT_DIRECTORY", filepath.Join(cfg.MetadataDbDir, "exports"))
+
+	retentionStr := getEnv("ACCOUNT_EXPORT_RETENTION_HOURS", "72")
+	retention, err := strconv.Atoi(retentionStr)
+	if err != nil || retention < 1 {
+		customLog.Warnf("Invalid ACCOUNT_EXPORT_RETENTION_HOURS '%s'. Using default 72. Error: %v", retentionStr, err)
+		retention = 72
+	}
+	cfg.AccountExportRetention = time.Duration(retention) * time.Hour
+}
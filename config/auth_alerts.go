@@ -0,0 +1,38 @@
+// config/auth_alerts.go
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// loadAuthAlerts reads and validates the auth anomaly alert settings into cfg.
+func loadAuthAlerts(cfg *Config) error {
+	failedLoginsStr := getEnv("AUTH_ALERT_FAILED_LOGINS", "10")
+	failedLogins, err := strconv.Atoi(failedLoginsStr)
+	if err != nil || failedLogins < 0 {
+		customLog.Warnf("Invalid AUTH_ALERT_FAILED_LOGINS '%s'. Using default 10. Error: %v", failedLoginsStr, err)
+		failedLogins = 10
+	}
+	cfg.AuthAlertFailedLogins = failedLogins
+
+	windowStr := getEnv("AUTH_ALERT_FAILED_LOGIN_WINDOW_MINUTES", "15")
+	windowMinutes, err := strconv.Atoi(windowStr)
+	if err != nil || windowMinutes <= 0 {
+		customLog.Warnf("Invalid AUTH_ALERT_FAILED_LOGIN_WINDOW_MINUTES '%s'. Using default 15. Error: %v", windowStr, err)
+		windowMinutes = 15
+	}
+	cfg.AuthAlertFailedLoginWindow = time.Minute * time.Duration(windowMinutes)
+
+	cfg.AuthAlertCountryHeader = getEnvOptional("AUTH_ALERT_COUNTRY_HEADER")
+	cfg.AuthAlertWebhookURL = getEnvOptional("AUTH_ALERT_WEBHOOK_URL")
+	if cfg.AuthAlertWebhookURL != "" {
+		target, err := url.Parse(cfg.AuthAlertWebhookURL)
+		if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+			return fmt.Errorf("invalid AUTH_ALERT_WEBHOOK_URL '%s' (use an http or https URL)", cfg.AuthAlertWebhookURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// config/client_certificates.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// loadClientCertificates reads the mutual TLS settings into cfg. With a client CA,
+// every connection must present a certificate it signed, unless TLS_CLIENT_AUTH_ROUTES
+// limits the requirement to some path prefixes. TLS_CLIENT_IDENTITIES maps certificate
+// identities to the user ID of the service account they authenticate as.
+func loadClientCertificates(cfg *Config) error {
+	cfg.TLSClientCAFile = getEnvOptional("TLS_CLIENT_CA_FILE")
+	cfg.TLSClientAuthRoutes = splitList(getEnvOptional("TLS_CLIENT_AUTH_ROUTES"))
+	identities := splitList(getEnvOptional("TLS_CLIENT_IDENTITIES"))
+
+	if cfg.TLSClientCAFile == "" {
+		if len(cfg.TLSClientAuthRoutes) > 0 || len(identities) > 0 {
+			return errors.New("TLS_CLIENT_AUTH_ROUTES and TLS_CLIENT_IDENTITIES require TLS_CLIENT_CA_FILE")
+		}
+		return nil
+	}
+	if cfg.TLSCertFile == "" && len(cfg.TLSAutocertDomains) == 0 {
+		return errors.New("TLS_CLIENT_CA_FILE requires native TLS (TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS)")
+	}
+	for _, route := range cfg.TLSClientAuthRoutes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("TLS_CLIENT_AUTH_ROUTES: '%s' is not a path prefix (e.g. /api/v1/admin)", route)
+		}
+	}
+
+	cfg.TLSClientIdentities = make(map[string]string, len(identities))
+	for _, entry := range identities {
+		// Identities may contain '=' (e.g. URI SANs), user IDs do not
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return fmt.Errorf("TLS_CLIENT_IDENTITIES: '%s' must be identity=user_id", entry)
+		}
+		identity, userID := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		if _, ok := cfg.TLSClientIdentities[identity]; ok {
+			return fmt.Errorf("TLS_CLIENT_IDENTITIES: '%s' is mapped twice", identity)
+		}
+		cfg.TLSClientIdentities[identity] = userID
+	}
+	return nil
+}
@@ -1,14 +1,21 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 
 	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/secrets"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
 )
 
 var (
@@ -18,10 +25,246 @@ var (
 // Config holds application configuration values
 type Config struct {
 	ServerPort     string
-	JWTSecret      string
+	GRPCPort       string // gRPC listener port (disabled when empty)
+	JWTSecret      string // Initial secret; use JWTSigningSecret/JWTVerificationSecrets, which follow rotations
 	JWTExpiration  time.Duration
 	MetadataDbDir  string
 	MetadataDbFile string
+
+	// CORS: allowed browser origins (exact, "*" or wildcard subdomains like
+	// https://*.example.com) and whether credentialed requests are allowed
+	CORSPreset           string
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+
+	// Metadata store backend: "sqlite" (file in MetadataDbDir) or "postgres"
+	MetadataBackend          string
+	MetadataPostgresDSN      string
+	MetadataPostgresMaxConns int
+
+	// Metadata calls fail at once with 503 for MetadataBreakerCooldown after this many
+	// consecutive busy, timed out or I/O failures (0 disables the circuit breaker)
+	MetadataBreakerFailures int
+	MetadataBreakerCooldown time.Duration
+
+	// Tenant data backend: "sqlite" (one file per database) or "postgres" (one schema per database)
+	UserDataBackend          string
+	UserDataPostgresDSN      string
+	UserDataPostgresMaxConns int
+
+	// Master key for at-rest encryption of SQLite user DB files (SQLCipher); nil disables it
+	UserDBEncryptionKey []byte
+
+	// Backup files: compression ("none", "gzip" or "zstd") and AES-256 encryption key; the
+	// previous keys only decrypt backups written before a key rotation
+	BackupCompression            string
+	BackupEncryptionKey          []byte
+	BackupEncryptionPreviousKeys [][]byte
+
+	BackupDir      string
+	APIDocsEnabled bool   // Serve the API explorer at /docs
+	AdminUIEnabled bool   // Serve the admin console at /admin
+	SeedAPIEnabled bool   // Serve POST /databases/:db_name/seed (test environments)
+	MetricsEnabled bool   // Serve Prometheus metrics at /metrics
+	MetricsToken   string // Bearer token scrapes of /metrics must send; "" allows any
+	LogFormat      string // "json" (default) or "text"
+	LogLevel       string // Minimum level: debug, info, warn or error
+
+	// Initial service mode (normal, read_only or maintenance); admins can switch it at runtime
+	ServiceMode        string
+	ServiceModeMessage string
+
+	// Native TLS: either a certificate/key pair or ACME (Let's Encrypt) autocert domains
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomains  []string
+	TLSAutocertEmail    string
+	TLSAutocertCacheDir string
+
+	// Mutual TLS: client certificates signed by TLSClientCAFile are verified, and
+	// required on TLSClientAuthRoutes (every route when empty). TLSClientIdentities maps
+	// a certificate identity (subject CN or SAN) to the user ID it authenticates as
+	TLSClientCAFile     string
+	TLSClientAuthRoutes []string
+	TLSClientIdentities map[string]string
+	HTTPRedirectPort    string // Plain HTTP listener redirecting to HTTPS (and serving ACME challenges)
+
+	// HTTP access log (stdout unless a file is configured)
+	AccessLogEnabled       bool
+	AccessLogFile          string // Rotated by size when set
+	AccessLogMaxSizeMB     int
+	AccessLogMaxBackups    int
+	AccessLogMaxAgeDays    int
+	AccessLogBodies        bool     // Include JSON request bodies
+	AccessLogRedactHeaders []string // Header values replaced with [REDACTED]
+	AccessLogRedactFields  []string // JSON body fields replaced with [REDACTED]
+
+	// Cache of open user database handles
+	UserDBPoolMaxOpen     int
+	UserDBPoolIdleTimeout time.Duration
+
+	// Periodic WAL truncation for open user databases
+	WALCheckpointInterval  time.Duration
+	WALCheckpointThreshold int64 // Minimum -wal size in bytes before a checkpoint runs
+
+	// In-memory cache for record reads (disabled when max entries is 0)
+	ReadCacheMaxEntries int
+	ReadCacheTTL        time.Duration
+
+	// Refresh of read replicas designated by admins (disabled when 0)
+	ReadReplicaRefreshInterval time.Duration
+
+	// Off-site replication to S3-compatible storage (disabled when bucket is empty)
+	S3ReplicationEndpoint    string
+	S3ReplicationRegion      string
+	S3ReplicationBucket      string
+	S3ReplicationPrefix      string
+	S3ReplicationAccessKeyID string
+	S3ReplicationSecretKey   string
+	S3ReplicationPathStyle   bool
+	S3ReplicationInterval    time.Duration
+
+	// Transactional email (disabled when provider is empty); see internal/mail
+	MailProvider           string // "", "log", "smtp", "ses" or "sendgrid"
+	MailFrom               string
+	MailSMTPHost           string
+	MailSMTPPort           string
+	MailSMTPUsername       string
+	MailSMTPPassword       string
+	MailSMTPTLS            string // "starttls", "implicit" or "none"
+	MailSESRegion          string
+	MailSESAccessKeyID     string
+	MailSESSecretAccessKey string
+	MailSESSessionToken    string
+	MailSendGridAPIKey     string
+	MailMaxAttempts        int
+	MailPollInterval       time.Duration
+
+	// Auth anomaly alerts (see internal/anomaly): bursts of failed logins per account or
+	// client IP, API keys used from a new country and deleted API keys still in use
+	AuthAlertFailedLogins      int // Failed logins within the window that raise an alert (0 disables)
+	AuthAlertFailedLoginWindow time.Duration
+	AuthAlertCountryHeader     string // Header a trusted proxy sets to the client's country, e.g. CF-IPCountry ("" disables new-country alerts)
+	AuthAlertWebhookURL        string // Alerts are also POSTed here as JSON when set
+
+	// Database webhooks (see internal/webhooks): per-attempt timeout, attempts per event
+	// and whether webhooks may target loopback and private networks
+	WebhookTimeout           time.Duration
+	WebhookMaxAttempts       int
+	WebhookAllowPrivateHosts bool
+
+	// Event bridge (see internal/eventbridge): publishes record changes to Kafka or NATS
+	EventBridge          string   // kafka or nats ("" disables)
+	EventBridgeBrokers   []string // Kafka bootstrap brokers or NATS servers, host:port
+	EventBridgeTopic     string   // Topic/subject template, may use {user_id}, {db_name}, {table} and {event}
+	EventBridgeTLS       bool
+	EventBridgeUsername  string // Kafka SASL/PLAIN or NATS user
+	EventBridgePassword  string // Alone, the NATS token
+	EventBridgeQueueSize int    // Changes buffered while the broker is slow or down; more are dropped
+
+	// Error tracking (see internal/errortracking): panics and 5xx responses are reported
+	// to Sentry or a compatible service
+	SentryDSN         string // "" disables
+	SentryEnvironment string // Defaults to APP_ENV
+	SentryRelease     string
+	SentrySampleRate  float64 // Share of events sent, 0 to 1
+
+	// Instances sharing a data directory (see internal/coordination): heartbeats to detect
+	// each other, advisory locks on user DB writes and cache invalidation over Redis
+	InstanceID                string        // Defaults to the hostname and a random suffix
+	InstanceHeartbeat         time.Duration // 0 disables detection
+	FileLocksEnabled          bool
+	CacheInvalidationRedisURL string // redis:// or rediss:// ("" disables)
+	CacheInvalidationChannel  string
+
+	// Directories user database files are spread over, e.g. one per disk, and how a new
+	// database's directory is chosen: "most_free" (most free space) or "hash" (by
+	// account, keeping an account's databases together)
+	DataRoots     []string // Defaults to MetadataDbDir alone
+	DataPlacement string
+
+	// Database files on the data roots with no registered database, e.g. left behind by
+	// a failed delete: looked for every interval and, once older than the grace period,
+	// logged or removed
+	OrphanFileGCInterval  time.Duration // 0 disables the scheduled collection
+	OrphanFileGracePeriod time.Duration
+	OrphanFileGCRemove    bool
+
+	// Writes are rejected while the metadata directory or a data root has less free
+	// space than DiskSpaceMinFree bytes (a check interval of 0 disables monitoring)
+	DiskSpaceCheckInterval time.Duration
+	DiskSpaceMinFree       uint64
+
+	// API v1 lifecycle: once set, v1 responses carry Deprecation/Sunset headers
+	APIV1DeprecatedAt time.Time
+	APIV1SunsetAt     time.Time
+
+	// Whether the doctor checks (see internal/doctor) run at startup: "off", "warn" (log
+	// problems) or "strict" (refuse to start on errors)
+	StartupChecks string
+
+	// Requests running longer are cancelled with 504 (0 disables; long-running routes are exempt)
+	RequestTimeout time.Duration
+
+	// Overload protection: requests beyond these many in flight are rejected with 503
+	// (0 disables); one tenant (credentials, or client IP) may hold at most its share
+	MaxInFlightRequests  int
+	MaxInFlightPerTenant int // 0 allows a quarter of MaxInFlightRequests
+
+	// Table scripts (see internal/scripting): per-hook time budget, call stack depth and
+	// the hosts webhook() may call (none disables it)
+	ScriptTimeout             time.Duration
+	ScriptMaxCallStackSize    int
+	ScriptWebhookAllowedHosts []string
+
+	// Deleted databases and dropped tables stay restorable this long (0 deletes at once)
+	TrashRetention time.Duration
+
+	// Ephemeral databases live EphemeralDatabaseTTL unless created with a lifetime of
+	// their own, which may not exceed EphemeralDatabaseMaxTTL
+	EphemeralDatabaseTTL    time.Duration
+	EphemeralDatabaseMaxTTL time.Duration
+
+	// Record operations taking SlowQueryThreshold or more are captured per database, the
+	// SlowQueriesPerDatabase slowest statement shapes kept (0 disables)
+	SlowQueryThreshold     time.Duration
+	SlowQueriesPerDatabase int
+
+	// New password hashes use PasswordHash ("bcrypt" or "argon2id") with these
+	// parameters; existing hashes keep verifying and are upgraded on login
+	PasswordHash      string
+	BcryptCost        int
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	// PasswordPepper is mixed into new password hashes; hashes made with one of
+	// PasswordPepperPrevious keep verifying until they are upgraded on login
+	PasswordPepper         string
+	PasswordPepperPrevious []string
+
+	// Self-service account exports are written under AccountExportDir and deleted after
+	// AccountExportRetention
+	AccountExportDir       string
+	AccountExportRetention time.Duration
+
+	// Signed requests are accepted when their timestamp is within RequestSigningMaxSkew
+	// of the server's clock; their body is read whole to verify the signature, up to
+	// RequestSigningMaxBodySize bytes
+	RequestSigningMaxSkew     time.Duration
+	RequestSigningMaxBodySize int64
+
+	// Every table is limited to this many rows, e.g. by the hosting plan (0 disables);
+	// tables can set a lower limit of their own
+	TableMaxRows int64
+
+	// Settings sourced from secrets managers are re-read this often (0 disables refresh)
+	SecretsRefreshInterval time.Duration
+
+	secretResolver *secrets.Resolver
+	secretRefs     map[string]string // Env key -> secrets manager reference
+	secretValues   map[string]string // Env key -> value last loaded
+	jwtSecrets     *jwtSecretRing
 }
 
 // LoadConfig loads configuration from environment variables.
@@ -36,12 +279,45 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Optional YAML/TOML config file; environment variables (and .env) take precedence
+	if configFile := os.Getenv("CONFIG_FILE"); configFile != "" {
+		if err := applyConfigFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Settings may reference a secrets manager instead of holding the secret
+	secretResolver := newSecretResolver()
+	secretRefs, secretValues, err := resolveSecretReferences(secretResolver)
+	if err != nil {
+		return nil, err
+	}
+
 	// Read values from environment variables, providing defaults where appropriate
 	port := getEnv("SERVER_PORT", ":8080")                 // Default to :8080
 	jwtSecret := getEnv("JWT_SECRET", "")                  // No sensible default for secret!
 	jwtExpHoursStr := getEnv("JWT_EXPIRATION_HOURS", "24") // Default to 24 hours
 	dbDir := getEnv("DATABASE_DIRECTORY", "data")
 	dbFile := getEnv("DATABASE_DIRECTORY_FILE", "metadata.db")
+	backupDir := getEnv("BACKUP_DIRECTORY", filepath.Join(dbDir, "backups"))
+	// The API explorer is on by default outside production
+	apiDocsDefault := "true"
+	if os.Getenv("APP_ENV") == "production" {
+		apiDocsDefault = "false"
+	}
+	apiDocsEnabled := getEnv("API_DOCS_ENABLED", apiDocsDefault) == "true"
+	poolMaxOpenStr := getEnv("USER_DB_POOL_MAX_OPEN", "128")
+	poolIdleStr := getEnv("USER_DB_POOL_IDLE_TIMEOUT_SECONDS", "300")
+	walIntervalStr := getEnv("WAL_CHECKPOINT_INTERVAL_SECONDS", "300")
+	walThresholdStr := getEnv("WAL_CHECKPOINT_THRESHOLD_MB", "64")
+	readCacheEntriesStr := getEnv("READ_CACHE_MAX_ENTRIES", "1024")
+	readCacheTTLStr := getEnv("READ_CACHE_TTL_SECONDS", "30")
+	replicaRefreshStr := getEnv("READ_REPLICA_REFRESH_INTERVAL_SECONDS", "5")
+	s3Bucket := getEnvOptional("S3_REPLICATION_BUCKET")
+	accessLogMaxSizeStr := getEnv("ACCESS_LOG_MAX_SIZE_MB", "100")
+	accessLogMaxBackupsStr := getEnv("ACCESS_LOG_MAX_BACKUPS", "7")
+	accessLogMaxAgeStr := getEnv("ACCESS_LOG_MAX_AGE_DAYS", "28")
+	s3IntervalStr := getEnv("S3_REPLICATION_INTERVAL_MINUTES", "15")
 
 	// --- Validation and Parsing ---
 	// Critical: Ensure JWT Secret is set
@@ -60,14 +336,339 @@ func LoadConfig() (*Config, error) {
 	}
 	jwtExpiration := time.Hour * time.Duration(jwtExpHours)
 
+	// Parse user DB pool limits
+	poolMaxOpen, err := strconv.Atoi(poolMaxOpenStr)
+	if err != nil || poolMaxOpen <= 0 {
+		customLog.Warnf("Invalid USER_DB_POOL_MAX_OPEN '%s'. Using default 128. Error: %v", poolMaxOpenStr, err)
+		poolMaxOpen = 128
+	}
+	poolIdleSeconds, err := strconv.Atoi(poolIdleStr)
+	if err != nil || poolIdleSeconds <= 0 {
+		customLog.Warnf("Invalid USER_DB_POOL_IDLE_TIMEOUT_SECONDS '%s'. Using default 300s. Error: %v", poolIdleStr, err)
+		poolIdleSeconds = 300
+	}
+
+	// Parse WAL checkpoint settings
+	walIntervalSeconds, err := strconv.Atoi(walIntervalStr)
+	if err != nil || walIntervalSeconds <= 0 {
+		customLog.Warnf("Invalid WAL_CHECKPOINT_INTERVAL_SECONDS '%s'. Using default 300s. Error: %v", walIntervalStr, err)
+		walIntervalSeconds = 300
+	}
+	walThresholdMB, err := strconv.Atoi(walThresholdStr)
+	if err != nil || walThresholdMB < 0 {
+		customLog.Warnf("Invalid WAL_CHECKPOINT_THRESHOLD_MB '%s'. Using default 64MB. Error: %v", walThresholdStr, err)
+		walThresholdMB = 64
+	}
+
+	// Parse read cache limits
+	readCacheEntries, err := strconv.Atoi(readCacheEntriesStr)
+	if err != nil || readCacheEntries < 0 {
+		customLog.Warnf("Invalid READ_CACHE_MAX_ENTRIES '%s'. Using default 1024. Error: %v", readCacheEntriesStr, err)
+		readCacheEntries = 1024
+	}
+	readCacheTTLSeconds, err := strconv.Atoi(readCacheTTLStr)
+	if err != nil || readCacheTTLSeconds <= 0 {
+		customLog.Warnf("Invalid READ_CACHE_TTL_SECONDS '%s'. Using default 30s. Error: %v", readCacheTTLStr, err)
+		readCacheTTLSeconds = 30
+	}
+
+	// Parse read replica refresh interval
+	replicaRefreshSeconds, err := strconv.Atoi(replicaRefreshStr)
+	if err != nil || replicaRefreshSeconds < 0 {
+		customLog.Warnf("Invalid READ_REPLICA_REFRESH_INTERVAL_SECONDS '%s'. Using default 5s. Error: %v", replicaRefreshStr, err)
+		replicaRefreshSeconds = 5
+	}
+
+	// Parse access log rotation
+	accessLogMaxSize, err := strconv.Atoi(accessLogMaxSizeStr)
+	if err != nil || accessLogMaxSize <= 0 {
+		customLog.Warnf("Invalid ACCESS_LOG_MAX_SIZE_MB '%s'. Using default 100MB. Error: %v", accessLogMaxSizeStr, err)
+		accessLogMaxSize = 100
+	}
+	accessLogMaxBackups, err := strconv.Atoi(accessLogMaxBackupsStr)
+	if err != nil || accessLogMaxBackups < 0 {
+		customLog.Warnf("Invalid ACCESS_LOG_MAX_BACKUPS '%s'. Using default 7. Error: %v", accessLogMaxBackupsStr, err)
+		accessLogMaxBackups = 7
+	}
+	accessLogMaxAge, err := strconv.Atoi(accessLogMaxAgeStr)
+	if err != nil || accessLogMaxAge < 0 {
+		customLog.Warnf("Invalid ACCESS_LOG_MAX_AGE_DAYS '%s'. Using default 28. Error: %v", accessLogMaxAgeStr, err)
+		accessLogMaxAge = 28
+	}
+
+	// TLS settings must describe exactly one certificate source
+	tlsCertFile, tlsKeyFile := getEnvOptional("TLS_CERT_FILE"), getEnvOptional("TLS_KEY_FILE")
+	tlsAutocertDomains := splitList(getEnvOptional("TLS_AUTOCERT_DOMAINS"))
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if tlsCertFile != "" && len(tlsAutocertDomains) > 0 {
+		return nil, errors.New("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	}
+
+	metadataBackend := getEnv("METADATA_BACKEND", "sqlite")
+	metadataPostgresDSN := getEnvOptional("METADATA_POSTGRES_DSN")
+	switch metadataBackend {
+	case "sqlite":
+	case "postgres":
+		if metadataPostgresDSN == "" {
+			return nil, errors.New("METADATA_POSTGRES_DSN must be set when METADATA_BACKEND is postgres")
+		}
+	default:
+		return nil, fmt.Errorf("invalid METADATA_BACKEND '%s' (use sqlite or postgres)", metadataBackend)
+	}
+	metadataPostgresMaxConnsStr := getEnv("METADATA_POSTGRES_MAX_CONNS", "20")
+	metadataPostgresMaxConns, err := strconv.Atoi(metadataPostgresMaxConnsStr)
+	if err != nil || metadataPostgresMaxConns <= 0 {
+		customLog.Warnf("Invalid METADATA_POSTGRES_MAX_CONNS '%s'. Using default 20. Error: %v", metadataPostgresMaxConnsStr, err)
+		metadataPostgresMaxConns = 20
+	}
+
+	userDataBackend := getEnv("USER_DATA_BACKEND", "sqlite")
+	userDataPostgresDSN := getEnvOptional("USER_DATA_POSTGRES_DSN")
+	if userDataPostgresDSN == "" {
+		userDataPostgresDSN = metadataPostgresDSN // Same server unless told otherwise
+	}
+	switch userDataBackend {
+	case "sqlite":
+	case "postgres":
+		if userDataPostgresDSN == "" {
+			return nil, errors.New("USER_DATA_POSTGRES_DSN (or METADATA_POSTGRES_DSN) must be set when USER_DATA_BACKEND is postgres")
+		}
+	default:
+		return nil, fmt.Errorf("invalid USER_DATA_BACKEND '%s' (use sqlite or postgres)", userDataBackend)
+	}
+	userDataPostgresMaxConnsStr := getEnv("USER_DATA_POSTGRES_MAX_CONNS", "20")
+	userDataPostgresMaxConns, err := strconv.Atoi(userDataPostgresMaxConnsStr)
+	if err != nil || userDataPostgresMaxConns <= 0 {
+		customLog.Warnf("Invalid USER_DATA_POSTGRES_MAX_CONNS '%s'. Using default 20. Error: %v", userDataPostgresMaxConnsStr, err)
+		userDataPostgresMaxConns = 20
+	}
+
+	userDBEncryptionKey, err := loadEncryptionKey("USER_DB_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if userDBEncryptionKey != nil && userDataBackend != "sqlite" {
+		customLog.Warnf("USER_DB_ENCRYPTION_KEY is ignored with USER_DATA_BACKEND=%s", userDataBackend)
+	}
+
+	backupCompression := strings.ToLower(getEnv("BACKUP_COMPRESSION", "none"))
+	switch backupCompression {
+	case "none", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("invalid BACKUP_COMPRESSION '%s' (use none, gzip or zstd)", backupCompression)
+	}
+	backupEncryptionKey, err := loadEncryptionKey("BACKUP_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	var backupEncryptionPreviousKeys [][]byte
+	for _, value := range splitList(getEnvOptional("BACKUP_ENCRYPTION_PREVIOUS_KEYS")) {
+		key, err := DecodeEncryptionKey(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BACKUP_ENCRYPTION_PREVIOUS_KEYS: %w", err)
+		}
+		backupEncryptionPreviousKeys = append(backupEncryptionPreviousKeys, key)
+	}
+
+	corsPreset, corsOrigins, corsAllowCredentials, err := loadCORS()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceMode := getEnv("SERVICE_MODE", "normal")
+	if _, err := servicemode.Parse(serviceMode); err != nil {
+		return nil, fmt.Errorf("invalid SERVICE_MODE: %w", err)
+	}
+
+	// Off-site replication needs credentials as soon as a bucket is configured
+	if s3Bucket != "" && (getEnvOptional("S3_REPLICATION_ACCESS_KEY_ID") == "" || getEnvOptional("S3_REPLICATION_SECRET_ACCESS_KEY") == "") {
+		return nil, errors.New("S3_REPLICATION_ACCESS_KEY_ID and S3_REPLICATION_SECRET_ACCESS_KEY must be set when S3_REPLICATION_BUCKET is set")
+	}
+	s3IntervalMinutes, err := strconv.Atoi(s3IntervalStr)
+	if err != nil || s3IntervalMinutes <= 0 {
+		customLog.Warnf("Invalid S3_REPLICATION_INTERVAL_MINUTES '%s'. Using default 15m. Error: %v", s3IntervalStr, err)
+		s3IntervalMinutes = 15
+	}
+
+	apiV1DeprecatedAt, err := parseDate("API_V1_DEPRECATION_DATE")
+	if err != nil {
+		return nil, err
+	}
+	apiV1SunsetAt, err := parseDate("API_V1_SUNSET_DATE")
+	if err != nil {
+		return nil, err
+	}
+	if !apiV1SunsetAt.IsZero() && apiV1DeprecatedAt.After(apiV1SunsetAt) {
+		return nil, errors.New("API_V1_SUNSET_DATE must not be before API_V1_DEPRECATION_DATE")
+	}
+
+	requestTimeoutStr := getEnv("REQUEST_TIMEOUT_SECONDS", "30")
+	requestTimeoutSeconds, err := strconv.Atoi(requestTimeoutStr)
+	if err != nil || requestTimeoutSeconds < 0 {
+		customLog.Warnf("Invalid REQUEST_TIMEOUT_SECONDS '%s'. Using default 30. Error: %v", requestTimeoutStr, err)
+		requestTimeoutSeconds = 30
+	}
+
+	scriptTimeoutStr := getEnv("SCRIPT_TIMEOUT_MS", "1000")
+	scriptTimeoutMs, err := strconv.Atoi(scriptTimeoutStr)
+	if err != nil || scriptTimeoutMs <= 0 {
+		customLog.Warnf("Invalid SCRIPT_TIMEOUT_MS '%s'. Using default 1000. Error: %v", scriptTimeoutStr, err)
+		scriptTimeoutMs = 1000
+	}
+
+	scriptStackStr := getEnv("SCRIPT_MAX_CALL_STACK_SIZE", "256")
+	scriptStackSize, err := strconv.Atoi(scriptStackStr)
+	if err != nil || scriptStackSize <= 0 {
+		customLog.Warnf("Invalid SCRIPT_MAX_CALL_STACK_SIZE '%s'. Using default 256. Error: %v", scriptStackStr, err)
+		scriptStackSize = 256
+	}
+
+	trashRetentionStr := getEnv("TRASH_RETENTION_HOURS", "168")
+	trashRetentionHours, err := strconv.Atoi(trashRetentionStr)
+	if err != nil || trashRetentionHours < 0 {
+		customLog.Warnf("Invalid TRASH_RETENTION_HOURS '%s'. Using default 168. Error: %v", trashRetentionStr, err)
+		trashRetentionHours = 168
+	}
+
+	tableMaxRowsStr := getEnv("TABLE_MAX_ROWS", "0")
+	tableMaxRows, err := strconv.ParseInt(tableMaxRowsStr, 10, 64)
+	if err != nil || tableMaxRows < 0 {
+		customLog.Warnf("Invalid TABLE_MAX_ROWS '%s'. Using default 0 (no limit). Error: %v", tableMaxRowsStr, err)
+		tableMaxRows = 0
+	}
+
+	secretsRefreshStr := getEnv("SECRETS_REFRESH_INTERVAL_SECONDS", "300")
+	secretsRefreshSeconds, err := strconv.Atoi(secretsRefreshStr)
+	if err != nil || secretsRefreshSeconds < 0 {
+		customLog.Warnf("Invalid SECRETS_REFRESH_INTERVAL_SECONDS '%s'. Using default 300. Error: %v", secretsRefreshStr, err)
+		secretsRefreshSeconds = 300
+	}
+
 	// Return final Config struct
 	cfg := &Config{
 		ServerPort:     port,
+		GRPCPort:       getEnvOptional("GRPC_PORT"),
 		JWTSecret:      jwtSecret,
 		JWTExpiration:  jwtExpiration,
 		MetadataDbDir:  dbDir,
 		MetadataDbFile: dbFile,
+
+		CORSPreset:           corsPreset,
+		CORSAllowedOrigins:   corsOrigins,
+		CORSAllowCredentials: corsAllowCredentials,
+
+		MetadataBackend:          metadataBackend,
+		MetadataPostgresDSN:      metadataPostgresDSN,
+		MetadataPostgresMaxConns: metadataPostgresMaxConns,
+		UserDataBackend:          userDataBackend,
+		UserDataPostgresDSN:      userDataPostgresDSN,
+		UserDataPostgresMaxConns: userDataPostgresMaxConns,
+		UserDBEncryptionKey:      userDBEncryptionKey,
+
+		BackupCompression:            backupCompression,
+		BackupEncryptionKey:          backupEncryptionKey,
+		BackupEncryptionPreviousKeys: backupEncryptionPreviousKeys,
+
+		BackupDir:      backupDir,
+		APIDocsEnabled: apiDocsEnabled,
+		AdminUIEnabled: getEnv("ADMIN_UI_ENABLED", "true") == "true",
+		SeedAPIEnabled: getEnv("SEED_API_ENABLED", "false") == "true",
+		MetricsEnabled: getEnv("METRICS_ENABLED", "false") == "true",
+		MetricsToken:   getEnvOptional("METRICS_TOKEN"),
+		LogFormat:      getEnv("LOG_FORMAT", "json"),
+		LogLevel:       getEnv("LOG_LEVEL", "debug"),
+
+		ServiceMode:        serviceMode,
+		ServiceModeMessage: getEnvOptional("SERVICE_MODE_MESSAGE"),
+
+		TLSCertFile:         tlsCertFile,
+		TLSKeyFile:          tlsKeyFile,
+		TLSAutocertDomains:  tlsAutocertDomains,
+		TLSAutocertEmail:    getEnvOptional("TLS_AUTOCERT_EMAIL"),
+		TLSAutocertCacheDir: getEnv("TLS_AUTOCERT_CACHE_DIR", filepath.Join(dbDir, "autocert")),
+		HTTPRedirectPort:    getEnvOptional("HTTP_REDIRECT_PORT"),
+
+		AccessLogEnabled:       getEnv("ACCESS_LOG_ENABLED", "true") == "true",
+		AccessLogFile:          getEnvOptional("ACCESS_LOG_FILE"),
+		AccessLogMaxSizeMB:     accessLogMaxSize,
+		AccessLogMaxBackups:    accessLogMaxBackups,
+		AccessLogMaxAgeDays:    accessLogMaxAge,
+		AccessLogBodies:        getEnv("ACCESS_LOG_BODIES", "false") == "true",
+		AccessLogRedactHeaders: splitList(getEnv("ACCESS_LOG_REDACT_HEADERS", "Authorization,Cookie,Set-Cookie")),
+		AccessLogRedactFields:  splitList(getEnv("ACCESS_LOG_REDACT_FIELDS", "password,new_password,token,api_key,secret")),
+
+		UserDBPoolMaxOpen:     poolMaxOpen,
+		UserDBPoolIdleTimeout: time.Second * time.Duration(poolIdleSeconds),
+
+		WALCheckpointInterval:  time.Second * time.Duration(walIntervalSeconds),
+		WALCheckpointThreshold: int64(walThresholdMB) << 20,
+
+		ReadCacheMaxEntries: readCacheEntries,
+		ReadCacheTTL:        time.Second * time.Duration(readCacheTTLSeconds),
+
+		ReadReplicaRefreshInterval: time.Second * time.Duration(replicaRefreshSeconds),
+
+		S3ReplicationEndpoint:    getEnv("S3_REPLICATION_ENDPOINT", "https://s3.amazonaws.com"),
+		S3ReplicationRegion:      getEnv("S3_REPLICATION_REGION", "us-east-1"),
+		S3ReplicationBucket:      s3Bucket,
+		S3ReplicationPrefix:      getEnvOptional("S3_REPLICATION_PREFIX"),
+		S3ReplicationAccessKeyID: getEnvOptional("S3_REPLICATION_ACCESS_KEY_ID"),
+		S3ReplicationSecretKey:   getEnvOptional("S3_REPLICATION_SECRET_ACCESS_KEY"),
+		S3ReplicationPathStyle:   getEnv("S3_REPLICATION_PATH_STYLE", "true") == "true",
+		S3ReplicationInterval:    time.Minute * time.Duration(s3IntervalMinutes),
+
+		APIV1DeprecatedAt: apiV1DeprecatedAt,
+		APIV1SunsetAt:     apiV1SunsetAt,
+
+		RequestTimeout: time.Second * time.Duration(requestTimeoutSeconds),
+
+		ScriptTimeout:             time.Millisecond * time.Duration(scriptTimeoutMs),
+		ScriptMaxCallStackSize:    scriptStackSize,
+		ScriptWebhookAllowedHosts: splitList(getEnvOptional("SCRIPT_WEBHOOK_ALLOWED_HOSTS")),
+
+		TrashRetention: time.Hour * time.Duration(trashRetentionHours),
+		TableMaxRows:   tableMaxRows,
+
+		SecretsRefreshInterval: time.Second * time.Duration(secretsRefreshSeconds),
+		secretResolver:         secretResolver,
+		secretRefs:             secretRefs,
+		secretValues:           secretValues,
+		jwtSecrets:             &jwtSecretRing{current: jwtSecret},
+	}
+	if err := loadMail(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadAuthAlerts(cfg); err != nil {
+		return nil, err
 	}
+	loadWebhooks(cfg)
+	if err := loadEventBridge(cfg); err != nil {
+		return nil, err
+	}
+	loadErrorTracking(cfg)
+	if err := loadCoordination(cfg); err != nil {
+		return nil, err
+	}
+	if err := loadDataRoots(cfg); err != nil {
+		return nil, err
+	}
+	loadOrphanFiles(cfg)
+	loadLoadShedding(cfg)
+	loadMetadataBreaker(cfg)
+	loadDiskSpace(cfg)
+	loadStartupChecks(cfg)
+	loadEphemeralDatabases(cfg)
+	loadSlowQueries(cfg)
+	if err := loadPasswordHashing(cfg); err != nil {
+		return nil, err
+	}
+	loadRequestSigning(cfg)
+	if err := loadClientCertificates(cfg); err != nil {
+		return nil, err
+	}
+	loadAccountExports(cfg)
 
 	customLog.Printf("Configuration loaded successfully. Port: %s, JWT Exp: %v", cfg.ServerPort, cfg.JWTExpiration)
 	return cfg, nil
@@ -85,3 +686,73 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvOptional reads an optional environment variable, returning "" when unset.
+func getEnvOptional(key string) string {
+	return os.Getenv(key)
+}
+
+// loadEncryptionKey reads a 32-byte key from the env variable name, or from the file
+// named by name+"_FILE" (e.g. one mounted by a KMS or secrets agent). Both unset means no key.
+func loadEncryptionKey(name string) ([]byte, error) {
+	value, file := getEnvOptional(name), getEnvOptional(name+"_FILE")
+	if value != "" && file != "" {
+		return nil, fmt.Errorf("%s and %s_FILE are mutually exclusive", name, name)
+	}
+	if file != "" {
+		data, err := os.ReadFile(file) // #nosec G304 -- path comes from the operator's configuration
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		value = string(data)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	key, err := DecodeEncryptionKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return key, nil
+}
+
+// DecodeEncryptionKey decodes a 32-byte key given as hex or base64.
+func DecodeEncryptionKey(value string) ([]byte, error) {
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("key must be 32 bytes, hex or base64 encoded")
+	}
+	return key, nil
+}
+
+// parseDate reads an optional date (2006-01-02, as UTC midnight, or RFC 3339) from the
+// env variable name. Unset means the zero time.
+func parseDate(name string) (time.Time, error) {
+	value := strings.TrimSpace(getEnvOptional(name))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s '%s' (use YYYY-MM-DD or RFC 3339)", name, value)
+	}
+	return t, nil
+}
+
+// splitList splits a comma-separated environment value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
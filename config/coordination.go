@@ -0,0 +1,30 @@
+// config/coordination.go
+package config
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// loadCoordination reads the settings of instances sharing a data directory into cfg.
+// The Redis URL is parsed by internal/coordination when the coordinator is built.
+func loadCoordination(cfg *Config) error {
+	cfg.InstanceID = getEnvOptional("INSTANCE_ID")
+	cfg.FileLocksEnabled = getEnv("FILE_LOCKS_ENABLED", "false") == "true"
+	cfg.CacheInvalidationRedisURL = getEnvOptional("CACHE_INVALIDATION_REDIS_URL")
+	cfg.CacheInvalidationChannel = getEnv("CACHE_INVALIDATION_CHANNEL", "nebula:invalidations")
+
+	heartbeatStr := getEnv("INSTANCE_HEARTBEAT_SECONDS", "15")
+	heartbeat, err := strconv.Atoi(heartbeatStr)
+	if err != nil || heartbeat < 0 {
+		customLog.Warnf("Invalid INSTANCE_HEARTBEAT_SECONDS '%s'. Using default 15. Error: %v", heartbeatStr, err)
+		heartbeat = 15
+	}
+	cfg.InstanceHeartbeat = time.Duration(heartbeat) * time.Second
+
+	if cfg.CacheInvalidationRedisURL != "" && cfg.InstanceHeartbeat == 0 {
+		return errors.New("CACHE_INVALIDATION_REDIS_URL requires INSTANCE_HEARTBEAT_SECONDS > 0")
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// config/cors.go
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// CORS presets (CORS_PRESET). Development allows the usual local dev servers when no
+// origins are configured; production requires explicit origins and rejects "*".
+const (
+	CORSPresetDevelopment = "development"
+	CORSPresetProduction  = "production"
+)
+
+// developmentOrigins are allowed by the development preset when ALLOWED_ORIGINS is unset.
+var developmentOrigins = []string{
+	"http://localhost:3000",
+	"http://localhost:5173",
+	"http://127.0.0.1:3000",
+	"http://127.0.0.1:5173",
+}
+
+// loadCORS reads and validates the CORS settings.
+func loadCORS() (preset string, origins []string, allowCredentials bool, err error) {
+	presetDefault := CORSPresetDevelopment
+	if os.Getenv("APP_ENV") == "production" {
+		presetDefault = CORSPresetProduction
+	}
+	preset = strings.ToLower(getEnv("CORS_PRESET", presetDefault))
+	if preset != CORSPresetDevelopment && preset != CORSPresetProduction {
+		return "", nil, false, fmt.Errorf("invalid CORS_PRESET '%s' (use development or production)", preset)
+	}
+	allowCredentials = getEnv("CORS_ALLOW_CREDENTIALS", "false") == "true"
+
+	origins = parseOrigins(getEnvOptional("ALLOWED_ORIGINS"))
+	if len(origins) == 0 {
+		if preset == CORSPresetProduction {
+			return "", nil, false, fmt.Errorf("ALLOWED_ORIGINS must list the browser origins allowed to call the API (e.g. https://app.example.com,https://*.example.com)")
+		}
+		origins = developmentOrigins
+	}
+
+	for _, origin := range origins {
+		if origin == "*" {
+			if len(origins) > 1 {
+				return "", nil, false, fmt.Errorf("ALLOWED_ORIGINS: '*' cannot be combined with other origins")
+			}
+			if preset == CORSPresetProduction {
+				return "", nil, false, fmt.Errorf("ALLOWED_ORIGINS: '*' is not allowed with the production CORS preset")
+			}
+			if allowCredentials {
+				return "", nil, false, fmt.Errorf("ALLOWED_ORIGINS: '*' cannot be used with CORS_ALLOW_CREDENTIALS (browsers reject it)")
+			}
+			continue
+		}
+		if err := validateOrigin(origin); err != nil {
+			return "", nil, false, fmt.Errorf("ALLOWED_ORIGINS: %w", err)
+		}
+	}
+	return preset, origins, allowCredentials, nil
+}
+
+// parseOrigins splits a comma- or space-separated origin list.
+func parseOrigins(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	origins := make([]string, 0, len(fields))
+	for _, field := range fields {
+		origins = append(origins, strings.TrimSuffix(field, "/"))
+	}
+	return origins
+}
+
+// validateOrigin accepts scheme://host[:port], where the host may start with a "*."
+// wildcard label to allow every subdomain.
+func validateOrigin(origin string) error {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid origin '%s' (expected http(s)://host[:port])", origin)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("invalid origin '%s': origins have no path, query or credentials", origin)
+	}
+	if strings.Count(origin, "*") > 1 || (strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+		return fmt.Errorf("invalid origin '%s': '*' is only allowed as the first host label (https://*.example.com)", origin)
+	}
+	if strings.Contains(origin, "://*.") && !strings.Contains(strings.TrimPrefix(u.Hostname(), "wildcard."), ".") {
+		return fmt.Errorf("invalid origin '%s': wildcard must be followed by a registrable domain", origin)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadCORS(t *testing.T) {
+	cases := []struct {
+		name, preset, origins, credentials string
+		want                               []string
+		wantErr                            string
+	}{
+		{name: "comma and space separated", preset: "production", origins: "https://app.example.com, https://*.example.com http://localhost:3000/",
+			want: []string{"https://app.example.com", "https://*.example.com", "http://localhost:3000"}},
+		{name: "development defaults", preset: "development", want: developmentOrigins},
+		{name: "production requires origins", preset: "production", wantErr: "ALLOWED_ORIGINS must list"},
+		{name: "wildcard in production", preset: "production", origins: "*", wantErr: "not allowed with the production"},
+		{name: "wildcard with credentials", preset: "development", origins: "*", credentials: "true", wantErr: "CORS_ALLOW_CREDENTIALS"},
+		{name: "path", preset: "development", origins: "https://example.com/app", wantErr: "no path"},
+		{name: "inner wildcard", preset: "development", origins: "https://api.*.example.com", wantErr: "first host label"},
+		{name: "bare wildcard domain", preset: "development", origins: "https://*.com", wantErr: "registrable domain"},
+		{name: "unknown preset", preset: "staging", wantErr: "CORS_PRESET"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CORS_PRESET", tc.preset)
+			t.Setenv("ALLOWED_ORIGINS", tc.origins)
+			t.Setenv("CORS_ALLOW_CREDENTIALS", tc.credentials)
+
+			_, origins, _, err := loadCORS()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil || strings.Join(origins, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("origins = %v, %v; want %v", origins, err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// config/data_roots.go
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// loadDataRoots reads where user database files are placed into cfg. Without
+// DATA_ROOTS they all go to DATABASE_DIRECTORY.
+func loadDataRoots(cfg *Config) error {
+	cfg.DataRoots = splitList(getEnvOptional("DATA_ROOTS"))
+	if len(cfg.DataRoots) == 0 {
+		cfg.DataRoots = []string{cfg.MetadataDbDir}
+	}
+	seen := make(map[string]bool, len(cfg.DataRoots))
+	for i, root := range cfg.DataRoots {
+		root = filepath.Clean(root)
+		if seen[root] {
+			return fmt.Errorf("DATA_ROOTS lists '%s' twice", root)
+		}
+		seen[root] = true
+		cfg.DataRoots[i] = root
+	}
+
+	cfg.DataPlacement = getEnv("DATA_PLACEMENT_STRATEGY", "most_free")
+	switch cfg.DataPlacement {
+	case "most_free", "hash":
+	default:
+		return fmt.Errorf("invalid DATA_PLACEMENT_STRATEGY '%s' (use most_free or hash)", cfg.DataPlacement)
+	}
+	return nil
+}
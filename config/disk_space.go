@@ -0,0 +1,27 @@
+// config/disk_space.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadDiskSpace reads how often free disk space is checked, and below how much writes
+// are rejected, into cfg.
+func loadDiskSpace(cfg *Config) {
+	intervalStr := getEnv("DISK_SPACE_CHECK_INTERVAL_SECONDS", "30")
+	interval, err := strconv.Atoi(intervalStr)
+	if err != nil || interval < 0 {
+		customLog.Warnf("Invalid DISK_SPACE_CHECK_INTERVAL_SECONDS '%s'. Using default 30. Error: %v", intervalStr, err)
+		interval = 30
+	}
+	cfg.DiskSpaceCheckInterval = time.Duration(interval) * time.Second
+
+	minFreeStr := getEnv("DISK_SPACE_MIN_FREE_MB", "512")
+	minFreeMB, err := strconv.Atoi(minFreeStr)
+	if err != nil || minFreeMB < 1 {
+		customLog.Warnf("Invalid DISK_SPACE_MIN_FREE_MB '%s'. Using default 512. Error: %v", minFreeStr, err)
+		minFreeMB = 512
+	}
+	cfg.DiskSpaceMinFree = uint64(minFreeMB) << 20
+}
@@ -0,0 +1,31 @@
+// config/ephemeral_databases.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadEphemeralDatabases reads the lifetimes of ephemeral databases into cfg. By
+// default they live a day, and at most a week.
+func loadEphemeralDatabases(cfg *Config) {
+	ttlStr := getEnv("EPHEMERAL_DATABASE_TTL_HOURS", "24")
+	ttl, err := strconv.Atoi(ttlStr)
+	if err != nil || ttl < 1 {
+		customLog.Warnf("Invalid EPHEMERAL_DATABASE_TTL_HOURS '%s'. Using default 24. Error: %v", ttlStr, err)
+		ttl = 24
+	}
+	cfg.EphemeralDatabaseTTL = time.Duration(ttl) * time.Hour
+
+	maxStr := getEnv("EPHEMERAL_DATABASE_MAX_TTL_HOURS", "168")
+	maxTTL, err := strconv.Atoi(maxStr)
+	if err != nil || maxTTL < 1 {
+		customLog.Warnf("Invalid EPHEMERAL_DATABASE_MAX_TTL_HOURS '%s'. Using default 168. Error: %v", maxStr, err)
+		maxTTL = 168
+	}
+	if maxTTL < ttl {
+		customLog.Warnf("EPHEMERAL_DATABASE_MAX_TTL_HOURS %d is below EPHEMERAL_DATABASE_TTL_HOURS %d. Using %d.", maxTTL, ttl, ttl)
+		maxTTL = ttl
+	}
+	cfg.EphemeralDatabaseMaxTTL = time.Duration(maxTTL) * time.Hour
+}
@@ -0,0 +1,26 @@
+// config/error_tracking.go
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// loadErrorTracking reads the settings of Sentry error tracking into cfg. The DSN is
+// parsed by internal/errortracking when the tracker is built.
+func loadErrorTracking(cfg *Config) {
+	cfg.SentryDSN = getEnvOptional("SENTRY_DSN")
+	cfg.SentryEnvironment = getEnvOptional("SENTRY_ENVIRONMENT")
+	if cfg.SentryEnvironment == "" {
+		cfg.SentryEnvironment = os.Getenv("APP_ENV")
+	}
+	cfg.SentryRelease = getEnvOptional("SENTRY_RELEASE")
+
+	rateStr := getEnv("SENTRY_SAMPLE_RATE", "1")
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		customLog.Warnf("Invalid SENTRY_SAMPLE_RATE '%s'. Using default 1. Error: %v", rateStr, err)
+		rate = 1
+	}
+	cfg.SentrySampleRate = rate
+}
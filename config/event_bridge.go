@@ -0,0 +1,44 @@
+// config/event_bridge.go
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// loadEventBridge reads and validates the settings of the Kafka/NATS event bridge into cfg.
+func loadEventBridge(cfg *Config) error {
+	cfg.EventBridge = strings.ToLower(getEnvOptional("EVENT_BRIDGE"))
+	if cfg.EventBridge == "none" {
+		cfg.EventBridge = ""
+	}
+	cfg.EventBridgeBrokers = splitList(getEnvOptional("EVENT_BRIDGE_BROKERS"))
+	cfg.EventBridgeTopic = getEnv("EVENT_BRIDGE_TOPIC", "nebula.changes")
+	cfg.EventBridgeTLS = getEnv("EVENT_BRIDGE_TLS", "false") == "true"
+	cfg.EventBridgeUsername = getEnvOptional("EVENT_BRIDGE_USERNAME")
+	cfg.EventBridgePassword = getEnvOptional("EVENT_BRIDGE_PASSWORD")
+
+	queueStr := getEnv("EVENT_BRIDGE_QUEUE_SIZE", "10000")
+	queueSize, err := strconv.Atoi(queueStr)
+	if err != nil || queueSize <= 0 {
+		customLog.Warnf("Invalid EVENT_BRIDGE_QUEUE_SIZE '%s'. Using default 10000. Error: %v", queueStr, err)
+		queueSize = 10000
+	}
+	cfg.EventBridgeQueueSize = queueSize
+
+	switch cfg.EventBridge {
+	case "":
+		return nil
+	case "kafka", "nats":
+	default:
+		return fmt.Errorf("invalid EVENT_BRIDGE '%s' (use none, kafka or nats)", cfg.EventBridge)
+	}
+	if len(cfg.EventBridgeBrokers) == 0 {
+		return fmt.Errorf("EVENT_BRIDGE_BROKERS must be set when EVENT_BRIDGE is %s", cfg.EventBridge)
+	}
+	if cfg.EventBridgeTopic == "" || strings.ContainsAny(cfg.EventBridgeTopic, " \t\r\n") {
+		return fmt.Errorf("invalid EVENT_BRIDGE_TOPIC '%s' (must be non-empty and contain no whitespace)", cfg.EventBridgeTopic)
+	}
+	return nil
+}
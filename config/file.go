@@ -0,0 +1,104 @@
+// config/file.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile loads a YAML or TOML config file and exports its settings as
+// environment variables that are not already set, so the environment (and .env)
+// always overrides the file.
+//
+// Keys are the environment variable names, case-insensitive, and nested tables are
+// joined with "_": `access_log: {max_size_mb: 50}` sets ACCESS_LOG_MAX_SIZE_MB.
+// Lists become comma-separated values.
+func applyConfigFile(path string) error {
+	values, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	applied := 0
+	for _, key := range keys {
+		if _, exists := os.LookupEnv(key); exists {
+			customLog.Debugf("Config: %s from the environment overrides %s", key, path)
+			continue
+		}
+		if err := os.Setenv(key, values[key]); err != nil {
+			return fmt.Errorf("failed to apply %s from config file: %w", key, err)
+		}
+		applied++
+	}
+	customLog.Printf("Config: Applied %d setting(s) from %s", applied, path)
+	return nil
+}
+
+// readConfigFile parses path by extension and flattens it into env-style keys.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	doc := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("unsupported config file type '%s' (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(prefix string, value any, out map[string]string) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			name := strings.ToUpper(key)
+			if prefix != "" {
+				name = prefix + "_" + name
+			}
+			if err := flatten(name, child, out); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				return fmt.Errorf("%s: lists may only contain plain values", prefix)
+			}
+			items[i] = fmt.Sprint(item)
+		}
+		out[prefix] = strings.Join(items, ",")
+	case nil:
+		// An empty value leaves the setting at its default
+	default:
+		out[prefix] = fmt.Sprint(v)
+	}
+	return nil
+}
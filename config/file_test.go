@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"nebula.yaml": "jwt_secret: from-file\naccess_log:\n  max_size_mb: 50\n  redact_fields: [password, token]\n",
+		"nebula.toml": "jwt_secret = \"from-file\"\n[access_log]\nmax_size_mb = 50\nredact_fields = [\"password\", \"token\"]\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			// The environment wins over the file
+			t.Setenv("JWT_SECRET", "from-env")
+			t.Setenv("ACCESS_LOG_MAX_SIZE_MB", "")
+			os.Unsetenv("ACCESS_LOG_MAX_SIZE_MB")
+			t.Setenv("ACCESS_LOG_REDACT_FIELDS", "")
+			os.Unsetenv("ACCESS_LOG_REDACT_FIELDS")
+
+			if err := applyConfigFile(path); err != nil {
+				t.Fatalf("applyConfigFile: %v", err)
+			}
+			want := map[string]string{"JWT_SECRET": "from-env", "ACCESS_LOG_MAX_SIZE_MB": "50", "ACCESS_LOG_REDACT_FIELDS": "password,token"}
+			for key, value := range want {
+				if got := os.Getenv(key); got != value {
+					t.Errorf("%s = %q, want %q", key, got, value)
+				}
+			}
+		})
+	}
+}
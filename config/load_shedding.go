@@ -0,0 +1,24 @@
+// config/load_shedding.go
+package config
+
+import "strconv"
+
+// loadLoadShedding reads how many requests may be in flight before new ones are shed
+// into cfg. Shedding is off by default.
+func loadLoadShedding(cfg *Config) {
+	maxStr := getEnv("MAX_IN_FLIGHT_REQUESTS", "0")
+	maxInFlight, err := strconv.Atoi(maxStr)
+	if err != nil || maxInFlight < 0 {
+		customLog.Warnf("Invalid MAX_IN_FLIGHT_REQUESTS '%s'. Using default 0 (disabled). Error: %v", maxStr, err)
+		maxInFlight = 0
+	}
+	cfg.MaxInFlightRequests = maxInFlight
+
+	perTenantStr := getEnv("MAX_IN_FLIGHT_PER_TENANT", "0")
+	perTenant, err := strconv.Atoi(perTenantStr)
+	if err != nil || perTenant < 0 {
+		customLog.Warnf("Invalid MAX_IN_FLIGHT_PER_TENANT '%s'. Using default 0 (a quarter of MAX_IN_FLIGHT_REQUESTS). Error: %v", perTenantStr, err)
+		perTenant = 0
+	}
+	cfg.MaxInFlightPerTenant = perTenant
+}
@@ -0,0 +1,77 @@
+// config/mail.go
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadMail reads and validates the transactional email settings into cfg.
+func loadMail(cfg *Config) error {
+	cfg.MailProvider = strings.ToLower(getEnvOptional("MAIL_PROVIDER"))
+	if cfg.MailProvider == "none" {
+		cfg.MailProvider = ""
+	}
+	cfg.MailFrom = getEnvOptional("MAIL_FROM")
+	cfg.MailSMTPHost = getEnvOptional("MAIL_SMTP_HOST")
+	cfg.MailSMTPPort = getEnv("MAIL_SMTP_PORT", "587")
+	cfg.MailSMTPUsername = getEnvOptional("MAIL_SMTP_USERNAME")
+	cfg.MailSMTPPassword = getEnvOptional("MAIL_SMTP_PASSWORD")
+	cfg.MailSMTPTLS = strings.ToLower(getEnv("MAIL_SMTP_TLS", "starttls"))
+	cfg.MailSESRegion = getEnv("MAIL_SES_REGION", getEnv("AWS_REGION", "us-east-1"))
+	// SES falls back to the standard AWS credentials shared with the secrets resolver
+	cfg.MailSESAccessKeyID = getEnvOptional("MAIL_SES_ACCESS_KEY_ID")
+	cfg.MailSESSecretAccessKey = getEnvOptional("MAIL_SES_SECRET_ACCESS_KEY")
+	if cfg.MailSESAccessKeyID == "" && cfg.MailSESSecretAccessKey == "" {
+		cfg.MailSESAccessKeyID = getEnvOptional("AWS_ACCESS_KEY_ID")
+		cfg.MailSESSecretAccessKey = getEnvOptional("AWS_SECRET_ACCESS_KEY")
+		cfg.MailSESSessionToken = getEnvOptional("AWS_SESSION_TOKEN")
+	}
+	cfg.MailSendGridAPIKey = getEnvOptional("MAIL_SENDGRID_API_KEY")
+
+	maxAttemptsStr := getEnv("MAIL_MAX_ATTEMPTS", "8")
+	maxAttempts, err := strconv.Atoi(maxAttemptsStr)
+	if err != nil || maxAttempts <= 0 {
+		customLog.Warnf("Invalid MAIL_MAX_ATTEMPTS '%s'. Using default 8. Error: %v", maxAttemptsStr, err)
+		maxAttempts = 8
+	}
+	cfg.MailMaxAttempts = maxAttempts
+
+	pollStr := getEnv("MAIL_POLL_INTERVAL_SECONDS", "5")
+	pollSeconds, err := strconv.Atoi(pollStr)
+	if err != nil || pollSeconds <= 0 {
+		customLog.Warnf("Invalid MAIL_POLL_INTERVAL_SECONDS '%s'. Using default 5. Error: %v", pollStr, err)
+		pollSeconds = 5
+	}
+	cfg.MailPollInterval = time.Second * time.Duration(pollSeconds)
+
+	switch cfg.MailProvider {
+	case "", "log":
+		return nil
+	case "smtp":
+		if cfg.MailSMTPHost == "" {
+			return fmt.Errorf("MAIL_SMTP_HOST must be set when MAIL_PROVIDER is smtp")
+		}
+		if cfg.MailSMTPTLS != "starttls" && cfg.MailSMTPTLS != "implicit" && cfg.MailSMTPTLS != "none" {
+			return fmt.Errorf("invalid MAIL_SMTP_TLS '%s' (use starttls, implicit or none)", cfg.MailSMTPTLS)
+		}
+	case "ses":
+		if cfg.MailSESAccessKeyID == "" || cfg.MailSESSecretAccessKey == "" {
+			return fmt.Errorf("MAIL_SES_ACCESS_KEY_ID and MAIL_SES_SECRET_ACCESS_KEY (or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY) must be set when MAIL_PROVIDER is ses")
+		}
+	case "sendgrid":
+		if cfg.MailSendGridAPIKey == "" {
+			return fmt.Errorf("MAIL_SENDGRID_API_KEY must be set when MAIL_PROVIDER is sendgrid")
+		}
+	default:
+		return fmt.Errorf("invalid MAIL_PROVIDER '%s' (use none, log, smtp, ses or sendgrid)", cfg.MailProvider)
+	}
+
+	if _, err := mail.ParseAddress(cfg.MailFrom); err != nil {
+		return fmt.Errorf("MAIL_FROM must be a valid sender address when MAIL_PROVIDER is %s: %w", cfg.MailProvider, err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// config/metadata_breaker.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadMetadataBreaker reads when the circuit breaker around the metadata database
+// opens, and for how long, into cfg.
+func loadMetadataBreaker(cfg *Config) {
+	failuresStr := getEnv("METADATA_BREAKER_FAILURES", "5")
+	failures, err := strconv.Atoi(failuresStr)
+	if err != nil || failures < 0 {
+		customLog.Warnf("Invalid METADATA_BREAKER_FAILURES '%s'. Using default 5. Error: %v", failuresStr, err)
+		failures = 5
+	}
+	cfg.MetadataBreakerFailures = failures
+
+	cooldownStr := getEnv("METADATA_BREAKER_COOLDOWN_SECONDS", "10")
+	cooldownSeconds, err := strconv.Atoi(cooldownStr)
+	if err != nil || cooldownSeconds < 1 {
+		customLog.Warnf("Invalid METADATA_BREAKER_COOLDOWN_SECONDS '%s'. Using default 10. Error: %v", cooldownStr, err)
+		cooldownSeconds = 10
+	}
+	cfg.MetadataBreakerCooldown = time.Duration(cooldownSeconds) * time.Second
+}
@@ -0,0 +1,29 @@
+// config/orphan_files.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadOrphanFiles reads how database files with no registered database are collected
+// into cfg. By default they are reported hourly and kept.
+func loadOrphanFiles(cfg *Config) {
+	intervalStr := getEnv("ORPHAN_FILE_GC_INTERVAL_MINUTES", "60")
+	interval, err := strconv.Atoi(intervalStr)
+	if err != nil || interval < 0 {
+		customLog.Warnf("Invalid ORPHAN_FILE_GC_INTERVAL_MINUTES '%s'. Using default 60. Error: %v", intervalStr, err)
+		interval = 60
+	}
+	cfg.OrphanFileGCInterval = time.Duration(interval) * time.Minute
+
+	graceStr := getEnv("ORPHAN_FILE_GRACE_PERIOD_HOURS", "24")
+	grace, err := strconv.Atoi(graceStr)
+	if err != nil || grace < 1 {
+		customLog.Warnf("Invalid ORPHAN_FILE_GRACE_PERIOD_HOURS '%s'. Using default 24. Error: %v", graceStr, err)
+		grace = 24
+	}
+	cfg.OrphanFileGracePeriod = time.Duration(grace) * time.Hour
+
+	cfg.OrphanFileGCRemove = getEnv("ORPHAN_FILE_GC_REMOVE", "false") == "true"
+}
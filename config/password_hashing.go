@@ -0,0 +1,63 @@
+// config/password_hashing.go
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MinPepperLength is the shortest password pepper accepted.
+const MinPepperLength = 32
+
+// loadPasswordHashing reads how new password hashes are computed into cfg: bcrypt at
+// cost 10 by default, or Argon2id, optionally with a pepper. An unknown algorithm or a
+// short pepper is an error rather than a silent fallback to a weaker setting.
+func loadPasswordHashing(cfg *Config) error {
+	cfg.PasswordHash = strings.ToLower(getEnv("PASSWORD_HASH", "bcrypt"))
+	if cfg.PasswordHash != "bcrypt" && cfg.PasswordHash != "argon2id" {
+		return fmt.Errorf("invalid PASSWORD_HASH '%s' (use bcrypt or argon2id)", cfg.PasswordHash)
+	}
+
+	costStr := getEnv("BCRYPT_COST", "10")
+	cost, err := strconv.Atoi(costStr)
+	if err != nil || cost < 4 || cost > 31 {
+		customLog.Warnf("Invalid BCRYPT_COST '%s' (must be 4-31). Using default 10. Error: %v", costStr, err)
+		cost = 10
+	}
+	cfg.BcryptCost = cost
+
+	memoryStr := getEnv("ARGON2_MEMORY_KIB", "65536")
+	memory, err := strconv.ParseUint(memoryStr, 10, 32)
+	if err != nil || memory < 1024 {
+		customLog.Warnf("Invalid ARGON2_MEMORY_KIB '%s' (must be at least 1024). Using default 65536. Error: %v", memoryStr, err)
+		memory = 65536
+	}
+	cfg.Argon2Memory = uint32(memory)
+
+	iterationsStr := getEnv("ARGON2_ITERATIONS", "3")
+	iterations, err := strconv.ParseUint(iterationsStr, 10, 32)
+	if err != nil || iterations < 1 {
+		customLog.Warnf("Invalid ARGON2_ITERATIONS '%s'. Using default 3. Error: %v", iterationsStr, err)
+		iterations = 3
+	}
+	cfg.Argon2Iterations = uint32(iterations)
+
+	parallelismStr := getEnv("ARGON2_PARALLELISM", "2")
+	parallelism, err := strconv.ParseUint(parallelismStr, 10, 8)
+	if err != nil || parallelism < 1 {
+		customLog.Warnf("Invalid ARGON2_PARALLELISM '%s' (must be 1-255). Using default 2. Error: %v", parallelismStr, err)
+		parallelism = 2
+	}
+	cfg.Argon2Parallelism = uint8(parallelism)
+
+	// Peppers are secrets: source them from a secrets manager rather than a plain value
+	cfg.PasswordPepper = getEnvOptional("PASSWORD_PEPPER")
+	cfg.PasswordPepperPrevious = splitList(getEnvOptional("PASSWORD_PEPPER_PREVIOUS"))
+	for _, pepper := range append([]string{cfg.PasswordPepper}, cfg.PasswordPepperPrevious...) {
+		if pepper != "" && len(pepper) < MinPepperLength {
+			return fmt.Errorf("PASSWORD_PEPPER and PASSWORD_PEPPER_PREVIOUS values must be at least %d characters", MinPepperLength)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+// config/request_signing.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadRequestSigning reads how far the timestamp of a signed request may be from the
+// server's clock, and how large a body may be read to verify its signature, into cfg.
+// By default requests are accepted for 5 minutes with bodies of up to 32 MiB.
+func loadRequestSigning(cfg *Config) {
+	skewStr := getEnv("REQUEST_SIGNING_MAX_SKEW_SECONDS", "300")
+	skew, err := strconv.Atoi(skewStr)
+	if err != nil || skew < 1 {
+		customLog.Warnf("Invalid REQUEST_SIGNING_MAX_SKEW_SECONDS '%s'. Using default 300. Error: %v", skewStr, err)
+		skew = 300
+	}
+	cfg.RequestSigningMaxSkew = time.Duration(skew) * time.Second
+
+	bodyStr := getEnv("REQUEST_SIGNING_MAX_BODY_MB", "32")
+	bodyMB, err := strconv.Atoi(bodyStr)
+	if err != nil || bodyMB < 1 {
+		customLog.Warnf("Invalid REQUEST_SIGNING_MAX_BODY_MB '%s'. Using default 32. Error: %v", bodyStr, err)
+		bodyMB = 32
+	}
+	cfg.RequestSigningMaxBodySize = int64(bodyMB) << 20
+}
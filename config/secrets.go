@@ -0,0 +1,140 @@
+// config/secrets.go
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/secrets"
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+// newSecretResolver configures the secrets manager clients from their usual variables.
+func newSecretResolver() *secrets.Resolver {
+	region := getEnvOptional("AWS_REGION")
+	if region == "" {
+		region = getEnvOptional("AWS_DEFAULT_REGION")
+	}
+	return &secrets.Resolver{
+		VaultAddr:      getEnvOptional("VAULT_ADDR"),
+		VaultToken:     getEnvOptional("VAULT_TOKEN"),
+		VaultNamespace: getEnvOptional("VAULT_NAMESPACE"),
+		AWSRegion:      region,
+		AWSCredentials: sigv4.Credentials{
+			AccessKeyID:     getEnvOptional("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getEnvOptional("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getEnvOptional("AWS_SESSION_TOKEN"),
+		},
+		AWSEndpoint:    getEnvOptional("AWS_SECRETS_MANAGER_ENDPOINT"),
+		GCPAccessToken: getEnvOptional("GCP_ACCESS_TOKEN"),
+	}
+}
+
+// resolveSecretReferences replaces every environment variable holding a secrets manager
+// reference (vault://, aws-sm://, gcp-sm://) with the secret itself, so the rest of
+// LoadConfig reads plain values. It returns the references and resolved values by key.
+func resolveSecretReferences(resolver *secrets.Resolver) (refs, values map[string]string, err error) {
+	refs = make(map[string]string)
+	for _, entry := range os.Environ() {
+		key, value, _ := strings.Cut(entry, "=")
+		if secrets.IsReference(value) {
+			refs[key] = value
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, 0, len(refs))
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	values = make(map[string]string, len(refs))
+	for _, key := range keys {
+		value, err := resolver.Resolve(ctx, refs[key])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load %s from secrets manager: %w", key, err)
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return nil, nil, fmt.Errorf("failed to apply %s: %w", key, err)
+		}
+		values[key] = value
+	}
+	customLog.Printf("Config: Loaded %d setting(s) from secrets managers: %s", len(keys), strings.Join(keys, ", "))
+	return refs, values, nil
+}
+
+// WatchSecrets re-reads settings sourced from secrets managers every
+// SecretsRefreshInterval until ctx is done. A rotated JWT_SECRET takes effect
+// immediately; every other rotated setting is passed to onChange. Returns at once when
+// nothing comes from a secrets manager or refreshing is disabled.
+func (c *Config) WatchSecrets(ctx context.Context, onChange func(key, value string)) {
+	if len(c.secretRefs) == 0 || c.SecretsRefreshInterval <= 0 {
+		return
+	}
+	c.secretResolver.Watch(ctx, c.secretRefs, c.secretValues, c.SecretsRefreshInterval, func(key, value string) {
+		if key == "JWT_SECRET" {
+			c.RotateJWTSecret(value)
+			return
+		}
+		onChange(key, value)
+	})
+}
+
+// jwtSecretRing holds the JWT secrets once they can rotate at runtime.
+type jwtSecretRing struct {
+	mu            sync.RWMutex
+	current       string
+	previous      string
+	previousUntil time.Time // Tokens signed with previous have all expired by then
+}
+
+// JWTSigningSecret returns the secret new tokens are signed with.
+func (c *Config) JWTSigningSecret() string {
+	if c.jwtSecrets == nil {
+		return c.JWTSecret
+	}
+	c.jwtSecrets.mu.RLock()
+	defer c.jwtSecrets.mu.RUnlock()
+	return c.jwtSecrets.current
+}
+
+// JWTVerificationSecrets returns the secrets tokens are accepted with: the current one
+// and, until tokens issued before the last rotation have expired, the previous one.
+func (c *Config) JWTVerificationSecrets() []string {
+	if c.jwtSecrets == nil {
+		return []string{c.JWTSecret}
+	}
+	c.jwtSecrets.mu.RLock()
+	defer c.jwtSecrets.mu.RUnlock()
+	if c.jwtSecrets.previous != "" && time.Now().Before(c.jwtSecrets.previousUntil) {
+		return []string{c.jwtSecrets.current, c.jwtSecrets.previous}
+	}
+	return []string{c.jwtSecrets.current}
+}
+
+// RotateJWTSecret signs new tokens with secret while tokens signed with the old secret
+// stay valid for the rest of their lifetime. Safe for concurrent use on a Config
+// returned by LoadConfig.
+func (c *Config) RotateJWTSecret(secret string) {
+	if c.jwtSecrets == nil {
+		c.jwtSecrets = &jwtSecretRing{current: c.JWTSecret}
+	}
+	c.jwtSecrets.mu.Lock()
+	defer c.jwtSecrets.mu.Unlock()
+	if secret == c.jwtSecrets.current {
+		return
+	}
+	c.jwtSecrets.previous = c.jwtSecrets.current
+	c.jwtSecrets.previousUntil = time.Now().Add(c.JWTExpiration)
+	c.jwtSecrets.current = secret
+}
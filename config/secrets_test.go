@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/auth"
+)
+
+func TestRotateJWTSecret(t *testing.T) {
+	cfg := &Config{JWTSecret: "old-secret", JWTExpiration: time.Hour}
+	token, err := auth.GenerateJWT("u1", cfg.JWTSigningSecret(), cfg.JWTExpiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.RotateJWTSecret("new-secret")
+	if got := cfg.JWTSigningSecret(); got != "new-secret" {
+		t.Fatalf("JWTSigningSecret = %q after rotation", got)
+	}
+	// Tokens issued before the rotation stay valid until they expire
+	if userID, err := auth.ValidateJWT(token, cfg.JWTVerificationSecrets()...); err != nil || userID != "u1" {
+		t.Fatalf("old token after rotation = %q, %v", userID, err)
+	}
+
+	cfg.jwtSecrets.previousUntil = time.Now()
+	if _, err := auth.ValidateJWT(token, cfg.JWTVerificationSecrets()...); err == nil {
+		t.Fatal("old token still accepted after its lifetime")
+	}
+}
@@ -0,0 +1,27 @@
+// config/slow_queries.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadSlowQueries reads the capture of slow statements per database into cfg. By
+// default statements taking 200 ms or more are captured, and the 20 slowest kept.
+func loadSlowQueries(cfg *Config) {
+	thresholdStr := getEnv("SLOW_QUERY_THRESHOLD_MS", "200")
+	threshold, err := strconv.Atoi(thresholdStr)
+	if err != nil || threshold < 0 {
+		customLog.Warnf("Invalid SLOW_QUERY_THRESHOLD_MS '%s'. Using default 200. Error: %v", thresholdStr, err)
+		threshold = 200
+	}
+	cfg.SlowQueryThreshold = time.Duration(threshold) * time.Millisecond
+
+	keepStr := getEnv("SLOW_QUERIES_PER_DATABASE", "20")
+	keep, err := strconv.Atoi(keepStr)
+	if err != nil || keep < 0 {
+		customLog.Warnf("Invalid SLOW_QUERIES_PER_DATABASE '%s'. Using default 20. Error: %v", keepStr, err)
+		keep = 20
+	}
+	cfg.SlowQueriesPerDatabase = keep
+}
@@ -0,0 +1,22 @@
+// config/startup_checks.go
+package config
+
+// Startup check modes (STARTUP_CHECKS).
+const (
+	StartupChecksOff    = "off"
+	StartupChecksWarn   = "warn"   // Problems are logged and the server starts anyway
+	StartupChecksStrict = "strict" // The server refuses to start on errors
+)
+
+// loadStartupChecks reads whether the doctor checks run before the server starts into
+// cfg. By default problems are only logged.
+func loadStartupChecks(cfg *Config) {
+	mode := getEnv("STARTUP_CHECKS", StartupChecksWarn)
+	switch mode {
+	case StartupChecksOff, StartupChecksWarn, StartupChecksStrict:
+	default:
+		customLog.Warnf("Invalid STARTUP_CHECKS '%s'. Using default %s (use off, warn or strict).", mode, StartupChecksWarn)
+		mode = StartupChecksWarn
+	}
+	cfg.StartupChecks = mode
+}
@@ -0,0 +1,28 @@
+// config/webhooks.go
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// loadWebhooks reads the delivery settings of database webhooks into cfg.
+func loadWebhooks(cfg *Config) {
+	timeoutStr := getEnv("WEBHOOK_TIMEOUT_SECONDS", "10")
+	timeoutSeconds, err := strconv.Atoi(timeoutStr)
+	if err != nil || timeoutSeconds <= 0 {
+		customLog.Warnf("Invalid WEBHOOK_TIMEOUT_SECONDS '%s'. Using default 10. Error: %v", timeoutStr, err)
+		timeoutSeconds = 10
+	}
+	cfg.WebhookTimeout = time.Second * time.Duration(timeoutSeconds)
+
+	attemptsStr := getEnv("WEBHOOK_MAX_ATTEMPTS", "3")
+	attempts, err := strconv.Atoi(attemptsStr)
+	if err != nil || attempts <= 0 {
+		customLog.Warnf("Invalid WEBHOOK_MAX_ATTEMPTS '%s'. Using default 3. Error: %v", attemptsStr, err)
+		attempts = 3
+	}
+	cfg.WebhookMaxAttempts = attempts
+
+	cfg.WebhookAllowPrivateHosts = getEnv("WEBHOOK_ALLOW_PRIVATE_HOSTS", "false") == "true"
+}
@@ -0,0 +1,376 @@
+// internal/anomaly/anomaly.go
+
+// Package anomaly detects unusual authentication activity: bursts of failed logins
+// for an account or from a client IP, API keys used from a country they were not used
+// from before, and deleted (rotated) API keys that are still being presented. It also
+// alerts when the server runs low on disk space. Each finding is recorded in the audit
+// log, emailed to the account owner when email is configured and there is one, and
+// POSTed to the alert webhook when one is set.
+package anomaly
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/mail"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var customLog = logger.NewLogger()
+
+// Audit log events of the alerts.
+const (
+	EventFailedLoginSpike  = "auth.failed_login_spike"
+	EventAPIKeyNewCountry  = "auth.api_key_new_country"
+	EventRevokedAPIKeyUsed = "auth.revoked_api_key_used"
+	EventLowDiskSpace      = "system.low_disk_space"
+)
+
+const (
+	alertCooldown  = time.Hour // The same alert is raised at most this often
+	maxTracked     = 10000     // Bound of each in-memory table
+	webhookTimeout = 10 * time.Second
+)
+
+// Store is the part of the metadata store the detector needs (storage.MetadataStore
+// satisfies it).
+type Store interface {
+	mail.Store
+	FindUserByUserId(ctx context.Context, userId string) (*domain.UserMetadata, error)
+	AddAuditEvent(ctx context.Context, event domain.AuditEvent) (int64, error)
+	RecordAPIKeyCountry(ctx context.Context, key, country string) (bool, error)
+	FindRevokedAPIKey(ctx context.Context, key string) (int64, string, time.Time, error)
+}
+
+// Options configure the detector.
+type Options struct {
+	FailedLogins      int // Failed logins within FailedLoginWindow that raise an alert; 0 disables
+	FailedLoginWindow time.Duration
+	CountryHeader     string // Request header holding the client's country; "" disables new-country alerts
+	WebhookURL        string // Alerts are also POSTed here as JSON when set
+	Email             bool   // Email alerts to the account owner (a mail provider is configured)
+}
+
+// Alert is a detected anomaly, as sent to the webhook.
+type Alert struct {
+	Event   string            `json:"event"`
+	UserID  string            `json:"user_id,omitempty"`
+	IP      string            `json:"ip,omitempty"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details"`
+	Time    time.Time         `json:"time"`
+}
+
+// Detector keeps the recent authentication activity anomalies are detected from.
+type Detector struct {
+	store   Store
+	opts    Options
+	client  *http.Client
+	now     func() time.Time
+	mu      sync.Mutex
+	failed  map[string][]time.Time // "email:<address>" or "ip:<address>" -> recent failures
+	raised  map[string]time.Time   // Alert key -> last raised
+	checked map[string]bool        // Key hash + country already recorded
+}
+
+// NewDetector returns a detector raising alerts through store.
+func NewDetector(store Store, opts Options) *Detector {
+	return &Detector{
+		store:   store,
+		opts:    opts,
+		client:  &http.Client{Timeout: webhookTimeout},
+		now:     time.Now,
+		failed:  make(map[string][]time.Time),
+		raised:  make(map[string]time.Time),
+		checked: make(map[string]bool),
+	}
+}
+
+var (
+	mu       sync.RWMutex
+	detector *Detector // nil until Configure; every report is then ignored
+)
+
+// Configure enables anomaly detection for the process.
+func Configure(store Store, opts Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	detector = NewDetector(store, opts)
+}
+
+func current() *Detector {
+	mu.RLock()
+	defer mu.RUnlock()
+	return detector
+}
+
+// Country returns the client's country from the configured header, read with header,
+// or "" when new-country alerts are disabled or the header is missing.
+func Country(header func(name string) string) string {
+	d := current()
+	if d == nil || d.opts.CountryHeader == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(header(d.opts.CountryHeader)))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' || country == "XX" {
+		return "" // Not an ISO 3166 code, or Cloudflare's "unknown"
+	}
+	return country
+}
+
+// LoginFailed reports a failed login for email from ip; userId is the account the
+// email belongs to, or "" when there is none.
+func LoginFailed(ctx context.Context, email, userId, ip string) {
+	if d := current(); d != nil {
+		d.LoginFailed(ctx, email, userId, ip)
+	}
+}
+
+// APIKeyUsed reports an authenticated request with an API key of userId's database.
+func APIKeyUsed(ctx context.Context, key, userId string, databaseId int64, country, ip string) {
+	if d := current(); d != nil {
+		d.APIKeyUsed(ctx, key, userId, databaseId, country, ip)
+	}
+}
+
+// UnknownAPIKey reports a request with an API key that does not exist.
+func UnknownAPIKey(ctx context.Context, key, ip string) {
+	if d := current(); d != nil {
+		d.UnknownAPIKey(ctx, key, ip)
+	}
+}
+
+// LowDiskSpace raises an alert for a volume that fell below the minimum free space
+// (see storage.RunDiskSpaceMonitor).
+func LowDiskSpace(ctx context.Context, volume storage.DiskSpace) {
+	if d := current(); d != nil {
+		d.LowDiskSpace(ctx, volume)
+	}
+}
+
+// LoginFailed counts a failed login against the account and the client IP, raising an
+// alert for either once FailedLogins failures fall within FailedLoginWindow.
+func (d *Detector) LoginFailed(ctx context.Context, email, userId, ip string) {
+	if d.opts.FailedLogins <= 0 {
+		return
+	}
+	window := d.opts.FailedLoginWindow.Round(time.Minute).String()
+	if userId != "" && d.countFailure("email:"+strings.ToLower(email)) {
+		d.dispatch(ctx, "login:"+userId, Alert{
+			Event:   EventFailedLoginSpike,
+			UserID:  userId,
+			IP:      ip,
+			Title:   "Repeated failed sign-in attempts",
+			Message: fmt.Sprintf("There were %d failed sign-in attempts for your account within %s. If this was not you, change your password.", d.opts.FailedLogins, window),
+			Details: map[string]string{"email": email, "last_ip": ip},
+		})
+	}
+	if ip != "" && d.countFailure("ip:"+ip) {
+		d.dispatch(ctx, "login-ip:"+ip, Alert{
+			Event:   EventFailedLoginSpike,
+			IP:      ip,
+			Title:   "Repeated failed sign-in attempts from one address",
+			Message: fmt.Sprintf("%s made %d failed sign-in attempts within %s.", ip, d.opts.FailedLogins, window),
+			Details: map[string]string{"last_email": email},
+		})
+	}
+}
+
+// countFailure records a failure for key and reports whether the failures within the
+// window reached the threshold.
+func (d *Detector) countFailure(key string) bool {
+	now := d.now()
+	cutoff := now.Add(-d.opts.FailedLoginWindow)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.failed) >= maxTracked {
+		for k, times := range d.failed {
+			if !times[len(times)-1].After(cutoff) {
+				delete(d.failed, k)
+			}
+		}
+	}
+	recent := d.failed[key][:0]
+	for _, t := range d.failed[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) > d.opts.FailedLogins {
+		recent = recent[len(recent)-d.opts.FailedLogins:]
+	}
+	d.failed[key] = recent
+	return len(recent) >= d.opts.FailedLogins
+}
+
+// APIKeyUsed records the country the key was used from, raising an alert when the key
+// had only been used from other countries before.
+func (d *Detector) APIKeyUsed(ctx context.Context, key, userId string, databaseId int64, country, ip string) {
+	if country == "" {
+		return
+	}
+	hash := keyHash(key)
+	d.mu.Lock()
+	if d.checked[hash+":"+country] {
+		d.mu.Unlock()
+		return
+	}
+	if len(d.checked) >= maxTracked {
+		clear(d.checked)
+	}
+	d.checked[hash+":"+country] = true
+	d.mu.Unlock()
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		isNew, err := d.store.RecordAPIKeyCountry(ctx, key, country)
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Anomaly: Failed to record API key country: %v", err)
+			d.mu.Lock()
+			delete(d.checked, hash+":"+country) // Retried on the next request
+			d.mu.Unlock()
+			return
+		}
+		if isNew {
+			d.raise(ctx, "country:"+hash+":"+country, Alert{
+				Event:   EventAPIKeyNewCountry,
+				UserID:  userId,
+				IP:      ip,
+				Title:   "API key used from a new country",
+				Message: fmt.Sprintf("An API key of your account was used from %s for the first time.", country),
+				Details: map[string]string{"country": country, "database_id": fmt.Sprint(databaseId), "key_prefix": keyPrefix(key)},
+			})
+		}
+	}()
+}
+
+// UnknownAPIKey raises an alert when key is a deleted API key, which usually means a
+// client was not updated after a key rotation, or the old key leaked.
+func (d *Detector) UnknownAPIKey(ctx context.Context, key, ip string) {
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		databaseId, userId, revokedAt, err := d.store.FindRevokedAPIKey(ctx, key)
+		if err != nil {
+			if !errors.Is(err, storage.ErrAPIKeyNotFound) {
+				customLog.Ctx(ctx).Warnf("Anomaly: Failed to look up revoked API key: %v", err)
+			}
+			return
+		}
+		d.raise(ctx, "revoked:"+keyHash(key), Alert{
+			Event:   EventRevokedAPIKeyUsed,
+			UserID:  userId,
+			IP:      ip,
+			Title:   "Deleted API key still in use",
+			Message: "A request was made with an API key of your account that was deleted. Update the clients still using it; if the key leaked, review recent activity.",
+			Details: map[string]string{"database_id": fmt.Sprint(databaseId), "key_prefix": keyPrefix(key), "revoked_at": revokedAt.UTC().Format(time.RFC3339)},
+		})
+	}()
+}
+
+// LowDiskSpace alerts that writes are rejected until space is freed on volume.
+func (d *Detector) LowDiskSpace(ctx context.Context, volume storage.DiskSpace) {
+	d.dispatch(ctx, "disk:"+volume.Path, Alert{
+		Event:   EventLowDiskSpace,
+		Title:   "Server low on disk space",
+		Message: fmt.Sprintf("'%s' has %d MB free. Writes are rejected until space is freed.", volume.Path, volume.FreeBytes>>20),
+		Details: map[string]string{"path": volume.Path, "free_bytes": fmt.Sprint(volume.FreeBytes), "total_bytes": fmt.Sprint(volume.TotalBytes)},
+	})
+}
+
+// dispatch raises an alert without holding up the request that caused it.
+func (d *Detector) dispatch(ctx context.Context, key string, alert Alert) {
+	go d.raise(context.WithoutCancel(ctx), key, alert)
+}
+
+// raise records the alert in the audit log, emails the account owner and calls the
+// webhook, unless the alert with the same key was raised within the cooldown.
+func (d *Detector) raise(ctx context.Context, key string, alert Alert) {
+	alert.Time = d.now().UTC()
+	d.mu.Lock()
+	if last, ok := d.raised[key]; ok && alert.Time.Sub(last) < alertCooldown {
+		d.mu.Unlock()
+		return
+	}
+	if len(d.raised) >= maxTracked {
+		for k, last := range d.raised {
+			if alert.Time.Sub(last) >= alertCooldown {
+				delete(d.raised, k)
+			}
+		}
+	}
+	d.raised[key] = alert.Time
+	d.mu.Unlock()
+
+	customLog.Ctx(ctx).Warnf("Anomaly: %s (user %q, ip %q): %s", alert.Event, alert.UserID, alert.IP, alert.Message)
+	if _, err := d.store.AddAuditEvent(ctx, domain.AuditEvent{UserID: alert.UserID, Event: alert.Event, IP: alert.IP, Details: alert.Details}); err != nil {
+		customLog.Ctx(ctx).Warnf("Anomaly: Failed to add audit event: %v", err)
+	}
+
+	if d.opts.Email && alert.UserID != "" {
+		if user, err := d.store.FindUserByUserId(ctx, alert.UserID); err != nil {
+			customLog.Ctx(ctx).Warnf("Anomaly: Failed to find user %s to email: %v", alert.UserID, err)
+		} else if err := mail.Enqueue(ctx, d.store, user.Email, mail.TemplateAlert, mail.AlertData{
+			Title:   alert.Title,
+			Message: alert.Message,
+			Details: alert.Details,
+			Time:    alert.Time.Format(time.RFC1123),
+		}); err != nil {
+			customLog.Ctx(ctx).Warnf("Anomaly: Failed to queue alert email: %v", err)
+		}
+	}
+
+	if d.opts.WebhookURL != "" {
+		if err := d.postWebhook(ctx, alert); err != nil {
+			customLog.Ctx(ctx).Warnf("Anomaly: Alert webhook failed: %v", err)
+		}
+	}
+}
+
+// postWebhook sends the alert as JSON to the configured webhook.
+func (d *Detector) postWebhook(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.opts.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// keyHash identifies an API key in memory without keeping the key itself.
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// keyPrefix is the part of an API key shown in alerts.
+func keyPrefix(key string) string {
+	if len(key) > 8 {
+		return key[:8] + "…"
+	}
+	return key
+}
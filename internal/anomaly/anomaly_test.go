@@ -0,0 +1,114 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func newTestStore(t *testing.T) storage.MetadataStore {
+	t.Helper()
+	store, err := storage.ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if _, err := store.CreateUser(context.Background(), "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return store
+}
+
+// waitForEvents polls the audit log, which alerts are added to in the background,
+// until it holds want events.
+func waitForEvents(t *testing.T, store storage.MetadataStore, want int) []domain.AuditEvent {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		events, _, err := store.ListAuditEvents(context.Background(), "", 0, 0)
+		if err != nil {
+			t.Fatalf("ListAuditEvents: %v", err)
+		}
+		if len(events) >= want || time.Now().After(deadline) {
+			if len(events) != want {
+				t.Fatalf("audit log has %d events, want %d: %+v", len(events), want, events)
+			}
+			return events
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFailedLoginSpike(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	d := NewDetector(store, Options{FailedLogins: 3, FailedLoginWindow: 10 * time.Minute})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+
+	d.LoginFailed(ctx, "u1@example.com", "u1", "10.0.0.1")
+	now = now.Add(11 * time.Minute) // The first failure leaves the window
+	d.LoginFailed(ctx, "U1@example.com", "u1", "10.0.0.2")
+	d.LoginFailed(ctx, "u1@example.com", "u1", "10.0.0.3")
+	waitForEvents(t, store, 0)
+
+	d.LoginFailed(ctx, "u1@example.com", "u1", "10.0.0.3")
+	events := waitForEvents(t, store, 1)
+	if e := events[0]; e.Event != EventFailedLoginSpike || e.UserID != "u1" || e.Details["last_ip"] != "10.0.0.3" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	// Failures from one address across accounts are reported without an account
+	d.LoginFailed(ctx, "nobody@example.com", "", "10.0.0.3")
+	events = waitForEvents(t, store, 2)
+	if e := events[0]; e.UserID != "" || e.IP != "10.0.0.3" || e.Details["last_email"] != "nobody@example.com" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	// Alerts already raised stay quiet during the cooldown
+	d.LoginFailed(ctx, "u1@example.com", "u1", "10.0.0.3")
+	waitForEvents(t, store, 2)
+}
+
+func TestAPIKeyAlerts(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	if err := store.RegisterDatabase(ctx, "u1", "app", "app.db"); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, _ := store.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+	key, err := store.StoreAPIKey(ctx, "u1", databaseID)
+	if err != nil {
+		t.Fatalf("StoreAPIKey: %v", err)
+	}
+	d := NewDetector(store, Options{})
+
+	d.APIKeyUsed(ctx, key, "u1", databaseID, "DE", "10.0.0.1") // First country: no alert
+	time.Sleep(50 * time.Millisecond)
+	d.APIKeyUsed(ctx, key, "u1", databaseID, "DE", "10.0.0.1")
+	d.APIKeyUsed(ctx, key, "u1", databaseID, "FR", "10.0.0.2")
+	events := waitForEvents(t, store, 1)
+	if e := events[0]; e.Event != EventAPIKeyNewCountry || e.UserID != "u1" || e.Details["country"] != "FR" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	d.UnknownAPIKey(ctx, key, "10.0.0.3") // Still valid: not a revoked key
+	time.Sleep(50 * time.Millisecond)
+	waitForEvents(t, store, 1)
+
+	if err := store.DeleteAPIKey(ctx, key); err != nil {
+		t.Fatalf("DeleteAPIKey: %v", err)
+	}
+	d.UnknownAPIKey(ctx, key, "10.0.0.3")
+	events = waitForEvents(t, store, 2)
+	if e := events[0]; e.Event != EventRevokedAPIKeyUsed || e.UserID != "u1" || e.IP != "10.0.0.3" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if user, _, _ := store.ListAuditEvents(ctx, "u1", 1, 0); len(user) != 1 || user[0].EventID != events[0].EventID {
+		t.Errorf("ListAuditEvents(u1, limit 1) = %+v", user)
+	}
+}
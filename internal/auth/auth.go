@@ -2,11 +2,19 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5" // Use v5 or adjust if using v4
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/Annany2002/nebula-backend/api/models" // Import DTO for CustomClaims
@@ -28,9 +36,127 @@ var (
 
 // --- Password Utilities ---
 
-// HashPassword generates a bcrypt hash for the given password
+// Password hashing algorithms.
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+// PasswordHashing selects how new password hashes are computed. Stored hashes carry
+// their algorithm and parameters, so hashes made with other settings keep verifying.
+type PasswordHashing struct {
+	Algorithm         string // HashBcrypt or HashArgon2id
+	BcryptCost        int
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	// Pepper, when set, is mixed into every new hash and kept out of the metadata
+	// database, so stolen hashes cannot be cracked without it. Hashes made with one of
+	// PreviousPeppers keep verifying until they are upgraded on login.
+	Pepper          string
+	PreviousPeppers []string
+}
+
+// pepperedPrefix tags hashes made with a pepper: $peppered$<pepper ID> followed by the
+// bcrypt or Argon2id hash.
+const pepperedPrefix = "$peppered$"
+
+// argon2KeyLength and argon2SaltLength are the sizes of Argon2id hashes and salts.
+const (
+	argon2KeyLength  = 32
+	argon2SaltLength = 16
+)
+
+// passwordHashing is set once by ConfigurePasswordHashing.
+var passwordHashing = PasswordHashing{Algorithm: HashBcrypt, BcryptCost: bcrypt.DefaultCost}
+
+// ConfigurePasswordHashing sets how HashPassword hashes passwords. Call once at
+// startup.
+func ConfigurePasswordHashing(settings PasswordHashing) error {
+	switch settings.Algorithm {
+	case HashBcrypt:
+		if settings.BcryptCost < bcrypt.MinCost || settings.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost %d is outside %d-%d", settings.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case HashArgon2id:
+		if settings.Argon2Memory < 8*uint32(settings.Argon2Parallelism) || settings.Argon2Iterations < 1 || settings.Argon2Parallelism < 1 {
+			return errors.New("argon2id needs at least 1 iteration, 1 thread and 8 KiB of memory per thread")
+		}
+	default:
+		return fmt.Errorf("unknown password hashing algorithm '%s' (use %s or %s)", settings.Algorithm, HashBcrypt, HashArgon2id)
+	}
+	if settings.Pepper != "" {
+		customLog.Printf("Password hashing: %s with pepper %s", settings.Algorithm, PepperID(settings.Pepper))
+	}
+	passwordHashing = settings
+	return nil
+}
+
+// PepperID identifies a pepper in the hashes made with it, without revealing it.
+func PepperID(pepper string) string {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte("nebula-password-pepper-id"))
+	return hex.EncodeToString(mac.Sum(nil)[:4])
+}
+
+// applyPepper mixes pepper into password. The HMAC is encoded to 43 characters, within
+// the 72 bytes bcrypt reads.
+func applyPepper(pepper, password string) []byte {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(password))
+	return []byte(base64.RawStdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// splitPeppered returns the pepper ID and the inner hash of a hash made with a pepper.
+func splitPeppered(hash string) (id, inner string, ok bool) {
+	rest, found := strings.CutPrefix(hash, pepperedPrefix)
+	if !found {
+		return "", "", false
+	}
+	id, inner, found = strings.Cut(rest, "$")
+	return id, "$" + inner, found
+}
+
+// findPepper returns the configured pepper, current or previous, with the given ID.
+func findPepper(settings PasswordHashing, id string) (string, bool) {
+	for _, pepper := range append([]string{settings.Pepper}, settings.PreviousPeppers...) {
+		if pepper != "" && PepperID(pepper) == id {
+			return pepper, true
+		}
+	}
+	return "", false
+}
+
+// HashPassword hashes a password with the configured algorithm and pepper. Argon2id
+// hashes use the PHC string format ($argon2id$v=19$m=...,t=...,p=...$salt$hash); bcrypt
+// hashes are tagged by their own $2a$/$2b$ prefix.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	settings := passwordHashing
+	if settings.Pepper == "" {
+		return hashWith(settings, []byte(password))
+	}
+	hash, err := hashWith(settings, applyPepper(settings.Pepper, password))
+	if err != nil {
+		return "", err
+	}
+	return pepperedPrefix + PepperID(settings.Pepper) + hash, nil
+}
+
+// hashWith hashes password with the algorithm and parameters of settings.
+func hashWith(settings PasswordHashing, password []byte) (string, error) {
+	if settings.Algorithm == HashArgon2id {
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			customLog.Warnf("Error generating argon2id salt: %v", err)
+			return "", fmt.Errorf("failed to hash password")
+		}
+		key := argon2.IDKey(password, salt, settings.Argon2Iterations, settings.Argon2Memory, settings.Argon2Parallelism, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, settings.Argon2Memory, settings.Argon2Iterations, settings.Argon2Parallelism,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword(password, settings.BcryptCost)
 	if err != nil {
 		customLog.Warnf("Error generating bcrypt hash: %v", err)
 		// Don't return raw bcrypt error to caller usually
@@ -39,9 +165,29 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), nil
 }
 
-// CheckPasswordHash compares a plaintext password with a stored bcrypt hash
+// CheckPasswordHash compares a plaintext password with a stored bcrypt or Argon2id hash
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	input := []byte(password)
+	if id, inner, ok := splitPeppered(hash); ok {
+		pepper, found := findPepper(passwordHashing, id)
+		if !found {
+			customLog.Warnf("Password hash made with pepper %s, which is not configured", id)
+			return false
+		}
+		input, hash = applyPepper(pepper, password), inner
+	}
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			customLog.Warnf("Unexpected error comparing password hash: %v", err)
+			return false
+		}
+		computed := argon2.IDKey(input, salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(computed, key) == 1
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), input)
 	// Log unexpected errors, but return false for mismatch or other errors
 	if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
 		customLog.Warnf("Unexpected error comparing password hash: %v", err)
@@ -49,6 +195,53 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// NeedsRehash reports whether a stored hash was made with another algorithm, other
+// parameters or another pepper than the configured ones, so it should be replaced after
+// a successful login.
+func NeedsRehash(hash string) bool {
+	settings := passwordHashing
+	if id, inner, ok := splitPeppered(hash); ok {
+		if settings.Pepper == "" || id != PepperID(settings.Pepper) {
+			return true
+		}
+		hash = inner
+	} else if settings.Pepper != "" {
+		return true
+	}
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, _, _, err := parseArgon2Hash(hash)
+		return err != nil || settings.Algorithm != HashArgon2id || params.Argon2Memory != settings.Argon2Memory ||
+			params.Argon2Iterations != settings.Argon2Iterations || params.Argon2Parallelism != settings.Argon2Parallelism
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || settings.Algorithm != HashBcrypt || cost != settings.BcryptCost
+}
+
+// parseArgon2Hash splits an Argon2id hash in the PHC string format.
+func parseArgon2Hash(hash string) (PasswordHashing, []byte, []byte, error) {
+	params := PasswordHashing{Algorithm: HashArgon2id}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version '%s'", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Iterations, &params.Argon2Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("malformed argon2id key")
+	}
+	return params, salt, key, nil
+}
+
 // --- JWT Utilities ---
 
 // GenerateJWT creates a signed JWT string for a given userID
@@ -77,8 +270,9 @@ func GenerateJWT(userID, jwtSecret string, jwtExpiration time.Duration) (string,
 	return signedToken, nil
 }
 
-// ValidateJWT parses and validates a JWT string, returning the UserID if valid.
-func ValidateJWT(tokenString, jwtSecret string) (string, error) {
+// ValidateJWT parses and validates a JWT string, returning the UserID if valid. The
+// token is accepted when it is signed with any of jwtSecrets (several during a rotation).
+func ValidateJWT(tokenString string, jwtSecrets ...string) (string, error) {
 	claims := &models.CustomClaims{} // Use pointer to the DTO struct
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -88,8 +282,15 @@ func ValidateJWT(tokenString, jwtSecret string) (string, error) {
 			// Use wrapped error defined above
 			return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
 		}
-		// Return the secret key for validation
-		return []byte(jwtSecret), nil
+		// Return the secret key(s) for validation
+		if len(jwtSecrets) == 1 {
+			return []byte(jwtSecrets[0]), nil
+		}
+		keys := jwt.VerificationKeySet{}
+		for _, secret := range jwtSecrets {
+			keys.Keys = append(keys.Keys, []byte(secret))
+		}
+		return keys, nil
 	})
 
 	// Handle parsing errors, mapping library errors to our defined errors
@@ -122,3 +323,125 @@ func ValidateJWT(tokenString, jwtSecret string) (string, error) {
 	// Token is valid! Return the UserID.
 	return claims.UserID, nil
 }
+
+// --- Share Tokens ---
+
+// shareAudience marks share tokens, so they are never accepted as login tokens and
+// login tokens are never accepted as share tokens.
+const shareAudience = "nebula-share"
+
+// GenerateShareToken creates a signed token for the share link shareID, valid until
+// expiresAt.
+func GenerateShareToken(shareID, jwtSecret string, expiresAt time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		ID:        shareID,
+		Audience:  jwt.ClaimStrings{shareAudience},
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    "nebula-backend",
+	}
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+	if err != nil {
+		customLog.Warnf("Error signing share token %s: %v", shareID, err)
+		return "", fmt.Errorf("failed to generate token")
+	}
+	return signedToken, nil
+}
+
+// ValidateShareToken parses and validates a share token, returning the ID of its share
+// link. Like ValidateJWT, it accepts a token signed with any of jwtSecrets.
+func ValidateShareToken(tokenString string, jwtSecrets ...string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	keys := jwt.VerificationKeySet{}
+	for _, secret := range jwtSecrets {
+		keys.Keys = append(keys.Keys, []byte(secret))
+	}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return keys, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(shareAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return "", ErrTokenMalformed
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return "", ErrTokenExpired
+		default:
+			return "", ErrTokenInvalid
+		}
+	}
+	if claims.ID == "" {
+		return "", ErrTokenClaimsInvalid
+	}
+	return claims.ID, nil
+}
+
+// --- Scoped Access Tokens ---
+
+// scopedAudience marks scoped access tokens. They carry the user in the subject rather
+// than the userId claim, so ValidateJWT never takes them for login tokens.
+const scopedAudience = "nebula-scoped"
+
+// TokenScope is what a scoped access token grants: one database of a user, optionally
+// a single table of it, optionally read-only.
+type TokenScope struct {
+	UserID     string `json:"-"`
+	DatabaseID int64  `json:"db"`
+	Table      string `json:"tbl,omitempty"` // Every table when empty
+	ReadOnly   bool   `json:"ro,omitempty"`
+}
+
+// scopedClaims are the claims of a scoped access token.
+type scopedClaims struct {
+	TokenScope
+	jwt.RegisteredClaims
+}
+
+// GenerateScopedToken creates a signed access token limited to scope, valid until
+// expiresAt.
+func GenerateScopedToken(scope TokenScope, jwtSecret string, expiresAt time.Time) (string, error) {
+	claims := scopedClaims{
+		TokenScope: scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   scope.UserID,
+			Audience:  jwt.ClaimStrings{scopedAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "nebula-backend",
+		},
+	}
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecret))
+	if err != nil {
+		customLog.Warnf("Error signing scoped token for user %s: %v", scope.UserID, err)
+		return "", fmt.Errorf("failed to generate token")
+	}
+	return signedToken, nil
+}
+
+// ValidateScopedToken parses and validates a scoped access token, returning its scope.
+// Like ValidateJWT, it accepts a token signed with any of jwtSecrets.
+func ValidateScopedToken(tokenString string, jwtSecrets ...string) (*TokenScope, error) {
+	claims := &scopedClaims{}
+	keys := jwt.VerificationKeySet{}
+	for _, secret := range jwtSecrets {
+		keys.Keys = append(keys.Keys, []byte(secret))
+	}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return keys, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(scopedAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return nil, ErrTokenMalformed
+		case errors.Is(err, jwt.ErrTokenExpired):
+			return nil, ErrTokenExpired
+		default:
+			return nil, ErrTokenInvalid
+		}
+	}
+	if claims.Subject == "" || claims.DatabaseID == 0 {
+		return nil, ErrTokenClaimsInvalid
+	}
+	scope := claims.TokenScope
+	scope.UserID = claims.Subject
+	return &scope, nil
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareToken(t *testing.T) {
+	token, err := GenerateShareToken("share-1", "old", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := ValidateShareToken(token, "new", "old"); err != nil || id != "share-1" {
+		t.Errorf("ValidateShareToken = %q, %v", id, err)
+	}
+	if _, err := ValidateShareToken(token, "other"); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("wrong secret: err = %v, want ErrTokenInvalid", err)
+	}
+	// Share tokens and login tokens are not interchangeable
+	if _, err := ValidateJWT(token, "old"); err == nil {
+		t.Error("share token accepted as login token")
+	}
+	login, _ := GenerateJWT("u1", "old", time.Hour)
+	if _, err := ValidateShareToken(login, "old"); err == nil {
+		t.Error("login token accepted as share token")
+	}
+
+	expired, _ := GenerateShareToken("share-1", "old", time.Now().Add(-time.Minute))
+	if _, err := ValidateShareToken(expired, "old"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired token: err = %v, want ErrTokenExpired", err)
+	}
+}
+
+func TestPasswordHashing(t *testing.T) {
+	defer func(saved PasswordHashing) { passwordHashing = saved }(passwordHashing)
+
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 4}); err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := HashPassword("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPasswordHash("secret-password", bcryptHash) || NeedsRehash(bcryptHash) {
+		t.Errorf("bcrypt hash %q does not verify or needs a rehash", bcryptHash)
+	}
+
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}); err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := HashPassword("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("argon2id hash = %q, want PHC string format", argonHash)
+	}
+	if !CheckPasswordHash("secret-password", argonHash) || CheckPasswordHash("wrong-password", argonHash) {
+		t.Error("argon2id hash does not verify the right password only")
+	}
+	if NeedsRehash(argonHash) {
+		t.Error("argon2id hash with the configured settings needs a rehash")
+	}
+	// Hashes made with other settings keep verifying but are due for a rehash
+	if !CheckPasswordHash("secret-password", bcryptHash) || !NeedsRehash(bcryptHash) {
+		t.Error("bcrypt hash after switching to argon2id: want it to verify and need a rehash")
+	}
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashArgon2id, Argon2Memory: 2048, Argon2Iterations: 1, Argon2Parallelism: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !NeedsRehash(argonHash) {
+		t.Error("argon2id hash with less memory than configured does not need a rehash")
+	}
+	if CheckPasswordHash("secret-password", "$argon2id$v=19$m=1024$bad") {
+		t.Error("malformed argon2id hash verified")
+	}
+
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: "md5"}); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 40}); err == nil {
+		t.Error("bcrypt cost 40 accepted")
+	}
+}
+
+func TestPasswordPepper(t *testing.T) {
+	defer func(saved PasswordHashing) { passwordHashing = saved }(passwordHashing)
+	oldPepper, newPepper := strings.Repeat("a", 32), strings.Repeat("b", 32)
+
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 4}); err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := HashPassword("secret-password")
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 4, Pepper: oldPepper}); err != nil {
+		t.Fatal(err)
+	}
+	peppered, err := HashPassword("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(peppered, pepperedPrefix+PepperID(oldPepper)+"$2a$04$") {
+		t.Errorf("peppered hash = %q, want tagged with the pepper ID", peppered)
+	}
+	if !CheckPasswordHash("secret-password", peppered) || NeedsRehash(peppered) {
+		t.Error("peppered hash does not verify or needs a rehash")
+	}
+	// Unpeppered hashes keep verifying but are due for a rehash
+	if !CheckPasswordHash("secret-password", plain) || !NeedsRehash(plain) {
+		t.Error("unpeppered hash: want it to verify and need a rehash")
+	}
+	// The pepper is part of the hash: the inner hash alone does not verify
+	if _, inner, _ := splitPeppered(peppered); CheckPasswordHash("secret-password", inner) {
+		t.Error("inner hash verified without the pepper")
+	}
+
+	// Rotation: hashes of the previous pepper verify until they are upgraded
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 4, Pepper: newPepper, PreviousPeppers: []string{oldPepper}}); err != nil {
+		t.Fatal(err)
+	}
+	if !CheckPasswordHash("secret-password", peppered) || !NeedsRehash(peppered) {
+		t.Error("hash of the previous pepper: want it to verify and need a rehash")
+	}
+	if CheckPasswordHash("wrong-password", peppered) {
+		t.Error("wrong password verified against a hash of the previous pepper")
+	}
+	// Once the old pepper is retired, its hashes no longer verify
+	if err := ConfigurePasswordHashing(PasswordHashing{Algorithm: HashBcrypt, BcryptCost: 4, Pepper: newPepper}); err != nil {
+		t.Fatal(err)
+	}
+	if CheckPasswordHash("secret-password", peppered) {
+		t.Error("hash of a retired pepper verified")
+	}
+}
+
+func TestScopedToken(t *testing.T) {
+	scope := TokenScope{UserID: "u1", DatabaseID: 7, Table: "items", ReadOnly: true}
+	token, err := GenerateScopedToken(scope, "old", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ValidateScopedToken(token, "new", "old"); err != nil || *got != scope {
+		t.Errorf("ValidateScopedToken = %+v, %v; want %+v", got, err, scope)
+	}
+	// Scoped tokens are neither login nor share tokens, and the other way round
+	if _, err := ValidateJWT(token, "old"); err == nil {
+		t.Error("scoped token accepted as login token")
+	}
+	if _, err := ValidateShareToken(token, "old"); err == nil {
+		t.Error("scoped token accepted as share token")
+	}
+	login, _ := GenerateJWT("u1", "old", time.Hour)
+	if _, err := ValidateScopedToken(login, "old"); err == nil {
+		t.Error("login token accepted as scoped token")
+	}
+
+	expired, _ := GenerateScopedToken(scope, "old", time.Now().Add(-time.Minute))
+	if _, err := ValidateScopedToken(expired, "old"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired token: err = %v, want ErrTokenExpired", err)
+	}
+}
@@ -0,0 +1,46 @@
+// internal/auth/request_signing.go
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RequestSigningScheme is the Authorization scheme of signed requests:
+//
+//	Authorization: NEBULA-HMAC-SHA256 KeyId=7, Timestamp=1760000000, Signature=<hex>
+//
+// The API key itself never travels with the request.
+const RequestSigningScheme = "NEBULA-HMAC-SHA256"
+
+// RequestSignature returns the hex HMAC-SHA256, keyed with an API key, of a request:
+// its method, path, query (encoded sorted by key), Unix timestamp and the SHA-256 of
+// its body, one per line.
+func RequestSignature(apiKey, method, path string, query url.Values, timestamp int64, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	stringToSign := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		query.Encode(),
+		strconv.FormatInt(timestamp, 10),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	mac := hmac.New(sha256.New, []byte(apiKey))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidRequestSignature reports whether signature (hex) is the signature of the request
+// made with apiKey.
+func ValidRequestSignature(signature, apiKey, method, path string, query url.Values, timestamp int64, body []byte) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(RequestSignature(apiKey, method, path, query, timestamp, body))
+	return hmac.Equal(got, expected)
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignedURL(t *testing.T) {
+	const path = "/api/v1/databases/app/tables/orders/records/42"
+	query := SignURL(path, url.Values{"fields": {"id,total"}}, "u1", "old", time.Now().Add(time.Minute))
+
+	if user, err := VerifySignedURL(path, query, "new", "old"); err != nil || user != "u1" {
+		t.Errorf("VerifySignedURL = %q, %v; want u1", user, err)
+	}
+	if _, err := VerifySignedURL(path, query, "other"); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("wrong secret: err = %v, want ErrTokenInvalid", err)
+	}
+	// Neither the path nor any parameter can be changed
+	if _, err := VerifySignedURL("/api/v1/databases/app/tables/orders/records/43", query, "old"); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("other path: err = %v, want ErrTokenInvalid", err)
+	}
+	for key, value := range map[string]string{"fields": "*", SignedURLUser: "u2", SignedURLExpires: "9999999999", "limit": "1"} {
+		changed := url.Values{}
+		for k, v := range query {
+			changed[k] = v
+		}
+		changed.Set(key, value)
+		if _, err := VerifySignedURL(path, changed, "old"); !errors.Is(err, ErrTokenInvalid) {
+			t.Errorf("changed %s: err = %v, want ErrTokenInvalid", key, err)
+		}
+	}
+
+	expired := SignURL(path, nil, "u1", "old", time.Now().Add(-time.Second))
+	if _, err := VerifySignedURL(path, expired, "old"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired URL: err = %v, want ErrTokenExpired", err)
+	}
+	if _, err := VerifySignedURL(path, url.Values{SignedURLSignature: {"zz"}}, "old"); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("malformed URL: err = %v, want ErrTokenMalformed", err)
+	}
+}
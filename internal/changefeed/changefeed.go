@@ -0,0 +1,129 @@
+// internal/changefeed/changefeed.go
+
+// Package changefeed delivers the record writes storage reports (see
+// storage.OnRecordChange) to the webhooks of their database and publishes them to the
+// event bridge. Every writer goes through storage, so single-record writes of any API,
+// imports, generated and seeded data, dedupes and table copies all notify. Restores
+// replace a whole database and send no record changes.
+package changefeed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/eventbridge"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+	"github.com/Annany2002/nebula-backend/internal/webhooks"
+)
+
+var customLog = logger.NewLogger()
+
+// databaseTTL bounds how long the database registered at a location is remembered, so
+// a bulk write looks it up once.
+const databaseTTL = 10 * time.Second
+
+// Store is the part of the metadata store the feed needs (storage.MetadataStore
+// satisfies it).
+type Store interface {
+	FindDatabaseByLocation(ctx context.Context, location string) (*domain.DatabaseMetadata, error)
+}
+
+// Feed delivers and publishes record changes.
+type Feed struct {
+	store Store
+	now   func() time.Time
+
+	mu        sync.Mutex
+	databases map[string]cachedDatabase // By storage location
+}
+
+type cachedDatabase struct {
+	db      *domain.DatabaseMetadata
+	expires time.Time
+}
+
+// New returns a feed resolving storage locations with store.
+func New(store Store) *Feed {
+	return &Feed{store: store, now: time.Now, databases: make(map[string]cachedDatabase)}
+}
+
+// Handle delivers change to the subscribed webhooks and publishes it to the event
+// bridge. The write is already committed, so failures are only logged.
+func (f *Feed) Handle(ctx context.Context, change storage.RecordChange) {
+	if !webhooks.Enabled() && !eventbridge.Enabled() {
+		return
+	}
+	db, err := f.database(ctx, change.Location)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Change feed: Failed to look up database of change to table '%s': %v", change.Table, err)
+		return
+	}
+	hooks := webhooks.Subscribers(ctx, db.DatabaseID, change.Event)
+	if len(hooks) == 0 && !eventbridge.Enabled() {
+		return
+	}
+
+	var record map[string]any
+	if change.Event != storage.RecordDeleted {
+		record = f.record(ctx, change)
+	}
+	webhooks.Publish(ctx, hooks, webhooks.Event{
+		Event:    change.Event,
+		DBName:   db.DBName,
+		Table:    change.Table,
+		RecordID: change.RecordID,
+		Record:   record,
+	})
+	eventbridge.Publish(ctx, eventbridge.Change{
+		Event:    change.Event,
+		UserID:   db.UserID,
+		DBName:   db.DBName,
+		Table:    change.Table,
+		RecordID: change.RecordID,
+		Record:   record,
+	})
+}
+
+// database returns the registration of the database stored at location.
+func (f *Feed) database(ctx context.Context, location string) (*domain.DatabaseMetadata, error) {
+	now := f.now()
+	f.mu.Lock()
+	cached, ok := f.databases[location]
+	f.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.db, nil
+	}
+
+	db, err := f.store.FindDatabaseByLocation(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for loc, entry := range f.databases { // Keep the map to the databases written lately
+		if !now.Before(entry.expires) {
+			delete(f.databases, loc)
+		}
+	}
+	f.databases[location] = cachedDatabase{db: db, expires: now.Add(databaseTTL)}
+	return db, nil
+}
+
+// record reads the stored record of change, nil when it cannot be read.
+func (f *Feed) record(ctx context.Context, change storage.RecordChange) map[string]any {
+	userDB, err := storage.OpenUserData(ctx, change.Location)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Change feed: Failed to open database for record ID %d of table '%s': %v", change.RecordID, change.Table, err)
+		return nil
+	}
+	defer userDB.Release()
+	record, err := userDB.GetRecord(ctx, change.Table, change.RecordID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Change feed: Failed to read record ID %d of table '%s': %v", change.RecordID, change.Table, err)
+		return nil
+	}
+	return record
+}
@@ -0,0 +1,204 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/eventbridge"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+	"github.com/Annany2002/nebula-backend/internal/webhooks"
+)
+
+// fakeStore registers one database and its webhooks.
+type fakeStore struct {
+	db      domain.DatabaseMetadata
+	hooks   []domain.Webhook
+	lookups int
+}
+
+func (s *fakeStore) ListWebhooks(context.Context, int64) ([]domain.Webhook, error) {
+	return append([]domain.Webhook(nil), s.hooks...), nil
+}
+
+func (s *fakeStore) RecordWebhookDelivery(context.Context, string, int, string, time.Time) error {
+	return nil
+}
+
+func (s *fakeStore) FindDatabaseByLocation(_ context.Context, location string) (*domain.DatabaseMetadata, error) {
+	s.lookups++
+	if location != s.db.FilePath {
+		return nil, storage.ErrDatabaseNotFound
+	}
+	db := s.db
+	return &db, nil
+}
+
+// fakePublisher keeps the published changes.
+type fakePublisher struct {
+	mu      sync.Mutex
+	changes []eventbridge.Change
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msgs []eventbridge.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range msgs {
+		var change eventbridge.Change
+		if err := json.Unmarshal(msg.Value, &change); err != nil {
+			return err
+		}
+		p.changes = append(p.changes, change)
+	}
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func (p *fakePublisher) waitFor(t *testing.T, n int) []eventbridge.Change {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p.mu.Lock()
+		changes := append([]eventbridge.Change(nil), p.changes...)
+		p.mu.Unlock()
+		if len(changes) >= n || time.Now().After(deadline) {
+			return changes
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFeedPublishesStoreWrites(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := &fakePublisher{}
+	bridge := eventbridge.NewBridge(publisher, "nebula.{db_name}", 100)
+	go bridge.Run(ctx)
+	eventbridge.Configure(bridge)
+	t.Cleanup(func() { eventbridge.Configure(nil) })
+
+	location := storage.UserDataLocation(t.TempDir(), "u1", "app")
+	if err := storage.PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store := &fakeStore{db: domain.DatabaseMetadata{DatabaseID: 1, UserID: "u1", DBName: "app", FilePath: location}}
+	storage.OnRecordChange(New(store).Handle)
+	t.Cleanup(func() { storage.OnRecordChange(nil) })
+
+	userDB, err := storage.OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer userDB.Release()
+	if err := userDB.CreateTable(ctx, "contacts", []core.ColumnSpec{{Name: "email", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	for _, email := range []string{"a@x.io", "a@x.io"} {
+		if _, err := userDB.InsertRecord(ctx, "contacts", []string{"email"}, []any{email}); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+	if _, err := userDB.UpdateRecord(ctx, "contacts", 1, []string{"email"}, []any{"b@x.io"}); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if _, err := userDB.UpdateRecord(ctx, "contacts", 2, []string{"email"}, []any{"b@x.io"}); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if deleted, err := userDB.DeleteDuplicates(ctx, "contacts", []string{"email"}, false); err != nil || len(deleted) != 1 {
+		t.Fatalf("DeleteDuplicates() = %v, %v; want 1 deletion", deleted, err)
+	}
+
+	changes := publisher.waitFor(t, 5)
+	want := []struct {
+		event string
+		id    int64
+		email any
+	}{
+		{storage.RecordCreated, 1, "a@x.io"},
+		{storage.RecordCreated, 2, "a@x.io"},
+		{storage.RecordUpdated, 1, "b@x.io"},
+		{storage.RecordUpdated, 2, "b@x.io"},
+		{storage.RecordDeleted, 2, nil},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("published %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		got := changes[i]
+		if got.Event != w.event || got.RecordID != w.id || got.UserID != "u1" || got.DBName != "app" || got.Table != "contacts" {
+			t.Errorf("change %d = %+v, want %s of record %d", i, got, w.event, w.id)
+		}
+		if got.Record["email"] != w.email {
+			t.Errorf("change %d record = %v, want email %v", i, got.Record, w.email)
+		}
+	}
+	if store.lookups != 1 {
+		t.Errorf("database looked up %d times, want 1", store.lookups)
+	}
+}
+
+func TestFeedDeliversWebhooks(t *testing.T) {
+	ctx := context.Background()
+	deliveries := make(chan webhooks.Event, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event webhooks.Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("delivery body %q: %v", body, err)
+		}
+		deliveries <- event
+	}))
+	defer server.Close()
+
+	location := storage.UserDataLocation(t.TempDir(), "u1", "app")
+	if err := storage.PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store := &fakeStore{
+		db:    domain.DatabaseMetadata{DatabaseID: 1, UserID: "u1", DBName: "app", FilePath: location},
+		hooks: []domain.Webhook{{ID: "w1", URL: server.URL, Secret: "whsec_test", Events: []string{webhooks.EventRecordDeleted}, Enabled: true}},
+	}
+	webhooks.Configure(store, webhooks.Options{AllowPrivateHosts: true})
+	storage.OnRecordChange(New(store).Handle)
+	t.Cleanup(func() { storage.OnRecordChange(nil) })
+
+	userDB, err := storage.OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer userDB.Release()
+	if err := userDB.CreateTable(ctx, "contacts", []core.ColumnSpec{{Name: "email", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	for range 2 {
+		if _, err := userDB.InsertRecord(ctx, "contacts", []string{"email"}, []any{"a@x.io"}); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+	if _, err := userDB.DeleteDuplicates(ctx, "contacts", []string{"email"}, false); err != nil {
+		t.Fatalf("DeleteDuplicates: %v", err)
+	}
+
+	select {
+	case event := <-deliveries:
+		if event.Event != webhooks.EventRecordDeleted || event.DBName != "app" || event.Table != "contacts" || event.RecordID != 2 {
+			t.Errorf("delivered %+v, want record.deleted of record 2", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dedupe delivered no webhook")
+	}
+	select {
+	case event := <-deliveries:
+		t.Errorf("unsubscribed event delivered: %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
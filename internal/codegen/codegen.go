@@ -0,0 +1,173 @@
+// internal/codegen/codegen.go
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Supported output languages.
+const (
+	LangGo         = "go"
+	LangTypeScript = "typescript"
+)
+
+// Generate renders client models for tables in the given language. pkg is the Go
+// package name and is ignored for TypeScript.
+func Generate(lang, dbName, pkg string, tables []domain.TableMetadata) (string, error) {
+	switch lang {
+	case LangGo:
+		return GoModels(dbName, pkg, tables)
+	case LangTypeScript:
+		return TypeScriptModels(dbName, tables), nil
+	}
+	return "", fmt.Errorf("unsupported language '%s'", lang)
+}
+
+// columnKind classifies a declared SQLite column type the way records are returned by the
+// API: integers and booleans (stored as 0/1) as numbers, timestamps as RFC 3339 strings,
+// and BLOBs as strings.
+type columnKind int
+
+const (
+	kindString columnKind = iota
+	kindInteger
+	kindReal
+	kindBoolean
+	kindTimestamp
+)
+
+func kindOf(sqlType string) columnKind {
+	upper := strings.ToUpper(sqlType)
+	switch {
+	case upper == "BOOLEAN":
+		return kindBoolean
+	case strings.Contains(upper, "INT"):
+		return kindInteger
+	case upper == "REAL", strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"), upper == "NUMERIC":
+		return kindReal
+	case strings.Contains(upper, "TIMESTAMP"), strings.Contains(upper, "DATE"):
+		return kindTimestamp
+	}
+	return kindString
+}
+
+// nullable reports whether the API may return null for the column.
+func nullable(col domain.ColumnInfo) bool {
+	return col.NotNull == 0 && col.PK == 0
+}
+
+// GoModels renders one struct per table with JSON tags matching the record API.
+func GoModels(dbName, pkg string, tables []domain.TableMetadata) (string, error) {
+	if pkg == "" {
+		pkg = "models"
+	}
+	if !token.IsIdentifier(pkg) {
+		return "", fmt.Errorf("invalid Go package name '%s'", pkg)
+	}
+	var body strings.Builder
+	usesTime := false
+	typeNames := make(map[string]bool)
+	for _, table := range tables {
+		typeName := unique(typeNames, goName(table.Name))
+		fmt.Fprintf(&body, "\n// %s is a record of table '%s'.\ntype %s struct {\n", typeName, table.Name, typeName)
+		fieldNames := make(map[string]bool)
+		for _, col := range table.Columns {
+			var typ string
+			switch kindOf(col.Type) {
+			case kindInteger:
+				typ = "int64"
+			case kindReal:
+				typ = "float64"
+			case kindBoolean:
+				typ = "int64" // Stored and returned as 0 or 1
+			case kindTimestamp:
+				typ = "time.Time"
+				usesTime = true
+			default:
+				typ = "string"
+			}
+			if nullable(col) {
+				typ = "*" + typ
+			}
+			fmt.Fprintf(&body, "\t%s %s `json:\"%s\"` // %s\n", unique(fieldNames, goName(col.Name)), typ, col.Name, strings.ToUpper(col.Type))
+		}
+		body.WriteString("}\n")
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by nebula from database '%s'. DO NOT EDIT.\n\npackage %s\n", dbName, pkg)
+	if usesTime {
+		src.WriteString("\nimport \"time\"\n")
+	}
+	src.WriteString(body.String())
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format generated Go code: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// TypeScriptModels renders one interface per table matching the record API's JSON.
+func TypeScriptModels(dbName string, tables []domain.TableMetadata) string {
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by nebula from database '%s'. DO NOT EDIT.\n", dbName)
+	typeNames := make(map[string]bool)
+	for _, table := range tables {
+		fmt.Fprintf(&src, "\n/** A record of table '%s'. */\nexport interface %s {\n", table.Name, unique(typeNames, goName(table.Name)))
+		for _, col := range table.Columns {
+			typ := "string"
+			switch kindOf(col.Type) {
+			case kindInteger, kindReal:
+				typ = "number"
+			case kindBoolean:
+				typ = "number" // Stored and returned as 0 or 1
+			}
+			if nullable(col) {
+				typ += " | null"
+			}
+			key := col.Name
+			if key[0] >= '0' && key[0] <= '9' {
+				key = fmt.Sprintf("%q", key)
+			}
+			fmt.Fprintf(&src, "  %s: %s; // %s\n", key, typ, strings.ToUpper(col.Type))
+		}
+		src.WriteString("}\n")
+	}
+	return src.String()
+}
+
+// goName converts a snake_case identifier to an exported Go name ("user_id" -> "UserID").
+func goName(name string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if strings.EqualFold(part, "id") {
+			sb.WriteString("ID")
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	out := sb.String()
+	if out == "" || (out[0] >= '0' && out[0] <= '9') {
+		out = "X" + out
+	}
+	return out
+}
+
+// unique returns name, or name with a numeric suffix if it was already used.
+func unique(used map[string]bool, name string) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
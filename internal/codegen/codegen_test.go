@@ -0,0 +1,44 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestGenerate(t *testing.T) {
+	tables := []domain.TableMetadata{{
+		Name: "blog_posts",
+		Columns: []domain.ColumnInfo{
+			{Name: "id", Type: "INTEGER", PK: 1},
+			{Name: "title", Type: "TEXT", NotNull: 1},
+			{Name: "rating", Type: "REAL"},
+			{Name: "created_at", Type: "TIMESTAMP", NotNull: 1},
+		},
+	}}
+
+	goSrc, err := Generate(LangGo, "app", "", tables)
+	if err != nil {
+		t.Fatalf("Generate(go): %v", err)
+	}
+	for _, want := range []string{"package models", `import "time"`, "type BlogPosts struct", "ID        int64", "Rating    *float64", "CreatedAt time.Time"} {
+		if !strings.Contains(goSrc, want) {
+			t.Errorf("Go output missing %q:\n%s", want, goSrc)
+		}
+	}
+
+	tsSrc, err := Generate(LangTypeScript, "app", "", tables)
+	if err != nil {
+		t.Fatalf("Generate(typescript): %v", err)
+	}
+	for _, want := range []string{"export interface BlogPosts {", "id: number;", "rating: number | null;", "created_at: string;"} {
+		if !strings.Contains(tsSrc, want) {
+			t.Errorf("TypeScript output missing %q:\n%s", want, tsSrc)
+		}
+	}
+
+	if _, err := Generate(LangGo, "app", "bad-name", tables); err == nil {
+		t.Error("Generate accepted an invalid package name")
+	}
+}
@@ -0,0 +1,469 @@
+// internal/coordination/coordination.go
+
+// Package coordination lets several Nebula instances share one data directory. Each
+// instance announces itself with a heartbeat file in the directory, so instances see
+// each other and warn about unsafe setups (writers without file locks, caches nobody
+// invalidates, per-instance state). With a Redis URL configured, the cache
+// invalidations caused by one instance's writes are broadcast to the others over
+// Redis pub/sub. Writes themselves are serialized across instances by the advisory
+// file locks of internal/storage.
+package coordination
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/health"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var customLog = logger.NewLogger()
+
+const (
+	workerName        = "instance_coordination"
+	instancesDir      = ".instances"
+	invalidationQueue = 4096
+	maxBatch          = 256
+	maxRetryDelay     = 30 * time.Second
+	staleAfterBeats   = 3 // Heartbeats an instance may miss before it counts as gone
+	dropLogEvery      = 1000
+	defaultHeartbeat  = 15 * time.Second
+	defaultChannel    = "nebula:invalidations"
+)
+
+// Instance is the heartbeat an instance writes to the shared data directory.
+type Instance struct {
+	ID          string    `json:"id"`
+	Hostname    string    `json:"hostname"`
+	PID         int       `json:"pid"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	FileLocks   bool      `json:"file_locks"`   // Writes take the advisory file locks
+	SharedCache bool      `json:"shared_cache"` // Cache invalidations are exchanged over Redis
+	Metadata    string    `json:"metadata_backend"`
+	UserData    string    `json:"user_data_backend"`
+}
+
+// Status is what an instance knows about the instances sharing its data directory.
+type Status struct {
+	Self     Instance   `json:"self"`
+	Peers    []Instance `json:"peers"`    // Other live instances
+	Warnings []string   `json:"warnings"` // Unsafe aspects of the current topology
+}
+
+// Options configures a Coordinator.
+type Options struct {
+	InstanceID        string // Defaults to the hostname and a random suffix
+	DataDir           string // Shared data directory (DATABASE_DIRECTORY)
+	Heartbeat         time.Duration
+	FileLocks         bool
+	MetadataBackend   string
+	UserDataBackend   string
+	PubSub            PubSub // Nil disables shared cache invalidation
+	Channel           string
+	NetworkFilesystem string // Filesystem type of DataDir when it is a network filesystem
+}
+
+// PubSub is the channel cache invalidations are exchanged on.
+type PubSub interface {
+	Publish(ctx context.Context, channel string, payload []byte) (int64, error)
+	Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error
+	Close() error
+}
+
+// invalidationMessage is a batch of invalidations published by one instance. Paths are
+// relative to the data directory so instances mounting it elsewhere agree.
+type invalidationMessage struct {
+	Origin        string         `json:"origin"`
+	Invalidations []invalidation `json:"invalidations"`
+}
+
+type invalidation struct {
+	Kind  string `json:"kind"`
+	Path  string `json:"path"`
+	Table string `json:"table,omitempty"`
+}
+
+// Coordinator heartbeats, watches for other instances and, with a PubSub, exchanges
+// cache invalidations.
+type Coordinator struct {
+	opts       Options
+	self       Instance
+	queue      chan invalidation
+	retryDelay time.Duration // Before resubscribing or republishing, doubling after each failure
+	dropped    atomic.Int64
+
+	mu       sync.RWMutex
+	status   Status
+	warnings string // Warnings last logged, to log only changes
+}
+
+// NewCoordinator returns a coordinator for opts.
+func NewCoordinator(opts Options) *Coordinator {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+	if opts.Channel == "" {
+		opts.Channel = defaultChannel
+	}
+	hostname, _ := os.Hostname()
+	if opts.InstanceID == "" {
+		suffix := make([]byte, 4)
+		_, _ = rand.Read(suffix)
+		opts.InstanceID = hostname + "-" + hex.EncodeToString(suffix)
+	}
+	now := time.Now().UTC()
+	self := Instance{
+		ID:          opts.InstanceID,
+		Hostname:    hostname,
+		PID:         os.Getpid(),
+		StartedAt:   now,
+		HeartbeatAt: now,
+		FileLocks:   opts.FileLocks,
+		SharedCache: opts.PubSub != nil,
+		Metadata:    opts.MetadataBackend,
+		UserData:    opts.UserDataBackend,
+	}
+	return &Coordinator{
+		opts:       opts,
+		self:       self,
+		queue:      make(chan invalidation, invalidationQueue),
+		retryDelay: time.Second,
+		status:     Status{Self: self, Peers: []Instance{}, Warnings: []string{}},
+	}
+}
+
+// New builds a coordinator from configuration and enables the storage features it
+// relies on. It returns nil when instance heartbeats are disabled.
+func New(cfg *config.Config) (*Coordinator, error) {
+	if cfg.FileLocksEnabled {
+		if err := storage.ConfigureFileLocks(cfg.MetadataDbDir); err != nil {
+			return nil, fmt.Errorf("failed to enable file locks: %w", err)
+		}
+	}
+	if cfg.InstanceHeartbeat <= 0 {
+		return nil, nil
+	}
+	var pubsub PubSub
+	if cfg.CacheInvalidationRedisURL != "" {
+		client, err := ParseRedisURL(cfg.CacheInvalidationRedisURL)
+		if err != nil {
+			return nil, err
+		}
+		pubsub = client
+	}
+	return NewCoordinator(Options{
+		InstanceID:        cfg.InstanceID,
+		DataDir:           cfg.MetadataDbDir,
+		Heartbeat:         cfg.InstanceHeartbeat,
+		FileLocks:         cfg.FileLocksEnabled,
+		MetadataBackend:   cfg.MetadataBackend,
+		UserDataBackend:   cfg.UserDataBackend,
+		PubSub:            pubsub,
+		Channel:           cfg.CacheInvalidationChannel,
+		NetworkFilesystem: networkFilesystem(cfg.MetadataDbDir),
+	}), nil
+}
+
+// Run heartbeats and exchanges invalidations until ctx is done, then removes the
+// instance's heartbeat file.
+func (c *Coordinator) Run(ctx context.Context) {
+	customLog.Ctx(ctx).Printf("Coordination: Instance '%s' sharing data directory '%s' (file locks: %t, shared cache: %t)",
+		c.self.ID, c.opts.DataDir, c.self.FileLocks, c.self.SharedCache)
+	health.RegisterWorker(workerName, c.opts.Heartbeat)
+	defer health.UnregisterWorker(workerName)
+	defer c.removeHeartbeat()
+
+	if c.opts.PubSub != nil {
+		defer c.opts.PubSub.Close()
+		storage.OnInvalidation(c.enqueue)
+		defer storage.OnInvalidation(nil)
+		go c.subscribe(ctx)
+		go c.publish(ctx)
+	}
+
+	ticker := time.NewTicker(c.opts.Heartbeat)
+	defer ticker.Stop()
+	for {
+		c.beat(ctx)
+		health.Beat(workerName)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status returns the instances last seen and the warnings they raise.
+func (c *Coordinator) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	status := c.status
+	status.Peers = slices.Clone(status.Peers)
+	status.Warnings = slices.Clone(status.Warnings)
+	return status
+}
+
+// beat writes the heartbeat file and re-reads the others.
+func (c *Coordinator) beat(ctx context.Context) {
+	dir := filepath.Join(c.opts.DataDir, instancesDir)
+	c.self.HeartbeatAt = time.Now().UTC()
+	if err := writeHeartbeat(dir, c.self); err != nil {
+		customLog.Ctx(ctx).Warnf("Coordination: Failed to write heartbeat: %v", err)
+	}
+	peers, err := readPeers(dir, c.self.ID, time.Now().Add(-staleAfterBeats*c.opts.Heartbeat))
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Coordination: Failed to read other instances' heartbeats: %v", err)
+	}
+
+	warnings := Warnings(c.self, peers, c.opts.NetworkFilesystem)
+	c.mu.Lock()
+	c.status = Status{Self: c.self, Peers: peers, Warnings: warnings}
+	changed := strings.Join(warnings, "\n") != c.warnings
+	c.warnings = strings.Join(warnings, "\n")
+	c.mu.Unlock()
+
+	if changed {
+		if len(peers) > 0 {
+			customLog.Ctx(ctx).Printf("Coordination: %d other instance(s) share the data directory", len(peers))
+		}
+		for _, warning := range warnings {
+			customLog.Ctx(ctx).Warnf("Coordination: Unsafe topology: %s", warning)
+		}
+	}
+}
+
+// Warnings lists what makes sharing the data directory between self and peers unsafe.
+// fsType names the network filesystem the directory is on, if any.
+func Warnings(self Instance, peers []Instance, fsType string) []string {
+	warnings := []string{}
+	if fsType != "" && self.UserData == storage.BackendSQLite {
+		warnings = append(warnings, fmt.Sprintf("the data directory is on a network filesystem (%s), where SQLite's locking is unreliable; keep SQLite files on local disks or use USER_DATA_BACKEND=postgres", fsType))
+	}
+	if len(peers) == 0 {
+		return warnings
+	}
+	all := append([]Instance{self}, peers...)
+	without := func(has func(Instance) bool) []string {
+		var ids []string
+		for _, instance := range all {
+			if !has(instance) {
+				ids = append(ids, instance.ID)
+			}
+		}
+		return ids
+	}
+
+	if self.Metadata == storage.BackendSQLite {
+		warnings = append(warnings, "the metadata database is a SQLite file shared by several instances; use METADATA_BACKEND=postgres")
+	}
+	if self.UserData == storage.BackendSQLite {
+		if ids := without(func(i Instance) bool { return i.FileLocks }); len(ids) > 0 {
+			warnings = append(warnings, fmt.Sprintf("instances without FILE_LOCKS_ENABLED (%s) write to shared SQLite files concurrently, which can corrupt their WALs", strings.Join(ids, ", ")))
+		}
+	}
+	if ids := without(func(i Instance) bool { return i.SharedCache }); len(ids) > 0 {
+		warnings = append(warnings, fmt.Sprintf("instances without CACHE_INVALIDATION_REDIS_URL (%s) serve cached reads and schemas that other instances' writes made stale", strings.Join(ids, ", ")))
+	}
+	warnings = append(warnings, "rate limits and the service mode are kept per instance; set the service mode on every instance")
+	return warnings
+}
+
+// enqueue queues an invalidation of this instance for publishing. It never blocks the
+// writer; invalidations are dropped, and counted, when the queue is full.
+func (c *Coordinator) enqueue(inv storage.Invalidation) {
+	msg := invalidation{Kind: inv.Kind, Path: storage.RelativeDataPath(c.opts.DataDir, inv.Path), Table: inv.Table}
+	select {
+	case c.queue <- msg:
+	default:
+		if dropped := c.dropped.Add(1); dropped%dropLogEvery == 1 {
+			customLog.Warnf("Coordination: Invalidation queue full, dropped %d invalidation(s) so far", dropped)
+		}
+	}
+}
+
+// publish sends queued invalidations in batches until ctx is done.
+func (c *Coordinator) publish(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case first := <-c.queue:
+			batch := []invalidation{first}
+			for len(batch) < maxBatch && len(c.queue) > 0 {
+				batch = append(batch, <-c.queue)
+			}
+			c.publishBatch(ctx, batch)
+		}
+	}
+}
+
+// publishBatch publishes batch, retrying until it is accepted or ctx is done.
+func (c *Coordinator) publishBatch(ctx context.Context, batch []invalidation) {
+	payload, err := json.Marshal(invalidationMessage{Origin: c.self.ID, Invalidations: batch})
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Coordination: Failed to encode %d invalidation(s): %v", len(batch), err)
+		return
+	}
+	delay := c.retryDelay
+	for attempt := 1; ; attempt++ {
+		_, err := c.opts.PubSub.Publish(ctx, c.opts.Channel, payload)
+		if err == nil {
+			return
+		}
+		customLog.Ctx(ctx).Warnf("Coordination: Failed to publish %d invalidation(s) (attempt %d): %v", len(batch), attempt, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// subscribe applies the invalidations of other instances until ctx is done,
+// resubscribing after connection failures.
+func (c *Coordinator) subscribe(ctx context.Context) {
+	delay := c.retryDelay
+	for {
+		started := time.Now()
+		err := c.opts.PubSub.Subscribe(ctx, c.opts.Channel, c.apply)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > maxRetryDelay {
+			delay = c.retryDelay
+		}
+		// Invalidations published while unsubscribed are lost, so nothing cached then
+		// can be trusted once the subscription is back
+		storage.ClearCaches()
+		customLog.Ctx(ctx).Warnf("Coordination: Invalidation subscription lost, reads may be stale until it is back: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+		storage.ClearCaches()
+	}
+}
+
+// apply applies one message of another instance.
+func (c *Coordinator) apply(payload []byte) {
+	var msg invalidationMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		customLog.Warnf("Coordination: Ignoring malformed invalidation message: %v", err)
+		return
+	}
+	if msg.Origin == c.self.ID {
+		return
+	}
+	for _, inv := range msg.Invalidations {
+		path := filepath.FromSlash(inv.Path)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.opts.DataDir, path)
+		}
+		storage.ApplyInvalidation(storage.Invalidation{Kind: inv.Kind, Path: path, Table: inv.Table})
+	}
+}
+
+// writeHeartbeat replaces the heartbeat file of instance in dir.
+func writeHeartbeat(dir string, instance Instance) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	data, err := json.Marshal(instance)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, instance.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readPeers returns the instances other than selfID that heartbeat after since.
+func readPeers(dir, selfID string, since time.Time) ([]Instance, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []Instance{}, err
+	}
+	peers := []Instance{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue // Replaced or removed meanwhile
+		}
+		var instance Instance
+		if json.Unmarshal(data, &instance) != nil || instance.ID == selfID || instance.HeartbeatAt.Before(since) {
+			continue
+		}
+		peers = append(peers, instance)
+	}
+	slices.SortFunc(peers, func(a, b Instance) int { return strings.Compare(a.ID, b.ID) })
+	return peers, nil
+}
+
+// RunningInstances returns the instances with a current heartbeat in dataDir, for tools
+// that must not run while a server uses the directory.
+func RunningInstances(dataDir string, heartbeat time.Duration) ([]Instance, error) {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	instances, err := readPeers(filepath.Join(dataDir, instancesDir), "", time.Now().Add(-staleAfterBeats*heartbeat))
+	if errors.Is(err, os.ErrNotExist) {
+		return instances, nil // No instance ever ran with heartbeats
+	}
+	return instances, err
+}
+
+// removeHeartbeat removes the instance's heartbeat file on shutdown.
+func (c *Coordinator) removeHeartbeat() {
+	path := filepath.Join(c.opts.DataDir, instancesDir, c.self.ID+".json")
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		customLog.Warnf("Coordination: Failed to remove heartbeat file: %v", err)
+	}
+}
+
+var (
+	mu          sync.RWMutex
+	coordinator *Coordinator
+)
+
+// Configure makes c the coordinator CurrentStatus reports on; nil disables it.
+func Configure(c *Coordinator) {
+	mu.Lock()
+	defer mu.Unlock()
+	coordinator = c
+}
+
+// CurrentStatus returns the status of the configured coordinator. ok is false when
+// instance heartbeats are disabled.
+func CurrentStatus() (status Status, ok bool) {
+	mu.RLock()
+	c := coordinator
+	mu.RUnlock()
+	if c == nil {
+		return Status{}, false
+	}
+	return c.Status(), true
+}
@@ -0,0 +1,28 @@
+// internal/coordination/netfs_linux.go
+
+//go:build linux
+
+package coordination
+
+import "syscall"
+
+// Superblock magic numbers of network filesystems (see statfs(2)).
+var networkFilesystems = map[int64]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x00c36400: "ceph",
+	0x01021997: "9p",
+	0x5346414f: "afs",
+}
+
+// networkFilesystem returns the type of network filesystem dir is on, or "" for local
+// disks and when it cannot tell.
+func networkFilesystem(dir string) string {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return ""
+	}
+	return networkFilesystems[int64(stat.Type)]
+}
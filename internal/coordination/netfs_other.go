@@ -0,0 +1,10 @@
+// internal/coordination/netfs_other.go
+
+//go:build !linux
+
+package coordination
+
+// networkFilesystem cannot tell network filesystems apart on this platform.
+func networkFilesystem(string) string {
+	return ""
+}
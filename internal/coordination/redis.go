@@ -0,0 +1,251 @@
+// internal/coordination/redis.go
+package coordination
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisPubSub publishes to and subscribes to Redis channels with the RESP protocol.
+// Publishing uses one connection, reopened after errors; each Subscribe call uses a
+// connection of its own, as Redis requires.
+type RedisPubSub struct {
+	Addr     string      // host:port
+	TLS      *tls.Config // Nil for plaintext
+	Username string      // Redis 6 ACL user; "" authenticates as default
+	Password string
+	Timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// ParseRedisURL builds a client from redis://[user:password@]host[:port] or rediss://
+// for TLS.
+func ParseRedisURL(raw string) (*RedisPubSub, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := &RedisPubSub{Timeout: 10 * time.Second}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		client.TLS = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("invalid Redis URL scheme '%s' (use redis or rediss)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("invalid Redis URL: missing host")
+	}
+	client.Addr = u.Host
+	if u.Port() == "" {
+		client.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.Password, _ = u.User.Password()
+		client.Username = u.User.Username()
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			// redis://secret@host is the password alone
+			client.Username, client.Password = "", u.User.Username()
+		}
+	}
+	return client, nil
+}
+
+// Publish sends payload to channel and returns the number of subscribers that got it.
+func (r *RedisPubSub) Publish(ctx context.Context, channel string, payload []byte) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		conn, reader, err := r.dial(ctx)
+		if err != nil {
+			return 0, err
+		}
+		r.conn, r.r = conn, reader
+	}
+	_ = r.conn.SetDeadline(r.deadline(ctx))
+	receivers, err := r.command(r.conn, r.r, "PUBLISH", channel, string(payload))
+	if err != nil {
+		r.closeConn()
+		return 0, fmt.Errorf("redis: %w", err)
+	}
+	n, _ := receivers.(int64)
+	return n, nil
+}
+
+// Subscribe calls handle with every message published to channel until ctx is done or
+// the connection fails. It returns nil only when ctx is done.
+func (r *RedisPubSub) Subscribe(ctx context.Context, channel string, handle func(payload []byte)) error {
+	conn, reader, err := r.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	_ = conn.SetDeadline(r.deadline(ctx))
+	if err := writeCommand(conn, "SUBSCRIBE", channel); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("redis: %w", err)
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) < 3 {
+			continue
+		}
+		if kind, _ := parts[0].(string); kind == "message" {
+			if payload, ok := parts[2].(string); ok {
+				handle([]byte(payload))
+			}
+		}
+		// Subscription confirmations need no answer
+	}
+}
+
+// Close closes the publishing connection.
+func (r *RedisPubSub) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeConn()
+	return nil
+}
+
+func (r *RedisPubSub) closeConn() {
+	if r.conn != nil {
+		_ = r.conn.Close()
+		r.conn, r.r = nil, nil
+	}
+}
+
+func (r *RedisPubSub) timeout() time.Duration {
+	if r.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return r.Timeout
+}
+
+func (r *RedisPubSub) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(r.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return deadline
+}
+
+// dial connects and authenticates.
+func (r *RedisPubSub) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	conn, err := (&net.Dialer{Timeout: r.timeout()}).DialContext(ctx, "tcp", r.Addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("redis: %w", err)
+	}
+	if r.TLS != nil {
+		tlsConn := tls.Client(conn, r.TLS.Clone())
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis: %s: TLS handshake: %w", r.Addr, err)
+		}
+		conn = tlsConn
+	}
+	reader := bufio.NewReader(conn)
+	if r.Password != "" {
+		_ = conn.SetDeadline(r.deadline(ctx))
+		args := []string{"AUTH", r.Password}
+		if r.Username != "" {
+			args = []string{"AUTH", r.Username, r.Password}
+		}
+		if _, err := r.command(conn, reader, args...); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis: %s: %w", r.Addr, err)
+		}
+	}
+	return conn, reader, nil
+}
+
+// command sends one command and reads its reply.
+func (r *RedisPubSub) command(conn net.Conn, reader *bufio.Reader, args ...string) (any, error) {
+	if err := writeCommand(conn, args...); err != nil {
+		return nil, err
+	}
+	return readReply(reader)
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string { return "server error: " + string(e) }
+
+// writeCommand writes args as a RESP array of bulk strings.
+func writeCommand(w io.Writer, args ...string) error {
+	var b strings.Builder
+	b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		b.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// readReply reads one RESP reply: a string, int64, nil, []any or a redisError, which is
+// returned as the error.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,115 @@
+// internal/core/column_rules.go
+package core
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Formats a column rule can require of text values.
+const (
+	FormatEmail = "email"
+	FormatURL   = "url"
+)
+
+// maxRulePatternLength bounds user-supplied regular expressions.
+const maxRulePatternLength = 512
+
+// FieldError describes why one field of a record failed its column rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"` // "pattern", "format", "min" or "max"
+	Message string `json:"message"`
+}
+
+// ValidateColumnRules checks rules before they are stored: each names an existing
+// column (columnTypes maps column name to type) at most once, patterns compile,
+// formats are known and min does not exceed max.
+func ValidateColumnRules(rules []domain.ColumnRule, columnTypes map[string]string) error {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if _, ok := columnTypes[rule.Column]; !ok {
+			return fmt.Errorf("rule for unknown column '%s'", rule.Column)
+		}
+		if seen[rule.Column] {
+			return fmt.Errorf("more than one rule for column '%s'", rule.Column)
+		}
+		seen[rule.Column] = true
+
+		if len(rule.Pattern) > maxRulePatternLength {
+			return fmt.Errorf("pattern for column '%s' is longer than %d characters", rule.Column, maxRulePatternLength)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern for column '%s': %v", rule.Column, err)
+		}
+		if rule.Format != "" && rule.Format != FormatEmail && rule.Format != FormatURL {
+			return fmt.Errorf("invalid format '%s' for column '%s' (use email or url)", rule.Format, rule.Column)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return fmt.Errorf("min is greater than max for column '%s'", rule.Column)
+		}
+	}
+	return nil
+}
+
+// CheckColumnRules returns the fields of record that break their column's rule. Only
+// fields present in record are checked, so partial updates validate what they change;
+// null values pass. Rules are assumed to have passed ValidateColumnRules.
+func CheckColumnRules(rules []domain.ColumnRule, record map[string]any) []FieldError {
+	var errs []FieldError
+	for _, rule := range rules {
+		value, ok := record[rule.Column]
+		if !ok || value == nil {
+			continue
+		}
+		if fe := checkColumnRule(rule, value); fe != nil {
+			errs = append(errs, *fe)
+		}
+	}
+	return errs
+}
+
+func checkColumnRule(rule domain.ColumnRule, value any) *FieldError {
+	fail := func(kind, format string, args ...any) *FieldError {
+		return &FieldError{Field: rule.Column, Rule: kind, Message: fmt.Sprintf(format, args...)}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if rule.Pattern != "" && !regexp.MustCompile(rule.Pattern).MatchString(v) {
+			return fail("pattern", "must match %s", rule.Pattern)
+		}
+		switch rule.Format {
+		case FormatEmail:
+			if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+				return fail("format", "must be an email address")
+			}
+		case FormatURL:
+			if u, err := url.ParseRequestURI(v); err != nil || u.Scheme == "" || u.Host == "" {
+				return fail("format", "must be an absolute URL")
+			}
+		}
+		length := float64(utf8.RuneCountInString(v))
+		if rule.Min != nil && length < *rule.Min {
+			return fail("min", "must be at least %g characters long", *rule.Min)
+		}
+		if rule.Max != nil && length > *rule.Max {
+			return fail("max", "must be at most %g characters long", *rule.Max)
+		}
+	case int64: // Integers of imports and GraphQL literals
+		return checkColumnRule(rule, float64(v))
+	case float64:
+		if rule.Min != nil && v < *rule.Min {
+			return fail("min", "must be at least %g", *rule.Min)
+		}
+		if rule.Max != nil && v > *rule.Max {
+			return fail("max", "must be at most %g", *rule.Max)
+		}
+	}
+	return nil
+}
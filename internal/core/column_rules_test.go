@@ -0,0 +1,66 @@
+// internal/core/column_rules_test.go
+package core
+
+import (
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestValidateColumnRules(t *testing.T) {
+	columns := map[string]string{"email": "TEXT", "age": "INTEGER"}
+	one, two := 1.0, 2.0
+
+	testCases := []struct {
+		name    string
+		rules   []domain.ColumnRule
+		wantErr bool
+	}{
+		{"valid", []domain.ColumnRule{{Column: "email", Format: FormatEmail}, {Column: "age", Min: &one, Max: &two}}, false},
+		{"unknown column", []domain.ColumnRule{{Column: "name"}}, true},
+		{"duplicate column", []domain.ColumnRule{{Column: "age"}, {Column: "age"}}, true},
+		{"bad pattern", []domain.ColumnRule{{Column: "email", Pattern: "("}}, true},
+		{"bad format", []domain.ColumnRule{{Column: "email", Format: "phone"}}, true},
+		{"min above max", []domain.ColumnRule{{Column: "age", Min: &two, Max: &one}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateColumnRules(tc.rules, columns); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateColumnRules() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckColumnRules(t *testing.T) {
+	three, ten := 3.0, 10.0
+	rules := []domain.ColumnRule{
+		{Column: "email", Format: FormatEmail},
+		{Column: "site", Format: FormatURL},
+		{Column: "username", Pattern: "^[a-z]+$", Min: &three},
+		{Column: "age", Max: &ten},
+	}
+
+	valid := map[string]any{"email": "ada@example.com", "site": "https://example.com", "username": "ada", "age": 9.0}
+	if errs := CheckColumnRules(rules, valid); len(errs) != 0 {
+		t.Errorf("CheckColumnRules(valid) = %+v, want none", errs)
+	}
+	if errs := CheckColumnRules(rules, map[string]any{"age": int64(11)}); len(errs) != 1 || errs[0].Rule != "max" {
+		t.Errorf("CheckColumnRules(int64) = %+v, want a max error", errs)
+	}
+	if errs := CheckColumnRules(rules, map[string]any{"email": nil}); len(errs) != 0 {
+		t.Errorf("CheckColumnRules(null) = %+v, want none", errs)
+	}
+
+	invalid := map[string]any{"email": "Ada <ada@example.com>", "site": "example.com", "username": "ab", "age": 11.0}
+	errs := CheckColumnRules(rules, invalid)
+	want := map[string]string{"email": "format", "site": "format", "username": "min", "age": "max"}
+	if len(errs) != len(want) {
+		t.Fatalf("CheckColumnRules(invalid) = %+v, want %d errors", errs, len(want))
+	}
+	for _, fe := range errs {
+		if want[fe.Field] != fe.Rule {
+			t.Errorf("field %s failed rule %q, want %q", fe.Field, fe.Rule, want[fe.Field])
+		}
+	}
+}
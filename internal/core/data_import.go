@@ -0,0 +1,312 @@
+// internal/core/data_import.go
+package core
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrTooManyImportRows is returned when uploaded data holds more rows than allowed.
+var ErrTooManyImportRows = errors.New("too many rows to import")
+
+// ImportData is tabular data parsed from an uploaded CSV or JSON document.
+type ImportData struct {
+	Fields []string         // Field names in order of first appearance
+	Rows   []map[string]any // Keyed by field; absent fields are nil
+	Text   bool             // Values are unparsed CSV strings
+}
+
+// ImportColumn maps a field of imported data onto a table column. Fields with an empty
+// Name (the built-in id and created_at columns) are not imported.
+type ImportColumn struct {
+	Field string `json:"field"`
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+// ParseCSVData reads a CSV document whose first line names the fields. Empty values
+// are read as NULL.
+func ParseCSVData(r io.Reader, maxRows int) (*ImportData, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Short rows are padded with NULLs below
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV data is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Byte order mark of spreadsheet exports
+	}
+
+	data := &ImportData{Text: true}
+	for _, field := range header {
+		data.Fields = append(data.Fields, strings.TrimSpace(field))
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(record) > len(header) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("invalid CSV: line %d has %d values but the header names %d fields", line, len(record), len(header))
+		}
+		if len(data.Rows) == maxRows {
+			return nil, fmt.Errorf("%w: at most %d are allowed", ErrTooManyImportRows, maxRows)
+		}
+		row := make(map[string]any, len(header))
+		for i, field := range data.Fields {
+			if i < len(record) && record[i] != "" {
+				row[field] = record[i]
+			} else {
+				row[field] = nil
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data, nil
+}
+
+// ParseJSONData reads a JSON array of objects. Field order follows the first object
+// each field appears in.
+func ParseJSONData(r io.Reader, maxRows int) (*ImportData, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("invalid JSON: expected an array of objects")
+	}
+
+	data := &ImportData{}
+	seen := make(map[string]bool)
+	for decoder.More() {
+		if len(data.Rows) == maxRows {
+			return nil, fmt.Errorf("%w: at most %d are allowed", ErrTooManyImportRows, maxRows)
+		}
+		if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+			return nil, fmt.Errorf("invalid JSON: element %d is not an object", len(data.Rows)+1)
+		}
+		row := make(map[string]any)
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			field := token.(string) // Object keys are always strings
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			if number, ok := value.(json.Number); ok {
+				if value, err = numberValue(number); err != nil {
+					return nil, fmt.Errorf("invalid JSON: %w", err)
+				}
+			}
+			row[field] = value
+			if !seen[field] {
+				seen[field] = true
+				data.Fields = append(data.Fields, field)
+			}
+		}
+		if _, err := decoder.Token(); err != nil { // Closing brace
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	if _, err := decoder.Token(); err != nil { // Closing bracket
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return data, nil
+}
+
+// numberValue converts a JSON number to int64 when it is a whole number that fits,
+// and to float64 otherwise.
+func numberValue(number json.Number) (any, error) {
+	if n, err := number.Int64(); err == nil {
+		return n, nil
+	}
+	return number.Float64()
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// InferImportColumns derives table columns from imported data: field names are turned
+// into lowercase identifiers and each column gets the narrowest type holding all of
+// its values (INTEGER, REAL, BOOLEAN, else TEXT). CSV values are parsed to find their
+// type; numbers with leading zeros stay TEXT so codes like "007" are kept as written.
+func InferImportColumns(data *ImportData) []ImportColumn {
+	columns := make([]ImportColumn, 0, len(data.Fields))
+	used := make(map[string]bool)
+	for i, field := range data.Fields {
+		name := strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(field), "_"), "_")
+		if name == "id" || name == "created_at" {
+			columns = append(columns, ImportColumn{Field: field})
+			continue
+		}
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		if len(name) > 56 {
+			name = name[:56]
+		}
+		if strings.HasPrefix(name, TrashedTablePrefix) {
+			name = "col" + name
+		}
+		for base, n := name, 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[name] = true
+
+		kinds := make(map[string]bool)
+		for _, row := range data.Rows {
+			if kind := importValueKind(row[field], data.Text); kind != "" {
+				kinds[kind] = true
+			}
+		}
+		columns = append(columns, ImportColumn{Field: field, Name: name, Type: inferredType(kinds)})
+	}
+	return columns
+}
+
+// importValueKind classifies an imported value as a profiler value kind, or "" for
+// NULL. text tells that value is an unparsed CSV string.
+func importValueKind(value any, text bool) string {
+	if value == nil {
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok || !text {
+		return ValueKind(value)
+	}
+	if _, err := parseImportInteger(s); err == nil {
+		return ValueKindInteger
+	}
+	if _, err := parseImportReal(s); err == nil {
+		return ValueKindReal
+	}
+	if _, err := parseImportBoolean(s); err == nil {
+		return ValueKindBoolean
+	}
+	return ValueKindText
+}
+
+// inferredType picks the column type holding values of every kind seen.
+func inferredType(kinds map[string]bool) string {
+	switch {
+	case len(kinds) == 1 && kinds[ValueKindInteger]:
+		return "INTEGER"
+	case len(kinds) == 1 && kinds[ValueKindBoolean]:
+		return "BOOLEAN"
+	case len(kinds) == 1 && kinds[ValueKindReal], len(kinds) == 2 && kinds[ValueKindInteger] && kinds[ValueKindReal]:
+		return "REAL"
+	}
+	return "TEXT"
+}
+
+// InferValueType returns the column type inferred for a decoded JSON value (numbers
+// are float64): whole numbers are INTEGER, other numbers REAL, booleans BOOLEAN and
+// anything else, including null, TEXT.
+func InferValueType(value any) string {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+		return "INTEGER"
+	}
+	kinds := make(map[string]bool)
+	if kind := importValueKind(value, false); kind != "" {
+		kinds[kind] = true
+	}
+	return inferredType(kinds)
+}
+
+// ImportRecords converts the rows of data into records for the named columns. CSV
+// values are parsed according to the column types and numbers and booleans bound for
+// TEXT columns are written as text; errors name the offending row (counted from 1) and
+// field. Nested JSON values are kept for EncodeJSONValues, and other type mismatches
+// are left for RecordAssignments to report.
+func ImportRecords(data *ImportData, columns []ImportColumn) ([]map[string]any, error) {
+	records := make([]map[string]any, len(data.Rows))
+	for i, row := range data.Rows {
+		record := make(map[string]any, len(columns))
+		for _, col := range columns {
+			if col.Name == "" {
+				continue
+			}
+			value, err := importValue(row[col.Field], col.Type, data.Text)
+			if err != nil {
+				return nil, fmt.Errorf("row %d, field '%s': %w", i+1, col.Field, err)
+			}
+			record[col.Name] = value
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// importValue converts one imported value for a column of columnType.
+func importValue(value any, columnType string, text bool) (any, error) {
+	if s, ok := value.(string); ok && text {
+		switch columnType {
+		case "INTEGER":
+			return parseImportInteger(s)
+		case "REAL":
+			return parseImportReal(s)
+		case "BOOLEAN":
+			return parseImportBoolean(s)
+		}
+		return s, nil
+	}
+	switch value.(type) {
+	case int64, float64, bool:
+		if columnType == "TEXT" {
+			return fmt.Sprint(value), nil
+		}
+	}
+	if n, ok := value.(int64); ok {
+		return float64(n), nil // Decoded JSON numbers are float64 everywhere else
+	}
+	return value, nil
+}
+
+func parseImportInteger(s string) (float64, error) {
+	digits := strings.TrimPrefix(s, "-")
+	if len(digits) > 1 && digits[0] == '0' {
+		return 0, fmt.Errorf("'%s' is not an integer", s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n > 1<<53 || n < -(1<<53) { // Must survive the float64 of decoded JSON
+		return 0, fmt.Errorf("'%s' is not an integer", s)
+	}
+	return float64(n), nil
+}
+
+func parseImportReal(s string) (float64, error) {
+	digits := strings.TrimPrefix(s, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) || strings.ContainsAny(s, "xXpP_") {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+	return f, nil
+}
+
+func parseImportBoolean(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("'%s' is not a boolean", s)
+}
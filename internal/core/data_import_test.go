@@ -0,0 +1,101 @@
+// internal/core/data_import_test.go
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInferImportColumnsCSV(t *testing.T) {
+	csv := "\ufeffID,Full Name,Age,Score,Active,Zip,Notes,name\n" +
+		"1,Ann,30,4.5,true,0750,,x\n" +
+		"2,Bob,41,3,FALSE,1000,hi,y\n" +
+		"3,Cid,,2.25,true,2000\n"
+	data, err := ParseCSVData(strings.NewReader(csv), 10)
+	if err != nil {
+		t.Fatalf("ParseCSVData() error = %v", err)
+	}
+
+	columns := InferImportColumns(data)
+	want := []ImportColumn{
+		{Field: "ID"},
+		{Field: "Full Name", Name: "full_name", Type: "TEXT"},
+		{Field: "Age", Name: "age", Type: "INTEGER"},
+		{Field: "Score", Name: "score", Type: "REAL"},
+		{Field: "Active", Name: "active", Type: "BOOLEAN"},
+		{Field: "Zip", Name: "zip", Type: "TEXT"},
+		{Field: "Notes", Name: "notes", Type: "TEXT"},
+		{Field: "name", Name: "name", Type: "TEXT"},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("InferImportColumns() = %v, want %v", columns, want)
+	}
+	for i := range want {
+		if columns[i] != want[i] {
+			t.Errorf("column %d = %+v, want %+v", i, columns[i], want[i])
+		}
+	}
+
+	records, err := ImportRecords(data, columns)
+	if err != nil {
+		t.Fatalf("ImportRecords() error = %v", err)
+	}
+	if r := records[1]; r["age"] != float64(41) || r["score"] != float64(3) || r["active"] != false || r["zip"] != "1000" || r["notes"] != "hi" {
+		t.Errorf("unexpected record: %v", r)
+	}
+	if r := records[2]; r["age"] != nil || r["notes"] != nil || r["name"] != nil {
+		t.Errorf("missing values should be nil: %v", r)
+	}
+	if _, ok := records[0]["id"]; ok {
+		t.Errorf("id should not be imported: %v", records[0])
+	}
+}
+
+func TestInferImportColumnsJSON(t *testing.T) {
+	doc := `[{"name": "Ann", "age": 30, "tags": ["a"], "mixed": 1},
+		{"age": 31.5, "name": "Bob", "mixed": true, "extra": null}]`
+	data, err := ParseJSONData(strings.NewReader(doc), 10)
+	if err != nil {
+		t.Fatalf("ParseJSONData() error = %v", err)
+	}
+	if got := strings.Join(data.Fields, ","); got != "name,age,tags,mixed,extra" {
+		t.Errorf("fields = %s, want first-appearance order", got)
+	}
+
+	columns := InferImportColumns(data)
+	types := make(map[string]string)
+	for _, col := range columns {
+		types[col.Name] = col.Type
+	}
+	if types["name"] != "TEXT" || types["age"] != "REAL" || types["tags"] != "TEXT" || types["mixed"] != "TEXT" || types["extra"] != "TEXT" {
+		t.Errorf("unexpected inferred types: %v", types)
+	}
+
+	records, err := ImportRecords(data, columns)
+	if err != nil {
+		t.Fatalf("ImportRecords() error = %v", err)
+	}
+	if records[0]["age"] != float64(30) || records[0]["mixed"] != "1" || records[1]["mixed"] != "true" {
+		t.Errorf("unexpected records: %v", records)
+	}
+}
+
+func TestParseImportDataErrors(t *testing.T) {
+	if _, err := ParseCSVData(strings.NewReader("a\n1\n2\n3\n"), 2); !errors.Is(err, ErrTooManyImportRows) {
+		t.Errorf("ParseCSVData() over the row limit: error = %v", err)
+	}
+	if _, err := ParseCSVData(strings.NewReader("a\n1,2\n"), 10); err == nil {
+		t.Error("ParseCSVData() accepted a row longer than the header")
+	}
+	for _, doc := range []string{`{"a": 1}`, `[1, 2]`, `[{"a": 1}`} {
+		if _, err := ParseJSONData(strings.NewReader(doc), 10); err == nil {
+			t.Errorf("ParseJSONData(%s) succeeded", doc)
+		}
+	}
+
+	data, _ := ParseCSVData(strings.NewReader("n\nabc\n"), 10)
+	if _, err := ImportRecords(data, []ImportColumn{{Field: "n", Name: "n", Type: "INTEGER"}}); err == nil || !strings.Contains(err.Error(), "row 1") {
+		t.Errorf("ImportRecords() error = %v, want a row 1 error", err)
+	}
+}
@@ -0,0 +1,46 @@
+// internal/core/form_values.go
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FormRecord converts the fields of an HTML form post into a record, parsing each value
+// according to the type of its column: empty values of non-TEXT columns are NULL and
+// BOOLEAN columns also accept the "on" of checkboxes. Fields of unknown columns are kept
+// as text for RecordAssignments to report.
+func FormRecord(values url.Values, columnTypes map[string]string) (map[string]any, error) {
+	record := make(map[string]any, len(values))
+	for field, vs := range values {
+		value := vs[0]
+		columnType := columnTypes[strings.ToLower(field)]
+		if value == "" && columnType != "TEXT" && columnType != "" {
+			record[field] = nil
+			continue
+		}
+		var parsed any
+		var err error
+		switch columnType {
+		case "BOOLEAN":
+			switch strings.ToLower(value) {
+			case "on", "1":
+				parsed = true
+			case "off", "0":
+				parsed = false
+			default:
+				parsed, err = parseImportBoolean(value)
+			}
+		case "":
+			parsed = value
+		default:
+			parsed, err = importValue(value, columnType, true)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", field, err)
+		}
+		record[field] = parsed
+	}
+	return record, nil
+}
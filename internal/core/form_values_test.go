@@ -0,0 +1,25 @@
+// internal/core/form_values_test.go
+package core
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFormRecord(t *testing.T) {
+	columnTypes := map[string]string{"name": "TEXT", "age": "INTEGER", "score": "REAL", "subscribe": "BOOLEAN"}
+	values := url.Values{"name": {"Ada"}, "age": {"36"}, "score": {""}, "subscribe": {"on"}, "captcha_token": {"t"}}
+	record, err := FormRecord(values, columnTypes)
+	if err != nil {
+		t.Fatalf("FormRecord() error = %v", err)
+	}
+	want := map[string]any{"name": "Ada", "age": float64(36), "score": nil, "subscribe": true, "captcha_token": "t"}
+	if !reflect.DeepEqual(record, want) {
+		t.Errorf("FormRecord() = %v, want %v", record, want)
+	}
+
+	if _, err := FormRecord(url.Values{"age": {"old"}}, columnTypes); err == nil {
+		t.Error("FormRecord() accepted a non-integer for an INTEGER column")
+	}
+}
@@ -0,0 +1,51 @@
+// internal/core/labels.go
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxDatabaseLabels caps the labels of one database.
+const MaxDatabaseLabels = 32
+
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+	labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,63}$`)
+)
+
+// ValidateLabels checks the key/value labels of a database: keys are lowercase letters,
+// digits, '_', '.' and '-' starting with a letter or digit, values are non-empty and
+// may also use uppercase letters, both at most 63 characters.
+func ValidateLabels(labels map[string]string) error {
+	if len(labels) > MaxDatabaseLabels {
+		return fmt.Errorf("too many labels: at most %d are allowed", MaxDatabaseLabels)
+	}
+	for key, value := range labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key '%s': use up to 63 lowercase letters, digits, '_', '.' or '-'", key)
+		}
+		if !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value '%s' for label '%s': use 1 to 63 letters, digits, '_', '.' or '-'", value, key)
+		}
+	}
+	return nil
+}
+
+// LabelSelector matches databases carrying the label Key, with the value Value unless
+// it is empty.
+type LabelSelector struct {
+	Key   string
+	Value string
+}
+
+// ParseLabelSelector parses a label filter: "key" matches any value of the label,
+// "key:value" only that value.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	key, value, hasValue := strings.Cut(selector, ":")
+	if !labelKeyPattern.MatchString(key) || (hasValue && !labelValuePattern.MatchString(value)) {
+		return LabelSelector{}, fmt.Errorf("invalid label filter '%s': use 'key' or 'key:value'", selector)
+	}
+	return LabelSelector{Key: key, Value: value}, nil
+}
@@ -0,0 +1,41 @@
+// internal/core/labels_test.go
+package core
+
+import "testing"
+
+func TestValidateLabels(t *testing.T) {
+	if err := ValidateLabels(map[string]string{"env": "prod", "team.name": "Billing-EU", "tier_2": "1"}); err != nil {
+		t.Errorf("ValidateLabels() rejected valid labels: %v", err)
+	}
+	for _, labels := range []map[string]string{
+		{"Env": "prod"},
+		{"-env": "prod"},
+		{"env": ""},
+		{"env": "prod east"},
+		{"env:x": "prod"},
+	} {
+		if err := ValidateLabels(labels); err == nil {
+			t.Errorf("ValidateLabels(%v) succeeded", labels)
+		}
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     LabelSelector
+		wantErr  bool
+	}{
+		{"env", LabelSelector{Key: "env"}, false},
+		{"env:prod", LabelSelector{Key: "env", Value: "prod"}, false},
+		{"env:", LabelSelector{}, true},
+		{":prod", LabelSelector{}, true},
+		{"env:prod:eu", LabelSelector{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLabelSelector(tt.selector)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLabelSelector(%q) = %+v, %v; want %+v, error %v", tt.selector, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// internal/core/profile.go
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Kinds of values seen by the profiler.
+const (
+	ValueKindInteger   = "integer"
+	ValueKindReal      = "real"
+	ValueKindBoolean   = "boolean"
+	ValueKindText      = "text"
+	ValueKindBlob      = "blob"
+	ValueKindTimestamp = "timestamp"
+)
+
+// ValueCount is a value and how many sampled rows hold it.
+type ValueCount struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// ColumnProfile summarizes the values of one column over a sample of rows.
+type ColumnProfile struct {
+	Name            string         `json:"name"`
+	Type            string         `json:"type"`
+	NonNull         int            `json:"non_null"`
+	FillRate        float64        `json:"fill_rate"`        // Share of rows with a value
+	TypeConsistency float64        `json:"type_consistency"` // Share of values matching Type
+	Distinct        int            `json:"distinct"`
+	ValueKinds      map[string]int `json:"value_kinds"`
+	TopValues       []ValueCount   `json:"top_values"`
+}
+
+type columnStats struct {
+	nonNull    int
+	consistent int
+	kinds      map[string]int
+	counts     map[string]*ValueCount
+}
+
+// Profiler accumulates per-column statistics of the records passed to Add. Values
+// are those scanned from user databases, where SQLite does not enforce column types,
+// so the kinds actually stored can differ from the declared type.
+type Profiler struct {
+	columnTypes map[string]string
+	rows        int
+	stats       map[string]*columnStats
+}
+
+// NewProfiler returns a profiler for a table with the given column types.
+func NewProfiler(columnTypes map[string]string) *Profiler {
+	p := &Profiler{columnTypes: columnTypes, stats: make(map[string]*columnStats, len(columnTypes))}
+	for name := range columnTypes {
+		p.stats[name] = &columnStats{kinds: make(map[string]int), counts: make(map[string]*ValueCount)}
+	}
+	return p
+}
+
+// Add counts one record.
+func (p *Profiler) Add(record map[string]any) {
+	p.rows++
+	for name, stats := range p.stats {
+		value := record[name]
+		if value == nil {
+			continue
+		}
+		stats.nonNull++
+		kind := ValueKind(value)
+		stats.kinds[kind]++
+		if kindMatchesType(kind, value, p.columnTypes[name]) {
+			stats.consistent++
+		}
+		key := kind + "\x00" + fmt.Sprint(value)
+		if count, ok := stats.counts[key]; ok {
+			count.Count++
+		} else {
+			stats.counts[key] = &ValueCount{Value: value, Count: 1}
+		}
+	}
+}
+
+// Rows returns the number of records added.
+func (p *Profiler) Rows() int {
+	return p.rows
+}
+
+// Profiles returns the statistics of every column, ordered by name, with up to topN
+// of the most frequent values of each. Values seen only once are not listed.
+func (p *Profiler) Profiles(topN int) []ColumnProfile {
+	names := make([]string, 0, len(p.stats))
+	for name := range p.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := make([]ColumnProfile, 0, len(names))
+	for _, name := range names {
+		stats := p.stats[name]
+		profile := ColumnProfile{
+			Name:       name,
+			Type:       p.columnTypes[name],
+			NonNull:    stats.nonNull,
+			Distinct:   len(stats.counts),
+			ValueKinds: stats.kinds,
+			TopValues:  make([]ValueCount, 0, topN),
+		}
+		if p.rows > 0 {
+			profile.FillRate = ratio(stats.nonNull, p.rows)
+		}
+		if stats.nonNull > 0 {
+			profile.TypeConsistency = ratio(stats.consistent, stats.nonNull)
+		}
+
+		top := make([]*ValueCount, 0, len(stats.counts))
+		for _, count := range stats.counts {
+			if count.Count > 1 {
+				top = append(top, count)
+			}
+		}
+		sort.Slice(top, func(i, j int) bool {
+			if top[i].Count != top[j].Count {
+				return top[i].Count > top[j].Count
+			}
+			return fmt.Sprint(top[i].Value) < fmt.Sprint(top[j].Value)
+		})
+		for i := 0; i < len(top) && i < topN; i++ {
+			profile.TopValues = append(profile.TopValues, *top[i])
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// ratio returns part/total rounded to four decimals.
+func ratio(part, total int) float64 {
+	return math.Round(float64(part)/float64(total)*10000) / 10000
+}
+
+// ValueKind classifies a value scanned from a user database.
+func ValueKind(value any) string {
+	switch value.(type) {
+	case int64, int, int32:
+		return ValueKindInteger
+	case float64:
+		return ValueKindReal
+	case bool:
+		return ValueKindBoolean
+	case []byte:
+		return ValueKindBlob
+	case time.Time:
+		return ValueKindTimestamp
+	}
+	return ValueKindText
+}
+
+// kindMatchesType reports whether a value of kind is what a column of the (normalized)
+// declared type should hold. Blobs are read back as strings, so BLOB accepts text.
+func kindMatchesType(kind string, value any, declaredType string) bool {
+	switch declaredType {
+	case "INTEGER":
+		return kind == ValueKindInteger || kind == ValueKindBoolean
+	case "REAL":
+		return kind == ValueKindReal || kind == ValueKindInteger
+	case "BOOLEAN":
+		if n, ok := value.(int64); ok {
+			return n == 0 || n == 1
+		}
+		return kind == ValueKindBoolean
+	case "TEXT":
+		return kind == ValueKindText
+	case "BLOB":
+		return kind == ValueKindBlob || kind == ValueKindText
+	case "TIMESTAMP":
+		return kind == ValueKindTimestamp || kind == ValueKindText
+	}
+	return true
+}
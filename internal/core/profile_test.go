@@ -0,0 +1,35 @@
+// internal/core/profile_test.go
+package core
+
+import "testing"
+
+func TestProfiler(t *testing.T) {
+	p := NewProfiler(map[string]string{"age": "INTEGER", "city": "TEXT"})
+	records := []map[string]any{
+		{"age": int64(30), "city": "Paris"},
+		{"age": int64(30), "city": "Paris"},
+		{"age": "thirty", "city": "Lyon"},
+		{"age": nil, "city": "Paris"},
+	}
+	for _, record := range records {
+		p.Add(record)
+	}
+
+	profiles := p.Profiles(5)
+	if p.Rows() != 4 || len(profiles) != 2 {
+		t.Fatalf("got %d rows and %d profiles, want 4 and 2", p.Rows(), len(profiles))
+	}
+	age, city := profiles[0], profiles[1]
+	if age.Name != "age" || age.NonNull != 3 || age.FillRate != 0.75 || age.TypeConsistency != 0.6667 || age.Distinct != 2 {
+		t.Errorf("unexpected age profile: %+v", age)
+	}
+	if age.ValueKinds[ValueKindText] != 1 || age.ValueKinds[ValueKindInteger] != 2 {
+		t.Errorf("unexpected age value kinds: %v", age.ValueKinds)
+	}
+	if len(city.TopValues) != 1 || city.TopValues[0].Value != "Paris" || city.TopValues[0].Count != 3 {
+		t.Errorf("unexpected city top values: %v", city.TopValues)
+	}
+	if city.TypeConsistency != 1 || city.FillRate != 1 {
+		t.Errorf("unexpected city profile: %+v", city)
+	}
+}
@@ -6,23 +6,39 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Default and limit constants for pagination
 const (
-	DefaultLimit = 100
-	MaxLimit     = 1000
-	DefaultOrder = "asc"
+	DefaultLimit   = 100
+	MaxLimit       = 1000
+	MaxStreamLimit = 100000 // Streamed responses are not buffered, so allow much larger pages
+	DefaultOrder   = "asc"
+)
+
+// Streaming formats for list responses
+const (
+	StreamJSON   = "json"   // One JSON document, written row by row
+	StreamNDJSON = "ndjson" // One JSON record per line
+	StreamXLSX   = "xlsx"   // An Excel workbook with a single sheet
 )
 
 // ReservedParams contains query parameter names reserved for pagination, sorting, and field selection.
 // These should not be treated as column filters.
 var ReservedParams = map[string]bool{
-	"limit":  true,
-	"offset": true,
-	"sort":   true,
-	"order":  true,
-	"fields": true,
+	"limit":            true,
+	"offset":           true,
+	"sort":             true,
+	"order":            true,
+	"fields":           true,
+	"stream":           true,
+	"case_insensitive": true,
+
+	// Signed URL parameters (see auth.SignURL)
+	"x-nebula-user":      true,
+	"x-nebula-expires":   true,
+	"x-nebula-signature": true,
 }
 
 // ListQueryOptions holds parsed query parameters for ListRecords
@@ -37,6 +53,42 @@ type ListQueryOptions struct {
 
 	// Field Selection
 	Fields []string // Columns to return (empty = all columns)
+
+	// Streaming ("" = buffered response, otherwise StreamJSON, StreamNDJSON or StreamXLSX)
+	Stream string
+
+	// Filtering
+	CaseInsensitive bool // Match TEXT filters without regard to letter case
+}
+
+// ParsePagination reads the limit (DefaultLimit when absent, at most maxLimit) and
+// offset query parameters of a list request.
+func ParsePagination(queryParams url.Values, maxLimit int) (int, int, error) {
+	limit, offset := DefaultLimit, 0
+	if limitStr := queryParams.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid 'limit' parameter: must be an integer")
+		}
+		if limit < 1 {
+			return 0, 0, fmt.Errorf("invalid 'limit' parameter: must be at least 1")
+		}
+		if limit > maxLimit {
+			return 0, 0, fmt.Errorf("invalid 'limit' parameter: maximum is %d", maxLimit)
+		}
+	}
+	if offsetStr := queryParams.Get("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid 'offset' parameter: must be an integer")
+		}
+		if offset < 0 {
+			return 0, 0, fmt.Errorf("invalid 'offset' parameter: must be non-negative")
+		}
+	}
+	return limit, offset, nil
 }
 
 // ParseListQueryOptions extracts pagination, sorting, and field selection options from query parameters.
@@ -50,31 +102,24 @@ func ParseListQueryOptions(queryParams url.Values) (*ListQueryOptions, error) {
 		Fields:    nil,
 	}
 
-	// Parse limit
-	if limitStr := queryParams.Get("limit"); limitStr != "" {
-		limit, err := strconv.Atoi(limitStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid 'limit' parameter: must be an integer")
-		}
-		if limit < 1 {
-			return nil, fmt.Errorf("invalid 'limit' parameter: must be at least 1")
-		}
-		if limit > MaxLimit {
-			return nil, fmt.Errorf("invalid 'limit' parameter: maximum is %d", MaxLimit)
+	// Parse stream format first; it raises the limit ceiling
+	maxLimit := MaxLimit
+	if stream := strings.ToLower(queryParams.Get("stream")); stream != "" {
+		switch stream {
+		case "true", StreamJSON:
+			opts.Stream = StreamJSON
+		case StreamNDJSON, StreamXLSX:
+			opts.Stream = stream
+		default:
+			return nil, fmt.Errorf("invalid 'stream' parameter: must be 'json', 'ndjson' or 'xlsx'")
 		}
-		opts.Limit = limit
+		maxLimit = MaxStreamLimit
 	}
 
-	// Parse offset
-	if offsetStr := queryParams.Get("offset"); offsetStr != "" {
-		offset, err := strconv.Atoi(offsetStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid 'offset' parameter: must be an integer")
-		}
-		if offset < 0 {
-			return nil, fmt.Errorf("invalid 'offset' parameter: must be non-negative")
-		}
-		opts.Offset = offset
+	// Parse limit and offset
+	var err error
+	if opts.Limit, opts.Offset, err = ParsePagination(queryParams, maxLimit); err != nil {
+		return nil, err
 	}
 
 	// Parse sort column
@@ -113,9 +158,74 @@ func ParseListQueryOptions(queryParams url.Values) (*ListQueryOptions, error) {
 		}
 	}
 
+	// Parse case_insensitive
+	if ciStr := queryParams.Get("case_insensitive"); ciStr != "" {
+		caseInsensitive, err := strconv.ParseBool(ciStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'case_insensitive' parameter: must be 'true' or 'false'")
+		}
+		opts.CaseInsensitive = caseInsensitive
+	}
+
 	return opts, nil
 }
 
+// Filter operators written as column[operator] in a filter key. A plain column name
+// filters on equality.
+const (
+	FilterEqual  = ""
+	FilterIs     = "is"     // Value FilterNull or FilterNotNull
+	FilterAfter  = "after"  // Timestamp columns; value parsed by ParseFilterTime
+	FilterBefore = "before" // Timestamp columns; value parsed by ParseFilterTime
+)
+
+// Values of the FilterIs operator.
+const (
+	FilterNull    = "null"
+	FilterNotNull = "not_null"
+)
+
+// ParseFilterKey splits a filter key such as "email" or "email[is]" into its column
+// name and operator, rejecting malformed keys and unknown operators.
+func ParseFilterKey(key string) (column, operator string, err error) {
+	column = key
+	if open := strings.IndexByte(key, '['); open >= 0 {
+		if !strings.HasSuffix(key, "]") {
+			return "", "", fmt.Errorf("invalid filter key format '%s'", key)
+		}
+		column, operator = key[:open], strings.ToLower(key[open+1:len(key)-1])
+		switch operator {
+		case FilterIs, FilterAfter, FilterBefore:
+		default:
+			return "", "", fmt.Errorf("unknown filter operator '%s' in '%s'", operator, key)
+		}
+	}
+	if !IsValidIdentifier(column) {
+		return "", "", fmt.Errorf("invalid filter key format '%s'", key)
+	}
+	return column, operator, nil
+}
+
+// filterTimeLayouts are the accepted forms of a date filter value, most specific first.
+var filterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseFilterTime parses the value of a date filter: an RFC 3339 timestamp, or a date
+// with an optional time of day taken as UTC. The result is in UTC.
+func ParseFilterTime(value string) (time.Time, error) {
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a date: use YYYY-MM-DD or an RFC 3339 timestamp", value)
+}
+
 // IsReservedParam checks if a query parameter name is reserved for pagination/sorting/fields.
 func IsReservedParam(key string) bool {
 	return ReservedParams[strings.ToLower(key)]
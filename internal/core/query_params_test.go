@@ -0,0 +1,46 @@
+// internal/core/query_params_test.go
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFilterKey(t *testing.T) {
+	for key, want := range map[string][2]string{
+		"email":      {"email", FilterEqual},
+		"email[is]":  {"email", FilterIs},
+		"Email[IS]":  {"Email", FilterIs},
+		"created_at": {"created_at", FilterEqual},
+		"day[after]": {"day", FilterAfter},
+	} {
+		column, operator, err := ParseFilterKey(key)
+		if err != nil || column != want[0] || operator != want[1] {
+			t.Errorf("ParseFilterKey(%q) = %q, %q, %v; want %q, %q", key, column, operator, err, want[0], want[1])
+		}
+	}
+	for _, key := range []string{"email[is", "email[like]", "[is]", "email[is]x", "bad-name[is]"} {
+		if _, _, err := ParseFilterKey(key); err == nil {
+			t.Errorf("ParseFilterKey(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func TestParseFilterTime(t *testing.T) {
+	for value, want := range map[string]string{
+		"2024-01-31":                "2024-01-31T00:00:00Z",
+		"2024-01-31 08:30:00":       "2024-01-31T08:30:00Z",
+		"2024-01-31T08:30":          "2024-01-31T08:30:00Z",
+		"2024-01-31T08:30:00+02:00": "2024-01-31T06:30:00Z",
+	} {
+		got, err := ParseFilterTime(value)
+		if err != nil || got.Format(time.RFC3339) != want {
+			t.Errorf("ParseFilterTime(%q) = %v, %v; want %s", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "yesterday", "2024-13-01", "01/31/2024"} {
+		if _, err := ParseFilterTime(value); err == nil {
+			t.Errorf("ParseFilterTime(%q) succeeded, want error", value)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// internal/core/saved_query.go
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// placeholderPattern matches {param} placeholders in saved query values.
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_]*)\}`)
+
+// pagingParams may be passed when running a saved query unless it fixes them itself.
+var pagingParams = []string{"limit", "offset", "stream"}
+
+// ValidateSavedQuery checks a saved query against the column types of its table: names
+// are identifiers, filters name existing columns, placeholders name declared parameters
+// and the list options parse once every placeholder is filled in.
+func ValidateSavedQuery(query domain.SavedQuery, columnTypes map[string]string) error {
+	if !IsValidIdentifier(query.Name) {
+		return fmt.Errorf("invalid query name '%s'", query.Name)
+	}
+	declared := make(map[string]bool, len(query.Params))
+	for _, param := range query.Params {
+		if !IsValidIdentifier(param.Name) {
+			return fmt.Errorf("invalid parameter name '%s'", param.Name)
+		}
+		if declared[param.Name] {
+			return fmt.Errorf("duplicate parameter '%s'", param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	for key, value := range query.Query {
+		if !ReservedParams[key] {
+			column, _, err := ParseFilterKey(key)
+			if err != nil {
+				return err
+			}
+			if _, ok := columnTypes[strings.ToLower(column)]; !ok && strings.ToLower(column) != "id" {
+				return fmt.Errorf("filter on unknown column '%s'", column)
+			}
+		}
+		for _, match := range placeholderPattern.FindAllStringSubmatch(value, -1) {
+			if !declared[match[1]] {
+				return fmt.Errorf("placeholder {%s} in '%s' is not a declared parameter", match[1], key)
+			}
+		}
+	}
+
+	// Check the options with a sample argument for every parameter
+	sample := make(url.Values)
+	for _, param := range query.Params {
+		sample.Set(param.Name, param.Default)
+		if param.Default == "" {
+			sample.Set(param.Name, "1")
+		}
+	}
+	values, err := BindSavedQuery(query, sample)
+	if err != nil {
+		return err
+	}
+	if _, err := ParseListQueryOptions(values); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BindSavedQuery fills the placeholders of a saved query with args and returns the list
+// parameters to run it with. Arguments that are neither declared parameters nor paging
+// parameters the query leaves open are rejected.
+func BindSavedQuery(query domain.SavedQuery, args url.Values) (url.Values, error) {
+	declared := make(map[string]domain.QueryParam, len(query.Params))
+	for _, param := range query.Params {
+		declared[param.Name] = param
+	}
+	values := make(url.Values, len(query.Query))
+	for _, key := range pagingParams {
+		if _, fixed := query.Query[key]; !fixed && args.Has(key) {
+			values.Set(key, args.Get(key))
+		}
+	}
+	for key := range args {
+		if _, ok := declared[key]; !ok && !values.Has(key) {
+			return nil, fmt.Errorf("unknown parameter '%s'", key)
+		}
+	}
+
+	resolved := make(map[string]string, len(query.Params))
+	for _, param := range query.Params {
+		switch {
+		case args.Has(param.Name):
+			resolved[param.Name] = args.Get(param.Name)
+		case param.Required:
+			return nil, fmt.Errorf("missing required parameter '%s'", param.Name)
+		default:
+			resolved[param.Name] = param.Default
+		}
+	}
+	for key, value := range query.Query {
+		values.Set(key, placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+			return resolved[match[1:len(match)-1]]
+		}))
+	}
+	return values, nil
+}
@@ -0,0 +1,51 @@
+// internal/core/saved_query_test.go
+package core
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestSavedQuery(t *testing.T) {
+	columns := map[string]string{"status": "TEXT", "owner": "TEXT"}
+	query := domain.SavedQuery{
+		Name:      "open_by_owner",
+		TableName: "tasks",
+		Query:     map[string]string{"status": "open", "owner": "{owner}", "sort": "{sort}", "order": "desc"},
+		Params:    []domain.QueryParam{{Name: "owner", Required: true}, {Name: "sort", Default: "created_at"}},
+	}
+	if err := ValidateSavedQuery(query, columns); err != nil {
+		t.Fatalf("ValidateSavedQuery: %v", err)
+	}
+
+	values, err := BindSavedQuery(query, url.Values{"owner": {"ada"}, "limit": {"5"}})
+	if err != nil {
+		t.Fatalf("BindSavedQuery: %v", err)
+	}
+	want := url.Values{"status": {"open"}, "owner": {"ada"}, "sort": {"created_at"}, "order": {"desc"}, "limit": {"5"}}
+	if values.Encode() != want.Encode() {
+		t.Errorf("BindSavedQuery = %v, want %v", values, want)
+	}
+
+	for name, args := range map[string]url.Values{
+		"missing required": {},
+		"unknown argument": {"owner": {"ada"}, "status": {"closed"}},
+	} {
+		if _, err := BindSavedQuery(query, args); err == nil {
+			t.Errorf("BindSavedQuery(%s) succeeded, want error", name)
+		}
+	}
+
+	undeclared := query
+	undeclared.Query = map[string]string{"owner": "{who}"}
+	if err := ValidateSavedQuery(undeclared, columns); err == nil {
+		t.Error("ValidateSavedQuery accepted an undeclared placeholder")
+	}
+	unknownColumn := query
+	unknownColumn.Query = map[string]string{"priority": "1"}
+	if err := ValidateSavedQuery(unknownColumn, columns); err == nil {
+		t.Error("ValidateSavedQuery accepted a filter on an unknown column")
+	}
+}
@@ -0,0 +1,136 @@
+// internal/core/seed.go
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxSeedRows bounds the rows of one seed document.
+const MaxSeedRows = 10000
+
+// Seed is a declarative set of tables and rows loaded into a database to give tests
+// a known starting point.
+type Seed struct {
+	Tables []SeedTable `json:"tables"`
+}
+
+// SeedTable is a table of a seed. Columns may be left out for a table the database
+// already has; rows are then checked against its columns when the seed is loaded.
+type SeedTable struct {
+	Name    string           `json:"name"`
+	Columns []SeedColumn     `json:"columns,omitempty"`
+	Rows    []map[string]any `json:"rows,omitempty"` // An "id" key fixes the record id
+}
+
+// SeedColumn is a column of a SeedTable, as in table creation requests.
+type SeedColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Default any    `json:"default,omitempty"`
+	Collate string `json:"collate,omitempty"`
+}
+
+// Specs returns the columns of t for creating the table.
+func (t SeedTable) Specs() []ColumnSpec {
+	specs := make([]ColumnSpec, len(t.Columns))
+	for i, col := range t.Columns {
+		specs[i] = ColumnSpec{Name: col.Name, Type: col.Type, Default: col.Default, Collate: col.Collate}
+	}
+	return specs
+}
+
+// ColumnTypes returns the declared column types of t keyed by lowercase name, as
+// RecordAssignments expects.
+func (t SeedTable) ColumnTypes() map[string]string {
+	types := make(map[string]string, len(t.Columns))
+	for _, col := range t.Columns {
+		types[strings.ToLower(col.Name)] = col.Type
+	}
+	return types
+}
+
+// ParseSeed decodes and validates a JSON seed document. Column types are normalized,
+// and the rows of tables with columns are checked against them. Unknown fields are
+// rejected so that typos do not silently load less than intended.
+func ParseSeed(data []byte) (*Seed, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var seed Seed
+	if err := decoder.Decode(&seed); err != nil {
+		return nil, fmt.Errorf("invalid seed document: %w", err)
+	}
+	if len(seed.Tables) == 0 {
+		return nil, errors.New("seed has no tables")
+	}
+
+	names := make(map[string]bool, len(seed.Tables))
+	rows := 0
+	for i, table := range seed.Tables {
+		lower := strings.ToLower(table.Name)
+		if names[lower] {
+			return nil, fmt.Errorf("table '%s' appears more than once", table.Name)
+		}
+		names[lower] = true
+		rows += len(table.Rows)
+		if rows > MaxSeedRows {
+			return nil, fmt.Errorf("seed has more than %d rows", MaxSeedRows)
+		}
+
+		if len(table.Columns) == 0 {
+			if !IsValidIdentifier(table.Name) {
+				return nil, fmt.Errorf("invalid table name '%s'", table.Name)
+			}
+			continue
+		}
+		specs, err := ValidateTableDefinition(table.Name, table.Specs())
+		if err != nil {
+			return nil, fmt.Errorf("table '%s': %w", table.Name, err)
+		}
+		for j, spec := range specs {
+			seed.Tables[i].Columns[j] = SeedColumn{Name: spec.Name, Type: spec.Type, Default: spec.Default, Collate: spec.Collate}
+		}
+		if _, _, err := SeedRowAssignments(seed.Tables[i].Name, seed.Tables[i].ColumnTypes(), table.Rows); err != nil {
+			return nil, err
+		}
+	}
+	return &seed, nil
+}
+
+// SeedRowAssignments validates the rows of a seed table against its column types and
+// returns the columns and values to insert for each row, including a fixed id.
+func SeedRowAssignments(tableName string, columnTypes map[string]string, rows []map[string]any) ([][]string, [][]any, error) {
+	columns := make([][]string, len(rows))
+	values := make([][]any, len(rows))
+	ids := make(map[int64]bool)
+	for i, row := range rows {
+		names, vals, err := RecordAssignments(columnTypes, row)
+		if err != nil {
+			return nil, nil, fmt.Errorf("table '%s', row %d: %w", tableName, i+1, err)
+		}
+		for key, val := range row {
+			if strings.ToLower(key) != "id" {
+				continue
+			}
+			id, ok := val.(float64)
+			if !ok || id < 1 || id != math.Trunc(id) || id > math.MaxInt64 {
+				return nil, nil, fmt.Errorf("table '%s', row %d: id must be a positive integer", tableName, i+1)
+			}
+			if ids[int64(id)] {
+				return nil, nil, fmt.Errorf("table '%s', row %d: id %d is used by an earlier row", tableName, i+1, int64(id))
+			}
+			ids[int64(id)] = true
+			names = append(names, "id")
+			vals = append(vals, int64(id))
+		}
+		if len(names) == 0 {
+			return nil, nil, fmt.Errorf("table '%s', row %d: row has no values", tableName, i+1)
+		}
+		columns[i], values[i] = names, vals
+	}
+	return columns, values, nil
+}
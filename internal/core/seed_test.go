@@ -0,0 +1,38 @@
+// internal/core/seed_test.go
+package core
+
+import "testing"
+
+func TestParseSeed(t *testing.T) {
+	seed, err := ParseSeed([]byte(`{"tables": [
+		{"name": "customers", "columns": [{"name": "name", "type": "text"}, {"name": "vip", "type": "boolean"}],
+		 "rows": [{"id": 7, "name": "Ada", "vip": true}, {"name": "Bob"}]},
+		{"name": "orders"}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseSeed() error = %v", err)
+	}
+	if len(seed.Tables) != 2 || seed.Tables[0].Columns[0].Type != "TEXT" {
+		t.Fatalf("ParseSeed() = %+v; want two tables with normalized types", seed)
+	}
+	columns, values, err := SeedRowAssignments("customers", seed.Tables[0].ColumnTypes(), seed.Tables[0].Rows)
+	if err != nil || len(columns) != 2 || len(columns[0]) != 3 || values[0][2] != int64(7) || len(columns[1]) != 1 {
+		t.Errorf("SeedRowAssignments() = %v, %v, %v; want the id last in the first row", columns, values, err)
+	}
+
+	for name, doc := range map[string]string{
+		"no tables":       `{"tables": []}`,
+		"unknown field":   `{"tables": [{"name": "t", "colums": []}]}`,
+		"duplicate table": `{"tables": [{"name": "t"}, {"name": "T"}]}`,
+		"invalid type":    `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "money"}]}]}`,
+		"unknown column":  `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "text"}], "rows": [{"b": "x"}]}]}`,
+		"wrong value":     `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "integer"}], "rows": [{"a": "x"}]}]}`,
+		"fractional id":   `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "text"}], "rows": [{"id": 1.5, "a": "x"}]}]}`,
+		"repeated id":     `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "text"}], "rows": [{"id": 1}, {"id": 1}]}]}`,
+		"empty row":       `{"tables": [{"name": "t", "columns": [{"name": "a", "type": "text"}], "rows": [{}]}]}`,
+	} {
+		if _, err := ParseSeed([]byte(doc)); err == nil {
+			t.Errorf("ParseSeed(%s) succeeded, want error", name)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// internal/core/table_description.go
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// MaxDescriptionLength caps each table or column description, in characters.
+const MaxDescriptionLength = 1000
+
+// ValidateTableDescription checks the descriptions of a table against its columns
+// (keyed by lowercase name, as from ColumnTypes) and returns them with trimmed texts
+// and lowercase column names.
+func ValidateTableDescription(description domain.TableDescription, columnTypes map[string]string) (domain.TableDescription, error) {
+	normalized := domain.TableDescription{
+		Description: strings.TrimSpace(description.Description),
+		Columns:     make(map[string]string, len(description.Columns)),
+	}
+	if utf8.RuneCountInString(normalized.Description) > MaxDescriptionLength {
+		return domain.TableDescription{}, fmt.Errorf("table description is longer than %d characters", MaxDescriptionLength)
+	}
+	for column, text := range description.Columns {
+		name := strings.ToLower(column)
+		if _, exists := columnTypes[name]; !exists {
+			return domain.TableDescription{}, fmt.Errorf("column '%s' not found in table schema", column)
+		}
+		text = strings.TrimSpace(text)
+		if utf8.RuneCountInString(text) > MaxDescriptionLength {
+			return domain.TableDescription{}, fmt.Errorf("description of column '%s' is longer than %d characters", column, MaxDescriptionLength)
+		}
+		if text != "" {
+			normalized.Columns[name] = text
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,32 @@
+// internal/core/table_description_test.go
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestValidateTableDescription(t *testing.T) {
+	columns := map[string]string{"id": "INTEGER", "email": "TEXT"}
+	got, err := ValidateTableDescription(domain.TableDescription{
+		Description: "  Customers ",
+		Columns:     map[string]string{"Email": "Login address", "id": " "},
+	}, columns)
+	if err != nil {
+		t.Fatalf("ValidateTableDescription() error = %v", err)
+	}
+	if got.Description != "Customers" || len(got.Columns) != 1 || got.Columns["email"] != "Login address" {
+		t.Errorf("ValidateTableDescription() = %+v", got)
+	}
+
+	for name, description := range map[string]domain.TableDescription{
+		"unknown column":   {Columns: map[string]string{"phone": "x"}},
+		"long description": {Description: strings.Repeat("é", MaxDescriptionLength+1)},
+	} {
+		if _, err := ValidateTableDescription(description, columns); err == nil {
+			t.Errorf("ValidateTableDescription(%s) succeeded, want error", name)
+		}
+	}
+}
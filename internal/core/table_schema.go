@@ -0,0 +1,160 @@
+// internal/core/table_schema.go
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnSpec is a column requested for a new user table.
+type ColumnSpec struct {
+	Name    string
+	Type    string
+	Default any    // Decoded JSON value applied when an insert omits the column; nil for none
+	Collate string // CollateNoCase for case-insensitive comparison of TEXT values; "" for binary
+}
+
+// CollateNoCase is the collation comparing TEXT values without regard to letter case.
+const CollateNoCase = "NOCASE"
+
+// TrashedTablePrefix starts the names dropped tables are renamed to while they wait in
+// the trash. Such tables are hidden from listings and the prefix is reserved.
+const TrashedTablePrefix = "_trash_"
+
+// ValidateTableDefinition checks a requested table name and columns and returns the
+// columns with their types normalized. Errors describe the offending name or type for
+// the client.
+func ValidateTableDefinition(tableName string, columns []ColumnSpec) ([]ColumnSpec, error) {
+	if !IsValidIdentifier(tableName) {
+		return nil, errors.New("invalid table name format")
+	}
+	if strings.HasPrefix(strings.ToLower(tableName), TrashedTablePrefix) {
+		return nil, fmt.Errorf("table names starting with '%s' are reserved", TrashedTablePrefix)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no columns provided")
+	}
+
+	normalized := make([]ColumnSpec, 0, len(columns))
+	columnNames := make(map[string]bool) // Check for duplicate column names
+
+	for _, col := range columns {
+		colNameLower := strings.ToLower(col.Name)
+		if !IsValidIdentifier(col.Name) || colNameLower == "id" {
+			return nil, fmt.Errorf("invalid column name '%s': use a valid identifier other than 'id'", col.Name)
+		}
+		if columnNames[colNameLower] {
+			return nil, fmt.Errorf("duplicate column name '%s'", col.Name)
+		}
+		columnNames[colNameLower] = true
+
+		normalizedType, ok := NormalizeAndValidateType(col.Type)
+		if !ok {
+			return nil, fmt.Errorf("invalid type '%s' for column '%s'", col.Type, col.Name)
+		}
+		if col.Default != nil {
+			if _, ok := DefaultLiteral(col.Default); !ok || !IsCompatibleValue(normalizedType, col.Default) {
+				return nil, fmt.Errorf("invalid default for column '%s': expected a value compatible with %s", col.Name, normalizedType)
+			}
+			if v, ok := col.Default.(float64); ok && normalizedType == "BOOLEAN" {
+				col.Default = v == 1 // Postgres rejects numeric defaults for BOOLEAN
+			}
+		}
+		switch strings.ToUpper(col.Collate) {
+		case "", "BINARY":
+			col.Collate = ""
+		case CollateNoCase:
+			if normalizedType != "TEXT" {
+				return nil, fmt.Errorf("invalid collation for column '%s': only TEXT columns can be case-insensitive", col.Name)
+			}
+			col.Collate = CollateNoCase
+		default:
+			return nil, fmt.Errorf("invalid collation '%s' for column '%s': use 'nocase' or 'binary'", col.Collate, col.Name)
+		}
+		normalized = append(normalized, ColumnSpec{Name: col.Name, Type: normalizedType, Default: col.Default, Collate: col.Collate}) // Use original name case
+	}
+	return normalized, nil
+}
+
+// BuildCreateTableSQL validates a table definition and returns the SQLite CREATE TABLE
+// statement for it. Every user table gets an auto-increment id and a created_at timestamp
+// in addition to the requested columns.
+func BuildCreateTableSQL(tableName string, columns []ColumnSpec) (string, error) {
+	return buildCreateTableSQL(tableName, columns, false)
+}
+
+// BuildStrictCreateTableSQL is BuildCreateTableSQL for a SQLite STRICT table, where the
+// database itself rejects values that do not match the declared column types instead
+// of storing them as they come. STRICT tables only know INTEGER, REAL, TEXT and BLOB,
+// so created_at holds ISO 8601 UTC text and BOOLEAN columns are refused (see
+// ValidateStrictColumns).
+func BuildStrictCreateTableSQL(tableName string, columns []ColumnSpec) (string, error) {
+	return buildCreateTableSQL(tableName, columns, true)
+}
+
+func buildCreateTableSQL(tableName string, columns []ColumnSpec, strict bool) (string, error) {
+	normalized, err := ValidateTableDefinition(tableName, columns)
+	if err != nil {
+		return "", err
+	}
+	if strict {
+		if err := ValidateStrictColumns(normalized); err != nil {
+			return "", err
+		}
+	}
+
+	columnDefs := make([]string, len(normalized))
+	for i, col := range normalized {
+		columnDefs[i] = fmt.Sprintf("%s %s", col.Name, col.Type)
+		if col.Collate != "" {
+			columnDefs[i] += " COLLATE " + col.Collate
+		}
+		if col.Default != nil {
+			literal, _ := DefaultLiteral(col.Default) // Checked by ValidateTableDefinition
+			columnDefs[i] += " DEFAULT " + literal
+		}
+	}
+
+	if strict {
+		return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s , created_at TEXT DEFAULT (strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', 'now'))) STRICT;",
+			tableName,
+			strings.Join(columnDefs, ", "),
+		), nil
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s , created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP);",
+		tableName,
+		strings.Join(columnDefs, ", "),
+	), nil
+}
+
+// ValidateStrictColumns checks that normalized columns can be declared in a STRICT
+// table, which has no BOOLEAN type.
+func ValidateStrictColumns(columns []ColumnSpec) error {
+	for _, col := range columns {
+		if col.Type == "BOOLEAN" {
+			return fmt.Errorf("column '%s': strict tables cannot have BOOLEAN columns, use INTEGER with 0 and 1", col.Name)
+		}
+	}
+	return nil
+}
+
+// DefaultLiteral renders a column default as an SQL literal understood by both SQLite
+// and Postgres. Only strings, numbers and booleans (and nil, as NULL) can be defaults.
+func DefaultLiteral(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", true
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		if v {
+			return "TRUE", true
+		}
+		return "FALSE", true
+	}
+	return "", false
+}
@@ -2,6 +2,9 @@
 package core
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
 	"regexp"
 	"strings"
 )
@@ -32,3 +35,87 @@ func NormalizeAndValidateType(colType string) (string, bool) {
 	// Could add extra checks here if needed (e.g., disallow specific types in certain contexts)
 	return normalizedType, ok
 }
+
+// IsCompatibleValue reports whether a decoded JSON value can be stored in a column of
+// the given (normalized) type. Unknown types are accepted leniently.
+func IsCompatibleValue(expectedType string, val any) bool {
+	if val == nil {
+		return true
+	}
+	switch expectedType {
+	case "INTEGER":
+		switch v := val.(type) {
+		case float64:
+			return math.Floor(v) == v
+		case int, int64:
+			return true
+		}
+		return false
+	case "REAL":
+		switch val.(type) {
+		case float64, int, int64:
+			return true
+		}
+		return false
+	case "TEXT", "BLOB": // BLOB accepts strings (lenient)
+		_, ok := val.(string)
+		return ok
+	case "BOOLEAN":
+		switch v := val.(type) {
+		case bool:
+			return true
+		case float64:
+			return v == 0 || v == 1
+		}
+		return false
+	}
+	return true
+}
+
+// RecordAssignments validates decoded record data against a table's column types (as
+// returned by storage.PragmaTableInfo) and returns the columns and values to write.
+// Keys that are not valid identifiers and the id column are skipped.
+func RecordAssignments(columnTypes map[string]string, data map[string]any) ([]string, []any, error) {
+	var columns []string
+	var values []any
+
+	for key, val := range data {
+		lowerKey := strings.ToLower(key)
+		if !IsValidIdentifier(key) || lowerKey == "id" {
+			continue
+		} // Skip invalid/id
+
+		expectedType, exists := columnTypes[lowerKey]
+		if !exists {
+			return nil, nil, fmt.Errorf("column '%s' does not exist", key)
+		}
+		if !IsCompatibleValue(expectedType, val) {
+			return nil, nil, fmt.Errorf("invalid data type for column '%s'. Expected compatible with %s", key, expectedType)
+		}
+		columns = append(columns, key)
+		values = append(values, val)
+	}
+	return columns, values, nil
+}
+
+// EncodeJSONValues replaces object and array values bound for TEXT columns with their
+// JSON encoding, so a table with a JSON Schema can hold nested documents. Other values
+// are left for RecordAssignments to check.
+func EncodeJSONValues(columnTypes map[string]string, data map[string]any) error {
+	for key, val := range data {
+		switch val.(type) {
+		case map[string]any, []any:
+		default:
+			continue
+		}
+		if columnTypes[strings.ToLower(key)] != "TEXT" {
+			continue
+		}
+		encoded, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Errorf("cannot encode value of column '%s': %w", key, err)
+		}
+		data[key] = string(encoded)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+// internal/doctor/doctor.go
+
+// Package doctor checks the configuration and environment the server runs in: the JWT
+// secret, TLS files, the data directories, the SQLite library and the metadata schema.
+// Each problem comes with what to do about it. It backs the doctor subcommand and the
+// startup checks.
+package doctor
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// Severities of findings.
+const (
+	SeverityOK      = "ok"
+	SeverityWarning = "warning" // The server works, but should not be run like this
+	SeverityError   = "error"   // The server fails, or is unsafe, until this is fixed
+)
+
+// jwtPlaceholder is the JWT_SECRET of .env.example.
+const jwtPlaceholder = "!!replace_this_with_a_real_secret_key!!"
+
+// minSQLiteVersion is the oldest SQLite with everything the metadata schema uses
+// (RETURNING).
+const minSQLiteVersion = "3.35.0"
+
+// Finding is the result of one check.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Fix      string `json:"fix,omitempty"` // What to do about a warning or error
+}
+
+func ok(check, message string) Finding {
+	return Finding{Check: check, Severity: SeverityOK, Message: message}
+}
+
+func warning(check, message, fix string) Finding {
+	return Finding{Check: check, Severity: SeverityWarning, Message: message, Fix: fix}
+}
+
+func failure(check, message, fix string) Finding {
+	return Finding{Check: check, Severity: SeverityError, Message: message, Fix: fix}
+}
+
+// Run checks cfg and the environment, returning the findings in a stable order.
+func Run(ctx context.Context, cfg *config.Config) []Finding {
+	var findings []Finding
+	findings = append(findings, checkJWTSecret(cfg))
+	findings = append(findings, checkTLSFiles(cfg)...)
+	findings = append(findings, checkDirectories(cfg)...)
+	findings = append(findings, checkSQLite(ctx, cfg)...)
+	findings = append(findings, checkMetadataSchema(ctx, cfg))
+	return findings
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	return slices.ContainsFunc(findings, func(f Finding) bool { return f.Severity == SeverityError })
+}
+
+// Print writes the findings to w, one per line with the fix indented below.
+func Print(w io.Writer, findings []Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(w, "[%s] %s: %s\n", strings.ToUpper(f.Severity), f.Check, f.Message)
+		if f.Fix != "" {
+			fmt.Fprintf(w, "    fix: %s\n", f.Fix)
+		}
+	}
+}
+
+func checkJWTSecret(cfg *config.Config) Finding {
+	const fix = "Set JWT_SECRET to at least 32 random bytes, e.g. the output of `openssl rand -base64 48`."
+	secret := cfg.JWTSigningSecret()
+	distinct := make(map[rune]bool)
+	for _, r := range secret {
+		distinct[r] = true
+	}
+	switch {
+	case secret == jwtPlaceholder:
+		return failure("jwt_secret", "JWT_SECRET is the placeholder from .env.example; anyone can sign tokens", fix)
+	case len(secret) < 32:
+		return warning("jwt_secret", fmt.Sprintf("JWT_SECRET is only %d bytes long", len(secret)), fix)
+	case len(distinct) < 10:
+		return warning("jwt_secret", fmt.Sprintf("JWT_SECRET uses only %d distinct characters", len(distinct)), fix)
+	}
+	return ok("jwt_secret", "JWT_SECRET is long and varied")
+}
+
+func checkTLSFiles(cfg *config.Config) []Finding {
+	var findings []Finding
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		findings = append(findings, failure("tls", "only one of TLS_CERT_FILE and TLS_KEY_FILE is set",
+			"Set both, or neither to serve plain HTTP behind a TLS-terminating proxy."))
+	}
+	for _, file := range []struct{ setting, path string }{{"TLS_CERT_FILE", cfg.TLSCertFile}, {"TLS_KEY_FILE", cfg.TLSKeyFile}} {
+		if file.path == "" {
+			continue
+		}
+		f, err := os.Open(file.path)
+		if err != nil {
+			findings = append(findings, failure("tls", fmt.Sprintf("%s cannot be read: %v", file.setting, err),
+				fmt.Sprintf("Point %s at a PEM file the server's user can read.", file.setting)))
+			continue
+		}
+		f.Close()
+	}
+	return findings
+}
+
+// checkDirectories checks that the directories the server writes to are directories it
+// can write to, and that other OS users cannot read them.
+func checkDirectories(cfg *config.Config) []Finding {
+	type directory struct{ setting, path string }
+	dirs := []directory{{"DATABASE_DIRECTORY", cfg.MetadataDbDir}}
+	for _, root := range cfg.DataRoots {
+		if filepath.Clean(root) != filepath.Clean(cfg.MetadataDbDir) {
+			dirs = append(dirs, directory{"DATA_ROOTS", root})
+		}
+	}
+	if cfg.BackupDir != "" {
+		dirs = append(dirs, directory{"BACKUP_DIRECTORY", cfg.BackupDir})
+	}
+	if cfg.AccountExportDir != "" {
+		dirs = append(dirs, directory{"ACCOUNT_EXPORT_DIRECTORY", cfg.AccountExportDir})
+	}
+
+	findings := make([]Finding, 0, len(dirs))
+	for _, dir := range dirs {
+		findings = append(findings, checkDirectory(dir.setting, dir.path))
+	}
+	return findings
+}
+
+func checkDirectory(setting, dir string) Finding {
+	check := "directory:" + dir
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Created with its missing parents, in the closest one that exists
+		parent := filepath.Dir(filepath.Clean(dir))
+		for _, err := os.Stat(parent); errors.Is(err, os.ErrNotExist) && parent != filepath.Dir(parent); _, err = os.Stat(parent) {
+			parent = filepath.Dir(parent)
+		}
+		if err := writable(parent); err != nil {
+			return failure(check, fmt.Sprintf("does not exist and cannot be created: %v", err),
+				fmt.Sprintf("Create %s for the server's user, or point %s elsewhere.", dir, setting))
+		}
+		return ok(check, "does not exist yet; created at startup")
+	case err != nil:
+		return failure(check, fmt.Sprintf("cannot be read: %v", err), fmt.Sprintf("Give the server's user access to %s.", dir))
+	case !info.IsDir():
+		return failure(check, "is not a directory", fmt.Sprintf("Point %s at a directory.", setting))
+	}
+	if err := writable(dir); err != nil {
+		return failure(check, fmt.Sprintf("is not writable: %v", err),
+			fmt.Sprintf("`chown` %s to the server's user, or point %s elsewhere.", dir, setting))
+	}
+	if perm := info.Mode().Perm(); perm&0o007 != 0 {
+		return warning(check, fmt.Sprintf("is accessible to all users (%s)", perm),
+			fmt.Sprintf("Run `chmod o-rwx %s`; the database files in it hold tenant data.", dir))
+	}
+	return ok(check, "writable, and private to the server's user and group")
+}
+
+// writable creates and removes a temporary file in dir.
+func writable(dir string) error {
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func checkSQLite(ctx context.Context, cfg *config.Config) []Finding {
+	info, err := storage.ProbeSQLite(ctx)
+	if err != nil {
+		return []Finding{failure("sqlite", fmt.Sprintf("SQLite cannot be used: %v", err), "Build the server with CGO_ENABLED=1.")}
+	}
+
+	var findings []Finding
+	if compareVersions(info.Version, minSQLiteVersion) < 0 {
+		findings = append(findings, failure("sqlite", fmt.Sprintf("SQLite %s is older than %s", info.Version, minSQLiteVersion),
+			"Build with the SQLite bundled with github.com/mattn/go-sqlite3, or link a newer system library."))
+	} else {
+		findings = append(findings, ok("sqlite", "SQLite "+info.Version))
+	}
+	if info.FTS5 {
+		findings = append(findings, ok("sqlite_fts5", "FTS5 full-text search is available"))
+	} else {
+		// Nothing depends on it yet
+		findings = append(findings, ok("sqlite_fts5", "FTS5 full-text search is not compiled in (build with `-tags sqlite_fts5` to add it)"))
+	}
+	if len(cfg.UserDBEncryptionKey) > 0 && info.SQLCipher == "" {
+		findings = append(findings, failure("sqlite_encryption", "USER_DB_ENCRYPTION_KEY is set but SQLite was built without SQLCipher",
+			"Build with `-tags libsqlite3` and link against SQLCipher, or unset USER_DB_ENCRYPTION_KEY."))
+	}
+
+	// WAL is used for the metadata database and every user database file
+	var walDirs []string
+	if cfg.MetadataBackend != storage.BackendPostgres {
+		walDirs = append(walDirs, filepath.Clean(cfg.MetadataDbDir))
+	}
+	if cfg.UserDataBackend != storage.BackendPostgres {
+		for _, root := range cfg.DataRoots {
+			if !slices.Contains(walDirs, filepath.Clean(root)) {
+				walDirs = append(walDirs, filepath.Clean(root))
+			}
+		}
+	}
+	for _, dir := range walDirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue // Reported by checkDirectories
+		}
+		if err := storage.ProbeWAL(ctx, dir); err != nil {
+			findings = append(findings, failure("sqlite_wal", fmt.Sprintf("WAL does not work in %s: %v", dir, err),
+				"Keep database files on a local filesystem; WAL needs shared memory that network filesystems often lack."))
+		} else {
+			findings = append(findings, ok("sqlite_wal", "WAL works in "+dir))
+		}
+	}
+	return findings
+}
+
+func checkMetadataSchema(ctx context.Context, cfg *config.Config) Finding {
+	current, latest, err := storage.MetadataSchemaVersions(ctx, cfg)
+	switch {
+	case err != nil:
+		return failure("metadata_schema", fmt.Sprintf("cannot read the metadata database: %v", err),
+			"Check DATABASE_DIRECTORY_FILE, or METADATA_POSTGRES_DSN with the Postgres backend.")
+	case current > latest:
+		return failure("metadata_schema", fmt.Sprintf("schema version %d is newer than this server supports (%d)", current, latest),
+			"Run the release that migrated the database, or a newer one; older releases cannot use it.")
+	case current == 0:
+		return ok("metadata_schema", fmt.Sprintf("no schema yet; version %d is created at startup", latest))
+	case current < latest:
+		return ok("metadata_schema", fmt.Sprintf("version %d; %d migrations are applied at startup", current, latest-current))
+	}
+	return ok("metadata_schema", fmt.Sprintf("version %d, up to date", current))
+}
+
+// compareVersions compares dotted version numbers, returning -1, 0 or 1.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(as), len(bs)) {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		JWTSecret:      jwtPlaceholder,
+		MetadataDbDir:  dir,
+		MetadataDbFile: "meta.db",
+		DataRoots:      []string{dir},
+		BackupDir:      filepath.Join(dir, "backups", "daily"),
+	}
+
+	severities := func() map[string]string {
+		got := make(map[string]string)
+		for _, f := range Run(ctx, cfg) {
+			if f.Severity != SeverityOK && f.Fix == "" {
+				t.Errorf("%s finding %s has no fix", f.Severity, f.Check)
+			}
+			got[f.Check] = f.Severity
+		}
+		return got
+	}
+	got := severities()
+	want := map[string]string{
+		"jwt_secret":                 SeverityError,
+		"directory:" + dir:           SeverityWarning,
+		"directory:" + cfg.BackupDir: SeverityOK,
+		"sqlite_wal":                 SeverityOK,
+		"metadata_schema":            SeverityOK,
+	}
+	for check, severity := range want {
+		if got[check] != severity {
+			t.Errorf("%s = %q; want %q", check, got[check], severity)
+		}
+	}
+
+	// A schema migrated by a newer release
+	store, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	store.Close()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "meta.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (9999, 'future')`); err != nil {
+		t.Fatal(err)
+	}
+	cfg.JWTSecret = "8q1Vt3xKc0Zm7Rj2Wn5Ld9Hs4Pf6Yb1Ea"
+	if got := severities(); got["metadata_schema"] != SeverityError || got["jwt_secret"] != SeverityOK {
+		t.Errorf("metadata_schema = %q, jwt_secret = %q; want error for the newer schema and ok", got["metadata_schema"], got["jwt_secret"])
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{{"3.35.0", "3.35.0", 0}, {"3.34.1", "3.35.0", -1}, {"3.46", "3.35.0", 1}, {"3.35.0.1", "3.35", 1}} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%s, %s) = %d; want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -3,6 +3,12 @@ package domain
 
 import "time"
 
+// User roles stored in users.role.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // May use the /api/v1/admin endpoints
+)
+
 // User defines the structure for user data in the DB
 type UserMetadata struct {
 	UserId       string    `json:"userId"`
@@ -10,42 +16,269 @@ type UserMetadata struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"password"`
 	CreatedAt    time.Time `json:"createdAt"`
+	Role         string    `json:"role"`
 }
 
 // DatabaseMetadata define the structure for user's databases
 type DatabaseMetadata struct {
-	DatabaseID int64     `json:"databaseId"`
-	UserID     string    `json:"userId"`
-	DBName     string    `json:"dbName"`
-	FilePath   string    `json:"filePath"`
-	CreatedAt  time.Time `json:"createdAt"`
-	Tables     int64     `json:"tables"`
-	APIKey     string    `json:"apiKey"`
+	DatabaseID int64             `json:"databaseId"`
+	UserID     string            `json:"userId"`
+	DBName     string            `json:"dbName"`
+	FilePath   string            `json:"filePath"`
+	DataRoot   string            `json:"dataRoot,omitempty"`   // Data directory holding the file
+	Placement  string            `json:"placement,omitempty"`  // Strategy that chose DataRoot
+	Quarantine string            `json:"quarantine,omitempty"` // Why requests to it are refused
+	ExpiresAt  *time.Time        `json:"expiresAt,omitempty"`  // When an ephemeral database is deleted
+	CreatedAt  time.Time         `json:"createdAt"`
+	Tables     int64             `json:"tables"`
+	APIKey     string            `json:"apiKey"`
+	Labels     map[string]string `json:"labels"`
 }
 
 // ColumnInfo represents the information for a single column.
 type ColumnInfo struct {
-	ColumnId string `json:"cid"`
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	NotNull  int    `json:"notnull"`
-	Default  any    `json:"dflt_value"`
-	PK       int    `json:"pk"`
+	ColumnId    string `json:"cid"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	NotNull     int    `json:"notnull"`
+	Default     any    `json:"dflt_value"`
+	PK          int    `json:"pk"`
+	Description string `json:"description,omitempty"`
 }
 
 // TableMetadata represents the information for a table, including its columns.
 type TableMetadata struct {
-	Type      string       `json:"type"`
-	Name      string       `json:"name"`
-	TableName string       `json:"tbl_name"`
-	RootPage  string       `json:"rootpage"`
-	Sql       string       `json:"sql"`
-	CreatedAt time.Time    `json:"createdAt"`
-	Columns   []ColumnInfo `json:"columns"`
+	Type        string       `json:"type"`
+	Name        string       `json:"name"`
+	TableName   string       `json:"tbl_name"`
+	RootPage    string       `json:"rootpage"`
+	Sql         string       `json:"sql"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	Columns     []ColumnInfo `json:"columns"`
+	Description string       `json:"description,omitempty"`
 }
 
 type TableSchemaMetaData struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	PrimaryKey bool   `json:"pk"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	PrimaryKey  bool   `json:"pk"`
+	Description string `json:"description,omitempty"`
+}
+
+// BackupMetadata describes a point-in-time snapshot of a user database.
+type BackupMetadata struct {
+	BackupID  string    `json:"backupId"`
+	DBName    string    `json:"dbName"`
+	SizeBytes int64     `json:"sizeBytes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AccountExport is an archive of everything stored about an account (see
+// storage.CreateAccountExport), downloadable until ExpiresAt.
+type AccountExport struct {
+	ExportID  string    `json:"export_id"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ErasureReport is the compliance report of an erased account: how much of its data
+// was deleted or anonymized where. It is kept as the account's tombstone, so it only
+// holds counts.
+type ErasureReport struct {
+	ErasureID             string    `json:"erasure_id"`
+	UserID                string    `json:"user_id"`
+	RequestedBy           string    `json:"requested_by"` // UserID itself for self-service erasures
+	Databases             int       `json:"databases"`
+	APIKeys               int       `json:"api_keys"`
+	TrashItems            int       `json:"trash_items"`
+	Backups               int       `json:"backups"`         // Local backup files
+	OffsiteObjects        int       `json:"offsite_objects"` // Replicated backups and snapshots
+	AccountExports        int       `json:"account_exports"`
+	AuditEventsAnonymized int       `json:"audit_events_anonymized"`
+	MailDeleted           int       `json:"mail_deleted"`
+	ErasedAt              time.Time `json:"erased_at"`
+}
+
+// Mail outbox statuses stored in mail_outbox.status.
+const (
+	MailPending = "pending"
+	MailSent    = "sent"
+	MailFailed  = "failed" // Gave up after the maximum number of attempts
+)
+
+// OutboxMail is an email waiting in (or delivered from) the mail outbox.
+type OutboxMail struct {
+	MailID    int64  `json:"mailId"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	TextBody  string `json:"textBody"`
+	HTMLBody  string `json:"htmlBody"`
+	Attempts  int    `json:"attempts"` // Including the one in progress once claimed
+}
+
+// ColumnRule is a validation rule on one column of a user table, checked before
+// records are written. Unset fields don't constrain the value.
+type ColumnRule struct {
+	Column  string   `json:"column"`
+	Pattern string   `json:"pattern,omitempty"` // Regular expression text values must match
+	Format  string   `json:"format,omitempty"`  // "email" or "url"
+	Min     *float64 `json:"min,omitempty"`     // Smallest number, or shortest text length
+	Max     *float64 `json:"max,omitempty"`     // Largest number, or longest text length
+}
+
+// SavedQuery is a named, parameterized record listing of one table. Query holds list
+// parameters (column filters, sort, order, fields, limit, offset) whose values may
+// contain {param} placeholders filled in from the caller's arguments.
+type SavedQuery struct {
+	Name        string            `json:"name"`
+	TableName   string            `json:"table_name"`
+	Description string            `json:"description,omitempty"`
+	Query       map[string]string `json:"query"`
+	Params      []QueryParam      `json:"params,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// QueryParam declares an argument of a SavedQuery.
+type QueryParam struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required,omitempty"`
+	Default  string `json:"default,omitempty"` // Used when an optional argument is omitted
+}
+
+// TrashItem is a deleted database, or a table dropped from a database, kept restorable
+// until ExpiresAt.
+type TrashItem struct {
+	TrashID       int64     `json:"trash_id"`
+	UserID        string    `json:"-"`
+	DBName        string    `json:"db_name"`
+	TableName     string    `json:"table_name,omitempty"` // Empty for a database
+	Location      string    `json:"-"`                    // Storage of the database once restored
+	TrashLocation string    `json:"-"`                    // Storage of the trashed database, or the trashed table's name
+	DeletedAt     time.Time `json:"deleted_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// TableDescription documents a user table and its columns.
+type TableDescription struct {
+	Description string            `json:"description"`
+	Columns     map[string]string `json:"columns"` // Column name to description
+}
+
+// TableOptions are per-table behaviour switches; the zero value is the default.
+type TableOptions struct {
+	AutoColumns  bool   `json:"auto_columns"`  // Unknown keys of created records add columns instead of failing
+	DefaultSort  string `json:"default_sort"`  // Column listed records are sorted by when a request names none; "" for id
+	DefaultOrder string `json:"default_order"` // "asc" or "desc" with DefaultSort, else ""
+	MaxRows      int64  `json:"max_rows"`      // Inserts fail once the table holds this many rows; 0 for no limit of its own
+}
+
+// DatabaseUsage is the API traffic of a user database during one UTC day.
+type DatabaseUsage struct {
+	Day       string  `json:"day,omitempty"` // YYYY-MM-DD
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`     // Responses with a status of 400 or above
+	ErrorRate float64 `json:"error_rate"` // Errors / Requests
+	BytesIn   int64   `json:"bytes_in"`
+	BytesOut  int64   `json:"bytes_out"`
+}
+
+// AuditEvent is an entry of the security audit log, such as an auth anomaly alert.
+type AuditEvent struct {
+	EventID   int64             `json:"event_id"`
+	UserID    string            `json:"user_id,omitempty"` // Empty for events not tied to an account
+	Event     string            `json:"event"`
+	IP        string            `json:"ip,omitempty"`
+	Details   map[string]string `json:"details"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// APIKeyUsage is the traffic of an API key, per UTC day and route when listed from
+// the metadata store; summaries leave out the fields they aggregate over.
+type APIKeyUsage struct {
+	Day       string  `json:"day,omitempty"` // YYYY-MM-DD
+	Method    string  `json:"method,omitempty"`
+	Route     string  `json:"route,omitempty"`
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`     // Responses with a status of 400 or above
+	ErrorRate float64 `json:"error_rate"` // Errors / Requests
+}
+
+// APIKeyClient is a client address an API key was used from.
+type APIKeyClient struct {
+	IP         string    `json:"ip"`
+	Requests   int64     `json:"requests"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Webhook is an HTTP endpoint notified of writes to a database. Secret signs the
+// deliveries; it is only returned when the webhook is created or the secret rotated.
+type Webhook struct {
+	ID             string     `json:"id"`
+	DatabaseID     int64      `json:"-"`
+	URL            string     `json:"url"`
+	Description    string     `json:"description,omitempty"`
+	Events         []string   `json:"events"` // Empty for every event
+	Secret         string     `json:"secret,omitempty"`
+	Enabled        bool       `json:"enabled"`
+	LastStatus     *int       `json:"last_status,omitempty"` // HTTP status of the last delivery; 0 when it failed without one
+	LastError      string     `json:"last_error,omitempty"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Form accepts unauthenticated submissions into a table, e.g. from the contact form of
+// a static site. Only Columns are writable; CaptchaSecret is never returned.
+type Form struct {
+	ID              string    `json:"id"`
+	DatabaseID      int64     `json:"-"`
+	Table           string    `json:"table"`
+	Description     string    `json:"description,omitempty"`
+	Columns         []string  `json:"columns"`
+	AllowedOrigins  []string  `json:"allowed_origins"` // Empty for any origin
+	CaptchaProvider string    `json:"captcha_provider,omitempty"`
+	CaptchaSecret   string    `json:"-"`
+	RedirectURL     string    `json:"redirect_url,omitempty"` // Where HTML form posts are sent after submitting
+	RateLimit       int       `json:"rate_limit"`             // Submissions per client IP and hour
+	Enabled         bool      `json:"enabled"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ShareLink grants read-only access to one table or saved query (exactly one of Table
+// and Query is set) to anyone holding its token, until ExpiresAt.
+type ShareLink struct {
+	ID          string    `json:"id"`
+	DatabaseID  int64     `json:"-"`
+	Table       string    `json:"table,omitempty"`
+	Query       string    `json:"query,omitempty"` // Name of a saved query
+	Description string    `json:"description,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PublishableKey is a browser-safe key of a database: it only reads Tables, and only
+// from AllowedOrigins, so frontend apps can embed it.
+type PublishableKey struct {
+	ID             string    `json:"id"`
+	DatabaseID     int64     `json:"-"`
+	OwnerID        string    `json:"-"` // Owner of the database, set when looking a key up
+	Key            string    `json:"key"`
+	Tables         []string  `json:"tables"`
+	AllowedOrigins []string  `json:"allowed_origins"`
+	Description    string    `json:"description,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ReadReplica designates a read-only copy of a user database that serves its record
+// reads, offloading a read-heavy database from the writer.
+type ReadReplica struct {
+	DatabaseID    int64     `json:"database_id"`
+	UserID        string    `json:"user_id"`
+	DBName        string    `json:"db_name"`
+	FilePath      string    `json:"-"`               // Of the primary
+	MaxLagSeconds int       `json:"max_lag_seconds"` // How stale replica reads may be; 0 only reads an up-to-date replica
+	CreatedAt     time.Time `json:"created_at"`
 }
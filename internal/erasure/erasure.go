@@ -0,0 +1,106 @@
+// internal/erasure/erasure.go
+
+// Package erasure carries out the right to erasure of an account. The storage of its
+// databases, trash, backups (local and shipped off-site) and data exports is deleted
+// first; then one metadata transaction anonymizes its audit events, deletes its mail
+// and its registration, and leaves a tombstone holding the compliance report.
+package erasure
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/replication"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var customLog = logger.NewLogger()
+
+// Erase erases the account userId on behalf of requestedBy and returns the report kept
+// as its tombstone. Metadata goes last, so an erasure that fails part way leaves the
+// account registered and can simply be run again.
+func Erase(ctx context.Context, store storage.MetadataStore, cfg *config.Config, userId, requestedBy string) (*domain.ErasureReport, error) {
+	user, err := store.FindUserByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.ErasureReport{ErasureID: uuid.New().String(), UserID: userId, RequestedBy: requestedBy}
+
+	// From metadata only: unreadable files must be deleted too
+	databases, err := store.ListUserDatabaseRegistrations(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range databases {
+		if err := storage.DeleteUserData(ctx, db.FilePath); err != nil {
+			return nil, fmt.Errorf("failed to delete database '%s': %w", db.DBName, err)
+		}
+	}
+	report.Databases = len(databases)
+
+	// Dropped tables are inside their databases; only dropped databases have storage
+	trash, err := store.ListTrashItems(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range trash {
+		if item.TableName != "" {
+			continue
+		}
+		if err := storage.DeleteUserData(ctx, item.TrashLocation); err != nil {
+			return nil, fmt.Errorf("failed to delete trashed database '%s': %w", item.DBName, err)
+		}
+	}
+	report.TrashItems = len(trash)
+
+	if cfg.BackupDir != "" {
+		if report.Backups, err = removeDirectory(filepath.Join(cfg.BackupDir, userId)); err != nil {
+			return nil, fmt.Errorf("failed to delete backups: %w", err)
+		}
+	}
+	if replicator := replication.NewReplicator(cfg); replicator != nil {
+		if report.OffsiteObjects, err = replicator.DeleteUserObjects(ctx, userId); err != nil {
+			return nil, fmt.Errorf("failed to delete off-site backups: %w", err)
+		}
+	}
+	if cfg.AccountExportDir != "" {
+		if report.AccountExports, err = removeDirectory(storage.AccountExportDirectory(cfg.AccountExportDir, userId)); err != nil {
+			return nil, fmt.Errorf("failed to delete account exports: %w", err)
+		}
+	}
+
+	report.ErasedAt = time.Now().UTC()
+	if err := store.EraseUser(ctx, report, user.Email); err != nil {
+		return nil, err
+	}
+	customLog.Ctx(ctx).Warnf("Erasure: Erased UserID %s (erasure %s, requested by UserID %s)", userId, report.ErasureID, requestedBy)
+	return report, nil
+}
+
+// removeDirectory deletes dir and everything in it, returning how many files it held.
+// A missing directory holds none.
+func removeDirectory(dir string) (int, error) {
+	files := 0
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			files++
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	return files, os.RemoveAll(dir)
+}
@@ -0,0 +1,143 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/mail"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func TestErase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		MetadataDbDir:    dir,
+		MetadataDbFile:   "meta.db",
+		BackupDir:        filepath.Join(dir, "backups"),
+		AccountExportDir: filepath.Join(dir, "exports"),
+	}
+	store, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	for _, u := range [][2]string{{"u1", "u1@example.com"}, {"u2", "u2@example.com"}} {
+		if _, err := store.CreateUser(ctx, u[0], "user_"+u[0], u[1], "hash"); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	location := storage.UserDataLocation(dir, "u1", "notes")
+	if err := storage.PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	if err := os.WriteFile(location, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "notes", location); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	// A database that cannot be opened, left with WAL files by a crash
+	broken := storage.UserDataLocation(dir, "u1", "broken")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.WriteFile(broken+suffix, []byte("not a database file"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "broken", broken); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseId, err := store.FindDatabaseIDByNameAndUser(ctx, "u1", "notes")
+	if err != nil {
+		t.Fatalf("FindDatabaseIDByNameAndUser: %v", err)
+	}
+	if _, err := store.StoreAPIKey(ctx, "u1", databaseId); err != nil {
+		t.Fatalf("StoreAPIKey: %v", err)
+	}
+	for _, path := range []string{
+		storage.BackupDirectory(cfg.BackupDir, "u1", "notes") + "/a.db",
+		storage.BackupDirectory(cfg.BackupDir, "u1", "notes") + "/b.db",
+		storage.BackupDirectory(cfg.BackupDir, "u2", "notes") + "/c.db",
+		storage.AccountExportDirectory(cfg.AccountExportDir, "u1") + "/e.zip",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, event := range []domain.AuditEvent{
+		{UserID: "u1", Event: "auth.failed_login_spike", IP: "203.0.113.7", Details: map[string]string{"email": "u1@example.com"}},
+		{Event: "auth.failed_login_spike", IP: "203.0.113.7", Details: map[string]string{"last_email": "U1@example.com"}},
+		{Event: "auth.failed_login_spike", IP: "198.51.100.2", Details: map[string]string{"last_email": "u2@example.com"}},
+		{UserID: "u2", Event: "auth.failed_login_spike", IP: "198.51.100.2"},
+	} {
+		if _, err := store.AddAuditEvent(ctx, event); err != nil {
+			t.Fatalf("AddAuditEvent: %v", err)
+		}
+	}
+	if err := mail.Enqueue(ctx, store, "u1@example.com", mail.TemplateAlert, mail.AlertData{Title: "x"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	report, err := Erase(ctx, store, cfg, "u1", "admin")
+	if err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	want := domain.ErasureReport{
+		ErasureID: report.ErasureID, UserID: "u1", RequestedBy: "admin", ErasedAt: report.ErasedAt,
+		Databases: 2, APIKeys: 1, Backups: 2, AccountExports: 1, AuditEventsAnonymized: 2, MailDeleted: 1,
+	}
+	if *report != want {
+		t.Errorf("report = %+v; want %+v", *report, want)
+	}
+
+	if _, err := store.FindUserByUserId(ctx, "u1"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("erased user still exists: %v", err)
+	}
+	if _, err := os.Stat(location); !os.IsNotExist(err) {
+		t.Errorf("database file still exists: %v", err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if _, err := os.Stat(broken + suffix); !os.IsNotExist(err) {
+			t.Errorf("file of the unreadable database still exists: %s%s", broken, suffix)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.BackupDir, "u1")); !os.IsNotExist(err) {
+		t.Errorf("backups still exist: %v", err)
+	}
+	if _, err := os.Stat(storage.BackupDirectory(cfg.BackupDir, "u2", "notes") + "/c.db"); err != nil {
+		t.Errorf("backup of another user was deleted: %v", err)
+	}
+	events, _, err := store.ListAuditEvents(ctx, "", 0, 0)
+	if err != nil || len(events) != 4 {
+		t.Fatalf("ListAuditEvents = %d events, %v; want the 4 events kept", len(events), err)
+	}
+	for _, event := range events {
+		mentionsU2 := event.UserID == "u2" || event.Details["last_email"] == "u2@example.com"
+		if !mentionsU2 && (event.UserID != "" || event.IP != "" || len(event.Details) != 0) {
+			t.Errorf("event %d of the erased user was not anonymized: %+v", event.EventID, event)
+		}
+		if mentionsU2 && event.IP == "" {
+			t.Errorf("event %d of another user was anonymized: %+v", event.EventID, event)
+		}
+	}
+
+	tombstone, err := store.FindErasure(ctx, report.ErasureID)
+	if err != nil || tombstone.UserID != "u1" || tombstone.Databases != 2 || !tombstone.ErasedAt.Equal(report.ErasedAt) {
+		t.Errorf("FindErasure = %+v, %v; want the report", tombstone, err)
+	}
+	if reports, err := store.ListErasures(ctx); err != nil || len(reports) != 1 {
+		t.Errorf("ListErasures = %+v, %v; want one report", reports, err)
+	}
+	if _, err := Erase(ctx, store, cfg, "u1", "admin"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("erasing an erased user = %v; want ErrUserNotFound", err)
+	}
+}
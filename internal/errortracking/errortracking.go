@@ -0,0 +1,511 @@
+// internal/errortracking/errortracking.go
+
+// Package errortracking reports panics and server errors to Sentry, or any service
+// accepting Sentry's envelope protocol (self-hosted Sentry, GlitchTip), with the
+// request they happened in and a stack trace. Events are queued in memory and sent by
+// a background worker; events that do not fit in the queue are dropped, so reporting
+// never slows requests down.
+package errortracking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+var customLog = logger.NewLogger()
+
+const (
+	clientName     = "nebula-backend/1.0"
+	modulePath     = "github.com/Annany2002/nebula-backend"
+	queueSize      = 100
+	maxFrames      = 64
+	dropLogEvery   = 100
+	defaultTimeout = 5 * time.Second
+	flushTimeout   = 2 * time.Second
+)
+
+// DSN is a parsed Sentry DSN: https://<public key>[:<secret key>]@<host>[/<path>]/<project id>.
+type DSN struct {
+	raw       string
+	endpoint  string // Envelope endpoint of the project
+	publicKey string
+	secretKey string
+}
+
+// ParseDSN parses a Sentry DSN.
+func ParseDSN(raw string) (*DSN, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid DSN scheme '%s' (use http or https)", u.Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("invalid DSN: missing public key")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if slash < 0 || projectID == "" {
+		return nil, errors.New("invalid DSN: missing project ID")
+	}
+	secretKey, _ := u.User.Password()
+	return &DSN{
+		raw:       raw,
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], projectID),
+		publicKey: u.User.Username(),
+		secretKey: secretKey,
+	}, nil
+}
+
+// authHeader is the X-Sentry-Auth header of requests to the DSN's project.
+func (d *DSN) authHeader() string {
+	header := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", clientName, d.publicKey)
+	if d.secretKey != "" {
+		header += ", sentry_secret=" + d.secretKey
+	}
+	return header
+}
+
+// Request describes the HTTP request an event happened in. Headers should only hold
+// values that are safe to send to a third party.
+type Request struct {
+	Method    string
+	URL       string
+	Query     string
+	Route     string // Route pattern, e.g. /api/v1/databases/:db_name
+	Headers   map[string]string
+	ClientIP  string
+	RequestID string
+	UserID    string
+}
+
+// Options configure a Tracker.
+type Options struct {
+	Environment string
+	Release     string
+	SampleRate  float64 // Share of events sent, from 0 to 1
+	Timeout     time.Duration
+}
+
+// Tracker sends events to the project of a DSN.
+type Tracker struct {
+	dsn        *DSN
+	opts       Options
+	serverName string
+	client     *http.Client
+	queue      chan *event
+	dropped    atomic.Int64
+	// Unix time before which events are dropped, after the service answered 429
+	rateLimitedUntil atomic.Int64
+}
+
+// NewTracker returns a tracker sending events to dsn's project.
+func NewTracker(dsn *DSN, opts Options) *Tracker {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	serverName, _ := os.Hostname()
+	return &Tracker{
+		dsn:        dsn,
+		opts:       opts,
+		serverName: serverName,
+		client:     &http.Client{Timeout: opts.Timeout},
+		queue:      make(chan *event, queueSize),
+	}
+}
+
+// New builds a tracker from configuration. It returns nil when no DSN is configured.
+func New(cfg *config.Config) (*Tracker, error) {
+	if cfg.SentryDSN == "" {
+		return nil, nil
+	}
+	dsn, err := ParseDSN(cfg.SentryDSN)
+	if err != nil {
+		return nil, err
+	}
+	return NewTracker(dsn, Options{
+		Environment: cfg.SentryEnvironment,
+		Release:     cfg.SentryRelease,
+		SampleRate:  cfg.SentrySampleRate,
+	}), nil
+}
+
+// event is the Sentry event payload.
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Exception   *exceptions       `json:"exception,omitempty"`
+	Request     *eventRequest     `json:"request,omitempty"`
+	User        *eventUser        `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]any    `json:"extra,omitempty"`
+}
+
+type exceptions struct {
+	Values []exception `json:"values"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Mechanism  *mechanism  `json:"mechanism,omitempty"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type mechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"` // Oldest call first
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+type eventRequest struct {
+	URL         string            `json:"url,omitempty"`
+	Method      string            `json:"method,omitempty"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+}
+
+type eventUser struct {
+	ID        string `json:"id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// CapturePanic queues an event for a recovered panic. It must be called from the
+// deferred function that recovered, so the stack still holds the panicking frames.
+func (t *Tracker) CapturePanic(ctx context.Context, recovered any, req Request) {
+	value := fmt.Sprint(recovered)
+	typ := fmt.Sprintf("%T", recovered)
+	if err, ok := recovered.(error); ok {
+		value, typ = err.Error(), errorType(err)
+	}
+	t.enqueue(ctx, t.newEvent("fatal", exception{
+		Type:       typ,
+		Value:      value,
+		Mechanism:  &mechanism{Type: "panic", Handled: false},
+		Stacktrace: panicStack(),
+	}, req, nil))
+}
+
+// CaptureError queues an event for err, which made the request fail with status.
+func (t *Tracker) CaptureError(ctx context.Context, err error, status int, req Request) {
+	t.captureError(ctx, err, status, req)
+}
+
+// captureError is called directly by both CaptureError functions, so the stack
+// starts at their caller.
+func (t *Tracker) captureError(ctx context.Context, err error, status int, req Request) {
+	ev := t.newEvent("error", exception{
+		Type:       errorType(err),
+		Value:      err.Error(),
+		Mechanism:  &mechanism{Type: "http", Handled: true},
+		Stacktrace: callerStack(3),
+	}, req, map[string]any{"status": status})
+	ev.Tags["status"] = strconv.Itoa(status)
+	t.enqueue(ctx, ev)
+}
+
+func (t *Tracker) newEvent(level string, exc exception, req Request, extra map[string]any) *event {
+	ev := &event{
+		EventID:     strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Timestamp:   time.Now().UTC(),
+		Level:       level,
+		Platform:    "go",
+		Logger:      "nebula",
+		ServerName:  t.serverName,
+		Environment: t.opts.Environment,
+		Release:     t.opts.Release,
+		Exception:   &exceptions{Values: []exception{exc}},
+		Tags:        map[string]string{},
+		Extra:       extra,
+	}
+	if req.Method != "" {
+		ev.Transaction = strings.TrimSpace(req.Method + " " + req.Route)
+		ev.Request = &eventRequest{URL: req.URL, Method: req.Method, QueryString: req.Query, Headers: req.Headers}
+		if req.ClientIP != "" {
+			ev.Request.Env = map[string]string{"REMOTE_ADDR": req.ClientIP}
+		}
+	}
+	if req.UserID != "" || req.ClientIP != "" {
+		ev.User = &eventUser{ID: req.UserID, IPAddress: req.ClientIP}
+	}
+	if req.RequestID != "" {
+		ev.Tags["request_id"] = req.RequestID
+	}
+	if req.Route != "" {
+		ev.Tags["route"] = req.Route
+	}
+	return ev
+}
+
+// enqueue queues ev unless it is sampled out, the service asked to back off or the
+// queue is full.
+func (t *Tracker) enqueue(ctx context.Context, ev *event) {
+	if t.opts.SampleRate < 1 && rand.Float64() >= t.opts.SampleRate {
+		return
+	}
+	if time.Now().Unix() < t.rateLimitedUntil.Load() {
+		return
+	}
+	select {
+	case t.queue <- ev:
+	default:
+		if dropped := t.dropped.Add(1); dropped%dropLogEvery == 1 {
+			customLog.Ctx(ctx).Warnf("ErrorTracking: Queue full, dropped %d event(s) so far", dropped)
+		}
+	}
+}
+
+// Run sends queued events until ctx is done, then sends what is still queued for up
+// to two seconds.
+func (t *Tracker) Run(ctx context.Context) {
+	customLog.Ctx(ctx).Printf("ErrorTracking: Reporting errors to %s", t.dsn.endpoint)
+	for {
+		select {
+		case ev := <-t.queue:
+			t.send(ctx, ev)
+		case <-ctx.Done():
+			t.flush()
+			return
+		}
+	}
+}
+
+func (t *Tracker) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	for {
+		select {
+		case ev := <-t.queue:
+			t.send(ctx, ev)
+		default:
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// send posts ev as an envelope. Failed events are logged and dropped.
+func (t *Tracker) send(ctx context.Context, ev *event) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		customLog.Warnf("ErrorTracking: Failed to encode event %s: %v", ev.EventID, err)
+		return
+	}
+	var body bytes.Buffer
+	envelopeHeader, _ := json.Marshal(map[string]string{
+		"event_id": ev.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      t.dsn.raw,
+	})
+	itemHeader, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+	body.Write(envelopeHeader)
+	body.WriteByte('\n')
+	body.Write(itemHeader)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.dsn.endpoint, &body)
+	if err != nil {
+		customLog.Warnf("ErrorTracking: Failed to build request for event %s: %v", ev.EventID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.dsn.authHeader())
+	resp, err := t.client.Do(req)
+	if err != nil {
+		customLog.Warnf("ErrorTracking: Failed to send event %s: %v", ev.EventID, err)
+		return
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		backoff := 60
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			backoff = seconds
+		}
+		t.rateLimitedUntil.Store(time.Now().Unix() + int64(backoff))
+		customLog.Warnf("ErrorTracking: Rate limited, dropping events for %ds", backoff)
+	case resp.StatusCode >= 300:
+		customLog.Warnf("ErrorTracking: Event %s rejected with status %d", ev.EventID, resp.StatusCode)
+	}
+}
+
+// errorType names the innermost error of err's chain, which is usually more telling
+// than the *fmt.wrapError around it.
+func errorType(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+// panicStack returns the stack of the panicking goroutine below runtime.gopanic,
+// i.e. from the frame that panicked down.
+func panicStack() *stacktrace {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var collected []runtime.Frame
+	panicking := false
+	for {
+		fr, more := frames.Next()
+		if panicking {
+			collected = append(collected, fr)
+		} else if fr.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			break
+		}
+	}
+	if !panicking { // Not called while panicking; report the caller's stack instead
+		return callerStack(2)
+	}
+	return toStacktrace(collected)
+}
+
+// callerStack returns the stack of the caller skip frames above callerStack.
+func callerStack(skip int) *stacktrace {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var collected []runtime.Frame
+	for {
+		fr, more := frames.Next()
+		collected = append(collected, fr)
+		if !more {
+			break
+		}
+	}
+	return toStacktrace(collected)
+}
+
+// toStacktrace converts frames, innermost first, to Sentry's order (oldest first).
+func toStacktrace(frames []runtime.Frame) *stacktrace {
+	st := &stacktrace{Frames: make([]frame, 0, len(frames))}
+	for i := len(frames) - 1; i >= 0; i-- {
+		fr := frames[i]
+		module, function := splitFunction(fr.Function)
+		st.Frames = append(st.Frames, frame{
+			Function: function,
+			Module:   module,
+			Filename: shortFile(fr.File),
+			AbsPath:  fr.File,
+			Lineno:   fr.Line,
+			InApp:    strings.HasPrefix(module, modulePath),
+		})
+	}
+	return st
+}
+
+// splitFunction splits "github.com/a/b/pkg.(*T).Method" into its package path and
+// "(*T).Method".
+func splitFunction(name string) (module, function string) {
+	lastSlash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[lastSlash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:lastSlash+1+dot], name[lastSlash+1+dot+1:]
+}
+
+// shortFile keeps the path of repository files relative to the module, and the last
+// two elements of others.
+func shortFile(path string) string {
+	if i := strings.Index(path, modulePath+"/"); i >= 0 {
+		return path[i+len(modulePath)+1:]
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		return strings.Join(parts[len(parts)-2:], "/")
+	}
+	return path
+}
+
+var (
+	mu      sync.RWMutex
+	tracker *Tracker
+)
+
+// Configure makes t the tracker events are reported to; nil disables reporting.
+func Configure(t *Tracker) {
+	mu.Lock()
+	defer mu.Unlock()
+	tracker = t
+}
+
+func current() *Tracker {
+	mu.RLock()
+	defer mu.RUnlock()
+	return tracker
+}
+
+// Enabled reports whether events are reported.
+func Enabled() bool {
+	return current() != nil
+}
+
+// CapturePanic reports a recovered panic to the configured tracker, if any. Like
+// Tracker.CapturePanic, it must be called from the recovering deferred function.
+func CapturePanic(ctx context.Context, recovered any, req Request) {
+	if t := current(); t != nil {
+		t.CapturePanic(ctx, recovered, req)
+	}
+}
+
+// CaptureError reports err, which made a request fail with status, to the configured
+// tracker, if any.
+func CaptureError(ctx context.Context, err error, status int, req Request) {
+	if t := current(); t != nil {
+		t.captureError(ctx, err, status, req)
+	}
+}
@@ -0,0 +1,267 @@
+// internal/eventbridge/bridge.go
+
+// Package eventbridge publishes the change feed of user databases (records created,
+// updated and deleted) to Kafka or NATS, so downstream systems can consume tenant
+// changes without polling. Changes are queued in memory and published in batches by a
+// background worker; delivery is at-least-once, and changes still queued when the
+// process stops are lost.
+package eventbridge
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/health"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+var customLog = logger.NewLogger()
+
+// Brokers the bridge can publish to.
+const (
+	BrokerKafka = "kafka"
+	BrokerNATS  = "nats"
+)
+
+const (
+	maxBatch       = 500
+	maxRetryDelay  = 30 * time.Second
+	beatInterval   = 30 * time.Second
+	dropLogEvery   = 1000
+	workerName     = "event_bridge"
+	defaultTimeout = 10 * time.Second
+)
+
+// Message is one message to publish.
+type Message struct {
+	Topic   string // Kafka topic or NATS subject
+	Key     []byte // Kafka partitioning key; unused by NATS
+	Value   []byte
+	Headers []Header
+}
+
+// Header is a Kafka record header.
+type Header struct {
+	Key   string
+	Value string
+}
+
+// Publisher publishes batches of messages to a broker. Publish returns once the broker
+// accepted every message, or an error after which the whole batch is retried.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// Change is a write to a user database, the value of published messages.
+type Change struct {
+	ID       string         `json:"id"`
+	Event    string         `json:"event"` // record.created, record.updated or record.deleted
+	UserID   string         `json:"user_id"`
+	DBName   string         `json:"db_name"`
+	Table    string         `json:"table"`
+	RecordID int64          `json:"record_id"`
+	Record   map[string]any `json:"record,omitempty"` // The stored record, for created and updated records
+	Time     time.Time      `json:"time"`
+}
+
+// Bridge queues changes and publishes them with a Publisher.
+type Bridge struct {
+	publisher  Publisher
+	topic      string // Topic template, see Topic
+	queue      chan Message
+	retryDelay time.Duration // Before the second attempt, doubling after each
+	dropped    atomic.Int64
+}
+
+// NewBridge returns a bridge publishing with publisher to the topics built from the
+// topic template, queueing up to queueSize changes.
+func NewBridge(publisher Publisher, topic string, queueSize int) *Bridge {
+	return &Bridge{
+		publisher:  publisher,
+		topic:      topic,
+		queue:      make(chan Message, queueSize),
+		retryDelay: time.Second,
+	}
+}
+
+// New builds a bridge from configuration. It returns nil when no broker is configured.
+func New(cfg *config.Config) (*Bridge, error) {
+	if cfg.EventBridge == "" {
+		return nil, nil
+	}
+	if len(cfg.EventBridgeBrokers) == 0 {
+		return nil, errors.New("no event bridge brokers configured")
+	}
+	var tlsConfig *tls.Config
+	if cfg.EventBridgeTLS {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	var publisher Publisher
+	switch cfg.EventBridge {
+	case BrokerKafka:
+		publisher = &KafkaPublisher{
+			Brokers:  cfg.EventBridgeBrokers,
+			TLS:      tlsConfig,
+			Username: cfg.EventBridgeUsername,
+			Password: cfg.EventBridgePassword,
+			Timeout:  defaultTimeout,
+		}
+	case BrokerNATS:
+		publisher = &NATSPublisher{
+			Servers:  cfg.EventBridgeBrokers,
+			TLS:      tlsConfig,
+			Username: cfg.EventBridgeUsername,
+			Password: cfg.EventBridgePassword,
+			Timeout:  defaultTimeout,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event bridge broker '%s'", cfg.EventBridge)
+	}
+	return NewBridge(publisher, cfg.EventBridgeTopic, cfg.EventBridgeQueueSize), nil
+}
+
+// Topic expands the placeholders {user_id}, {db_name}, {table} and {event} of the
+// topic template for change.
+func Topic(template string, change Change) string {
+	return strings.NewReplacer(
+		"{user_id}", change.UserID,
+		"{db_name}", change.DBName,
+		"{table}", change.Table,
+		"{event}", change.Event,
+	).Replace(template)
+}
+
+// Enqueue queues change for publishing. Changes are dropped, and counted, when the
+// queue is full so writes never wait on the broker.
+func (b *Bridge) Enqueue(ctx context.Context, change Change) {
+	if change.ID == "" {
+		change.ID = uuid.New().String()
+	}
+	if change.Time.IsZero() {
+		change.Time = time.Now().UTC()
+	}
+	value, err := json.Marshal(change)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("EventBridge: Failed to encode %s of record ID %d of table '%s': %v", change.Event, change.RecordID, change.Table, err)
+		return
+	}
+	// Keying by record keeps the changes of a record in order on one partition
+	key := change.UserID + "/" + change.DBName + "/" + change.Table + "/" + strconv.FormatInt(change.RecordID, 10)
+	msg := Message{
+		Topic: Topic(b.topic, change),
+		Key:   []byte(key),
+		Value: value,
+		Headers: []Header{
+			{Key: "event", Value: change.Event},
+			{Key: "id", Value: change.ID},
+		},
+	}
+	select {
+	case b.queue <- msg:
+	default:
+		if dropped := b.dropped.Add(1); dropped%dropLogEvery == 1 {
+			customLog.Ctx(ctx).Warnf("EventBridge: Queue full, dropped %d change(s) so far", dropped)
+		}
+	}
+}
+
+// Run publishes queued changes in batches until ctx is done, retrying failed batches
+// with increasing delays. It closes the publisher when it returns.
+func (b *Bridge) Run(ctx context.Context) {
+	customLog.Ctx(ctx).Printf("EventBridge: Publishing changes to '%s'", b.topic)
+	health.RegisterWorker(workerName, beatInterval)
+	defer health.UnregisterWorker(workerName)
+	defer b.publisher.Close()
+	ticker := time.NewTicker(beatInterval)
+	defer ticker.Stop()
+
+	for {
+		health.Beat(workerName)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			continue
+		case msg := <-b.queue:
+			b.publish(ctx, b.drain(msg))
+		}
+	}
+}
+
+// drain returns first and the messages queued behind it, up to maxBatch.
+func (b *Bridge) drain(first Message) []Message {
+	batch := []Message{first}
+	for len(batch) < maxBatch {
+		select {
+		case msg := <-b.queue:
+			batch = append(batch, msg)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// publish publishes batch, retrying until it is accepted or ctx is done.
+func (b *Bridge) publish(ctx context.Context, batch []Message) {
+	delay := b.retryDelay
+	for attempt := 1; ; attempt++ {
+		err := b.publisher.Publish(ctx, batch)
+		if err == nil {
+			if attempt > 1 {
+				customLog.Ctx(ctx).Printf("EventBridge: Published %d change(s) after %d attempts", len(batch), attempt)
+			}
+			return
+		}
+		customLog.Ctx(ctx).Warnf("EventBridge: Failed to publish %d change(s) (attempt %d): %v", len(batch), attempt, err)
+		health.Beat(workerName) // Retrying is progress; the broker's health is not ours
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+var (
+	mu     sync.RWMutex
+	bridge *Bridge
+)
+
+// Configure makes b the bridge Publish queues changes on; nil disables publishing.
+func Configure(b *Bridge) {
+	mu.Lock()
+	defer mu.Unlock()
+	bridge = b
+}
+
+func current() *Bridge {
+	mu.RLock()
+	defer mu.RUnlock()
+	return bridge
+}
+
+// Enabled reports whether changes are published.
+func Enabled() bool {
+	return current() != nil
+}
+
+// Publish queues change on the configured bridge, if any.
+func Publish(ctx context.Context, change Change) {
+	if b := current(); b != nil {
+		b.Enqueue(ctx, change)
+	}
+}
@@ -0,0 +1,314 @@
+package eventbridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKafka is a single-node Kafka broker that answers metadata and produce requests
+// and keeps the values produced to each topic and partition.
+type fakeKafka struct {
+	listener net.Listener
+	mu       sync.Mutex
+	produced map[string][]string // "topic/partition" -> values
+	requests []int16             // API keys, in order
+}
+
+func newFakeKafka(t *testing.T) *fakeKafka {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeKafka{listener: listener, produced: make(map[string][]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(t, conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeKafka) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+		d := kafkaDecoder{buf: req}
+		apiKey := d.int16()
+		d.int16() // version
+		correlation := d.int32()
+		d.string() // client ID
+		f.mu.Lock()
+		f.requests = append(f.requests, apiKey)
+		f.mu.Unlock()
+
+		var resp kafkaEncoder
+		resp.int32(0)
+		resp.int32(correlation)
+		switch apiKey {
+		case kafkaMetadata:
+			host, port, _ := net.SplitHostPort(f.listener.Addr().String())
+			portNum, _ := strconv.Atoi(port)
+			resp.int32(0) // throttle
+			resp.int32(1)
+			resp.int32(1)
+			resp.string(host)
+			resp.int32(int32(portNum))
+			resp.nullableString(nil)
+			resp.nullableString(nil) // cluster ID
+			resp.int32(1)            // controller
+			topics := d.int32()
+			resp.int32(topics)
+			for ; topics > 0; topics-- {
+				resp.int16(0)
+				resp.string(d.string())
+				resp.bool(false)
+				resp.int32(2) // partitions
+				for p := int32(0); p < 2; p++ {
+					resp.int16(0)
+					resp.int32(p)
+					resp.int32(1) // leader
+					resp.int32(0)
+					resp.int32(0)
+				}
+			}
+		case kafkaProduce:
+			d.nullableString()
+			if acks := d.int16(); acks != -1 {
+				t.Errorf("acks = %d, want -1", acks)
+			}
+			d.int32() // timeout
+			topics := d.int32()
+			resp.int32(topics)
+			for ; topics > 0; topics-- {
+				topic := d.string()
+				resp.string(topic)
+				partitions := d.int32()
+				resp.int32(partitions)
+				for ; partitions > 0; partitions-- {
+					partition := d.int32()
+					batch := d.take(int(d.int32()))
+					values, err := decodeRecordBatch(batch)
+					if err != nil {
+						t.Errorf("record batch: %v", err)
+					}
+					f.mu.Lock()
+					key := topic + "/" + strconv.Itoa(int(partition))
+					f.produced[key] = append(f.produced[key], values...)
+					f.mu.Unlock()
+					resp.int32(partition)
+					resp.int16(0)
+					resp.int64(0)
+					resp.int64(-1)
+				}
+			}
+			resp.int32(0) // throttle
+		default:
+			t.Errorf("unexpected API key %d", apiKey)
+			return
+		}
+		binary.BigEndian.PutUint32(resp.buf, uint32(len(resp.buf)-4))
+		if _, err := conn.Write(resp.buf); err != nil {
+			return
+		}
+	}
+}
+
+// decodeRecordBatch checks the CRC of a v2 record batch and returns its values.
+func decodeRecordBatch(batch []byte) ([]string, error) {
+	d := kafkaDecoder{buf: batch}
+	d.int64() // base offset
+	d.int32() // length
+	d.int32() // leader epoch
+	if magic := d.take(1); len(magic) != 1 || magic[0] != 2 {
+		return nil, errors.New("not a v2 batch")
+	}
+	crc := uint32(d.int32())
+	if crc32.Checksum(d.buf, castagnoli) != crc {
+		return nil, errors.New("CRC mismatch")
+	}
+	d.take(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	count := d.int32()
+	varint := func() int64 {
+		v, n := binary.Varint(d.buf)
+		d.take(n)
+		return v
+	}
+	var values []string
+	for ; count > 0; count-- {
+		varint() // length
+		d.take(1)
+		varint()
+		varint()
+		d.take(int(varint())) // key
+		values = append(values, string(d.take(int(varint()))))
+		for headers := varint(); headers > 0; headers-- {
+			d.take(int(varint()))
+			d.take(int(varint()))
+		}
+	}
+	return values, d.err
+}
+
+func TestKafkaPublish(t *testing.T) {
+	broker := newFakeKafka(t)
+	publisher := &KafkaPublisher{Brokers: []string{broker.listener.Addr().String()}, Timeout: 5 * time.Second}
+	defer publisher.Close()
+
+	var msgs []Message
+	for i := 0; i < 6; i++ {
+		msgs = append(msgs, Message{Topic: "changes", Key: []byte("record/" + strconv.Itoa(i)), Value: []byte("v" + strconv.Itoa(i))})
+	}
+	if err := publisher.Publish(context.Background(), msgs); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := publisher.Publish(context.Background(), msgs[:1]); err != nil {
+		t.Fatalf("second Publish: %v", err)
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.requests) != 3 || broker.requests[0] != kafkaMetadata {
+		t.Errorf("requests = %v, want metadata once then produce requests", broker.requests)
+	}
+	total := 0
+	for _, m := range msgs {
+		key := "changes/" + strconv.Itoa(int(partitionFor(m.Key, 2)))
+		if !strings.Contains(strings.Join(broker.produced[key], ","), string(m.Value)) {
+			t.Errorf("%s not produced to %s: %v", m.Value, key, broker.produced)
+		}
+	}
+	for _, values := range broker.produced {
+		total += len(values)
+	}
+	if total != 7 {
+		t.Errorf("produced %d values, want 7", total)
+	}
+}
+
+func TestNATSPublish(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				var opts map[string]any
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &opts); err != nil || opts["auth_token"] != "s3cret" {
+					conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+					return
+				}
+			case strings.HasPrefix(line, "PUB "):
+				fields := strings.Fields(line)
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				io.ReadFull(r, payload)
+				received <- fields[1] + " " + string(payload[:size])
+			case line == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	publisher := &NATSPublisher{Servers: []string{"nats://" + listener.Addr().String()}, Password: "s3cret", Timeout: 5 * time.Second}
+	defer publisher.Close()
+	err = publisher.Publish(context.Background(), []Message{
+		{Topic: "nebula.u1.app", Value: []byte(`{"id":"a"}`)},
+		{Topic: "nebula.u1.app", Value: []byte(`{"id":"b"}`)},
+	})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// The PONG confirming the batch is only sent once both messages were read
+	if got := len(received); got != 2 {
+		t.Fatalf("received %d messages, want 2", got)
+	}
+	if got := <-received; got != `nebula.u1.app {"id":"a"}` {
+		t.Errorf("first message = %q", got)
+	}
+}
+
+// fakePublisher fails its first failures batches.
+type fakePublisher struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]Message
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msgs []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.batches = append(p.batches, msgs)
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestBridgeRetriesAndDrops(t *testing.T) {
+	publisher := &fakePublisher{failures: 1}
+	b := NewBridge(publisher, "nebula.{user_id}.{db_name}.{table}", 2)
+	b.retryDelay = time.Millisecond
+	for i := int64(1); i <= 3; i++ {
+		b.Enqueue(context.Background(), Change{Event: "record.created", UserID: "u1", DBName: "app", Table: "users", RecordID: i})
+	}
+	if got := b.dropped.Load(); got != 1 {
+		t.Errorf("dropped = %d, want the change beyond the queue size", got)
+	}
+
+	b.publish(context.Background(), b.drain(<-b.queue))
+	if len(publisher.batches) != 1 || len(publisher.batches[0]) != 2 {
+		t.Fatalf("batches = %v, want one batch of both queued changes after a retry", publisher.batches)
+	}
+	msg := publisher.batches[0][1]
+	if msg.Topic != "nebula.u1.app.users" || string(msg.Key) != "u1/app/users/2" {
+		t.Errorf("topic = %q, key = %q", msg.Topic, msg.Key)
+	}
+	var change Change
+	if err := json.Unmarshal(msg.Value, &change); err != nil || change.RecordID != 2 || change.ID == "" || change.Time.IsZero() {
+		t.Errorf("value = %s (%v)", msg.Value, err)
+	}
+}
@@ -0,0 +1,538 @@
+// internal/eventbridge/kafka.go
+package eventbridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kafka API keys and the versions spoken; all are supported from Kafka 1.0 through 4.x.
+const (
+	kafkaProduce          = 0
+	kafkaMetadata         = 3
+	kafkaSaslHandshake    = 17
+	kafkaSaslAuthenticate = 36
+
+	kafkaProduceVersion   = 3
+	kafkaMetadataVersion  = 4
+	kafkaHandshakeVersion = 1
+	kafkaAuthVersion      = 0
+)
+
+// Kafka error codes after which the partition leaders are looked up again.
+const (
+	kafkaUnknownTopicOrPartition = 3
+	kafkaLeaderNotAvailable      = 5
+	kafkaNotLeaderForPartition   = 6
+)
+
+const kafkaClientID = "nebula-event-bridge"
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// KafkaPublisher produces messages to Kafka with the wire protocol: it looks up
+// partition leaders via metadata requests, hashes message keys to partitions and
+// waits for all in-sync replicas to acknowledge each batch (acks=all).
+type KafkaPublisher struct {
+	Brokers  []string    // Bootstrap brokers, host:port
+	TLS      *tls.Config // Nil for plaintext
+	Username string      // SASL/PLAIN credentials; empty disables SASL
+	Password string
+	Timeout  time.Duration
+
+	mu          sync.Mutex
+	conns       map[string]*kafkaConn // Broker address -> connection
+	brokers     map[int32]string      // Node ID -> address
+	leaders     map[string][]int32    // Topic -> leader node of each partition
+	correlation int32
+}
+
+// kafkaConn is a connection to one broker.
+type kafkaConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Publish produces msgs, one request per leader broker, and returns once every
+// message is acknowledged.
+func (k *KafkaPublisher) Publish(ctx context.Context, msgs []Message) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	topics := make([]string, 0)
+	for _, m := range msgs {
+		if _, ok := k.leaders[m.Topic]; !ok && !slices.Contains(topics, m.Topic) {
+			topics = append(topics, m.Topic)
+		}
+	}
+	if len(topics) > 0 {
+		if err := k.refreshMetadata(ctx, topics); err != nil {
+			return err
+		}
+	}
+
+	// Group messages by leader, then topic and partition
+	byLeader := make(map[int32]map[string]map[int32][]Message)
+	for _, m := range msgs {
+		leaders := k.leaders[m.Topic]
+		if len(leaders) == 0 {
+			return fmt.Errorf("kafka: topic '%s' has no partitions", m.Topic)
+		}
+		partition := partitionFor(m.Key, len(leaders))
+		leader := leaders[partition]
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[string]map[int32][]Message)
+		}
+		if byLeader[leader][m.Topic] == nil {
+			byLeader[leader][m.Topic] = make(map[int32][]Message)
+		}
+		byLeader[leader][m.Topic][partition] = append(byLeader[leader][m.Topic][partition], m)
+	}
+
+	for leader, topicMsgs := range byLeader {
+		addr, ok := k.brokers[leader]
+		if !ok {
+			k.leaders = nil
+			return fmt.Errorf("kafka: leader %d is not a known broker", leader)
+		}
+		if err := k.produce(ctx, addr, topicMsgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the broker connections.
+func (k *KafkaPublisher) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for addr, c := range k.conns {
+		_ = c.conn.Close()
+		delete(k.conns, addr)
+	}
+	return nil
+}
+
+// partitionFor picks the partition of a message key, so that the changes of one
+// record stay in order. Messages without a key go to partition 0.
+func partitionFor(key []byte, partitions int) int32 {
+	if len(key) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int32(h.Sum32() % uint32(partitions))
+}
+
+// refreshMetadata looks up the brokers and the partition leaders of topics.
+func (k *KafkaPublisher) refreshMetadata(ctx context.Context, topics []string) error {
+	var body kafkaEncoder
+	body.int32(int32(len(topics)))
+	for _, topic := range topics {
+		body.string(topic)
+	}
+	body.bool(true) // allow_auto_topic_creation
+
+	var lastErr error
+	for _, addr := range k.bootstrapOrder() {
+		resp, err := k.roundTrip(ctx, addr, kafkaMetadata, kafkaMetadataVersion, body.buf)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return k.readMetadata(resp)
+	}
+	return fmt.Errorf("kafka: no broker answered the metadata request: %w", lastErr)
+}
+
+// bootstrapOrder returns the known brokers, bootstrap brokers first.
+func (k *KafkaPublisher) bootstrapOrder() []string {
+	addrs := append([]string(nil), k.Brokers...)
+	for _, addr := range k.brokers {
+		if !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func (k *KafkaPublisher) readMetadata(resp []byte) error {
+	d := kafkaDecoder{buf: resp}
+	d.int32() // throttle_time_ms
+	if k.brokers == nil {
+		k.brokers = make(map[int32]string)
+	}
+	for n := d.int32(); n > 0; n-- {
+		node := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.nullableString() // rack
+		k.brokers[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.nullableString() // cluster_id
+	d.int32()          // controller_id
+	if k.leaders == nil {
+		k.leaders = make(map[string][]int32)
+	}
+	for n := d.int32(); n > 0; n-- {
+		code := d.int16()
+		topic := d.string()
+		d.bool() // is_internal
+		leaders := make(map[int32]int32)
+		count := d.int32()
+		for i := int32(0); i < count; i++ {
+			d.int16() // partition error_code
+			index := d.int32()
+			leaders[index] = d.int32()
+			d.int32Array() // replica_nodes
+			d.int32Array() // isr_nodes
+		}
+		if d.err != nil {
+			break
+		}
+		if code != 0 {
+			return fmt.Errorf("kafka: metadata of topic '%s' failed with error code %d", topic, code)
+		}
+		byIndex := make([]int32, len(leaders))
+		for index, leader := range leaders {
+			if int(index) >= len(byIndex) {
+				return fmt.Errorf("kafka: topic '%s' has a gap in its partitions", topic)
+			}
+			byIndex[index] = leader
+		}
+		k.leaders[topic] = byIndex
+	}
+	if d.err != nil {
+		return fmt.Errorf("kafka: malformed metadata response: %w", d.err)
+	}
+	return nil
+}
+
+// produce sends one produce request for messages grouped by topic and partition.
+func (k *KafkaPublisher) produce(ctx context.Context, addr string, topicMsgs map[string]map[int32][]Message) error {
+	var body kafkaEncoder
+	body.nullableString(nil) // transactional_id
+	body.int16(-1)           // acks: all in-sync replicas
+	body.int32(int32(k.timeout().Milliseconds()))
+	body.int32(int32(len(topicMsgs)))
+	for topic, partitions := range topicMsgs {
+		body.string(topic)
+		body.int32(int32(len(partitions)))
+		for partition, msgs := range partitions {
+			body.int32(partition)
+			body.bytes(encodeRecordBatch(msgs, time.Now()))
+		}
+	}
+
+	resp, err := k.roundTrip(ctx, addr, kafkaProduce, kafkaProduceVersion, body.buf)
+	if err != nil {
+		return err
+	}
+	d := kafkaDecoder{buf: resp}
+	for n := d.int32(); n > 0; n-- {
+		topic := d.string()
+		for p := d.int32(); p > 0; p-- {
+			partition := d.int32()
+			code := d.int16()
+			d.int64() // base_offset
+			d.int64() // log_append_time_ms
+			if d.err == nil && code != 0 {
+				if code == kafkaNotLeaderForPartition || code == kafkaLeaderNotAvailable || code == kafkaUnknownTopicOrPartition {
+					delete(k.leaders, topic)
+				}
+				return fmt.Errorf("kafka: producing to %s/%d failed with error code %d", topic, partition, code)
+			}
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("kafka: malformed produce response: %w", d.err)
+	}
+	return nil
+}
+
+func (k *KafkaPublisher) timeout() time.Duration {
+	if k.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return k.Timeout
+}
+
+// roundTrip sends a request to the broker at addr and returns the response body. The
+// connection is dropped on any error, so the next request starts afresh.
+func (k *KafkaPublisher) roundTrip(ctx context.Context, addr string, apiKey, version int16, body []byte) ([]byte, error) {
+	c, err := k.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := k.exchange(ctx, c, apiKey, version, body)
+	if err != nil {
+		_ = c.conn.Close()
+		delete(k.conns, addr)
+		return nil, fmt.Errorf("kafka: %s: %w", addr, err)
+	}
+	return resp, nil
+}
+
+func (k *KafkaPublisher) exchange(ctx context.Context, c *kafkaConn, apiKey, version int16, body []byte) ([]byte, error) {
+	k.correlation++
+	correlation := k.correlation
+
+	var req kafkaEncoder
+	req.int32(0) // Size, filled in below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(correlation)
+	req.string(kafkaClientID)
+	req.buf = append(req.buf, body...)
+	binary.BigEndian.PutUint32(req.buf, uint32(len(req.buf)-4))
+
+	deadline := time.Now().Add(k.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+	if _, err := c.conn.Write(req.buf); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > 64<<20 {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if got := int32(binary.BigEndian.Uint32(header[4:])); got != correlation {
+		return nil, fmt.Errorf("response to request %d received for request %d", got, correlation)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// conn returns the connection to addr, dialing and authenticating it if needed.
+func (k *KafkaPublisher) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	if c, ok := k.conns[addr]; ok {
+		return c, nil
+	}
+	dialer := &net.Dialer{Timeout: k.timeout()}
+	var conn net.Conn
+	var err error
+	if k.TLS != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: k.TLS}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kafka: %w", err)
+	}
+	c := &kafkaConn{conn: conn, r: bufio.NewReader(conn)}
+	if k.Username != "" {
+		if err := k.authenticate(ctx, c); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("kafka: %s: %w", addr, err)
+		}
+	}
+	if k.conns == nil {
+		k.conns = make(map[string]*kafkaConn)
+	}
+	k.conns[addr] = c
+	return c, nil
+}
+
+// authenticate runs the SASL/PLAIN handshake on a new connection.
+func (k *KafkaPublisher) authenticate(ctx context.Context, c *kafkaConn) error {
+	var handshake kafkaEncoder
+	handshake.string("PLAIN")
+	resp, err := k.exchange(ctx, c, kafkaSaslHandshake, kafkaHandshakeVersion, handshake.buf)
+	if err != nil {
+		return err
+	}
+	d := kafkaDecoder{buf: resp}
+	if code := d.int16(); code != 0 {
+		return fmt.Errorf("SASL handshake failed with error code %d (is PLAIN enabled?)", code)
+	}
+
+	var auth kafkaEncoder
+	auth.bytes([]byte("\x00" + k.Username + "\x00" + k.Password))
+	resp, err = k.exchange(ctx, c, kafkaSaslAuthenticate, kafkaAuthVersion, auth.buf)
+	if err != nil {
+		return err
+	}
+	d = kafkaDecoder{buf: resp}
+	if code := d.int16(); code != 0 {
+		message := d.nullableString()
+		return fmt.Errorf("SASL authentication failed with error code %d: %s", code, message)
+	}
+	return nil
+}
+
+// encodeRecordBatch encodes msgs as an uncompressed v2 record batch.
+func encodeRecordBatch(msgs []Message, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+	var records kafkaEncoder
+	for i, m := range msgs {
+		var r kafkaEncoder
+		r.int8(0)           // attributes
+		r.varint(0)         // timestamp delta
+		r.varint(int64(i))  // offset delta
+		r.varbytes(m.Key)   // key
+		r.varbytes(m.Value) // value
+		r.varint(int64(len(m.Headers)))
+		for _, h := range m.Headers {
+			r.varbytes([]byte(h.Key))
+			r.varbytes([]byte(h.Value))
+		}
+		records.varint(int64(len(r.buf)))
+		records.buf = append(records.buf, r.buf...)
+	}
+
+	// The CRC covers everything from the attributes on
+	var tail kafkaEncoder
+	tail.int16(0) // attributes: no compression, not transactional
+	tail.int32(int32(len(msgs) - 1))
+	tail.int64(timestamp) // base timestamp
+	tail.int64(timestamp) // max timestamp
+	tail.int64(-1)        // producer ID
+	tail.int16(-1)        // producer epoch
+	tail.int32(-1)        // base sequence
+	tail.int32(int32(len(msgs)))
+	tail.buf = append(tail.buf, records.buf...)
+
+	var batch kafkaEncoder
+	batch.int64(0)                                // base offset
+	batch.int32(int32(4 + 1 + 4 + len(tail.buf))) // batch length, from the leader epoch on
+	batch.int32(-1)                               // partition leader epoch
+	batch.int8(2)                                 // magic
+	batch.int32(int32(crc32.Checksum(tail.buf, castagnoli)))
+	batch.buf = append(batch.buf, tail.buf...)
+	return batch.buf
+}
+
+// kafkaEncoder appends Kafka protocol primitives to buf.
+type kafkaEncoder struct{ buf []byte }
+
+func (e *kafkaEncoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *kafkaEncoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v) // Zigzag, as Kafka expects
+}
+
+func (e *kafkaEncoder) bool(v bool) {
+	if v {
+		e.int8(1)
+	} else {
+		e.int8(0)
+	}
+}
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) nullableString(s *string) {
+	if s == nil {
+		e.int16(-1)
+		return
+	}
+	e.string(*s)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *kafkaEncoder) varbytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder reads Kafka protocol primitives from buf. The first error sticks and
+// makes later reads return zero values.
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+var errShortResponse = errors.New("response too short")
+
+func (d *kafkaDecoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errShortResponse
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) bool() bool {
+	b := d.take(1)
+	return b != nil && b[0] != 0
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.take(int(d.int16())))
+}
+
+func (d *kafkaDecoder) nullableString() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.take(int(n)))
+}
+
+func (d *kafkaDecoder) int32Array() {
+	n := d.int32()
+	if n > 0 {
+		d.take(int(n) * 4)
+	}
+}
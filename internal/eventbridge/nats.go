@@ -0,0 +1,201 @@
+// internal/eventbridge/nats.go
+package eventbridge
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSPublisher publishes messages to NATS subjects with the client protocol. A batch
+// is confirmed with a PING once published, so a returned nil means the server accepted
+// every message. Subjects captured by a JetStream stream are stored durably.
+type NATSPublisher struct {
+	Servers  []string    // host:port, optionally prefixed with nats:// or tls://
+	TLS      *tls.Config // Nil for plaintext, unless the server requires TLS
+	Username string      // User and password, or only a token in Password
+	Password string
+	Timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	next int // Server tried next when (re)connecting
+}
+
+// natsInfo is the part of the server's INFO message the publisher uses.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// Publish publishes msgs and waits for the server to confirm them.
+func (n *NATSPublisher) Publish(ctx context.Context, msgs []Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := n.publish(ctx, msgs); err != nil {
+		n.closeConn()
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// Close closes the server connection.
+func (n *NATSPublisher) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closeConn()
+	return nil
+}
+
+func (n *NATSPublisher) closeConn() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+		n.conn, n.r = nil, nil
+	}
+}
+
+func (n *NATSPublisher) timeout() time.Duration {
+	if n.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return n.Timeout
+}
+
+func (n *NATSPublisher) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(n.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return deadline
+}
+
+// connect dials the servers in turn until one accepts the connection.
+func (n *NATSPublisher) connect(ctx context.Context) error {
+	if len(n.Servers) == 0 {
+		return errors.New("nats: no servers configured")
+	}
+	var lastErr error
+	for range n.Servers {
+		server := n.Servers[n.next%len(n.Servers)]
+		n.next++
+		if err := n.dial(ctx, server); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("nats: no server accepted the connection: %w", lastErr)
+}
+
+func (n *NATSPublisher) dial(ctx context.Context, server string) error {
+	addr, forceTLS := strings.CutPrefix(server, "tls://")
+	addr = strings.TrimPrefix(addr, "nats://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "4222")
+	}
+	conn, err := (&net.Dialer{Timeout: n.timeout()}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(n.deadline(ctx))
+
+	// The server speaks first, with INFO; TLS is negotiated after it
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	var info natsInfo
+	if !ok || json.Unmarshal([]byte(infoJSON), &info) != nil {
+		conn.Close()
+		return fmt.Errorf("%s: unexpected greeting %q", addr, strings.TrimSpace(line))
+	}
+	if n.TLS != nil || forceTLS || info.TLSRequired {
+		config := &tls.Config{MinVersion: tls.VersionTLS12}
+		if n.TLS != nil {
+			config = n.TLS.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("%s: TLS handshake: %w", addr, err)
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect := map[string]any{"verbose": false, "pedantic": false, "name": kafkaClientID, "lang": "go", "version": "1", "protocol": 1}
+	switch {
+	case n.Username != "":
+		connect["user"], connect["pass"] = n.Username, n.Password
+	case n.Password != "":
+		connect["auth_token"] = n.Password
+	}
+	payload, _ := json.Marshal(connect)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", payload); err != nil {
+		conn.Close()
+		return err
+	}
+	n.conn, n.r = conn, r
+	if err := n.awaitPong(); err != nil {
+		n.closeConn()
+		return fmt.Errorf("%s: %w", addr, err)
+	}
+	return nil
+}
+
+// publish writes a PUB per message followed by a PING, and waits for the PONG.
+func (n *NATSPublisher) publish(ctx context.Context, msgs []Message) error {
+	_ = n.conn.SetDeadline(n.deadline(ctx))
+	w := bufio.NewWriter(n.conn)
+	for _, m := range msgs {
+		w.WriteString("PUB " + m.Topic + " " + strconv.Itoa(len(m.Value)) + "\r\n")
+		w.Write(m.Value)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("PING\r\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return n.awaitPong()
+}
+
+// awaitPong reads server messages until the PONG answering the last PING, answering
+// the server's own PINGs meanwhile.
+func (n *NATSPublisher) awaitPong() error {
+	for {
+		line, err := n.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need no answer
+	}
+}
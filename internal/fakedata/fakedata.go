@@ -0,0 +1,277 @@
+// internal/fakedata/fakedata.go
+// Package fakedata generates realistic-looking fake records for tables, so frontend
+// developers can prototype against populated tables. Values are chosen from the name
+// and type of each column: a TEXT column named "email" gets email addresses, an
+// INTEGER column named "age" plausible ages, a column ending in "_at" timestamps.
+// Columns without a recognized name get filler of their type. Generated addresses,
+// phone numbers and hosts come from ranges reserved for documentation.
+package fakedata
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+var (
+	firstNames = []string{"Ada", "Alan", "Amara", "Ben", "Carla", "Chen", "Dana", "Diego", "Elena", "Farah", "Grace", "Hiro", "Ines", "Ivan", "Jonas", "Kofi", "Lena", "Liam", "Maya", "Mateo", "Nadia", "Omar", "Priya", "Quinn", "Rosa", "Sam", "Sofia", "Tariq", "Uma", "Yara"}
+	lastNames  = []string{"Adams", "Berg", "Costa", "Diaz", "Evans", "Fischer", "Garcia", "Hughes", "Ito", "Jensen", "Khan", "Lopez", "Martin", "Novak", "Okafor", "Patel", "Quist", "Rossi", "Silva", "Tanaka", "Usman", "Varga", "Weber", "Young", "Zhang"}
+	cities     = []string{"Amsterdam", "Austin", "Bangalore", "Berlin", "Buenos Aires", "Cape Town", "Lisbon", "Melbourne", "Montreal", "Nairobi", "Osaka", "Paris", "Seoul", "Stockholm", "Toronto"}
+	countries  = []string{"Argentina", "Australia", "Brazil", "Canada", "France", "Germany", "India", "Japan", "Kenya", "Netherlands", "Portugal", "South Africa", "South Korea", "Sweden", "United States"}
+	streets    = []string{"Oak Street", "Maple Avenue", "Park Road", "Church Lane", "High Street", "Mill Road", "River Drive", "Station Road", "Elm Court", "Hill View"}
+	companies  = []string{"Acme", "Bluebird", "Cobalt", "Driftwood", "Evergreen", "Foxglove", "Granite", "Harbor", "Ironwood", "Juniper", "Kestrel", "Lumen"}
+	suffixes   = []string{"Inc.", "Labs", "Group", "Systems", "Co.", "Studio"}
+	words      = []string{"alpha", "amber", "bright", "calm", "cloud", "delta", "early", "field", "forest", "garden", "harbor", "island", "light", "market", "meadow", "north", "ocean", "quiet", "river", "silver", "spring", "stone", "summer", "valley", "winter"}
+	statuses   = []string{"active", "pending", "inactive", "archived"}
+	colors     = []string{"red", "orange", "yellow", "green", "blue", "purple", "pink", "gray", "black", "white"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// epoch anchors generated timestamps: they fall in the three years before it.
+var epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Generator produces fake records. It is not safe for concurrent use.
+type Generator struct {
+	rng *rand.Rand
+	seq int // Numbers records, to keep emails and usernames distinct
+}
+
+// New returns a Generator; the same seed gives the same records.
+func New(seed int64) *Generator {
+	return &Generator{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Record returns a fake record for a table with the given column types (as returned
+// by storage.PragmaTableInfo), with values as decoded from JSON (numbers are float64).
+// The built-in id and created_at columns are left out, as are columns whose rule has a
+// pattern, which generated values could not be relied on to match. Formats and min/max
+// bounds of the rules are respected.
+func (g *Generator) Record(columnTypes map[string]string, rules []domain.ColumnRule) map[string]any {
+	g.seq++
+	byColumn := make(map[string]domain.ColumnRule, len(rules))
+	for _, rule := range rules {
+		byColumn[rule.Column] = rule
+	}
+	first := firstNames[g.rng.Intn(len(firstNames))]
+	last := lastNames[g.rng.Intn(len(lastNames))]
+
+	record := make(map[string]any, len(columnTypes))
+	for _, name := range slices.Sorted(maps.Keys(columnTypes)) { // Sorted, so a seed repeats its records
+		columnType := columnTypes[name]
+		if name == "id" || name == "created_at" {
+			continue
+		}
+		rule := byColumn[name]
+		if rule.Pattern != "" {
+			continue
+		}
+		switch columnType {
+		case "INTEGER":
+			record[name] = g.integer(name, rule)
+		case "REAL":
+			record[name] = g.real(name, rule)
+		case "BOOLEAN":
+			record[name] = g.rng.Intn(2) == 1
+		default:
+			record[name] = fitLength(g.text(name, rule, first, last), rule, g.rng)
+		}
+	}
+	return record
+}
+
+// text returns a TEXT value for the column; first and last name the person the record
+// describes, so that its name and email columns agree.
+func (g *Generator) text(name string, rule domain.ColumnRule, first, last string) string {
+	switch rule.Format {
+	case core.FormatEmail:
+		return g.email(first, last)
+	case core.FormatURL:
+		return g.url()
+	}
+	switch {
+	case strings.Contains(name, "email"):
+		return g.email(first, last)
+	case name == "first_name" || name == "firstname" || name == "given_name":
+		return first
+	case name == "last_name" || name == "lastname" || name == "surname" || name == "family_name":
+		return last
+	case strings.Contains(name, "username") || name == "login" || name == "handle":
+		return fmt.Sprintf("%s%s%d", strings.ToLower(first), strings.ToLower(last[:1]), g.seq)
+	case name == "name" || strings.HasSuffix(name, "_name") || name == "author" || name == "owner":
+		if strings.HasPrefix(name, "company") || strings.HasPrefix(name, "org") {
+			return g.company()
+		}
+		return first + " " + last
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile"):
+		return fmt.Sprintf("+1-555-%03d-%04d", g.rng.Intn(1000), g.rng.Intn(10000))
+	case strings.Contains(name, "city"):
+		return pick(g.rng, cities)
+	case strings.Contains(name, "country"):
+		return pick(g.rng, countries)
+	case strings.Contains(name, "address") || strings.Contains(name, "street"):
+		return fmt.Sprintf("%d %s", 1+g.rng.Intn(999), pick(g.rng, streets))
+	case strings.Contains(name, "zip") || strings.Contains(name, "postal"):
+		return fmt.Sprintf("%05d", g.rng.Intn(100000))
+	case strings.Contains(name, "company") || strings.Contains(name, "organization"):
+		return g.company()
+	case strings.Contains(name, "image") || strings.Contains(name, "avatar") || strings.Contains(name, "photo"):
+		return fmt.Sprintf("https://%s/images/%d.png", pick(g.rng, domains), g.rng.Intn(100000))
+	case strings.Contains(name, "url") || strings.Contains(name, "website") || strings.Contains(name, "link"):
+		return g.url()
+	case name == "ip" || strings.HasPrefix(name, "ip_") || strings.HasSuffix(name, "_ip"):
+		return fmt.Sprintf("192.0.2.%d", 1+g.rng.Intn(254))
+	case strings.Contains(name, "uuid") || strings.Contains(name, "guid"):
+		return g.uuid()
+	case strings.Contains(name, "status") || strings.Contains(name, "state"):
+		return pick(g.rng, statuses)
+	case strings.Contains(name, "color") || strings.Contains(name, "colour"):
+		return pick(g.rng, colors)
+	case strings.Contains(name, "slug"):
+		return g.words(3, "-")
+	case strings.Contains(name, "code") || strings.Contains(name, "sku"):
+		return fmt.Sprintf("%s-%04d", strings.ToUpper(pick(g.rng, words)[:3]), g.rng.Intn(10000))
+	case strings.Contains(name, "title") || strings.Contains(name, "subject") || strings.Contains(name, "label"):
+		return capitalize(g.words(2+g.rng.Intn(3), " "))
+	case strings.Contains(name, "description") || strings.Contains(name, "body") || strings.Contains(name, "content") ||
+		strings.Contains(name, "notes") || strings.Contains(name, "comment") || strings.Contains(name, "bio") || strings.Contains(name, "message"):
+		return capitalize(g.words(8+g.rng.Intn(12), " ")) + "."
+	case strings.HasSuffix(name, "_at") || strings.Contains(name, "date") || strings.Contains(name, "time"):
+		return g.timestamp().Format(time.RFC3339)
+	case strings.HasSuffix(name, "_id"):
+		return g.uuid()
+	}
+	return g.words(2, " ")
+}
+
+// integer returns an INTEGER value for the column, within the bounds of its rule.
+func (g *Generator) integer(name string, rule domain.ColumnRule) float64 {
+	low, high := 0.0, 1000.0
+	switch {
+	case strings.Contains(name, "age"):
+		low, high = 18, 80
+	case strings.Contains(name, "year"):
+		low, high = 1990, float64(epoch.Year())
+	case strings.Contains(name, "rating") || strings.Contains(name, "stars"):
+		low, high = 1, 5
+	case strings.Contains(name, "quantity") || strings.Contains(name, "qty") || strings.Contains(name, "count") || strings.Contains(name, "stock"):
+		low, high = 0, 100
+	case strings.Contains(name, "price") || strings.Contains(name, "amount") || strings.Contains(name, "cost") || strings.Contains(name, "total"):
+		low, high = 1, 500
+	case strings.HasSuffix(name, "_at") || strings.Contains(name, "timestamp"):
+		return float64(g.timestamp().Unix())
+	}
+	low, high = bounds(low, high, rule)
+	low, high = math.Ceil(low), math.Floor(high)
+	if high < low { // No integer between the bounds; the rule check reports it
+		return low
+	}
+	return low + float64(g.rng.Int63n(int64(high-low)+1))
+}
+
+// real returns a REAL value for the column, within the bounds of its rule.
+func (g *Generator) real(name string, rule domain.ColumnRule) float64 {
+	low, high, decimals := 0.0, 1000.0, 2.0
+	switch {
+	case strings.HasPrefix(name, "lat"):
+		low, high, decimals = -90, 90, 6
+	case strings.HasPrefix(name, "lng") || strings.HasPrefix(name, "lon"):
+		low, high, decimals = -180, 180, 6
+	case strings.Contains(name, "rating") || strings.Contains(name, "score"):
+		low, high, decimals = 1, 5, 1
+	case strings.Contains(name, "price") || strings.Contains(name, "amount") || strings.Contains(name, "cost") || strings.Contains(name, "total"):
+		low, high = 1, 500
+	case strings.Contains(name, "percent") || strings.Contains(name, "ratio"):
+		low, high = 0, 100
+	}
+	low, high = bounds(low, high, rule)
+	scale := math.Pow(10, decimals)
+	value := math.Round((low+g.rng.Float64()*(high-low))*scale) / scale
+	return math.Min(math.Max(value, low), high)
+}
+
+// bounds narrows the default range [low, high] of a column to the min and max of its
+// rule, shifting it when the rule excludes it entirely.
+func bounds(low, high float64, rule domain.ColumnRule) (float64, float64) {
+	if rule.Min != nil {
+		low = math.Max(low, *rule.Min)
+	}
+	if rule.Max != nil {
+		high = math.Min(high, *rule.Max)
+	}
+	if low > high {
+		switch {
+		case rule.Min != nil && rule.Max != nil:
+			low, high = *rule.Min, *rule.Max
+		case rule.Min != nil:
+			high = low + 1000
+		default:
+			low = high - 1000
+		}
+	}
+	return low, high
+}
+
+// fitLength pads or cuts value to the length bounds of rule.
+func fitLength(value string, rule domain.ColumnRule, rng *rand.Rand) string {
+	if rule.Max != nil && float64(len([]rune(value))) > *rule.Max {
+		value = string([]rune(value)[:int(math.Max(*rule.Max, 0))])
+	}
+	if rule.Min != nil {
+		for float64(len([]rune(value))) < *rule.Min {
+			value += string(rune('a' + rng.Intn(26)))
+		}
+	}
+	return value
+}
+
+func (g *Generator) email(first, last string) string {
+	return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), g.seq, pick(g.rng, domains))
+}
+
+func (g *Generator) url() string {
+	return fmt.Sprintf("https://%s/%s", pick(g.rng, domains), g.words(2, "-"))
+}
+
+func (g *Generator) company() string {
+	return pick(g.rng, companies) + " " + pick(g.rng, suffixes)
+}
+
+func (g *Generator) uuid() string {
+	b := make([]byte, 16)
+	g.rng.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// timestamp returns a time in the three years before epoch, to the second.
+func (g *Generator) timestamp() time.Time {
+	const span = 3 * 365 * 24 * 60 * 60
+	return epoch.Add(-time.Duration(1+g.rng.Int63n(span)) * time.Second)
+}
+
+func (g *Generator) words(n int, sep string) string {
+	picked := make([]string, n)
+	for i := range picked {
+		picked[i] = pick(g.rng, words)
+	}
+	return strings.Join(picked, sep)
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,99 @@
+// internal/fakedata/fakedata_test.go
+package fakedata
+
+import (
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+var testColumns = map[string]string{
+	"id":         "INTEGER",
+	"created_at": "TEXT",
+	"email":      "TEXT",
+	"full_name":  "TEXT",
+	"age":        "INTEGER",
+	"price":      "REAL",
+	"active":     "BOOLEAN",
+	"signed_up":  "TEXT",
+	"updated_at": "TEXT",
+	"website":    "TEXT",
+	"notes":      "TEXT",
+}
+
+func TestRecordMatchesColumns(t *testing.T) {
+	g := New(1)
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		record := g.Record(testColumns, nil)
+		if _, ok := record["id"]; ok {
+			t.Fatal("record sets the id column")
+		}
+		if _, ok := record["created_at"]; ok {
+			t.Fatal("record sets the created_at column")
+		}
+		if _, _, err := core.RecordAssignments(testColumns, record); err != nil {
+			t.Fatalf("RecordAssignments(%v) error = %v", record, err)
+		}
+
+		email := record["email"].(string)
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			t.Errorf("email = %q, not an email address", email)
+		}
+		if seen[email] {
+			t.Errorf("email %q generated twice", email)
+		}
+		seen[email] = true
+		if name := record["full_name"].(string); len(strings.Fields(name)) != 2 {
+			t.Errorf("full_name = %q, want first and last name", name)
+		}
+		if age := record["age"].(float64); age < 18 || age > 80 {
+			t.Errorf("age = %v, want 18 to 80", age)
+		}
+		if _, err := time.Parse(time.RFC3339, record["updated_at"].(string)); err != nil {
+			t.Errorf("updated_at = %q, not a timestamp", record["updated_at"])
+		}
+		if !strings.HasPrefix(record["website"].(string), "https://") {
+			t.Errorf("website = %q, not a URL", record["website"])
+		}
+	}
+}
+
+func TestRecordRespectsRules(t *testing.T) {
+	columns := map[string]string{"contact": "TEXT", "score": "INTEGER", "ratio": "REAL", "code": "TEXT", "sku": "TEXT"}
+	low, high, length := 1000.0, 1010.0, 3.0
+	rules := []domain.ColumnRule{
+		{Column: "contact", Format: core.FormatEmail},
+		{Column: "score", Min: &low, Max: &high},
+		{Column: "ratio", Max: &length},
+		{Column: "code", Max: &length},
+		{Column: "sku", Pattern: "^[0-9]+$"},
+	}
+	if err := core.ValidateColumnRules(rules, columns); err != nil {
+		t.Fatal(err)
+	}
+	g := New(2)
+	for i := 0; i < 100; i++ {
+		record := g.Record(columns, rules)
+		if _, ok := record["sku"]; ok {
+			t.Fatal("record sets a column with a pattern rule")
+		}
+		if errs := core.CheckColumnRules(rules, record); len(errs) > 0 {
+			t.Fatalf("record %v breaks its rules: %v", record, errs)
+		}
+	}
+}
+
+func TestRecordSeed(t *testing.T) {
+	a, b := New(42), New(42)
+	for i := 0; i < 10; i++ {
+		if ra, rb := a.Record(testColumns, nil), b.Record(testColumns, nil); !reflect.DeepEqual(ra, rb) {
+			t.Fatalf("records of the same seed differ:\n%v\n%v", ra, rb)
+		}
+	}
+}
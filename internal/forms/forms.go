@@ -0,0 +1,190 @@
+// internal/forms/forms.go
+
+// Package forms supports public submission forms: unauthenticated endpoints that write
+// records into a table, for the contact forms of static sites. It checks the origin of
+// submissions, limits how often a client can submit and verifies CAPTCHA responses
+// with hCaptcha, reCAPTCHA or Cloudflare Turnstile.
+package forms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CAPTCHA providers forms can verify submissions with.
+const (
+	CaptchaHCaptcha  = "hcaptcha"
+	CaptchaReCaptcha = "recaptcha"
+	CaptchaTurnstile = "turnstile"
+)
+
+// CaptchaProviders lists the supported CAPTCHA providers.
+var CaptchaProviders = []string{CaptchaHCaptcha, CaptchaReCaptcha, CaptchaTurnstile}
+
+// verifyURLs are the siteverify endpoints of the providers, which all take the secret,
+// the response token and the client IP as a form POST and answer {"success": ...}.
+var verifyURLs = map[string]string{
+	CaptchaHCaptcha:  "https://api.hcaptcha.com/siteverify",
+	CaptchaReCaptcha: "https://www.google.com/recaptcha/api/siteverify",
+	CaptchaTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+// CaptchaFields are the submission fields a CAPTCHA response is read from: the names
+// the provider widgets use, and captcha_token for JSON submissions.
+var CaptchaFields = []string{"captcha_token", "h-captcha-response", "g-recaptcha-response", "cf-turnstile-response"}
+
+// Limits of form settings.
+const (
+	DefaultRateLimit = 10
+	MaxRateLimit     = 1000
+	MaxColumns       = 50
+	MaxOrigins       = 20
+)
+
+// ErrCaptchaFailed is returned when a CAPTCHA response is missing or rejected.
+var ErrCaptchaFailed = errors.New("CAPTCHA verification failed")
+
+var captchaClient = &http.Client{Timeout: 10 * time.Second}
+
+// VerifyCaptcha checks a CAPTCHA response token with provider, returning
+// ErrCaptchaFailed when the provider rejects it.
+func VerifyCaptcha(ctx context.Context, provider, secret, token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("%w: no CAPTCHA response submitted", ErrCaptchaFailed)
+	}
+	endpoint, ok := verifyURLs[provider]
+	if !ok {
+		return fmt.Errorf("unsupported CAPTCHA provider '%s'", provider)
+	}
+	form := url.Values{"secret": {secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := captchaClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered with status %d", provider, resp.StatusCode)
+	}
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid %s response: %w", provider, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// ValidateOrigins checks allowed origins of a form: scheme and host, without a path,
+// e.g. https://example.com.
+func ValidateOrigins(origins []string) error {
+	if len(origins) > MaxOrigins {
+		return fmt.Errorf("at most %d allowed origins are supported", MaxOrigins)
+	}
+	for _, origin := range origins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("invalid allowed origin '%s' (use scheme://host[:port])", origin)
+		}
+	}
+	return nil
+}
+
+// NormalizeOrigins lower-cases origins and strips trailing slashes, as browsers send
+// them in the Origin header.
+func NormalizeOrigins(origins []string) []string {
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(origin), "/"))
+	}
+	return normalized
+}
+
+// OriginAllowed reports whether a submission sent with an Origin header of origin is
+// accepted. Requests without one (servers, curl) are accepted; browsers always send it
+// with cross-origin and form POSTs.
+func OriginAllowed(allowed []string, origin string) bool {
+	if len(allowed) == 0 || origin == "" {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, a := range allowed {
+		if a == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRedirectURL checks the URL HTML form posts are redirected to.
+func ValidateRedirectURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid redirect URL '%s' (use an http or https URL)", raw)
+	}
+	return nil
+}
+
+// Limiter counts the submissions of each client to each form within a sliding hour.
+type Limiter struct {
+	mu          sync.Mutex
+	submissions map[string][]time.Time // form ID + client IP -> submission times
+	window      time.Duration
+	now         func() time.Time
+	sweepAt     time.Time // When counters of idle clients are dropped next
+}
+
+// NewLimiter returns a limiter with an hour window.
+func NewLimiter() *Limiter {
+	return &Limiter{submissions: make(map[string][]time.Time), window: time.Hour, now: time.Now}
+}
+
+// Allow records a submission of clientIP to formId and reports whether it is within
+// limit submissions per hour.
+func (l *Limiter) Allow(formId, clientIP string, limit int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	windowStart := now.Add(-l.window)
+	if now.After(l.sweepAt) {
+		for key, times := range l.submissions {
+			if len(times) == 0 || !times[len(times)-1].After(windowStart) {
+				delete(l.submissions, key)
+			}
+		}
+		l.sweepAt = now.Add(l.window)
+	}
+
+	key := formId + "|" + clientIP
+	recent := l.submissions[key][:0]
+	for _, t := range l.submissions[key] {
+		if t.After(windowStart) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		l.submissions[key] = recent
+		return false
+	}
+	l.submissions[key] = append(recent, now)
+	return true
+}
@@ -0,0 +1,83 @@
+package forms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterSlidingHour(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !l.Allow("f1", "10.0.0.1", 2) {
+			t.Fatalf("submission %d rejected", i+1)
+		}
+	}
+	if l.Allow("f1", "10.0.0.1", 2) {
+		t.Error("third submission within the hour allowed")
+	}
+	if !l.Allow("f1", "10.0.0.2", 2) || !l.Allow("f2", "10.0.0.1", 2) {
+		t.Error("limit shared across clients or forms")
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	if !l.Allow("f1", "10.0.0.1", 2) {
+		t.Error("submission rejected after the window passed")
+	}
+	if len(l.submissions) != 1 {
+		t.Errorf("%d counters kept, want idle ones dropped", len(l.submissions))
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	if err := ValidateOrigins([]string{"https://example.com", "http://localhost:3000/"}); err != nil {
+		t.Errorf("ValidateOrigins: %v", err)
+	}
+	for _, origin := range []string{"example.com", "https://example.com/contact", "ftp://example.com"} {
+		if ValidateOrigins([]string{origin}) == nil {
+			t.Errorf("ValidateOrigins accepted %q", origin)
+		}
+	}
+
+	allowed := NormalizeOrigins([]string{"https://Example.com/"})
+	if !OriginAllowed(allowed, "https://example.com") || !OriginAllowed(allowed, "") {
+		t.Error("allowed origin or request without origin rejected")
+	}
+	if OriginAllowed(allowed, "https://evil.example") {
+		t.Error("other origin allowed")
+	}
+	if !OriginAllowed(nil, "https://anywhere.example") {
+		t.Error("form without allowed origins rejected an origin")
+	}
+}
+
+func TestVerifyCaptcha(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("secret") == "s3cret" && r.PostFormValue("response") == "good" && r.PostFormValue("remoteip") == "10.0.0.1" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+	previous := verifyURLs[CaptchaTurnstile]
+	verifyURLs[CaptchaTurnstile] = server.URL
+	defer func() { verifyURLs[CaptchaTurnstile] = previous }()
+
+	ctx := context.Background()
+	if err := VerifyCaptcha(ctx, CaptchaTurnstile, "s3cret", "good", "10.0.0.1"); err != nil {
+		t.Errorf("valid response: %v", err)
+	}
+	if err := VerifyCaptcha(ctx, CaptchaTurnstile, "s3cret", "bad", "10.0.0.1"); !errors.Is(err, ErrCaptchaFailed) {
+		t.Errorf("invalid response: %v, want ErrCaptchaFailed", err)
+	}
+	if err := VerifyCaptcha(ctx, CaptchaTurnstile, "s3cret", "", "10.0.0.1"); !errors.Is(err, ErrCaptchaFailed) {
+		t.Errorf("missing response: %v, want ErrCaptchaFailed", err)
+	}
+}
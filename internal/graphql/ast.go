@@ -0,0 +1,135 @@
+// internal/graphql/ast.go
+package graphql
+
+// Document is a parsed executable GraphQL document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query or mutation definition.
+type Operation struct {
+	Type         string // "query" or "mutation"
+	Name         string
+	Variables    []*VariableDefinition
+	SelectionSet []Selection
+}
+
+// VariableDefinition declares an operation variable.
+type VariableDefinition struct {
+	Name         string
+	Type         string // Type reference as written, e.g. "Int!" or "[String]"
+	DefaultValue Value
+}
+
+// Fragment is a named fragment definition.
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	SelectionSet  []Selection
+}
+
+// Selection is one of *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	selection()
+}
+
+// Field selects a field, optionally aliased, with arguments and a sub-selection.
+type Field struct {
+	Alias        string
+	Name         string
+	Arguments    map[string]Value
+	Directives   []*Directive
+	SelectionSet []Selection
+}
+
+// FragmentSpread includes a named fragment (...Name).
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+}
+
+// InlineFragment is an anonymous fragment (... on Type { ... }).
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	SelectionSet  []Selection
+}
+
+func (*Field) selection()          {}
+func (*FragmentSpread) selection() {}
+func (*InlineFragment) selection() {}
+
+// ResponseKey is the key a field is reported under: its alias, or its name.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Directive is an @name(args) annotation.
+type Directive struct {
+	Name      string
+	Arguments map[string]Value
+}
+
+// Value is a literal or variable reference in a document.
+type Value interface {
+	value()
+}
+
+// Literal and variable value nodes.
+type (
+	Variable     struct{ Name string }
+	IntValue     struct{ Value int64 }
+	FloatValue   struct{ Value float64 }
+	StringValue  struct{ Value string }
+	BooleanValue struct{ Value bool }
+	NullValue    struct{}
+	EnumValue    struct{ Value string }
+	ListValue    struct{ Values []Value }
+	ObjectValue  struct{ Fields map[string]Value }
+)
+
+func (*Variable) value()     {}
+func (*IntValue) value()     {}
+func (*FloatValue) value()   {}
+func (*StringValue) value()  {}
+func (*BooleanValue) value() {}
+func (*NullValue) value()    {}
+func (*EnumValue) value()    {}
+func (*ListValue) value()    {}
+func (*ObjectValue) value()  {}
+
+// Resolve turns a value node into plain Go data (int64, float64, string, bool, nil,
+// []any, map[string]any), substituting variables. Enum values resolve to their name.
+func Resolve(v Value, variables map[string]any) any {
+	switch v := v.(type) {
+	case *Variable:
+		return variables[v.Name]
+	case *IntValue:
+		return v.Value
+	case *FloatValue:
+		return v.Value
+	case *StringValue:
+		return v.Value
+	case *BooleanValue:
+		return v.Value
+	case *EnumValue:
+		return v.Value
+	case *ListValue:
+		out := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			out[i] = Resolve(item, variables)
+		}
+		return out
+	case *ObjectValue:
+		out := make(map[string]any, len(v.Fields))
+		for name, field := range v.Fields {
+			out[name] = Resolve(field, variables)
+		}
+		return out
+	}
+	return nil
+}
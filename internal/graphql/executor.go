@@ -0,0 +1,624 @@
+// internal/graphql/executor.go
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/recordwrite"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var (
+	customLog = logger.NewLogger()
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Error is a GraphQL error entry.
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Response is a GraphQL response. Data is nil when the request could not be executed.
+type Response struct {
+	Data   *Object `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Object is a JSON object that keeps its keys in selection order.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+// Set adds or replaces key.
+func (o *Object) Set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get returns the value stored under key.
+func (o *Object) Get(key string) any {
+	return o.values[key]
+}
+
+// MarshalJSON writes the keys in insertion order.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Operation selects the operation to run: the named one, or the only one in the document.
+func (d *Document) Operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(d.Operations) != 1 {
+			return nil, errors.New("operationName is required when the document contains several operations")
+		}
+		return d.Operations[0], nil
+	}
+	for _, op := range d.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// Execute runs op against userDB. The returned error is a request error (bad or missing
+// variables); failures of individual fields are reported in Response.Errors instead.
+// Mutation fields run one after another, in document order.
+func Execute(ctx context.Context, userDB *sql.DB, schema *Schema, doc *Document, op *Operation, variables map[string]any) (*Response, error) {
+	vars, err := coerceVariables(op, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &executor{ctx: ctx, userDB: userDB, schema: schema, doc: doc, vars: vars}
+	rootType, fields := "Query", schema.queries
+	if op.Type == "mutation" {
+		rootType, fields = "Mutation", schema.mutations
+	}
+
+	data := newObject()
+	for _, group := range e.collectFields(op.SelectionSet, rootType) {
+		key := group[0].ResponseKey()
+		value, err := e.resolveRoot(rootType, fields, group)
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Path: []any{key}})
+			value = nil
+		}
+		data.Set(key, value)
+	}
+	return &Response{Data: data, Errors: e.errors}, nil
+}
+
+// coerceVariables applies defaults and checks that required variables were supplied.
+func coerceVariables(op *Operation, provided map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(op.Variables))
+	for _, def := range op.Variables {
+		value, ok := provided[def.Name]
+		if !ok && def.DefaultValue != nil {
+			value, ok = Resolve(def.DefaultValue, nil), true
+		}
+		if value == nil && strings.HasSuffix(def.Type, "!") {
+			return nil, fmt.Errorf("variable $%s of type %s must not be null", def.Name, def.Type)
+		}
+		if ok {
+			vars[def.Name] = value
+		}
+	}
+	return vars, nil
+}
+
+type executor struct {
+	ctx    context.Context
+	userDB *sql.DB
+	schema *Schema
+	doc    *Document
+	vars   map[string]any
+	errors []Error
+}
+
+// collectFields flattens fragments and applies @skip/@include, grouping fields by
+// response key in first-seen order.
+func (e *executor) collectFields(selections []Selection, typeName string) [][]*Field {
+	var groups [][]*Field
+	index := make(map[string]int)
+	visited := make(map[string]bool)
+
+	var collect func([]Selection)
+	collect = func(selections []Selection) {
+		for _, sel := range selections {
+			switch sel := sel.(type) {
+			case *Field:
+				if !e.included(sel.Directives) {
+					continue
+				}
+				key := sel.ResponseKey()
+				if i, ok := index[key]; ok {
+					groups[i] = append(groups[i], sel)
+					continue
+				}
+				index[key] = len(groups)
+				groups = append(groups, []*Field{sel})
+			case *InlineFragment:
+				if e.included(sel.Directives) && (sel.TypeCondition == "" || sel.TypeCondition == typeName) {
+					collect(sel.SelectionSet)
+				}
+			case *FragmentSpread:
+				if visited[sel.Name] || !e.included(sel.Directives) {
+					continue
+				}
+				visited[sel.Name] = true
+				frag, ok := e.doc.Fragments[sel.Name]
+				if ok && frag.TypeCondition == typeName {
+					collect(frag.SelectionSet)
+				}
+			}
+		}
+	}
+	collect(selections)
+	return groups
+}
+
+func (e *executor) included(directives []*Directive) bool {
+	for _, dir := range directives {
+		cond, _ := Resolve(dir.Arguments["if"], e.vars).(bool)
+		if (dir.Name == "skip" && cond) || (dir.Name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// subSelection merges the selection sets of all fields sharing a response key.
+func subSelection(group []*Field) []Selection {
+	var merged []Selection
+	for _, field := range group {
+		merged = append(merged, field.SelectionSet...)
+	}
+	return merged
+}
+
+func (e *executor) resolveRoot(rootType string, fields map[string]rootField, group []*Field) (any, error) {
+	field := group[0]
+	switch field.Name {
+	case "__typename":
+		return rootType, nil
+	case "__schema", "__type":
+		return nil, errors.New("introspection is not supported; fetch the schema from the graphql/schema endpoint instead")
+	}
+	root, ok := fields[field.Name]
+	if !ok {
+		return nil, fmt.Errorf("cannot query field %q on type %q", field.Name, rootType)
+	}
+
+	args := make(map[string]any, len(field.Arguments))
+	for name, value := range field.Arguments {
+		args[name] = Resolve(value, e.vars)
+	}
+	if root.op != opDelete && len(field.SelectionSet) == 0 {
+		return nil, fmt.Errorf("field %q must have a selection of subfields", field.Name)
+	}
+
+	table := root.table
+	switch root.op {
+	case opList:
+		return e.resolveList(table, args, subSelection(group))
+	case opByID:
+		id, err := idArgument(args)
+		if err != nil {
+			return nil, err
+		}
+		return e.fetchRecord(table, id, subSelection(group))
+	case opInsert:
+		script, err := e.tableScript(table)
+		if err != nil {
+			return nil, err
+		}
+		columns, values, err := e.writeInput(table, args, script, scripting.BeforeCreate, 0)
+		if err != nil {
+			return nil, err
+		}
+		if table.MaxRows > 0 {
+			count, err := storage.CountRecords(e.ctx, e.userDB, table.Name, table.MaxRows)
+			if err != nil {
+				return nil, storageError(err)
+			}
+			if count >= table.MaxRows {
+				return nil, &storage.RowLimitError{Table: table.Name, Limit: table.MaxRows, Configured: table.MaxRowsConfigured}
+			}
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table.Name, strings.Join(columns, ", "), placeholders)
+		lastID, err := storage.InsertRecord(e.ctx, e.userDB, insertSQL, values...)
+		if err != nil {
+			return nil, storageError(err)
+		}
+		storage.RecordWritten(e.ctx, e.userDB, storage.RecordCreated, table.Name, lastID)
+		e.runAfterHook(table, script, scripting.AfterCreate, lastID)
+		if !table.HasID {
+			return nil, nil // Nothing to read the new record back by
+		}
+		return e.fetchRecord(table, lastID, subSelection(group))
+	case opUpdate:
+		id, err := idArgument(args)
+		if err != nil {
+			return nil, err
+		}
+		script, err := e.tableScript(table)
+		if err != nil {
+			return nil, err
+		}
+		columns, values, err := e.writeInput(table, args, script, scripting.BeforeUpdate, id)
+		if err != nil {
+			return nil, err
+		}
+		setClauses := make([]string, len(columns))
+		for i, col := range columns {
+			setClauses[i] = col + " = ?"
+		}
+		updateSQL := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table.Name, strings.Join(setClauses, ", "))
+		if _, err := storage.UpdateRecord(e.ctx, e.userDB, updateSQL, append(values, id)...); err != nil {
+			if errors.Is(err, storage.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, storageError(err)
+		}
+		storage.RecordWritten(e.ctx, e.userDB, storage.RecordUpdated, table.Name, id)
+		e.runAfterHook(table, script, scripting.AfterUpdate, id)
+		return e.fetchRecord(table, id, subSelection(group))
+	case opDelete:
+		id, err := idArgument(args)
+		if err != nil {
+			return nil, err
+		}
+		deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE id = ?", table.Name)
+		if _, err := storage.DeleteRecord(e.ctx, e.userDB, deleteSQL, id); err != nil {
+			if errors.Is(err, storage.ErrRecordNotFound) {
+				return false, nil
+			}
+			return nil, storageError(err)
+		}
+		storage.RecordWritten(e.ctx, e.userDB, storage.RecordDeleted, table.Name, id)
+		return true, nil
+	}
+	return nil, fmt.Errorf("unsupported field %q", field.Name)
+}
+
+// resolveList maps the list arguments onto ListRecords query parameters.
+func (e *executor) resolveList(table *Table, args map[string]any, selection []Selection) (any, error) {
+	params := url.Values{}
+	for name, value := range args {
+		switch name {
+		case "where":
+			if value == nil {
+				continue
+			}
+			where, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.New("argument \"where\" must be an object")
+			}
+			for key, filterValue := range where {
+				col, ok := table.Column(key)
+				if !ok || !col.filterable() || core.IsReservedParam(key) {
+					return nil, fmt.Errorf("cannot filter %s on %q", table.TypeName, key)
+				}
+				str, err := filterString(filterValue)
+				if err != nil {
+					return nil, fmt.Errorf("filter %q: %w", key, err)
+				}
+				params.Set(col.Name, str)
+			}
+		case "limit", "offset":
+			if value == nil {
+				continue
+			}
+			n, ok := integer(value)
+			if !ok {
+				return nil, fmt.Errorf("argument %q must be an Int", name)
+			}
+			params.Set(name, strconv.FormatInt(n, 10))
+		case "sort":
+			if value == nil {
+				continue
+			}
+			sort, ok := value.(string)
+			if !ok {
+				return nil, errors.New("argument \"sort\" must be a String")
+			}
+			params.Set("sort", sort)
+		case "order":
+			if value == nil {
+				continue
+			}
+			order, ok := value.(string)
+			if !ok || (order != "ASC" && order != "DESC") {
+				return nil, errors.New("argument \"order\" must be ASC or DESC")
+			}
+			params.Set("order", strings.ToLower(order))
+		default:
+			return nil, fmt.Errorf("unknown argument %q on field %q", name, table.Name)
+		}
+	}
+
+	opts, err := core.ParseListQueryOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := storage.ListRecords(e.ctx, e.userDB, table.Name, params, opts)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	pageType := table.TypeName + "Page"
+	page := newObject()
+	for _, group := range e.collectFields(selection, pageType) {
+		field := group[0]
+		switch field.Name {
+		case "__typename":
+			page.Set(field.ResponseKey(), pageType)
+		case "total":
+			page.Set(field.ResponseKey(), result.Pagination.Total)
+		case "limit":
+			page.Set(field.ResponseKey(), result.Pagination.Limit)
+		case "offset":
+			page.Set(field.ResponseKey(), result.Pagination.Offset)
+		case "records":
+			records := make([]*Object, 0, len(result.Records))
+			for _, record := range result.Records {
+				obj, err := e.completeRecord(table, record, subSelection(group))
+				if err != nil {
+					return nil, err
+				}
+				records = append(records, obj)
+			}
+			page.Set(field.ResponseKey(), records)
+		default:
+			return nil, fmt.Errorf("cannot query field %q on type %q", field.Name, pageType)
+		}
+	}
+	return page, nil
+}
+
+// fetchRecord reads one record by id; a missing record resolves to null.
+func (e *executor) fetchRecord(table *Table, id int64, selection []Selection) (any, error) {
+	selectSQL := fmt.Sprintf("SELECT * FROM %s WHERE id = ? LIMIT 1;", table.Name)
+	record, err := storage.GetRecord(e.ctx, e.userDB, selectSQL, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, storageError(err)
+	}
+	return e.completeRecord(table, record, selection)
+}
+
+// completeRecord projects the selected columns of a record, converting SQLite values
+// to their GraphQL representation.
+func (e *executor) completeRecord(table *Table, record map[string]any, selection []Selection) (*Object, error) {
+	obj := newObject()
+	for _, group := range e.collectFields(selection, table.TypeName) {
+		field := group[0]
+		if field.Name == "__typename" {
+			obj.Set(field.ResponseKey(), table.TypeName)
+			continue
+		}
+		col, ok := table.Column(field.Name)
+		if !ok {
+			return nil, fmt.Errorf("cannot query field %q on type %q", field.Name, table.TypeName)
+		}
+		obj.Set(field.ResponseKey(), outputValue(col, record[col.Name]))
+	}
+	return obj, nil
+}
+
+func outputValue(col Column, value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case int64:
+		if col.GraphQLType() == "Boolean" {
+			return v != 0
+		}
+	}
+	return value
+}
+
+// recordInput validates the data argument against the table, returning columns and
+// values in table order.
+func recordInput(table *Table, input any) ([]string, []any, error) {
+	data, ok := input.(map[string]any)
+	if !ok {
+		return nil, nil, errors.New("argument \"data\" must be an object")
+	}
+	for key := range data {
+		if _, ok := table.Column(key); !ok || strings.EqualFold(key, "id") {
+			return nil, nil, fmt.Errorf("field %q is not defined by type %sInput", key, table.TypeName)
+		}
+	}
+
+	var columns []string
+	var values []any
+	for _, col := range table.Columns {
+		val, ok := data[col.Name]
+		if !ok {
+			continue
+		}
+		if !core.IsCompatibleValue(col.SQLType, val) {
+			return nil, nil, fmt.Errorf("invalid value for %q: expected %s", col.Name, col.GraphQLType())
+		}
+		if f, isFloat := val.(float64); isFloat && col.SQLType == "INTEGER" {
+			val = int64(f)
+		}
+		columns = append(columns, col.Name)
+		values = append(values, val)
+	}
+	if len(columns) == 0 {
+		return nil, nil, errors.New("argument \"data\" must set at least one field")
+	}
+	return columns, values, nil
+}
+
+// writeInput returns the columns and values a mutation writes to table: its "data"
+// argument, passed through the before hook of script and checked against the JSON
+// Schema and column rules of table. Updates are checked property by property.
+func (e *executor) writeInput(table *Table, args map[string]any, script *scripting.Script, hook string, recordID int64) ([]string, []any, error) {
+	writes := e.schema.Writes
+	data := args["data"]
+	if record, ok := data.(map[string]any); ok && writes != nil {
+		out, err := recordwrite.RunBeforeHook(e.ctx, writes.Cfg, script, hook, table.Name, recordID, record)
+		if err != nil {
+			return nil, nil, checkError(err)
+		}
+		data = out
+	}
+	columns, values, err := recordInput(table, data)
+	if err != nil || writes == nil {
+		return columns, values, err
+	}
+
+	record := make(map[string]any, len(columns))
+	for i, col := range columns {
+		record[col] = values[i]
+	}
+	partial := hook == scripting.BeforeUpdate
+	if err := recordwrite.CheckJSONSchema(e.ctx, writes.Store, writes.DatabaseID, table.Name, record, partial); err != nil {
+		return nil, nil, checkError(err)
+	}
+	if err := recordwrite.CheckColumnRules(e.ctx, writes.Store, writes.DatabaseID, table.Name, record); err != nil {
+		return nil, nil, checkError(err)
+	}
+	return columns, values, nil
+}
+
+// tableScript loads the script of table, nil when it has none or writes are unchecked.
+func (e *executor) tableScript(table *Table) (*scripting.Script, error) {
+	if e.schema.Writes == nil {
+		return nil, nil
+	}
+	script, err := recordwrite.LoadTableScript(e.ctx, e.schema.Writes.Store, e.schema.Writes.Cfg, e.schema.Writes.DatabaseID, table.Name)
+	if err != nil {
+		return nil, checkError(err)
+	}
+	return script, nil
+}
+
+// runAfterHook runs an after hook of script (which may be nil) with the stored record.
+func (e *executor) runAfterHook(table *Table, script *scripting.Script, hook string, recordID int64) {
+	if script == nil || !script.Defines(hook) {
+		return
+	}
+	userDB, err := storage.OpenUserData(e.ctx, e.schema.Writes.Location)
+	if err != nil {
+		customLog.Ctx(e.ctx).Warnf("GraphQL: Failed to open database for hook %s of table '%s': %v", hook, table.Name, err)
+		return
+	}
+	defer userDB.Release()
+	recordwrite.RunAfterHook(e.ctx, e.schema.Writes.Cfg, script, hook, userDB, table.Name, recordID)
+}
+
+func idArgument(args map[string]any) (int64, error) {
+	id, ok := integer(args["id"])
+	if !ok {
+		return 0, errors.New("argument \"id\" must be an Int")
+	}
+	return id, nil
+}
+
+// integer accepts literal ints and integral JSON numbers from variables.
+func integer(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case float64:
+		if math.Trunc(v) == v && math.Abs(v) < 1<<53 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// filterString renders a filter value the way ListRecords expects it in a query string.
+func filterString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", errors.New("value must be a scalar")
+}
+
+// checkError passes a rejected record on and hides other failures of the write checks
+// and hooks.
+func checkError(err error) error {
+	if errors.Is(err, recordwrite.ErrValidationFailed) || errors.Is(err, recordwrite.ErrEmptyHookRecord) ||
+		errors.Is(err, scripting.ErrScriptTimeout) || errors.As(err, new(*scripting.RejectError)) {
+		return err
+	}
+	customLog.Warnf("GraphQL: Failed to check record: %v", err)
+	return errors.New("internal error while resolving field")
+}
+
+// storageError keeps messages of client errors and hides internal failures.
+func storageError(err error) error {
+	for _, clientErr := range []error{
+		storage.ErrTableNotFound, storage.ErrColumnNotFound, storage.ErrTypeMismatch,
+		storage.ErrConstraintViolation, storage.ErrInvalidFilterValue, storage.ErrInvalidSortColumn,
+		storage.ErrInvalidFieldColumn, storage.ErrDatabaseBusy,
+	} {
+		if errors.Is(err, clientErr) {
+			return err
+		}
+	}
+	customLog.Warnf("GraphQL: Storage error while resolving field: %v", err)
+	return errors.New("internal error while resolving field")
+}
@@ -0,0 +1,244 @@
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+func execute(t *testing.T, userDB *sql.DB, query string, vars map[string]any) string {
+	t.Helper()
+	return executeWrites(t, userDB, nil, query, vars)
+}
+
+// executeWrites runs query with mutations checked through writes.
+func executeWrites(t *testing.T, userDB *sql.DB, writes *Writes, query string, vars map[string]any) string {
+	t.Helper()
+	ctx := context.Background()
+	schema, err := LoadSchema(ctx, userDB)
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	schema.Writes = writes
+	doc, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	op, err := doc.Operation("")
+	if err != nil {
+		t.Fatalf("Operation: %v", err)
+	}
+	resp, err := Execute(ctx, userDB, schema, doc, op, vars)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return string(out)
+}
+
+func TestExecuteQueriesAndMutations(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := storage.ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer storage.InvalidateUserDB(dbPath)
+	defer storage.ReleaseUserDB(userDB)
+
+	if err := storage.CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT, stars INTEGER, done BOOLEAN)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	got := execute(t, userDB, `mutation Add($body: String!) {
+		a: insert_notes(data: {body: $body, stars: 3, done: true}) { id body done }
+		b: insert_notes(data: {body: "second", stars: 1}) { id }
+	}`, map[string]any{"body": "first"})
+	want := `{"data":{"a":{"id":1,"body":"first","done":true},"b":{"id":2}}}`
+	if got != want {
+		t.Fatalf("insert:\n got %s\nwant %s", got, want)
+	}
+
+	got = execute(t, userDB, `{
+		notes(where: {done: true}, order: DESC, sort: "stars") { total records { ...noteFields } }
+		missing: notes_by_id(id: 99) { id }
+	}
+	fragment noteFields on Notes { __typename body stars }`, nil)
+	want = `{"data":{"notes":{"total":1,"records":[{"__typename":"Notes","body":"first","stars":3}]},"missing":null}}`
+	if got != want {
+		t.Fatalf("query:\n got %s\nwant %s", got, want)
+	}
+
+	got = execute(t, userDB, `mutation { update_notes(id: 2, data: {stars: 5}) { stars } delete_notes(id: 1) }`, nil)
+	want = `{"data":{"update_notes":{"stars":5},"delete_notes":true}}`
+	if got != want {
+		t.Fatalf("update/delete:\n got %s\nwant %s", got, want)
+	}
+
+	got = execute(t, userDB, `{ notes { records { nope } } }`, nil)
+	if !strings.Contains(got, `"data":{"notes":null}`) || !strings.Contains(got, `cannot query field \"nope\"`) {
+		t.Fatalf("unknown field: got %s", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		`{ notes { } }`,
+		`query { notes(where: {body: "unterminated }) { total } }`,
+		`subscription { notes { total } }`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", query)
+		}
+	}
+}
+
+func TestParseNestingLimit(t *testing.T) {
+	deep := []string{
+		strings.Repeat("{ a ", 100000) + strings.Repeat("}", 100000),
+		"{ notes(where: " + strings.Repeat("[", 100000) + strings.Repeat("]", 100000) + ") { total } }",
+		"{ notes(where: " + strings.Repeat("{a: ", 100000) + "1" + strings.Repeat("}", 100000) + ") { total } }",
+	}
+	for _, query := range deep {
+		if _, err := Parse(query); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+			t.Errorf("Parse(deeply nested) = %v, want a nesting error", err)
+		}
+	}
+	if _, err := Parse(strings.Repeat("{ a ", maxNestingDepth) + strings.Repeat("}", maxNestingDepth)); err != nil {
+		t.Errorf("Parse(%d levels) = %v, want success", maxNestingDepth, err)
+	}
+}
+
+// checkedWrites registers the database at dbPath and returns its writes.
+func checkedWrites(t *testing.T, dbPath string) *Writes {
+	t.Helper()
+	ctx := context.Background()
+	cfg := &config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"}
+	metaDB, err := storage.ConnectMetadataDB(cfg)
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	t.Cleanup(func() { metaDB.Close() })
+	if _, err := metaDB.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := metaDB.RegisterDatabase(ctx, "u1", "app", dbPath); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, err := metaDB.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+	if err != nil {
+		t.Fatalf("FindDatabaseIDByNameAndUser: %v", err)
+	}
+	scripts := &config.Config{ScriptTimeout: time.Second, ScriptMaxCallStackSize: 64}
+	return &Writes{Store: metaDB, Cfg: scripts, DatabaseID: databaseID, Location: dbPath}
+}
+
+func TestMutationsCheckColumnRules(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := storage.ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer storage.InvalidateUserDB(dbPath)
+	defer storage.ReleaseUserDB(userDB)
+	if err := storage.CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT, stars INTEGER)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	writes := checkedWrites(t, dbPath)
+	minStars := 1.0
+	if err := writes.Store.ReplaceColumnRules(ctx, writes.DatabaseID, "notes", []domain.ColumnRule{{Column: "stars", Min: &minStars}}); err != nil {
+		t.Fatalf("ReplaceColumnRules: %v", err)
+	}
+
+	got := executeWrites(t, userDB, writes, `mutation { ok: insert_notes(data: {body: "fine", stars: 2}) { id } bad: insert_notes(data: {body: "meh", stars: 0}) { id } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"ok":{"id":1},"bad":null}`) || !strings.Contains(got, "Record failed validation.") {
+		t.Fatalf("insert breaking a rule = %s, want it rejected", got)
+	}
+	got = executeWrites(t, userDB, writes, `mutation { update_notes(id: 1, data: {stars: 0}) { stars } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"update_notes":null}`) || !strings.Contains(got, "Record failed validation.") {
+		t.Fatalf("update breaking a rule = %s, want it rejected", got)
+	}
+	got = executeWrites(t, userDB, writes, `{ notes { total records { stars } } }`, nil)
+	if want := `{"data":{"notes":{"total":1,"records":[{"stars":2}]}}}`; got != want {
+		t.Fatalf("stored records:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestMutationsCheckJSONSchema(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := storage.ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer storage.InvalidateUserDB(dbPath)
+	defer storage.ReleaseUserDB(userDB)
+	if err := storage.CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT, stars INTEGER)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	writes := checkedWrites(t, dbPath)
+	schema := `{"type":"object","required":["body"],"properties":{"body":{"type":"string","maxLength":5}}}`
+	if err := writes.Store.SetTableJSONSchema(ctx, writes.DatabaseID, "notes", schema); err != nil {
+		t.Fatalf("SetTableJSONSchema: %v", err)
+	}
+
+	got := executeWrites(t, userDB, writes, `mutation { ok: insert_notes(data: {body: "fine"}) { id } bad: insert_notes(data: {stars: 3}) { id } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"ok":{"id":1},"bad":null}`) || !strings.Contains(got, "does not match the table's JSON schema") {
+		t.Fatalf("insert without a required field = %s, want it rejected", got)
+	}
+	// Updates are partial, so leaving out required fields is fine
+	got = executeWrites(t, userDB, writes, `mutation { ok: update_notes(id: 1, data: {stars: 3}) { stars } bad: update_notes(id: 1, data: {body: "far too long"}) { body } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"ok":{"stars":3},"bad":null}`) || !strings.Contains(got, "does not match the table's JSON schema") {
+		t.Fatalf("updates = %s, want only the long body rejected", got)
+	}
+}
+
+func TestMutationsRunTableScripts(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := storage.ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer storage.InvalidateUserDB(dbPath)
+	defer storage.ReleaseUserDB(userDB)
+	if err := storage.CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT, stars INTEGER)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	writes := checkedWrites(t, dbPath)
+	script := `function beforeCreate(record) {
+		if (record.body === "nope") throw new Error("no nopes");
+		record.body = record.body.toUpperCase();
+	}
+	function beforeUpdate(record) {
+		if (record.stars > 5) throw new Error("too many stars");
+	}
+	function afterUpdate(record) {}`
+	if err := writes.Store.SetTableScript(ctx, writes.DatabaseID, "notes", script); err != nil {
+		t.Fatalf("SetTableScript: %v", err)
+	}
+
+	got := executeWrites(t, userDB, writes, `mutation { ok: insert_notes(data: {body: "hi"}) { id body } bad: insert_notes(data: {body: "nope"}) { id } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"ok":{"id":1,"body":"HI"},"bad":null}`) || !strings.Contains(got, "no nopes") {
+		t.Fatalf("inserts = %s, want the first through beforeCreate and the second rejected", got)
+	}
+	got = executeWrites(t, userDB, writes, `mutation { ok: update_notes(id: 1, data: {stars: 5}) { stars } bad: update_notes(id: 1, data: {stars: 6}) { stars } }`, nil)
+	if !strings.HasPrefix(got, `{"data":{"ok":{"stars":5},"bad":null}`) || !strings.Contains(got, "too many stars") {
+		t.Fatalf("updates = %s, want the second rejected by beforeUpdate", got)
+	}
+}
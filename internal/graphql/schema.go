@@ -0,0 +1,231 @@
+// internal/graphql/schema.go
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// nameRegex matches valid GraphQL names.
+var nameRegex = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// Type names used by the generated schema itself; tables may not claim them.
+var builtinTypeNames = map[string]bool{
+	"Query": true, "Mutation": true, "Subscription": true, "SortOrder": true,
+	"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true,
+}
+
+// Column is a table column exposed as a GraphQL field.
+type Column struct {
+	Name    string
+	SQLType string // Upper-cased declared type, as reported by PragmaTableInfo
+}
+
+// GraphQLType returns the scalar the column is exposed as.
+func (c Column) GraphQLType() string {
+	switch {
+	case c.SQLType == "BOOLEAN":
+		return "Boolean"
+	case strings.Contains(c.SQLType, "INT"):
+		return "Int"
+	case c.SQLType == "REAL", strings.Contains(c.SQLType, "FLOA"), strings.Contains(c.SQLType, "DOUB"), c.SQLType == "NUMERIC":
+		return "Float"
+	}
+	return "String"
+}
+
+// filterable reports whether ListRecords can filter on the column.
+func (c Column) filterable() bool {
+	switch c.SQLType {
+	case "INTEGER", "BOOLEAN", "REAL", "TEXT":
+		return true
+	}
+	return false
+}
+
+// Table is a user table exposed as an object type with query and mutation fields.
+type Table struct {
+	Name     string
+	TypeName string
+	Columns  []Column
+	HasID    bool  // Tables without an integer id column get no by-id, update or delete fields
+	MaxRows  int64 // Inserts fail once the table holds this many rows; 0 for no limit
+	// MaxRowsConfigured is true when MaxRows is the table's own limit (see storage.RowLimit)
+	MaxRowsConfigured bool
+}
+
+// Column looks up a column by its field name.
+func (t *Table) Column(name string) (Column, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
+}
+
+// Root field kinds.
+const (
+	opList   = "list"
+	opByID   = "by_id"
+	opInsert = "insert"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+type rootField struct {
+	table *Table
+	op    string
+}
+
+// Schema is the GraphQL view of one user database.
+type Schema struct {
+	Tables    []*Table
+	Writes    *Writes // Checks of mutations; nil runs none
+	queries   map[string]rootField
+	mutations map[string]rootField
+}
+
+// Writes is the registration of the database, so mutations check their records
+// against its JSON Schemas and column rules and run its table scripts like writes
+// through the other APIs (see package recordwrite).
+type Writes struct {
+	Store      storage.MetadataStore
+	Cfg        *config.Config
+	DatabaseID int64
+	Location   string // Storage location of the database, read by after hooks
+}
+
+// LoadSchema introspects the tables of userDB. Tables and columns whose names are not
+// valid GraphQL names, or whose generated names would collide, are left out.
+func LoadSchema(ctx context.Context, userDB *sql.DB) (*Schema, error) {
+	tables, err := storage.ListTables(ctx, userDB)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &Schema{
+		queries:   make(map[string]rootField),
+		mutations: make(map[string]rootField),
+	}
+	usedTypes := make(map[string]bool, len(builtinTypeNames))
+	for name := range builtinTypeNames {
+		usedTypes[name] = true
+	}
+
+	for _, meta := range tables {
+		if !nameRegex.MatchString(meta.Name) || strings.HasPrefix(meta.Name, "__") {
+			customLog.Ctx(ctx).Printf("GraphQL: Skipping table '%s': not a valid GraphQL name", meta.Name)
+			continue
+		}
+		table := &Table{Name: meta.Name, TypeName: strings.ToUpper(meta.Name[:1]) + meta.Name[1:]}
+
+		typeNames := []string{table.TypeName, table.TypeName + "Page", table.TypeName + "Filter", table.TypeName + "Input"}
+		collides := false
+		for _, name := range typeNames {
+			collides = collides || usedTypes[name]
+		}
+		fieldNames := []string{table.Name, table.Name + "_by_id", "insert_" + table.Name, "update_" + table.Name, "delete_" + table.Name}
+		for _, name := range fieldNames {
+			_, inQuery := schema.queries[name]
+			_, inMutation := schema.mutations[name]
+			collides = collides || inQuery || inMutation
+		}
+		if collides {
+			customLog.Ctx(ctx).Warnf("GraphQL: Skipping table '%s': generated names collide with another table", meta.Name)
+			continue
+		}
+
+		for _, col := range meta.Columns {
+			if !nameRegex.MatchString(col.Name) || strings.HasPrefix(col.Name, "__") {
+				continue
+			}
+			column := Column{Name: col.Name, SQLType: strings.ToUpper(col.Type)}
+			if strings.EqualFold(col.Name, "id") && column.GraphQLType() == "Int" {
+				table.HasID = true
+			}
+			table.Columns = append(table.Columns, column)
+		}
+		if len(table.Columns) == 0 {
+			continue
+		}
+
+		for _, name := range typeNames {
+			usedTypes[name] = true
+		}
+		schema.Tables = append(schema.Tables, table)
+		schema.queries[table.Name] = rootField{table, opList}
+		schema.mutations["insert_"+table.Name] = rootField{table, opInsert}
+		if table.HasID {
+			schema.queries[table.Name+"_by_id"] = rootField{table, opByID}
+			schema.mutations["update_"+table.Name] = rootField{table, opUpdate}
+			schema.mutations["delete_"+table.Name] = rootField{table, opDelete}
+		}
+	}
+	return schema, nil
+}
+
+// SDL renders the schema in GraphQL schema definition language.
+func (s *Schema) SDL() string {
+	var sb strings.Builder
+	sb.WriteString("enum SortOrder {\n  ASC\n  DESC\n}\n\n")
+
+	if len(s.Tables) == 0 {
+		sb.WriteString("# The database has no tables yet.\ntype Query {\n  _empty: Boolean\n}\n")
+		return sb.String()
+	}
+
+	sb.WriteString("type Query {\n")
+	for _, t := range s.Tables {
+		fmt.Fprintf(&sb, "  %s(where: %sFilter, limit: Int, offset: Int, sort: String, order: SortOrder): %sPage!\n", t.Name, t.TypeName, t.TypeName)
+		if t.HasID {
+			fmt.Fprintf(&sb, "  %s_by_id(id: Int!): %s\n", t.Name, t.TypeName)
+		}
+	}
+	sb.WriteString("}\n\ntype Mutation {\n")
+	for _, t := range s.Tables {
+		fmt.Fprintf(&sb, "  insert_%s(data: %sInput!): %s\n", t.Name, t.TypeName, t.TypeName)
+		if t.HasID {
+			fmt.Fprintf(&sb, "  update_%s(id: Int!, data: %sInput!): %s\n", t.Name, t.TypeName, t.TypeName)
+			fmt.Fprintf(&sb, "  delete_%s(id: Int!): Boolean!\n", t.Name)
+		}
+	}
+	sb.WriteString("}\n")
+
+	for _, t := range s.Tables {
+		fmt.Fprintf(&sb, "\ntype %s {\n", t.TypeName)
+		for _, col := range t.Columns {
+			nonNull := ""
+			if t.HasID && strings.EqualFold(col.Name, "id") {
+				nonNull = "!"
+			}
+			fmt.Fprintf(&sb, "  %s: %s%s\n", col.Name, col.GraphQLType(), nonNull)
+		}
+		sb.WriteString("}\n")
+
+		fmt.Fprintf(&sb, "\ntype %sPage {\n  records: [%s!]!\n  total: Int!\n  limit: Int!\n  offset: Int!\n}\n", t.TypeName, t.TypeName)
+
+		fmt.Fprintf(&sb, "\ninput %sFilter {\n", t.TypeName)
+		for _, col := range t.Columns {
+			if col.filterable() {
+				fmt.Fprintf(&sb, "  %s: %s\n", col.Name, col.GraphQLType())
+			}
+		}
+		sb.WriteString("}\n")
+
+		fmt.Fprintf(&sb, "\ninput %sInput {\n", t.TypeName)
+		for _, col := range t.Columns {
+			if !strings.EqualFold(col.Name, "id") {
+				fmt.Fprintf(&sb, "  %s: %s\n", col.Name, col.GraphQLType())
+			}
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
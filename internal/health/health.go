@@ -0,0 +1,90 @@
+// internal/health/health.go
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+// Component statuses reported by readiness checks.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Component is the result of one readiness check.
+type Component struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// staleAfter is how many missed intervals mark a background worker as stuck.
+const staleAfter = 3
+
+type worker struct {
+	interval time.Duration
+	lastBeat time.Time
+}
+
+var (
+	mu      sync.Mutex
+	workers = make(map[string]*worker)
+)
+
+// RegisterWorker starts tracking a background worker that should call Beat at least
+// once per interval.
+func RegisterWorker(name string, interval time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	workers[name] = &worker{interval: interval, lastBeat: time.Now()}
+}
+
+// UnregisterWorker stops tracking a worker, e.g. when it exits on shutdown.
+func UnregisterWorker(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(workers, name)
+}
+
+// Beat records that a worker completed a cycle.
+func Beat(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if w, ok := workers[name]; ok {
+		w.lastBeat = time.Now()
+	}
+}
+
+// Workers reports every registered worker, failing those that have not beaten for
+// several intervals.
+func Workers() []Component {
+	mu.Lock()
+	defer mu.Unlock()
+	components := make([]Component, 0, len(workers))
+	for name, w := range workers {
+		c := Component{Name: "worker:" + name, Status: StatusOK}
+		if since := time.Since(w.lastBeat); since > staleAfter*w.interval {
+			c.Status = StatusFail
+			c.Message = "no heartbeat for " + since.Truncate(time.Second).String()
+		}
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return components
+}
+
+// RegisterGauges makes the worker registry report the running workers, by status,
+// through metrics.BackgroundWorkers. Call once at startup.
+func RegisterGauges() {
+	metrics.BackgroundWorkers.SetSource(func() map[string]float64 {
+		counts := map[string]float64{StatusOK: 0, StatusFail: 0}
+		for _, w := range Workers() {
+			counts[w.Status]++
+		}
+		return counts
+	})
+}
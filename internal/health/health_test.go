@@ -0,0 +1,21 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkersReportsStaleHeartbeat(t *testing.T) {
+	RegisterWorker("test", 20*time.Millisecond)
+	defer UnregisterWorker("test")
+
+	time.Sleep(100 * time.Millisecond)
+	if got := Workers(); len(got) != 1 || got[0].Status != StatusFail {
+		t.Fatalf("Workers() = %+v, want one failing worker", got)
+	}
+
+	Beat("test")
+	if got := Workers(); got[0].Status != StatusOK {
+		t.Fatalf("Workers() after Beat = %+v, want ok", got)
+	}
+}
@@ -0,0 +1,338 @@
+// internal/importer/importer.go
+// Package importer copies tables from an external Postgres database into a Nebula
+// database. Source columns are mapped onto Nebula's column types; columns, tables and
+// constraints that have no equivalent are skipped and reported instead of failing the
+// import.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var customLog = logger.NewLogger()
+
+// ErrUnsupportedSource is returned for source DSNs this build cannot connect to.
+var ErrUnsupportedSource = errors.New("unsupported source database")
+
+// createdAtLayout matches the CURRENT_TIMESTAMP format of Nebula's created_at column.
+const createdAtLayout = "2006-01-02 15:04:05"
+
+// Column is a source column and the Nebula column it is imported into.
+type Column struct {
+	Name       string // Name in both databases
+	SourceType string // As reported by information_schema
+	Type       string // Nebula type; "" for the built-in id and created_at columns
+}
+
+// TablePlan describes how one source table is imported. Issues lists what will not
+// be carried over; a plan without columns cannot be imported at all.
+type TablePlan struct {
+	Name    string
+	Columns []Column
+	Issues  []string
+}
+
+// Importable reports whether the table has anything to import.
+func (p *TablePlan) Importable() bool {
+	return len(p.Columns) > 0
+}
+
+// OpenSource connects to the source database at dsn. Only Postgres DSNs are supported.
+func OpenSource(ctx context.Context, dsn string) (*sql.DB, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return nil, fmt.Errorf("%w: only postgres:// DSNs can be imported", ErrUnsupportedSource)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to source database: %w", err)
+	}
+	return db, nil
+}
+
+// Plan inspects the base tables of schema in the source database and returns how each
+// would be imported. With no names every table of the schema is planned; named tables
+// that do not exist get a plan with only an issue.
+func Plan(ctx context.Context, src *sql.DB, schema string, tables []string) ([]TablePlan, error) {
+	available, err := listSourceTables(ctx, src, schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		tables = available
+	}
+	exists := make(map[string]bool, len(available))
+	for _, name := range available {
+		exists[name] = true
+	}
+
+	plans := make([]TablePlan, 0, len(tables))
+	for _, name := range tables {
+		plan := TablePlan{Name: name}
+		switch {
+		case !exists[name]:
+			plan.Issues = append(plan.Issues, fmt.Sprintf("table not found in schema '%s'", schema))
+		case !core.IsValidIdentifier(name) || strings.HasPrefix(strings.ToLower(name), core.TrashedTablePrefix):
+			plan.Issues = append(plan.Issues, "table name is not a valid Nebula table name")
+		default:
+			if err := planColumns(ctx, src, schema, &plan); err != nil {
+				return nil, err
+			}
+			if err := planConstraints(ctx, src, schema, &plan); err != nil {
+				return nil, err
+			}
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+func listSourceTables(ctx context.Context, src *sql.DB, schema string) ([]string, error) {
+	rows, err := src.QueryContext(ctx, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source tables: %w", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func planColumns(ctx context.Context, src *sql.DB, schema string, plan *TablePlan) error {
+	rows, err := src.QueryContext(ctx, `SELECT c.column_name, c.data_type, c.udt_name, c.is_generated,
+			c.column_default IS NOT NULL, COALESCE(t.typtype = 'e', false)
+		FROM information_schema.columns c
+		LEFT JOIN pg_catalog.pg_namespace n ON n.nspname = c.udt_schema
+		LEFT JOIN pg_catalog.pg_type t ON t.typname = c.udt_name AND t.typnamespace = n.oid
+		WHERE c.table_schema = $1 AND c.table_name = $2
+		ORDER BY c.ordinal_position`, schema, plan.Name)
+	if err != nil {
+		return fmt.Errorf("failed to inspect columns of '%s': %w", plan.Name, err)
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var name, dataType, udtName, generated string
+		var hasDefault, isEnum bool
+		if err := rows.Scan(&name, &dataType, &udtName, &generated, &hasDefault, &isEnum); err != nil {
+			return err
+		}
+		lower := strings.ToLower(name)
+		nebulaType, note, ok := MapType(dataType, udtName, isEnum)
+		switch {
+		case !ok:
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': type %s is not supported, skipped", name, describeType(dataType, udtName)))
+			continue
+		case generated == "ALWAYS":
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': generated columns are imported as plain values", name))
+		case hasDefault && lower != "id" && lower != "created_at":
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': default is not carried over", name))
+		}
+		if note != "" {
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': %s", name, note))
+		}
+
+		switch {
+		case lower == "id" && nebulaType == "INTEGER":
+			nebulaType = "" // Kept as the record id
+		case lower == "created_at" && strings.HasPrefix(dataType, "timestamp"):
+			nebulaType = "" // Kept as the record timestamp
+		case lower == "id" || lower == "created_at" || !core.IsValidIdentifier(name):
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': name is reserved or not a valid identifier, skipped", name))
+			continue
+		}
+		if seen[lower] {
+			plan.Issues = append(plan.Issues, fmt.Sprintf("column '%s': differs from another column only by case, skipped", name))
+			continue
+		}
+		seen[lower] = true
+		plan.Columns = append(plan.Columns, Column{Name: name, SourceType: describeType(dataType, udtName), Type: nebulaType})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !hasDataColumns(plan.Columns) {
+		plan.Columns = nil
+		plan.Issues = append(plan.Issues, "no importable columns")
+	}
+	return nil
+}
+
+// planConstraints reports the constraints of a table other than its primary key, none
+// of which are recreated by the import.
+func planConstraints(ctx context.Context, src *sql.DB, schema string, plan *TablePlan) error {
+	rows, err := src.QueryContext(ctx, `SELECT constraint_name, constraint_type FROM information_schema.table_constraints
+		WHERE table_schema = $1 AND table_name = $2 AND constraint_type IN ('FOREIGN KEY', 'UNIQUE', 'CHECK')
+			AND constraint_name NOT LIKE '%_not_null'
+		ORDER BY constraint_name`, schema, plan.Name)
+	if err != nil {
+		return fmt.Errorf("failed to inspect constraints of '%s': %w", plan.Name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			return err
+		}
+		plan.Issues = append(plan.Issues, fmt.Sprintf("%s constraint '%s' is not imported", kind, name))
+	}
+	return rows.Err()
+}
+
+func hasDataColumns(columns []Column) bool {
+	for _, col := range columns {
+		if col.Type != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func describeType(dataType, udtName string) string {
+	if dataType == "USER-DEFINED" || dataType == "ARRAY" {
+		return fmt.Sprintf("%s (%s)", dataType, udtName)
+	}
+	return dataType
+}
+
+// MapType returns the Nebula column type for a Postgres column, as described by the
+// data_type and udt_name columns of information_schema.columns. note explains a lossy
+// mapping; ok is false when the type cannot be imported.
+func MapType(dataType, udtName string, isEnum bool) (nebulaType, note string, ok bool) {
+	switch dataType {
+	case "smallint", "integer", "bigint":
+		return "INTEGER", "", true
+	case "real", "double precision":
+		return "REAL", "", true
+	case "numeric":
+		return "REAL", "numeric values are stored as REAL and may lose precision", true
+	case "boolean":
+		return "BOOLEAN", "", true
+	case "bytea":
+		return "BLOB", "", true
+	case "text", "character varying", "character", "uuid", "json", "jsonb", "xml",
+		"inet", "cidr", "macaddr", "money", "interval", "date",
+		"time without time zone", "time with time zone",
+		"timestamp without time zone", "timestamp with time zone":
+		return "TEXT", "", true
+	case "USER-DEFINED":
+		if isEnum || udtName == "citext" {
+			return "TEXT", "", true
+		}
+	}
+	return "", "", false
+}
+
+// ImportTable creates the planned table in dst and copies the source rows into it,
+// unless schemaOnly is set, returning the number of rows copied. The table must not
+// exist in dst yet.
+func ImportTable(ctx context.Context, src *sql.DB, schema string, plan TablePlan, dst storage.UserDataStore, schemaOnly bool) (int64, error) {
+	if !plan.Importable() {
+		return 0, fmt.Errorf("table '%s' has no importable columns", plan.Name)
+	}
+	if _, err := dst.ColumnTypes(ctx, plan.Name); err == nil {
+		return 0, fmt.Errorf("%w: '%s'", storage.ErrTableExists, plan.Name)
+	} else if !errors.Is(err, storage.ErrTableNotFound) {
+		return 0, err
+	}
+
+	specs := make([]core.ColumnSpec, 0, len(plan.Columns))
+	names := make([]string, len(plan.Columns))
+	quoted := make([]string, len(plan.Columns))
+	for i, col := range plan.Columns {
+		if col.Type != "" {
+			specs = append(specs, core.ColumnSpec{Name: col.Name, Type: col.Type})
+		}
+		names[i] = col.Name
+		quoted[i] = pq.QuoteIdentifier(col.Name)
+	}
+	if err := dst.CreateTable(ctx, plan.Name, specs); err != nil {
+		return 0, err
+	}
+	if schemaOnly {
+		return 0, nil
+	}
+
+	rows, err := src.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s.%s",
+		strings.Join(quoted, ", "), pq.QuoteIdentifier(schema), pq.QuoteIdentifier(plan.Name)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source table '%s': %w", plan.Name, err)
+	}
+	defer rows.Close()
+
+	var copied int64
+	values := make([]any, len(plan.Columns))
+	scanArgs := make([]any, len(plan.Columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return copied, err
+		}
+		converted := make([]any, len(values))
+		for i, col := range plan.Columns {
+			if converted[i], err = convertValue(col, values[i]); err != nil {
+				return copied, fmt.Errorf("row %d of '%s': %w", copied+1, plan.Name, err)
+			}
+		}
+		if _, err := dst.InsertRecord(ctx, plan.Name, names, converted); err != nil {
+			return copied, fmt.Errorf("row %d of '%s': %w", copied+1, plan.Name, err)
+		}
+		copied++
+	}
+	if err := rows.Err(); err != nil {
+		return copied, err
+	}
+	customLog.Ctx(ctx).Printf("Importer: Copied %d row(s) into table '%s'", copied, plan.Name)
+	return copied, nil
+}
+
+// convertValue turns a value scanned by lib/pq into one Nebula stores in col.
+func convertValue(col Column, value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		if col.Type == "" {
+			return v.UTC().Format(createdAtLayout), nil
+		}
+		return v.Format(time.RFC3339Nano), nil
+	case []byte:
+		switch col.Type {
+		case "BLOB":
+			return v, nil
+		case "REAL":
+			f, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("column '%s': cannot convert %q to REAL", col.Name, v)
+			}
+			return f, nil
+		}
+		return string(v), nil
+	}
+	return value, nil
+}
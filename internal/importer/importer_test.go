@@ -0,0 +1,65 @@
+// internal/importer/importer_test.go
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMapType(t *testing.T) {
+	testCases := []struct {
+		dataType, udtName string
+		isEnum            bool
+		want              string
+		wantNote, wantOK  bool
+	}{
+		{"bigint", "int8", false, "INTEGER", false, true},
+		{"double precision", "float8", false, "REAL", false, true},
+		{"numeric", "numeric", false, "REAL", true, true},
+		{"boolean", "bool", false, "BOOLEAN", false, true},
+		{"bytea", "bytea", false, "BLOB", false, true},
+		{"jsonb", "jsonb", false, "TEXT", false, true},
+		{"timestamp with time zone", "timestamptz", false, "TEXT", false, true},
+		{"USER-DEFINED", "mood", true, "TEXT", false, true},
+		{"USER-DEFINED", "citext", false, "TEXT", false, true},
+		{"USER-DEFINED", "geometry", false, "", false, false},
+		{"ARRAY", "_int4", false, "", false, false},
+		{"tsvector", "tsvector", false, "", false, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.dataType+"/"+tc.udtName, func(t *testing.T) {
+			got, note, ok := MapType(tc.dataType, tc.udtName, tc.isEnum)
+			if got != tc.want || (note != "") != tc.wantNote || ok != tc.wantOK {
+				t.Errorf("MapType() = %q, %q, %v; want %q, note %v, %v", got, note, ok, tc.want, tc.wantNote, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	stamp := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		name  string
+		col   Column
+		value any
+		want  any
+	}{
+		{"numeric", Column{Name: "price", Type: "REAL"}, []byte("12.50"), 12.5},
+		{"uuid", Column{Name: "ref", Type: "TEXT"}, []byte("a0eebc99"), "a0eebc99"},
+		{"timestamp", Column{Name: "seen", Type: "TEXT"}, stamp, "2024-03-01T12:30:00Z"},
+		{"created_at", Column{Name: "created_at"}, stamp, "2024-03-01 12:30:00"},
+		{"null", Column{Name: "note", Type: "TEXT"}, nil, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := convertValue(tc.col, tc.value)
+			if err != nil || got != tc.want {
+				t.Errorf("convertValue() = %v, %v; want %v", got, err, tc.want)
+			}
+		})
+	}
+
+	if _, err := convertValue(Column{Name: "price", Type: "REAL"}, []byte("NaN?")); err == nil {
+		t.Error("convertValue() accepted a malformed number")
+	}
+}
@@ -0,0 +1,149 @@
+// internal/jobs/jobs.go
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+var (
+	customLog = logger.NewLogger()
+)
+
+// Status is the lifecycle state of a background job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// defaultRetention is how long finished jobs stay queryable.
+const defaultRetention = 24 * time.Hour
+
+// Job is a snapshot of a background job's state.
+type Job struct {
+	ID         string     `json:"jobId"`
+	Kind       string     `json:"kind"`
+	OwnerID    string     `json:"-"`
+	Status     Status     `json:"status"`
+	Result     any        `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// Func is the work performed by a job. The returned value is exposed as the job result.
+type Func func(ctx context.Context) (any, error)
+
+// Manager runs jobs in background goroutines and tracks their state in memory.
+type Manager struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	retention time.Duration
+	ctx       context.Context
+}
+
+// NewManager creates a Manager whose jobs are cancelled when ctx is done.
+func NewManager(ctx context.Context) *Manager {
+	return &Manager{
+		jobs:      make(map[string]*Job),
+		retention: defaultRetention,
+		ctx:       ctx,
+	}
+}
+
+// Submit starts fn in the background and returns the queued job.
+func (m *Manager) Submit(ownerID, kind string, fn Func) Job {
+	job := &Job{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		OwnerID:   ownerID,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	m.mu.Lock()
+	m.pruneLocked()
+	m.jobs[job.ID] = job
+	snapshot := *job
+	m.mu.Unlock()
+
+	go m.run(job, fn)
+	return snapshot
+}
+
+// Get returns a snapshot of the job with the given ID.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// run executes fn, recording status transitions and recovering from panics.
+func (m *Manager) run(job *Job, fn Func) {
+	m.update(job, func(j *Job) {
+		now := time.Now().UTC()
+		j.Status = StatusRunning
+		j.StartedAt = &now
+	})
+
+	var result any
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		result, err = fn(m.ctx)
+	}()
+
+	m.update(job, func(j *Job) {
+		now := time.Now().UTC()
+		j.FinishedAt = &now
+		if err != nil {
+			j.Status = StatusFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = StatusSucceeded
+		j.Result = result
+	})
+
+	if err != nil {
+		customLog.Warnf("Jobs: %s job %s failed: %v", job.Kind, job.ID, err)
+	} else {
+		customLog.Printf("Jobs: %s job %s succeeded", job.Kind, job.ID)
+	}
+}
+
+func (m *Manager) update(job *Job, mutate func(*Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mutate(job)
+}
+
+// pruneLocked drops finished jobs older than the retention window. Caller holds m.mu.
+func (m *Manager) pruneLocked() {
+	cutoff := time.Now().Add(-m.retention)
+	for id, job := range m.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
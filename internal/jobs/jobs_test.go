@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitForJob(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, ok := m.Get(id)
+		if !ok {
+			t.Fatalf("job %s not found", id)
+		}
+		if job.Status == StatusSucceeded || job.Status == StatusFailed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish in time", id)
+	return Job{}
+}
+
+func TestManagerRecordsOutcome(t *testing.T) {
+	m := NewManager(context.Background())
+
+	ok := m.Submit("user-1", "test", func(ctx context.Context) (any, error) { return "done", nil })
+	if job := waitForJob(t, m, ok.ID); job.Status != StatusSucceeded || job.Result != "done" {
+		t.Errorf("successful job = %+v", job)
+	}
+
+	failed := m.Submit("user-1", "test", func(ctx context.Context) (any, error) { return nil, errors.New("boom") })
+	if job := waitForJob(t, m, failed.ID); job.Status != StatusFailed || job.Error != "boom" {
+		t.Errorf("failed job = %+v", job)
+	}
+
+	panicked := m.Submit("user-1", "test", func(ctx context.Context) (any, error) { panic("oops") })
+	if job := waitForJob(t, m, panicked.ID); job.Status != StatusFailed {
+		t.Errorf("panicking job = %+v; want failed", job)
+	}
+}
@@ -0,0 +1,572 @@
+// internal/jsonschema/jsonschema.go
+// Package jsonschema validates decoded JSON values against a JSON Schema. It implements
+// the validation vocabulary tables need (types, enums, numeric/string/array/object
+// constraints and the allOf/anyOf/oneOf/not combinators); schemas using other keywords,
+// such as $ref, are rejected by Compile rather than half-enforced.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// MaxSchemaSize bounds the encoded size of a schema.
+	MaxSchemaSize = 64 << 10
+	maxDepth      = 32
+)
+
+// ErrInvalidSchema wraps every Compile error.
+var ErrInvalidSchema = errors.New("invalid JSON schema")
+
+// annotations are keywords that carry no validation and are accepted anywhere.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true, "description": true,
+	"default": true, "examples": true, "readOnly": true, "writeOnly": true, "deprecated": true,
+}
+
+var knownTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	always *bool // Set for the boolean schemas true and false
+
+	types []string
+	enum  []any
+	cnst  *any
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+
+	items              *Schema
+	minItems, maxItems *int
+	uniqueItems        bool
+
+	properties                   map[string]*Schema
+	required                     []string
+	additionalProperties         *Schema
+	minProperties, maxProperties *int
+
+	allOf, anyOf, oneOf []*Schema
+	not                 *Schema
+}
+
+// ValidationError is one way a value fails its schema.
+type ValidationError struct {
+	Field   string `json:"field"` // Path of the value, e.g. "address.zip" or "tags[2]"; empty for the value itself
+	Rule    string `json:"rule"`  // Keyword that failed
+	Message string `json:"message"`
+}
+
+// Compile parses and checks a schema document.
+func Compile(data []byte) (*Schema, error) {
+	if len(data) > MaxSchemaSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidSchema, MaxSchemaSize)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data after schema", ErrInvalidSchema)
+	}
+	s, err := compile(doc, "#", 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return s, nil
+}
+
+func compile(doc any, at string, depth int) (*Schema, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%s: nested deeper than %d levels", at, maxDepth)
+	}
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", at)
+	}
+
+	s := &Schema{}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Deterministic error messages
+
+	var err error
+	for _, key := range keys {
+		value := obj[key]
+		where := at + "/" + key
+		switch key {
+		case "type":
+			s.types, err = compileTypes(value, where)
+		case "enum":
+			list, ok := value.([]any)
+			if !ok || len(list) == 0 {
+				return nil, fmt.Errorf("%s: must be a non-empty array", where)
+			}
+			s.enum = list
+		case "const":
+			s.cnst = &value
+		case "minimum":
+			s.minimum, err = number(value, where)
+		case "maximum":
+			s.maximum, err = number(value, where)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = number(value, where)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = number(value, where)
+		case "multipleOf":
+			if s.multipleOf, err = number(value, where); err == nil && *s.multipleOf <= 0 {
+				err = fmt.Errorf("%s: must be greater than 0", where)
+			}
+		case "minLength":
+			s.minLength, err = count(value, where)
+		case "maxLength":
+			s.maxLength, err = count(value, where)
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", where)
+			}
+			if s.pattern, err = regexp.Compile(str); err != nil {
+				err = fmt.Errorf("%s: %v", where, err)
+			}
+		case "format":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", where)
+			}
+			s.format = str
+		case "items":
+			s.items, err = compile(value, where, depth+1)
+		case "minItems":
+			s.minItems, err = count(value, where)
+		case "maxItems":
+			s.maxItems, err = count(value, where)
+		case "uniqueItems":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a boolean", where)
+			}
+			s.uniqueItems = b
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", where)
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				if s.properties[name], err = compile(sub, where+"/"+name, depth+1); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = stringList(value, where)
+		case "additionalProperties":
+			s.additionalProperties, err = compile(value, where, depth+1)
+		case "minProperties":
+			s.minProperties, err = count(value, where)
+		case "maxProperties":
+			s.maxProperties, err = count(value, where)
+		case "allOf":
+			s.allOf, err = compileList(value, where, depth)
+		case "anyOf":
+			s.anyOf, err = compileList(value, where, depth)
+		case "oneOf":
+			s.oneOf, err = compileList(value, where, depth)
+		case "not":
+			s.not, err = compile(value, where, depth+1)
+		default:
+			if !annotations[key] {
+				return nil, fmt.Errorf("%s: unsupported keyword", where)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func compileTypes(value any, at string) ([]string, error) {
+	if str, ok := value.(string); ok {
+		value = []any{str}
+	}
+	types, err := stringList(value, at)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		if !knownTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type '%s'", at, t)
+		}
+	}
+	return types, nil
+}
+
+func compileList(value any, at string, depth int) ([]*Schema, error) {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s: must be a non-empty array of schemas", at)
+	}
+	schemas := make([]*Schema, len(list))
+	for i, sub := range list {
+		var err error
+		if schemas[i], err = compile(sub, fmt.Sprintf("%s/%d", at, i), depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return schemas, nil
+}
+
+func number(value any, at string) (*float64, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", at, err)
+	}
+	return &f, nil
+}
+
+func count(value any, at string) (*int, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	return &i, nil
+}
+
+func stringList(value any, at string) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an array of strings", at)
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		if out[i], ok = item.(string); !ok {
+			return nil, fmt.Errorf("%s: must be an array of strings", at)
+		}
+	}
+	return out, nil
+}
+
+// Property returns the schema a property named name of an object must match, or nil if
+// it is unconstrained.
+func (s *Schema) Property(name string) *Schema {
+	if sub, ok := s.properties[name]; ok {
+		return sub
+	}
+	return s.additionalProperties
+}
+
+// Validate checks a value decoded by encoding/json (numbers as float64 or json.Number)
+// and returns every failure, or nil if it matches.
+func (s *Schema) Validate(value any) []ValidationError {
+	var errs []ValidationError
+	s.validate(value, "", &errs)
+	return errs
+}
+
+// ValidateProperties checks only the given properties of an object against the
+// subschemas they must match, ignoring required and other whole-object keywords. It
+// suits partial updates, which send just the properties they change.
+func (s *Schema) ValidateProperties(obj map[string]any) []ValidationError {
+	var errs []ValidationError
+	for _, name := range sortedKeys(obj) {
+		if sub := s.Property(name); sub != nil {
+			sub.validate(obj[name], name, &errs)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validate(value any, path string, errs *[]ValidationError) {
+	fail := func(rule, format string, args ...any) {
+		*errs = append(*errs, ValidationError{Field: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			fail("false", "is not allowed")
+		}
+		return
+	}
+	value = normalize(value)
+
+	if len(s.types) > 0 && !matchesType(s.types, value) {
+		if len(s.types) == 1 {
+			fail("type", "must be of type %s", s.types[0])
+		} else {
+			fail("type", "must be one of the types %v", s.types)
+		}
+		return
+	}
+	if s.enum != nil {
+		found := false
+		for _, option := range s.enum {
+			if equal(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "must be one of the allowed values")
+		}
+	}
+	if s.cnst != nil && !equal(*s.cnst, value) {
+		fail("const", "must equal the constant value")
+	}
+
+	switch v := value.(type) {
+	case float64:
+		s.validateNumber(v, fail)
+	case string:
+		s.validateString(v, fail)
+	case []any:
+		s.validateArray(v, path, errs, fail)
+	case map[string]any:
+		s.validateObject(v, path, errs, fail)
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(value, path, errs)
+	}
+	if s.anyOf != nil {
+		matched := 0
+		for _, sub := range s.anyOf {
+			if sub.matches(value) {
+				matched++
+				break
+			}
+		}
+		if matched == 0 {
+			fail("anyOf", "must match at least one of the allowed schemas")
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.matches(value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("oneOf", "must match exactly one of the allowed schemas, matched %d", matched)
+		}
+	}
+	if s.not != nil && s.not.matches(value) {
+		fail("not", "must not match the disallowed schema")
+	}
+}
+
+func (s *Schema) matches(value any) bool {
+	return len(s.Validate(value)) == 0
+}
+
+func (s *Schema) validateNumber(v float64, fail func(string, string, ...any)) {
+	if s.minimum != nil && v < *s.minimum {
+		fail("minimum", "must be at least %g", *s.minimum)
+	}
+	if s.maximum != nil && v > *s.maximum {
+		fail("maximum", "must be at most %g", *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+		fail("exclusiveMinimum", "must be greater than %g", *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+		fail("exclusiveMaximum", "must be less than %g", *s.exclusiveMaximum)
+	}
+	if s.multipleOf != nil {
+		q := v / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "must be a multiple of %g", *s.multipleOf)
+		}
+	}
+}
+
+func (s *Schema) validateString(v string, fail func(string, string, ...any)) {
+	length := utf8.RuneCountInString(v)
+	if s.minLength != nil && length < *s.minLength {
+		fail("minLength", "must be at least %d characters long", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		fail("maxLength", "must be at most %d characters long", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		fail("pattern", "must match %s", s.pattern)
+	}
+	if s.format != "" && !checkFormat(s.format, v) {
+		fail("format", "must be a valid %s", s.format)
+	}
+}
+
+func (s *Schema) validateArray(v []any, path string, errs *[]ValidationError, fail func(string, string, ...any)) {
+	if s.minItems != nil && len(v) < *s.minItems {
+		fail("minItems", "must have at least %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(v) > *s.maxItems {
+		fail("maxItems", "must have at most %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+		for i := 1; i < len(v); i++ {
+			for j := 0; j < i; j++ {
+				if equal(v[i], v[j]) {
+					fail("uniqueItems", "must not contain duplicate items")
+					i = len(v) // Report once
+					break
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range v {
+			s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateObject(v map[string]any, path string, errs *[]ValidationError, fail func(string, string, ...any)) {
+	if s.minProperties != nil && len(v) < *s.minProperties {
+		fail("minProperties", "must have at least %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(v) > *s.maxProperties {
+		fail("maxProperties", "must have at most %d properties", *s.maxProperties)
+	}
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			*errs = append(*errs, ValidationError{Field: join(path, name), Rule: "required", Message: "is required"})
+		}
+	}
+	for _, name := range sortedKeys(v) {
+		if sub := s.Property(name); sub != nil {
+			sub.validate(v[name], join(path, name), errs)
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// normalize converts json.Number and Go integer types to float64 so values decoded
+// either way compare alike.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return value
+}
+
+func matchesType(types []string, value any) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]any); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal compares JSON values, treating numbers by value.
+func equal(a, b any) bool {
+	a, b = normalize(a), normalize(b)
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			other, ok := bv[k]
+			if !ok || !equal(v, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
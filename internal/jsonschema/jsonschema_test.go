@@ -0,0 +1,91 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const profileSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["name", "address"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "member"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+		"address": {
+			"type": "object",
+			"required": ["zip"],
+			"properties": {"zip": {"type": "string", "pattern": "^[0-9]{5}$"}}
+		},
+		"contact": {"oneOf": [{"type": "string", "format": "email"}, {"type": "null"}]}
+	}
+}`
+
+func decode(t *testing.T, doc string) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(profileSchema))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	valid := decode(t, `{"name": "Ada", "age": 36, "role": "admin", "tags": ["a", "b"], "address": {"zip": "12345"}, "contact": null}`)
+	if errs := schema.Validate(valid); len(errs) != 0 {
+		t.Errorf("Validate(valid) = %+v, want none", errs)
+	}
+
+	invalid := decode(t, `{"name": "A", "age": 1.5, "role": "owner", "tags": ["a", "a"], "address": {"zip": "1234x"}, "contact": "nobody", "extra": 1}`)
+	want := map[string]string{
+		"name": "minLength", "age": "type", "role": "enum", "tags": "uniqueItems",
+		"address.zip": "pattern", "contact": "oneOf", "extra": "false",
+	}
+	errs := schema.Validate(invalid)
+	if len(errs) != len(want) {
+		t.Fatalf("Validate(invalid) = %+v, want %d errors", errs, len(want))
+	}
+	for _, e := range errs {
+		if want[e.Field] != e.Rule {
+			t.Errorf("field %q failed %q, want %q", e.Field, e.Rule, want[e.Field])
+		}
+	}
+
+	missing := schema.Validate(decode(t, `{"address": {}}`))
+	if len(missing) != 2 || missing[0].Field != "name" || missing[1].Field != "address.zip" {
+		t.Errorf("Validate(missing) = %+v, want required name and address.zip", missing)
+	}
+
+	// Partial updates skip required but still check what they send
+	if errs := schema.ValidateProperties(decode(t, `{"age": 40}`)); len(errs) != 0 {
+		t.Errorf("ValidateProperties(valid) = %+v, want none", errs)
+	}
+	if errs := schema.ValidateProperties(decode(t, `{"age": -1}`)); len(errs) != 1 || errs[0].Rule != "minimum" {
+		t.Errorf("ValidateProperties(invalid) = %+v, want one minimum error", errs)
+	}
+}
+
+func TestCompileRejects(t *testing.T) {
+	for _, doc := range []string{
+		`[]`,
+		`{"type": "decimal"}`,
+		`{"$ref": "#/definitions/x"}`,
+		`{"pattern": "("}`,
+		`{"minLength": -1}`,
+		`{"properties": {"a": {"if": true}}}`,
+		`{} {}`,
+	} {
+		if _, err := Compile([]byte(doc)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("Compile(%s) error = %v, want ErrInvalidSchema", doc, err)
+		}
+	}
+}
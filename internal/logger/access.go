@@ -0,0 +1,32 @@
+// internal/logger/access.go
+package logger
+
+import (
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// NewAccessLogger returns a JSON logger for the HTTP access log. It writes to stdout
+// when filePath is empty, otherwise to filePath, rotated once it reaches maxSizeMB and
+// keeping at most maxBackups old files for maxAgeDays (0 keeps them all).
+func NewAccessLogger(filePath string, maxSizeMB, maxBackups, maxAgeDays int) *Logger {
+	var out io.Writer = os.Stdout
+	if filePath != "" {
+		out = &lumberjack.Logger{
+			Filename:   filePath,
+			MaxSize:    maxSizeMB,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAgeDays,
+			Compress:   true,
+		}
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	logger.SetFormatter(jsonFormatter())
+	logger.SetOutput(out)
+	return &Logger{Logger: logger}
+}
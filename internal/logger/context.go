@@ -0,0 +1,86 @@
+// internal/logger/context.go
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fieldsKey struct{}
+
+// requestFields holds the per-request values added to every log line. It is stored
+// by pointer so that middleware running later (authentication, database
+// resolution) can fill in values without replacing the request context.
+type requestFields struct {
+	mu        sync.Mutex
+	requestID string
+	userID    string
+	database  string
+}
+
+// NewContext returns a copy of ctx that carries requestID for logging.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &requestFields{requestID: requestID})
+}
+
+func fieldsFrom(ctx context.Context) *requestFields {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(fieldsKey{}).(*requestFields)
+	return f
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.requestID
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user on the request carried by ctx.
+func SetUserID(ctx context.Context, userID string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.userID = userID
+		f.mu.Unlock()
+	}
+}
+
+// SetDatabase records the database the request operates on.
+func SetDatabase(ctx context.Context, dbName string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.database = dbName
+		f.mu.Unlock()
+	}
+}
+
+// contextHook copies request fields from the entry context onto the log line.
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	f := fieldsFrom(entry.Context)
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.requestID != "" {
+		entry.Data["request_id"] = f.requestID
+	}
+	if f.userID != "" {
+		entry.Data["user_id"] = f.userID
+	}
+	if f.database != "" {
+		entry.Data["db_name"] = f.database
+	}
+	return nil
+}
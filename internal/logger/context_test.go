@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestContextHookAddsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := logrus.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(jsonFormatter())
+	l.AddHook(contextHook{})
+
+	ctx := NewContext(context.Background(), "req-1")
+	SetUserID(ctx, "user-1")
+	SetDatabase(ctx, "app")
+	l.WithContext(ctx).Info("hello")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v (%s)", err, buf.String())
+	}
+	want := map[string]string{"message": "hello", "request_id": "req-1", "user_id": "user-1", "db_name": "app"}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %q", key, line[key], value)
+		}
+	}
+
+	// Lines without a request context carry no request fields
+	buf.Reset()
+	l.WithContext(context.Background()).Info("plain")
+	if bytes.Contains(buf.Bytes(), []byte("request_id")) {
+		t.Errorf("unexpected request_id in %s", buf.String())
+	}
+}
@@ -1,9 +1,14 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -13,21 +18,31 @@ type Logger struct {
 	*logrus.Logger
 }
 
-// NewLogger creates a new logger instance
+var (
+	baseOnce sync.Once
+	base     *logrus.Logger
+)
+
+// NewLogger returns a logger backed by the process-wide logrus instance, so that
+// every package writes through the same output, format and level.
 func NewLogger() *Logger {
+	baseOnce.Do(func() {
+		base = newBaseLogger()
+	})
+	return &Logger{Logger: base}
+}
+
+func newBaseLogger() *logrus.Logger {
 	logger := logrus.New()
 
 	// Set the log level
 	logger.SetLevel(logrus.DebugLevel)
 
-	// Set the log format
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		ForceColors:     true,
-		DisableColors:   false,
-		PadLevelText:    true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
+	// Structured JSON by default; Configure can switch to text for local development
+	logger.SetFormatter(jsonFormatter())
+
+	// Request, user and database fields come from the entry context
+	logger.AddHook(contextHook{})
 
 	// Set the output file
 	logFilePath := filepath.Join("logs", "api-nebula.log")
@@ -45,7 +60,57 @@ func NewLogger() *Logger {
 	// Set the output
 	logger.SetOutput(mw)
 
-	return &Logger{Logger: logger}
+	return logger
+}
+
+func jsonFormatter() logrus.Formatter {
+	return &logrus.JSONFormatter{
+		TimestampFormat: time.RFC3339Nano,
+		FieldMap:        logrus.FieldMap{logrus.FieldKeyMsg: "message"},
+	}
+}
+
+// Configure sets the format ("json" or "text") and minimum level of the shared logger.
+func Configure(format, level string) error {
+	l := NewLogger()
+	switch strings.ToLower(format) {
+	case "", "json":
+		l.SetFormatter(jsonFormatter())
+	case "text":
+		l.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp:   true,
+			ForceColors:     true,
+			DisableColors:   false,
+			PadLevelText:    true,
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	default:
+		return fmt.Errorf("unknown log format '%s'", format)
+	}
+	if level != "" {
+		return SetLevel(level)
+	}
+	return nil
+}
+
+// SetLevel changes the minimum level of the shared logger at runtime.
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	NewLogger().Logger.SetLevel(parsed)
+	return nil
+}
+
+// Level returns the current minimum level of the shared logger.
+func Level() string {
+	return NewLogger().GetLevel().String()
+}
+
+// Ctx returns an entry carrying the request, user and database of ctx.
+func (l *Logger) Ctx(ctx context.Context) *logrus.Entry {
+	return l.WithContext(ctx)
 }
 
 // Info logs an informational message
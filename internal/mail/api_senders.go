@@ -0,0 +1,107 @@
+// internal/mail/api_senders.go
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+// SESSender delivers mail through the Amazon SES v2 API.
+type SESSender struct {
+	Region      string
+	Credentials sigv4.Credentials
+	From        string
+	Endpoint    string // Overrides https://email.<region>.amazonaws.com
+	HTTPClient  *http.Client
+}
+
+func (s *SESSender) Send(ctx context.Context, msg Message) error {
+	body := map[string]any{"Text": map[string]string{"Data": msg.Text, "Charset": "UTF-8"}}
+	if msg.HTML != "" {
+		body["Html"] = map[string]string{"Data": msg.HTML, "Charset": "UTF-8"}
+	}
+	payload, err := json.Marshal(map[string]any{
+		"FromEmailAddress": s.From,
+		"Destination":      map[string]any{"ToAddresses": []string{msg.To}},
+		"Content": map[string]any{"Simple": map[string]any{
+			"Subject": map[string]string{"Data": msg.Subject, "Charset": "UTF-8"},
+			"Body":    body,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + s.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sigv4.Sign(req, s.Credentials, s.Region, "ses", sigv4.PayloadHash(payload), time.Now().UTC())
+	return doProviderRequest(s.HTTPClient, req, "SES")
+}
+
+// SendGridSender delivers mail through the SendGrid v3 API.
+type SendGridSender struct {
+	APIKey     string
+	From       string
+	Endpoint   string // Overrides https://api.sendgrid.com
+	HTTPClient *http.Client
+}
+
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	content := []map[string]string{{"type": "text/plain", "value": msg.Text}}
+	if msg.HTML != "" {
+		content = append(content, map[string]string{"type": "text/html", "value": msg.HTML})
+	}
+	payload, err := json.Marshal(map[string]any{
+		"personalizations": []map[string]any{{"to": []map[string]string{{"email": msg.To}}}},
+		"from":             map[string]string{"email": s.From},
+		"subject":          msg.Subject,
+		"content":          content,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.sendgrid.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	return doProviderRequest(s.HTTPClient, req, "SendGrid")
+}
+
+// doProviderRequest sends a mail API request and turns non-2xx responses into errors.
+func doProviderRequest(client *http.Client, req *http.Request, provider string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s rejected the message with status %d: %s", provider, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// internal/mail/mail.go
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+var customLog = logger.NewLogger()
+
+// Mail providers (MAIL_PROVIDER).
+const (
+	ProviderNone     = ""
+	ProviderLog      = "log" // Development: write messages to the log instead of sending them
+	ProviderSMTP     = "smtp"
+	ProviderSES      = "ses"
+	ProviderSendGrid = "sendgrid"
+)
+
+// Message is a rendered email to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string // Optional alternative to Text
+}
+
+// Sender delivers a message through a mail provider. A returned error means the
+// message may be retried.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender for the configured provider, or nil when mail is disabled.
+func NewSender(cfg *config.Config) (Sender, error) {
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	switch cfg.MailProvider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderLog:
+		return logSender{}, nil
+	case ProviderSMTP:
+		return &SMTPSender{
+			Host:     cfg.MailSMTPHost,
+			Port:     cfg.MailSMTPPort,
+			Username: cfg.MailSMTPUsername,
+			Password: cfg.MailSMTPPassword,
+			TLSMode:  cfg.MailSMTPTLS,
+			From:     cfg.MailFrom,
+		}, nil
+	case ProviderSES:
+		return &SESSender{
+			Region: cfg.MailSESRegion,
+			Credentials: sigv4.Credentials{
+				AccessKeyID:     cfg.MailSESAccessKeyID,
+				SecretAccessKey: cfg.MailSESSecretAccessKey,
+				SessionToken:    cfg.MailSESSessionToken,
+			},
+			From:       cfg.MailFrom,
+			HTTPClient: httpClient,
+		}, nil
+	case ProviderSendGrid:
+		return &SendGridSender{APIKey: cfg.MailSendGridAPIKey, From: cfg.MailFrom, HTTPClient: httpClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider '%s'", cfg.MailProvider)
+	}
+}
+
+// logSender writes messages to the log, for development setups without a provider.
+type logSender struct{}
+
+func (logSender) Send(ctx context.Context, msg Message) error {
+	customLog.Ctx(ctx).Printf("Mail: To %s, subject %q:\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
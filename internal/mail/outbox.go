@@ -0,0 +1,116 @@
+// internal/mail/outbox.go
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Store is the part of the metadata store the outbox needs (storage.MetadataStore
+// satisfies it).
+type Store interface {
+	EnqueueMail(ctx context.Context, mail domain.OutboxMail) (int64, error)
+	ClaimDueMail(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxMail, error)
+	MarkMailSent(ctx context.Context, mailID int64) error
+	RetryMail(ctx context.Context, mailID int64, lastError string, retryAt time.Time) error
+}
+
+const (
+	claimBatchSize = 20
+	claimLease     = 5 * time.Minute // A claimed email is retried after this if its instance dies
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+)
+
+// Enqueue renders a template for to and stores it in the outbox. Delivery happens
+// asynchronously in the Dispatcher, so callers don't wait on (or fail with) the provider.
+func Enqueue(ctx context.Context, store Store, to, template string, data any) error {
+	msg, err := Render(template, to, data)
+	if err != nil {
+		return err
+	}
+	_, err = store.EnqueueMail(ctx, domain.OutboxMail{
+		Recipient: msg.To,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HTMLBody:  msg.HTML,
+	})
+	return err
+}
+
+// Dispatcher delivers queued emails, retrying failed ones with exponential backoff.
+type Dispatcher struct {
+	Store        Store
+	Sender       Sender
+	MaxAttempts  int
+	PollInterval time.Duration
+}
+
+// Run delivers due emails every PollInterval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	customLog.Printf("Mail: Dispatcher started (poll interval %v, max attempts %d)", d.PollInterval, d.MaxAttempts)
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.DeliverDue(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends the emails due at now, in batches until none are left.
+func (d *Dispatcher) DeliverDue(ctx context.Context, now time.Time) {
+	for ctx.Err() == nil {
+		mails, err := d.Store.ClaimDueMail(ctx, now, claimLease, claimBatchSize)
+		if err != nil {
+			customLog.Warnf("Mail: Failed to claim queued mail: %v", err)
+			return
+		}
+		for _, m := range mails {
+			d.deliver(ctx, m, now)
+		}
+		if len(mails) < claimBatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, m domain.OutboxMail, now time.Time) {
+	sendCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	err := d.Sender.Send(sendCtx, Message{To: m.Recipient, Subject: m.Subject, Text: m.TextBody, HTML: m.HTMLBody})
+	cancel()
+
+	if err == nil {
+		if err := d.Store.MarkMailSent(ctx, m.MailID); err != nil {
+			customLog.Warnf("Mail: Sent mail %d but failed to record it: %v", m.MailID, err)
+		}
+		return
+	}
+
+	var retryAt time.Time
+	if m.Attempts < d.MaxAttempts {
+		retryAt = now.Add(retryDelay(m.Attempts))
+		customLog.Warnf("Mail: Attempt %d/%d for mail %d to %s failed, retrying at %s: %v",
+			m.Attempts, d.MaxAttempts, m.MailID, m.Recipient, retryAt.Format(time.RFC3339), err)
+	} else {
+		customLog.Warnf("Mail: Giving up on mail %d to %s after %d attempts: %v", m.MailID, m.Recipient, m.Attempts, err)
+	}
+	if err := d.Store.RetryMail(ctx, m.MailID, err.Error(), retryAt); err != nil {
+		customLog.Warnf("Mail: Failed to reschedule mail %d: %v", m.MailID, err)
+	}
+}
+
+// retryDelay is the backoff after the given number of failed attempts: 30s, 1m, 2m, ...
+// capped at retryMaxDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+type fakeSender struct {
+	fail int // Number of sends that fail before the first success
+	sent []Message
+}
+
+func (f *fakeSender) Send(ctx context.Context, msg Message) error {
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("connection refused")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	msg, err := Render(TemplatePasswordReset, "ada@example.com", LinkData{Username: "ada", URL: "https://app.example.com/reset?t=<x>", ExpiresIn: "30 minutes"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if msg.Subject != "Reset your Nebula password" || msg.To != "ada@example.com" {
+		t.Fatalf("unexpected message header: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "https://app.example.com/reset?t=<x>") {
+		t.Errorf("text body misses the link: %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "reset?t=%3cx%3e") || !strings.Contains(msg.HTML, "<html>") {
+		t.Errorf("HTML body is not escaped or misses the layout: %q", msg.HTML)
+	}
+
+	if _, err := Render("nope", "ada@example.com", nil); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("unknown template: err = %v", err)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+
+	if err := Enqueue(ctx, store, "ada@example.com", TemplateAlert, AlertData{Title: "Disk almost full", Message: "92% used", Time: "now"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	sender := &fakeSender{fail: 1}
+	d := &Dispatcher{Store: store, Sender: sender, MaxAttempts: 3}
+
+	now := time.Now()
+	d.DeliverDue(ctx, now)
+	if len(sender.sent) != 0 {
+		t.Fatalf("first attempt should have failed")
+	}
+	// Not due again until the backoff passes
+	d.DeliverDue(ctx, now.Add(retryBaseDelay-time.Second))
+	if len(sender.sent) != 0 {
+		t.Fatalf("mail retried before its backoff")
+	}
+	d.DeliverDue(ctx, now.Add(retryBaseDelay))
+	if len(sender.sent) != 1 || sender.sent[0].Subject != "[Nebula] Disk almost full" {
+		t.Fatalf("sent = %+v, want the alert after one retry", sender.sent)
+	}
+	// Delivered mail is not sent twice
+	d.DeliverDue(ctx, now.Add(time.Hour))
+	if len(sender.sent) != 1 {
+		t.Fatalf("mail sent %d times", len(sender.sent))
+	}
+
+	// A mail that keeps failing is given up after MaxAttempts
+	if err := Enqueue(ctx, store, "bob@example.com", TemplateAlert, AlertData{Title: "x"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	sender.fail = 100
+	for i := 0; i < 5; i++ {
+		d.DeliverDue(ctx, now.Add(time.Duration(i+2)*time.Hour))
+	}
+	if sender.fail != 97 {
+		t.Errorf("attempts = %d, want 3", 100-sender.fail)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if got := retryDelay(1); got != retryBaseDelay {
+		t.Errorf("retryDelay(1) = %v", got)
+	}
+	if got := retryDelay(3); got != 4*retryBaseDelay {
+		t.Errorf("retryDelay(3) = %v", got)
+	}
+	if got := retryDelay(50); got != retryMaxDelay {
+		t.Errorf("retryDelay(50) = %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+// internal/mail/templates.go
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	"text/template"
+)
+
+// Template names. Each has a templates/<name>.txt whose first line is "Subject: ..."
+// followed by the plain text body, and a templates/<name>.html defining "content".
+const (
+	TemplateVerification  = "verification"
+	TemplateMagicLink     = "magic_link"
+	TemplatePasswordReset = "password_reset"
+	TemplateAlert         = "alert"
+	TemplateAccountExport = "account_export"
+)
+
+// LinkData is the data of the verification, magic link and password reset templates.
+type LinkData struct {
+	Username  string
+	URL       string
+	ExpiresIn string // e.g. "30 minutes"
+}
+
+// AccountExportData is the data of the account export template.
+type AccountExportData struct {
+	Username     string
+	DownloadPath string // API path the archive is downloaded from
+	ExpiresAt    string
+}
+
+// AlertData is the data of the alert template.
+type AlertData struct {
+	Title   string
+	Message string
+	Details map[string]string
+	Time    string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	textTemplates = template.Must(template.ParseFS(templateFS, "templates/*.txt"))
+	htmlLayout    = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/layout.html"))
+)
+
+// ErrUnknownTemplate is returned by Render for a template that does not exist.
+var ErrUnknownTemplate = errors.New("unknown mail template")
+
+// Render fills the named template with data and returns the message for to.
+func Render(name, to string, data any) (Message, error) {
+	textTmpl := textTemplates.Lookup(name + ".txt")
+	if textTmpl == nil {
+		return Message{}, fmt.Errorf("%w '%s'", ErrUnknownTemplate, name)
+	}
+	var text bytes.Buffer
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render mail template '%s': %w", name, err)
+	}
+	subject, body, ok := strings.Cut(text.String(), "\n")
+	if !ok || !strings.HasPrefix(subject, "Subject: ") {
+		return Message{}, fmt.Errorf("mail template '%s' must start with a Subject line", name)
+	}
+	msg := Message{
+		To:      to,
+		Subject: strings.TrimSpace(strings.TrimPrefix(subject, "Subject: ")),
+		Text:    strings.TrimSpace(body) + "\n",
+	}
+
+	htmlPath := "templates/" + name + ".html"
+	if _, err := fs.Stat(templateFS, htmlPath); err != nil {
+		return msg, nil // Text-only template
+	}
+	// Each HTML template defines "content", so it is parsed into its own copy of the layout
+	layout, err := htmlLayout.Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	htmlTmpl, err := layout.ParseFS(templateFS, htmlPath)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to parse mail template '%s': %w", name, err)
+	}
+	var html bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render mail template '%s': %w", name, err)
+	}
+	msg.HTML = html.String()
+	return msg, nil
+}
@@ -0,0 +1,70 @@
+// internal/metrics/counter.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Counter is a monotonically increasing count per value of one label.
+type Counter struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewCounter returns a counter exposed as name, split by label.
+func NewCounter(name, help, label string) *Counter {
+	return &Counter{name: name, help: help, label: label, counts: make(map[string]uint64)}
+}
+
+// ShedRequests counts requests turned away by overload protection, by the limit that
+// was reached ("server" or "tenant").
+var ShedRequests = NewCounter("nebula_http_requests_shed_total", "HTTP requests rejected with 503 because too many were in flight.", "limit")
+
+// MetadataBreakerTransitions counts the changes of state of the circuit breaker around
+// the metadata database, by the state it went to.
+var MetadataBreakerTransitions = NewCounter("nebula_metadata_circuit_transitions_total", "Changes of state of the circuit breaker around the metadata database.", "state")
+
+// Inc adds one to the count of value.
+func (c *Counter) Inc(value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[value]++
+}
+
+// Counts returns a copy of the counts by label value.
+func (c *Counter) Counts() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]uint64, len(c.counts))
+	for value, n := range c.counts {
+		counts[value] = n
+	}
+	return counts
+}
+
+// WritePrometheus writes the counter in the Prometheus text exposition format.
+func (c *Counter) WritePrometheus(w io.Writer) error {
+	counts := c.Counts()
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", c.name)
+	for _, value := range values {
+		fmt.Fprintf(&b, "%s{%s=\"%s\"} %d\n", c.name, c.label, escapeLabel(value), counts[value])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
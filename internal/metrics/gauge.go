@@ -0,0 +1,128 @@
+// internal/metrics/gauge.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Gauge is a current value per value of one label, read from its source whenever it
+// is collected, so it never goes stale. A gauge without a label has a single series,
+// which its source reports under "". Nothing is exposed before SetSource.
+type Gauge struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.Mutex
+	source func() map[string]float64
+}
+
+// NewGauge returns a gauge exposed as name, split by label ("" for a single series).
+func NewGauge(name, help, label string) *Gauge {
+	return &Gauge{name: name, help: help, label: label}
+}
+
+// Capacity gauges, watched to catch resource exhaustion before it causes an outage.
+var (
+	// UserDBHandles counts the open user DB handles, by state ("in_use" or "idle").
+	UserDBHandles = NewGauge("nebula_user_db_handles", "Open user database handles.", "state")
+
+	// CachedSchemas counts the entries of the table schema cache, by kind ("databases"
+	// or "tables").
+	CachedSchemas = NewGauge("nebula_schema_cache_entries", "Entries of the table schema cache.", "kind")
+
+	// BackgroundWorkers counts the running background workers, by health ("ok" or
+	// "fail" for those that stopped beating).
+	BackgroundWorkers = NewGauge("nebula_background_workers", "Running background workers.", "status")
+
+	// RateLimiterKeys is the number of clients tracked by the request rate limiter.
+	RateLimiterKeys = NewGauge("nebula_rate_limiter_keys", "Clients tracked by the request rate limiter.", "")
+
+	// Goroutines is the number of goroutines of the process.
+	Goroutines = &Gauge{name: "nebula_goroutines", help: "Goroutines of the process.", source: func() map[string]float64 {
+		return map[string]float64{"": float64(runtime.NumGoroutine())}
+	}}
+)
+
+// Gauges lists every capacity gauge, in exposition order.
+var Gauges = []*Gauge{UserDBHandles, CachedSchemas, BackgroundWorkers, RateLimiterKeys, Goroutines}
+
+// SetSource sets the function the gauge reads its values from.
+func (g *Gauge) SetSource(source func() map[string]float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.source = source
+}
+
+// Values returns the current values by label value, or nil before SetSource.
+func (g *Gauge) Values() map[string]float64 {
+	g.mu.Lock()
+	source := g.source
+	g.mu.Unlock()
+	if source == nil {
+		return nil
+	}
+	return source()
+}
+
+// Snapshot returns the current value of every gauge with a source, keyed by name
+// without the nebula_ prefix: a number for gauges without a label, else the values by
+// label value.
+func Snapshot() map[string]any {
+	snapshot := make(map[string]any, len(Gauges))
+	for _, g := range Gauges {
+		values := g.Values()
+		if values == nil {
+			continue
+		}
+		key := strings.TrimPrefix(g.name, "nebula_")
+		if g.label == "" {
+			snapshot[key] = values[""]
+		} else {
+			snapshot[key] = values
+		}
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the gauge in the Prometheus text exposition format.
+func (g *Gauge) WritePrometheus(w io.Writer) error {
+	values := g.Values()
+	if values == nil {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", g.name, g.help)
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", g.name)
+	if g.label == "" {
+		fmt.Fprintf(&b, "%s %s\n", g.name, strconv.FormatFloat(values[""], 'g', -1, 64))
+	} else {
+		labels := make([]string, 0, len(values))
+		for value := range values {
+			labels = append(labels, value)
+		}
+		sort.Strings(labels)
+		for _, value := range labels {
+			fmt.Fprintf(&b, "%s{%s=\"%s\"} %s\n", g.name, g.label, escapeLabel(value), strconv.FormatFloat(values[value], 'g', -1, 64))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteGauges writes every capacity gauge in the Prometheus text exposition format.
+func WriteGauges(w io.Writer) error {
+	for _, g := range Gauges {
+		if err := g.WritePrometheus(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency histograms.
+var latencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// RouteKey identifies the requests of one route; Route is the route pattern (e.g.
+// /api/v1/databases/:db_name) and Handler the function serving it.
+type RouteKey struct {
+	Method  string `json:"method,omitempty"`
+	Route   string `json:"route,omitempty"`
+	Handler string `json:"handler"`
+}
+
+// histogram counts latencies per bucket; counts has one more entry than
+// latencyBuckets for the latencies above the last bound.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64 // Seconds
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+}
+
+func (h *histogram) observe(seconds float64) {
+	h.counts[sort.SearchFloat64s(latencyBuckets, seconds)]++
+	h.count++
+	h.sum += seconds
+}
+
+func (h *histogram) merge(other *histogram) {
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// quantile estimates the q-quantile (0 < q < 1) in seconds by linear interpolation
+// within its bucket, like Prometheus' histogram_quantile. Latencies above the last
+// bound are reported as that bound.
+func (h *histogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+	var seen uint64
+	for i, n := range h.counts {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		if i == len(latencyBuckets) {
+			return latencyBuckets[i-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		return lower + (latencyBuckets[i]-lower)*(rank-float64(seen))/float64(n)
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+// Registry collects request latencies per route.
+type Registry struct {
+	mu     sync.Mutex
+	since  time.Time
+	routes map[RouteKey]*histogram
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{since: time.Now(), routes: make(map[RouteKey]*histogram)}
+}
+
+// Requests is the registry the HTTP server records into.
+var Requests = NewRegistry()
+
+// Observe records the latency of one request served by key.
+func (r *Registry) Observe(key RouteKey, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.routes[key]
+	if !ok {
+		h = newHistogram()
+		r.routes[key] = h
+	}
+	h.observe(latency.Seconds())
+}
+
+// Reset drops every recorded latency, e.g. to compare against a fresh deploy.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.since = time.Now()
+	r.routes = make(map[RouteKey]*histogram)
+}
+
+// LatencySummary summarizes the latencies of a route or handler, in milliseconds.
+type LatencySummary struct {
+	RouteKey
+	Count  uint64  `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+// Summary is the latency percentiles recorded since Since, per route and per handler
+// (routes served by the same handler merged), slowest p99 first.
+type Summary struct {
+	Since    time.Time        `json:"since"`
+	Routes   []LatencySummary `json:"routes"`
+	Handlers []LatencySummary `json:"handlers"`
+}
+
+// Summarize computes the latency percentiles of the registry.
+func (r *Registry) Summarize() Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	handlers := make(map[string]*histogram)
+	summary := Summary{Since: r.since, Routes: make([]LatencySummary, 0, len(r.routes))}
+	for key, h := range r.routes {
+		summary.Routes = append(summary.Routes, summarize(key, h))
+		merged, ok := handlers[key.Handler]
+		if !ok {
+			merged = newHistogram()
+			handlers[key.Handler] = merged
+		}
+		merged.merge(h)
+	}
+	summary.Handlers = make([]LatencySummary, 0, len(handlers))
+	for handler, h := range handlers {
+		summary.Handlers = append(summary.Handlers, summarize(RouteKey{Handler: handler}, h))
+	}
+	sortSlowestFirst(summary.Routes)
+	sortSlowestFirst(summary.Handlers)
+	return summary
+}
+
+func summarize(key RouteKey, h *histogram) LatencySummary {
+	summary := LatencySummary{RouteKey: key, Count: h.count}
+	if h.count > 0 {
+		summary.MeanMs = milliseconds(h.sum / float64(h.count))
+	}
+	summary.P50Ms = milliseconds(h.quantile(0.50))
+	summary.P95Ms = milliseconds(h.quantile(0.95))
+	summary.P99Ms = milliseconds(h.quantile(0.99))
+	return summary
+}
+
+func milliseconds(seconds float64) float64 {
+	return math.Round(seconds*1e6) / 1e3 // Microsecond precision
+}
+
+func sortSlowestFirst(summaries []LatencySummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].P99Ms != summaries[j].P99Ms {
+			return summaries[i].P99Ms > summaries[j].P99Ms
+		}
+		a, b := summaries[i].RouteKey, summaries[j].RouteKey
+		return a.Route+" "+a.Method+" "+a.Handler < b.Route+" "+b.Method+" "+b.Handler
+	})
+}
+
+// WritePrometheus writes the latency histograms in the Prometheus text exposition
+// format, as nebula_http_request_duration_seconds.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	keys := make([]RouteKey, 0, len(r.routes))
+	histograms := make(map[RouteKey]histogram, len(r.routes))
+	for key, h := range r.routes {
+		keys = append(keys, key)
+		histograms[key] = histogram{counts: append([]uint64(nil), h.counts...), count: h.count, sum: h.sum}
+	}
+	r.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Route != keys[j].Route {
+			return keys[i].Route < keys[j].Route
+		}
+		return keys[i].Method < keys[j].Method
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP nebula_http_request_duration_seconds Latency of HTTP requests by route.\n")
+	b.WriteString("# TYPE nebula_http_request_duration_seconds histogram\n")
+	for _, key := range keys {
+		h := histograms[key]
+		labels := fmt.Sprintf(`method="%s",route="%s",handler="%s"`, escapeLabel(key.Method), escapeLabel(key.Route), escapeLabel(key.Handler))
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "nebula_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "nebula_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "nebula_http_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "nebula_http_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelEscaper escapes label values as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,111 @@
+// internal/metrics/metrics_test.go
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	r := NewRegistry()
+	list := RouteKey{Method: "GET", Route: "/api/v1/databases", Handler: "handlers.(*DatabaseHandler).ListDatabases"}
+	listV2 := RouteKey{Method: "GET", Route: "/api/v2/databases", Handler: list.Handler}
+	for i := 0; i < 98; i++ {
+		r.Observe(list, 3*time.Millisecond) // (2.5ms, 5ms] bucket
+	}
+	r.Observe(list, 2*time.Second)
+	r.Observe(listV2, 20*time.Millisecond)
+
+	summary := r.Summarize()
+	if len(summary.Routes) != 2 || len(summary.Handlers) != 1 {
+		t.Fatalf("summary = %+v; want 2 routes and 1 handler", summary)
+	}
+	route := summary.Routes[0] // Slowest p99 first
+	if route.Route != list.Route || route.Count != 99 {
+		t.Fatalf("route = %+v; want %s with 99 requests", route, list.Route)
+	}
+	if route.P50Ms <= 2.5 || route.P50Ms > 5 || route.P95Ms > 5 {
+		t.Errorf("p50 = %v, p95 = %v; want both within the (2.5ms, 5ms] bucket", route.P50Ms, route.P95Ms)
+	}
+	if route.P99Ms <= 1000 || route.P99Ms > 2500 {
+		t.Errorf("p99 = %v; want the (1s, 2.5s] bucket", route.P99Ms)
+	}
+	if handler := summary.Handlers[0]; handler.Count != 100 || handler.Route != "" {
+		t.Errorf("handler = %+v; want both routes merged", handler)
+	}
+
+	r.Reset()
+	if summary := r.Summarize(); len(summary.Routes) != 0 {
+		t.Errorf("routes after Reset = %v", summary.Routes)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	key := RouteKey{Method: "GET", Route: "/api/v1/databases/:db_name", Handler: `weird"name`}
+	r.Observe(key, 3*time.Millisecond)
+	r.Observe(key, time.Minute)
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	labels := `method="GET",route="/api/v1/databases/:db_name",handler="weird\"name"`
+	for _, line := range []string{
+		"# TYPE nebula_http_request_duration_seconds histogram",
+		`nebula_http_request_duration_seconds_bucket{` + labels + `,le="0.0025"} 0`,
+		`nebula_http_request_duration_seconds_bucket{` + labels + `,le="0.005"} 1`,
+		`nebula_http_request_duration_seconds_bucket{` + labels + `,le="30"} 1`,
+		`nebula_http_request_duration_seconds_bucket{` + labels + `,le="+Inf"} 2`,
+		`nebula_http_request_duration_seconds_count{` + labels + `} 2`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("exposition lacks %q:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestGaugeWritePrometheus(t *testing.T) {
+	labeled := NewGauge("nebula_test_handles", "Test handles.", "state")
+	single := NewGauge("nebula_test_keys", "Test keys.", "")
+
+	var b strings.Builder
+	if err := labeled.WritePrometheus(&b); err != nil || b.Len() != 0 {
+		t.Fatalf("gauge without a source wrote %q, %v; want nothing", b.String(), err)
+	}
+	labeled.SetSource(func() map[string]float64 { return map[string]float64{"idle": 2, "in_use": 1} })
+	single.SetSource(func() map[string]float64 { return map[string]float64{"": 40} })
+	if err := labeled.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	if err := single.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE nebula_test_handles gauge",
+		`nebula_test_handles{state="idle"} 2`,
+		`nebula_test_handles{state="in_use"} 1`,
+		"nebula_test_keys 40",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("exposition lacks %q:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	RateLimiterKeys.SetSource(func() map[string]float64 { return map[string]float64{"": 7} })
+	defer RateLimiterKeys.SetSource(nil)
+
+	snapshot := Snapshot()
+	if snapshot["rate_limiter_keys"] != 7.0 {
+		t.Errorf("rate_limiter_keys = %v; want 7", snapshot["rate_limiter_keys"])
+	}
+	if goroutines, ok := snapshot["goroutines"].(float64); !ok || goroutines < 1 {
+		t.Errorf("goroutines = %v; want the goroutine count", snapshot["goroutines"])
+	}
+	if _, ok := snapshot["user_db_handles"]; ok {
+		t.Error("snapshot includes user_db_handles, which has no source")
+	}
+}
@@ -0,0 +1,67 @@
+// internal/metrics/state.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// StateGauge exposes which of a fixed set of states something is in, as one series per
+// state that is 1 for the current state and 0 for the others. Nothing is exposed
+// before the first SetState.
+type StateGauge struct {
+	name   string
+	help   string
+	label  string
+	states []string
+
+	mu      sync.Mutex
+	current string
+}
+
+// NewStateGauge returns a gauge exposed as name, with the states as values of label.
+func NewStateGauge(name, help, label string, states ...string) *StateGauge {
+	return &StateGauge{name: name, help: help, label: label, states: states}
+}
+
+// MetadataBreakerState is the state of the circuit breaker around the metadata
+// database ("closed", "open" or "half_open").
+var MetadataBreakerState = NewStateGauge("nebula_metadata_circuit_state", "State of the circuit breaker around the metadata database.", "state",
+	"closed", "open", "half_open")
+
+// SetState records the current state.
+func (g *StateGauge) SetState(state string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.current = state
+}
+
+// State returns the current state, or "" before the first SetState.
+func (g *StateGauge) State() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.current
+}
+
+// WritePrometheus writes the gauge in the Prometheus text exposition format.
+func (g *StateGauge) WritePrometheus(w io.Writer) error {
+	current := g.State()
+	if current == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", g.name, g.help)
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", g.name)
+	for _, state := range g.states {
+		value := 0
+		if state == current {
+			value = 1
+		}
+		fmt.Fprintf(&b, "%s{%s=\"%s\"} %d\n", g.name, g.label, escapeLabel(state), value)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
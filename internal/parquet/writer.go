@@ -0,0 +1,360 @@
+// internal/parquet/writer.go
+// Package parquet writes user table data as Apache Parquet files, the columnar format
+// read by DuckDB, Spark, pandas and most analytics tools. The writer covers what table
+// exports need: flat schemas of optional columns, PLAIN encoding and no compression.
+// Rows are buffered into row groups, so a file can be streamed while it is written.
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRowGroupSize is the number of rows buffered before a row group is written.
+const DefaultRowGroupSize = 50_000
+
+// ContentType is the media type of Parquet files.
+const ContentType = "application/vnd.apache.parquet"
+
+var magic = []byte("PAR1")
+
+// Parquet physical types, encodings and enum values used by the writer.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	repetitionOptional = 1
+	convertedUTF8      = 0
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageTypeData = 0
+	codecNone    = 0
+)
+
+// Column is a column of the exported table: its name and Nebula type (INTEGER, REAL,
+// BOOLEAN, BLOB, TEXT or TIMESTAMP). Unknown types are written as strings.
+type Column struct {
+	Name string
+	Type string
+}
+
+// physicalType returns the Parquet type a Nebula column is stored as, and whether it
+// holds UTF-8 text.
+func (c Column) physicalType() (int32, bool) {
+	switch strings.ToUpper(c.Type) {
+	case "INTEGER":
+		return typeInt64, false
+	case "REAL":
+		return typeDouble, false
+	case "BOOLEAN":
+		return typeBoolean, false
+	case "BLOB":
+		return typeByteArray, false
+	}
+	return typeByteArray, true
+}
+
+type chunkMeta struct {
+	offset           int64
+	size             int64
+	numValues        int64
+	physicalType     int32
+	uncompressedSize int64
+}
+
+type rowGroupMeta struct {
+	numRows int64
+	size    int64
+	chunks  []chunkMeta
+}
+
+// Writer writes one Parquet file. Rows are added with Write and the file is completed
+// by Close; the underlying writer is not closed.
+type Writer struct {
+	w            io.Writer
+	offset       int64
+	columns      []Column
+	RowGroupSize int
+
+	pending   [][]any // Converted values of the buffered rows, by column
+	rows      int
+	rowGroups []rowGroupMeta
+	totalRows int64
+}
+
+// NewWriter starts a Parquet file with the given columns on w.
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	pw := &Writer{w: w, columns: columns, RowGroupSize: DefaultRowGroupSize, pending: make([][]any, len(columns))}
+	if err := pw.write(magic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write adds a row, holding one value per column in column order. Values are those
+// scanned from SQLite: nil, int64, float64, bool, string, []byte or time.Time.
+func (w *Writer) Write(row []any) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(w.columns))
+	}
+	for i, col := range w.columns {
+		value, err := convert(col, row[i])
+		if err != nil {
+			return err
+		}
+		w.pending[i] = append(w.pending[i], value)
+	}
+	w.rows++
+	if w.rows >= w.RowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the file footer.
+func (w *Writer) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	footer := w.footer()
+	if err := w.write(footer); err != nil {
+		return err
+	}
+	if err := w.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer)))); err != nil {
+		return err
+	}
+	return w.write(magic)
+}
+
+func (w *Writer) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
+
+// flush writes the buffered rows as a row group with one data page per column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroupMeta{numRows: int64(w.rows)}
+	for i, col := range w.columns {
+		physicalType, _ := col.physicalType()
+		page := encodePage(physicalType, w.pending[i])
+		header := pageHeader(len(page), w.rows)
+
+		chunk := chunkMeta{offset: w.offset, numValues: int64(w.rows), physicalType: physicalType}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		chunk.size = w.offset - chunk.offset
+		chunk.uncompressedSize = chunk.size
+		group.size += chunk.size
+		group.chunks = append(group.chunks, chunk)
+		w.pending[i] = w.pending[i][:0]
+	}
+	w.rowGroups = append(w.rowGroups, group)
+	w.totalRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// encodePage returns the body of a v1 data page: RLE definition levels followed by the
+// PLAIN encoded non-null values.
+func encodePage(physicalType int32, values []any) []byte {
+	// Definition levels as bit-packed runs of width 1: 1 for a value, 0 for null
+	levels := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v != nil {
+			levels[i/8] |= 1 << (i % 8)
+		}
+	}
+	var hybrid []byte
+	hybrid = binary.AppendUvarint(hybrid, uint64(len(levels))<<1|1)
+	hybrid = append(hybrid, levels...)
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(hybrid)))
+	page = append(page, hybrid...)
+
+	var bits []byte
+	present := 0
+	for _, v := range values {
+		switch v := v.(type) {
+		case int64:
+			page = binary.LittleEndian.AppendUint64(page, uint64(v))
+		case float64:
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(v))
+		case bool:
+			if present%8 == 0 {
+				bits = append(bits, 0)
+			}
+			if v {
+				bits[present/8] |= 1 << (present % 8)
+			}
+			present++
+		case []byte:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(v)))
+			page = append(page, v...)
+		}
+	}
+	if physicalType == typeBoolean {
+		page = append(page, bits...)
+	}
+	return page
+}
+
+func pageHeader(size, numValues int) []byte {
+	t := &thriftWriter{}
+	t.beginStruct()
+	t.i32(1, pageTypeData)
+	t.i32(2, int32(size)) // Uncompressed
+	t.i32(3, int32(size)) // Compressed
+	t.structField(5)
+	t.i32(1, int32(numValues))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE) // Definition levels
+	t.i32(4, encodingRLE) // Repetition levels (none)
+	t.endStruct()
+	t.endStruct()
+	return t.buf
+}
+
+// footer encodes the FileMetaData structure.
+func (w *Writer) footer() []byte {
+	t := &thriftWriter{}
+	t.beginStruct()
+	t.i32(1, 1) // Version
+
+	t.listField(2, thriftStruct, len(w.columns)+1)
+	t.beginStruct()
+	t.str(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.endStruct()
+	for _, col := range w.columns {
+		physicalType, utf8 := col.physicalType()
+		t.beginStruct()
+		t.i32(1, physicalType)
+		t.i32(3, repetitionOptional)
+		t.str(4, col.Name)
+		if utf8 {
+			t.i32(6, convertedUTF8)
+		}
+		t.endStruct()
+	}
+
+	t.i64(3, w.totalRows)
+	t.listField(4, thriftStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		t.beginStruct()
+		t.listField(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			t.beginStruct()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, chunk.physicalType)
+			t.listField(2, thriftI32, 2)
+			t.listI32(encodingPlain, encodingRLE)
+			t.listField(3, thriftBinary, 1)
+			t.listStr(w.columns[i].Name)
+			t.i32(4, codecNone)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+		t.endStruct()
+	}
+	t.str(6, "nebula-backend")
+	t.endStruct()
+	return t.buf
+}
+
+// convert turns a scanned SQLite value into the Go type stored for col: int64,
+// float64, bool or []byte, or nil for NULL. SQLite does not enforce column types, so
+// values of another type are converted when that is lossless.
+func convert(col Column, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	physicalType, _ := col.physicalType()
+	switch physicalType {
+	case typeInt64:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				return int64(v), nil
+			}
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case typeDouble:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+	case typeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case float64:
+			return v != 0, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+	case typeByteArray:
+		switch v := value.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		case time.Time:
+			return []byte(v.UTC().Format("2006-01-02 15:04:05")), nil
+		case int64:
+			return []byte(strconv.FormatInt(v, 10)), nil
+		case float64:
+			return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+		case bool:
+			return []byte(strconv.FormatBool(v)), nil
+		}
+	}
+	return nil, fmt.Errorf("parquet: column '%s': cannot store %T value %v as %s", col.Name, value, value, col.Type)
+}
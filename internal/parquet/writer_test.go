@@ -0,0 +1,80 @@
+// internal/parquet/writer_test.go
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func TestWriterLayout(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{"id", "INTEGER"}, {"name", "TEXT"}, {"score", "REAL"}, {"active", "BOOLEAN"}, {"created_at", "TIMESTAMP"}})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	w.RowGroupSize = 2
+	rows := [][]any{
+		{int64(1), "alice", 9.5, true, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{int64(2), nil, int64(7), int64(0), "2024-01-02 03:04:05"},
+		{int64(3), []byte("carol"), nil, nil, nil},
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatalf("Write(%v) error = %v", row, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, magic) || !bytes.HasSuffix(data, magic) {
+		t.Fatal("file does not start and end with PAR1")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		t.Fatalf("footer length %d out of range for %d byte file", footerLen, len(data))
+	}
+	if len(w.rowGroups) != 2 || w.totalRows != 3 {
+		t.Errorf("got %d row groups with %d rows, want 2 with 3", len(w.rowGroups), w.totalRows)
+	}
+	// Column chunks are laid out back to back after the magic bytes
+	offset := int64(len(magic))
+	for _, group := range w.rowGroups {
+		for _, chunk := range group.chunks {
+			if chunk.offset != offset {
+				t.Fatalf("chunk at offset %d, want %d", chunk.offset, offset)
+			}
+			offset += chunk.size
+		}
+	}
+	if offset != int64(len(data)-footerLen-8) {
+		t.Errorf("footer starts at %d, want %d", len(data)-footerLen-8, offset)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		col     Column
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{Column{"n", "INTEGER"}, "42", int64(42), false},
+		{Column{"n", "INTEGER"}, 3.0, int64(3), false},
+		{Column{"n", "INTEGER"}, 3.5, nil, true},
+		{Column{"n", "INTEGER"}, "abc", nil, true},
+		{Column{"f", "REAL"}, int64(2), 2.0, false},
+		{Column{"b", "BOOLEAN"}, int64(1), true, false},
+		{Column{"b", "BOOLEAN"}, "maybe", nil, true},
+		{Column{"s", "TEXT"}, nil, nil, false},
+	}
+	for _, tc := range testCases {
+		got, err := convert(tc.col, tc.value)
+		if (err != nil) != tc.wantErr || (!tc.wantErr && got != tc.want) {
+			t.Errorf("convert(%s, %v) = %v, %v; want %v, error %v", tc.col.Type, tc.value, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
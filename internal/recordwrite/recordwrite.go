@@ -0,0 +1,88 @@
+// internal/recordwrite/recordwrite.go
+
+// Package recordwrite holds the table checks and script hooks a single-record write
+// runs around the write, whichever API it arrives through. The HTTP handlers, the gRPC
+// service and GraphQL mutations call the same functions in the same order, and only
+// differ in how they report a rejection.
+package recordwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/jsonschema"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+// ErrValidationFailed is wrapped by every *ValidationError.
+var ErrValidationFailed = errors.New("record failed validation")
+
+// ValidationError rejects a record, listing the fields that failed.
+type ValidationError struct {
+	Message string
+	Fields  []core.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	failures := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		if field.Field == "" {
+			failures = append(failures, field.Message)
+		} else {
+			failures = append(failures, field.Field+": "+field.Message)
+		}
+	}
+	return fmt.Sprintf("%s %s", e.Message, strings.Join(failures, "; "))
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidationFailed }
+
+// CheckColumnRules returns a *ValidationError when record breaks a column rule of
+// tableName in the database databaseID. Only the fields present in record are checked,
+// so it also validates partial updates.
+func CheckColumnRules(ctx context.Context, store storage.MetadataStore, databaseID int64, tableName string, record map[string]any) error {
+	rules, err := store.ListColumnRules(ctx, databaseID, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to load validation rules: %w", err)
+	}
+	if fieldErrors := core.CheckColumnRules(rules, record); len(fieldErrors) > 0 {
+		return &ValidationError{Message: "Record failed validation.", Fields: fieldErrors}
+	}
+	return nil
+}
+
+// CheckJSONSchema returns a *ValidationError when record does not match the JSON Schema
+// of tableName in the database databaseID, if the table has one. Partial records
+// (updates) are checked property by property. Object and array values bound for TEXT
+// columns still need core.EncodeJSONValues before they are stored.
+func CheckJSONSchema(ctx context.Context, store storage.MetadataStore, databaseID int64, tableName string, record map[string]any, partial bool) error {
+	document, err := store.GetTableJSONSchema(ctx, databaseID, tableName)
+	if errors.Is(err, storage.ErrJSONSchemaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load JSON schema: %w", err)
+	}
+	schema, err := jsonschema.Compile([]byte(document))
+	if err != nil { // Only stored after compiling
+		return fmt.Errorf("failed to load JSON schema: %w", err)
+	}
+
+	var failures []jsonschema.ValidationError
+	if partial {
+		failures = schema.ValidateProperties(record)
+	} else {
+		failures = schema.Validate(record)
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	fields := make([]core.FieldError, 0, len(failures))
+	for _, failure := range failures {
+		fields = append(fields, core.FieldError(failure))
+	}
+	return &ValidationError{Message: "Record does not match the table's JSON schema.", Fields: fields}
+}
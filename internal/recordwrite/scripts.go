@@ -0,0 +1,79 @@
+// internal/recordwrite/scripts.go
+package recordwrite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/scripting"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var customLog = logger.NewLogger()
+
+// ErrEmptyHookRecord is returned when a before hook returns an empty record.
+var ErrEmptyHookRecord = errors.New("table script hook returned an empty record")
+
+// ScriptLimits returns the sandbox limits of table scripts.
+func ScriptLimits(cfg *config.Config) scripting.Limits {
+	limits := scripting.Limits{Timeout: cfg.ScriptTimeout, MaxCallStackSize: cfg.ScriptMaxCallStackSize}
+	if len(cfg.ScriptWebhookAllowedHosts) > 0 {
+		limits.Webhooks = &scripting.WebhookClient{AllowedHosts: cfg.ScriptWebhookAllowedHosts}
+	}
+	return limits
+}
+
+// LoadTableScript returns the compiled script of tableName in the database databaseID,
+// nil when it has none.
+func LoadTableScript(ctx context.Context, store storage.MetadataStore, cfg *config.Config, databaseID int64, tableName string) (*scripting.Script, error) {
+	source, err := store.GetTableScript(ctx, databaseID, tableName)
+	if errors.Is(err, storage.ErrTableScriptNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table script: %w", err)
+	}
+	script, err := scripting.Compile(source, ScriptLimits(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load table script: %w", err)
+	}
+	return script, nil
+}
+
+// RunBeforeHook passes record through a before hook of script (which may be nil) and
+// returns the record to write. A rejection is a *scripting.RejectError, a hook running
+// too long scripting.ErrScriptTimeout and an empty result ErrEmptyHookRecord.
+func RunBeforeHook(ctx context.Context, cfg *config.Config, script *scripting.Script, hook, tableName string, recordID int64, record map[string]any) (map[string]any, error) {
+	if script == nil || !script.Defines(hook) {
+		return record, nil
+	}
+	event := scripting.Event{Table: tableName, Hook: hook, RecordID: recordID}
+	out, err := script.Run(ctx, hook, record, event, ScriptLimits(cfg))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrEmptyHookRecord, hook)
+	}
+	return out, nil
+}
+
+// RunAfterHook runs an after hook of script (which may be nil) with the stored record.
+// The write is already committed, so failures are only logged.
+func RunAfterHook(ctx context.Context, cfg *config.Config, script *scripting.Script, hook string, userDB storage.UserDataStore, tableName string, recordID int64) {
+	if script == nil || !script.Defines(hook) {
+		return
+	}
+	record, err := userDB.GetRecord(ctx, tableName, recordID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Record write: Failed to read record ID %d of table '%s' for hook %s: %v", recordID, tableName, hook, err)
+		return
+	}
+	event := scripting.Event{Table: tableName, Hook: hook, RecordID: recordID}
+	if _, err := script.Run(ctx, hook, record, event, ScriptLimits(cfg)); err != nil {
+		customLog.Ctx(ctx).Warnf("Record write: Table script hook %s failed for record ID %d of table '%s': %v", hook, recordID, tableName, err)
+	}
+}
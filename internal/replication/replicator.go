@@ -0,0 +1,180 @@
+// internal/replication/replicator.go
+package replication
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/health"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+	"github.com/Annany2002/nebula-backend/internal/storage"
+)
+
+var (
+	customLog = logger.NewLogger()
+)
+
+// Replicator ships database backups and periodic snapshots to off-site S3 storage.
+type Replicator struct {
+	client   *S3Client
+	prefix   string
+	interval time.Duration
+	stageDir string
+
+	mu          sync.Mutex
+	lastShipped map[string]time.Time // file path -> modification time of the last shipped snapshot
+}
+
+// NewReplicator builds a Replicator from configuration.
+// It returns nil when off-site replication is not configured.
+func NewReplicator(cfg *config.Config) *Replicator {
+	if cfg.S3ReplicationBucket == "" {
+		return nil
+	}
+	return &Replicator{
+		client: &S3Client{
+			Endpoint:        cfg.S3ReplicationEndpoint,
+			Region:          cfg.S3ReplicationRegion,
+			Bucket:          cfg.S3ReplicationBucket,
+			AccessKeyID:     cfg.S3ReplicationAccessKeyID,
+			SecretAccessKey: cfg.S3ReplicationSecretKey,
+			PathStyle:       cfg.S3ReplicationPathStyle,
+			HTTPClient:      &http.Client{Timeout: 10 * time.Minute},
+		},
+		prefix:      cfg.S3ReplicationPrefix,
+		interval:    cfg.S3ReplicationInterval,
+		stageDir:    filepath.Join(cfg.BackupDir, ".replication"),
+		lastShipped: make(map[string]time.Time),
+	}
+}
+
+// objectKey builds the bucket key for a file belonging to one user database.
+func (r *Replicator) objectKey(userId, dbName, kind, name string) string {
+	return path.Join(r.prefix, userId, dbName, kind, name)
+}
+
+// ShipBackup uploads a backup file created by the backup subsystem.
+func (r *Replicator) ShipBackup(ctx context.Context, userId, dbName, backupID, backupPath string) error {
+	key := r.objectKey(userId, dbName, "backups", backupID+".db")
+	if err := r.client.PutFile(ctx, key, backupPath); err != nil {
+		customLog.Ctx(ctx).Warnf("Replication: Failed to ship backup %s for DB '%s': %v", backupID, dbName, err)
+		return err
+	}
+	customLog.Ctx(ctx).Printf("Replication: Shipped backup %s for DB '%s' to s3://%s/%s", backupID, dbName, r.client.Bucket, key)
+	return nil
+}
+
+// DeleteUserObjects deletes every backup and snapshot of a user shipped to the bucket
+// and returns how many objects were deleted.
+func (r *Replicator) DeleteUserObjects(ctx context.Context, userId string) (int, error) {
+	keys, err := r.client.ListKeys(ctx, path.Join(r.prefix, userId)+"/")
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err := r.client.DeleteObject(ctx, key); err != nil {
+			return i, err
+		}
+	}
+	if len(keys) > 0 {
+		customLog.Ctx(ctx).Printf("Replication: Deleted %d objects of UserID %s from s3://%s", len(keys), userId, r.client.Bucket)
+	}
+	return len(keys), nil
+}
+
+// Run snapshots every registered database on the configured interval and ships the
+// snapshots whose source changed since the previous run. It blocks until ctx is done.
+func (r *Replicator) Run(ctx context.Context, metaDB storage.MetadataStore) {
+	customLog.Ctx(ctx).Printf("Replication: Shipping snapshots to bucket '%s' every %v", r.client.Bucket, r.interval)
+	health.RegisterWorker("replicator", r.interval)
+	defer health.UnregisterWorker("replicator")
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.replicateAll(ctx, metaDB)
+		health.Beat("replicator")
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replicateAll runs one snapshot pass over all registered databases.
+func (r *Replicator) replicateAll(ctx context.Context, metaDB storage.MetadataStore) {
+	databases, err := metaDB.ListAllDatabases(ctx)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Replication: Failed to list databases: %v", err)
+		return
+	}
+
+	for _, db := range databases {
+		if ctx.Err() != nil {
+			return
+		}
+		if db.Quarantine != "" {
+			continue // Its file may be missing or broken
+		}
+		if err := r.replicateOne(ctx, db.UserID, db.DBName, db.FilePath); err != nil {
+			customLog.Ctx(ctx).Warnf("Replication: Snapshot of DB '%s' (UserID %s) failed: %v", db.DBName, db.UserID, err)
+		}
+	}
+}
+
+// replicateOne ships a fresh snapshot of one database if it changed since the last pass.
+func (r *Replicator) replicateOne(ctx context.Context, userId, dbName, filePath string) error {
+	modTime := latestModTime(filePath)
+	if modTime.IsZero() {
+		return nil // Registered but never written
+	}
+
+	r.mu.Lock()
+	unchanged := !modTime.After(r.lastShipped[filePath])
+	r.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	userDB, err := storage.ConnectUserDB(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	defer storage.ReleaseUserDB(userDB)
+
+	snapshot, err := storage.CreateBackup(ctx, userDB, r.stageDir, dbName)
+	if err != nil {
+		return err
+	}
+	snapshotPath := filepath.Join(r.stageDir, snapshot.BackupID+".db")
+	defer os.Remove(snapshotPath)
+
+	name := snapshot.CreatedAt.Format(sigv4.DateFormat) + ".db"
+	if err := r.client.PutFile(ctx, r.objectKey(userId, dbName, "snapshots", name), snapshotPath); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.lastShipped[filePath] = modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// latestModTime returns the newest modification time of a database file and its WAL.
+func latestModTime(filePath string) time.Time {
+	var latest time.Time
+	for _, p := range []string{filePath, filePath + "-wal"} {
+		if info, err := os.Stat(p); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
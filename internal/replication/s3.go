@@ -0,0 +1,178 @@
+// internal/replication/s3.go
+package replication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+// S3Client uploads, lists and deletes objects in an S3-compatible bucket (AWS S3, MinIO, R2, ...)
+// using Signature Version 4 request signing.
+type S3Client struct {
+	Endpoint        string // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Use endpoint/bucket/key instead of bucket.endpoint/key
+	HTTPClient      *http.Client
+}
+
+// PutFile uploads the file at path under key.
+func (s *S3Client) PutFile(ctx context.Context, key, path string) error {
+	f, err := os.Open(path) // #nosec G304 -- path comes from the storage layer
+	if err != nil {
+		return fmt.Errorf("failed to open file for upload: %w", err)
+	}
+	defer f.Close()
+
+	// Hash the payload up front so the request is fully signed, then rewind for the body
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, f)
+	if err != nil {
+		return fmt.Errorf("failed to hash file for upload: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind file for upload: %w", err)
+	}
+
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), f)
+	if err != nil {
+		return fmt.Errorf("failed to build upload request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/vnd.sqlite3")
+	s.sign(req, hex.EncodeToString(hasher.Sum(nil)), time.Now().UTC())
+
+	res, err := s.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("upload to bucket '%s' failed: %w", s.Bucket, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("upload of '%s' rejected with status %d: %s", key, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response ListKeys reads.
+type listBucketResult struct {
+	Contents              []struct{ Key string }
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// ListKeys returns the keys of every object whose key starts with prefix.
+func (s *S3Client) ListKeys(ctx context.Context, prefix string) ([]string, error) {
+	bucketURL, err := s.objectURL("")
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		bucketURL.RawQuery = query.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, bucketURL.String(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build list request: %w", err)
+		}
+		s.sign(req, sigv4.PayloadHash(nil), time.Now().UTC())
+
+		res, err := s.httpClient().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("listing bucket '%s' failed: %w", s.Bucket, err)
+		}
+		var result listBucketResult
+		if res.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+			res.Body.Close()
+			return nil, fmt.Errorf("listing of '%s' rejected with status %d: %s", prefix, res.StatusCode, strings.TrimSpace(string(body)))
+		}
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid listing of bucket '%s': %w", s.Bucket, err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// DeleteObject deletes the object at key. Deleting a missing object succeeds.
+func (s *S3Client) DeleteObject(ctx context.Context, key string) error {
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, objectURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build delete request: %w", err)
+	}
+	s.sign(req, sigv4.PayloadHash(nil), time.Now().UTC())
+
+	res, err := s.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("delete from bucket '%s' failed: %w", s.Bucket, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 && res.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("delete of '%s' rejected with status %d: %s", key, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *S3Client) httpClient() *http.Client {
+	if s.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return s.HTTPClient
+}
+
+// objectURL builds the request URL for key in either path or virtual-hosted style.
+func (s *S3Client) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", s.Endpoint)
+	}
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+// sign adds SigV4 authentication headers to req.
+func (s *S3Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	creds := sigv4.Credentials{AccessKeyID: s.AccessKeyID, SecretAccessKey: s.SecretAccessKey}
+	sigv4.Sign(req, creds, s.Region, "s3", payloadHash, now)
+}
@@ -0,0 +1,231 @@
+// internal/scripting/scripting.go
+// Package scripting runs the JavaScript hooks users attach to tables. A table script
+// defines any of the functions beforeCreate, beforeUpdate, afterCreate and afterUpdate;
+// each runs in a fresh, sandboxed goja runtime with no access to the host beyond the
+// globals installed here, and is interrupted once its time budget is spent.
+package scripting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+var customLog = logger.NewLogger()
+
+// Hook names, which are also the names of the script functions.
+const (
+	BeforeCreate = "beforeCreate"
+	BeforeUpdate = "beforeUpdate"
+	AfterCreate  = "afterCreate"
+	AfterUpdate  = "afterUpdate"
+)
+
+// Hooks lists every hook a script may define.
+var Hooks = []string{BeforeCreate, BeforeUpdate, AfterCreate, AfterUpdate}
+
+const (
+	// MaxSourceSize bounds the size of a table script.
+	MaxSourceSize = 64 << 10
+	// maxRecordSize bounds the encoded record a before hook may return.
+	maxRecordSize = 1 << 20
+)
+
+var (
+	// ErrInvalidScript wraps compile errors.
+	ErrInvalidScript = errors.New("invalid script")
+	// ErrScriptTimeout is returned when a hook exceeds its time budget.
+	ErrScriptTimeout = errors.New("script timed out")
+)
+
+// RejectError is returned when a hook throws, rejecting the write with the thrown message.
+type RejectError struct {
+	Message string
+}
+
+func (e *RejectError) Error() string {
+	return e.Message
+}
+
+// Limits sandbox hook runs. The call stack limit and the timeout together bound the
+// memory a hook can allocate, since goja has no heap quota of its own.
+type Limits struct {
+	Timeout          time.Duration
+	MaxCallStackSize int
+	Webhooks         *WebhookClient // Nil disables webhook()
+}
+
+// Script is a compiled table script.
+type Script struct {
+	program *goja.Program
+	defines map[string]bool
+}
+
+// Compile parses source and records which hooks it defines. Top-level code runs once
+// here, under limits, so scripts that throw or loop at load time are rejected.
+func Compile(source string, limits Limits) (*Script, error) {
+	if len(source) > MaxSourceSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidScript, MaxSourceSize)
+	}
+	program, err := goja.Compile("table-script.js", source, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScript, err)
+	}
+
+	script := &Script{program: program, defines: make(map[string]bool)}
+	vm, stop, err := script.load(context.Background(), limits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScript, err)
+	}
+	defer stop()
+	for _, hook := range Hooks {
+		if _, ok := goja.AssertFunction(vm.Get(hook)); ok {
+			script.defines[hook] = true
+		}
+	}
+	return script, nil
+}
+
+// Defines reports whether the script has a function for hook.
+func (s *Script) Defines(hook string) bool {
+	return s.defines[hook]
+}
+
+// Event describes the write a hook runs for.
+type Event struct {
+	Table    string `json:"table"`
+	Hook     string `json:"hook"`
+	RecordID int64  `json:"recordId,omitempty"` // Unset for beforeCreate
+}
+
+// Run calls hook with record and event. Before hooks may modify the record or return a
+// replacement, which Run returns; after hooks' return values are ignored. An exception
+// thrown by the script is returned as *RejectError.
+func (s *Script) Run(ctx context.Context, hook string, record map[string]any, event Event, limits Limits) (map[string]any, error) {
+	if !s.defines[hook] {
+		return record, nil
+	}
+	vm, stop, err := s.load(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	fn, _ := goja.AssertFunction(vm.Get(hook))
+	arg := vm.ToValue(record)
+	result, err := fn(goja.Undefined(), arg, vm.ToValue(event))
+	if err != nil {
+		return nil, scriptError(ctx, err)
+	}
+	if hook == AfterCreate || hook == AfterUpdate {
+		return record, nil
+	}
+
+	if goja.IsUndefined(result) || goja.IsNull(result) {
+		result = arg // Modified in place
+	}
+	exported, ok := result.Export().(map[string]any)
+	if !ok {
+		return nil, &RejectError{Message: fmt.Sprintf("%s must return an object or nothing", hook)}
+	}
+	// Round-trip through JSON so the record holds the same types as a decoded request body
+	encoded, err := json.Marshal(exported)
+	if err != nil {
+		return nil, &RejectError{Message: fmt.Sprintf("%s returned a record that cannot be encoded: %v", hook, err)}
+	}
+	if len(encoded) > maxRecordSize {
+		return nil, &RejectError{Message: fmt.Sprintf("%s returned a record larger than %d bytes", hook, maxRecordSize)}
+	}
+	var out map[string]any
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// load creates a sandboxed runtime with the script's top level evaluated. stop must be
+// called when the runtime is no longer used.
+func (s *Script) load(ctx context.Context, limits Limits) (*goja.Runtime, func(), error) {
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	if limits.MaxCallStackSize > 0 {
+		vm.SetMaxCallStackSize(limits.MaxCallStackSize)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	if limits.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, limits.Timeout)
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-runCtx.Done():
+			vm.Interrupt(ErrScriptTimeout)
+		case <-done:
+		}
+	}()
+	stop := func() {
+		close(done)
+		cancel()
+	}
+
+	installGlobals(runCtx, vm, limits)
+	if _, err := vm.RunProgram(s.program); err != nil {
+		stop()
+		return nil, nil, scriptError(ctx, err)
+	}
+	return vm, stop, nil
+}
+
+func installGlobals(ctx context.Context, vm *goja.Runtime, limits Limits) {
+	_ = vm.Set("log", func(call goja.FunctionCall) goja.Value {
+		args := make([]any, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			args[i] = arg.Export()
+		}
+		customLog.Ctx(ctx).Printf("Script: %s", fmt.Sprint(args...))
+		return goja.Undefined()
+	})
+	_ = vm.Set("webhook", func(url string, payload any) map[string]any {
+		if limits.Webhooks == nil {
+			panic(vm.NewGoError(errors.New("webhook() is disabled on this server")))
+		}
+		status, body, err := limits.Webhooks.Post(ctx, url, payload)
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return map[string]any{"status": status, "body": body}
+	})
+}
+
+// scriptError converts a goja failure into ErrScriptTimeout or a *RejectError.
+func scriptError(ctx context.Context, err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if ctx.Err() != nil {
+			return ctx.Err() // The request itself was cancelled
+		}
+		return ErrScriptTimeout
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		value := exception.Value()
+		if obj, ok := value.(*goja.Object); ok {
+			if message := obj.Get("message"); message != nil && !goja.IsUndefined(message) {
+				return &RejectError{Message: message.String()}
+			}
+		}
+		return &RejectError{Message: value.String()}
+	}
+	var stackOverflow *goja.StackOverflowError
+	if errors.As(err, &stackOverflow) {
+		return &RejectError{Message: "maximum call stack size exceeded"}
+	}
+	return &RejectError{Message: err.Error()}
+}
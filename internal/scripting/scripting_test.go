@@ -0,0 +1,87 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testLimits = Limits{Timeout: 200 * time.Millisecond, MaxCallStackSize: 64}
+
+func TestRunBeforeHook(t *testing.T) {
+	script, err := Compile(`
+		function beforeCreate(record, event) {
+			if (record.total < 0) throw new Error("total must not be negative");
+			record.slug = record.title.toLowerCase().replace(/ /g, "-");
+			record.table = event.table;
+		}
+		function beforeUpdate(changes) {
+			return { title: changes.title.trim() };
+		}`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !script.Defines(BeforeCreate) || script.Defines(AfterCreate) {
+		t.Fatalf("Defines reports the wrong hooks")
+	}
+
+	record, err := script.Run(context.Background(), BeforeCreate, map[string]any{"title": "Hello World", "total": 3.0}, Event{Table: "posts", Hook: BeforeCreate}, testLimits)
+	if err != nil || record["slug"] != "hello-world" || record["table"] != "posts" || record["total"] != 3.0 {
+		t.Errorf("beforeCreate = %v, %v", record, err)
+	}
+
+	_, err = script.Run(context.Background(), BeforeCreate, map[string]any{"title": "x", "total": -1.0}, Event{Table: "posts", Hook: BeforeCreate}, testLimits)
+	var reject *RejectError
+	if !errors.As(err, &reject) || reject.Message != "total must not be negative" {
+		t.Errorf("beforeCreate with negative total = %v, want rejection", err)
+	}
+
+	record, err = script.Run(context.Background(), BeforeUpdate, map[string]any{"title": "  Draft ", "total": 1.0}, Event{Table: "posts", Hook: BeforeUpdate, RecordID: 7}, testLimits)
+	if err != nil || len(record) != 1 || record["title"] != "Draft" {
+		t.Errorf("beforeUpdate = %v, %v; want only the returned title", record, err)
+	}
+}
+
+func TestRunLimits(t *testing.T) {
+	script, err := Compile(`
+		function beforeCreate() { for (;;) {} }
+		function beforeUpdate() { return beforeUpdate(); }
+		function afterCreate(record) { webhook("https://example.com/hook", record); }`, testLimits)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	if _, err := script.Run(context.Background(), BeforeCreate, map[string]any{}, Event{}, testLimits); !errors.Is(err, ErrScriptTimeout) {
+		t.Errorf("endless loop = %v, want ErrScriptTimeout", err)
+	}
+	var reject *RejectError
+	if _, err := script.Run(context.Background(), BeforeUpdate, map[string]any{}, Event{}, testLimits); !errors.As(err, &reject) {
+		t.Errorf("unbounded recursion = %v, want rejection", err)
+	}
+	if _, err := script.Run(context.Background(), AfterCreate, map[string]any{}, Event{}, testLimits); !errors.As(err, &reject) {
+		t.Errorf("webhook without client = %v, want rejection", err)
+	}
+
+	if _, err := Compile(`while (true) {}`, testLimits); !errors.Is(err, ErrInvalidScript) {
+		t.Errorf("Compile(endless top level) = %v, want ErrInvalidScript", err)
+	}
+	if _, err := Compile(`function beforeCreate( {`, testLimits); !errors.Is(err, ErrInvalidScript) {
+		t.Errorf("Compile(syntax error) = %v, want ErrInvalidScript", err)
+	}
+}
+
+func TestWebhookAllowlist(t *testing.T) {
+	client := &WebhookClient{AllowedHosts: []string{"hooks.example.com", "*.internal.example.org"}}
+	for host, want := range map[string]bool{
+		"hooks.example.com":      true,
+		"HOOKS.example.com":      true,
+		"a.internal.example.org": true,
+		"internal.example.org":   false,
+		"localhost":              false,
+	} {
+		if got := client.allowed(host); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
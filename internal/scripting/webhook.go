@@ -0,0 +1,80 @@
+// internal/scripting/webhook.go
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxWebhookResponse bounds the response body a script can read.
+const maxWebhookResponse = 64 << 10
+
+// defaultWebhookClient does not follow redirects, which could lead off the allowlist.
+var defaultWebhookClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// WebhookClient performs the synchronous webhook() calls of scripts. Only hosts on the
+// allowlist can be called, which keeps scripts from reaching internal services.
+type WebhookClient struct {
+	AllowedHosts []string     // Exact host names, or "*.example.com" for subdomains
+	Client       *http.Client // Defaults to a client that does not follow redirects
+}
+
+// Post sends payload as JSON to rawURL and returns the status and the response body,
+// decoded when it is JSON. The call is bounded by ctx, the hook's deadline.
+func (w *WebhookClient) Post(ctx context.Context, rawURL string, payload any) (int, any, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") {
+		return 0, nil, fmt.Errorf("webhook: invalid URL %q", rawURL)
+	}
+	if !w.allowed(target.Hostname()) {
+		return 0, nil, fmt.Errorf("webhook: host %q is not allowed", target.Hostname())
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("webhook: cannot encode payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+	if err != nil {
+		return 0, nil, fmt.Errorf("webhook: reading response: %w", err)
+	}
+	var decoded any
+	if json.Unmarshal(raw, &decoded) != nil {
+		decoded = string(raw)
+	}
+	return resp.StatusCode, decoded, nil
+}
+
+func (w *WebhookClient) allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range w.AllowedHosts {
+		pattern = strings.ToLower(pattern)
+		if pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return true
+		}
+	}
+	return false
+}
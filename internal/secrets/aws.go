@@ -0,0 +1,54 @@
+// internal/secrets/aws.go
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+// resolveAWS returns the current value of an AWS Secrets Manager secret.
+func (r *Resolver) resolveAWS(ctx context.Context, secretID string) (string, error) {
+	if r.AWSRegion == "" || r.AWSCredentials.AccessKeyID == "" || r.AWSCredentials.SecretAccessKey == "" {
+		return "", errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	endpoint := r.AWSEndpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + r.AWSRegion + ".amazonaws.com"
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, r.AWSCredentials, r.AWSRegion, "secretsmanager", sigv4.PayloadHash(body), time.Now().UTC())
+
+	var res struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := r.doJSON(req, &res); err != nil {
+		return "", err
+	}
+	if res.SecretString != "" {
+		return res.SecretString, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(res.SecretBinary)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
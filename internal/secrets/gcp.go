@@ -0,0 +1,72 @@
+// internal/secrets/gcp.go
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// gcpMetadataTokenURL hands out access tokens for the instance's service account.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// resolveGCP returns a version of a GCP Secret Manager secret, by resource name
+// (projects/<project>/secrets/<secret>[/versions/<version>]).
+func (r *Resolver) resolveGCP(ctx context.Context, name string) (string, error) {
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+		return "", errors.New("expected projects/<project>/secrets/<secret>")
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := r.gcpToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	endpoint := r.GCPEndpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(endpoint, "/")+"/v1/"+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := r.doJSON(req, &res); err != nil {
+		return "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// gcpToken returns the configured access token or one from the metadata server.
+func (r *Resolver) gcpToken(ctx context.Context) (string, error) {
+	if r.GCPAccessToken != "" {
+		return r.GCPAccessToken, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := r.doJSON(req, &res); err != nil {
+		return "", errors.New("no GCP access token: set GCP_ACCESS_TOKEN or run on GCP with a service account")
+	}
+	return res.AccessToken, nil
+}
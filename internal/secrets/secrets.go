@@ -0,0 +1,166 @@
+// internal/secrets/secrets.go
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/logger"
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+var customLog = logger.NewLogger()
+
+// Reference schemes. A reference names a secret and, after "#", an optional field of
+// the JSON object stored in it:
+//
+//	vault://secret/data/nebula#jwt_secret           (KV v1 or v2 path, field required)
+//	aws-sm://prod/nebula#jwt_secret                 (secret ID or ARN)
+//	gcp-sm://projects/p/secrets/nebula-jwt          (latest version unless /versions/N is given)
+const (
+	SchemeVault = "vault://"
+	SchemeAWS   = "aws-sm://"
+	SchemeGCP   = "gcp-sm://"
+)
+
+// ErrSecretNotFound is returned when a secret or the requested field does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// IsReference reports whether value points into a secrets manager rather than holding
+// the secret itself.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, SchemeVault) || strings.HasPrefix(value, SchemeAWS) || strings.HasPrefix(value, SchemeGCP)
+}
+
+// Resolver fetches secrets from HashiCorp Vault, AWS Secrets Manager and GCP Secret
+// Manager over their HTTP APIs. Only the providers that are referenced need settings.
+type Resolver struct {
+	VaultAddr      string // e.g. https://vault.internal:8200
+	VaultToken     string
+	VaultNamespace string // Vault Enterprise namespace, optional
+
+	AWSRegion      string
+	AWSCredentials sigv4.Credentials
+	AWSEndpoint    string // Overrides https://secretsmanager.<region>.amazonaws.com
+
+	GCPAccessToken string // OAuth token; fetched from the metadata server when empty
+	GCPEndpoint    string // Overrides https://secretmanager.googleapis.com
+
+	HTTPClient *http.Client
+}
+
+// Resolve returns the secret value ref points to.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	name, field, _ := strings.Cut(ref, "#")
+	var value string
+	var err error
+	switch {
+	case strings.HasPrefix(name, SchemeVault):
+		value, err = r.resolveVault(ctx, strings.TrimPrefix(name, SchemeVault), field)
+		field = "" // Vault returns fields, not a JSON document
+	case strings.HasPrefix(name, SchemeAWS):
+		value, err = r.resolveAWS(ctx, strings.TrimPrefix(name, SchemeAWS))
+	case strings.HasPrefix(name, SchemeGCP):
+		value, err = r.resolveGCP(ctx, strings.TrimPrefix(name, SchemeGCP))
+	default:
+		return "", fmt.Errorf("unsupported secret reference '%s'", ref)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	if field == "" {
+		return value, nil
+	}
+	return jsonField(value, field)
+}
+
+// Watch re-resolves refs (env key -> reference) every interval and calls onChange with
+// the key and new value of each secret whose value differs from the last one seen,
+// starting from current. Failures are logged and retried on the next tick. Blocks
+// until ctx is done.
+func (r *Resolver) Watch(ctx context.Context, refs, current map[string]string, interval time.Duration, onChange func(key, value string)) {
+	seen := make(map[string]string, len(current))
+	for key, value := range current {
+		seen[key] = value
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for key, ref := range refs {
+			value, err := r.Resolve(ctx, ref)
+			if err != nil {
+				customLog.Warnf("Secrets: Refresh of %s failed: %v", key, err)
+				continue
+			}
+			if value != seen[key] {
+				customLog.Printf("Secrets: %s was rotated", key)
+				seen[key] = value
+				onChange(key, value)
+			}
+		}
+	}
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// doJSON sends req and decodes a JSON response into out.
+func (r *Resolver) doJSON(req *http.Request, out any) error {
+	res, err := r.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return ErrSecretNotFound
+	}
+	if res.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(body))
+		if len(msg) > 256 {
+			msg = msg[:256]
+		}
+		return fmt.Errorf("request rejected with status %d: %s", res.StatusCode, msg)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jsonField extracts field from a secret holding a JSON object.
+func jsonField(value, field string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot read field '%s'", field)
+	}
+	return stringField(fields, field)
+}
+
+func stringField(fields map[string]any, field string) (string, error) {
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: no field '%s'", ErrSecretNotFound, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/sigv4"
+)
+
+func TestResolveProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/secret/data/nebula" && r.Header.Get("X-Vault-Token") == "vault-token":
+			_, _ = w.Write([]byte(`{"data":{"data":{"jwt_secret":"from-vault"},"metadata":{"version":3}}}`))
+		case r.URL.Path == "/" && r.Header.Get("X-Amz-Target") == "secretsmanager.GetSecretValue":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"SecretString":"{\"jwt_secret\":\"from-aws\"}"}`))
+		case r.URL.Path == "/v1/projects/p/secrets/jwt/versions/latest:access" && r.Header.Get("Authorization") == "Bearer gcp-token":
+			_, _ = w.Write([]byte(`{"payload":{"data":"` + base64.StdEncoding.EncodeToString([]byte("from-gcp")) + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resolver := &Resolver{
+		VaultAddr:      server.URL,
+		VaultToken:     "vault-token",
+		AWSRegion:      "us-east-1",
+		AWSCredentials: sigv4.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		AWSEndpoint:    server.URL,
+		GCPAccessToken: "gcp-token",
+		GCPEndpoint:    server.URL,
+	}
+	ctx := context.Background()
+
+	for ref, want := range map[string]string{
+		"vault://secret/data/nebula#jwt_secret": "from-vault",
+		"aws-sm://prod/nebula#jwt_secret":       "from-aws",
+		"gcp-sm://projects/p/secrets/jwt":       "from-gcp",
+	} {
+		if !IsReference(ref) {
+			t.Errorf("IsReference(%q) = false", ref)
+		}
+		if got, err := resolver.Resolve(ctx, ref); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+
+	if _, err := resolver.Resolve(ctx, "vault://secret/data/nebula#missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("missing field = %v, want ErrSecretNotFound", err)
+	}
+	if _, err := resolver.Resolve(ctx, "gcp-sm://projects/p/secrets/other"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("missing secret = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestWatchReportsRotations(t *testing.T) {
+	value := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"key":"` + value + `"}}`))
+	}))
+	defer server.Close()
+	resolver := &Resolver{VaultAddr: server.URL, VaultToken: "t"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	value = "v2"
+	changed := make(chan string, 1)
+	go resolver.Watch(ctx, map[string]string{"KEY": "vault://kv/app#key"}, map[string]string{"KEY": "v1"}, 10*time.Millisecond, func(key, v string) {
+		changed <- key + "=" + v
+		cancel()
+	})
+
+	select {
+	case got := <-changed:
+		if got != "KEY=v2" {
+			t.Fatalf("onChange(%s), want KEY=v2", got)
+		}
+	case <-ctx.Done():
+		t.Fatal("rotation was not reported")
+	}
+}
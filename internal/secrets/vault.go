@@ -0,0 +1,46 @@
+// internal/secrets/vault.go
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// resolveVault reads field from the secret at path (e.g. secret/data/nebula for KV v2).
+func (r *Resolver) resolveVault(ctx context.Context, path, field string) (string, error) {
+	if r.VaultAddr == "" || r.VaultToken == "" {
+		return "", errors.New("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	if field == "" {
+		return "", errors.New("vault references need a #field")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(r.VaultAddr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", r.VaultToken)
+	if r.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.VaultNamespace)
+	}
+
+	var res struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := r.doJSON(req, &res); err != nil {
+		return "", err
+	}
+	if res.Data == nil {
+		return "", fmt.Errorf("%w: empty response for '%s'", ErrSecretNotFound, path)
+	}
+	// KV v2 nests the secret under data.data; KV v1 returns it as data
+	if nested, ok := res.Data["data"].(map[string]any); ok {
+		if _, versioned := res.Data["metadata"]; versioned {
+			return stringField(nested, field)
+		}
+	}
+	return stringField(res.Data, field)
+}
@@ -0,0 +1,95 @@
+// internal/servicemode/servicemode.go
+package servicemode
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Mode is the instance-wide availability switch.
+type Mode string
+
+const (
+	Normal      Mode = "normal"      // All requests are served
+	ReadOnly    Mode = "read_only"   // Reads are served, writes are rejected with 503
+	Maintenance Mode = "maintenance" // Everything except admin routes and probes is rejected with 503
+)
+
+// State is the current mode with the message shown to rejected clients.
+type State struct {
+	Mode      Mode      `json:"mode"`
+	Message   string    `json:"message,omitempty"`
+	ChangedAt time.Time `json:"changedAt"`
+	// Why writes are rejected in normal mode, e.g. low disk space (see SetWriteProtection)
+	WriteProtection string `json:"writeProtection,omitempty"`
+}
+
+var (
+	mu         sync.RWMutex
+	state      = State{Mode: Normal, ChangedAt: time.Now().UTC()}
+	protection string
+)
+
+// Parse validates a mode name.
+func Parse(name string) (Mode, error) {
+	switch Mode(name) {
+	case Normal, ReadOnly, Maintenance:
+		return Mode(name), nil
+	}
+	return "", fmt.Errorf("unknown service mode '%s' (use normal, read_only or maintenance)", name)
+}
+
+// Set switches the mode. The message is cleared when returning to Normal.
+func Set(mode Mode, message string) State {
+	mu.Lock()
+	defer mu.Unlock()
+	if mode == Normal {
+		message = ""
+	}
+	state = State{Mode: mode, Message: message, ChangedAt: time.Now().UTC()}
+	return withProtection(state)
+}
+
+// SetWriteProtection rejects writes in normal mode while reason is not empty, e.g.
+// while the server is low on disk space; "" lifts it. Unlike Set, it is for conditions
+// the server detects itself, so switching the mode leaves it in place.
+func SetWriteProtection(reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+	protection = reason
+}
+
+// Current returns the current state.
+func Current() State {
+	mu.RLock()
+	defer mu.RUnlock()
+	return withProtection(state)
+}
+
+func withProtection(s State) State {
+	s.WriteProtection = protection
+	return s
+}
+
+// AllowsWrites reports whether requests may modify data.
+func AllowsWrites() bool {
+	s := Current()
+	return s.Mode == Normal && s.WriteProtection == ""
+}
+
+// RejectionMessage is the message returned to clients whose request is refused in
+// the current mode.
+func RejectionMessage() string {
+	s := Current()
+	if s.Mode == Normal && s.WriteProtection != "" {
+		return "Writes are temporarily disabled: " + s.WriteProtection + "."
+	}
+	if s.Message != "" {
+		return s.Message
+	}
+	if s.Mode == Maintenance {
+		return "The service is down for maintenance. Please try again later."
+	}
+	return "The service is in read-only mode. Writes are temporarily disabled."
+}
@@ -0,0 +1,288 @@
+// internal/storage/account_export.go
+package storage
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// ErrAccountExportNotFound is returned when an account has no export of an ID, or it
+// expired.
+var ErrAccountExportNotFound = errors.New("account export not found")
+
+// auditEventPage is how many audit events are read at a time into an account export.
+const auditEventPage = 500
+
+// exportedProfile is the account profile of an export, without the password hash.
+type exportedProfile struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedDatabase is the registration of a database in an export. Storage locations
+// and API keys are left out: they are not the user's data.
+type exportedDatabase struct {
+	Name      string            `json:"name"`
+	Tables    int64             `json:"tables"`
+	Labels    map[string]string `json:"labels"`
+	ExpiresAt *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Dump      string            `json:"dump,omitempty"`    // Path of the SQL dump in the archive
+	Omitted   string            `json:"omitted,omitempty"` // Why the contents are not in the archive
+}
+
+// AccountExportDirectory returns the directory holding the exports of one account.
+func AccountExportDirectory(exportRoot, userId string) string {
+	return filepath.Join(exportRoot, userId)
+}
+
+// CreateAccountExport writes a zip archive of everything stored about an account into
+// exportDir: profile.json, databases.json, audit_log.json and, when databases are SQLite
+// files, an SQL dump of each readable database under databases/. databases.json lists
+// the databases that could not be dumped, and why. The archive is only visible once
+// complete.
+func CreateAccountExport(ctx context.Context, store MetadataStore, exportDir, userId string, retention time.Duration) (*domain.AccountExport, error) {
+	user, err := store.FindUserByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	// Every registration is exported, including databases whose files cannot be read
+	databases, err := store.ListUserDatabaseRegistrations(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range databases {
+		if databases[i].Labels, err = store.GetDatabaseLabels(ctx, databases[i].DatabaseID); err != nil {
+			return nil, err
+		}
+	}
+	var events []domain.AuditEvent
+	for offset := 0; ; offset += auditEventPage {
+		page, _, err := store.ListAuditEvents(ctx, userId, auditEventPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, page...)
+		if len(page) < auditEventPage {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(exportDir, 0o750); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error creating export directory '%s': %v", exportDir, err)
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	exportID := uuid.New().String()
+	exportPath := filepath.Join(exportDir, exportID+".zip")
+	tmpPath := exportPath + ".tmp"
+	if err := writeAccountArchive(ctx, tmpPath, user, databases, events); err != nil {
+		_ = os.Remove(tmpPath)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to write export of UserID %s: %v", userId, err)
+		return nil, fmt.Errorf("failed to write account export: %w", err)
+	}
+	if err := os.Rename(tmpPath, exportPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write account export: %w", err)
+	}
+
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account export: %w", err)
+	}
+	return accountExport(exportID, info, retention), nil
+}
+
+// writeAccountArchive writes the archive of CreateAccountExport to path.
+func writeAccountArchive(ctx context.Context, path string, user *domain.UserMetadata, databases []domain.DatabaseMetadata, events []domain.AuditEvent) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+
+	writeJSON := func(name string, value any) error {
+		w, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	profile := exportedProfile{UserID: user.UserId, Username: user.Username, Email: user.Email, Role: user.Role, CreatedAt: user.CreatedAt}
+	if err := writeJSON("profile.json", profile); err != nil {
+		return err
+	}
+
+	// Database contents can only be dumped from SQLite files
+	dump := UserDataBackend() == BackendSQLite
+	registrations := make([]exportedDatabase, 0, len(databases))
+	for _, db := range databases {
+		registration := exportedDatabase{Name: db.DBName, Labels: db.Labels, ExpiresAt: db.ExpiresAt, CreatedAt: db.CreatedAt}
+		if dump {
+			if err := dumpExportedDatabase(ctx, archive, db, &registration); err != nil {
+				return err
+			}
+		}
+		registrations = append(registrations, registration)
+	}
+	if err := writeJSON("databases.json", registrations); err != nil {
+		return err
+	}
+	if events == nil {
+		events = []domain.AuditEvent{}
+	}
+	if err := writeJSON("audit_log.json", events); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// dumpExportedDatabase adds the SQL dump of db to archive. A database that cannot be
+// read is listed with the reason it was omitted instead of failing the export.
+func dumpExportedDatabase(ctx context.Context, archive *zip.Writer, db domain.DatabaseMetadata, registration *exportedDatabase) error {
+	if db.Quarantine != "" {
+		registration.Omitted = "Database is quarantined after a consistency check"
+		return nil
+	}
+	tables, err := countUserTables(ctx, db.FilePath)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Omitting unreadable database '%s' from export of UserID %s: %v", db.DBName, db.UserID, err)
+		registration.Omitted = "Database file could not be read"
+		return nil
+	}
+	registration.Tables = tables
+	registration.Dump = "databases/" + db.DBName + ".sql"
+	w, err := archive.Create(registration.Dump)
+	if err != nil {
+		return err
+	}
+	userDB, err := ConnectUserDB(ctx, db.FilePath)
+	if err != nil {
+		return fmt.Errorf("database '%s': %w", db.DBName, err)
+	}
+	defer ReleaseUserDB(userDB)
+	if err := DumpSQL(ctx, userDB, w); err != nil {
+		return fmt.Errorf("database '%s': %w", db.DBName, err)
+	}
+	return nil
+}
+
+// ListAccountExports returns the exports in exportDir that have not expired, newest
+// first.
+func ListAccountExports(exportDir string, retention time.Duration) ([]domain.AccountExport, error) {
+	entries, err := os.ReadDir(exportDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []domain.AccountExport{}, nil
+		}
+		return nil, fmt.Errorf("failed to list account exports: %w", err)
+	}
+	exports := []domain.AccountExport{}
+	for _, entry := range entries {
+		exportID, ok := strings.CutSuffix(entry.Name(), ".zip")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if export := accountExport(exportID, info, retention); export.ExpiresAt.After(time.Now()) {
+			exports = append(exports, *export)
+		}
+	}
+	sort.Slice(exports, func(i, j int) bool { return exports[i].CreatedAt.After(exports[j].CreatedAt) })
+	return exports, nil
+}
+
+// FindAccountExport returns the path of an unexpired export in exportDir.
+func FindAccountExport(exportDir, exportID string, retention time.Duration) (string, error) {
+	if _, err := uuid.Parse(exportID); err != nil {
+		return "", ErrAccountExportNotFound
+	}
+	exportPath := filepath.Join(exportDir, exportID+".zip")
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrAccountExportNotFound
+		}
+		return "", fmt.Errorf("failed to read account export: %w", err)
+	}
+	if !accountExport(exportID, info, retention).ExpiresAt.After(time.Now()) {
+		return "", ErrAccountExportNotFound
+	}
+	return exportPath, nil
+}
+
+// accountExport describes the export file of info.
+func accountExport(exportID string, info os.FileInfo, retention time.Duration) *domain.AccountExport {
+	createdAt := info.ModTime().UTC()
+	return &domain.AccountExport{
+		ExportID:  exportID,
+		SizeBytes: info.Size(),
+		CreatedAt: createdAt,
+		ExpiresAt: createdAt.Add(retention),
+	}
+}
+
+// RunAccountExportPurger periodically deletes the account exports under exportRoot
+// older than retention, until ctx is done.
+func RunAccountExportPurger(ctx context.Context, exportRoot string, retention, interval time.Duration) {
+	health.RegisterWorker("account_export_purger", interval)
+	defer health.UnregisterWorker("account_export_purger")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purgeExpiredAccountExports(ctx, exportRoot, time.Now().Add(-retention))
+			health.Beat("account_export_purger")
+		}
+	}
+}
+
+// purgeExpiredAccountExports deletes the exports, and archives left unfinished by a
+// crash, written before cutoff.
+func purgeExpiredAccountExports(ctx context.Context, exportRoot string, cutoff time.Time) {
+	paths, err := filepath.Glob(filepath.Join(exportRoot, "*", "*.zip*"))
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Account export purge skipped: %v", err)
+		return
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to delete expired account export '%s': %v", path, err)
+			continue
+		}
+		customLog.Ctx(ctx).Printf("Storage: Deleted expired account export '%s'", path)
+	}
+}
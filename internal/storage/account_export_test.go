@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestCreateAccountExport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: dir, MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "secret-hash"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	location := UserDataLocation(dir, "u1", "notes")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	userDB, err := ConnectUserDB(ctx, location)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	if _, err := userDB.ExecContext(ctx, `CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT); INSERT INTO notes (body) VALUES ('hello export')`); err != nil {
+		t.Fatal(err)
+	}
+	ReleaseUserDB(userDB)
+	if err := store.RegisterDatabase(ctx, "u1", "notes", location); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+
+	// A database whose file cannot be read is listed as omitted, not left out
+	broken := UserDataLocation(dir, "u1", "broken")
+	if err := os.WriteFile(broken, []byte("not a database file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "broken", broken); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+
+	exportRoot := filepath.Join(dir, "exports")
+	exportDir := AccountExportDirectory(exportRoot, "u1")
+	export, err := CreateAccountExport(ctx, store, exportDir, "u1", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAccountExport: %v", err)
+	}
+
+	archive, err := zip.OpenReader(filepath.Join(exportDir, export.ExportID+".zip"))
+	if err != nil {
+		t.Fatalf("opening archive: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(data)
+	}
+	archive.Close()
+	for _, name := range []string{"profile.json", "databases.json", "audit_log.json", "databases/notes.sql"} {
+		if _, ok := contents[name]; !ok {
+			t.Errorf("archive is missing %s", name)
+		}
+	}
+	if !strings.Contains(contents["profile.json"], "u1@example.com") || strings.Contains(contents["profile.json"], "secret-hash") {
+		t.Errorf("profile.json = %s; want the email and no password hash", contents["profile.json"])
+	}
+	if _, ok := contents["databases/broken.sql"]; ok {
+		t.Errorf("archive has a dump of the unreadable database")
+	}
+	var databases []exportedDatabase
+	if err := json.Unmarshal([]byte(contents["databases.json"]), &databases); err != nil {
+		t.Fatalf("databases.json: %v", err)
+	}
+	if len(databases) != 2 || databases[0].Name != "broken" || databases[0].Omitted == "" || databases[1].Omitted != "" || databases[1].Tables != 1 {
+		t.Errorf("databases.json = %+v; want broken omitted and notes dumped", databases)
+	}
+	if !strings.Contains(contents["databases/notes.sql"], "hello export") {
+		t.Errorf("databases/notes.sql does not contain the records:\n%s", contents["databases/notes.sql"])
+	}
+
+	exports, err := ListAccountExports(exportDir, time.Hour)
+	if err != nil || len(exports) != 1 || exports[0].ExportID != export.ExportID {
+		t.Errorf("ListAccountExports = %+v, %v; want the new export", exports, err)
+	}
+	if _, err := FindAccountExport(exportDir, export.ExportID, time.Hour); err != nil {
+		t.Errorf("FindAccountExport: %v", err)
+	}
+	if _, err := FindAccountExport(exportDir, "../meta", time.Hour); !errors.Is(err, ErrAccountExportNotFound) {
+		t.Errorf("FindAccountExport of a path = %v; want ErrAccountExportNotFound", err)
+	}
+	if _, err := FindAccountExport(exportDir, export.ExportID, -time.Second); !errors.Is(err, ErrAccountExportNotFound) {
+		t.Errorf("FindAccountExport of an expired export = %v; want ErrAccountExportNotFound", err)
+	}
+
+	purgeExpiredAccountExports(ctx, exportRoot, time.Now().Add(-time.Hour))
+	if exports, _ := ListAccountExports(exportDir, time.Hour); len(exports) != 1 {
+		t.Errorf("export purged before its retention")
+	}
+	purgeExpiredAccountExports(ctx, exportRoot, time.Now().Add(time.Minute))
+	if exports, _ := ListAccountExports(exportDir, time.Hour); len(exports) != 0 {
+		t.Errorf("expired export not purged: %+v", exports)
+	}
+}
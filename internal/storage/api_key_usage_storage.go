@@ -0,0 +1,229 @@
+// internal/storage/api_key_usage_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// apiKeyUsageKey identifies the pending traffic of the API key of one database to one
+// route on one day. Each database has at most one key, so requests only resolve the
+// key's ID when flushed.
+type apiKeyUsageKey struct {
+	databaseID int64
+	day        string
+	method     string
+	route      string
+}
+
+// apiKeyClientKey identifies a client address of the API key of one database.
+type apiKeyClientKey struct {
+	databaseID int64
+	ip         string
+}
+
+// apiKeyCounter accumulates API key traffic in memory between flushes to the metadata DB.
+type apiKeyCounter struct {
+	mu      sync.Mutex
+	usage   map[apiKeyUsageKey]*domain.APIKeyUsage
+	clients map[apiKeyClientKey]*domain.APIKeyClient
+}
+
+var apiKeyTraffic = &apiKeyCounter{
+	usage:   make(map[apiKeyUsageKey]*domain.APIKeyUsage),
+	clients: make(map[apiKeyClientKey]*domain.APIKeyClient),
+}
+
+// RecordAPIKeyUsage counts one request authenticated with the API key of databaseId to
+// route (the route pattern) from ip. Requests failing with a status of 400 or above
+// also count as errors.
+func RecordAPIKeyUsage(databaseId int64, method, route, ip string, status int) {
+	now := time.Now().UTC()
+	key := apiKeyUsageKey{databaseID: databaseId, day: now.Format(UsageDayLayout), method: method, route: route}
+	apiKeyTraffic.mu.Lock()
+	defer apiKeyTraffic.mu.Unlock()
+	usage, ok := apiKeyTraffic.usage[key]
+	if !ok {
+		usage = &domain.APIKeyUsage{Day: key.day, Method: method, Route: route}
+		apiKeyTraffic.usage[key] = usage
+	}
+	usage.Requests++
+	if status >= 400 {
+		usage.Errors++
+	}
+
+	clientKey := apiKeyClientKey{databaseID: databaseId, ip: ip}
+	client, ok := apiKeyTraffic.clients[clientKey]
+	if !ok {
+		client = &domain.APIKeyClient{IP: ip}
+		apiKeyTraffic.clients[clientKey] = client
+	}
+	client.Requests++
+	client.LastSeenAt = now
+}
+
+// FlushAPIKeyUsage adds the API key traffic counted since the last flush to the
+// metadata DB. Traffic of keys deleted meanwhile is dropped; traffic that failed to be
+// stored is kept for the next flush.
+func FlushAPIKeyUsage(ctx context.Context, store MetadataStore) error {
+	apiKeyTraffic.mu.Lock()
+	pendingUsage, pendingClients := apiKeyTraffic.usage, apiKeyTraffic.clients
+	apiKeyTraffic.usage = make(map[apiKeyUsageKey]*domain.APIKeyUsage)
+	apiKeyTraffic.clients = make(map[apiKeyClientKey]*domain.APIKeyClient)
+	apiKeyTraffic.mu.Unlock()
+
+	usageByDatabase := make(map[int64][]domain.APIKeyUsage)
+	for key, usage := range pendingUsage {
+		usageByDatabase[key.databaseID] = append(usageByDatabase[key.databaseID], *usage)
+	}
+	clientsByDatabase := make(map[int64][]domain.APIKeyClient)
+	for key, client := range pendingClients {
+		clientsByDatabase[key.databaseID] = append(clientsByDatabase[key.databaseID], *client)
+	}
+
+	var firstErr error
+	for databaseID, clients := range clientsByDatabase { // Every counted request has a client
+		err := store.AddAPIKeyUsage(ctx, databaseID, usageByDatabase[databaseID], clients)
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			apiKeyTraffic.restore(databaseID, usageByDatabase[databaseID], clients)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// restore merges the traffic of a database's key back into the pending traffic after
+// a failed flush.
+func (a *apiKeyCounter) restore(databaseID int64, usage []domain.APIKeyUsage, clients []domain.APIKeyClient) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, u := range usage {
+		key := apiKeyUsageKey{databaseID: databaseID, day: u.Day, method: u.Method, route: u.Route}
+		if current, ok := a.usage[key]; ok {
+			current.Requests += u.Requests
+			current.Errors += u.Errors
+		} else {
+			a.usage[key] = &u
+		}
+	}
+	for _, c := range clients {
+		key := apiKeyClientKey{databaseID: databaseID, ip: c.IP}
+		if current, ok := a.clients[key]; ok {
+			current.Requests += c.Requests // current was seen later
+		} else {
+			a.clients[key] = &c
+		}
+	}
+}
+
+// FindAPIKeyID returns the ID of the API key of a database, or ErrAPIKeyNotFound if
+// it has none.
+func (s *sqlMetadataStore) FindAPIKeyID(ctx context.Context, databaseId int64) (int64, error) {
+	var keyId int64
+	err := s.queryRow(ctx, `SELECT api_key_id FROM api_keys WHERE api_database_id = ?`, databaseId).Scan(&keyId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrAPIKeyNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error finding API key ID of DBID %d: %v", databaseId, err)
+		return 0, fmt.Errorf("database error finding api key: %w", err)
+	}
+	return keyId, nil
+}
+
+// AddAPIKeyUsage adds traffic to the usage buckets and client addresses of the current
+// API key of a database. Returns ErrAPIKeyNotFound if the database has no key.
+func (s *sqlMetadataStore) AddAPIKeyUsage(ctx context.Context, databaseId int64, usage []domain.APIKeyUsage, clients []domain.APIKeyClient) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error storing api key usage: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	var keyId int64
+	err = tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT api_key_id FROM api_keys WHERE api_database_id = ?`), databaseId).Scan(&keyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("database error finding api key: %w", err)
+	}
+
+	usageSQL := s.dialect.rebind(`INSERT INTO api_key_usage (api_key_id, day, method, route, requests, errors) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (api_key_id, day, method, route) DO UPDATE SET requests = api_key_usage.requests + excluded.requests,
+		errors = api_key_usage.errors + excluded.errors`)
+	for _, u := range usage {
+		if _, err := tx.ExecContext(ctx, usageSQL, keyId, u.Day, u.Method, u.Route, u.Requests, u.Errors); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to store usage of API key %d on %s: %v", keyId, u.Day, err)
+			return fmt.Errorf("database error storing api key usage: %w", err)
+		}
+	}
+	clientSQL := s.dialect.rebind(`INSERT INTO api_key_clients (api_key_id, ip, requests, last_seen_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (api_key_id, ip) DO UPDATE SET requests = api_key_clients.requests + excluded.requests,
+		last_seen_at = excluded.last_seen_at`)
+	for _, c := range clients {
+		if _, err := tx.ExecContext(ctx, clientSQL, keyId, c.IP, c.Requests, c.LastSeenAt.UTC()); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to store client of API key %d: %v", keyId, err)
+			return fmt.Errorf("database error storing api key client: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error storing api key usage: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeyUsage returns the usage buckets of an API key from day since (YYYY-MM-DD)
+// on, by day then route. Days without traffic are absent.
+func (s *sqlMetadataStore) ListAPIKeyUsage(ctx context.Context, apiKeyId int64, since string) ([]domain.APIKeyUsage, error) {
+	rows, err := s.query(ctx, `SELECT day, method, route, requests, errors FROM api_key_usage
+		WHERE api_key_id = ? AND day >= ? ORDER BY day, route, method`, apiKeyId, since)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list usage of API key %d: %v", apiKeyId, err)
+		return nil, fmt.Errorf("database error listing api key usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]domain.APIKeyUsage, 0)
+	for rows.Next() {
+		var u domain.APIKeyUsage
+		if err := rows.Scan(&u.Day, &u.Method, &u.Route, &u.Requests, &u.Errors); err != nil {
+			return nil, fmt.Errorf("database error reading api key usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// ListAPIKeyClients returns up to limit client addresses of an API key, most recently
+// seen first.
+func (s *sqlMetadataStore) ListAPIKeyClients(ctx context.Context, apiKeyId int64, limit int) ([]domain.APIKeyClient, error) {
+	rows, err := s.query(ctx, `SELECT ip, requests, last_seen_at FROM api_key_clients
+		WHERE api_key_id = ? ORDER BY last_seen_at DESC, ip LIMIT ?`, apiKeyId, limit)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list clients of API key %d: %v", apiKeyId, err)
+		return nil, fmt.Errorf("database error listing api key clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := make([]domain.APIKeyClient, 0)
+	for rows.Next() {
+		var c domain.APIKeyClient
+		if err := rows.Scan(&c.IP, &c.Requests, &c.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("database error reading api key clients: %w", err)
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
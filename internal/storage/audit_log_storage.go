@@ -0,0 +1,131 @@
+// internal/storage/audit_log_storage.go
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// AddAuditEvent appends an event to the audit log and returns its ID. Events with an
+// empty UserID are not tied to an account.
+func (s *sqlMetadataStore) AddAuditEvent(ctx context.Context, event domain.AuditEvent) (int64, error) {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return 0, fmt.Errorf("failed encoding audit event details: %w", err)
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+	var userID any
+	if event.UserID != "" {
+		userID = event.UserID
+	}
+
+	var id int64
+	err = s.queryRow(ctx, `INSERT INTO audit_log (user_id, event, ip, details) VALUES (?, ?, ?, ?) RETURNING event_id`,
+		userID, event.Event, event.IP, string(details)).Scan(&id)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to add audit event '%s': %v", event.Event, err)
+		return 0, fmt.Errorf("database error adding audit event: %w", err)
+	}
+	return id, nil
+}
+
+// ListAuditEvents returns a page of the audit log of a user, newest first, or of every
+// account when userId is empty. A limit of 0 returns every event.
+func (s *sqlMetadataStore) ListAuditEvents(ctx context.Context, userId string, limit, offset int) ([]domain.AuditEvent, PaginationMeta, error) {
+	where, args := "", []any{}
+	if userId != "" {
+		where, args = " WHERE user_id = ?", append(args, userId)
+	}
+	pagination := PaginationMeta{Limit: limit, Offset: offset}
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&pagination.Total); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to count audit events: %v", err)
+		return nil, pagination, fmt.Errorf("database error counting audit events: %w", err)
+	}
+
+	query := `SELECT event_id, user_id, event, ip, details, created_at FROM audit_log` + where + ` ORDER BY event_id DESC`
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list audit events: %v", err)
+		return nil, pagination, fmt.Errorf("database error listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]domain.AuditEvent, 0)
+	for rows.Next() {
+		var event domain.AuditEvent
+		var userID sql.NullString
+		var details string
+		if err := rows.Scan(&event.EventID, &userID, &event.Event, &event.IP, &details, &event.CreatedAt); err != nil {
+			return nil, pagination, fmt.Errorf("database error reading audit events: %w", err)
+		}
+		event.UserID = userID.String
+		if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+			return nil, pagination, fmt.Errorf("invalid details of audit event %d: %w", event.EventID, err)
+		}
+		events = append(events, event)
+	}
+	return events, pagination, rows.Err()
+}
+
+// RecordAPIKeyCountry notes that key was used from country and reports whether that
+// is a new country for a key already used from another one. The first country a key
+// is seen from is never reported.
+func (s *sqlMetadataStore) RecordAPIKeyCountry(ctx context.Context, key, country string) (bool, error) {
+	result, err := s.exec(ctx, `INSERT INTO api_key_countries (api_key_id, country)
+		SELECT api_key_id, ? FROM api_keys WHERE key = ?
+		ON CONFLICT (api_key_id, country) DO NOTHING`, country, key)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to record API key country: %v", err)
+		return false, fmt.Errorf("database error recording api key country: %w", err)
+	}
+	if added, err := result.RowsAffected(); err != nil || added == 0 {
+		return false, err
+	}
+
+	var countries int
+	err = s.queryRow(ctx, `SELECT COUNT(*) FROM api_key_countries c
+		JOIN api_keys k ON k.api_key_id = c.api_key_id WHERE k.key = ?`, key).Scan(&countries)
+	if err != nil {
+		return false, fmt.Errorf("database error counting api key countries: %w", err)
+	}
+	return countries > 1, nil
+}
+
+// FindRevokedAPIKey resolves a deleted API key to the database it was issued for, that
+// database's owner and when the key was deleted. Returns ErrAPIKeyNotFound for keys
+// that were never issued, or whose database is gone.
+func (s *sqlMetadataStore) FindRevokedAPIKey(ctx context.Context, key string) (int64, string, time.Time, error) {
+	var databaseId int64
+	var userId string
+	var revokedAt time.Time
+	err := s.queryRow(ctx, `SELECT database_id, owner_id, revoked_at FROM revoked_api_keys WHERE key_hash = ?`,
+		apiKeyHash(key)).Scan(&databaseId, &userId, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", time.Time{}, ErrAPIKeyNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up revoked API key: %v", err)
+		return 0, "", time.Time{}, fmt.Errorf("database error finding revoked api key: %w", err)
+	}
+	return databaseId, userId, revokedAt, nil
+}
+
+// apiKeyHash is the hex SHA-256 digest revoked keys are stored by.
+func apiKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
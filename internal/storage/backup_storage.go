@@ -0,0 +1,432 @@
+// internal/storage/backup_storage.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Specific errors for backup and restore operations
+var (
+	ErrBackupNotFound      = errors.New("backup not found")
+	ErrInvalidDatabaseFile = errors.New("file is not a valid SQLite database")
+)
+
+// sqliteFileHeader is the magic string every SQLite 3 database file starts with.
+const sqliteFileHeader = "SQLite format 3\x00"
+
+// BackupDirectory returns the directory holding backups for one user database.
+func BackupDirectory(backupRoot, userId, dbName string) string {
+	return filepath.Join(backupRoot, userId, dbName)
+}
+
+// CreateBackup writes a consistent snapshot of the user DB into backupDir using VACUUM INTO,
+// compressed and encrypted as set by ConfigureBackups.
+func CreateBackup(ctx context.Context, userDB *sql.DB, backupDir, dbName string) (*domain.BackupMetadata, error) {
+	if err := os.MkdirAll(backupDir, 0o750); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error creating backup directory '%s': %v", backupDir, err)
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	backupID := uuid.New().String()
+	backupPath := filepath.Join(backupDir, backupID+".db")
+
+	// Compressed or encrypted backups are encoded from a raw snapshot next to them
+	snapshotPath := backupPath
+	if backupEncodingEnabled() {
+		snapshotPath = backupPath + ".tmp"
+		defer os.Remove(snapshotPath)
+	}
+
+	if _, err := userDB.ExecContext(ctx, "VACUUM INTO ?", snapshotPath); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed VACUUM INTO '%s': %v", snapshotPath, err)
+		_ = os.Remove(snapshotPath)
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if snapshotPath != backupPath {
+		if err := encodeBackupFile(snapshotPath, backupPath); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to encode backup '%s': %v", backupPath, err)
+			_ = os.Remove(backupPath)
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup file: %w", err)
+	}
+
+	return &domain.BackupMetadata{
+		BackupID:  backupID,
+		DBName:    dbName,
+		SizeBytes: info.Size(),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+// ListBackups returns the backups stored in backupDir, newest first.
+func ListBackups(backupDir, dbName string) ([]domain.BackupMetadata, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make([]domain.BackupMetadata, 0), nil
+		}
+		customLog.Warnf("Storage: Error reading backup directory '%s': %v", backupDir, err)
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := make([]domain.BackupMetadata, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // File vanished between ReadDir and Info
+		}
+		backups = append(backups, domain.BackupMetadata{
+			BackupID:  strings.TrimSuffix(entry.Name(), ".db"),
+			DBName:    dbName,
+			SizeBytes: info.Size(),
+			CreatedAt: info.ModTime().UTC(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// FindBackupPath resolves a backup ID to its file inside backupDir.
+func FindBackupPath(backupDir, backupID string) (string, error) {
+	// Backup IDs are UUIDs; rejecting anything else also rules out path traversal
+	if _, err := uuid.Parse(backupID); err != nil {
+		return "", ErrBackupNotFound
+	}
+
+	backupPath := filepath.Join(backupDir, backupID+".db")
+	if _, err := os.Stat(backupPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrBackupNotFound
+		}
+		return "", fmt.Errorf("failed to access backup: %w", err)
+	}
+	return backupPath, nil
+}
+
+// ValidateDatabaseFile checks that the file at path is a well-formed SQLite database.
+// With encryption enabled the file must be encrypted with the key of the tenant whose
+// directory it is in.
+func ValidateDatabaseFile(ctx context.Context, path string) error {
+	f, err := os.Open(path) // #nosec G304 -- path is generated by the storage layer
+	if err != nil {
+		return fmt.Errorf("failed to open database file: %w", err)
+	}
+	header := make([]byte, len(sqliteFileHeader))
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || (!encryptionEnabled() && !bytes.Equal(header, []byte(sqliteFileHeader))) {
+		return fmt.Errorf("%w: missing SQLite header", ErrInvalidDatabaseFile)
+	}
+
+	driverName, _ := userDBDriver()
+	db, err := sql.Open(driverName, "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDatabaseFile, err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA quick_check;").Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDatabaseFile, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", ErrInvalidDatabaseFile, result)
+	}
+	return nil
+}
+
+// RestoreDatabase validates the SQLite data read from src (a plain file or a backup
+// written by CreateBackup) and atomically swaps it in place of the database file at dbFilePath.
+func RestoreDatabase(ctx context.Context, src io.Reader, dbFilePath string) error {
+	content, err := openBackupReader(src)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	dir := filepath.Dir(dbFilePath)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to prepare database directory: %w", err)
+	}
+
+	// Stage the new file next to the target so the final rename stays on one filesystem
+	tmp, err := os.CreateTemp(dir, ".restore-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to stage restore file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once the rename succeeded
+
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write restore file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to flush restore file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close restore file: %w", err)
+	}
+
+	if err := ValidateDatabaseFile(ctx, tmpPath); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Rejected restore file for '%s': %v", dbFilePath, err)
+		return err
+	}
+
+	// Hold off other writers until the new file is in place; writers that acquired the
+	// old handle meanwhile reopen the file once it is their turn (see lockUserDBWrites)
+	unlock, err := userDBWriteLocks.lock(ctx, dbFilePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Fold any pending WAL frames into the current file first, otherwise SQLite would
+	// replay the old WAL on top of the restored pages.
+	if _, err := os.Stat(dbFilePath); err == nil {
+		current, err := ConnectUserDB(ctx, dbFilePath)
+		if err != nil {
+			return err
+		}
+		_, err = current.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);")
+		ReleaseUserDB(current)
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed WAL checkpoint before restore of '%s': %v", dbFilePath, err)
+			return fmt.Errorf("failed to checkpoint database before restore: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dbFilePath); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to swap restored file into '%s': %v", dbFilePath, err)
+		return fmt.Errorf("failed to replace database file: %w", err)
+	}
+	// Pooled handles still point at the replaced file
+	InvalidateUserDB(dbFilePath)
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbFilePath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to remove stale '%s%s' after restore: %v", dbFilePath, suffix, err)
+		}
+	}
+
+	customLog.Ctx(ctx).Printf("Storage: Restored database file '%s'", dbFilePath)
+	return nil
+}
+
+// CloneDatabase copies srcDB into a new database file at dstFilePath. With includeData
+// the SQLite online backup API copies every page; otherwise only the schema
+// (tables, indexes, triggers, views) is recreated.
+func CloneDatabase(ctx context.Context, srcDB *sql.DB, dstFilePath string, includeData bool) error {
+	dstDB, err := ConnectUserDB(ctx, dstFilePath)
+	if err != nil {
+		return err
+	}
+	defer ReleaseUserDB(dstDB)
+
+	if !includeData {
+		objects, err := listSchemaObjects(ctx, srcDB)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objects {
+			if obj.Name == "sqlite_sequence" {
+				continue // Created automatically alongside AUTOINCREMENT tables
+			}
+			if _, err := dstDB.ExecContext(ctx, obj.SQL); err != nil {
+				customLog.Ctx(ctx).Warnf("Storage: Failed to recreate %s '%s' in clone '%s': %v", obj.Type, obj.Name, dstFilePath, err)
+				return fmt.Errorf("failed to copy schema: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if err := copyDatabase(ctx, dstDB, srcDB); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Backup API copy into '%s' failed: %v", dstFilePath, err)
+		return fmt.Errorf("failed to copy database: %w", err)
+	}
+	return nil
+}
+
+// copyDatabase replaces the content of dstDB with every page of srcDB using the SQLite
+// online backup API. Both must be keyed alike when encryption is enabled.
+func copyDatabase(ctx context.Context, dstDB, srcDB *sql.DB) error {
+	srcConn, err := srcDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire source connection: %w", err)
+	}
+	defer srcConn.Close()
+	dstConn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire destination connection: %w", err)
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dstDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			dst, okDst := dstDriverConn.(*sqlite3.SQLiteConn)
+			src, okSrc := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !okDst || !okSrc {
+				return errors.New("unexpected driver connection type")
+			}
+
+			backup, err := dst.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+			// -1 copies all remaining pages in a single step
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// createTablePrefix matches the head of a CREATE TABLE statement up to the table name.
+var createTablePrefix = regexp.MustCompile(`(?i)^\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:"[^"]+"|\[[^\]]+\]|` + "`[^`]+`" + `|\w+)`)
+
+// CopyTable recreates tableName of the database file at srcFilePath in dstDB as
+// targetName, copying its rows with includeData, and returns the number of rows copied.
+// The source is ATTACHed to a connection of dstDB, which must belong to the same user
+// so both files share an encryption key; srcFilePath may be dstDB's own file. It
+// returns ErrTableNotFound if the source table does not exist and ErrTableExists if
+// the target does. Copied rows are reported as created records (see OnRecordChange).
+// Names should be pre-validated by the caller.
+func CopyTable(ctx context.Context, srcFilePath, tableName string, dstDB *sql.DB, targetName string, includeData bool) (int64, error) {
+	copied, ids, err := copyTable(ctx, srcFilePath, tableName, dstDB, targetName, includeData)
+	if err != nil {
+		return 0, err
+	}
+	notifyRecordChanges(ctx, userDBs.pathOf(dstDB), RecordCreated, targetName, ids...)
+	return copied, nil
+}
+
+// copyTable runs CopyTable under the write lock of dstDB. It also returns the row IDs
+// of the copy when record changes are listened to, to report them once unlocked.
+func copyTable(ctx context.Context, srcFilePath, tableName string, dstDB *sql.DB, targetName string, includeData bool) (int64, []int64, error) {
+	dstDB, unlock, err := lockUserDBWrites(ctx, dstDB)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer unlock()
+	defer invalidateReads(dstDB)
+
+	conn, err := dstDB.Conn(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	source := "main"
+	if srcFilePath != userDBs.pathOf(dstDB) {
+		source = "copy_source"
+		if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS copy_source;", srcFilePath); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to attach '%s' for table copy: %v", srcFilePath, err)
+			return 0, nil, fmt.Errorf("failed to open source database: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "DETACH DATABASE copy_source;"); err != nil {
+				customLog.Ctx(ctx).Warnf("Storage: Failed to detach '%s' after table copy: %v", srcFilePath, err)
+			}
+		}()
+	}
+
+	var createSQL string
+	err = conn.QueryRowContext(ctx, fmt.Sprintf("SELECT sql FROM %s.sqlite_master WHERE type = 'table' AND name = ?;", source), tableName).Scan(&createSQL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, ErrTableNotFound
+	} else if err != nil {
+		return 0, nil, fmt.Errorf("database error reading table schema: %w", err)
+	}
+	var exists int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM main.sqlite_master WHERE name = ?;", targetName).Scan(&exists); err != nil {
+		return 0, nil, fmt.Errorf("database error checking target table: %w", err)
+	} else if exists > 0 {
+		return 0, nil, ErrTableExists
+	}
+	if !createTablePrefix.MatchString(createSQL) {
+		return 0, nil, fmt.Errorf("unsupported table definition of '%s'", tableName)
+	}
+	createSQL = createTablePrefix.ReplaceAllLiteralString(createSQL, "CREATE TABLE main."+targetName)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin table copy: %w", err)
+	}
+	defer tx.Rollback() // No-op after commit
+	if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create copy of Table '%s': %v\nSQL: %s", tableName, err, createSQL)
+		return 0, nil, fmt.Errorf("failed to create table: %w", err)
+	}
+	var copied int64
+	if includeData {
+		// nolint:gosec // Names are validated identifiers
+		result, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO main.%s SELECT * FROM %s.%s;", targetName, source, tableName))
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to copy rows of Table '%s': %v", tableName, err)
+			return 0, nil, fmt.Errorf("failed to copy rows: %w", err)
+		}
+		if copied, err = result.RowsAffected(); err != nil {
+			return 0, nil, fmt.Errorf("failed confirming row copy: %w", err)
+		}
+	}
+	var ids []int64
+	if includeData && copied > 0 && recordChangeListener() != nil {
+		if ids, err = copiedRowIDs(ctx, tx, targetName); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit table copy: %w", err)
+	}
+	invalidateTableSchema(dstDB, targetName)
+	return copied, ids, nil
+}
+
+// copiedRowIDs returns the row IDs of the table copy targetName, in order.
+func copiedRowIDs(ctx context.Context, tx *sql.Tx, targetName string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT rowid FROM main.%s ORDER BY rowid;", targetName))
+	if err != nil {
+		return nil, fmt.Errorf("failed reading copied rows: %w", err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed reading copied rows: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading copied rows: %w", err)
+	}
+	return ids, nil
+}
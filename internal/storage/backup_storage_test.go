@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBackupAndRestoreDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	if _, err := userDB.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT); INSERT INTO notes (body) VALUES ('first');"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	backupDir := filepath.Join(dir, "backups")
+	backup, err := CreateBackup(ctx, userDB, backupDir, "app")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+
+	// Change the live database after the snapshot was taken
+	if _, err := userDB.ExecContext(ctx, "INSERT INTO notes (body) VALUES ('second');"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	ReleaseUserDB(userDB)
+
+	backups, err := ListBackups(backupDir, "app")
+	if err != nil || len(backups) != 1 || backups[0].BackupID != backup.BackupID {
+		t.Fatalf("ListBackups = %v, %v; want the created backup", backups, err)
+	}
+
+	backupPath, err := FindBackupPath(backupDir, backup.BackupID)
+	if err != nil {
+		t.Fatalf("FindBackupPath: %v", err)
+	}
+	f, err := os.Open(backupPath)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer f.Close()
+
+	if err := RestoreDatabase(ctx, f, dbPath); err != nil {
+		t.Fatalf("RestoreDatabase: %v", err)
+	}
+
+	restored, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer ReleaseUserDB(restored)
+
+	var count int
+	if err := restored.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("restored row count = %d; want 1", count)
+	}
+}
+
+func TestWriteAfterRestoreUsesRestoredFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(userDB)
+	if _, err := userDB.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT); INSERT INTO notes (body) VALUES ('first');"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	backup, err := CreateBackup(ctx, userDB, filepath.Join(dir, "backups"), "app")
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	backupPath, err := FindBackupPath(filepath.Join(dir, "backups"), backup.BackupID)
+	if err != nil {
+		t.Fatalf("FindBackupPath: %v", err)
+	}
+	f, err := os.Open(backupPath)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer f.Close()
+
+	// userDB was acquired before the restore, like a request queued behind it
+	if err := RestoreDatabase(ctx, f, dbPath); err != nil {
+		t.Fatalf("RestoreDatabase: %v", err)
+	}
+	if _, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "after restore"); err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	restored, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer ReleaseUserDB(restored)
+	var count int
+	if err := restored.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes WHERE body = 'after restore'").Scan(&count); err != nil || count != 1 {
+		t.Errorf("rows written after restore = %d, %v; want 1", count, err)
+	}
+}
+
+func TestRestoreDatabaseRejectsInvalidFile(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+
+	err := RestoreDatabase(context.Background(), strings.NewReader("definitely not sqlite"), dbPath)
+	if err == nil {
+		t.Fatal("RestoreDatabase accepted a non-SQLite file")
+	}
+	if _, statErr := os.Stat(dbPath); !os.IsNotExist(statErr) {
+		t.Errorf("target file should not exist after a rejected restore")
+	}
+}
+
+func TestFindBackupPathRejectsTraversal(t *testing.T) {
+	if _, err := FindBackupPath(t.TempDir(), "../../metadata"); err != ErrBackupNotFound {
+		t.Errorf("FindBackupPath traversal err = %v; want ErrBackupNotFound", err)
+	}
+}
+
+func TestCloneDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	src, err := ConnectUserDB(ctx, filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(src)
+	if _, err := src.ExecContext(ctx, "CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT); INSERT INTO notes (body) VALUES ('a'), ('b');"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	for _, includeData := range []bool{true, false} {
+		dstPath := filepath.Join(dir, "clone.db")
+		InvalidateUserDB(dstPath)
+		_ = os.Remove(dstPath)
+		if err := CloneDatabase(ctx, src, dstPath, includeData); err != nil {
+			t.Fatalf("CloneDatabase(includeData=%v): %v", includeData, err)
+		}
+
+		dst, err := ConnectUserDB(ctx, dstPath)
+		if err != nil {
+			t.Fatalf("open clone: %v", err)
+		}
+		var count int
+		err = dst.QueryRowContext(ctx, "SELECT COUNT(*) FROM notes").Scan(&count)
+		ReleaseUserDB(dst)
+
+		want := 0
+		if includeData {
+			want = 2
+		}
+		if err != nil || count != want {
+			t.Errorf("clone(includeData=%v) row count = %d, %v; want %d", includeData, count, err, want)
+		}
+	}
+}
+
+func TestCopyTable(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.db")
+
+	src, err := ConnectUserDB(ctx, srcPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(src)
+	if _, err := src.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT DEFAULT 'x'); INSERT INTO notes (body) VALUES ('a'), ('b');"); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	dst, err := ConnectUserDB(ctx, filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(dst)
+
+	var changes []RecordChange
+	OnRecordChange(func(_ context.Context, change RecordChange) { changes = append(changes, change) })
+	t.Cleanup(func() { OnRecordChange(nil) })
+
+	if copied, err := CopyTable(ctx, srcPath, "notes", dst, "notes", true); err != nil || copied != 2 {
+		t.Fatalf("CopyTable = %d, %v; want 2 rows", copied, err)
+	}
+	dstPath := filepath.Join(dir, "dst.db")
+	if want := []RecordChange{{dstPath, RecordCreated, "notes", 1}, {dstPath, RecordCreated, "notes", 2}}; !slices.Equal(changes, want) {
+		t.Errorf("copy reported %+v, want %+v", changes, want)
+	}
+	if _, err := CopyTable(ctx, srcPath, "notes", dst, "notes", true); !errors.Is(err, ErrTableExists) {
+		t.Fatalf("CopyTable onto existing table = %v, want ErrTableExists", err)
+	}
+	if _, err := CopyTable(ctx, srcPath, "missing", dst, "missing", true); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("CopyTable of missing table = %v, want ErrTableNotFound", err)
+	}
+	// Within one database, under another name and without rows
+	if copied, err := CopyTable(ctx, srcPath, "notes", src, "notes_template", false); err != nil || copied != 0 {
+		t.Fatalf("CopyTable within database = %d, %v", copied, err)
+	}
+
+	var body string
+	if err := dst.QueryRowContext(ctx, "SELECT body FROM notes WHERE id = 2").Scan(&body); err != nil || body != "b" {
+		t.Errorf("copied row = %q, %v", body, err)
+	}
+	if _, err := src.ExecContext(ctx, "INSERT INTO notes_template DEFAULT VALUES"); err != nil {
+		t.Errorf("insert into copied schema: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// internal/storage/column_rules_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ListColumnRules returns the validation rules of a user table, ordered by column.
+func (s *sqlMetadataStore) ListColumnRules(ctx context.Context, databaseId int64, tableName string) ([]domain.ColumnRule, error) {
+	rows, err := s.query(ctx, `SELECT column_name, pattern, format, min_value, max_value FROM column_rules
+		WHERE database_id = ? AND table_name = ? ORDER BY column_name`, databaseId, tableName)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list column rules for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return nil, fmt.Errorf("database error listing column rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []domain.ColumnRule{}
+	for rows.Next() {
+		var rule domain.ColumnRule
+		var minValue, maxValue sql.NullFloat64
+		if err := rows.Scan(&rule.Column, &rule.Pattern, &rule.Format, &minValue, &maxValue); err != nil {
+			return nil, fmt.Errorf("database error reading column rules: %w", err)
+		}
+		if minValue.Valid {
+			rule.Min = &minValue.Float64
+		}
+		if maxValue.Valid {
+			rule.Max = &maxValue.Float64
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// ReplaceColumnRules replaces all validation rules of a user table with rules (none
+// removes them). Rules are expected to be validated by core.ValidateColumnRules.
+func (s *sqlMetadataStore) ReplaceColumnRules(ctx context.Context, databaseId int64, tableName string, rules []domain.ColumnRule) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error replacing column rules: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM column_rules WHERE database_id = ? AND table_name = ?`), databaseId, tableName); err != nil {
+		return fmt.Errorf("database error replacing column rules: %w", err)
+	}
+	insertSQL := s.dialect.rebind(`INSERT INTO column_rules (database_id, table_name, column_name, pattern, format, min_value, max_value)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	for _, rule := range rules {
+		if _, err := tx.ExecContext(ctx, insertSQL, databaseId, tableName, rule.Column, rule.Pattern, rule.Format, rule.Min, rule.Max); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to store rule for column '%s' of DBID %d, Table '%s': %v", rule.Column, databaseId, tableName, err)
+			return fmt.Errorf("database error storing column rule for '%s': %w", rule.Column, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error replacing column rules: %w", err)
+	}
+	return nil
+}
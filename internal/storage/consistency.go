@@ -0,0 +1,300 @@
+// internal/storage/consistency.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrDatabaseQuarantined is returned for a database an admin quarantined, e.g. because
+// the consistency check found its file missing or damaged.
+var ErrDatabaseQuarantined = errors.New("database is quarantined")
+
+// Problems CheckConsistency finds with a registered database.
+const (
+	ProblemMissingFile    = "missing_file"
+	ProblemNotRegularFile = "not_regular_file"
+	ProblemPermissions    = "permissions"
+	ProblemEmptyFile      = "empty_file"
+	ProblemTruncatedFile  = "truncated_file"
+	ProblemNotSQLite      = "not_sqlite"
+	ProblemWrongDataRoot  = "wrong_data_root"
+)
+
+// What CheckConsistency did about an issue.
+const (
+	RepairRegistrationUpdated = "registration_updated"
+	RepairPermissionsFixed    = "permissions_fixed"
+	RepairQuarantined         = "quarantined"
+)
+
+// sqliteHeaderSize is the size of the header at the start of every SQLite file.
+const sqliteHeaderSize = 100
+
+// ConsistencyIssue is a mismatch between a database's registration and its file.
+type ConsistencyIssue struct {
+	DatabaseID int64  `json:"database_id"`
+	UserID     string `json:"user_id"`
+	DBName     string `json:"db_name"`
+	FilePath   string `json:"file_path"`
+	Problem    string `json:"problem"`
+	Detail     string `json:"detail"`
+	Broken     bool   `json:"broken"`           // Requests to the database would fail or see wrong data
+	Repair     string `json:"repair,omitempty"` // What was done about it
+	Error      string `json:"error,omitempty"`  // Why repairing or quarantining failed
+
+	foundAt string // Where a missing file was found instead
+}
+
+// ConsistencyOptions chooses what CheckConsistency does about the issues it finds.
+type ConsistencyOptions struct {
+	// Repair points registrations at files found on another data root or at the data
+	// root actually holding them, and takes away access to files by other OS users.
+	Repair bool
+	// Quarantine refuses requests to databases that are still broken, until an admin
+	// releases them.
+	Quarantine bool
+}
+
+// ConsistencyReport is the result of CheckConsistency.
+type ConsistencyReport struct {
+	Checked     int                `json:"checked"`
+	Issues      []ConsistencyIssue `json:"issues"`
+	Repaired    int                `json:"repaired"`
+	Quarantined int                `json:"quarantined"`
+}
+
+// CheckConsistency verifies the file of every registered database: that it exists on
+// the data root it is registered on, can be read and written by the server only, and
+// is a SQLite file of plausible size. Issues are repaired and broken databases
+// quarantined as opts says.
+func CheckConsistency(ctx context.Context, store MetadataStore, opts ConsistencyOptions) (*ConsistencyReport, error) {
+	if userData.backend == BackendPostgres {
+		return nil, ErrUserDataUnsupported
+	}
+	databases, err := store.ListAllDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConsistencyReport{Issues: []ConsistencyIssue{}}
+	for _, db := range databases {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		report.Checked++
+		issues := checkDatabaseFile(db)
+		broken := false
+		for i := range issues {
+			issue := &issues[i]
+			if opts.Repair {
+				repairIssue(ctx, store, db, issue)
+				if issue.Repair != "" {
+					report.Repaired++
+				}
+			}
+			broken = broken || (issue.Broken && issue.Repair == "")
+		}
+		if broken && opts.Quarantine && db.Quarantine == "" {
+			if quarantineDatabase(ctx, store, db, issues) {
+				report.Quarantined++
+			}
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report, nil
+}
+
+// checkDatabaseFile returns the issues with the file of one database.
+func checkDatabaseFile(db domain.DatabaseMetadata) []ConsistencyIssue {
+	var issues []ConsistencyIssue
+	add := func(problem string, broken bool, format string, args ...any) *ConsistencyIssue {
+		issues = append(issues, ConsistencyIssue{
+			DatabaseID: db.DatabaseID, UserID: db.UserID, DBName: db.DBName, FilePath: db.FilePath,
+			Problem: problem, Broken: broken, Detail: fmt.Sprintf(format, args...),
+		})
+		return &issues[len(issues)-1]
+	}
+
+	info, err := os.Stat(db.FilePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if found := findOnDataRoots(db); found != "" {
+			add(ProblemMissingFile, true, "file is at '%s' instead", found).foundAt = found
+		} else if _, err := os.Stat(filepath.Dir(db.FilePath)); err != nil {
+			add(ProblemMissingFile, true, "neither the file nor its directory exists")
+		} else {
+			// The file appears on first use, so this may be a database never written to
+			add(ProblemMissingFile, false, "no file; the database may not have been used yet")
+		}
+		return issues
+	case err != nil:
+		add(ProblemPermissions, true, "cannot read the file's attributes: %v", err)
+		return issues
+	case !info.Mode().IsRegular():
+		add(ProblemNotRegularFile, true, "file is a %s", info.Mode().Type())
+		return issues
+	}
+
+	file, err := os.OpenFile(db.FilePath, os.O_RDWR, 0) // #nosec G304 -- path is a registered database file
+	if err != nil {
+		add(ProblemPermissions, true, "cannot open the file for reading and writing: %v", err)
+	} else {
+		header := make([]byte, sqliteHeaderSize)
+		n, err := io.ReadFull(file, header)
+		file.Close()
+		checkDatabaseSize(add, info.Size(), header[:n], err)
+	}
+	if perm := info.Mode().Perm(); perm&0o007 != 0 {
+		add(ProblemPermissions, false, "file is accessible to all users (%s)", perm)
+	}
+
+	if db.DataRoot != "" {
+		if root := DataRootOf(db.FilePath); root == "" {
+			add(ProblemWrongDataRoot, false, "file is on no configured data root (registered on '%s')", db.DataRoot)
+		} else if root != db.DataRoot {
+			add(ProblemWrongDataRoot, false, "file is on data root '%s', registered on '%s'", root, db.DataRoot)
+		}
+	}
+	return issues
+}
+
+// checkDatabaseSize adds issues for a file whose size or header shows it is not a whole
+// SQLite database. Encrypted files only reveal their size.
+func checkDatabaseSize(add func(string, bool, string, ...any) *ConsistencyIssue, size int64, header []byte, readErr error) {
+	switch {
+	case size == 0:
+		add(ProblemEmptyFile, false, "file is empty")
+		return
+	case readErr != nil && !errors.Is(readErr, io.ErrUnexpectedEOF):
+		add(ProblemPermissions, true, "cannot read the file: %v", readErr)
+		return
+	case len(header) < sqliteHeaderSize:
+		add(ProblemTruncatedFile, true, "file has only %d bytes, less than a SQLite header", size)
+		return
+	}
+
+	if !bytes.HasPrefix(header, []byte(sqliteFileHeader)) {
+		if !encryptionEnabled() {
+			add(ProblemNotSQLite, true, "file does not start with the SQLite header")
+		} else if size%512 != 0 {
+			add(ProblemTruncatedFile, true, "size %d is not a whole number of pages", size)
+		}
+		return
+	}
+
+	pageSize := int64(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		add(ProblemNotSQLite, true, "header has an invalid page size %d", pageSize)
+		return
+	}
+	if size%pageSize != 0 {
+		add(ProblemTruncatedFile, true, "size %d is not a whole number of %d-byte pages", size, pageSize)
+		return
+	}
+	// The page count in the header is only kept up to date when the change counter is
+	// copied into version-valid-for
+	pages := int64(binary.BigEndian.Uint32(header[28:32]))
+	if bytes.Equal(header[24:28], header[92:96]) && pages*pageSize > size {
+		add(ProblemTruncatedFile, true, "file has %d of its %d pages", size/pageSize, pages)
+	}
+}
+
+// findOnDataRoots returns where the missing file of a database is on another data root,
+// e.g. after it was moved by hand, or "".
+func findOnDataRoots(db domain.DatabaseMetadata) string {
+	for _, root := range DataRoots() {
+		candidate := UserDataLocation(root, db.UserID, db.DBName)
+		if candidate == filepath.Clean(db.FilePath) {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// repairIssue fixes one issue if it can, recording what it did in the issue.
+func repairIssue(ctx context.Context, store MetadataStore, db domain.DatabaseMetadata, issue *ConsistencyIssue) {
+	var err error
+	switch {
+	case issue.Problem == ProblemMissingFile && issue.foundAt != "":
+		if err = registrationUnchanged(ctx, store, db); err == nil {
+			err = store.SetDatabaseLocation(ctx, db.DatabaseID, issue.foundAt, DataRootOf(issue.foundAt), db.Placement)
+		}
+		if err == nil {
+			InvalidateUserDB(db.FilePath)
+			issue.Repair = RepairRegistrationUpdated
+		}
+	case issue.Problem == ProblemWrongDataRoot && DataRootOf(db.FilePath) != "":
+		if err = store.SetDatabaseLocation(ctx, db.DatabaseID, db.FilePath, DataRootOf(db.FilePath), db.Placement); err == nil {
+			issue.Repair = RepairRegistrationUpdated
+		}
+	case issue.Problem == ProblemPermissions:
+		var info os.FileInfo
+		if info, err = os.Stat(db.FilePath); err == nil {
+			err = os.Chmod(db.FilePath, info.Mode().Perm()&^0o007|0o600)
+		}
+		if err == nil {
+			issue.Repair = RepairPermissionsFixed
+		}
+	default:
+		return
+	}
+	if err != nil {
+		issue.Error = err.Error()
+		customLog.Ctx(ctx).Warnf("Storage: Failed to repair %s of DBID %d: %v", issue.Problem, db.DatabaseID, err)
+		return
+	}
+	customLog.Ctx(ctx).Printf("Storage: Repaired %s of DBID %d (%s)", issue.Problem, db.DatabaseID, issue.Repair)
+}
+
+// quarantineDatabase quarantines a broken database with the first unrepaired broken
+// issue as the reason, and reports whether it did.
+func quarantineDatabase(ctx context.Context, store MetadataStore, db domain.DatabaseMetadata, issues []ConsistencyIssue) bool {
+	var issue *ConsistencyIssue
+	for i := range issues {
+		if issues[i].Broken && issues[i].Repair == "" {
+			issue = &issues[i]
+			break
+		}
+	}
+	err := registrationUnchanged(ctx, store, db)
+	if err == nil {
+		err = store.SetDatabaseQuarantine(ctx, db.DatabaseID, issue.Problem+": "+issue.Detail)
+	}
+	if err != nil {
+		issue.Error = err.Error()
+		customLog.Ctx(ctx).Warnf("Storage: Failed to quarantine DBID %d: %v", db.DatabaseID, err)
+		return false
+	}
+	InvalidateUserDB(db.FilePath)
+	issue.Repair = RepairQuarantined
+	customLog.Ctx(ctx).Warnf("Storage: Quarantined DB '%s' (DBID %d) of UserID %s: %s: %s", db.DBName, db.DatabaseID, db.UserID, issue.Problem, issue.Detail)
+	return true
+}
+
+// registrationUnchanged returns an error if the database was moved or deleted since it
+// was checked, when its issues may no longer apply.
+func registrationUnchanged(ctx context.Context, store MetadataStore, db domain.DatabaseMetadata) error {
+	current, err := store.FindDatabaseByID(ctx, db.DatabaseID)
+	if err != nil {
+		return err
+	}
+	if current.FilePath != db.FilePath {
+		return errors.New("database was moved during the check")
+	}
+	return nil
+}
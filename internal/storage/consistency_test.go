@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	rootA, rootB := t.TempDir(), t.TempDir()
+	if err := ConfigureDataRoots([]string{rootA, rootB}, PlacementMostFree); err != nil {
+		t.Fatalf("ConfigureDataRoots: %v", err)
+	}
+	defer func() { dataRoots.roots = nil }()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	register := func(name, root string) string {
+		t.Helper()
+		location := UserDataLocation(root, "u1", name)
+		if err := PrepareUserData(ctx, location); err != nil {
+			t.Fatalf("PrepareUserData: %v", err)
+		}
+		if err := store.RegisterDatabase(ctx, "u1", name, location); err != nil {
+			t.Fatalf("RegisterDatabase: %v", err)
+		}
+		return location
+	}
+
+	// healthy is a real database; moved was moved to rootB by hand; broken is cut short
+	healthy := register("healthy", rootA)
+	userDB, err := ConnectUserDB(ctx, healthy)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	err = CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)")
+	ReleaseUserDB(userDB)
+	InvalidateUserDB(healthy)
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if err := os.Chmod(healthy, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(healthy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register("moved", rootA)
+	movedTo := UserDataLocation(rootB, "u1", "moved")
+	if err := os.MkdirAll(filepath.Dir(movedTo), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(movedTo, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(register("broken", rootA), data[:len(data)-100], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	register("unused", rootA)
+
+	report, err := CheckConsistency(ctx, store, ConsistencyOptions{})
+	if err != nil {
+		t.Fatalf("CheckConsistency: %v", err)
+	}
+	problems := make(map[string]ConsistencyIssue)
+	for _, issue := range report.Issues {
+		problems[issue.DBName] = issue
+	}
+	if report.Checked != 4 || len(report.Issues) != 4 {
+		t.Fatalf("check = %+v; want 4 databases with one issue each", report)
+	}
+	for name, want := range map[string]struct {
+		problem string
+		broken  bool
+	}{
+		"healthy": {ProblemPermissions, false},
+		"moved":   {ProblemMissingFile, true},
+		"broken":  {ProblemTruncatedFile, true},
+		"unused":  {ProblemMissingFile, false},
+	} {
+		if got := problems[name]; got.Problem != want.problem || got.Broken != want.broken || got.Repair != "" {
+			t.Errorf("%s: issue = %+v; want %s (broken %t), not repaired", name, got, want.problem, want.broken)
+		}
+	}
+
+	report, err = CheckConsistency(ctx, store, ConsistencyOptions{Repair: true, Quarantine: true})
+	if err != nil {
+		t.Fatalf("CheckConsistency with repairs: %v", err)
+	}
+	if report.Repaired != 2 || report.Quarantined != 1 {
+		t.Errorf("repairs = %+v; want 2 repaired, 1 quarantined", report)
+	}
+	if info, err := os.Stat(healthy); err != nil || info.Mode().Perm() != 0o660 {
+		t.Errorf("permissions after repair = %v, %v; want 0660", info.Mode().Perm(), err)
+	}
+	if location, err := store.FindDatabasePath(ctx, "u1", "moved"); err != nil || location != movedTo {
+		t.Errorf("moved database = %q, %v; want %q", location, err, movedTo)
+	}
+	if _, err := store.FindDatabasePath(ctx, "u1", "broken"); !errors.Is(err, ErrDatabaseQuarantined) {
+		t.Errorf("broken database lookup = %v; want ErrDatabaseQuarantined", err)
+	}
+	if _, err := store.FindDatabasePath(ctx, "u1", "unused"); err != nil {
+		t.Errorf("unused database lookup = %v; want it left alone", err)
+	}
+}
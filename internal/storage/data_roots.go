@@ -0,0 +1,175 @@
+// internal/storage/data_roots.go
+package storage
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Placements recorded with a database's data root.
+const (
+	PlacementMostFree  = "most_free" // Root with the most free space when it was created
+	PlacementHash      = "hash"      // Root chosen by a hash of the account, with its other databases
+	PlacementRebalance = "rebalance" // Moved by a rebalance
+	PlacementRelocate  = "relocate"  // Moved by an admin with RelocateUserDatabases
+)
+
+// dataRootSet holds the directories user DB files are spread over and the placement
+// strategy for new databases.
+type dataRootSet struct {
+	mu       sync.RWMutex
+	roots    []string
+	strategy string
+}
+
+var dataRoots = &dataRootSet{strategy: PlacementMostFree}
+
+// ConfigureDataRoots sets the directories new user DB files are placed in and how one
+// is chosen (PlacementMostFree or PlacementHash), creating missing directories. Call
+// once at startup. Existing databases stay where their registration says.
+func ConfigureDataRoots(roots []string, strategy string) error {
+	if len(roots) == 0 {
+		return fmt.Errorf("no data roots configured")
+	}
+	if strategy != PlacementMostFree && strategy != PlacementHash {
+		return fmt.Errorf("unknown placement strategy '%s'", strategy)
+	}
+	for _, root := range roots {
+		if err := os.MkdirAll(root, 0o750); err != nil {
+			return fmt.Errorf("failed to create data root '%s': %w", root, err)
+		}
+	}
+	dataRoots.mu.Lock()
+	defer dataRoots.mu.Unlock()
+	dataRoots.roots = append([]string(nil), roots...)
+	dataRoots.strategy = strategy
+	return nil
+}
+
+// DataRoots returns the configured data roots.
+func DataRoots() []string {
+	dataRoots.mu.RLock()
+	defer dataRoots.mu.RUnlock()
+	return append([]string(nil), dataRoots.roots...)
+}
+
+// PlaceUserData picks the data root for a new database of userId and returns it with
+// the placement that chose it. defaultRoot is used, with no placement, when no roots are
+// configured or databases are not files.
+func PlaceUserData(defaultRoot, userId string) (root, placement string) {
+	dataRoots.mu.RLock()
+	roots, strategy := dataRoots.roots, dataRoots.strategy
+	dataRoots.mu.RUnlock()
+	switch {
+	case len(roots) == 0 || userData.backend == BackendPostgres:
+		return defaultRoot, ""
+	case len(roots) == 1:
+		return roots[0], strategy
+	case strategy == PlacementHash:
+		h := fnv.New32a()
+		h.Write([]byte(userId))
+		return roots[h.Sum32()%uint32(len(roots))], strategy
+	}
+
+	best, bestFree := roots[0], uint64(0)
+	for _, root := range roots {
+		_, free, err := diskSpace(root)
+		if err != nil {
+			customLog.Warnf("Storage: Cannot read free space of data root '%s': %v", root, err)
+			continue
+		}
+		if free > bestFree {
+			best, bestFree = root, free
+		}
+	}
+	return best, strategy
+}
+
+// IsDataRoot reports whether dir is one of the configured data roots.
+func IsDataRoot(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, root := range DataRoots() {
+		if root == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// DataRootOf returns the configured data root holding filePath, or "" if none does.
+func DataRootOf(filePath string) string {
+	var match string
+	for _, root := range DataRoots() {
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > len(match) { // Nested roots: the innermost holds the file
+			match = root
+		}
+	}
+	return match
+}
+
+// DataRootUsage describes the disk of a data root and the databases placed on it.
+type DataRootUsage struct {
+	Path          string `json:"path"`
+	TotalBytes    uint64 `json:"total_bytes"`
+	FreeBytes     uint64 `json:"free_bytes"`
+	Databases     int    `json:"databases"`
+	DatabaseBytes int64  `json:"database_bytes"` // Size of the database files, with their -wal files
+	Error         string `json:"error,omitempty"`
+}
+
+// DataRootUsages returns the usage of every configured data root by databases.
+func DataRootUsages(databases []domain.DatabaseMetadata) []DataRootUsage {
+	roots := DataRoots()
+	usages := make([]DataRootUsage, len(roots))
+	index := make(map[string]int, len(roots))
+	for i, root := range roots {
+		usages[i].Path = root
+		index[root] = i
+		total, free, err := diskSpace(root)
+		if err != nil {
+			usages[i].Error = err.Error()
+			continue
+		}
+		usages[i].TotalBytes, usages[i].FreeBytes = total, free
+	}
+	for _, db := range databases {
+		i, ok := index[databaseRoot(db)]
+		if !ok {
+			continue
+		}
+		usages[i].Databases++
+		usages[i].DatabaseBytes += userDBFileSize(db.FilePath)
+	}
+	return usages
+}
+
+// databaseRoot returns the data root of a registered database. Databases registered
+// before data roots existed have none recorded.
+func databaseRoot(db domain.DatabaseMetadata) string {
+	if db.DataRoot != "" {
+		return db.DataRoot
+	}
+	return DataRootOf(db.FilePath)
+}
+
+// userDBFileSize returns the size of a user DB file with its -wal file; 0 if it does not
+// exist.
+func userDBFileSize(path string) int64 {
+	var size int64
+	for _, suffix := range []string{"", "-wal"} {
+		if info, err := os.Stat(path + suffix); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
@@ -0,0 +1,54 @@
+// internal/storage/database_expiry.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// RunDatabaseExpirer periodically deletes ephemeral databases whose expiry passed,
+// until ctx is done. They are deleted for good rather than moved to the trash: they
+// were created to be thrown away.
+func RunDatabaseExpirer(ctx context.Context, store MetadataStore, interval time.Duration) {
+	health.RegisterWorker("database_expirer", interval)
+	defer health.UnregisterWorker("database_expirer")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleteExpiredDatabases(ctx, store, time.Now())
+			health.Beat("database_expirer")
+		}
+	}
+}
+
+// deleteExpiredDatabases runs one pass of the database expirer and returns the number
+// of databases deleted.
+func deleteExpiredDatabases(ctx context.Context, store MetadataStore, now time.Time) int {
+	databases, err := store.ListExpiredDatabases(ctx, now)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Database expiry skipped: %v", err)
+		return 0
+	}
+	deleted := 0
+	for _, db := range databases {
+		// The registration goes first, so that no request opens the storage again
+		if err := store.DeleteDatabaseRegistration(ctx, db.UserID, db.DBName); err != nil && !errors.Is(err, ErrDatabaseNotFound) {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to delete expired DB '%s' of UserID %s: %v", db.DBName, db.UserID, err)
+			continue
+		}
+		if err := DeleteUserData(ctx, db.FilePath); err != nil {
+			// The orphan file collector picks the file up later
+			customLog.Ctx(ctx).Warnf("Storage: Failed to delete storage '%s' of expired DB '%s': %v", db.FilePath, db.DBName, err)
+		}
+		customLog.Ctx(ctx).Printf("Storage: Deleted ephemeral DB '%s' of UserID %s (expired %s)", db.DBName, db.UserID, db.ExpiresAt.Format(time.RFC3339))
+		deleted++
+	}
+	return deleted
+}
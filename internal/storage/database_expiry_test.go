@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestDeleteExpiredDatabases(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: dir, MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	now := time.Now()
+	for name, expiresAt := range map[string]time.Time{"preview": now.Add(time.Hour), "kept": {}} {
+		location := UserDataLocation(dir, "u1", name)
+		if err := PrepareUserData(ctx, location); err != nil {
+			t.Fatalf("PrepareUserData: %v", err)
+		}
+		if err := os.WriteFile(location, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RegisterDatabase(ctx, "u1", name, location); err != nil {
+			t.Fatalf("RegisterDatabase: %v", err)
+		}
+		id, err := store.FindDatabaseIDByNameAndUser(ctx, "u1", name)
+		if err != nil {
+			t.Fatalf("FindDatabaseIDByNameAndUser: %v", err)
+		}
+		if err := store.SetDatabaseExpiry(ctx, id, expiresAt); err != nil {
+			t.Fatalf("SetDatabaseExpiry: %v", err)
+		}
+		db, err := store.FindDatabaseByID(ctx, id)
+		if err != nil || (db.ExpiresAt == nil) != expiresAt.IsZero() {
+			t.Fatalf("FindDatabaseByID(%s) = %+v, %v; want expiry %v", name, db, err, expiresAt)
+		}
+	}
+
+	if n := deleteExpiredDatabases(ctx, store, now); n != 0 {
+		t.Errorf("deleted %d databases before the expiry; want 0", n)
+	}
+	if n := deleteExpiredDatabases(ctx, store, now.Add(2*time.Hour)); n != 1 {
+		t.Errorf("deleted %d databases after the expiry; want 1", n)
+	}
+	if _, err := store.FindDatabasePath(ctx, "u1", "preview"); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("expired database still registered: %v", err)
+	}
+	if _, err := os.Stat(UserDataLocation(dir, "u1", "preview")); !os.IsNotExist(err) {
+		t.Errorf("expired database file still exists: %v", err)
+	}
+	if _, err := store.FindDatabasePath(ctx, "u1", "kept"); err != nil {
+		t.Errorf("database without expiry was deleted: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// internal/storage/database_label_storage.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+// DatabaseListOptions narrows and pages the databases returned by ListUserDatabases.
+type DatabaseListOptions struct {
+	Limit  int // 0 returns every database
+	Offset int
+	Search string               // Case-insensitive substring of the database name
+	Labels []core.LabelSelector // Databases must match every selector
+}
+
+// GetDatabaseLabels returns the labels of a user database, empty if none were set.
+func (s *sqlMetadataStore) GetDatabaseLabels(ctx context.Context, databaseId int64) (map[string]string, error) {
+	rows, err := s.query(ctx, `SELECT label_key, label_value FROM database_labels WHERE database_id = ?`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get labels for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error getting database labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("database error reading database labels: %w", err)
+		}
+		labels[key] = value
+	}
+	return labels, rows.Err()
+}
+
+// ReplaceDatabaseLabels replaces the labels of a user database; an empty map removes
+// them all. Labels are expected to be validated by core.ValidateLabels.
+func (s *sqlMetadataStore) ReplaceDatabaseLabels(ctx context.Context, databaseId int64, labels map[string]string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error replacing database labels: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM database_labels WHERE database_id = ?`), databaseId); err != nil {
+		return fmt.Errorf("database error replacing database labels: %w", err)
+	}
+	insertSQL := s.dialect.rebind(`INSERT INTO database_labels (database_id, label_key, label_value) VALUES (?, ?, ?)`)
+	for key, value := range labels {
+		if _, err := tx.ExecContext(ctx, insertSQL, databaseId, key, value); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to store label '%s' of DBID %d: %v", key, databaseId, err)
+			return fmt.Errorf("database error storing database label: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error replacing database labels: %w", err)
+	}
+	return nil
+}
+
+// listUserDatabaseLabels returns the labels of every database of a user, keyed by
+// database ID.
+func (s *sqlMetadataStore) listUserDatabaseLabels(ctx context.Context, userId string) (map[int64]map[string]string, error) {
+	rows, err := s.query(ctx, `SELECT l.database_id, l.label_key, l.label_value FROM database_labels l
+		JOIN databases d ON d.database_id = l.database_id WHERE d.owner_id = ?`, userId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list database labels for UserID %s: %v", userId, err)
+		return nil, fmt.Errorf("database error listing database labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[int64]map[string]string)
+	for rows.Next() {
+		var databaseId int64
+		var key, value string
+		if err := rows.Scan(&databaseId, &key, &value); err != nil {
+			return nil, fmt.Errorf("database error reading database labels: %w", err)
+		}
+		if labels[databaseId] == nil {
+			labels[databaseId] = make(map[string]string)
+		}
+		labels[databaseId][key] = value
+	}
+	return labels, rows.Err()
+}
+
+// labelFilter returns the conditions restricting the databases table to the rows
+// matching every selector, with their arguments.
+func labelFilter(selectors []core.LabelSelector) (string, []any) {
+	var conditions strings.Builder
+	args := make([]any, 0, 2*len(selectors))
+	for _, selector := range selectors {
+		conditions.WriteString(" AND EXISTS (SELECT 1 FROM database_labels l WHERE l.database_id = databases.database_id AND l.label_key = ?")
+		args = append(args, selector.Key)
+		if selector.Value != "" {
+			conditions.WriteString(" AND l.label_value = ?")
+			args = append(args, selector.Value)
+		}
+		conditions.WriteString(")")
+	}
+	return conditions.String(), args
+}
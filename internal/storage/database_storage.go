@@ -2,11 +2,14 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	_ "github.com/lib/pq"           // Driver registration
 	_ "github.com/mattn/go-sqlite3" // Driver registration
 
 	"github.com/Annany2002/nebula-backend/config" // Import config package
@@ -17,9 +20,39 @@ var (
 	customLog = logger.NewLogger()
 )
 
-// ConnectMetadataDB initializes the connection pool for the metadata SQLite database
-// and ensures the required tables ('users', 'databases', 'api_key') exist.
-func ConnectMetadataDB(cfg *config.Config) (*sql.DB, error) {
+// ConnectMetadataDB opens the metadata store selected by cfg.MetadataBackend (SQLite
+// by default) and applies any pending schema migrations (see migrate.go).
+func ConnectMetadataDB(cfg *config.Config) (MetadataStore, error) {
+	var (
+		store *sqlMetadataStore
+		err   error
+	)
+	switch cfg.MetadataBackend {
+	case BackendPostgres:
+		store, err = openPostgresMetadataDB(cfg)
+	case BackendSQLite, "":
+		store, err = openSQLiteMetadataDB(cfg)
+	default:
+		return nil, fmt.Errorf("unknown metadata backend '%s'", cfg.MetadataBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create or upgrade the metadata schema
+	if err = migrateMetadataDB(context.Background(), store); err != nil {
+		store.Close()
+		customLog.Warnf("Storage: Failed to migrate metadata db: %v", err)
+		return nil, fmt.Errorf("failed to migrate metadata db: %w", err)
+	}
+	customLog.Println("Storage: Metadata schema up to date.")
+	store.breaker = newCircuitBreaker(cfg.MetadataBreakerFailures, cfg.MetadataBreakerCooldown)
+
+	return store, nil
+}
+
+// openSQLiteMetadataDB opens the metadata database file in cfg.MetadataDbDir.
+func openSQLiteMetadataDB(cfg *config.Config) (*sqlMetadataStore, error) {
 	dbPath := filepath.Join(cfg.MetadataDbDir, cfg.MetadataDbFile)
 	customLog.Printf("Storage: Initializing metadata database: %s", dbPath)
 
@@ -44,64 +77,28 @@ func ConnectMetadataDB(cfg *config.Config) (*sql.DB, error) {
 	}
 	customLog.Println("Storage: Metadata database connection successful.")
 
-	// --- Ensure 'users' table exists ---
-	createUsersTableSQL := `
-	CREATE TABLE IF NOT EXISTS users (
-		user_id TEXT PRIMARY KEY UNIQUE NOT NULL,
-		username TEXT NOT NULL,
-		email TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-	if _, err = db.Exec(createUsersTableSQL); err != nil {
-		db.Close()
-		customLog.Warnf("Storage: Failed to create users table: %v", err)
-		return nil, fmt.Errorf("failed to ensure users table: %w", err)
-	}
-	customLog.Println("Storage: Users table ensured.")
-
-	// --- Ensure 'databases' table exists ---
-	createDatabasesTableSQL := `
-	CREATE TABLE IF NOT EXISTS databases (
-		database_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		owner_id TEXT NOT NULL,
-		db_name TEXT NOT NULL,
-		file_path TEXT UNIQUE NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE (owner_id, db_name),
-		FOREIGN KEY (owner_id) REFERENCES users(user_id) ON DELETE CASCADE
-	);`
-	if _, err = db.Exec(createDatabasesTableSQL); err != nil {
-		db.Close()
-		customLog.Warnf("Storage: Failed to create databases table: %v", err)
-		return nil, fmt.Errorf("failed to ensure databases table: %w", err)
+	return &sqlMetadataStore{db: db, dialect: sqliteDialect}, nil
+}
+
+// openPostgresMetadataDB connects to the Postgres database at cfg.MetadataPostgresDSN,
+// which several server instances can share.
+func openPostgresMetadataDB(cfg *config.Config) (*sqlMetadataStore, error) {
+	customLog.Println("Storage: Initializing Postgres metadata database")
+
+	db, err := sql.Open("postgres", cfg.MetadataPostgresDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata db: %w", err)
 	}
-	customLog.Println("Storage: Databases table ensured.")
-
-	// Configure connection pool settings (optional but recommended)
-	// db.SetMaxOpenConns(25)
-	// db.SetMaxIdleConns(5)
-	// db.SetConnMaxLifetime(5*time.Minute)
-
-	// Ensure 'api_keys' table  ---
-	// nolint:gosec // G101 false positive - this is table schema, not hardcoded credentials
-	createAPIKeysTableSQL := `
-	CREATE TABLE IF NOT EXISTS api_keys (
-		api_key_id INTEGER PRIMARY KEY AUTOINCREMENT,
-		api_owner_id TEXT NOT NULL,
-		api_database_id INTEGER UNIQUE NOT NULL,
-		key TEXT UNIQUE NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (api_owner_id) REFERENCES users(user_id) ON DELETE CASCADE,
-		FOREIGN KEY (api_database_id) REFERENCES databases(database_id) ON DELETE CASCADE
-	);`
-	if _, err = db.Exec(createAPIKeysTableSQL); err != nil {
+	db.SetMaxOpenConns(cfg.MetadataPostgresMaxConns)
+	db.SetMaxIdleConns(cfg.MetadataPostgresMaxConns)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	if err = db.Ping(); err != nil {
 		db.Close()
-		customLog.Printf("Storage: Failed to create api_keys table: %v", err)
-		return nil, fmt.Errorf("failed to ensure api_keys table: %w", err)
+		customLog.Warnf("Storage: Failed to ping Postgres metadata db: %v", err)
+		return nil, fmt.Errorf("failed to connect to metadata db: %w", err)
 	}
+	customLog.Println("Storage: Metadata database connection successful.")
 
-	customLog.Println("Storage: API Keys table ensured.")
-
-	return db, nil
+	return &sqlMetadataStore{db: db, dialect: postgresDialect}, nil
 }
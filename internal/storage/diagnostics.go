@@ -0,0 +1,119 @@
+// internal/storage/diagnostics.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+// SQLiteInfo describes the SQLite library the server is built with.
+type SQLiteInfo struct {
+	Version   string
+	FTS5      bool   // Full-text search tables can be created
+	SQLCipher string // SQLCipher version, "" without it
+}
+
+// ProbeSQLite reports the version and features of the SQLite library.
+func ProbeSQLite(ctx context.Context) (SQLiteInfo, error) {
+	var info SQLiteInfo
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return info, err
+	}
+	defer db.Close()
+	if err := db.QueryRowContext(ctx, `SELECT sqlite_version()`).Scan(&info.Version); err != nil {
+		return info, err
+	}
+	_, err = db.ExecContext(ctx, `CREATE VIRTUAL TABLE fts5_probe USING fts5(content)`)
+	info.FTS5 = err == nil
+
+	cipherDB, err := sql.Open(encryptedDriverName, ":memory:")
+	if err != nil {
+		return info, err
+	}
+	defer cipherDB.Close()
+	_ = cipherDB.QueryRowContext(ctx, `PRAGMA cipher_version;`).Scan(&info.SQLCipher) // No rows without SQLCipher
+	return info, nil
+}
+
+// ProbeWAL switches a scratch database in dir to WAL and writes to it, then removes it.
+// WAL needs shared memory, which some network filesystems lack.
+func ProbeWAL(ctx context.Context, dir string) error {
+	file, err := os.CreateTemp(dir, ".walprobe-*.db")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	defer func() {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(path + suffix)
+		}
+	}()
+
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var mode string
+	if err := db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&mode); err != nil {
+		return err
+	}
+	if mode != "wal" {
+		return fmt.Errorf("journal mode is '%s'", mode)
+	}
+	_, err = db.ExecContext(ctx, `CREATE TABLE probe (x INTEGER)`)
+	return err
+}
+
+// MetadataSchemaVersions returns the schema version of the metadata database and the
+// latest version this binary has migrations for, without applying any or creating the
+// database. current is 0 for a database that does not exist yet.
+func MetadataSchemaVersions(ctx context.Context, cfg *config.Config) (current, latest int, err error) {
+	backend := cfg.MetadataBackend
+	if backend == "" {
+		backend = BackendSQLite
+	}
+	migrations, err := loadMigrations(backend)
+	if err != nil {
+		return 0, 0, err
+	}
+	latest = len(migrations)
+
+	var db *sql.DB
+	tableQuery := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	switch backend {
+	case BackendPostgres:
+		db, err = sql.Open("postgres", cfg.MetadataPostgresDSN)
+		tableQuery = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	default:
+		path := filepath.Join(cfg.MetadataDbDir, cfg.MetadataDbFile)
+		if _, statErr := os.Stat(path); errors.Is(statErr, os.ErrNotExist) {
+			return 0, latest, nil
+		}
+		db, err = sql.Open("sqlite3", "file:"+path+"?mode=ro&_busy_timeout=5000")
+	}
+	if err != nil {
+		return 0, latest, err
+	}
+	defer db.Close()
+
+	var tables int
+	if err := db.QueryRowContext(ctx, tableQuery).Scan(&tables); err != nil {
+		return 0, latest, fmt.Errorf("failed to read metadata schema: %w", err)
+	}
+	if tables == 0 {
+		return 0, latest, nil
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, latest, fmt.Errorf("failed to read metadata schema version: %w", err)
+	}
+	return current, latest, nil
+}
@@ -0,0 +1,139 @@
+// internal/storage/disk_space_monitor.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/health"
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+)
+
+// ErrDiskSpaceLow is reported while a volume the server writes to has less free space
+// than the configured minimum. SQLite running out of space mid-write can leave a WAL
+// that does not replay, so writes are rejected well before that.
+var ErrDiskSpaceLow = errors.New("free disk space is below the minimum")
+
+// DiskSpace is the free space of a directory the server writes databases to.
+type DiskSpace struct {
+	Path       string `json:"path"`
+	TotalBytes uint64 `json:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	Low        bool   `json:"low"`
+	Error      string `json:"error,omitempty"`
+}
+
+var lowDiskSpace struct {
+	mu      sync.Mutex
+	volumes []DiskSpace
+}
+
+// CheckDiskSpace returns the free space of the metadata directory (if not "") and the
+// data roots, marking those with less than minFree bytes low. A directory whose free
+// space cannot be read is reported with Error and not counted as low.
+func CheckDiskSpace(metadataDir string, minFree uint64) []DiskSpace {
+	var dirs []string
+	if metadataDir != "" {
+		dirs = append(dirs, filepath.Clean(metadataDir))
+	}
+	if userData.backend != BackendPostgres {
+		for _, root := range DataRoots() {
+			if !slices.Contains(dirs, root) {
+				dirs = append(dirs, root)
+			}
+		}
+	}
+
+	volumes := make([]DiskSpace, 0, len(dirs))
+	for _, dir := range dirs {
+		volume := DiskSpace{Path: dir}
+		total, free, err := diskSpace(dir)
+		if err != nil {
+			volume.Error = err.Error()
+		} else {
+			volume.TotalBytes, volume.FreeBytes, volume.Low = total, free, free < minFree
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// LowDiskSpaceError returns an ErrDiskSpaceLow naming the volumes that were low at the
+// last check of RunDiskSpaceMonitor, or nil.
+func LowDiskSpaceError() error {
+	lowDiskSpace.mu.Lock()
+	defer lowDiskSpace.mu.Unlock()
+	if len(lowDiskSpace.volumes) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrDiskSpaceLow, describeLowVolumes(lowDiskSpace.volumes))
+}
+
+// RunDiskSpaceMonitor checks free space with CheckDiskSpace right away and then every
+// interval. While any volume is low, writes are rejected (see
+// servicemode.SetWriteProtection), and alert is called for each volume once when it
+// becomes low.
+func RunDiskSpaceMonitor(ctx context.Context, metadataDir string, interval time.Duration, minFree uint64, alert func(context.Context, DiskSpace)) {
+	health.RegisterWorker("disk_space_monitor", interval)
+	defer health.UnregisterWorker("disk_space_monitor")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		updateLowDiskSpace(ctx, CheckDiskSpace(metadataDir, minFree), minFree, alert)
+		health.Beat("disk_space_monitor")
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateLowDiskSpace records the low volumes of one check and switches write
+// protection on or off when that changes.
+func updateLowDiskSpace(ctx context.Context, volumes []DiskSpace, minFree uint64, alert func(context.Context, DiskSpace)) {
+	var low []DiskSpace
+	for _, volume := range volumes {
+		if volume.Error != "" {
+			customLog.Ctx(ctx).Warnf("Storage: Cannot read free space of '%s': %s", volume.Path, volume.Error)
+		}
+		if volume.Low {
+			low = append(low, volume)
+		}
+	}
+
+	lowDiskSpace.mu.Lock()
+	previous := lowDiskSpace.volumes
+	lowDiskSpace.volumes = low
+	lowDiskSpace.mu.Unlock()
+
+	for _, volume := range low {
+		if !slices.ContainsFunc(previous, func(v DiskSpace) bool { return v.Path == volume.Path }) {
+			customLog.Ctx(ctx).Warnf("Storage: '%s' has %d MB free, below the minimum of %d MB; rejecting writes", volume.Path, volume.FreeBytes>>20, minFree>>20)
+			if alert != nil {
+				alert(ctx, volume)
+			}
+		}
+	}
+	switch {
+	case len(low) > 0:
+		servicemode.SetWriteProtection("the server is low on disk space")
+	case len(previous) > 0:
+		servicemode.SetWriteProtection("")
+		customLog.Ctx(ctx).Printf("Storage: Free disk space is back above %d MB; accepting writes", minFree>>20)
+	}
+}
+
+func describeLowVolumes(volumes []DiskSpace) string {
+	parts := make([]string, len(volumes))
+	for i, volume := range volumes {
+		parts[i] = fmt.Sprintf("'%s' has %d MB free", volume.Path, volume.FreeBytes>>20)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/servicemode"
+)
+
+func TestDiskSpaceMonitor(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	defer servicemode.SetWriteProtection("")
+
+	volumes := CheckDiskSpace(dir, 1)
+	if len(volumes) != 1 || volumes[0].Path != dir || volumes[0].Low || volumes[0].Error != "" || volumes[0].FreeBytes == 0 {
+		t.Fatalf("CheckDiskSpace with a 1 byte minimum = %+v; want %s, not low", volumes, dir)
+	}
+	volumes = CheckDiskSpace(dir, math.MaxUint64)
+	if len(volumes) != 1 || !volumes[0].Low {
+		t.Fatalf("CheckDiskSpace with an unreachable minimum = %+v; want low", volumes)
+	}
+
+	var alerts []DiskSpace
+	alert := func(_ context.Context, volume DiskSpace) { alerts = append(alerts, volume) }
+	for range 2 {
+		updateLowDiskSpace(ctx, volumes, math.MaxUint64, alert)
+	}
+	if len(alerts) != 1 || alerts[0].Path != dir {
+		t.Errorf("alerts = %+v; want one for %s", alerts, dir)
+	}
+	if servicemode.AllowsWrites() {
+		t.Error("writes allowed while low on disk space")
+	}
+	if err := LowDiskSpaceError(); !errors.Is(err, ErrDiskSpaceLow) {
+		t.Errorf("LowDiskSpaceError = %v; want ErrDiskSpaceLow", err)
+	}
+
+	updateLowDiskSpace(ctx, CheckDiskSpace(dir, 1), 1, alert)
+	if !servicemode.AllowsWrites() || LowDiskSpaceError() != nil {
+		t.Errorf("after space was freed: writes allowed %v, LowDiskSpaceError %v", servicemode.AllowsWrites(), LowDiskSpaceError())
+	}
+}
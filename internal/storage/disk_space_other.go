@@ -0,0 +1,11 @@
+// internal/storage/disk_space_other.go
+
+//go:build !linux && !darwin
+
+package storage
+
+import "errors"
+
+func diskSpace(string) (total, free uint64, err error) {
+	return 0, 0, errors.New("free space is not available on this platform")
+}
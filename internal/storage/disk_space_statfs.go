@@ -0,0 +1,17 @@
+// internal/storage/disk_space_statfs.go
+
+//go:build linux || darwin
+
+package storage
+
+import "syscall"
+
+// diskSpace returns the size of the filesystem holding dir and the bytes available to
+// unprivileged users.
+func diskSpace(dir string) (total, free uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	return stat.Blocks * uint64(stat.Bsize), stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,125 @@
+// internal/storage/duplicate_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DuplicateGroup is a set of records holding the same values in the checked columns.
+type DuplicateGroup struct {
+	Values map[string]any `json:"values"`
+	Count  int64          `json:"count"`
+	IDs    []int64        `json:"ids"` // Ascending
+}
+
+// duplicateKeyFilter matches the rows whose columns are all set; NULLs are never
+// counted as duplicates of each other.
+func duplicateKeyFilter(columns []string) string {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " IS NOT NULL"
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// findDuplicates returns up to limit groups of rows of from (a table name, qualified if
+// the backend needs it) sharing the values of columns, largest groups first. columns
+// must be validated column names of the table.
+func findDuplicates(ctx context.Context, d dialect, query func(ctx context.Context, query string, args ...any) (*sql.Rows, error),
+	from string, columns []string, limit int) ([]DuplicateGroup, error) {
+	idList := "group_concat(id)"
+	if d.name == BackendPostgres {
+		idList = "string_agg(id::text, ',')"
+	}
+	keys := strings.Join(columns, ", ")
+
+	// nolint:gosec // from and columns are validated
+	duplicatesSQL := d.rebind(fmt.Sprintf(`SELECT %s, COUNT(*), %s FROM %s WHERE %s
+		GROUP BY %s HAVING COUNT(*) > 1 ORDER BY COUNT(*) DESC, MIN(id) LIMIT ?`,
+		keys, idList, from, duplicateKeyFilter(columns), keys))
+	rows, err := query(ctx, duplicatesSQL, limit)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed duplicate search: %v\nSQL: %s", err, duplicatesSQL)
+		return nil, fmt.Errorf("database error finding duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]DuplicateGroup, 0)
+	for rows.Next() {
+		values := make([]any, len(columns))
+		var count int64
+		var ids string
+		scanArgs := make([]any, 0, len(columns)+2)
+		for i := range values {
+			scanArgs = append(scanArgs, &values[i])
+		}
+		scanArgs = append(scanArgs, &count, &ids)
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, fmt.Errorf("failed reading duplicate group: %w", err)
+		}
+
+		group := DuplicateGroup{Values: make(map[string]any, len(columns)), Count: count, IDs: make([]int64, 0, count)}
+		for i, column := range columns {
+			if bytes, ok := values[i].([]byte); ok {
+				group.Values[column] = string(bytes)
+			} else {
+				group.Values[column] = values[i]
+			}
+		}
+		for _, raw := range strings.Split(ids, ",") {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading duplicate ids: %w", err)
+			}
+			group.IDs = append(group.IDs, id)
+		}
+		sort.Slice(group.IDs, func(i, j int) bool { return group.IDs[i] < group.IDs[j] })
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed processing duplicate groups: %w", err)
+	}
+	return groups, nil
+}
+
+// deleteDuplicates deletes every row of from that duplicates the values of columns of
+// another row, keeping the oldest row (lowest id) of each group, or the newest when
+// keepLast is set. It returns the IDs of the deleted rows.
+func deleteDuplicates(ctx context.Context, d dialect, query func(ctx context.Context, query string, args ...any) (*sql.Rows, error),
+	from string, columns []string, keepLast bool) ([]int64, error) {
+	keep := "MIN(id)"
+	if keepLast {
+		keep = "MAX(id)"
+	}
+	filter := duplicateKeyFilter(columns)
+
+	// nolint:gosec // from and columns are validated
+	dedupeSQL := d.rebind(fmt.Sprintf("DELETE FROM %s WHERE %s AND id NOT IN (SELECT %s FROM %s WHERE %s GROUP BY %s) RETURNING id",
+		from, filter, keep, from, filter, strings.Join(columns, ", ")))
+	rows, err := query(ctx, dedupeSQL)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed dedupe: %v\nSQL: %s", err, dedupeSQL)
+		return nil, fmt.Errorf("database error removing duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed confirming dedupe: %w", err)
+		}
+		deleted = append(deleted, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed confirming dedupe: %w", err)
+	}
+	slices.Sort(deleted)
+	return deleted, nil
+}
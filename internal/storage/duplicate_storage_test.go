@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestDuplicates(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	if err := store.CreateTable(ctx, "contacts", []core.ColumnSpec{{Name: "email", Type: "text"}, {Name: "city", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	rows := [][]any{{"a@x.io", "Paris"}, {"b@x.io", "Lyon"}, {"a@x.io", "Paris"}, {"a@x.io", "Nice"}, {nil, "Lyon"}, {nil, "Lyon"}}
+	for _, row := range rows {
+		if _, err := store.InsertRecord(ctx, "contacts", []string{"email", "city"}, row); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+
+	groups, err := store.FindDuplicates(ctx, "contacts", []string{"email"}, 10)
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("FindDuplicates(email) = %v, %v; want 1 group", groups, err)
+	}
+	if g := groups[0]; g.Values["email"] != "a@x.io" || g.Count != 3 || len(g.IDs) != 3 || g.IDs[0] != 1 || g.IDs[2] != 4 {
+		t.Errorf("unexpected group: %+v", g)
+	}
+	groups, err = store.FindDuplicates(ctx, "contacts", []string{"email", "city"}, 10)
+	if err != nil || len(groups) != 1 || groups[0].Count != 2 {
+		t.Errorf("FindDuplicates(email, city) = %v, %v; want 1 group of 2", groups, err)
+	}
+
+	deleted, err := store.DeleteDuplicates(ctx, "contacts", []string{"email"}, true)
+	if err != nil || !slices.Equal(deleted, []int64{1, 3}) {
+		t.Fatalf("DeleteDuplicates() = %v, %v; want [1 3]", deleted, err)
+	}
+	if _, err := store.GetRecord(ctx, "contacts", 4); err != nil {
+		t.Errorf("newest duplicate was not kept: %v", err)
+	}
+	if groups, _ := store.FindDuplicates(ctx, "contacts", []string{"email"}, 10); len(groups) != 0 {
+		t.Errorf("duplicates remain after dedupe: %v", groups)
+	}
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestTenantKey(t *testing.T) {
+	userDBEncryption.masterKey = bytes.Repeat([]byte{7}, 32)
+	defer func() { userDBEncryption.masterKey = nil }()
+
+	live := tenantKey(filepath.Join("data", "u1", "app.db"))
+	if len(live) != 32 {
+		t.Fatalf("tenantKey length = %d, want 32", len(live))
+	}
+	if staged := tenantKey(filepath.Join("data", "u1", ".restore-123.db")); !bytes.Equal(live, staged) {
+		t.Fatal("files of the same tenant got different keys")
+	}
+	if other := tenantKey(filepath.Join("data", "u2", "app.db")); bytes.Equal(live, other) {
+		t.Fatal("different tenants got the same key")
+	}
+}
+
+func TestConfigureEncryptionRequiresSQLCipher(t *testing.T) {
+	defer func() { userDBEncryption.masterKey = nil }()
+
+	if err := ConfigureEncryption(nil); err != nil || encryptionEnabled() {
+		t.Fatalf("ConfigureEncryption(nil) = %v, enabled %v", err, encryptionEnabled())
+	}
+	err := ConfigureEncryption(bytes.Repeat([]byte{7}, 32))
+	if err == nil {
+		t.Skip("linked SQLite is SQLCipher")
+	}
+	if !errors.Is(err, ErrEncryptionUnsupported) || encryptionEnabled() {
+		t.Fatalf("ConfigureEncryption = %v, enabled %v; want ErrEncryptionUnsupported", err, encryptionEnabled())
+	}
+}
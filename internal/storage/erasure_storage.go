@@ -0,0 +1,152 @@
+// internal/storage/erasure_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrErasureNotFound is returned when no erasure has an ID.
+var ErrErasureNotFound = errors.New("erasure not found")
+
+// EraseUser removes the metadata of an account in one transaction: its audit events are
+// anonymized, mail to email is deleted, the user is deleted with everything registered
+// to it, and report is filled in and stored as the account's tombstone. The storage of
+// its databases, backups and exports must be deleted beforehand.
+func (s *sqlMetadataStore) EraseUser(ctx context.Context, report *domain.ErasureReport, email string) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error erasing user: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	exec := func(query string, args ...any) (int, error) {
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(query), args...)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		return int(n), err
+	}
+
+	if err := tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM api_keys WHERE api_owner_id = ?`), report.UserID).Scan(&report.APIKeys); err != nil {
+		return fmt.Errorf("database error counting API keys: %w", err)
+	}
+	// Events are kept for security statistics, without anything identifying the user
+	if report.AuditEventsAnonymized, err = exec(`UPDATE audit_log SET user_id = NULL, ip = '', details = '{}' WHERE user_id = ?`, report.UserID); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to anonymize audit events of UserID %s: %v", report.UserID, err)
+		return fmt.Errorf("database error anonymizing audit events: %w", err)
+	}
+	// Events not tied to an account can still name the user's email, e.g. failed logins
+	mentioning, err := s.auditEventsMentioning(ctx, tx, email)
+	if err != nil {
+		return err
+	}
+	for _, eventId := range mentioning {
+		if _, err := exec(`UPDATE audit_log SET ip = '', details = '{}' WHERE event_id = ?`, eventId); err != nil {
+			return fmt.Errorf("database error anonymizing audit events: %w", err)
+		}
+	}
+	report.AuditEventsAnonymized += len(mentioning)
+	if report.MailDeleted, err = exec(`DELETE FROM mail_outbox WHERE recipient = ?`, email); err != nil {
+		return fmt.Errorf("database error deleting mail: %w", err)
+	}
+	deleted, err := exec(`DELETE FROM users WHERE user_id = ?`, report.UserID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete UserID %s: %v", report.UserID, err)
+		return fmt.Errorf("database error deleting user: %w", err)
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed encoding erasure report: %w", err)
+	}
+	if _, err := exec(`INSERT INTO erasures (erasure_id, user_id, requested_by, report, erased_at) VALUES (?, ?, ?, ?, ?)`,
+		report.ErasureID, report.UserID, report.RequestedBy, string(encoded), report.ErasedAt); err != nil {
+		return fmt.Errorf("database error storing erasure: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error erasing user: %w", err)
+	}
+	return nil
+}
+
+// auditEventsMentioning returns the events not tied to an account whose details have
+// email as a value.
+func (s *sqlMetadataStore) auditEventsMentioning(ctx context.Context, tx *sql.Tx, email string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT event_id, details FROM audit_log WHERE user_id IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("database error listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var eventIds []int64
+	for rows.Next() {
+		var eventId int64
+		var encoded string
+		if err := rows.Scan(&eventId, &encoded); err != nil {
+			return nil, fmt.Errorf("database error reading audit events: %w", err)
+		}
+		var details map[string]string
+		if err := json.Unmarshal([]byte(encoded), &details); err != nil {
+			continue
+		}
+		for _, value := range details {
+			if strings.EqualFold(value, email) {
+				eventIds = append(eventIds, eventId)
+				break
+			}
+		}
+	}
+	return eventIds, rows.Err()
+}
+
+// ListErasures returns the reports of every erased account, most recent first.
+func (s *sqlMetadataStore) ListErasures(ctx context.Context) ([]domain.ErasureReport, error) {
+	rows, err := s.query(ctx, `SELECT report FROM erasures ORDER BY erased_at DESC, erasure_id`)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list erasures: %v", err)
+		return nil, fmt.Errorf("database error listing erasures: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []domain.ErasureReport{}
+	for rows.Next() {
+		var encoded string
+		if err := rows.Scan(&encoded); err != nil {
+			return nil, fmt.Errorf("database error reading erasures: %w", err)
+		}
+		var report domain.ErasureReport
+		if err := json.Unmarshal([]byte(encoded), &report); err != nil {
+			return nil, fmt.Errorf("invalid erasure report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// FindErasure returns the report of an erasure, or ErrErasureNotFound.
+func (s *sqlMetadataStore) FindErasure(ctx context.Context, erasureId string) (*domain.ErasureReport, error) {
+	var encoded string
+	if err := s.queryRow(ctx, `SELECT report FROM erasures WHERE erasure_id = ?`, erasureId).Scan(&encoded); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrErasureNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up erasure %s: %v", erasureId, err)
+		return nil, fmt.Errorf("database error finding erasure: %w", err)
+	}
+	var report domain.ErasureReport
+	if err := json.Unmarshal([]byte(encoded), &report); err != nil {
+		return nil, fmt.Errorf("invalid erasure report: %w", err)
+	}
+	return &report, nil
+}
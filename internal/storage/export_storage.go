@@ -0,0 +1,228 @@
+// internal/storage/export_storage.go
+package storage
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+// schemaObject is one row of sqlite_master relevant to a dump.
+type schemaObject struct {
+	Type string
+	Name string
+	SQL  string
+}
+
+// DumpSQL writes a complete SQL dump (schema and data) of the user DB to w.
+// The output mirrors the sqlite3 shell's .dump and can be replayed with any SQLite client.
+func DumpSQL(ctx context.Context, userDB *sql.DB, w io.Writer) error {
+	objects, err := listSchemaObjects(ctx, userDB)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "PRAGMA foreign_keys=OFF;")
+	fmt.Fprintln(bw, "BEGIN TRANSACTION;")
+
+	hasSequence := false
+	for _, obj := range objects {
+		if obj.Type != "table" {
+			continue
+		}
+		if obj.Name == "sqlite_sequence" {
+			hasSequence = true
+			continue
+		}
+		fmt.Fprintf(bw, "%s;\n", obj.SQL)
+		if err := dumpTableRows(ctx, userDB, bw, obj.Name); err != nil {
+			return err
+		}
+	}
+
+	// Preserve AUTOINCREMENT counters so re-imported tables keep issuing fresh IDs
+	if hasSequence {
+		fmt.Fprintln(bw, "DELETE FROM sqlite_sequence;")
+		if err := dumpTableRows(ctx, userDB, bw, "sqlite_sequence"); err != nil {
+			return err
+		}
+	}
+
+	// Indexes, triggers and views depend on the tables, so they come last
+	for _, obj := range objects {
+		if obj.Type == "table" {
+			continue
+		}
+		fmt.Fprintf(bw, "%s;\n", obj.SQL)
+	}
+
+	fmt.Fprintln(bw, "COMMIT;")
+	return bw.Flush()
+}
+
+// listSchemaObjects returns the user-defined schema objects in creation order.
+func listSchemaObjects(ctx context.Context, userDB *sql.DB) ([]schemaObject, error) {
+	query := `SELECT type, name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND (name NOT LIKE 'sqlite_%' OR name = 'sqlite_sequence')
+		AND tbl_name NOT GLOB ?
+		ORDER BY rowid;`
+	rows, err := userDB.QueryContext(ctx, query, core.TrashedTablePrefix+"*") // Dropped tables stay out of dumps
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error reading schema for dump: %v", err)
+		return nil, fmt.Errorf("database error reading schema: %w", err)
+	}
+	defer rows.Close()
+
+	var objects []schemaObject
+	for rows.Next() {
+		var obj schemaObject
+		if err := rows.Scan(&obj.Type, &obj.Name, &obj.SQL); err != nil {
+			return nil, fmt.Errorf("failed processing schema: %w", err)
+		}
+		objects = append(objects, obj)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading schema: %w", err)
+	}
+	return objects, nil
+}
+
+// dumpTableRows writes one INSERT statement per row of tableName.
+func dumpTableRows(ctx context.Context, userDB *sql.DB, w io.Writer, tableName string) error {
+	// nolint:gosec // tableName comes from sqlite_master, not from user input
+	rows, err := userDB.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s;", quoteIdentifier(tableName)))
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed SELECT for dump of Table '%s': %v", tableName, err)
+		return fmt.Errorf("database error dumping table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed processing results: %w", err)
+	}
+	quotedColumns := make([]string, len(columns))
+	for i, col := range columns {
+		quotedColumns[i] = quoteIdentifier(col)
+	}
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteIdentifier(tableName), strings.Join(quotedColumns, ", "))
+
+	scanArgs := make([]any, len(columns))
+	values := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	literals := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("failed reading record data: %w", err)
+		}
+		for i, v := range values {
+			literals[i] = sqlLiteral(v)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", insertPrefix, strings.Join(literals, ", ")); err != nil {
+			return fmt.Errorf("failed writing dump: %w", err)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed processing all records: %w", err)
+	}
+	return nil
+}
+
+// ExportColumn is a column of an exported table and its declared type.
+type ExportColumn struct {
+	Name string
+	Type string
+}
+
+// ScanTableRows reads every row of tableName for file exports. start receives the
+// columns before any row; emit receives the values of each row in column order and
+// must not keep the slice.
+func ScanTableRows(ctx context.Context, userDB *sql.DB, tableName string,
+	start func([]ExportColumn) error, emit func([]any) error) error {
+	// nolint:gosec // tableName is checked against the table list by callers
+	rows, err := userDB.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s;", quoteIdentifier(tableName)))
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return ErrTableNotFound
+		}
+		return fmt.Errorf("database error exporting table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed processing results: %w", err)
+	}
+	columns := make([]ExportColumn, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ExportColumn{Name: ct.Name(), Type: strings.ToUpper(ct.DatabaseTypeName())}
+	}
+	if err := start(columns); err != nil {
+		return err
+	}
+
+	scanArgs := make([]any, len(columns))
+	values := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("failed reading record data: %w", err)
+		}
+		if err := emit(values); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed processing all records: %w", err)
+	}
+	return nil
+}
+
+// quoteIdentifier wraps an identifier in double quotes, escaping embedded quotes.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral renders a scanned SQLite value as a SQL literal.
+func sqlLiteral(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		return "X'" + hex.EncodeToString(val) + "'"
+	case time.Time:
+		// Matches the CURRENT_TIMESTAMP format used for created_at columns
+		layout := "2006-01-02 15:04:05"
+		if val.Nanosecond() != 0 {
+			layout = "2006-01-02 15:04:05.999999999"
+		}
+		return "'" + val.UTC().Format(layout) + "'"
+	case string:
+		return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(val), "'", "''") + "'"
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpSQLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	src, err := ConnectUserDB(ctx, filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(src)
+
+	seed := `CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT, score REAL, raw BLOB);
+		CREATE INDEX idx_notes_body ON notes (body);
+		INSERT INTO notes (body, score, raw) VALUES ('it''s here', 1.5, X'CAFE'), (NULL, NULL, NULL);`
+	if _, err := src.ExecContext(ctx, seed); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	var dump bytes.Buffer
+	if err := DumpSQL(ctx, src, &dump); err != nil {
+		t.Fatalf("DumpSQL: %v", err)
+	}
+	if !strings.Contains(dump.String(), "CREATE INDEX idx_notes_body") {
+		t.Errorf("dump is missing the index definition:\n%s", dump.String())
+	}
+
+	dst, err := ConnectUserDB(ctx, filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer ReleaseUserDB(dst)
+	if _, err := dst.ExecContext(ctx, dump.String()); err != nil {
+		t.Fatalf("replaying dump: %v\n%s", err, dump.String())
+	}
+
+	var body string
+	var raw []byte
+	if err := dst.QueryRowContext(ctx, "SELECT body, raw FROM notes WHERE id = 1").Scan(&body, &raw); err != nil {
+		t.Fatalf("query replayed data: %v", err)
+	}
+	if body != "it's here" || !bytes.Equal(raw, []byte{0xCA, 0xFE}) {
+		t.Errorf("replayed row = (%q, %x); want (\"it's here\", cafe)", body, raw)
+	}
+
+	var seq int
+	if err := dst.QueryRowContext(ctx, "SELECT seq FROM sqlite_sequence WHERE name = 'notes'").Scan(&seq); err != nil || seq != 2 {
+		t.Errorf("sqlite_sequence = %d, %v; want 2", seq, err)
+	}
+}
@@ -0,0 +1,128 @@
+// internal/storage/file_lock.go
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFileLocksUnsupported is returned by ConfigureFileLocks on platforms without
+// advisory file locks.
+var ErrFileLocksUnsupported = errors.New("advisory file locks are not supported on this platform")
+
+const (
+	fileLockPollMin = 5 * time.Millisecond
+	fileLockPollMax = 100 * time.Millisecond
+)
+
+// fileLocks extends the in-process write lock of a user DB to other processes sharing
+// the data directory: a writer also holds an exclusive advisory lock on a lock file of
+// the database. Lock files live in one directory, named after the database's path
+// relative to the data directory, so instances mounting the volume elsewhere agree.
+type fileLocks struct {
+	mu      sync.RWMutex
+	dataDir string // Absolute data directory
+	lockDir string // "" disables file locks
+}
+
+var userDBFileLocks = &fileLocks{}
+
+// ConfigureFileLocks makes writes to user DB files under dataDir also take an advisory
+// lock in dataDir/.locks, so instances sharing the directory write one at a time.
+// Call once at startup before serving requests.
+func ConfigureFileLocks(dataDir string) error {
+	if !fileLocksSupported {
+		return ErrFileLocksUnsupported
+	}
+	absDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return err
+	}
+	lockDir := filepath.Join(absDir, ".locks")
+	if err := os.MkdirAll(lockDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	userDBFileLocks.mu.Lock()
+	defer userDBFileLocks.mu.Unlock()
+	userDBFileLocks.dataDir, userDBFileLocks.lockDir = absDir, lockDir
+	return nil
+}
+
+// lockFilePath returns the lock file of the user DB at path, or "" when file locks are
+// disabled.
+func (f *fileLocks) lockFilePath(path string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.lockDir == "" {
+		return ""
+	}
+	name := RelativeDataPath(f.dataDir, path)
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(f.lockDir, hex.EncodeToString(sum[:16])+".lock")
+}
+
+// lock waits for the advisory lock of the user DB at path and returns the matching
+// unlock func. It returns a no-op unlock when file locks are disabled.
+func (f *fileLocks) lock(ctx context.Context, path string) (func(), error) {
+	lockPath := f.lockFilePath(path)
+	if lockPath == "" {
+		return func() {}, nil
+	}
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file of '%s': %w", path, err)
+	}
+
+	// Locks are polled without blocking so a waiting writer can give up with its context
+	delay := fileLockPollMin
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to lock '%s': %w", path, err)
+		}
+		if locked {
+			return func() {
+				if err := unlockFile(file); err != nil {
+					customLog.Warnf("Storage: Failed to release file lock of '%s': %v", path, err)
+				}
+				file.Close()
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			customLog.Ctx(ctx).Warnf("Storage: Gave up waiting for file lock on '%s', held by another instance: %v", path, ctx.Err())
+			return nil, fmt.Errorf("%w (%w): %v", ErrWriteLockTimeout, ErrDatabaseBusy, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, fileLockPollMax)
+	}
+}
+
+// RelativeDataPath returns path relative to dataDir with forward slashes, which names
+// a user DB the same way on every instance sharing the directory. Paths outside
+// dataDir are returned absolute.
+func RelativeDataPath(dataDir, path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return filepath.ToSlash(absPath)
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(absPath)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,17 @@
+// internal/storage/file_lock_other.go
+
+//go:build !unix
+
+package storage
+
+import "os"
+
+const fileLocksSupported = false
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, ErrFileLocksUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return ErrFileLocksUnsupported
+}
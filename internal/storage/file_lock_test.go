@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLockExcludesOtherHolders(t *testing.T) {
+	dataDir := t.TempDir()
+	// Two lock sets on one directory stand in for two instances
+	first := &fileLocks{dataDir: dataDir, lockDir: filepath.Join(dataDir, ".locks")}
+	second := &fileLocks{dataDir: dataDir, lockDir: filepath.Join(dataDir, ".locks")}
+	if err := os.MkdirAll(first.lockDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath := filepath.Join(dataDir, "u1", "app.db")
+	unlock, err := first.lock(context.Background(), dbPath)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := second.lock(ctx, dbPath); !errors.Is(err, ErrWriteLockTimeout) || !errors.Is(err, ErrDatabaseBusy) {
+		t.Fatalf("lock held by another instance = %v; want ErrWriteLockTimeout", err)
+	}
+	if other, err := second.lock(context.Background(), filepath.Join(dataDir, "u1", "other.db")); err != nil {
+		t.Fatalf("lock of another database: %v", err)
+	} else {
+		other()
+	}
+
+	unlock()
+	unlock, err = second.lock(context.Background(), dbPath)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	unlock()
+}
+
+func TestRelativeDataPath(t *testing.T) {
+	dataDir := t.TempDir()
+	if got := RelativeDataPath(dataDir, filepath.Join(dataDir, "u1", "app.db")); got != "u1/app.db" {
+		t.Errorf("RelativeDataPath inside = %q; want u1/app.db", got)
+	}
+	outside := filepath.Join(filepath.Dir(dataDir), "elsewhere.db")
+	if got := RelativeDataPath(dataDir, outside); got != filepath.ToSlash(outside) {
+		t.Errorf("RelativeDataPath outside = %q; want %q", got, outside)
+	}
+}
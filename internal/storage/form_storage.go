@@ -0,0 +1,125 @@
+// internal/storage/form_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrFormNotFound is returned when no form has an ID.
+var ErrFormNotFound = errors.New("form not found")
+
+const formColumns = `form_id, database_id, table_name, description, columns, allowed_origins, captcha_provider, captcha_secret, redirect_url, rate_limit, enabled, created_at, updated_at`
+
+func scanForm(scan func(dest ...any) error) (*domain.Form, error) {
+	var form domain.Form
+	var columns, origins string
+	if err := scan(&form.ID, &form.DatabaseID, &form.Table, &form.Description, &columns, &origins, &form.CaptchaProvider,
+		&form.CaptchaSecret, &form.RedirectURL, &form.RateLimit, &form.Enabled, &form.CreatedAt, &form.UpdatedAt); err != nil {
+		return nil, err
+	}
+	form.Columns = splitStoredList(columns)
+	form.AllowedOrigins = splitStoredList(origins)
+	return &form, nil
+}
+
+// splitStoredList splits a comma-separated column value, empty for an empty value.
+func splitStoredList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// ListForms returns the forms of a database, oldest first.
+func (s *sqlMetadataStore) ListForms(ctx context.Context, databaseId int64) ([]domain.Form, error) {
+	rows, err := s.query(ctx, `SELECT `+formColumns+` FROM forms WHERE database_id = ? ORDER BY created_at, form_id`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list forms for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing forms: %w", err)
+	}
+	defer rows.Close()
+
+	forms := []domain.Form{}
+	for rows.Next() {
+		form, err := scanForm(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading forms: %w", err)
+		}
+		forms = append(forms, *form)
+	}
+	return forms, rows.Err()
+}
+
+// GetForm returns one form of a database.
+func (s *sqlMetadataStore) GetForm(ctx context.Context, databaseId int64, formId string) (*domain.Form, error) {
+	form, err := scanForm(s.queryRow(ctx, `SELECT `+formColumns+` FROM forms WHERE database_id = ? AND form_id = ?`, databaseId, formId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFormNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get form %s for DBID %d: %v", formId, databaseId, err)
+		return nil, fmt.Errorf("database error getting form: %w", err)
+	}
+	return form, nil
+}
+
+// FindForm returns the form of an ID, whatever its database, for public submissions.
+func (s *sqlMetadataStore) FindForm(ctx context.Context, formId string) (*domain.Form, error) {
+	form, err := scanForm(s.queryRow(ctx, `SELECT `+formColumns+` FROM forms WHERE form_id = ?`, formId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrFormNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to find form %s: %v", formId, err)
+		return nil, fmt.Errorf("database error finding form: %w", err)
+	}
+	return form, nil
+}
+
+// CreateForm stores a new form of form.DatabaseID. The ID is chosen by the caller.
+func (s *sqlMetadataStore) CreateForm(ctx context.Context, form domain.Form) error {
+	_, err := s.exec(ctx, `INSERT INTO forms (form_id, database_id, table_name, description, columns, allowed_origins, captcha_provider, captcha_secret, redirect_url, rate_limit, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		form.ID, form.DatabaseID, form.Table, form.Description, strings.Join(form.Columns, ","), strings.Join(form.AllowedOrigins, ","),
+		form.CaptchaProvider, form.CaptchaSecret, form.RedirectURL, form.RateLimit, form.Enabled)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create form for DBID %d: %v", form.DatabaseID, err)
+		return fmt.Errorf("database error creating form: %w", err)
+	}
+	return nil
+}
+
+// UpdateForm stores every setting of form but its table.
+func (s *sqlMetadataStore) UpdateForm(ctx context.Context, form domain.Form) error {
+	result, err := s.exec(ctx, `UPDATE forms SET description = ?, columns = ?, allowed_origins = ?, captcha_provider = ?, captcha_secret = ?,
+		redirect_url = ?, rate_limit = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP WHERE database_id = ? AND form_id = ?`,
+		form.Description, strings.Join(form.Columns, ","), strings.Join(form.AllowedOrigins, ","), form.CaptchaProvider, form.CaptchaSecret,
+		form.RedirectURL, form.RateLimit, form.Enabled, form.DatabaseID, form.ID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update form %s for DBID %d: %v", form.ID, form.DatabaseID, err)
+		return fmt.Errorf("database error updating form: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrFormNotFound
+	}
+	return nil
+}
+
+// DeleteForm removes a form, returning ErrFormNotFound if it did not exist.
+func (s *sqlMetadataStore) DeleteForm(ctx context.Context, databaseId int64, formId string) error {
+	result, err := s.exec(ctx, `DELETE FROM forms WHERE database_id = ? AND form_id = ?`, databaseId, formId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete form %s for DBID %d: %v", formId, databaseId, err)
+		return fmt.Errorf("database error deleting form: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrFormNotFound
+	}
+	return nil
+}
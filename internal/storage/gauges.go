@@ -0,0 +1,17 @@
+// internal/storage/gauges.go
+package storage
+
+import "github.com/Annany2002/nebula-backend/internal/metrics"
+
+// RegisterGauges makes the user DB pool and the schema cache report their sizes
+// through metrics.UserDBHandles and metrics.CachedSchemas. Call once at startup.
+func RegisterGauges() {
+	metrics.UserDBHandles.SetSource(func() map[string]float64 {
+		inUse, idle := userDBs.handleCounts()
+		return map[string]float64{"in_use": float64(inUse), "idle": float64(idle)}
+	})
+	metrics.CachedSchemas.SetSource(func() map[string]float64 {
+		databases, tables := tableSchemas.size()
+		return map[string]float64{"databases": float64(databases), "tables": float64(tables)}
+	})
+}
@@ -0,0 +1,80 @@
+// internal/storage/invalidation.go
+package storage
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Kinds of cache invalidation, see Invalidation.
+const (
+	InvalidateReads    = "reads"    // Records were written
+	InvalidateSchema   = "schema"   // A table (or every table when Table is "") changed shape
+	InvalidateDatabase = "database" // The file was replaced, moved or deleted
+)
+
+// Invalidation describes a write that made cached state of a user DB stale. Instances
+// sharing the data directory exchange them so their caches follow each other's writes.
+type Invalidation struct {
+	Kind  string
+	Path  string // File path of the user DB
+	Table string // For InvalidateSchema
+}
+
+var (
+	invalidationMu       sync.RWMutex
+	invalidationListener func(Invalidation)
+)
+
+// OnInvalidation makes fn receive every invalidation caused by this process's writes;
+// nil stops notifications. fn runs on the writing goroutine and must not block.
+func OnInvalidation(fn func(Invalidation)) {
+	invalidationMu.Lock()
+	defer invalidationMu.Unlock()
+	invalidationListener = fn
+}
+
+func notifyInvalidation(inv Invalidation) {
+	invalidationMu.RLock()
+	fn := invalidationListener
+	invalidationMu.RUnlock()
+	if fn != nil {
+		fn(inv)
+	}
+}
+
+// ApplyInvalidation drops the cached state inv makes stale, without notifying the
+// OnInvalidation listener. Used for invalidations received from other instances.
+func ApplyInvalidation(inv Invalidation) {
+	switch inv.Kind {
+	case InvalidateReads:
+		recordReads.invalidate(inv.Path)
+		readReplicas.primaryChanged(inv.Path, false)
+	case InvalidateSchema:
+		tableSchemas.invalidate(inv.Path, inv.Table)
+		recordReads.invalidate(inv.Path)
+		readReplicas.primaryChanged(inv.Path, true)
+	case InvalidateDatabase:
+		invalidateUserDB(inv.Path)
+	}
+}
+
+// ClearCaches drops every cached read and table schema, e.g. after invalidations from
+// other instances may have been missed.
+func ClearCaches() {
+	recordReads.mu.Lock()
+	for path := range recordReads.generations {
+		recordReads.generations[path]++
+	}
+	recordReads.lru.Init()
+	recordReads.items = make(map[string]*list.Element)
+	recordReads.mu.Unlock()
+
+	tableSchemas.mu.Lock()
+	for _, entry := range tableSchemas.byDB {
+		entry.generation++
+		entry.counted = false
+		entry.tables = make(map[string]map[string]string)
+	}
+	tableSchemas.mu.Unlock()
+}
@@ -0,0 +1,53 @@
+// internal/storage/json_schema_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrJSONSchemaNotFound is returned when a user table has no JSON Schema.
+var ErrJSONSchemaNotFound = errors.New("table has no JSON schema")
+
+// GetTableJSONSchema returns the JSON Schema document of a user table.
+func (s *sqlMetadataStore) GetTableJSONSchema(ctx context.Context, databaseId int64, tableName string) (string, error) {
+	var schema string
+	err := s.queryRow(ctx, `SELECT schema FROM table_json_schemas WHERE database_id = ? AND table_name = ?`, databaseId, tableName).Scan(&schema)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrJSONSchemaNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get JSON schema for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return "", fmt.Errorf("database error getting JSON schema: %w", err)
+	}
+	return schema, nil
+}
+
+// SetTableJSONSchema stores the JSON Schema document of a user table, replacing any
+// previous one. The document is expected to compile with jsonschema.Compile.
+func (s *sqlMetadataStore) SetTableJSONSchema(ctx context.Context, databaseId int64, tableName, schema string) error {
+	_, err := s.exec(ctx, `INSERT INTO table_json_schemas (database_id, table_name, schema) VALUES (?, ?, ?)
+		ON CONFLICT (database_id, table_name) DO UPDATE SET schema = excluded.schema, updated_at = CURRENT_TIMESTAMP`,
+		databaseId, tableName, schema)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store JSON schema for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error storing JSON schema: %w", err)
+	}
+	return nil
+}
+
+// DeleteTableJSONSchema removes the JSON Schema of a user table, returning
+// ErrJSONSchemaNotFound if it had none.
+func (s *sqlMetadataStore) DeleteTableJSONSchema(ctx context.Context, databaseId int64, tableName string) error {
+	result, err := s.exec(ctx, `DELETE FROM table_json_schemas WHERE database_id = ? AND table_name = ?`, databaseId, tableName)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete JSON schema for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error deleting JSON schema: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrJSONSchemaNotFound
+	}
+	return nil
+}
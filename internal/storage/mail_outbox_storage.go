@@ -0,0 +1,74 @@
+// internal/storage/mail_outbox_storage.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// EnqueueMail stores an email for delivery as soon as possible.
+func (s *sqlMetadataStore) EnqueueMail(ctx context.Context, mail domain.OutboxMail) (int64, error) {
+	var id int64
+	err := s.queryRow(ctx, `INSERT INTO mail_outbox (recipient, subject, text_body, html_body, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING mail_id`,
+		mail.Recipient, mail.Subject, mail.TextBody, mail.HTMLBody, domain.MailPending, time.Now().Unix()).Scan(&id)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to enqueue mail to %s: %v", mail.Recipient, err)
+		return 0, fmt.Errorf("database error queueing mail: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimDueMail returns up to limit pending emails whose next attempt is due and pushes
+// that attempt lease into the future, so other instances polling the same outbox skip
+// them until the lease runs out (e.g. after a crash mid-delivery).
+func (s *sqlMetadataStore) ClaimDueMail(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxMail, error) {
+	// The outer due check makes Postgres skip rows another instance claimed concurrently
+	rows, err := s.query(ctx, `UPDATE mail_outbox SET attempts = attempts + 1, next_attempt_at = ?
+		WHERE status = ? AND next_attempt_at <= ? AND mail_id IN (
+			SELECT mail_id FROM mail_outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY mail_id LIMIT ?)
+		RETURNING mail_id, recipient, subject, text_body, html_body, attempts`,
+		now.Add(lease).Unix(), domain.MailPending, now.Unix(), domain.MailPending, now.Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("database error claiming mail: %w", err)
+	}
+	defer rows.Close()
+
+	var mails []domain.OutboxMail
+	for rows.Next() {
+		var m domain.OutboxMail
+		if err := rows.Scan(&m.MailID, &m.Recipient, &m.Subject, &m.TextBody, &m.HTMLBody, &m.Attempts); err != nil {
+			return nil, fmt.Errorf("database error reading claimed mail: %w", err)
+		}
+		mails = append(mails, m)
+	}
+	return mails, rows.Err()
+}
+
+// MarkMailSent records a successful delivery.
+func (s *sqlMetadataStore) MarkMailSent(ctx context.Context, mailID int64) error {
+	_, err := s.exec(ctx, `UPDATE mail_outbox SET status = ?, last_error = '', sent_at = ? WHERE mail_id = ?`,
+		domain.MailSent, time.Now().UTC(), mailID)
+	if err != nil {
+		return fmt.Errorf("database error marking mail sent: %w", err)
+	}
+	return nil
+}
+
+// RetryMail records a failed attempt. The email is retried at retryAt, or marked failed
+// for good when retryAt is zero.
+func (s *sqlMetadataStore) RetryMail(ctx context.Context, mailID int64, lastError string, retryAt time.Time) error {
+	status, next := domain.MailPending, retryAt.Unix()
+	if retryAt.IsZero() {
+		status, next = domain.MailFailed, 0
+	}
+	_, err := s.exec(ctx, `UPDATE mail_outbox SET status = ?, last_error = ?, next_attempt_at = ? WHERE mail_id = ?`,
+		status, lastError, next, mailID)
+	if err != nil {
+		return fmt.Errorf("database error rescheduling mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// internal/storage/maintenance_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Supported maintenance operations
+const (
+	MaintenanceVacuum        = "vacuum"
+	MaintenanceAnalyze       = "analyze"
+	MaintenanceWALCheckpoint = "wal_checkpoint"
+)
+
+// ErrUnknownMaintenanceOp is returned for operations other than the supported ones.
+var ErrUnknownMaintenanceOp = errors.New("unknown maintenance operation")
+
+// WALCheckpointResult holds the output of PRAGMA wal_checkpoint.
+type WALCheckpointResult struct {
+	Busy         int `json:"busy"`
+	LogFrames    int `json:"log_frames"`
+	Checkpointed int `json:"checkpointed_frames"`
+}
+
+// MaintenanceResult describes the effect of a maintenance operation.
+type MaintenanceResult struct {
+	Operation       string               `json:"operation"`
+	SizeBeforeBytes int64                `json:"size_before_bytes"`
+	SizeAfterBytes  int64                `json:"size_after_bytes"`
+	Checkpoint      *WALCheckpointResult `json:"checkpoint,omitempty"`
+}
+
+// IsMaintenanceOperation reports whether op is a supported maintenance operation.
+func IsMaintenanceOperation(op string) bool {
+	switch op {
+	case MaintenanceVacuum, MaintenanceAnalyze, MaintenanceWALCheckpoint:
+		return true
+	}
+	return false
+}
+
+// RunMaintenance executes a maintenance operation against the user DB stored at dbFilePath.
+func RunMaintenance(ctx context.Context, userDB *sql.DB, dbFilePath, op string) (*MaintenanceResult, error) {
+	if !IsMaintenanceOperation(op) {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownMaintenanceOp, op)
+	}
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result := &MaintenanceResult{
+		Operation:       op,
+		SizeBeforeBytes: databaseFileSize(dbFilePath),
+	}
+
+	switch op {
+	case MaintenanceVacuum:
+		if _, err := userDB.ExecContext(ctx, "VACUUM;"); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: VACUUM failed for '%s': %v", dbFilePath, err)
+			return nil, fmt.Errorf("database error during vacuum: %w", err)
+		}
+		// VACUUM in WAL mode writes to the WAL; fold it back so the size reflects reclaimed space
+		if _, err := CheckpointWAL(ctx, userDB); err != nil {
+			return nil, err
+		}
+	case MaintenanceAnalyze:
+		if _, err := userDB.ExecContext(ctx, "ANALYZE;"); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: ANALYZE failed for '%s': %v", dbFilePath, err)
+			return nil, fmt.Errorf("database error during analyze: %w", err)
+		}
+	case MaintenanceWALCheckpoint:
+		checkpoint, err := CheckpointWAL(ctx, userDB)
+		if err != nil {
+			return nil, err
+		}
+		result.Checkpoint = checkpoint
+	default:
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownMaintenanceOp, op)
+	}
+
+	result.SizeAfterBytes = databaseFileSize(dbFilePath)
+	return result, nil
+}
+
+// CheckpointWAL copies all WAL frames into the database file and truncates the WAL.
+func CheckpointWAL(ctx context.Context, userDB *sql.DB) (*WALCheckpointResult, error) {
+	var res WALCheckpointResult
+	err := userDB.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE);").Scan(&res.Busy, &res.LogFrames, &res.Checkpointed)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: WAL checkpoint failed: %v", err)
+		return nil, fmt.Errorf("database error during wal checkpoint: %w", err)
+	}
+	return &res, nil
+}
+
+// databaseFileSize returns the combined size of a database file and its WAL.
+func databaseFileSize(dbFilePath string) int64 {
+	var total int64
+	for _, p := range []string{dbFilePath, dbFilePath + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
@@ -0,0 +1,167 @@
+// internal/storage/metadata_breaker.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/Annany2002/nebula-backend/internal/metrics"
+)
+
+// ErrMetadataUnavailable is returned without querying the metadata database while its
+// circuit breaker is open. Handlers surface it as 503 with a Retry-After header.
+var ErrMetadataUnavailable = errors.New("metadata database is unavailable")
+
+// MetadataUnavailableError reports how long the breaker stays open.
+type MetadataUnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *MetadataUnavailableError) Error() string {
+	return fmt.Sprintf("metadata database is unavailable, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *MetadataUnavailableError) Unwrap() error { return ErrMetadataUnavailable }
+
+// States of the metadata circuit breaker, as exported in metrics.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// circuitBreaker stops calls to the metadata database after consecutive failures that
+// show the database itself is in trouble (locked, stalled or unreachable), so requests
+// fail at once instead of each waiting out the busy timeout. After the cooldown one
+// call is let through as a probe: its success closes the breaker, its failure opens it
+// again. A nil breaker lets every call through.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker returns a breaker that opens after threshold consecutive failures,
+// or nil (disabled) when threshold is 0.
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	metrics.MetadataBreakerState.SetState(BreakerClosed)
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now, state: BreakerClosed}
+}
+
+// allow returns an error if a call must not go to the database, and whether the call
+// is the probe of a half-open breaker.
+func (b *circuitBreaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if wait := b.cooldown - b.now().Sub(b.openedAt); wait > 0 {
+			return false, &MetadataUnavailableError{RetryAfter: wait}
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, &MetadataUnavailableError{RetryAfter: time.Second}
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of a call allow let through.
+func (b *circuitBreaker) record(probe bool, err error) {
+	if b == nil {
+		return
+	}
+	failed := metadataFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case probe:
+		b.probing = false
+		if failed {
+			b.open(err)
+		} else {
+			b.failures = 0
+			b.setState(BreakerClosed)
+			customLog.Println("Storage: Metadata database recovered, circuit breaker closed")
+		}
+	case b.state != BreakerClosed:
+		// A call from before the breaker opened
+	case failed:
+		if b.failures++; b.failures >= b.threshold {
+			b.open(err)
+		}
+	default:
+		b.failures = 0
+	}
+}
+
+func (b *circuitBreaker) open(err error) {
+	b.openedAt = b.now()
+	b.setState(BreakerOpen)
+	customLog.Warnf("Storage: Metadata database failing (%v), circuit breaker open for %s", err, b.cooldown)
+}
+
+func (b *circuitBreaker) setState(state string) {
+	b.state = state
+	metrics.MetadataBreakerState.SetState(state)
+	metrics.MetadataBreakerTransitions.Inc(state)
+}
+
+// metadataFailure reports whether err shows the metadata database is locked, stalled
+// or unreachable, rather than a problem with one query.
+func metadataFailure(err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if isBusyError(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrIoErr || sqliteErr.Code == sqlite3.ErrFull || sqliteErr.Code == sqlite3.ErrCantOpen
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// metadataRow is a *sql.Row whose Scan reports to the breaker.
+type metadataRow struct {
+	row   *sql.Row
+	err   error
+	probe bool
+	b     *circuitBreaker
+}
+
+func (r *metadataRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	err := r.row.Scan(dest...)
+	r.b.record(r.probe, err)
+	return err
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestMetadataCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db",
+		MetadataBreakerFailures: 2, MetadataBreakerCooldown: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	b := store.(*sqlMetadataStore).breaker
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+
+	// Query errors and missing rows do not count
+	for range 3 {
+		b.record(false, sqlite3.Error{Code: sqlite3.ErrConstraint})
+		if _, err := store.FindUserByUserId(ctx, "nobody"); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("FindUserByUserId = %v; want ErrUserNotFound", err)
+		}
+	}
+	b.record(false, busy)
+	b.record(false, nil) // A success resets the count
+	b.record(false, busy)
+	if b.state != BreakerClosed {
+		t.Fatalf("state after non-consecutive failures = %s; want closed", b.state)
+	}
+
+	b.record(false, busy)
+	if b.state != BreakerOpen {
+		t.Fatalf("state after 2 consecutive failures = %s; want open", b.state)
+	}
+	_, err = store.FindUserByUserId(ctx, "nobody")
+	var unavailableErr *MetadataUnavailableError
+	if !errors.As(err, &unavailableErr) || unavailableErr.RetryAfter != 10*time.Second {
+		t.Fatalf("FindUserByUserId while open = %v; want MetadataUnavailableError retrying in 10s", err)
+	}
+	if err := store.Ping(ctx); !errors.Is(err, ErrMetadataUnavailable) {
+		t.Fatalf("Ping while open = %v; want ErrMetadataUnavailable", err)
+	}
+
+	// After the cooldown one probe goes through; the others still fail fast
+	now = now.Add(11 * time.Second)
+	probe, err := b.allow()
+	if !probe || err != nil {
+		t.Fatalf("allow after cooldown = %v, %v; want the probe", probe, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrMetadataUnavailable) {
+		t.Fatalf("allow during probe = %v; want ErrMetadataUnavailable", err)
+	}
+	b.record(true, busy)
+	if b.state != BreakerOpen {
+		t.Fatalf("state after failed probe = %s; want open", b.state)
+	}
+
+	now = now.Add(11 * time.Second)
+	if _, err := store.FindUserByUserId(ctx, "nobody"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("FindUserByUserId as probe = %v; want ErrUserNotFound", err)
+	}
+	if b.state != BreakerClosed {
+		t.Fatalf("state after successful probe = %s; want closed", b.state)
+	}
+}
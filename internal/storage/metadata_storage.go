@@ -1,4 +1,4 @@
-// internal/storage/metadata_repo.go
+// internal/storage/metadata_storage.go
 package storage
 
 import (
@@ -9,8 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/mattn/go-sqlite3"
+	"time"
 
 	"github.com/Annany2002/nebula-backend/internal/domain"
 )
@@ -33,17 +32,14 @@ const apiKeySecretLength = 32    // Length of the random secret part in bytes
 // --- User Operations ---
 
 // CreateUser inserts a new user into the metadata database.
-func CreateUser(ctx context.Context, db *sql.DB, user_id, username, email, passwordHash string) (string, error) {
+func (s *sqlMetadataStore) CreateUser(ctx context.Context, user_id, username, email, passwordHash string) (string, error) {
 	sqlStatement := `INSERT INTO users (user_id, username, email, password_hash) VALUES (?, ?, ?, ?)`
-	_, err := db.ExecContext(ctx, sqlStatement, user_id, username, email, passwordHash)
+	_, err := s.exec(ctx, sqlStatement, user_id, username, email, passwordHash)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			if strings.Contains(sqliteErr.Error(), "users.email") {
-				return "", ErrEmailExists
-			}
+		if s.dialect.constraintViolation(err, "users.email") {
+			return "", ErrEmailExists
 		}
-		customLog.Warnf("Storage: Failed to insert user %s: %v", email, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to insert user %s: %v", email, err)
 		return "", fmt.Errorf("database error during user creation: %w", err)
 	}
 
@@ -51,41 +47,41 @@ func CreateUser(ctx context.Context, db *sql.DB, user_id, username, email, passw
 }
 
 // FindUserByEmail retrieves a user by their email address.
-func FindUserByEmail(ctx context.Context, db *sql.DB, email string) (*domain.UserMetadata, error) {
-	sqlStatement := `SELECT * FROM users WHERE email = ? LIMIT 1`
-	row := db.QueryRowContext(ctx, sqlStatement, email)
+func (s *sqlMetadataStore) FindUserByEmail(ctx context.Context, email string) (*domain.UserMetadata, error) {
+	sqlStatement := `SELECT user_id, username, email, password_hash, created_at, role FROM users WHERE email = ? LIMIT 1`
+	row := s.queryRow(ctx, sqlStatement, email)
 
 	var user domain.UserMetadata
-	err := row.Scan(&user.UserId, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	err := row.Scan(&user.UserId, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.Role)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
-		customLog.Warnf("Storage: Failed to find user by email %s: %v", email, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to find user by email %s: %v", email, err)
 		return nil, fmt.Errorf("database error finding user: %w", err)
 	}
 	return &user, nil
 }
 
 // FindUserByUserId finds a user with user_id
-func FindUserByUserId(ctx context.Context, db *sql.DB, user_id string) (*domain.UserMetadata, error) {
-	sqlStatement := `SELECT * FROM users WHERE user_id = ? LIMIT 1`
-	row := db.QueryRowContext(ctx, sqlStatement, user_id)
+func (s *sqlMetadataStore) FindUserByUserId(ctx context.Context, user_id string) (*domain.UserMetadata, error) {
+	sqlStatement := `SELECT user_id, username, email, password_hash, created_at, role FROM users WHERE user_id = ? LIMIT 1`
+	row := s.queryRow(ctx, sqlStatement, user_id)
 
 	var user domain.UserMetadata
-	err := row.Scan(&user.UserId, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	err := row.Scan(&user.UserId, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.Role)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
-		customLog.Warnf("Storage: Failed to find user by user_id %s: %v", user_id, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to find user by user_id %s: %v", user_id, err)
 		return nil, fmt.Errorf("database error finding user: %w", err)
 	}
 	return &user, nil
 }
 
 // UpdateUser updates user profile fields (username and/or email).
-func UpdateUser(ctx context.Context, db *sql.DB, userId, username, email string) error {
+func (s *sqlMetadataStore) UpdateUser(ctx context.Context, userId, username, email string) error {
 	// Build dynamic UPDATE query based on provided fields
 	setClauses := []string{}
 	args := []interface{}{}
@@ -107,15 +103,12 @@ func UpdateUser(ctx context.Context, db *sql.DB, userId, username, email string)
 	// nolint:gosec // setClauses only contains hardcoded column names ("username = ?" or "email = ?")
 	sqlStatement := fmt.Sprintf("UPDATE users SET %s WHERE user_id = ?", strings.Join(setClauses, ", "))
 
-	result, err := db.ExecContext(ctx, sqlStatement, args...)
+	result, err := s.exec(ctx, sqlStatement, args...)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
-			if strings.Contains(sqliteErr.Error(), "users.email") {
-				return ErrEmailExists
-			}
+		if s.dialect.constraintViolation(err, "users.email") {
+			return ErrEmailExists
 		}
-		customLog.Warnf("Storage: Failed to update user %s: %v", userId, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update user %s: %v", userId, err)
 		return fmt.Errorf("database error during user update: %w", err)
 	}
 
@@ -130,48 +123,113 @@ func UpdateUser(ctx context.Context, db *sql.DB, userId, username, email string)
 	return nil
 }
 
+// SetUserRole changes a user's role (domain.RoleUser or domain.RoleAdmin).
+func (s *sqlMetadataStore) SetUserRole(ctx context.Context, userId, role string) error {
+	result, err := s.exec(ctx, `UPDATE users SET role = ? WHERE user_id = ?`, role, userId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to set role of user %s: %v", userId, err)
+		return fmt.Errorf("database error during role update: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to confirm role update: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ReplacePasswordHash swaps a user's password hash for newHash if it is still oldHash,
+// so a rehash never overwrites a password changed in the meantime. It reports whether
+// the hash was replaced.
+func (s *sqlMetadataStore) ReplacePasswordHash(ctx context.Context, userId, oldHash, newHash string) (bool, error) {
+	result, err := s.exec(ctx, `UPDATE users SET password_hash = ? WHERE user_id = ? AND password_hash = ?`, newHash, userId, oldHash)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to replace password hash of user %s: %v", userId, err)
+		return false, fmt.Errorf("database error during password hash update: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm password hash update: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
 // --- Database Registration Operations ---
 
 // RegisterDatabase inserts a new database registration record.
-func RegisterDatabase(ctx context.Context, db *sql.DB, userId, dbName, filePath string) error {
+func (s *sqlMetadataStore) RegisterDatabase(ctx context.Context, userId, dbName, filePath string) error {
 	sqlStatement := `INSERT INTO databases (owner_id, db_name, file_path) VALUES (?, ?, ?)`
-	_, err := db.ExecContext(ctx, sqlStatement, userId, dbName, filePath)
+	_, err := s.exec(ctx, sqlStatement, userId, dbName, filePath)
 	if err != nil {
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		if s.dialect.constraintViolation(err, "") {
 			// Could be UNIQUE(user_id, db_name) or UNIQUE(file_path)
-			customLog.Warnf("Storage: Constraint violation registering DB '%s' for user %s: %v", dbName, userId, err)
+			customLog.Ctx(ctx).Warnf("Storage: Constraint violation registering DB '%s' for user %s: %v", dbName, userId, err)
 			return ErrDatabaseExists // Assume name conflict for user
 		}
-		customLog.Warnf("Storage: Failed to insert database record for UserID %s, DBName '%s': %v", userId, dbName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to insert database record for UserID %s, DBName '%s': %v", userId, dbName, err)
 		return fmt.Errorf("database error registering database: %w", err)
 	}
 	return nil
 }
 
 // FindDatabasePath retrieves the file path for a given user and database name.
-func FindDatabasePath(ctx context.Context, db *sql.DB, userId, dbName string) (string, error) {
+func (s *sqlMetadataStore) FindDatabasePath(ctx context.Context, userId, dbName string) (string, error) {
 	var dbFilePath string
 
-	lookupSQL := `SELECT file_path FROM databases WHERE owner_id = ? AND db_name = ? LIMIT 1`
-	err := db.QueryRowContext(ctx, lookupSQL, userId, dbName).Scan(&dbFilePath)
+	var quarantine string
+
+	lookupSQL := `SELECT file_path, quarantine_reason FROM databases WHERE owner_id = ? AND db_name = ? LIMIT 1`
+	err := s.queryRow(ctx, lookupSQL, userId, dbName).Scan(&dbFilePath, &quarantine)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", ErrDatabaseNotFound
 		}
-		customLog.Warnf("Storage: Error looking up database path for UserID %s, DBName '%s': %v", userId, dbName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up database path for UserID %s, DBName '%s': %v", userId, dbName, err)
 		return "", fmt.Errorf("database error finding database path: %w", err)
 	}
+	if quarantine != "" {
+		return "", fmt.Errorf("%w: %s", ErrDatabaseQuarantined, quarantine)
+	}
 	return dbFilePath, nil
 }
 
-// ListUserDatabases retrieves a list of database names registered by a specific user.
-func ListUserDatabases(ctx context.Context, db *sql.DB, userId string) ([]domain.DatabaseMetadata, error) {
-	query := `SELECT * FROM databases WHERE owner_id = ? ORDER BY db_name;`
-	rows, err := db.QueryContext(ctx, query, userId)
+// ListUserDatabases retrieves a page of the databases registered by a specific user,
+// ordered by name, with their labels, narrowed by opts. Only the databases of the page
+// are opened to count their tables.
+func (s *sqlMetadataStore) ListUserDatabases(ctx context.Context, userId string, opts DatabaseListOptions) ([]domain.DatabaseMetadata, PaginationMeta, error) {
+	pagination := PaginationMeta{Limit: opts.Limit, Offset: opts.Offset}
+	labels, err := s.listUserDatabaseLabels(ctx, userId)
 	if err != nil {
-		customLog.Warnf("Storage: Error listing databases for UserID %s: %v", userId, err)
-		return nil, fmt.Errorf("database error listing databases: %w", err)
+		return nil, pagination, err
+	}
+
+	filter := `owner_id = ?`
+	args := []any{userId}
+	if opts.Search != "" {
+		filter += ` AND LOWER(db_name) LIKE ? ESCAPE '\'`
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(opts.Search))+"%")
+	}
+	labelConditions, labelArgs := labelFilter(opts.Labels)
+	filter += labelConditions
+	args = append(args, labelArgs...)
+
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM databases WHERE `+filter, args...).Scan(&pagination.Total); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error counting databases for UserID %s: %v", userId, err)
+		return nil, pagination, fmt.Errorf("database error counting databases: %w", err)
+	}
+
+	query := `SELECT ` + databaseColumns + `
+		FROM databases WHERE ` + filter + ` ORDER BY db_name`
+	if opts.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, opts.Limit, opts.Offset)
+	}
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error listing databases for UserID %s: %v", userId, err)
+		return nil, pagination, fmt.Errorf("database error listing databases: %w", err)
 	}
 	defer rows.Close()
 
@@ -179,59 +237,113 @@ func ListUserDatabases(ctx context.Context, db *sql.DB, userId string) ([]domain
 
 	for rows.Next() {
 		var singleDb domain.DatabaseMetadata
-		if err := rows.Scan(&singleDb.DatabaseID, &singleDb.UserID, &singleDb.DBName, &singleDb.FilePath, &singleDb.CreatedAt); err != nil {
-			customLog.Warnf("Storage: Error scanning database name for UserID %s: %v", userId, err)
-			return nil, fmt.Errorf("failed processing database list: %w", err)
-		}
-
-		userSingleDb, err := ConnectUserDB(ctx, singleDb.FilePath)
-		if err != nil {
-			customLog.Warnf("Error opening database %s of user %s", singleDb.DBName, userId)
-			continue
-			// return nil, ErrTableNotFound
+		var expiresAt int64
+		if err := rows.Scan(&singleDb.DatabaseID, &singleDb.UserID, &singleDb.DBName, &singleDb.FilePath, &singleDb.DataRoot, &singleDb.Placement, &singleDb.Quarantine, &expiresAt, &singleDb.CreatedAt); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Error scanning database name for UserID %s: %v", userId, err)
+			return nil, pagination, fmt.Errorf("failed processing database list: %w", err)
 		}
-
-		if err := userSingleDb.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table';").Scan(&singleDb.Tables); err != nil {
-			customLog.Warnf("Error counting tables in %s: %v\n", singleDb.FilePath, err)
-			userSingleDb.Close()
-			continue
+		singleDb.ExpiresAt = databaseExpiry(expiresAt)
+
+		// A quarantined database's file is not opened; it may be missing or broken
+		if singleDb.Quarantine == "" {
+			singleDb.Tables, err = countUserTables(ctx, singleDb.FilePath)
+			if err != nil {
+				customLog.Ctx(ctx).Warnf("Error counting tables in %s of user %s: %v", singleDb.DBName, userId, err)
+				continue
+			}
 		}
-		userSingleDb.Close()
 
-		apiKey, err := FindAPIKeyByDatabaseId(ctx, db, singleDb.DatabaseID)
+		apiKey, err := s.FindAPIKeyByDatabaseId(ctx, singleDb.DatabaseID)
 		if err != nil {
-			customLog.Warnf("Error in retrieving api keys for %s: %v", singleDb.DBName, err)
+			customLog.Ctx(ctx).Warnf("Error in retrieving api keys for %s: %v", singleDb.DBName, err)
 		}
 
 		singleDb.APIKey = apiKey
+		singleDb.Labels = labels[singleDb.DatabaseID]
+		if singleDb.Labels == nil {
+			singleDb.Labels = make(map[string]string)
+		}
 		userDb = append(userDb, singleDb)
 	}
 	if err = rows.Err(); err != nil {
-		customLog.Warnf("Storage: Error iterating database list for UserID %s: %v", userId, err)
-		return nil, fmt.Errorf("failed reading database list: %w", err)
+		customLog.Ctx(ctx).Warnf("Storage: Error iterating database list for UserID %s: %v", userId, err)
+		return nil, pagination, fmt.Errorf("failed reading database list: %w", err)
 	}
 
 	// Return empty slice if user has no databases, not an error
 	if userDb == nil {
 		userDb = make([]domain.DatabaseMetadata, 0)
 	}
-	return userDb, nil
+	return userDb, pagination, nil
+}
+
+// ListAllDatabases retrieves every registered database across all users.
+// Used by background jobs; it does not open the database files.
+func (s *sqlMetadataStore) ListAllDatabases(ctx context.Context) ([]domain.DatabaseMetadata, error) {
+	return s.listDatabases(ctx, `SELECT `+databaseColumns+` FROM databases ORDER BY database_id;`)
+}
+
+// ListUserDatabaseRegistrations retrieves every database registered by a user, from
+// metadata only: unlike ListUserDatabases it neither opens nor skips unreadable files.
+func (s *sqlMetadataStore) ListUserDatabaseRegistrations(ctx context.Context, userId string) ([]domain.DatabaseMetadata, error) {
+	return s.listDatabases(ctx, `SELECT `+databaseColumns+` FROM databases WHERE owner_id = ? ORDER BY db_name;`, userId)
+}
+
+// ListExpiredDatabases retrieves the ephemeral databases of every user that expired by now.
+func (s *sqlMetadataStore) ListExpiredDatabases(ctx context.Context, now time.Time) ([]domain.DatabaseMetadata, error) {
+	return s.listDatabases(ctx, `SELECT `+databaseColumns+` FROM databases WHERE expires_at > 0 AND expires_at <= ? ORDER BY database_id;`, now.Unix())
+}
+
+// databaseColumns are the columns of databases listDatabases scans.
+const databaseColumns = `database_id, owner_id, db_name, file_path, data_root, placement, quarantine_reason, expires_at, created_at`
+
+func (s *sqlMetadataStore) listDatabases(ctx context.Context, query string, args ...any) ([]domain.DatabaseMetadata, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error listing databases: %v", err)
+		return nil, fmt.Errorf("database error listing databases: %w", err)
+	}
+	defer rows.Close()
+
+	databases := make([]domain.DatabaseMetadata, 0)
+	for rows.Next() {
+		var singleDb domain.DatabaseMetadata
+		var expiresAt int64
+		if err := rows.Scan(&singleDb.DatabaseID, &singleDb.UserID, &singleDb.DBName, &singleDb.FilePath, &singleDb.DataRoot, &singleDb.Placement, &singleDb.Quarantine, &expiresAt, &singleDb.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed processing database list: %w", err)
+		}
+		singleDb.ExpiresAt = databaseExpiry(expiresAt)
+		databases = append(databases, singleDb)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading database list: %w", err)
+	}
+	return databases, nil
+}
+
+// databaseExpiry converts databases.expires_at, nil for a database kept until deleted.
+func databaseExpiry(expiresAt int64) *time.Time {
+	if expiresAt == 0 {
+		return nil
+	}
+	t := time.Unix(expiresAt, 0).UTC()
+	return &t
 }
 
 // DeleteDatabaseRegistration removes the database entry from the metadata table.
 // It returns ErrDatabaseNotFound if no matching entry was found.
-func DeleteDatabaseRegistration(ctx context.Context, db *sql.DB, userId, dbName string) error {
+func (s *sqlMetadataStore) DeleteDatabaseRegistration(ctx context.Context, userId, dbName string) error {
 	deleteSQL := `DELETE FROM databases WHERE owner_id = ? AND db_name = ?;`
-	result, err := db.ExecContext(ctx, deleteSQL, userId, dbName)
+	result, err := s.exec(ctx, deleteSQL, userId, dbName)
 	if err != nil {
 		// Likely a connection or syntax issue, not "not found"
-		customLog.Warnf("Storage: Error executing delete registration for UserID %s, DB '%s': %v", userId, dbName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error executing delete registration for UserID %s, DB '%s': %v", userId, dbName, err)
 		return fmt.Errorf("database error deleting registration: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		customLog.Warnf("Storage: Error getting RowsAffected for delete registration UserID %s, DB '%s': %v", userId, dbName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error getting RowsAffected for delete registration UserID %s, DB '%s': %v", userId, dbName, err)
 		return fmt.Errorf("failed confirming registration deletion: %w", err)
 	}
 
@@ -245,28 +357,106 @@ func DeleteDatabaseRegistration(ctx context.Context, db *sql.DB, userId, dbName
 
 // FindDatabaseIDByNameAndUser retrieves the ID of a database owned by a specific user.
 // Returns the database ID or ErrDatabaseNotFound if no match.
-func FindDatabaseIDByNameAndUser(ctx context.Context, db *sql.DB, userId, dbName string) (int64, error) {
+func (s *sqlMetadataStore) FindDatabaseIDByNameAndUser(ctx context.Context, userId, dbName string) (int64, error) {
 	var databaseId int64
 	query := `SELECT database_id FROM databases WHERE owner_id = ? AND db_name = ? LIMIT 1;`
-	err := db.QueryRowContext(ctx, query, userId, dbName).Scan(&databaseId)
+	err := s.queryRow(ctx, query, userId, dbName).Scan(&databaseId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrDatabaseNotFound // Specific error
 		}
-		customLog.Warnf("Storage: Error finding database ID for UserID %s, DB '%s': %v", userId, dbName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error finding database ID for UserID %s, DB '%s': %v", userId, dbName, err)
 		return 0, fmt.Errorf("database error finding database ID: %w", err)
 	}
 	return databaseId, nil
 }
 
+// FindDatabaseByID retrieves the registration of a database by its ID, for requests
+// that address a database without its owner (e.g. public form submissions).
+// Returns ErrDatabaseNotFound if no match.
+func (s *sqlMetadataStore) FindDatabaseByID(ctx context.Context, databaseId int64) (*domain.DatabaseMetadata, error) {
+	return s.findDatabase(ctx, `database_id = ?`, databaseId)
+}
+
+// FindDatabaseByLocation retrieves the registration of the database stored at location,
+// for work that only knows the storage (e.g. record change notifications).
+// Returns ErrDatabaseNotFound if no match.
+func (s *sqlMetadataStore) FindDatabaseByLocation(ctx context.Context, location string) (*domain.DatabaseMetadata, error) {
+	return s.findDatabase(ctx, `file_path = ?`, location)
+}
+
+// findDatabase retrieves the registration of the database matching where.
+func (s *sqlMetadataStore) findDatabase(ctx context.Context, where string, arg any) (*domain.DatabaseMetadata, error) {
+	var db domain.DatabaseMetadata
+	var expiresAt int64
+	query := `SELECT ` + databaseColumns + ` FROM databases WHERE ` + where + `;`
+	err := s.queryRow(ctx, query, arg).Scan(&db.DatabaseID, &db.UserID, &db.DBName, &db.FilePath, &db.DataRoot, &db.Placement, &db.Quarantine, &expiresAt, &db.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDatabaseNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error finding database (%s %v): %v", where, arg, err)
+		return nil, fmt.Errorf("database error finding database: %w", err)
+	}
+	db.ExpiresAt = databaseExpiry(expiresAt)
+	return &db, nil
+}
+
+// SetDatabaseLocation records where a database's file is: after placing a new database
+// on a data root, or after moving its file. Returns ErrDatabaseNotFound if no match.
+func (s *sqlMetadataStore) SetDatabaseLocation(ctx context.Context, databaseId int64, filePath, dataRoot, placement string) error {
+	result, err := s.exec(ctx, `UPDATE databases SET file_path = ?, data_root = ?, placement = ? WHERE database_id = ?`,
+		filePath, dataRoot, placement, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update location of DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error updating database location: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDatabaseNotFound
+	}
+	return nil
+}
+
+// SetDatabaseQuarantine quarantines a database for reason, or releases it when reason
+// is empty. Returns ErrDatabaseNotFound if no match.
+func (s *sqlMetadataStore) SetDatabaseQuarantine(ctx context.Context, databaseId int64, reason string) error {
+	result, err := s.exec(ctx, `UPDATE databases SET quarantine_reason = ? WHERE database_id = ?`, reason, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update quarantine of DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error updating database quarantine: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDatabaseNotFound
+	}
+	return nil
+}
+
+// SetDatabaseExpiry makes a database ephemeral, deleted once expiresAt has passed, or
+// keeps it until deleted when expiresAt is zero. Returns ErrDatabaseNotFound if no match.
+func (s *sqlMetadataStore) SetDatabaseExpiry(ctx context.Context, databaseId int64, expiresAt time.Time) error {
+	var unix int64
+	if !expiresAt.IsZero() {
+		unix = expiresAt.Unix()
+	}
+	result, err := s.exec(ctx, `UPDATE databases SET expires_at = ? WHERE database_id = ?`, unix, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update expiry of DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error updating database expiry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDatabaseNotFound
+	}
+	return nil
+}
+
 // StoreAPIKey generates and stores a new API key scoped to a specific user and database.
 // It returns the *full, unhashed* key (prefix + secret) ONCE upon successful creation.
-func StoreAPIKey(ctx context.Context, db *sql.DB, userId string, databaseId int64) (string, error) {
+func (s *sqlMetadataStore) StoreAPIKey(ctx context.Context, userId string, databaseId int64) (string, error) {
 	// Generate cryptographically secure random bytes for the secret
 	randomBytes := make([]byte, apiKeySecretLength)
 	_, err := rand.Read(randomBytes)
 	if err != nil {
-		customLog.Warnf("Storage: Failed to generate random bytes for API key: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to generate random bytes for API key: %v", err)
 		return "", ErrAPIKeyGeneration
 	}
 
@@ -276,12 +466,11 @@ func StoreAPIKey(ctx context.Context, db *sql.DB, userId string, databaseId int6
 	key := authKeyPrefixMeta + secret
 	// Store the prefix, HASHED secret, and other details in the DB
 	insertSQL := `INSERT INTO api_keys (api_owner_id, api_database_id, key) VALUES (?, ?, ?);`
-	_, err = db.ExecContext(ctx, insertSQL, userId, databaseId, key)
+	_, err = s.exec(ctx, insertSQL, userId, databaseId, key)
 	if err != nil {
 		// Handle potential constraint violations (e.g., UNIQUE on hashed_key, though collisions are extremely unlikely)
-		customLog.Warnf("Storage: Failed to store API key for UserID %v, DBID %d: %v", userId, databaseId, err)
-		var sqliteErr sqlite3.Error
-		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store API key for UserID %v, DBID %d: %v", userId, databaseId, err)
+		if s.dialect.constraintViolation(err, "") {
 			return "", ErrConflict
 		}
 		return "", fmt.Errorf("database error storing API key: %w", err)
@@ -291,11 +480,11 @@ func StoreAPIKey(ctx context.Context, db *sql.DB, userId string, databaseId int6
 }
 
 // FindAPIKeyByDatabaseId retrieves potential key for a particular user
-func FindAPIKeyByDatabaseId(ctx context.Context, db *sql.DB, databaseId int64) (string, error) {
+func (s *sqlMetadataStore) FindAPIKeyByDatabaseId(ctx context.Context, databaseId int64) (string, error) {
 	query := `SELECT key FROM api_keys WHERE api_database_id = ?;`
-	rows, err := db.QueryContext(ctx, query, databaseId)
+	rows, err := s.query(ctx, query, databaseId)
 	if err != nil {
-		customLog.Warnf("Storage: Error querying API keys by database_id  '%d': %v", databaseId, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error querying API keys by database_id  '%d': %v", databaseId, err)
 		// Don't return specific errors like Not Found here, let middleware handle empty results
 		return "", fmt.Errorf("database error finding API keys: %w", err)
 	}
@@ -304,14 +493,14 @@ func FindAPIKeyByDatabaseId(ctx context.Context, db *sql.DB, databaseId int64) (
 	var key string
 	for rows.Next() {
 		if err := rows.Scan(&key); err != nil {
-			customLog.Warnf("Storage: Error scanning API key data with database_id '%d': %v", databaseId, err)
+			customLog.Ctx(ctx).Warnf("Storage: Error scanning API key data with database_id '%d': %v", databaseId, err)
 			// Return potentially partial results or an error? Let's return error.
 			return "", fmt.Errorf("failed processing API key data: %w", err)
 		}
 
 	}
 	if err = rows.Err(); err != nil {
-		customLog.Warnf("Storage: Error iterating API key results wuth database_id '%d': %v", databaseId, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error iterating API key results wuth database_id '%d': %v", databaseId, err)
 		return "", fmt.Errorf("failed reading API key data: %w", err)
 	}
 
@@ -319,20 +508,67 @@ func FindAPIKeyByDatabaseId(ctx context.Context, db *sql.DB, databaseId int64) (
 	return key, nil
 }
 
-// DeleteAPIKey deletes the api key from the database
-func DeleteAPIKey(ctx context.Context, db *sql.DB, key string) error {
-	deleteSQL := `DELETE FROM api_keys WHERE key = ?`
+// FindAPIKeyOwner resolves an API key to the database it was issued for and that
+// database's owner. Returns ErrAPIKeyNotFound for unknown keys.
+func (s *sqlMetadataStore) FindAPIKeyOwner(ctx context.Context, key string) (int64, string, error) {
+	query := `SELECT api_database_id, api_owner_id FROM api_keys WHERE key = ?` //nolint:gosec // G101 false positive - not credentials
+	var databaseId int64
+	var userId string
+	err := s.queryRow(ctx, query, key).Scan(&databaseId, &userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", ErrAPIKeyNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up API key owner: %v", err)
+		return 0, "", fmt.Errorf("database error finding API key: %w", err)
+	}
+	return databaseId, userId, nil
+}
+
+// FindAPIKeyByID returns an API key with the database it was issued for and that
+// database's owner, for clients that sign requests with the key instead of sending it.
+// Returns ErrAPIKeyNotFound for unknown IDs.
+func (s *sqlMetadataStore) FindAPIKeyByID(ctx context.Context, apiKeyId int64) (string, int64, string, error) {
+	query := `SELECT key, api_database_id, api_owner_id FROM api_keys WHERE api_key_id = ?` //nolint:gosec // G101 false positive - not credentials
+	var key, userId string
+	var databaseId int64
+	err := s.queryRow(ctx, query, apiKeyId).Scan(&key, &databaseId, &userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", 0, "", ErrAPIKeyNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up API key %d: %v", apiKeyId, err)
+		return "", 0, "", fmt.Errorf("database error finding API key: %w", err)
+	}
+	return key, databaseId, userId, nil
+}
 
-	result, err := db.ExecContext(ctx, deleteSQL, key)
+// DeleteAPIKey deletes the api key from the database. The key's hash is kept in
+// revoked_api_keys so later attempts to use it can be reported (see FindRevokedAPIKey).
+func (s *sqlMetadataStore) DeleteAPIKey(ctx context.Context, key string) error {
+	tx, err := s.beginTx(ctx)
 	if err != nil {
+		return fmt.Errorf("database error deleting api key: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	revokeSQL := s.dialect.rebind(`INSERT INTO revoked_api_keys (key_hash, database_id, owner_id)
+		SELECT ?, api_database_id, api_owner_id FROM api_keys WHERE key = ?
+		ON CONFLICT (key_hash) DO NOTHING`)
+	if _, err := tx.ExecContext(ctx, revokeSQL, apiKeyHash(key), key); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error recording revoked api key: %v", err)
+		return fmt.Errorf("database error revoking api key: %w", err)
+	}
 
-		customLog.Warnf("Storage: Error executing delete api key : %s, DB ", key)
+	result, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM api_keys WHERE key = ?`), key)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error executing delete api key: %v", err)
 		return fmt.Errorf("database error deleting registration: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		customLog.Warnf("Storage: Error getting RowsAffected for delete api key : %s", key)
+		customLog.Ctx(ctx).Warnf("Storage: Error getting RowsAffected for delete api key: %v", err)
 		return fmt.Errorf("failed confirming registration deletion: %w", err)
 	}
 
@@ -341,5 +577,8 @@ func DeleteAPIKey(ctx context.Context, db *sql.DB, key string) error {
 		return ErrAPIKeyNotFound
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error deleting api key: %w", err)
+	}
 	return nil // Success
 }
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestReplacePasswordHash(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "old"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	if replaced, err := store.ReplacePasswordHash(ctx, "u1", "old", "new"); err != nil || !replaced {
+		t.Fatalf("ReplacePasswordHash(old) = %v, %v; want true", replaced, err)
+	}
+	// The hash changed since it was read: the swap does not overwrite it
+	if replaced, err := store.ReplacePasswordHash(ctx, "u1", "old", "newer"); err != nil || replaced {
+		t.Fatalf("ReplacePasswordHash(stale) = %v, %v; want false", replaced, err)
+	}
+	user, err := store.FindUserByUserId(ctx, "u1")
+	if err != nil {
+		t.Fatalf("FindUserByUserId: %v", err)
+	}
+	if user.PasswordHash != "new" {
+		t.Errorf("password hash = %q, want new", user.PasswordHash)
+	}
+}
+
+func TestPublishableKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "hash"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "app", filepath.Join(t.TempDir(), "app.db")); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseId, err := store.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+	if err != nil {
+		t.Fatalf("FindDatabaseIDByNameAndUser: %v", err)
+	}
+
+	value, err := store.CreatePublishableKey(ctx, domain.PublishableKey{
+		ID: "k1", DatabaseID: databaseId, Tables: []string{"posts", "tags"}, AllowedOrigins: []string{"https://app.example.com"},
+	})
+	if err != nil || !strings.HasPrefix(value, PublishableKeyPrefix) {
+		t.Fatalf("CreatePublishableKey = %q, %v", value, err)
+	}
+	key, err := store.FindPublishableKey(ctx, value)
+	if err != nil {
+		t.Fatalf("FindPublishableKey: %v", err)
+	}
+	if key.ID != "k1" || key.OwnerID != "u1" || key.DatabaseID != databaseId || !reflect.DeepEqual(key.Tables, []string{"posts", "tags"}) {
+		t.Errorf("FindPublishableKey = %+v", key)
+	}
+	if keys, err := store.ListPublishableKeys(ctx, databaseId); err != nil || len(keys) != 1 || keys[0].Key != value {
+		t.Errorf("ListPublishableKeys = %+v, %v", keys, err)
+	}
+
+	if err := store.DeletePublishableKey(ctx, databaseId, "k1"); err != nil {
+		t.Fatalf("DeletePublishableKey: %v", err)
+	}
+	if _, err := store.FindPublishableKey(ctx, value); !errors.Is(err, ErrPublishableKeyNotFound) {
+		t.Errorf("FindPublishableKey after delete error = %v, want ErrPublishableKeyNotFound", err)
+	}
+	if err := store.DeletePublishableKey(ctx, databaseId, "k1"); !errors.Is(err, ErrPublishableKeyNotFound) {
+		t.Errorf("DeletePublishableKey twice error = %v, want ErrPublishableKeyNotFound", err)
+	}
+}
@@ -0,0 +1,287 @@
+// internal/storage/metadata_store.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// Metadata store backends, selected with METADATA_BACKEND.
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+// MetadataStore holds users, database registrations and API keys. The SQLite
+// implementation keeps them in a local file; the Postgres one lets several server
+// instances share them. Methods return the sentinel errors declared in
+// metadata_storage.go.
+type MetadataStore interface {
+	// Users
+	CreateUser(ctx context.Context, userId, username, email, passwordHash string) (string, error)
+	FindUserByEmail(ctx context.Context, email string) (*domain.UserMetadata, error)
+	FindUserByUserId(ctx context.Context, userId string) (*domain.UserMetadata, error)
+	UpdateUser(ctx context.Context, userId, username, email string) error
+	SetUserRole(ctx context.Context, userId, role string) error
+	ReplacePasswordHash(ctx context.Context, userId, oldHash, newHash string) (bool, error)
+
+	// Database registrations
+	RegisterDatabase(ctx context.Context, userId, dbName, filePath string) error
+	FindDatabasePath(ctx context.Context, userId, dbName string) (string, error)
+	FindDatabaseIDByNameAndUser(ctx context.Context, userId, dbName string) (int64, error)
+	FindDatabaseByID(ctx context.Context, databaseId int64) (*domain.DatabaseMetadata, error)
+	FindDatabaseByLocation(ctx context.Context, location string) (*domain.DatabaseMetadata, error)
+	ListUserDatabases(ctx context.Context, userId string, opts DatabaseListOptions) ([]domain.DatabaseMetadata, PaginationMeta, error)
+	ListAllDatabases(ctx context.Context) ([]domain.DatabaseMetadata, error)
+	ListUserDatabaseRegistrations(ctx context.Context, userId string) ([]domain.DatabaseMetadata, error)
+	ListExpiredDatabases(ctx context.Context, now time.Time) ([]domain.DatabaseMetadata, error)
+	DeleteDatabaseRegistration(ctx context.Context, userId, dbName string) error
+
+	// API keys
+	StoreAPIKey(ctx context.Context, userId string, databaseId int64) (string, error)
+	FindAPIKeyByDatabaseId(ctx context.Context, databaseId int64) (string, error)
+	FindAPIKeyOwner(ctx context.Context, key string) (int64, string, error)
+	FindAPIKeyByID(ctx context.Context, apiKeyId int64) (string, int64, string, error)
+	DeleteAPIKey(ctx context.Context, key string) error
+	RecordAPIKeyCountry(ctx context.Context, key, country string) (bool, error)
+	FindRevokedAPIKey(ctx context.Context, key string) (int64, string, time.Time, error)
+	FindAPIKeyID(ctx context.Context, databaseId int64) (int64, error)
+
+	// API traffic of API keys
+	AddAPIKeyUsage(ctx context.Context, databaseId int64, usage []domain.APIKeyUsage, clients []domain.APIKeyClient) error
+	ListAPIKeyUsage(ctx context.Context, apiKeyId int64, since string) ([]domain.APIKeyUsage, error)
+	ListAPIKeyClients(ctx context.Context, apiKeyId int64, limit int) ([]domain.APIKeyClient, error)
+
+	// Column validation rules of user tables
+	ListColumnRules(ctx context.Context, databaseId int64, tableName string) ([]domain.ColumnRule, error)
+	ReplaceColumnRules(ctx context.Context, databaseId int64, tableName string, rules []domain.ColumnRule) error
+
+	// JSON Schemas of user tables (see internal/jsonschema)
+	GetTableJSONSchema(ctx context.Context, databaseId int64, tableName string) (string, error)
+	SetTableJSONSchema(ctx context.Context, databaseId int64, tableName, schema string) error
+	DeleteTableJSONSchema(ctx context.Context, databaseId int64, tableName string) error
+
+	// JavaScript hooks of user tables (see internal/scripting)
+	GetTableScript(ctx context.Context, databaseId int64, tableName string) (string, error)
+	SetTableScript(ctx context.Context, databaseId int64, tableName, source string) error
+	DeleteTableScript(ctx context.Context, databaseId int64, tableName string) error
+
+	// Behaviour switches of user tables
+	GetTableOptions(ctx context.Context, databaseId int64, tableName string) (domain.TableOptions, error)
+	SetTableOptions(ctx context.Context, databaseId int64, tableName string, options domain.TableOptions) error
+	DeleteTableOptions(ctx context.Context, databaseId int64, tableName string) error
+
+	// Descriptions of user tables and their columns
+	ListTableDescriptions(ctx context.Context, databaseId int64) (map[string]domain.TableDescription, error)
+	GetTableDescription(ctx context.Context, databaseId int64, tableName string) (domain.TableDescription, error)
+	ReplaceTableDescription(ctx context.Context, databaseId int64, tableName string, description domain.TableDescription) error
+
+	// Labels of user databases
+	GetDatabaseLabels(ctx context.Context, databaseId int64) (map[string]string, error)
+	ReplaceDatabaseLabels(ctx context.Context, databaseId int64, labels map[string]string) error
+
+	// Daily API usage of user databases
+	AddDatabaseUsage(ctx context.Context, databaseId int64, usage domain.DatabaseUsage) error
+	ListDatabaseUsage(ctx context.Context, databaseId int64, since string) ([]domain.DatabaseUsage, error)
+
+	// Saved queries of user databases
+	ListSavedQueries(ctx context.Context, databaseId int64) ([]domain.SavedQuery, error)
+	GetSavedQuery(ctx context.Context, databaseId int64, name string) (*domain.SavedQuery, error)
+	PutSavedQuery(ctx context.Context, databaseId int64, query domain.SavedQuery) error
+	DeleteSavedQuery(ctx context.Context, databaseId int64, name string) error
+
+	// Webhooks of user databases (see internal/webhooks)
+	ListWebhooks(ctx context.Context, databaseId int64) ([]domain.Webhook, error)
+	GetWebhook(ctx context.Context, databaseId int64, webhookId string) (*domain.Webhook, error)
+	CreateWebhook(ctx context.Context, hook domain.Webhook) error
+	UpdateWebhook(ctx context.Context, hook domain.Webhook) error
+	SetWebhookSecret(ctx context.Context, databaseId int64, webhookId, secret string) error
+	DeleteWebhook(ctx context.Context, databaseId int64, webhookId string) error
+	RecordWebhookDelivery(ctx context.Context, webhookId string, status int, deliveryErr string, at time.Time) error
+
+	// Public submission forms of user tables (see internal/forms)
+	ListForms(ctx context.Context, databaseId int64) ([]domain.Form, error)
+	GetForm(ctx context.Context, databaseId int64, formId string) (*domain.Form, error)
+	FindForm(ctx context.Context, formId string) (*domain.Form, error)
+	CreateForm(ctx context.Context, form domain.Form) error
+	UpdateForm(ctx context.Context, form domain.Form) error
+	DeleteForm(ctx context.Context, databaseId int64, formId string) error
+
+	// Read-only share links of tables and saved queries
+	ListShareLinks(ctx context.Context, databaseId int64) ([]domain.ShareLink, error)
+	FindShareLink(ctx context.Context, shareId string) (*domain.ShareLink, error)
+	CreateShareLink(ctx context.Context, link domain.ShareLink) error
+	DeleteShareLink(ctx context.Context, databaseId int64, shareId string) error
+	DeleteExpiredShareLinks(ctx context.Context, databaseId int64, now time.Time) error
+
+	// Browser-safe keys that read some tables of a database from allowed origins
+	ListPublishableKeys(ctx context.Context, databaseId int64) ([]domain.PublishableKey, error)
+	FindPublishableKey(ctx context.Context, key string) (*domain.PublishableKey, error)
+	CreatePublishableKey(ctx context.Context, key domain.PublishableKey) (string, error)
+	DeletePublishableKey(ctx context.Context, databaseId int64, keyId string) error
+
+	// Right to erasure: account removal and the tombstones it leaves
+	EraseUser(ctx context.Context, report *domain.ErasureReport, email string) error
+	ListErasures(ctx context.Context) ([]domain.ErasureReport, error)
+	FindErasure(ctx context.Context, erasureId string) (*domain.ErasureReport, error)
+
+	// Read replicas of read-heavy user databases
+	ListReadReplicas(ctx context.Context) ([]domain.ReadReplica, error)
+	SetReadReplica(ctx context.Context, databaseId int64, maxLagSeconds int) error
+	DeleteReadReplica(ctx context.Context, databaseId int64) error
+
+	// Placement of user database files across data roots
+	SetDatabaseLocation(ctx context.Context, databaseId int64, filePath, dataRoot, placement string) error
+
+	// Databases refused to requests after the consistency check found them broken
+	SetDatabaseQuarantine(ctx context.Context, databaseId int64, reason string) error
+	SetDatabaseExpiry(ctx context.Context, databaseId int64, expiresAt time.Time) error
+
+	// Deleted databases and dropped tables awaiting restore or purge
+	AddTrashItem(ctx context.Context, item domain.TrashItem) (int64, error)
+	ListTrashItems(ctx context.Context, userId string) ([]domain.TrashItem, error)
+	ListExpiredTrashItems(ctx context.Context, now time.Time) ([]domain.TrashItem, error)
+	GetTrashItem(ctx context.Context, userId string, trashId int64) (*domain.TrashItem, error)
+	DeleteTrashItem(ctx context.Context, trashId int64) error
+
+	// Security audit log (see internal/anomaly)
+	AddAuditEvent(ctx context.Context, event domain.AuditEvent) (int64, error)
+	ListAuditEvents(ctx context.Context, userId string, limit, offset int) ([]domain.AuditEvent, PaginationMeta, error)
+
+	// Mail outbox (see internal/mail)
+	EnqueueMail(ctx context.Context, mail domain.OutboxMail) (int64, error)
+	ClaimDueMail(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxMail, error)
+	MarkMailSent(ctx context.Context, mailID int64) error
+	RetryMail(ctx context.Context, mailID int64, lastError string, retryAt time.Time) error
+
+	// Lifecycle
+	SchemaVersion(ctx context.Context) (int, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// dialect captures the SQL differences between the metadata backends.
+type dialect struct {
+	name string
+	// rebind rewrites "?" placeholders for the driver
+	rebind func(query string) string
+	// constraintViolation reports whether err is a constraint violation, and if
+	// column ("table.column") is set, whether it is a unique violation on that column.
+	constraintViolation func(err error, column string) bool
+}
+
+var sqliteDialect = dialect{
+	name:   BackendSQLite,
+	rebind: func(query string) string { return query },
+	constraintViolation: func(err error, column string) bool {
+		var sqliteErr sqlite3.Error
+		if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+			return false
+		}
+		return column == "" || (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(sqliteErr.Error(), column))
+	},
+}
+
+var postgresDialect = dialect{
+	name: BackendPostgres,
+	rebind: func(query string) string {
+		var sb strings.Builder
+		n := 0
+		for _, r := range query {
+			if r == '?' {
+				n++
+				sb.WriteString("$" + strconv.Itoa(n))
+				continue
+			}
+			sb.WriteRune(r)
+		}
+		return sb.String()
+	},
+	constraintViolation: func(err error, column string) bool {
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) || pqErr.Code.Class() != "23" {
+			return false
+		}
+		// Postgres names implicit unique constraints <table>_<column>_key
+		return column == "" || (pqErr.Code == "23505" && strings.HasPrefix(pqErr.Constraint, strings.ReplaceAll(column, ".", "_")))
+	},
+}
+
+// sqlMetadataStore implements MetadataStore on database/sql for any dialect. Its calls
+// go through breaker (see metadata_breaker.go).
+type sqlMetadataStore struct {
+	db      *sql.DB
+	dialect dialect
+	breaker *circuitBreaker
+}
+
+func (s *sqlMetadataStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	probe, err := s.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+	s.breaker.record(probe, err)
+	return result, err
+}
+
+func (s *sqlMetadataStore) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	probe, err := s.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	s.breaker.record(probe, err)
+	return rows, err
+}
+
+func (s *sqlMetadataStore) queryRow(ctx context.Context, query string, args ...any) *metadataRow {
+	probe, err := s.breaker.allow()
+	if err != nil {
+		return &metadataRow{err: err}
+	}
+	return &metadataRow{row: s.db.QueryRowContext(ctx, s.dialect.rebind(query), args...), probe: probe, b: s.breaker}
+}
+
+func (s *sqlMetadataStore) beginTx(ctx context.Context) (*sql.Tx, error) {
+	probe, err := s.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	s.breaker.record(probe, err)
+	return tx, err
+}
+
+// Ping verifies the connection to the metadata database. It fails at once while the
+// circuit breaker is open.
+func (s *sqlMetadataStore) Ping(ctx context.Context) error {
+	probe, err := s.breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = s.db.PingContext(ctx)
+	s.breaker.record(probe, err)
+	return err
+}
+
+// Close closes the connection pool.
+func (s *sqlMetadataStore) Close() error {
+	return s.db.Close()
+}
+
+// SchemaVersion returns the highest applied metadata migration.
+func (s *sqlMetadataStore) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.queryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
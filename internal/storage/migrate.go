@@ -0,0 +1,142 @@
+// internal/storage/migrate.go
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata schema migrations, applied in version order by ConnectMetadataDB. Each
+// backend has its own directory (migrations/<backend>) with the same versions.
+// Files are named NNNN_description.sql; shipped migrations must never be edited,
+// add a new file instead.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations reads the embedded migrations of a backend and checks that versions
+// are contiguous.
+func loadMigrations(backend string) ([]migration, error) {
+	dir := path.Join("migrations", backend)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name '%s'", entry.Name())
+		}
+		content, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{Version: version, Name: name, SQL: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, found %d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// migrateMetadataDB brings the metadata schema up to date, applying each pending
+// migration in its own transaction.
+func migrateMetadataDB(ctx context.Context, s *sqlMetadataStore) error {
+	migrations, err := loadMigrations(s.dialect.name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.exec(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current == 0 && s.dialect.name == BackendSQLite {
+		if current, err = baselineLegacySchema(ctx, s, migrations); err != nil {
+			return err
+		}
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("metadata schema version %d is newer than this binary supports (%d)", current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		if err := applyMigration(ctx, s, m); err != nil {
+			return err
+		}
+		customLog.Ctx(ctx).Printf("Storage: Applied metadata migration %04d_%s", m.Version, m.Name)
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, s *sqlMetadataStore, m migration) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.Version, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
+
+// baselineLegacySchema records the migrations already reflected in a SQLite metadata
+// DB created before schema_migrations existed, so they are not applied twice. It
+// returns the resulting version (0 for a fresh database).
+func baselineLegacySchema(ctx context.Context, s *sqlMetadataStore, migrations []migration) (int, error) {
+	db := s.db
+	var tables int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to inspect legacy schema: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	version := 1 // users, databases and api_keys
+	var roleColumns int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('users') WHERE name = 'role'`).Scan(&roleColumns); err != nil {
+		return 0, fmt.Errorf("failed to inspect legacy schema: %w", err)
+	}
+	if roleColumns > 0 {
+		version = 2
+	}
+
+	for _, m := range migrations[:version] {
+		if _, err := db.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+			return 0, fmt.Errorf("failed to baseline migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	customLog.Ctx(ctx).Printf("Storage: Baselined existing metadata schema at version %d", version)
+	return version, nil
+}
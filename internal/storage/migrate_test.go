@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestMigrateMetadataDB(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := loadMigrations(BackendSQLite)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	latest := len(migrations)
+
+	t.Run("fresh", func(t *testing.T) {
+		db, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+		if err != nil {
+			t.Fatalf("ConnectMetadataDB: %v", err)
+		}
+		defer db.Close()
+		if version, err := db.SchemaVersion(ctx); err != nil || version != latest {
+			t.Fatalf("version = %d, %v; want %d", version, err, latest)
+		}
+		// Running again is a no-op
+		if err := migrateMetadataDB(ctx, db.(*sqlMetadataStore)); err != nil {
+			t.Fatalf("second migrate: %v", err)
+		}
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy, err := sql.Open("sqlite3", filepath.Join(dir, "meta.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A metadata DB created by the CREATE TABLE IF NOT EXISTS code, before roles existed
+		if _, err := legacy.Exec(migrations[0].SQL + `INSERT INTO users (user_id, username, email, password_hash) VALUES ('u1', 'old', 'old@example.com', 'x');`); err != nil {
+			t.Fatalf("seed legacy schema: %v", err)
+		}
+		legacy.Close()
+
+		db, err := ConnectMetadataDB(&config.Config{MetadataDbDir: dir, MetadataDbFile: "meta.db"})
+		if err != nil {
+			t.Fatalf("ConnectMetadataDB: %v", err)
+		}
+		defer db.Close()
+		user, err := db.FindUserByUserId(ctx, "u1")
+		if err != nil || user.Role != "user" {
+			t.Fatalf("legacy user = %+v, %v; want role 'user'", user, err)
+		}
+		if version, _ := db.SchemaVersion(ctx); version != latest {
+			t.Fatalf("version = %d, want %d", version, latest)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+-- Users, their registered databases and database-scoped API keys.
+CREATE TABLE IF NOT EXISTS users (
+	user_id TEXT PRIMARY KEY,
+	username TEXT NOT NULL,
+	email TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS databases (
+	database_id BIGSERIAL PRIMARY KEY,
+	owner_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	db_name TEXT NOT NULL,
+	file_path TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (owner_id, db_name)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	api_key_id BIGSERIAL PRIMARY KEY,
+	api_owner_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	api_database_id BIGINT UNIQUE NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	key TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
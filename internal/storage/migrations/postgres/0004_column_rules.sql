@@ -0,0 +1,11 @@
+-- Validation rules enforced on record writes, per user table column (see core.CheckColumnRules).
+CREATE TABLE IF NOT EXISTS column_rules (
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	table_name TEXT NOT NULL,
+	column_name TEXT NOT NULL,
+	pattern TEXT NOT NULL DEFAULT '',
+	format TEXT NOT NULL DEFAULT '',
+	min_value DOUBLE PRECISION,
+	max_value DOUBLE PRECISION,
+	PRIMARY KEY (database_id, table_name, column_name)
+);
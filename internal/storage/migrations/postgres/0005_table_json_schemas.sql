@@ -0,0 +1,8 @@
+-- JSON Schemas record writes are validated against, per user table (see internal/jsonschema).
+CREATE TABLE IF NOT EXISTS table_json_schemas (
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	table_name TEXT NOT NULL,
+	schema TEXT NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, table_name)
+);
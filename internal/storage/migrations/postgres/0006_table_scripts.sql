@@ -0,0 +1,8 @@
+-- JavaScript hooks run around record writes, per user table (see internal/scripting).
+CREATE TABLE IF NOT EXISTS table_scripts (
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	table_name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, table_name)
+);
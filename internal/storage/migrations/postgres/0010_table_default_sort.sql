@@ -0,0 +1,3 @@
+-- Default sort of ListRecords when a request names none ('' sorts by id).
+ALTER TABLE table_options ADD COLUMN IF NOT EXISTS default_sort TEXT NOT NULL DEFAULT '';
+ALTER TABLE table_options ADD COLUMN IF NOT EXISTS default_order TEXT NOT NULL DEFAULT '';
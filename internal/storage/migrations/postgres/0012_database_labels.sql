@@ -0,0 +1,8 @@
+-- Key/value labels organizing the databases of a user.
+CREATE TABLE IF NOT EXISTS database_labels (
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	label_key TEXT NOT NULL,
+	label_value TEXT NOT NULL,
+	PRIMARY KEY (database_id, label_key)
+);
+CREATE INDEX IF NOT EXISTS idx_database_labels_key_value ON database_labels (label_key, label_value);
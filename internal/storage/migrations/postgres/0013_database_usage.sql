@@ -0,0 +1,10 @@
+-- API traffic of user databases per UTC day.
+CREATE TABLE IF NOT EXISTS database_usage (
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	day TEXT NOT NULL, -- YYYY-MM-DD
+	requests BIGINT NOT NULL DEFAULT 0,
+	errors BIGINT NOT NULL DEFAULT 0,
+	bytes_in BIGINT NOT NULL DEFAULT 0,
+	bytes_out BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (database_id, day)
+);
@@ -0,0 +1,28 @@
+-- Security audit log, and the API key history auth anomaly detection compares
+-- requests against (see internal/anomaly).
+CREATE TABLE IF NOT EXISTS audit_log (
+	event_id BIGSERIAL PRIMARY KEY,
+	user_id TEXT REFERENCES users(user_id) ON DELETE CASCADE, -- NULL for events not tied to an account
+	event TEXT NOT NULL,
+	ip TEXT NOT NULL DEFAULT '',
+	details TEXT NOT NULL DEFAULT '{}', -- JSON object of strings
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id, event_id);
+
+-- Countries (ISO codes from the configured proxy header) each API key was used from.
+CREATE TABLE IF NOT EXISTS api_key_countries (
+	api_key_id BIGINT NOT NULL REFERENCES api_keys(api_key_id) ON DELETE CASCADE,
+	country TEXT NOT NULL,
+	first_seen_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (api_key_id, country)
+);
+
+-- Deleted API keys, by SHA-256 hash, so later use of a rotated key can be reported.
+CREATE TABLE IF NOT EXISTS revoked_api_keys (
+	key_hash TEXT PRIMARY KEY,
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	owner_id TEXT NOT NULL,
+	revoked_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
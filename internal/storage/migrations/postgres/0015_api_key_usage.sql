@@ -0,0 +1,19 @@
+-- API traffic of API keys per UTC day and route, and the client addresses they were
+-- used from.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	api_key_id BIGINT NOT NULL REFERENCES api_keys(api_key_id) ON DELETE CASCADE,
+	day TEXT NOT NULL, -- YYYY-MM-DD
+	method TEXT NOT NULL,
+	route TEXT NOT NULL, -- Route pattern, e.g. /api/v1/databases/:db_name/tables
+	requests BIGINT NOT NULL DEFAULT 0,
+	errors BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (api_key_id, day, method, route)
+);
+
+CREATE TABLE IF NOT EXISTS api_key_clients (
+	api_key_id BIGINT NOT NULL REFERENCES api_keys(api_key_id) ON DELETE CASCADE,
+	ip TEXT NOT NULL,
+	requests BIGINT NOT NULL DEFAULT 0,
+	last_seen_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (api_key_id, ip)
+);
@@ -0,0 +1,17 @@
+-- Webhooks notified of writes to a database, managed via /databases/:db_name/webhooks.
+CREATE TABLE IF NOT EXISTS webhooks (
+	webhook_id TEXT PRIMARY KEY,
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	events TEXT NOT NULL DEFAULT '', -- Comma-separated events subscribed to; empty for every event
+	secret TEXT NOT NULL, -- Signs deliveries (HMAC-SHA256)
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	last_status INTEGER, -- HTTP status of the last delivery, 0 when it failed without one
+	last_error TEXT NOT NULL DEFAULT '',
+	last_delivery_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_database ON webhooks (database_id);
@@ -0,0 +1,19 @@
+-- Public submission forms of user tables, managed via /databases/:db_name/forms and
+-- submitted to via /forms/:form_id.
+CREATE TABLE IF NOT EXISTS forms (
+	form_id TEXT PRIMARY KEY,
+	database_id BIGINT NOT NULL REFERENCES databases(database_id) ON DELETE CASCADE,
+	table_name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	columns TEXT NOT NULL, -- Comma-separated writable columns
+	allowed_origins TEXT NOT NULL DEFAULT '', -- Comma-separated; empty for any origin
+	captcha_provider TEXT NOT NULL DEFAULT '', -- hcaptcha, recaptcha or turnstile; empty for none
+	captcha_secret TEXT NOT NULL DEFAULT '',
+	redirect_url TEXT NOT NULL DEFAULT '',
+	rate_limit INTEGER NOT NULL, -- Submissions per client IP and hour
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_forms_database ON forms (database_id);
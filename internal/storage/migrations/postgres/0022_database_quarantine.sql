@@ -0,0 +1,3 @@
+-- Why a database was quarantined by the consistency check ("" when it is not). Requests
+-- to a quarantined database fail until an admin releases it.
+ALTER TABLE databases ADD COLUMN IF NOT EXISTS quarantine_reason TEXT NOT NULL DEFAULT '';
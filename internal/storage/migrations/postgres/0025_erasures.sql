@@ -0,0 +1,11 @@
+-- Tombstones of erased accounts, with the compliance report of what was deleted or
+-- anonymized (JSON counts, no personal data). Written by internal/erasure.
+CREATE TABLE IF NOT EXISTS erasures (
+	erasure_id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL, -- No foreign key: the user is gone
+	requested_by TEXT NOT NULL,
+	report TEXT NOT NULL,
+	erased_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasures_user ON erasures (user_id);
@@ -0,0 +1,28 @@
+-- Users, their registered databases and database-scoped API keys.
+CREATE TABLE IF NOT EXISTS users (
+	user_id TEXT PRIMARY KEY UNIQUE NOT NULL,
+	username TEXT NOT NULL,
+	email TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS databases (
+	database_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id TEXT NOT NULL,
+	db_name TEXT NOT NULL,
+	file_path TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (owner_id, db_name),
+	FOREIGN KEY (owner_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	api_key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	api_owner_id TEXT NOT NULL,
+	api_database_id INTEGER UNIQUE NOT NULL,
+	key TEXT UNIQUE NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (api_owner_id) REFERENCES users(user_id) ON DELETE CASCADE,
+	FOREIGN KEY (api_database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
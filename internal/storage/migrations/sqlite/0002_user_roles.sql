@@ -0,0 +1,2 @@
+-- Roles for the admin endpoints ('user' or 'admin').
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
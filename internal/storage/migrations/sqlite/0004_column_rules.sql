@@ -0,0 +1,12 @@
+-- Validation rules enforced on record writes, per user table column (see core.CheckColumnRules).
+CREATE TABLE IF NOT EXISTS column_rules (
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL,
+	column_name TEXT NOT NULL,
+	pattern TEXT NOT NULL DEFAULT '',
+	format TEXT NOT NULL DEFAULT '',
+	min_value REAL,
+	max_value REAL,
+	PRIMARY KEY (database_id, table_name, column_name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- JSON Schemas record writes are validated against, per user table (see internal/jsonschema).
+CREATE TABLE IF NOT EXISTS table_json_schemas (
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL,
+	schema TEXT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, table_name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- JavaScript hooks run around record writes, per user table (see internal/scripting).
+CREATE TABLE IF NOT EXISTS table_scripts (
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL,
+	source TEXT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, table_name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- Named, parameterized record listings run via /databases/:db_name/queries/:name.
+CREATE TABLE IF NOT EXISTS saved_queries (
+	database_id INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	table_name TEXT NOT NULL,
+	definition TEXT NOT NULL, -- JSON: description, query and params of domain.SavedQuery
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+-- Deleted databases and dropped tables kept restorable until expires_at (a Unix
+-- timestamp, so expired items compare the same on every backend).
+CREATE TABLE IF NOT EXISTS trash (
+	trash_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	owner_id TEXT NOT NULL,
+	db_name TEXT NOT NULL,
+	table_name TEXT NOT NULL DEFAULT '', -- Empty for a deleted database
+	location TEXT NOT NULL, -- Where the database is stored when restored
+	trash_location TEXT NOT NULL, -- Storage of the trashed database, or the trashed table's name
+	deleted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at INTEGER NOT NULL,
+	FOREIGN KEY (owner_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trash_owner ON trash (owner_id);
+CREATE INDEX IF NOT EXISTS idx_trash_expires_at ON trash (expires_at);
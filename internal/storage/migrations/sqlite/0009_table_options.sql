@@ -0,0 +1,9 @@
+-- Per-table behaviour switches of user tables. Tables without a row use the defaults.
+CREATE TABLE IF NOT EXISTS table_options (
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL,
+	auto_columns BOOLEAN NOT NULL DEFAULT FALSE, -- Add columns for unknown keys of created records
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (database_id, table_name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Descriptions of user tables and their columns, for generated docs and admin UIs.
+CREATE TABLE IF NOT EXISTS table_descriptions (
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL,
+	column_name TEXT NOT NULL, -- '' describes the table itself
+	description TEXT NOT NULL,
+	PRIMARY KEY (database_id, table_name, column_name),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Key/value labels organizing the databases of a user.
+CREATE TABLE IF NOT EXISTS database_labels (
+	database_id INTEGER NOT NULL,
+	label_key TEXT NOT NULL,
+	label_value TEXT NOT NULL,
+	PRIMARY KEY (database_id, label_key),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_database_labels_key_value ON database_labels (label_key, label_value);
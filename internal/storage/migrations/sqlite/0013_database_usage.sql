@@ -0,0 +1,11 @@
+-- API traffic of user databases per UTC day.
+CREATE TABLE IF NOT EXISTS database_usage (
+	database_id INTEGER NOT NULL,
+	day TEXT NOT NULL, -- YYYY-MM-DD
+	requests INTEGER NOT NULL DEFAULT 0,
+	errors INTEGER NOT NULL DEFAULT 0,
+	bytes_in INTEGER NOT NULL DEFAULT 0,
+	bytes_out INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (database_id, day),
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,31 @@
+-- Security audit log, and the API key history auth anomaly detection compares
+-- requests against (see internal/anomaly).
+CREATE TABLE IF NOT EXISTS audit_log (
+	event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id TEXT, -- NULL for events not tied to an account
+	event TEXT NOT NULL,
+	ip TEXT NOT NULL DEFAULT '',
+	details TEXT NOT NULL DEFAULT '{}', -- JSON object of strings
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log (user_id, event_id);
+
+-- Countries (ISO codes from the configured proxy header) each API key was used from.
+CREATE TABLE IF NOT EXISTS api_key_countries (
+	api_key_id INTEGER NOT NULL,
+	country TEXT NOT NULL,
+	first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (api_key_id, country),
+	FOREIGN KEY (api_key_id) REFERENCES api_keys(api_key_id) ON DELETE CASCADE
+);
+
+-- Deleted API keys, by SHA-256 hash, so later use of a rotated key can be reported.
+CREATE TABLE IF NOT EXISTS revoked_api_keys (
+	key_hash TEXT PRIMARY KEY,
+	database_id INTEGER NOT NULL,
+	owner_id TEXT NOT NULL,
+	revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,21 @@
+-- API traffic of API keys per UTC day and route, and the client addresses they were
+-- used from.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+	api_key_id INTEGER NOT NULL,
+	day TEXT NOT NULL, -- YYYY-MM-DD
+	method TEXT NOT NULL,
+	route TEXT NOT NULL, -- Route pattern, e.g. /api/v1/databases/:db_name/tables
+	requests INTEGER NOT NULL DEFAULT 0,
+	errors INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (api_key_id, day, method, route),
+	FOREIGN KEY (api_key_id) REFERENCES api_keys(api_key_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS api_key_clients (
+	api_key_id INTEGER NOT NULL,
+	ip TEXT NOT NULL,
+	requests INTEGER NOT NULL DEFAULT 0,
+	last_seen_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (api_key_id, ip),
+	FOREIGN KEY (api_key_id) REFERENCES api_keys(api_key_id) ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+-- Read-only share links of a table or saved query, managed via
+-- /databases/:db_name/shares and opened via /share/:token. The token is signed and
+-- carries the expiry; a link is revoked by deleting its row.
+CREATE TABLE IF NOT EXISTS share_links (
+	share_id TEXT PRIMARY KEY,
+	database_id INTEGER NOT NULL,
+	table_name TEXT NOT NULL DEFAULT '', -- Shared table; empty when a saved query is shared
+	query_name TEXT NOT NULL DEFAULT '', -- Shared saved query; empty when a table is shared
+	description TEXT NOT NULL DEFAULT '',
+	expires_at INTEGER NOT NULL, -- Unix time
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_database ON share_links (database_id);
@@ -0,0 +1,2 @@
+-- Per-table row limit enforced on insert (0 leaves only the server-wide TABLE_MAX_ROWS).
+ALTER TABLE table_options ADD COLUMN max_rows INTEGER NOT NULL DEFAULT 0;
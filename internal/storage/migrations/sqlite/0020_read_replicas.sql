@@ -0,0 +1,8 @@
+-- User databases whose reads are partly served from a read-only replica file, kept
+-- fresh from the primary and designated by admins via /admin/databases/:id/replica.
+CREATE TABLE IF NOT EXISTS database_replicas (
+	database_id INTEGER PRIMARY KEY,
+	max_lag_seconds INTEGER NOT NULL DEFAULT 0, -- How stale replica reads may be; 0 only reads an up-to-date replica
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+-- Data directory each user database file was placed in and the strategy that chose it
+-- ("" for files placed before DATA_ROOTS existed, which live under DATABASE_DIRECTORY).
+ALTER TABLE databases ADD COLUMN data_root TEXT NOT NULL DEFAULT '';
+ALTER TABLE databases ADD COLUMN placement TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- When an ephemeral database is deleted (a Unix timestamp, so expired databases compare
+-- the same on every backend); 0 for databases that are kept until deleted.
+ALTER TABLE databases ADD COLUMN expires_at INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_databases_expires_at ON databases (expires_at);
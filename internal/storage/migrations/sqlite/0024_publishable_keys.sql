@@ -0,0 +1,14 @@
+-- Browser-safe keys of user databases: they only read the listed tables, from the
+-- listed origins. Managed via /account/databases/:db_name/publishable-keys.
+CREATE TABLE IF NOT EXISTS publishable_keys (
+	publishable_key_id TEXT PRIMARY KEY,
+	database_id INTEGER NOT NULL,
+	key TEXT UNIQUE NOT NULL,
+	tables TEXT NOT NULL, -- Comma-separated readable tables
+	allowed_origins TEXT NOT NULL, -- Comma-separated origins the key is accepted from
+	description TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (database_id) REFERENCES databases(database_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_publishable_keys_database ON publishable_keys (database_id);
@@ -0,0 +1,188 @@
+// internal/storage/orphan_files.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// OrphanedFile is a database file on a data root that no registered database uses,
+// e.g. left behind when deleting a database could not remove its file.
+type OrphanedFile struct {
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Removed    bool      `json:"removed,omitempty"`
+	Error      string    `json:"error,omitempty"` // Why removing it failed
+}
+
+// OrphanScan is the result of one CollectOrphanedFiles pass.
+type OrphanScan struct {
+	Scanned  int            `json:"scanned"` // Database files looked at
+	Recent   int            `json:"recent"`  // Unregistered files still within the grace period
+	Orphaned []OrphanedFile `json:"orphaned"`
+	DryRun   bool           `json:"dry_run"`
+}
+
+// CollectOrphanedFiles looks for database files on the data roots that no database is
+// registered at and, unless dryRun, removes them with their -wal and -shm files. Files
+// modified within gracePeriod are left alone: a database being created or moved has
+// its file before its registration points to it. A replica is orphaned with its primary.
+func CollectOrphanedFiles(ctx context.Context, store MetadataStore, gracePeriod time.Duration, dryRun bool) (*OrphanScan, error) {
+	scan := &OrphanScan{Orphaned: []OrphanedFile{}, DryRun: dryRun}
+	if userData.backend == BackendPostgres {
+		return scan, nil // No files
+	}
+	databases, err := store.ListAllDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]bool, len(databases))
+	for _, db := range databases {
+		registered[filepath.Clean(db.FilePath)] = true
+	}
+
+	cutoff := time.Now().Add(-gracePeriod)
+	for _, root := range DataRoots() {
+		paths, err := userDBFiles(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data root '%s': %w", root, err)
+		}
+		for _, path := range paths {
+			scan.Scanned++
+			if registered[path] || registered[primaryOf(path)] || userDBs.isOpen(path) {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue // Removed meanwhile
+			}
+			if info.ModTime().After(cutoff) {
+				scan.Recent++
+				continue
+			}
+			orphan := OrphanedFile{Path: path, Size: info.Size(), ModifiedAt: info.ModTime().UTC()}
+			if !dryRun {
+				if err := removeOrphanedFile(ctx, store, path); err != nil {
+					orphan.Error = err.Error()
+				} else {
+					orphan.Removed = true
+				}
+			}
+			scan.Orphaned = append(scan.Orphaned, orphan)
+		}
+	}
+	return scan, nil
+}
+
+// userDBFiles returns the database files in the account directories of a data root.
+// Trash and other dot directories, and anything deeper (e.g. backups), are skipped.
+func userDBFiles(root string) ([]string, error) {
+	dirs, err := os.ReadDir(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(root, dir.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if file.Type().IsRegular() && strings.HasSuffix(file.Name(), ".db") {
+				paths = append(paths, filepath.Join(root, dir.Name(), file.Name()))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// primaryOf returns the database file a replica file belongs to, or "" for other files.
+func primaryOf(path string) string {
+	if !strings.HasSuffix(path, ".replica.db") {
+		return ""
+	}
+	return strings.TrimSuffix(path, ".replica.db") + ".db"
+}
+
+// removeOrphanedFile deletes an orphaned file after checking once more that no database
+// was registered at it since the scan, holding its write lock against a concurrent create.
+func removeOrphanedFile(ctx context.Context, store MetadataStore, path string) error {
+	unlock, err := userDBWriteLocks.lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	primary := path
+	if p := primaryOf(path); p != "" {
+		primary = p
+	}
+	userId, dbName := filepath.Base(filepath.Dir(primary)), strings.TrimSuffix(filepath.Base(primary), ".db")
+	location, err := store.FindDatabasePath(ctx, userId, dbName)
+	switch {
+	case err == nil && filepath.Clean(location) == primary:
+		return errors.New("database registered since the scan")
+	case err != nil && !errors.Is(err, ErrDatabaseNotFound):
+		return err
+	}
+	_ = removeUserDBFiles(ctx, path)
+	if _, err := os.Stat(path); err == nil {
+		return errors.New("file could not be removed")
+	}
+	return nil
+}
+
+// RunOrphanFileCollector periodically looks for orphaned database files older than
+// gracePeriod, until ctx is done. They are removed if remove is set, otherwise only
+// logged.
+func RunOrphanFileCollector(ctx context.Context, store MetadataStore, interval, gracePeriod time.Duration, remove bool) {
+	health.RegisterWorker("orphan_file_collector", interval)
+	defer health.UnregisterWorker("orphan_file_collector")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			collectOrphanedFiles(ctx, store, gracePeriod, remove)
+			health.Beat("orphan_file_collector")
+		}
+	}
+}
+
+// collectOrphanedFiles runs one pass of the orphan file collector.
+func collectOrphanedFiles(ctx context.Context, store MetadataStore, gracePeriod time.Duration, remove bool) {
+	scan, err := CollectOrphanedFiles(ctx, store, gracePeriod, !remove)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Orphaned file collection skipped: %v", err)
+		return
+	}
+	for _, orphan := range scan.Orphaned {
+		switch {
+		case orphan.Removed:
+			customLog.Ctx(ctx).Printf("Storage: Removed orphaned database file '%s' (%d bytes)", orphan.Path, orphan.Size)
+		case orphan.Error != "":
+			customLog.Ctx(ctx).Warnf("Storage: Failed to remove orphaned database file '%s': %s", orphan.Path, orphan.Error)
+		default:
+			customLog.Ctx(ctx).Warnf("Storage: Found orphaned database file '%s' (%d bytes, last modified %s)", orphan.Path, orphan.Size, orphan.ModifiedAt.Format(time.RFC3339))
+		}
+	}
+}
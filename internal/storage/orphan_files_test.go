@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestCollectOrphanedFiles(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	root := t.TempDir()
+	if err := ConfigureDataRoots([]string{root}, PlacementMostFree); err != nil {
+		t.Fatalf("ConfigureDataRoots: %v", err)
+	}
+	defer func() { dataRoots.roots = nil }()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	registered := UserDataLocation(root, "u1", "app")
+	if err := store.RegisterDatabase(ctx, "u1", "app", registered); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	write := func(path string, modified time.Time) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orphan := filepath.Join(root, "u1", "deleted.db")
+	write(registered, old)
+	write(replicaPath(registered), old)
+	write(orphan, old)
+	write(orphan+"-wal", old)
+	write(replicaPath(orphan), old)
+	write(filepath.Join(root, "u1", "creating.db"), time.Now())
+	write(filepath.Join(root, "u1", ".trash", "old.1.db"), old)
+
+	scan, err := CollectOrphanedFiles(ctx, store, 24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if scan.Scanned != 5 || scan.Recent != 1 || len(scan.Orphaned) != 2 {
+		t.Fatalf("dry run = %+v; want 5 scanned, 1 recent, 2 orphaned", scan)
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Errorf("dry run removed '%s': %v", orphan, err)
+	}
+
+	scan, err = CollectOrphanedFiles(ctx, store, 24*time.Hour, false)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	for _, file := range scan.Orphaned {
+		if !file.Removed {
+			t.Errorf("'%s' not removed: %s", file.Path, file.Error)
+		}
+	}
+	for _, path := range []string{orphan, orphan + "-wal", replicaPath(orphan)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("'%s' after collection: %v; want it removed", path, err)
+		}
+	}
+	if _, err := os.Stat(registered); err != nil {
+		t.Errorf("registered file after collection: %v", err)
+	}
+}
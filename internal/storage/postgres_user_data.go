@@ -0,0 +1,460 @@
+// internal/storage/postgres_user_data.go
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// postgresLocationPrefix marks registrations whose data lives in a Postgres schema.
+const postgresLocationPrefix = "postgres:"
+
+// Postgres column types for each Nebula column type.
+var postgresColumnTypes = map[string]string{
+	"TEXT":    "TEXT",
+	"INTEGER": "BIGINT",
+	"REAL":    "DOUBLE PRECISION",
+	"BLOB":    "BYTEA",
+	"BOOLEAN": "BOOLEAN",
+}
+
+// tenantSchema derives the schema name for a database. Hashing keeps it a valid, short
+// identifier whatever the user ID looks like.
+func tenantSchema(userId, dbName string) string {
+	sum := sha256.Sum256([]byte(userId + "/" + dbName))
+	return "nebula_" + hex.EncodeToString(sum[:12])
+}
+
+// postgresSchema extracts the schema name from a registered location.
+func postgresSchema(location string) (string, error) {
+	schema, ok := strings.CutPrefix(location, postgresLocationPrefix)
+	if !ok || !core.IsValidIdentifier(schema) {
+		return "", fmt.Errorf("database at '%s' is not stored in Postgres: %w", location, ErrUserDataUnsupported)
+	}
+	return schema, nil
+}
+
+func createTenantSchema(ctx context.Context, db *sql.DB, schema string) error {
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA IF NOT EXISTS "+pq.QuoteIdentifier(schema)); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create tenant schema '%s': %v", schema, err)
+		return fmt.Errorf("failed to create database storage: %w", err)
+	}
+	return nil
+}
+
+func dropTenantSchema(ctx context.Context, db *sql.DB, schema string) error {
+	if _, err := db.ExecContext(ctx, "DROP SCHEMA IF EXISTS "+pq.QuoteIdentifier(schema)+" CASCADE"); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to drop tenant schema '%s': %v", schema, err)
+		return fmt.Errorf("failed to delete database storage: %w", err)
+	}
+	return nil
+}
+
+func renameTenantSchema(ctx context.Context, db *sql.DB, from, to string) error {
+	if _, err := db.ExecContext(ctx, "ALTER SCHEMA "+pq.QuoteIdentifier(from)+" RENAME TO "+pq.QuoteIdentifier(to)); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to rename tenant schema '%s' to '%s': %v", from, to, err)
+		return fmt.Errorf("failed to move database storage: %w", err)
+	}
+	return nil
+}
+
+// postgresUserData implements UserDataStore on one schema of the shared Postgres pool.
+// Table and column names are validated identifiers and left unquoted, so Postgres folds
+// them to lower case.
+type postgresUserData struct {
+	db     *sql.DB
+	schema string
+}
+
+// table returns the schema-qualified name of tableName.
+func (s *postgresUserData) table(tableName string) string {
+	return pq.QuoteIdentifier(s.schema) + "." + tableName
+}
+
+func (s *postgresUserData) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+// columns reads the column list of a table from information_schema.
+func (s *postgresUserData) columns(ctx context.Context, tableName string) ([]domain.ColumnInfo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.ordinal_position, c.column_name, c.data_type, c.is_nullable = 'NO', c.column_default,
+		       EXISTS (SELECT 1 FROM information_schema.table_constraints tc
+		               JOIN information_schema.key_column_usage k
+		                 ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+		               WHERE tc.table_schema = c.table_schema AND tc.table_name = c.table_name
+		                 AND tc.constraint_type = 'PRIMARY KEY' AND k.column_name = c.column_name)
+		FROM information_schema.columns c
+		WHERE c.table_schema = $1 AND c.table_name = $2
+		ORDER BY c.ordinal_position`, s.schema, strings.ToLower(tableName))
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error getting column info for table %s: %v", tableName, err)
+		return nil, fmt.Errorf("database error getting column info: %w", err)
+	}
+	defer rows.Close()
+
+	var columnInfos []domain.ColumnInfo
+	for rows.Next() {
+		var col domain.ColumnInfo
+		var dataType string
+		var notNull, pk bool
+		var dflt sql.NullString
+		if err := rows.Scan(&col.ColumnId, &col.Name, &dataType, &notNull, &dflt, &pk); err != nil {
+			return nil, fmt.Errorf("failed processing column info: %w", err)
+		}
+		col.Type = nebulaColumnType(dataType)
+		if notNull {
+			col.NotNull = 1
+		}
+		if pk {
+			col.PK = 1
+		}
+		if dflt.Valid {
+			col.Default = dflt.String
+		}
+		columnInfos = append(columnInfos, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading column info: %w", err)
+	}
+	return columnInfos, nil
+}
+
+// nebulaColumnType maps an information_schema data type back to a Nebula column type.
+func nebulaColumnType(dataType string) string {
+	switch dataType {
+	case "bigint", "integer", "smallint":
+		return "INTEGER"
+	case "double precision", "real", "numeric":
+		return "REAL"
+	case "bytea":
+		return "BLOB"
+	case "boolean":
+		return "BOOLEAN"
+	case "timestamp with time zone", "timestamp without time zone":
+		return "TIMESTAMP"
+	default:
+		return "TEXT"
+	}
+}
+
+// postgresUserDataError maps driver errors to the storage sentinel errors.
+func postgresUserDataError(err error, action string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "42P01": // undefined_table
+			return ErrTableNotFound
+		case pqErr.Code == "42P07": // duplicate_table
+			return ErrTableExists
+		case pqErr.Code == "42703": // undefined_column
+			return ErrColumnNotFound
+		case pqErr.Code == "42804" || pqErr.Code == "22P02": // datatype_mismatch, invalid_text_representation
+			return ErrTypeMismatch
+		case pqErr.Code.Class() == "23":
+			return ErrConstraintViolation
+		}
+	}
+	return fmt.Errorf("database error during %s: %w", action, err)
+}
+
+func (s *postgresUserData) ColumnTypes(ctx context.Context, tableName string) (map[string]string, error) {
+	columns, err := s.columns(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, ErrTableNotFound
+	}
+	columnTypes := make(map[string]string, len(columns))
+	for _, col := range columns {
+		columnTypes[strings.ToLower(col.Name)] = col.Type
+	}
+	return columnTypes, nil
+}
+
+func (s *postgresUserData) ListTables(ctx context.Context) ([]domain.TableMetadata, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name`, s.schema)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Error listing tables: %v", err)
+		return nil, fmt.Errorf("database error listing tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed processing table list: %w", err)
+		}
+		if strings.HasPrefix(name, core.TrashedTablePrefix) {
+			continue // Dropped, waiting in the trash
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading table list: %w", err)
+	}
+
+	tables := make([]domain.TableMetadata, 0, len(names))
+	for _, name := range names {
+		columns, err := s.columns(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, domain.TableMetadata{Type: "table", Name: name, TableName: name, Columns: columns})
+	}
+	return tables, nil
+}
+
+// columnDef renders the definition of a validated column.
+func (s *postgresUserData) columnDef(col core.ColumnSpec) string {
+	def := col.Name + " " + postgresColumnTypes[col.Type]
+	if col.Collate == core.CollateNoCase {
+		def += " COLLATE " + pq.QuoteIdentifier(s.schema) + ".nocase"
+	}
+	if col.Default != nil {
+		literal, _ := core.DefaultLiteral(col.Default) // Checked by ValidateTableDefinition
+		def += " DEFAULT " + literal
+	}
+	return def
+}
+
+// prepareCollations creates the nocase collation in the schema when one of columns
+// uses it. Postgres has no built-in case-insensitive collation, so it is a
+// nondeterministic ICU collation comparing letters at the secondary strength.
+func (s *postgresUserData) prepareCollations(ctx context.Context, columns []core.ColumnSpec) error {
+	for _, col := range columns {
+		if col.Collate != core.CollateNoCase {
+			continue
+		}
+		collationSQL := "CREATE COLLATION IF NOT EXISTS " + pq.QuoteIdentifier(s.schema) +
+			".nocase (provider = icu, locale = 'und-u-ks-level2', deterministic = false)"
+		if _, err := s.db.ExecContext(ctx, collationSQL); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to create nocase collation in schema '%s': %v", s.schema, err)
+			return fmt.Errorf("failed to create collation: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (s *postgresUserData) CreateTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	normalized, err := core.ValidateTableDefinition(tableName, columns)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareCollations(ctx, normalized); err != nil {
+		return err
+	}
+	columnDefs := make([]string, len(normalized))
+	for i, col := range normalized {
+		columnDefs[i] = s.columnDef(col)
+	}
+	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, %s, created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP)",
+		s.table(tableName), strings.Join(columnDefs, ", "))
+
+	if _, err := s.db.ExecContext(ctx, createSQL); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to execute CREATE TABLE: %v\nSQL: %s", err, createSQL)
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	return nil
+}
+
+// CreateStrictTable creates a table like CreateTable: Postgres always enforces column
+// types. BOOLEAN columns are refused all the same, so creating a strict table behaves
+// alike on every backend.
+func (s *postgresUserData) CreateStrictTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	normalized, err := core.ValidateTableDefinition(tableName, columns)
+	if err != nil {
+		return err
+	}
+	if err := core.ValidateStrictColumns(normalized); err != nil {
+		return err
+	}
+	return s.CreateTable(ctx, tableName, normalized)
+}
+
+func (s *postgresUserData) DropTable(ctx context.Context, tableName string) error {
+	if _, err := s.db.ExecContext(ctx, "DROP TABLE IF EXISTS "+s.table(tableName)); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed DROP TABLE for Table '%s': %v", tableName, err)
+		return fmt.Errorf("database error dropping table: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresUserData) RenameTable(ctx context.Context, from, to string) error {
+	if _, err := s.db.ExecContext(ctx, "ALTER TABLE "+s.table(from)+" RENAME TO "+to); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed RENAME of Table '%s' to '%s': %v", from, to, err)
+		return postgresUserDataError(err, "rename")
+	}
+	return nil
+}
+
+func (s *postgresUserData) AddColumns(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	normalized, err := core.ValidateTableDefinition(tableName, columns)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareCollations(ctx, normalized); err != nil {
+		return err
+	}
+	clauses := make([]string, len(normalized))
+	for i, col := range normalized {
+		clauses[i] = "ADD COLUMN IF NOT EXISTS " + s.columnDef(col)
+	}
+	if _, err := s.db.ExecContext(ctx, "ALTER TABLE "+s.table(tableName)+" "+strings.Join(clauses, ", ")); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to add columns to Table '%s': %v", tableName, err)
+		return postgresUserDataError(err, "alter")
+	}
+	return nil
+}
+
+func (s *postgresUserData) InsertRecord(ctx context.Context, tableName string, columns []string, values []any) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insertSQL := postgresDialect.rebind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id",
+		s.table(tableName), strings.Join(columns, ", "), placeholders))
+
+	var id int64
+	if err := s.db.QueryRowContext(ctx, insertSQL, values...).Scan(&id); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed INSERT: %v\nSQL: %s", err, insertSQL)
+		return 0, postgresUserDataError(err, "insert")
+	}
+	return id, nil
+}
+
+func (s *postgresUserData) CountRecords(ctx context.Context, tableName string, limit int64) (int64, error) {
+	count, err := countRows(ctx, s.query, fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s LIMIT $1) AS capped", s.table(tableName)), limit)
+	if err != nil {
+		return 0, postgresUserDataError(err, "count")
+	}
+	return int64(count), nil
+}
+
+func (s *postgresUserData) ListRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions) (*ListRecordsResult, error) {
+	result := &ListRecordsResult{Records: make([]map[string]any, 0)}
+	err := s.StreamRecords(ctx, tableName, queryParams, opts,
+		func(pagination PaginationMeta) error {
+			result.Pagination = pagination
+			return nil
+		},
+		func(record map[string]any) error {
+			result.Records = append(result.Records, record)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *postgresUserData) StreamRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions,
+	start func(PaginationMeta) error, emit func(map[string]any) error) error {
+	columnTypes, err := s.ColumnTypes(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	return streamRecords(ctx, postgresDialect, s.query, s.table(tableName), columnTypes, queryParams, opts, start, emit)
+}
+
+func (s *postgresUserData) GetRecord(ctx context.Context, tableName string, recordID int64) (map[string]any, error) {
+	selectSQL := fmt.Sprintf("SELECT * FROM %s WHERE id = $1 LIMIT 1", s.table(tableName))
+	rows, err := s.db.QueryContext(ctx, selectSQL, recordID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed SELECT by ID: %v\nSQL: %s", err, selectSQL)
+		return nil, postgresUserDataError(err, "select")
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed processing results: %w", err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed checking for record: %w", err)
+		}
+		return nil, ErrRecordNotFound
+	}
+	values := make([]any, len(columns))
+	scanArgs := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	if err := rows.Scan(scanArgs...); err != nil {
+		return nil, fmt.Errorf("failed reading record data: %w", err)
+	}
+
+	record := make(map[string]any, len(columns))
+	for i, colName := range columns {
+		if b, ok := values[i].([]byte); ok {
+			record[colName] = string(b)
+		} else {
+			record[colName] = values[i]
+		}
+	}
+	return record, nil
+}
+
+func (s *postgresUserData) UpdateRecord(ctx context.Context, tableName string, recordID int64, columns []string, values []any) (int64, error) {
+	setClauses := make([]string, len(columns))
+	for i, col := range columns {
+		setClauses[i] = col + " = ?"
+	}
+	updateSQL := postgresDialect.rebind(fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", s.table(tableName), strings.Join(setClauses, ", ")))
+
+	result, err := s.db.ExecContext(ctx, updateSQL, append(values, recordID)...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed UPDATE: %v\nSQL: %s", err, updateSQL)
+		return 0, postgresUserDataError(err, "update")
+	}
+	return rowsAffectedOrNotFound(result)
+}
+
+func (s *postgresUserData) DeleteRecord(ctx context.Context, tableName string, recordID int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", s.table(tableName)), recordID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed DELETE: %v", err)
+		return 0, postgresUserDataError(err, "delete")
+	}
+	return rowsAffectedOrNotFound(result)
+}
+
+func (s *postgresUserData) SearchText(ctx context.Context, tableName string, columns []string, term string, limit int) ([]map[string]any, error) {
+	return searchText(ctx, postgresDialect, s.query, s.table(tableName), columns, term, limit)
+}
+
+func (s *postgresUserData) FindDuplicates(ctx context.Context, tableName string, columns []string, limit int) ([]DuplicateGroup, error) {
+	return findDuplicates(ctx, postgresDialect, s.query, s.table(tableName), columns, limit)
+}
+
+func (s *postgresUserData) DeleteDuplicates(ctx context.Context, tableName string, columns []string, keepLast bool) ([]int64, error) {
+	return deleteDuplicates(ctx, postgresDialect, s.query, s.table(tableName), columns, keepLast)
+}
+
+// Release is a no-op: the schema shares the backend's pool.
+func (s *postgresUserData) Release() {}
+
+// rowsAffectedOrNotFound returns the affected row count, or ErrRecordNotFound for none.
+func rowsAffectedOrNotFound(result sql.Result) (int64, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed confirming write: %w", err)
+	}
+	if n == 0 {
+		return 0, ErrRecordNotFound
+	}
+	return n, nil
+}
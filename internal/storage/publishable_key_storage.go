@@ -0,0 +1,102 @@
+// internal/storage/publishable_key_storage.go
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// PublishableKeyPrefix starts every publishable key, telling them apart from API keys.
+const PublishableKeyPrefix = "nebpk_" // nolint:gosec // Not a credential, just a prefix
+
+// ErrPublishableKeyNotFound is returned when no publishable key has an ID or value.
+var ErrPublishableKeyNotFound = errors.New("publishable key not found")
+
+const publishableKeyColumns = `k.publishable_key_id, k.database_id, d.owner_id, k.key, k.tables, k.allowed_origins, k.description, k.created_at`
+
+func scanPublishableKey(scan func(dest ...any) error) (*domain.PublishableKey, error) {
+	var key domain.PublishableKey
+	var tables, origins string
+	if err := scan(&key.ID, &key.DatabaseID, &key.OwnerID, &key.Key, &tables, &origins, &key.Description, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	key.Tables = splitStoredList(tables)
+	key.AllowedOrigins = splitStoredList(origins)
+	return &key, nil
+}
+
+// ListPublishableKeys returns the publishable keys of a database, oldest first.
+func (s *sqlMetadataStore) ListPublishableKeys(ctx context.Context, databaseId int64) ([]domain.PublishableKey, error) {
+	rows, err := s.query(ctx, `SELECT `+publishableKeyColumns+` FROM publishable_keys k JOIN databases d ON d.database_id = k.database_id
+		WHERE k.database_id = ? ORDER BY k.created_at, k.publishable_key_id`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list publishable keys for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing publishable keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []domain.PublishableKey{}
+	for rows.Next() {
+		key, err := scanPublishableKey(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading publishable keys: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// FindPublishableKey returns the publishable key of a value, whatever its database,
+// with the owner of the database. Returns ErrPublishableKeyNotFound for unknown keys.
+func (s *sqlMetadataStore) FindPublishableKey(ctx context.Context, value string) (*domain.PublishableKey, error) {
+	key, err := scanPublishableKey(s.queryRow(ctx, `SELECT `+publishableKeyColumns+` FROM publishable_keys k
+		JOIN databases d ON d.database_id = k.database_id WHERE k.key = ?`, value).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPublishableKeyNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Error looking up publishable key: %v", err)
+		return nil, fmt.Errorf("database error finding publishable key: %w", err)
+	}
+	return key, nil
+}
+
+// CreatePublishableKey stores a new publishable key of key.DatabaseID and returns its
+// generated value. The ID is chosen by the caller.
+func (s *sqlMetadataStore) CreatePublishableKey(ctx context.Context, key domain.PublishableKey) (string, error) {
+	randomBytes := make([]byte, apiKeySecretLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to generate random bytes for publishable key: %v", err)
+		return "", ErrAPIKeyGeneration
+	}
+	value := PublishableKeyPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	_, err := s.exec(ctx, `INSERT INTO publishable_keys (publishable_key_id, database_id, key, tables, allowed_origins, description) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.DatabaseID, value, strings.Join(key.Tables, ","), strings.Join(key.AllowedOrigins, ","), key.Description)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create publishable key for DBID %d: %v", key.DatabaseID, err)
+		return "", fmt.Errorf("database error creating publishable key: %w", err)
+	}
+	return value, nil
+}
+
+// DeletePublishableKey removes a publishable key, returning ErrPublishableKeyNotFound
+// if it did not exist.
+func (s *sqlMetadataStore) DeletePublishableKey(ctx context.Context, databaseId int64, keyId string) error {
+	result, err := s.exec(ctx, `DELETE FROM publishable_keys WHERE database_id = ? AND publishable_key_id = ?`, databaseId, keyId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete publishable key %s for DBID %d: %v", keyId, databaseId, err)
+		return fmt.Errorf("database error deleting publishable key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrPublishableKeyNotFound
+	}
+	return nil
+}
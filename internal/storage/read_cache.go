@@ -0,0 +1,137 @@
+// internal/storage/read_cache.go
+package storage
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Defaults for the record read cache, overridable via ConfigureReadCache.
+const (
+	defaultReadCacheMaxEntries = 1024
+	defaultReadCacheTTL        = 30 * time.Second
+)
+
+// readCacheEntry is one cached read result.
+type readCacheEntry struct {
+	key        string
+	path       string
+	generation uint64 // Write generation of path when the result was read
+	expires    time.Time
+	value      any
+}
+
+// readCache keeps recent GetRecord/ListRecords results keyed by (db file, query).
+// Every write to a file bumps its generation, which makes all cached reads of that
+// file stale at once; stale and expired entries are dropped lazily or by LRU eviction.
+type readCache struct {
+	mu          sync.Mutex
+	maxEntries  int // 0 disables the cache
+	ttl         time.Duration
+	lru         *list.List // Front = most recently used
+	items       map[string]*list.Element
+	generations map[string]uint64
+}
+
+var recordReads = newReadCache(defaultReadCacheMaxEntries, defaultReadCacheTTL)
+
+func newReadCache(maxEntries int, ttl time.Duration) *readCache {
+	return &readCache{
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		lru:         list.New(),
+		items:       make(map[string]*list.Element),
+		generations: make(map[string]uint64),
+	}
+}
+
+// ConfigureReadCache sets the maximum number of cached reads and how long each stays
+// valid. maxEntries <= 0 disables read caching. Call once at startup.
+func ConfigureReadCache(maxEntries int, ttl time.Duration) {
+	recordReads.mu.Lock()
+	defer recordReads.mu.Unlock()
+	recordReads.maxEntries = max(maxEntries, 0)
+	if ttl > 0 {
+		recordReads.ttl = ttl
+	}
+	recordReads.lru.Init()
+	recordReads.items = make(map[string]*list.Element)
+}
+
+// lookup returns the cached value for key in the DB behind userDB, or nil together with
+// a store func that caches the freshly read value. store ignores values read while a
+// concurrent write was in progress.
+func (r *readCache) lookup(userDB *sql.DB, key string) (any, func(any)) {
+	noop := func(any) {}
+	path := userDBs.pathOf(userDB)
+	if path == "" {
+		return nil, noop
+	}
+	fullKey := path + "\x00" + key
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxEntries == 0 {
+		return nil, noop
+	}
+
+	generation := r.generations[path]
+	if elem, ok := r.items[fullKey]; ok {
+		entry := elem.Value.(*readCacheEntry)
+		if entry.generation == generation && time.Now().Before(entry.expires) {
+			r.lru.MoveToFront(elem)
+			return entry.value, noop
+		}
+		r.removeLocked(elem)
+	}
+
+	return nil, func(value any) {
+		r.put(fullKey, path, generation, value)
+	}
+}
+
+func (r *readCache) put(fullKey, path string, generation uint64, value any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxEntries == 0 || r.generations[path] != generation {
+		return
+	}
+	if elem, ok := r.items[fullKey]; ok {
+		r.removeLocked(elem)
+	}
+	entry := &readCacheEntry{
+		key:        fullKey,
+		path:       path,
+		generation: generation,
+		expires:    time.Now().Add(r.ttl),
+		value:      value,
+	}
+	r.items[fullKey] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.maxEntries {
+		r.removeLocked(r.lru.Back())
+	}
+}
+
+// invalidate marks every cached read of the file at path as stale.
+func (r *readCache) invalidate(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generations[path]++
+}
+
+// removeLocked drops one entry. Caller holds r.mu.
+func (r *readCache) removeLocked(elem *list.Element) {
+	entry := r.lru.Remove(elem).(*readCacheEntry)
+	delete(r.items, entry.key)
+}
+
+// invalidateReads marks cached reads of the DB behind userDB as stale after a write.
+func invalidateReads(userDB *sql.DB) {
+	if path := userDBs.pathOf(userDB); path != "" {
+		recordReads.invalidate(path)
+		readReplicas.primaryChanged(path, false)
+		notifyInvalidation(Invalidation{Kind: InvalidateReads, Path: path})
+	}
+}
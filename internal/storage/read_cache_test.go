@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRecordReadCacheInvalidatedByWrites(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	id, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "first")
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	const selectSQL = "SELECT * FROM notes WHERE id = ? LIMIT 1;"
+	if _, err := GetRecord(ctx, userDB, selectSQL, id); err != nil {
+		t.Fatalf("GetRecord: %v", err)
+	}
+
+	// A change made behind the storage layer's back is not seen while the entry is cached
+	if _, err := userDB.ExecContext(ctx, "UPDATE notes SET body = 'sneaky' WHERE id = ?", id); err != nil {
+		t.Fatalf("direct update: %v", err)
+	}
+	record, err := GetRecord(ctx, userDB, selectSQL, id)
+	if err != nil || record["body"] != "first" {
+		t.Fatalf("GetRecord = %v, %v; want the cached 'first'", record, err)
+	}
+
+	// Writes through the storage layer invalidate cached reads
+	if _, err := UpdateRecord(ctx, userDB, "UPDATE notes SET body = ? WHERE id = ?", "second", id); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	record, err = GetRecord(ctx, userDB, selectSQL, id)
+	if err != nil || record["body"] != "second" {
+		t.Errorf("GetRecord after update = %v, %v; want 'second'", record, err)
+	}
+}
+
+func TestInvalidationsOfOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+
+	var sent []Invalidation
+	OnInvalidation(func(inv Invalidation) { sent = append(sent, inv) })
+	defer OnInvalidation(nil)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	id, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "first")
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+	if len(sent) == 0 || sent[len(sent)-1] != (Invalidation{Kind: InvalidateReads, Path: dbPath}) {
+		t.Errorf("invalidations sent = %v; want the insert's", sent)
+	}
+
+	const selectSQL = "SELECT * FROM notes WHERE id = ? LIMIT 1;"
+	if _, err := GetRecord(ctx, userDB, selectSQL, id); err != nil {
+		t.Fatalf("GetRecord: %v", err)
+	}
+	// Stands in for a write by another instance, which then publishes its invalidation
+	if _, err := userDB.ExecContext(ctx, "UPDATE notes SET body = 'remote' WHERE id = ?", id); err != nil {
+		t.Fatalf("direct update: %v", err)
+	}
+	count := len(sent)
+	ApplyInvalidation(Invalidation{Kind: InvalidateReads, Path: dbPath})
+	record, err := GetRecord(ctx, userDB, selectSQL, id)
+	if err != nil || record["body"] != "remote" {
+		t.Errorf("GetRecord after remote invalidation = %v, %v; want 'remote'", record, err)
+	}
+	if len(sent) != count {
+		t.Errorf("applying a remote invalidation sent %v; want nothing", sent[count:])
+	}
+}
@@ -0,0 +1,264 @@
+// internal/storage/read_replica.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+const replicaRefresherWorker = "read_replica_refresher"
+
+// readReplica is a read-only copy of a user DB file that record reads are routed to.
+type readReplica struct {
+	databaseID int64
+	primary    string // File path of the primary
+	path       string // File path of the replica
+	maxLag     time.Duration
+
+	writes        uint64    // Writes to the primary, bumped on every invalidation
+	schemaChanges uint64    // Schema changes of the primary
+	syncedWrites  uint64    // Value of writes the replica was last copied at
+	syncedSchema  uint64    // Value of schemaChanges the replica was last copied at
+	syncedAt      time.Time // Start of the last copy; zero until the first one
+	lastError     string
+	reads         atomic.Int64 // Reads served by the replica
+}
+
+// inSync reports whether the replica holds every write of the primary. Caller holds
+// readReplicas.mu.
+func (r *readReplica) inSync() bool {
+	return !r.syncedAt.IsZero() && r.syncedWrites == r.writes && r.syncedSchema == r.schemaChanges
+}
+
+// usable reports whether reads may be served by the replica: it must be in sync, or
+// have the primary's schema and be no older than maxLag. Caller holds readReplicas.mu.
+func (r *readReplica) usable(now time.Time) bool {
+	if r.syncedAt.IsZero() || r.syncedSchema != r.schemaChanges {
+		return false
+	}
+	return r.syncedWrites == r.writes || now.Sub(r.syncedAt) <= r.maxLag
+}
+
+// replicaSet tracks the read replicas of designated user DBs, keyed by primary path.
+// Replicas live next to their primary (app.db -> app.replica.db) so they share its
+// encryption key, and are refreshed by RunReadReplicaRefresher with the online
+// backup API, which copies the primary's committed pages including those still in
+// its WAL.
+type replicaSet struct {
+	mu        sync.Mutex
+	byPrimary map[string]*readReplica
+}
+
+var readReplicas = &replicaSet{byPrimary: make(map[string]*readReplica)}
+
+// ReadReplicaStatus describes the state of one read replica.
+type ReadReplicaStatus struct {
+	InSync    bool       `json:"in_sync"`             // Holds every write of the primary
+	SyncedAt  *time.Time `json:"synced_at,omitempty"` // Primary state the replica holds; nil before the first copy
+	Reads     int64      `json:"reads"`               // Reads served since the server started
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// replicaPath returns the file path of the read replica of the user DB at primary.
+func replicaPath(primary string) string {
+	return strings.TrimSuffix(primary, ".db") + ".replica.db"
+}
+
+// primaryChanged records a write (or, with schema, a schema change) to the user DB at
+// path, which makes its replica stale until the next refresh.
+func (s *replicaSet) primaryChanged(path string, schema bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.byPrimary[path]; ok {
+		r.writes++
+		if schema {
+			r.schemaChanges++
+		}
+	}
+}
+
+// route returns the handle reads of userDB should use and the func to call once the
+// read is done. Reads go to the primary unless it has a usable replica.
+func (s *replicaSet) route(ctx context.Context, userDB *sql.DB) (*sql.DB, func()) {
+	noop := func() {}
+	path := userDBs.pathOf(userDB)
+	if path == "" {
+		return userDB, noop
+	}
+	s.mu.Lock()
+	r, ok := s.byPrimary[path]
+	if !ok || !r.usable(time.Now()) {
+		s.mu.Unlock()
+		return userDB, noop
+	}
+	s.mu.Unlock()
+
+	replicaDB, err := userDBs.acquire(ctx, r.path)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Reading '%s' from the primary, replica unavailable: %v", path, err)
+		return userDB, noop
+	}
+	r.reads.Add(1)
+	return replicaDB, func() { userDBs.release(replicaDB) }
+}
+
+// lagging reports whether reads of userDB may be served by a replica that misses some
+// of its writes. Such reads must not be cached: check after the read cache lookup, so
+// that a write after the check discards the value instead.
+func (s *replicaSet) lagging(userDB *sql.DB) bool {
+	path := userDBs.pathOf(userDB)
+	if path == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.byPrimary[path]
+	return ok && r.usable(time.Now()) && !r.inSync()
+}
+
+// sync makes the designated replicas those of designations, dropping the others and
+// deleting their files.
+func (s *replicaSet) sync(ctx context.Context, designations []domain.ReadReplica) {
+	wanted := make(map[string]domain.ReadReplica, len(designations))
+	for _, d := range designations {
+		wanted[d.FilePath] = d
+	}
+
+	s.mu.Lock()
+	var dropped []*readReplica
+	for primary, r := range s.byPrimary {
+		if _, ok := wanted[primary]; !ok {
+			delete(s.byPrimary, primary)
+			dropped = append(dropped, r)
+		}
+	}
+	for primary, d := range wanted {
+		r, ok := s.byPrimary[primary]
+		if !ok {
+			r = &readReplica{databaseID: d.DatabaseID, primary: primary, path: replicaPath(primary)}
+			s.byPrimary[primary] = r
+			customLog.Ctx(ctx).Printf("Storage: Read replica of '%s' designated", primary)
+		}
+		r.maxLag = time.Duration(d.MaxLagSeconds) * time.Second
+	}
+	s.mu.Unlock()
+
+	for _, r := range dropped {
+		_ = removeUserDBFiles(ctx, r.path)
+		customLog.Ctx(ctx).Printf("Storage: Read replica of '%s' removed", r.primary)
+	}
+}
+
+// refresh copies the primary into every replica that is not in sync.
+func (s *replicaSet) refresh(ctx context.Context) {
+	s.mu.Lock()
+	stale := make([]*readReplica, 0, len(s.byPrimary))
+	for _, r := range s.byPrimary {
+		if !r.inSync() {
+			stale = append(stale, r)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range stale {
+		if ctx.Err() != nil {
+			return
+		}
+		err := s.refreshOne(ctx, r)
+		s.mu.Lock()
+		r.lastError = ""
+		if err != nil {
+			r.lastError = err.Error()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to refresh read replica of '%s': %v", r.primary, err)
+		}
+	}
+}
+
+// refreshOne copies the primary of r into its replica.
+func (s *replicaSet) refreshOne(ctx context.Context, r *readReplica) error {
+	if _, err := os.Stat(r.primary); err != nil {
+		return err // Not created yet (no table was ever added) or moved to the trash
+	}
+
+	// Writes recorded from here on may be missing from the copy and keep it stale
+	s.mu.Lock()
+	writes, schemaChanges, started := r.writes, r.schemaChanges, time.Now()
+	s.mu.Unlock()
+
+	primaryDB, err := userDBs.acquire(ctx, r.primary)
+	if err != nil {
+		return err
+	}
+	defer userDBs.release(primaryDB)
+	replicaDB, err := userDBs.acquire(ctx, r.path)
+	if err != nil {
+		return err
+	}
+	defer userDBs.release(replicaDB)
+	if err := copyDatabase(ctx, replicaDB, primaryDB); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	r.syncedWrites, r.syncedSchema, r.syncedAt = writes, schemaChanges, started
+	s.mu.Unlock()
+	return nil
+}
+
+// status returns the state of the replica of primary, if it is designated.
+func (s *replicaSet) status(primary string) (ReadReplicaStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.byPrimary[primary]
+	if !ok {
+		return ReadReplicaStatus{}, false
+	}
+	status := ReadReplicaStatus{InSync: r.inSync(), Reads: r.reads.Load(), LastError: r.lastError}
+	if !r.syncedAt.IsZero() {
+		syncedAt := r.syncedAt.UTC()
+		status.SyncedAt = &syncedAt
+	}
+	return status, true
+}
+
+// ReadReplicaStatusOf returns the state of the read replica of the user DB at primary.
+// ok is false until the refresher picked up its designation.
+func ReadReplicaStatusOf(primary string) (status ReadReplicaStatus, ok bool) {
+	return readReplicas.status(primary)
+}
+
+// RunReadReplicaRefresher keeps the read replicas designated in store fresh until ctx
+// is done: every interval it picks up designation changes and copies each primary
+// written to since its last copy into its replica.
+func RunReadReplicaRefresher(ctx context.Context, store MetadataStore, interval time.Duration) {
+	health.RegisterWorker(replicaRefresherWorker, interval)
+	defer health.UnregisterWorker(replicaRefresherWorker)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		designations, err := store.ListReadReplicas(ctx)
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to load read replica designations: %v", err)
+		} else {
+			readReplicas.sync(ctx, designations)
+			readReplicas.refresh(ctx)
+		}
+		health.Beat(replicaRefresherWorker)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
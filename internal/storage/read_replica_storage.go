@@ -0,0 +1,59 @@
+// internal/storage/read_replica_storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrReadReplicaNotFound is returned when a database has no read replica.
+var ErrReadReplicaNotFound = errors.New("database has no read replica")
+
+// ListReadReplicas returns every database designated to have a read replica.
+func (s *sqlMetadataStore) ListReadReplicas(ctx context.Context) ([]domain.ReadReplica, error) {
+	rows, err := s.query(ctx, `SELECT r.database_id, d.owner_id, d.db_name, d.file_path, r.max_lag_seconds, r.created_at
+		FROM database_replicas r JOIN databases d ON d.database_id = r.database_id ORDER BY r.database_id`)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list read replicas: %v", err)
+		return nil, fmt.Errorf("database error listing read replicas: %w", err)
+	}
+	defer rows.Close()
+
+	replicas := make([]domain.ReadReplica, 0)
+	for rows.Next() {
+		var r domain.ReadReplica
+		if err := rows.Scan(&r.DatabaseID, &r.UserID, &r.DBName, &r.FilePath, &r.MaxLagSeconds, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("database error reading read replicas: %w", err)
+		}
+		replicas = append(replicas, r)
+	}
+	return replicas, rows.Err()
+}
+
+// SetReadReplica designates a read replica for a database, or updates its allowed lag.
+func (s *sqlMetadataStore) SetReadReplica(ctx context.Context, databaseId int64, maxLagSeconds int) error {
+	_, err := s.exec(ctx, `INSERT INTO database_replicas (database_id, max_lag_seconds) VALUES (?, ?)
+		ON CONFLICT (database_id) DO UPDATE SET max_lag_seconds = excluded.max_lag_seconds`, databaseId, maxLagSeconds)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store read replica of DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error storing read replica: %w", err)
+	}
+	return nil
+}
+
+// DeleteReadReplica removes the read replica designation of a database. It returns
+// ErrReadReplicaNotFound if the database had none.
+func (s *sqlMetadataStore) DeleteReadReplica(ctx context.Context, databaseId int64) error {
+	result, err := s.exec(ctx, `DELETE FROM database_replicas WHERE database_id = ?`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete read replica of DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error deleting read replica: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrReadReplicaNotFound
+	}
+	return nil
+}
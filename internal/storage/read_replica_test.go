@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestReadReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+	ConfigureReadCache(0, 0) // Every read must reach a database
+	defer ConfigureReadCache(defaultReadCacheMaxEntries, defaultReadCacheTTL)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	id, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "first")
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	designate := func(maxLagSeconds int) {
+		readReplicas.sync(ctx, []domain.ReadReplica{{DatabaseID: 1, FilePath: dbPath, MaxLagSeconds: maxLagSeconds}})
+	}
+	designate(0)
+	defer readReplicas.sync(ctx, nil)
+	readReplicas.refresh(ctx)
+
+	const selectSQL = "SELECT * FROM notes WHERE id = ? LIMIT 1;"
+	get := func() string {
+		t.Helper()
+		record, err := GetRecord(ctx, userDB, selectSQL, id)
+		if err != nil {
+			t.Fatalf("GetRecord: %v", err)
+		}
+		body, _ := record["body"].(string)
+		return body
+	}
+
+	// In sync: a change made behind the storage layer's back only exists on the primary
+	if _, err := userDB.ExecContext(ctx, "UPDATE notes SET body = 'primary only' WHERE id = ?", id); err != nil {
+		t.Fatalf("direct update: %v", err)
+	}
+	if body := get(); body != "first" {
+		t.Errorf("in sync: body = %q; want 'first' from the replica", body)
+	}
+	if status, _ := ReadReplicaStatusOf(dbPath); !status.InSync || status.Reads != 1 || status.SyncedAt == nil {
+		t.Errorf("status = %+v; want in sync with one read", status)
+	}
+
+	// Writes send reads to the primary until the replica is refreshed
+	if _, err := UpdateRecord(ctx, userDB, "UPDATE notes SET body = ? WHERE id = ?", "second", id); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if body := get(); body != "second" {
+		t.Errorf("stale replica: body = %q; want 'second' from the primary", body)
+	}
+	readReplicas.refresh(ctx)
+	if status, _ := ReadReplicaStatusOf(dbPath); !status.InSync {
+		t.Errorf("after refresh: status = %+v; want in sync", status)
+	}
+
+	// Within the allowed lag a stale replica keeps serving reads
+	designate(60)
+	if _, err := UpdateRecord(ctx, userDB, "UPDATE notes SET body = ? WHERE id = ?", "third", id); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	if body := get(); body != "second" {
+		t.Errorf("within lag: body = %q; want 'second' from the replica", body)
+	}
+
+	// Dropping the designation deletes the replica
+	readReplicas.sync(ctx, nil)
+	if _, err := os.Stat(replicaPath(dbPath)); !os.IsNotExist(err) {
+		t.Errorf("replica file after removal: %v; want it deleted", err)
+	}
+	if body := get(); body != "third" {
+		t.Errorf("after removal: body = %q; want 'third'", body)
+	}
+}
+
+func TestReadReplicaUsable(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		replica *readReplica
+		usable  bool
+	}{
+		{"never copied", &readReplica{}, false},
+		{"in sync", &readReplica{writes: 3, syncedWrites: 3, syncedAt: now.Add(-time.Hour)}, true},
+		{"stale", &readReplica{writes: 4, syncedWrites: 3, syncedAt: now.Add(-time.Second)}, false},
+		{"within lag", &readReplica{writes: 4, syncedWrites: 3, syncedAt: now.Add(-time.Second), maxLag: time.Minute}, true},
+		{"beyond lag", &readReplica{writes: 4, syncedWrites: 3, syncedAt: now.Add(-time.Hour), maxLag: time.Minute}, false},
+		{"schema changed", &readReplica{writes: 4, syncedWrites: 3, schemaChanges: 1, syncedAt: now, maxLag: time.Minute}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.replica.usable(now); got != tc.usable {
+			t.Errorf("%s: usable = %t; want %t", tc.name, got, tc.usable)
+		}
+	}
+}
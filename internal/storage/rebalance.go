@@ -0,0 +1,138 @@
+// internal/storage/rebalance.go
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// RebalanceMove is a database moved, or to be moved, from one data root to another.
+type RebalanceMove struct {
+	DatabaseID int64  `json:"database_id"`
+	UserID     string `json:"user_id"`
+	DBName     string `json:"db_name"`
+	From       string `json:"from"` // Data roots
+	To         string `json:"to"`
+	Bytes      int64  `json:"bytes"`
+	Error      string `json:"error,omitempty"` // Why the move failed; the database stayed
+}
+
+// RebalanceResult lists the moves of a rebalance and the data roots after them (as they
+// are, for a dry run).
+type RebalanceResult struct {
+	DryRun    bool            `json:"dry_run"`
+	Moves     []RebalanceMove `json:"moves"`
+	DataRoots []DataRootUsage `json:"data_roots"`
+}
+
+// rebalanceCandidate is a database file that may be moved.
+type rebalanceCandidate struct {
+	db    domain.DatabaseMetadata
+	root  string
+	bytes int64
+}
+
+// planRebalance evens out the free space of data roots by repeatedly moving a database
+// from the root with the least free space to the one with the most: the largest that
+// does not leave the target with less free space than the source had. At most
+// maxMoves databases are moved; each at most once.
+func planRebalance(free map[string]int64, candidates []rebalanceCandidate, maxMoves int) []RebalanceMove {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].bytes > candidates[j].bytes })
+	roots := make([]string, 0, len(free))
+	for root := range free {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots) // Deterministic ties
+
+	moved := make(map[int64]bool)
+	moves := make([]RebalanceMove, 0)
+	for len(moves) < maxMoves && len(roots) > 1 {
+		src, dst := roots[0], roots[0]
+		for _, root := range roots {
+			if free[root] < free[src] {
+				src = root
+			}
+			if free[root] > free[dst] {
+				dst = root
+			}
+		}
+		half := (free[dst] - free[src]) / 2
+
+		var pick *rebalanceCandidate
+		for i := range candidates {
+			c := &candidates[i]
+			if c.root == src && !moved[c.db.DatabaseID] && c.bytes > 0 && c.bytes <= half {
+				pick = c
+				break // Sorted largest first
+			}
+		}
+		if pick == nil {
+			break
+		}
+		moved[pick.db.DatabaseID] = true
+		free[src] += pick.bytes
+		free[dst] -= pick.bytes
+		moves = append(moves, RebalanceMove{DatabaseID: pick.db.DatabaseID, UserID: pick.db.UserID, DBName: pick.db.DBName,
+			From: src, To: dst, Bytes: pick.bytes})
+	}
+	return moves
+}
+
+// Rebalance moves databases between the configured data roots to even out their free
+// space, at most maxMoves of them, with RelocateUserDB. With dryRun it only plans the
+// moves. A failed move is recorded in its Error and the rebalance goes on.
+func Rebalance(ctx context.Context, store MetadataStore, maxMoves int, dryRun bool) (*RebalanceResult, error) {
+	if userData.backend == BackendPostgres {
+		return nil, ErrUserDataUnsupported
+	}
+	databases, err := store.ListAllDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	free := make(map[string]int64)
+	for _, usage := range DataRootUsages(databases) {
+		if usage.Error == "" {
+			free[usage.Path] = int64(usage.FreeBytes)
+		}
+	}
+	candidates := make([]rebalanceCandidate, 0, len(databases))
+	byID := make(map[int64]domain.DatabaseMetadata, len(databases))
+	for _, db := range databases {
+		root := databaseRoot(db)
+		if _, ok := free[root]; !ok {
+			continue // Outside the configured roots, or on one whose free space is unknown
+		}
+		if db.Quarantine != "" {
+			continue // Its file may be missing or broken
+		}
+		byID[db.DatabaseID] = db
+		candidates = append(candidates, rebalanceCandidate{db: db, root: root, bytes: userDBFileSize(db.FilePath)})
+	}
+
+	result := &RebalanceResult{DryRun: dryRun, Moves: planRebalance(free, candidates, maxMoves)}
+	if !dryRun {
+		for i := range result.Moves {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			move := &result.Moves[i]
+			db := byID[move.DatabaseID]
+			to := UserDataLocation(move.To, db.UserID, db.DBName)
+			_, err := RelocateUserDB(ctx, db.FilePath, to, func(ctx context.Context) error {
+				return store.SetDatabaseLocation(ctx, db.DatabaseID, to, move.To, PlacementRebalance)
+			})
+			if err != nil {
+				move.Error = err.Error()
+				customLog.Ctx(ctx).Warnf("Storage: Rebalance failed to move DBID %d to '%s': %v", db.DatabaseID, move.To, err)
+			}
+		}
+		if databases, err = store.ListAllDatabases(ctx); err != nil {
+			return nil, err
+		}
+	}
+	result.DataRoots = DataRootUsages(databases)
+	return result, nil
+}
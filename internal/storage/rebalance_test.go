@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestPlanRebalance(t *testing.T) {
+	candidate := func(id int64, root string, bytes int64) rebalanceCandidate {
+		return rebalanceCandidate{db: domain.DatabaseMetadata{DatabaseID: id}, root: root, bytes: bytes}
+	}
+	free := map[string]int64{"/a": 100, "/b": 500, "/c": 400}
+	candidates := []rebalanceCandidate{
+		candidate(1, "/a", 300), // Would leave /b with less free space than /a had
+		candidate(2, "/a", 150),
+		candidate(3, "/a", 60),
+		candidate(4, "/c", 10),
+	}
+
+	moves := planRebalance(free, candidates, 10)
+	got := make([][2]any, len(moves))
+	for i, move := range moves {
+		got[i] = [2]any{move.DatabaseID, move.To}
+	}
+	// Free space: /a 100 -> 250 -> 310, /b 500 -> 350, /c 400 -> 340
+	want := [][2]any{{int64(2), "/b"}, {int64(3), "/c"}}
+	if len(got) != len(want) {
+		t.Fatalf("moves = %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("move %d = %v; want %v", i, got[i], want[i])
+		}
+	}
+
+	if moves := planRebalance(map[string]int64{"/a": 100, "/b": 500}, []rebalanceCandidate{candidate(1, "/a", 150)}, 0); len(moves) != 0 {
+		t.Errorf("maxMoves 0: moves = %v", moves)
+	}
+	if moves := planRebalance(map[string]int64{"/a": 100}, []rebalanceCandidate{candidate(1, "/a", 10)}, 5); len(moves) != 0 {
+		t.Errorf("single root: moves = %v", moves)
+	}
+}
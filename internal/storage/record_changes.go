@@ -0,0 +1,113 @@
+// internal/storage/record_changes.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Record change events, named as webhooks and the event bridge publish them.
+const (
+	RecordCreated = "record.created"
+	RecordUpdated = "record.updated"
+	RecordDeleted = "record.deleted"
+)
+
+// RecordChange is a committed write of one record of a tenant database.
+type RecordChange struct {
+	Location string // Storage location of the database
+	Event    string // RecordCreated, RecordUpdated or RecordDeleted
+	Table    string
+	RecordID int64
+}
+
+// recordChanges holds the listener every committed record write is reported to,
+// whichever API or job made it.
+var recordChanges struct {
+	mu       sync.RWMutex
+	listener func(ctx context.Context, change RecordChange)
+}
+
+// OnRecordChange sets the function told about every committed record write: the record
+// operations of the stores returned by OpenUserData, GraphQL mutations and table copies.
+// It runs synchronously after the write, so it should hand slow work off. Call once at
+// startup; nil stops the notifications.
+func OnRecordChange(listener func(ctx context.Context, change RecordChange)) {
+	recordChanges.mu.Lock()
+	defer recordChanges.mu.Unlock()
+	recordChanges.listener = listener
+}
+
+func recordChangeListener() func(ctx context.Context, change RecordChange) {
+	recordChanges.mu.RLock()
+	defer recordChanges.mu.RUnlock()
+	return recordChanges.listener
+}
+
+// notifyRecordChanges reports event for each of recordIDs of table in the database
+// stored at location.
+func notifyRecordChanges(ctx context.Context, location, event, table string, recordIDs ...int64) {
+	listener := recordChangeListener()
+	if listener == nil || location == "" {
+		return
+	}
+	for _, id := range recordIDs {
+		listener(ctx, RecordChange{Location: location, Event: event, Table: table, RecordID: id})
+	}
+}
+
+// RecordWritten reports a committed record write made with a pooled SQLite handle
+// rather than through a UserDataStore (the GraphQL executor writes that way).
+func RecordWritten(ctx context.Context, userDB *sql.DB, event, table string, recordID int64) {
+	if recordChangeListener() == nil {
+		return
+	}
+	notifyRecordChanges(ctx, userDBs.pathOf(userDB), event, table, recordID)
+}
+
+// notifyUserData wraps store to report its record writes, unless no one listens.
+func notifyUserData(store UserDataStore, location string) UserDataStore {
+	if recordChangeListener() == nil {
+		return store
+	}
+	return &notifyingUserData{UserDataStore: store, location: location}
+}
+
+// notifyingUserData reports the committed record writes of a UserDataStore.
+type notifyingUserData struct {
+	UserDataStore
+	location string
+}
+
+func (s *notifyingUserData) InsertRecord(ctx context.Context, tableName string, columns []string, values []any) (int64, error) {
+	id, err := s.UserDataStore.InsertRecord(ctx, tableName, columns, values)
+	if err == nil {
+		notifyRecordChanges(ctx, s.location, RecordCreated, tableName, id)
+	}
+	return id, err
+}
+
+func (s *notifyingUserData) UpdateRecord(ctx context.Context, tableName string, recordID int64, columns []string, values []any) (int64, error) {
+	affected, err := s.UserDataStore.UpdateRecord(ctx, tableName, recordID, columns, values)
+	if err == nil {
+		notifyRecordChanges(ctx, s.location, RecordUpdated, tableName, recordID)
+	}
+	return affected, err
+}
+
+func (s *notifyingUserData) DeleteRecord(ctx context.Context, tableName string, recordID int64) (int64, error) {
+	affected, err := s.UserDataStore.DeleteRecord(ctx, tableName, recordID)
+	if err == nil {
+		notifyRecordChanges(ctx, s.location, RecordDeleted, tableName, recordID)
+	}
+	return affected, err
+}
+
+func (s *notifyingUserData) DeleteDuplicates(ctx context.Context, tableName string, columns []string, keepLast bool) ([]int64, error) {
+	deleted, err := s.UserDataStore.DeleteDuplicates(ctx, tableName, columns, keepLast)
+	if err == nil {
+		notifyRecordChanges(ctx, s.location, RecordDeleted, tableName, deleted...)
+	}
+	return deleted, err
+}
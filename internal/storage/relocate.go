@@ -0,0 +1,292 @@
+// internal/storage/relocate.go
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrRelocationMismatch is returned when the copy made to move a database does not
+// match the original.
+var ErrRelocationMismatch = errors.New("copy of the database does not match the original")
+
+// ErrUnknownDataRoot is returned when databases are to be moved to a directory that is
+// not a configured data root.
+var ErrUnknownDataRoot = errors.New("not a configured data root")
+
+// ErrDatabaseMoved is returned for a database file that was moved after the request
+// looked up its location. Retrying finds the new one. It also matches ErrDatabaseBusy.
+var ErrDatabaseMoved = fmt.Errorf("database was moved, retry the request: %w", ErrDatabaseBusy)
+
+// movedFileMemory is how long the old path of a moved database keeps failing with
+// ErrDatabaseMoved instead of opening (and creating) an empty file there.
+const movedFileMemory = 10 * time.Minute
+
+// movedFiles remembers the paths user DB files were recently moved away from.
+type movedFiles struct {
+	mu sync.Mutex
+	at map[string]time.Time
+}
+
+var movedUserDBs = &movedFiles{at: make(map[string]time.Time)}
+
+func (m *movedFiles) add(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for p, at := range m.at {
+		if now.Sub(at) > movedFileMemory {
+			delete(m.at, p)
+		}
+	}
+	m.at[path] = now
+}
+
+// forget clears path, once a database is placed there again.
+func (m *movedFiles) forget(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.at, path)
+}
+
+func (m *movedFiles) moved(path string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	at, ok := m.at[path]
+	return ok && time.Since(at) <= movedFileMemory
+}
+
+// RelocateUserDB moves the user DB file at from to to (e.g. another data root) while
+// it stays readable: writes wait until the move is done, the file is snapshotted into
+// to with the online backup API and the copy verified against the original's checksum,
+// commit records the new location, and only then is the old file removed. Writes that
+// were waiting, and requests that looked up the old location, fail with
+// ErrDatabaseMoved. If verification or commit fails the copy is removed and the
+// database stays. Returns the checksum of the moved contents ("" for a file that was
+// never created).
+func RelocateUserDB(ctx context.Context, from, to string, commit func(ctx context.Context) error) (string, error) {
+	if userData.backend == BackendPostgres {
+		return "", ErrUserDataUnsupported
+	}
+	if from == to {
+		return "", fmt.Errorf("cannot move database: '%s' is already there", to)
+	}
+	if _, err := os.Stat(to); err == nil {
+		return "", fmt.Errorf("cannot move database: '%s' already exists", to)
+	}
+	movedUserDBs.forget(to)
+
+	unlock, err := userDBWriteLocks.lock(ctx, from)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	// A file that was never created (no table was ever added) only needs its new location
+	var checksum string
+	if _, err := os.Stat(from); err == nil {
+		if checksum, err = copyUserDBFile(ctx, from, to); err != nil {
+			_ = removeUserDBFiles(ctx, to)
+			return "", err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	} else if err := os.MkdirAll(filepath.Dir(to), 0o750); err != nil {
+		return "", err
+	}
+
+	if err := commit(ctx); err != nil {
+		_ = removeUserDBFiles(ctx, to)
+		return "", err
+	}
+	movedUserDBs.add(from)
+	InvalidateUserDB(from)
+	_ = removeUserDBFiles(ctx, from)
+	customLog.Ctx(ctx).Printf("Storage: Moved user DB '%s' to '%s' (checksum %s)", from, to, checksum)
+	return checksum, nil
+}
+
+// copyUserDBFile copies the committed state of the user DB at from into a new file at
+// to, which shares its encryption key as long as both are in a directory of the same
+// account, and returns the checksum both have. The caller keeps writes out.
+func copyUserDBFile(ctx context.Context, from, to string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(to), 0o750); err != nil {
+		return "", err
+	}
+	srcDB, err := userDBs.acquire(ctx, from)
+	if err != nil {
+		return "", err
+	}
+	defer userDBs.release(srcDB)
+	dstDB, err := userDBs.acquire(ctx, to)
+	if err != nil {
+		return "", err
+	}
+	defer userDBs.release(dstDB)
+	if err := copyDatabase(ctx, dstDB, srcDB); err != nil {
+		return "", fmt.Errorf("failed to copy '%s' to '%s': %w", from, to, err)
+	}
+
+	want, err := userDBChecksum(ctx, srcDB)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum '%s': %w", from, err)
+	}
+	got, err := userDBChecksum(ctx, dstDB)
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum '%s': %w", to, err)
+	}
+	if got != want {
+		return "", fmt.Errorf("%w: '%s' has checksum %s, '%s' %s", ErrRelocationMismatch, from, want, to, got)
+	}
+	return want, nil
+}
+
+// userDBChecksum returns a SHA-256 over the schema and rows of a user DB. It covers
+// contents rather than file bytes, which differ between encrypted copies of one
+// database; rows are hashed in scan order, which a page-for-page copy preserves.
+func userDBChecksum(ctx context.Context, db *sql.DB) (string, error) {
+	// Unlike listSchemaObjects, trashed tables count: they are part of the file
+	rows, err := queryWithRetry(ctx, db, `SELECT type, name, COALESCE(sql, '') FROM sqlite_master ORDER BY type, name`)
+	if err != nil {
+		return "", err
+	}
+	var objects []schemaObject
+	for rows.Next() {
+		var obj schemaObject
+		if err := rows.Scan(&obj.Type, &obj.Name, &obj.SQL); err != nil {
+			rows.Close()
+			return "", err
+		}
+		objects = append(objects, obj)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, obj := range objects {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", obj.Type, obj.Name, obj.SQL)
+		if obj.Type != "table" {
+			continue
+		}
+		if err := hashRows(ctx, db, h, obj.Name); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashRows writes every row of table to w, each value tagged with its type.
+func hashRows(ctx context.Context, db *sql.DB, w io.Writer, table string) error {
+	rows, err := queryWithRetry(ctx, db, fmt.Sprintf(`SELECT * FROM "%s"`, strings.ReplaceAll(table, `"`, `""`)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for _, v := range values {
+			fmt.Fprintf(w, "%T:%v\x00", v, v)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	return rows.Err()
+}
+
+// removeUserDBFiles closes and deletes a user DB file with its -wal and -shm files,
+// returning the failures. Missing files are not an error.
+func removeUserDBFiles(ctx context.Context, path string) error {
+	userDBs.invalidate(path)
+	var errs []error
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to remove '%s%s': %v", path, suffix, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Relocation is the move of one database by RelocateUserDatabases.
+type Relocation struct {
+	DatabaseID int64  `json:"database_id"`
+	DBName     string `json:"db_name"`
+	From       string `json:"from"` // File paths
+	To         string `json:"to"`
+	Checksum   string `json:"checksum,omitempty"` // SHA-256 of the contents, verified on the copy
+	Skipped    bool   `json:"skipped,omitempty"`  // Already on the data root
+	Error      string `json:"error,omitempty"`    // Why the move failed; the database stayed
+}
+
+// RelocateUserDatabases moves the databases of userId (only dbName, if not empty) to
+// dataRoot, one at a time with RelocateUserDB, e.g. to take a disk out of service. A
+// failed move is recorded in its Error and the others go on.
+func RelocateUserDatabases(ctx context.Context, store MetadataStore, userId, dbName, dataRoot string) ([]Relocation, error) {
+	if userData.backend == BackendPostgres {
+		return nil, ErrUserDataUnsupported
+	}
+	if !IsDataRoot(dataRoot) {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownDataRoot, dataRoot)
+	}
+	dataRoot = filepath.Clean(dataRoot)
+	all, err := store.ListAllDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var databases []domain.DatabaseMetadata
+	for _, db := range all {
+		if db.UserID == userId && (dbName == "" || db.DBName == dbName) {
+			databases = append(databases, db)
+		}
+	}
+	if len(databases) == 0 {
+		return nil, ErrDatabaseNotFound
+	}
+
+	relocations := make([]Relocation, 0, len(databases))
+	for _, db := range databases {
+		if ctx.Err() != nil {
+			return relocations, ctx.Err()
+		}
+		to := UserDataLocation(dataRoot, db.UserID, db.DBName)
+		relocation := Relocation{DatabaseID: db.DatabaseID, DBName: db.DBName, From: db.FilePath, To: to}
+		if filepath.Clean(db.FilePath) == to {
+			relocation.Skipped = true
+			relocations = append(relocations, relocation)
+			continue
+		}
+		relocation.Checksum, err = RelocateUserDB(ctx, db.FilePath, to, func(ctx context.Context) error {
+			return store.SetDatabaseLocation(ctx, db.DatabaseID, to, dataRoot, PlacementRelocate)
+		})
+		if err != nil {
+			relocation.Error = err.Error()
+			customLog.Ctx(ctx).Warnf("Storage: Failed to relocate DBID %d to '%s': %v", db.DatabaseID, dataRoot, err)
+		}
+		relocations = append(relocations, relocation)
+	}
+	return relocations, nil
+}
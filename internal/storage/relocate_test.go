@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestRelocateUserDB(t *testing.T) {
+	ctx := context.Background()
+	from := filepath.Join(t.TempDir(), "u1", "app.db")
+	to := filepath.Join(t.TempDir(), "u1", "app.db")
+	if err := os.MkdirAll(filepath.Dir(from), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	userDB, err := ConnectUserDB(ctx, from)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	id, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "first")
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	// A failed commit leaves the database where it was
+	failed := errors.New("metadata down")
+	if _, err := RelocateUserDB(ctx, from, to, func(context.Context) error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("RelocateUserDB with failing commit = %v; want %v", err, failed)
+	}
+	if _, err := os.Stat(to); !os.IsNotExist(err) {
+		t.Errorf("copy left behind after failed commit: %v", err)
+	}
+
+	committed := false
+	checksum, err := RelocateUserDB(ctx, from, to, func(context.Context) error { committed = true; return nil })
+	if err != nil || !committed || len(checksum) != 64 {
+		t.Fatalf("RelocateUserDB = %q, %v (committed %t); want a SHA-256", checksum, err, committed)
+	}
+	defer InvalidateUserDB(to)
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Errorf("old file after move: %v; want it removed", err)
+	}
+
+	// Requests holding the old handle or location are sent to look it up again
+	if _, err := UpdateRecord(ctx, userDB, "UPDATE notes SET body = ? WHERE id = ?", "lost", id); !errors.Is(err, ErrDatabaseMoved) || !errors.Is(err, ErrDatabaseBusy) {
+		t.Errorf("write through the old handle = %v; want ErrDatabaseMoved", err)
+	}
+	ReleaseUserDB(userDB)
+	if _, err := ConnectUserDB(ctx, from); !errors.Is(err, ErrDatabaseMoved) {
+		t.Errorf("ConnectUserDB(old path) = %v; want ErrDatabaseMoved", err)
+	}
+
+	movedDB, err := ConnectUserDB(ctx, to)
+	if err != nil {
+		t.Fatalf("ConnectUserDB(new path): %v", err)
+	}
+	defer ReleaseUserDB(movedDB)
+	record, err := GetRecord(ctx, movedDB, "SELECT * FROM notes WHERE id = ? LIMIT 1;", id)
+	if err != nil || record["body"] != "first" {
+		t.Errorf("GetRecord after move = %v, %v; want 'first'", record, err)
+	}
+
+	// Placing a new database at the old path makes it usable again
+	if err := PrepareUserData(ctx, from); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	reused, err := ConnectUserDB(ctx, from)
+	if err != nil {
+		t.Fatalf("ConnectUserDB(reused path): %v", err)
+	}
+	ReleaseUserDB(reused)
+	InvalidateUserDB(from)
+}
+
+func TestRelocateUserDatabases(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	oldRoot, newRoot := t.TempDir(), t.TempDir()
+	if err := ConfigureDataRoots([]string{oldRoot, newRoot}, PlacementMostFree); err != nil {
+		t.Fatalf("ConfigureDataRoots: %v", err)
+	}
+	defer func() { dataRoots.roots = nil }()
+
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	from := UserDataLocation(oldRoot, "u1", "app")
+	if err := PrepareUserData(ctx, from); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "app", from); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	userDB, err := ConnectUserDB(ctx, from)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	err = CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)")
+	ReleaseUserDB(userDB)
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	if _, err := RelocateUserDatabases(ctx, store, "u1", "", t.TempDir()); !errors.Is(err, ErrUnknownDataRoot) {
+		t.Errorf("relocating outside the data roots = %v; want ErrUnknownDataRoot", err)
+	}
+
+	relocations, err := RelocateUserDatabases(ctx, store, "u1", "", newRoot)
+	if err != nil || len(relocations) != 1 || relocations[0].Error != "" || relocations[0].Checksum == "" {
+		t.Fatalf("RelocateUserDatabases = %+v, %v; want one verified move", relocations, err)
+	}
+	to := UserDataLocation(newRoot, "u1", "app")
+	defer InvalidateUserDB(to)
+	db, err := store.FindDatabaseByID(ctx, relocations[0].DatabaseID)
+	if err != nil || db.FilePath != to || db.DataRoot != newRoot || db.Placement != PlacementRelocate {
+		t.Errorf("registration after move = %+v, %v; want it on %s", db, err, newRoot)
+	}
+
+	// Moving again finds it already there
+	relocations, err = RelocateUserDatabases(ctx, store, "u1", "app", newRoot)
+	if err != nil || len(relocations) != 1 || !relocations[0].Skipped {
+		t.Errorf("second RelocateUserDatabases = %+v, %v; want it skipped", relocations, err)
+	}
+}
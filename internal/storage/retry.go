@@ -0,0 +1,95 @@
+// internal/storage/retry.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrDatabaseBusy is returned when a user DB stayed locked for longer than the caller
+// was willing to wait. Handlers surface it as 503 with a Retry-After header.
+var ErrDatabaseBusy = errors.New("database is busy, please retry")
+
+// Backoff settings for busy/locked retries
+const (
+	busyRetryBaseDelay = 10 * time.Millisecond
+	busyRetryMaxDelay  = 500 * time.Millisecond
+	// busyRetryBudget bounds retries for contexts without a deadline
+	busyRetryBudget = 10 * time.Second
+)
+
+// isBusyError reports whether err is SQLite signalling SQLITE_BUSY or SQLITE_LOCKED.
+func isBusyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
+
+// retryBusy runs fn until it succeeds, fails with a non-busy error, or the context
+// deadline (or busyRetryBudget) is reached. Waits grow exponentially with full jitter.
+// A persistent busy error is returned wrapped in ErrDatabaseBusy.
+func retryBusy(ctx context.Context, fn func() error) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(busyRetryBudget)
+	}
+
+	delay := busyRetryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !isBusyError(err) {
+			return err
+		}
+
+		wait := time.Duration(rand.Int64N(int64(delay))) + time.Millisecond
+		if time.Now().Add(wait).After(deadline) {
+			customLog.Ctx(ctx).Warnf("Storage: Giving up on busy database after %d attempts: %v", attempt, err)
+			return fmt.Errorf("%w: %v", ErrDatabaseBusy, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %v", ErrDatabaseBusy, err)
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, busyRetryMaxDelay)
+	}
+}
+
+// execWithRetry is ExecContext with busy/locked retries.
+func execWithRetry(ctx context.Context, db *sql.DB, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := retryBusy(ctx, func() error {
+		var err error
+		result, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// queryWithRetry is QueryContext with busy/locked retries.
+func queryWithRetry(ctx context.Context, db *sql.DB, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retryBusy(ctx, func() error {
+		var err error
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestRetryBusyRetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	err := retryBusy(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryBusy = %v after %d calls; want success after 3", err, calls)
+	}
+}
+
+func TestRetryBusyGivesUpAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := retryBusy(ctx, func() error {
+		return errors.New("database is locked")
+	})
+	if !errors.Is(err, ErrDatabaseBusy) {
+		t.Errorf("retryBusy err = %v; want ErrDatabaseBusy", err)
+	}
+}
+
+func TestRetryBusyPassesOtherErrorsThrough(t *testing.T) {
+	calls := 0
+	want := errors.New("no such table: notes")
+	err := retryBusy(context.Background(), func() error {
+		calls++
+		return want
+	})
+	if err != want || calls != 1 {
+		t.Errorf("retryBusy = %v after %d calls; want %v after 1", err, calls, want)
+	}
+}
@@ -0,0 +1,76 @@
+// internal/storage/row_limit.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrRowLimitReached is returned when an insert would grow a table past its row limit.
+var ErrRowLimitReached = errors.New("table row limit reached")
+
+// RowLimitError reports the limit an insert ran into. Configured is true when the limit
+// is the table's own (TableOptions.MaxRows) rather than the server-wide one.
+type RowLimitError struct {
+	Table      string
+	Limit      int64
+	Configured bool
+}
+
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("table '%s' has reached its limit of %d rows", e.Table, e.Limit)
+}
+
+func (e *RowLimitError) Unwrap() error { return ErrRowLimitReached }
+
+// RowLimit returns the row limit of a table: the lower of the server-wide limit and the
+// table's own, ignoring unset (0) ones, or 0 when neither is set. configured reports
+// whether the table's own limit is the one that applies.
+func RowLimit(serverMax int64, options domain.TableOptions) (limit int64, configured bool) {
+	if options.MaxRows > 0 && (serverMax <= 0 || options.MaxRows <= serverMax) {
+		return options.MaxRows, true
+	}
+	return max(serverMax, 0), false
+}
+
+// CheckRowLimit returns a *RowLimitError when adding rows to tableName would grow it
+// past limit. A limit of 0 allows any number of rows. userDB is nil for a table that is
+// about to be created, which holds no rows yet.
+func CheckRowLimit(ctx context.Context, userDB UserDataStore, tableName string, limit int64, configured bool, adding int64) error {
+	if limit <= 0 {
+		return nil
+	}
+	var count int64
+	if userDB != nil {
+		var err error
+		if count, err = userDB.CountRecords(ctx, tableName, limit); err != nil {
+			return err
+		}
+	}
+	if count+adding > limit {
+		return &RowLimitError{Table: tableName, Limit: limit, Configured: configured}
+	}
+	return nil
+}
+
+// CountRecords counts the rows of a table in a SQLite database, stopping at limit so
+// large tables are not scanned in full.
+func CountRecords(ctx context.Context, userDB *sql.DB, tableName string, limit int64) (int64, error) {
+	query := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		return queryWithRetry(ctx, userDB, query, args...)
+	}
+	count, err := countRows(ctx, query, fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s LIMIT ?) AS capped", tableName), limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return 0, ErrTableNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to count records of table '%s': %v", tableName, err)
+		return 0, fmt.Errorf("database error counting records: %w", err)
+	}
+	return int64(count), nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+func TestRowLimit(t *testing.T) {
+	cases := []struct {
+		serverMax, tableMax int64
+		limit               int64
+		configured          bool
+	}{
+		{0, 0, 0, false},
+		{100, 0, 100, false},
+		{0, 10, 10, true},
+		{100, 10, 10, true},
+		{100, 100, 100, true},
+		{10, 100, 10, false},
+	}
+	for _, tc := range cases {
+		limit, configured := RowLimit(tc.serverMax, domain.TableOptions{MaxRows: tc.tableMax})
+		if limit != tc.limit || configured != tc.configured {
+			t.Errorf("RowLimit(%d, %d) = %d, %v; want %d, %v", tc.serverMax, tc.tableMax, limit, configured, tc.limit, tc.configured)
+		}
+	}
+}
+
+func TestCheckRowLimit(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	if err := store.CreateTable(ctx, "notes", []core.ColumnSpec{{Name: "body", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	for _, body := range []string{"a", "b", "c"} {
+		if _, err := store.InsertRecord(ctx, "notes", []string{"body"}, []any{body}); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+
+	if count, err := store.CountRecords(ctx, "notes", 2); err != nil || count != 2 {
+		t.Errorf("CountRecords(limit 2) = %d, %v; want 2", count, err)
+	}
+	if err := CheckRowLimit(ctx, store, "notes", 4, true, 1); err != nil {
+		t.Errorf("CheckRowLimit(4, +1) = %v; want nil", err)
+	}
+	err = CheckRowLimit(ctx, store, "notes", 4, true, 2)
+	var limitErr *RowLimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrRowLimitReached) || limitErr.Limit != 4 || !limitErr.Configured {
+		t.Errorf("CheckRowLimit(4, +2) = %v; want a configured limit of 4", err)
+	}
+	if err := CheckRowLimit(ctx, nil, "new_table", 4, false, 4); err != nil {
+		t.Errorf("CheckRowLimit(new table, +4) = %v; want nil", err)
+	}
+	if _, err := store.CountRecords(ctx, "missing", 1); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("CountRecords(missing) = %v; want ErrTableNotFound", err)
+	}
+}
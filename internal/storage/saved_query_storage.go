@@ -0,0 +1,101 @@
+// internal/storage/saved_query_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrSavedQueryNotFound is returned when a database has no saved query of a name.
+var ErrSavedQueryNotFound = errors.New("saved query not found")
+
+// savedQueryDefinition is the part of a domain.SavedQuery stored as JSON.
+type savedQueryDefinition struct {
+	Description string              `json:"description,omitempty"`
+	Query       map[string]string   `json:"query"`
+	Params      []domain.QueryParam `json:"params,omitempty"`
+}
+
+const savedQueryColumns = `name, table_name, definition, created_at, updated_at`
+
+func scanSavedQuery(scan func(dest ...any) error) (*domain.SavedQuery, error) {
+	var query domain.SavedQuery
+	var definition string
+	if err := scan(&query.Name, &query.TableName, &definition, &query.CreatedAt, &query.UpdatedAt); err != nil {
+		return nil, err
+	}
+	var def savedQueryDefinition
+	if err := json.Unmarshal([]byte(definition), &def); err != nil {
+		return nil, fmt.Errorf("corrupt definition of saved query '%s': %w", query.Name, err)
+	}
+	query.Description, query.Query, query.Params = def.Description, def.Query, def.Params
+	return &query, nil
+}
+
+// ListSavedQueries returns the saved queries of a database, ordered by name.
+func (s *sqlMetadataStore) ListSavedQueries(ctx context.Context, databaseId int64) ([]domain.SavedQuery, error) {
+	rows, err := s.query(ctx, `SELECT `+savedQueryColumns+` FROM saved_queries WHERE database_id = ? ORDER BY name`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list saved queries for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing saved queries: %w", err)
+	}
+	defer rows.Close()
+
+	queries := []domain.SavedQuery{}
+	for rows.Next() {
+		query, err := scanSavedQuery(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading saved queries: %w", err)
+		}
+		queries = append(queries, *query)
+	}
+	return queries, rows.Err()
+}
+
+// GetSavedQuery returns one saved query of a database.
+func (s *sqlMetadataStore) GetSavedQuery(ctx context.Context, databaseId int64, name string) (*domain.SavedQuery, error) {
+	query, err := scanSavedQuery(s.queryRow(ctx, `SELECT `+savedQueryColumns+` FROM saved_queries WHERE database_id = ? AND name = ?`, databaseId, name).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSavedQueryNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get saved query '%s' for DBID %d: %v", name, databaseId, err)
+		return nil, fmt.Errorf("database error getting saved query: %w", err)
+	}
+	return query, nil
+}
+
+// PutSavedQuery creates or replaces a saved query. It is expected to be validated by
+// core.ValidateSavedQuery.
+func (s *sqlMetadataStore) PutSavedQuery(ctx context.Context, databaseId int64, query domain.SavedQuery) error {
+	definition, err := json.Marshal(savedQueryDefinition{Description: query.Description, Query: query.Query, Params: query.Params})
+	if err != nil {
+		return fmt.Errorf("failed to encode saved query: %w", err)
+	}
+	_, err = s.exec(ctx, `INSERT INTO saved_queries (database_id, name, table_name, definition) VALUES (?, ?, ?, ?)
+		ON CONFLICT (database_id, name) DO UPDATE SET table_name = excluded.table_name, definition = excluded.definition, updated_at = CURRENT_TIMESTAMP`,
+		databaseId, query.Name, query.TableName, string(definition))
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store saved query '%s' for DBID %d: %v", query.Name, databaseId, err)
+		return fmt.Errorf("database error storing saved query: %w", err)
+	}
+	return nil
+}
+
+// DeleteSavedQuery removes a saved query, returning ErrSavedQueryNotFound if it did not exist.
+func (s *sqlMetadataStore) DeleteSavedQuery(ctx context.Context, databaseId int64, name string) error {
+	result, err := s.exec(ctx, `DELETE FROM saved_queries WHERE database_id = ? AND name = ?`, databaseId, name)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete saved query '%s' for DBID %d: %v", name, databaseId, err)
+		return fmt.Errorf("database error deleting saved query: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrSavedQueryNotFound
+	}
+	return nil
+}
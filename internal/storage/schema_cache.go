@@ -0,0 +1,133 @@
+// internal/storage/schema_cache.go
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// schemaCacheEntry holds the cached column types of every looked-up table in one user DB.
+type schemaCacheEntry struct {
+	generation uint64                       // Bumped on every invalidation
+	tables     map[string]map[string]string // lower(table) -> column -> type
+	tableCount int64                        // Tables outside the trash, valid when counted
+	counted    bool
+}
+
+// schemaCache caches PragmaTableInfo results per (user DB file path, table), and the
+// number of tables of each file. Entries are dropped whenever the schema of a file may
+// have changed.
+type schemaCache struct {
+	mu   sync.Mutex
+	byDB map[string]*schemaCacheEntry
+}
+
+var tableSchemas = &schemaCache{byDB: make(map[string]*schemaCacheEntry)}
+
+// size returns the number of user DBs with cached schemas and of cached tables.
+func (s *schemaCache) size() (databases, tables int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.byDB {
+		if len(entry.tables) > 0 {
+			databases++
+			tables += len(entry.tables)
+		}
+	}
+	return databases, tables
+}
+
+// get returns a copy of the cached column types and the current generation for path.
+func (s *schemaCache) get(path, tableName string) (map[string]string, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.byDB[path]
+	if !ok {
+		return nil, 0, false
+	}
+	columns, ok := entry.tables[strings.ToLower(tableName)]
+	if !ok {
+		return nil, entry.generation, false
+	}
+	return copyColumnTypes(columns), entry.generation, true
+}
+
+// put stores column types unless the schema was invalidated since generation was read.
+func (s *schemaCache) put(path, tableName string, generation uint64, columns map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.byDB[path]
+	if !ok {
+		entry = &schemaCacheEntry{tables: make(map[string]map[string]string)}
+		s.byDB[path] = entry
+	}
+	if entry.generation != generation {
+		return
+	}
+	entry.tables[strings.ToLower(tableName)] = copyColumnTypes(columns)
+}
+
+// invalidate drops cached schemas for one table, or for the whole file when tableName is "".
+func (s *schemaCache) invalidate(path, tableName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.byDB[path]
+	if !ok {
+		// Remember the bump so an in-flight lookup cannot store a pre-change schema
+		s.byDB[path] = &schemaCacheEntry{generation: 1, tables: make(map[string]map[string]string)}
+		return
+	}
+	entry.generation++
+	entry.counted = false // Any schema change may create, drop or trash a table
+	if tableName == "" {
+		entry.tables = make(map[string]map[string]string)
+		return
+	}
+	delete(entry.tables, strings.ToLower(tableName))
+}
+
+// getTableCount returns the cached table count and the current generation for path.
+func (s *schemaCache) getTableCount(path string) (int64, uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.byDB[path]
+	if !ok {
+		return 0, 0, false
+	}
+	return entry.tableCount, entry.generation, entry.counted
+}
+
+// putTableCount stores the table count unless the schema was invalidated since
+// generation was read.
+func (s *schemaCache) putTableCount(path string, generation uint64, count int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.byDB[path]
+	if !ok {
+		entry = &schemaCacheEntry{tables: make(map[string]map[string]string)}
+		s.byDB[path] = entry
+	}
+	if entry.generation != generation {
+		return
+	}
+	entry.tableCount, entry.counted = count, true
+}
+
+// invalidateTableSchema drops the cached schema of tableName (or every table when
+// tableName is "") in the DB behind userDB.
+func invalidateTableSchema(userDB *sql.DB, tableName string) {
+	if path := userDBs.pathOf(userDB); path != "" {
+		tableSchemas.invalidate(path, tableName)
+		readReplicas.primaryChanged(path, true)
+		notifyInvalidation(Invalidation{Kind: InvalidateSchema, Path: path, Table: tableName})
+	}
+}
+
+func copyColumnTypes(columns map[string]string) map[string]string {
+	copied := make(map[string]string, len(columns))
+	for name, sqlType := range columns {
+		copied[name] = sqlType
+	}
+	return copied
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestCountUserTablesInvalidatedBySchemaChanges(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if count, err := countUserTables(ctx, dbPath); err != nil || count != 1 {
+		t.Fatalf("countUserTables = %d, %v; want 1", count, err)
+	}
+
+	// A table created behind the storage layer's back is not seen while the count is cached
+	if _, err := userDB.ExecContext(ctx, "CREATE TABLE sneaky (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatalf("direct create: %v", err)
+	}
+	if count, _ := countUserTables(ctx, dbPath); count != 1 {
+		t.Fatalf("countUserTables = %d; want the cached 1", count)
+	}
+
+	// Schema changes through the storage layer drop the cached count; trashed tables are not counted
+	if err := RenameTable(ctx, userDB, "notes", core.TrashedTablePrefix+"notes"); err != nil {
+		t.Fatalf("RenameTable: %v", err)
+	}
+	if count, err := countUserTables(ctx, dbPath); err != nil || count != 1 {
+		t.Errorf("countUserTables after trashing = %d, %v; want 1", count, err)
+	}
+	if err := DropTable(ctx, userDB, "sneaky"); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+	if count, err := countUserTables(ctx, dbPath); err != nil || count != 0 {
+		t.Errorf("countUserTables after drop = %d, %v; want 0", count, err)
+	}
+}
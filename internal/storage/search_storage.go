@@ -0,0 +1,69 @@
+// internal/storage/search_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// likeEscaper escapes the LIKE wildcards of a search term, with \ as escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchText returns up to limit rows of from (a table name, qualified if the backend
+// needs it) where any of columns contains term, case-insensitively, ordered by id.
+// columns must be validated column names of the table.
+func searchText(ctx context.Context, d dialect, query func(ctx context.Context, query string, args ...any) (*sql.Rows, error),
+	from string, columns []string, term string, limit int) ([]map[string]any, error) {
+	operator := "LIKE" // Case-insensitive for ASCII in SQLite
+	if d.name == BackendPostgres {
+		operator = `COLLATE "default" ILIKE` // Nondeterministic (nocase) collations cannot pattern match
+	}
+	pattern := "%" + likeEscaper.Replace(term) + "%"
+	conditions := make([]string, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf(`%s %s ? ESCAPE '\'`, column, operator)
+		args = append(args, pattern)
+	}
+	args = append(args, limit)
+
+	// nolint:gosec // from and columns are validated
+	searchSQL := d.rebind(fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY id LIMIT ?", from, strings.Join(conditions, " OR ")))
+	rows, err := query(ctx, searchSQL, args...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed search: %v\nSQL: %s", err, searchSQL)
+		return nil, fmt.Errorf("database error searching records: %w", err)
+	}
+	defer rows.Close()
+
+	resultColumns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed processing results: %w", err)
+	}
+	records := make([]map[string]any, 0)
+	for rows.Next() {
+		values := make([]any, len(resultColumns))
+		scanArgs := make([]any, len(resultColumns))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, fmt.Errorf("failed reading record data: %w", err)
+		}
+		record := make(map[string]any, len(resultColumns))
+		for i, column := range resultColumns {
+			if bytes, ok := values[i].([]byte); ok {
+				record[column] = string(bytes)
+			} else {
+				record[column] = values[i]
+			}
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed processing all records: %w", err)
+	}
+	return records, nil
+}
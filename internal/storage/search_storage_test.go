@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestSearchText(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	if err := store.CreateTable(ctx, "notes", []core.ColumnSpec{{Name: "title", Type: "text"}, {Name: "body", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	for _, row := range [][]any{{"Groceries", "milk, eggs"}, {"Discount", "100% off"}, {"Report", "Q3 numbers"}} {
+		if _, err := store.InsertRecord(ctx, "notes", []string{"title", "body"}, row); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"MILK", 1}, // Case-insensitive
+		{"er", 2},   // Matches in either column
+		{"%", 1},    // Wildcards match literally
+		{"_", 0},    // Likewise
+		{"nothing", 0},
+	}
+	for _, tt := range tests {
+		records, err := store.SearchText(ctx, "notes", []string{"body", "title"}, tt.term, 10)
+		if err != nil || len(records) != tt.want {
+			t.Errorf("SearchText(%q) = %v, %v; want %d records", tt.term, records, err, tt.want)
+		}
+	}
+}
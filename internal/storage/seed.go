@@ -0,0 +1,121 @@
+// internal/storage/seed.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrInvalidSeed is returned by LoadSeed when the seed does not fit the database, e.g.
+// rows for a table that neither exists nor is declared.
+var ErrInvalidSeed = errors.New("invalid seed")
+
+// SeedResult reports what LoadSeed did.
+type SeedResult struct {
+	Dropped []string         `json:"dropped"` // Tables dropped by a reset
+	Created []string         `json:"created"`
+	Rows    map[string]int64 `json:"rows"` // Rows inserted per table
+}
+
+// LoadSeed loads a parsed seed into the database of userDB. With reset, every table
+// of the database (outside the trash) is dropped first, so that repeated loads give
+// the same contents and record ids. Tables of the seed that do not exist are created;
+// rows of existing tables are checked against their columns. All rows are checked
+// before anything is written, but a failing insert leaves the rows before it.
+// forget, if not nil, is called for each dropped table to remove its metadata.
+func LoadSeed(ctx context.Context, userDB UserDataStore, seed *core.Seed, reset bool, forget func(ctx context.Context, tableName string)) (*SeedResult, error) {
+	result := &SeedResult{Dropped: []string{}, Created: []string{}, Rows: make(map[string]int64, len(seed.Tables))}
+
+	var existing []domain.TableMetadata
+	if reset {
+		var err error
+		if existing, err = userDB.ListTables(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	type plannedTable struct {
+		create  bool
+		columns [][]string
+		values  [][]any
+	}
+	plans := make([]plannedTable, len(seed.Tables))
+	for i, table := range seed.Tables {
+		columnTypes := table.ColumnTypes()
+		if !reset {
+			types, err := userDB.ColumnTypes(ctx, table.Name)
+			switch {
+			case err == nil:
+				columnTypes = types
+			case !errors.Is(err, ErrTableNotFound):
+				return nil, err
+			default:
+				plans[i].create = true
+			}
+		} else {
+			plans[i].create = true
+		}
+		if plans[i].create && len(table.Columns) == 0 {
+			return nil, fmt.Errorf("%w: table '%s' does not exist and the seed declares no columns for it", ErrInvalidSeed, table.Name)
+		}
+		columns, values, err := core.SeedRowAssignments(table.Name, columnTypes, table.Rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSeed, err)
+		}
+		plans[i].columns, plans[i].values = columns, values
+	}
+
+	for _, table := range existing {
+		if err := userDB.DropTable(ctx, table.Name); err != nil {
+			return result, fmt.Errorf("failed to drop table '%s': %w", table.Name, err)
+		}
+		if forget != nil {
+			forget(ctx, table.Name)
+		}
+		result.Dropped = append(result.Dropped, table.Name)
+	}
+
+	for i, table := range seed.Tables {
+		plan := plans[i]
+		if plan.create {
+			if err := userDB.CreateTable(ctx, table.Name, table.Specs()); err != nil {
+				return result, fmt.Errorf("failed to create table '%s': %w", table.Name, err)
+			}
+			result.Created = append(result.Created, table.Name)
+		}
+		result.Rows[table.Name] = 0
+		for j := range plan.columns {
+			if _, err := userDB.InsertRecord(ctx, table.Name, plan.columns[j], plan.values[j]); err != nil {
+				return result, fmt.Errorf("failed to insert row %d of table '%s': %w", j+1, table.Name, err)
+			}
+			result.Rows[table.Name]++
+		}
+	}
+	customLog.Ctx(ctx).Printf("Storage: Loaded seed (%d table(s) dropped, %d created)", len(result.Dropped), len(result.Created))
+	return result, nil
+}
+
+// ForgetTableMetadata removes the column rules, JSON schema, script, options and
+// descriptions of a dropped table, so that a table created later under the same name
+// starts without them. Failures are logged.
+func ForgetTableMetadata(ctx context.Context, store MetadataStore, databaseID int64, tableName string) {
+	if err := store.ReplaceColumnRules(ctx, databaseID, tableName, nil); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to remove column rules of dropped table '%s': %v", tableName, err)
+	}
+	if err := store.DeleteTableJSONSchema(ctx, databaseID, tableName); err != nil && !errors.Is(err, ErrJSONSchemaNotFound) {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to remove JSON schema of dropped table '%s': %v", tableName, err)
+	}
+	if err := store.DeleteTableScript(ctx, databaseID, tableName); err != nil && !errors.Is(err, ErrTableScriptNotFound) {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to remove script of dropped table '%s': %v", tableName, err)
+	}
+	if err := store.DeleteTableOptions(ctx, databaseID, tableName); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to remove options of dropped table '%s': %v", tableName, err)
+	}
+	if err := store.ReplaceTableDescription(ctx, databaseID, tableName, domain.TableDescription{}); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to remove descriptions of dropped table '%s': %v", tableName, err)
+	}
+}
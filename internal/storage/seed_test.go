@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestLoadSeed(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+	if err := store.CreateTable(ctx, "stale", []core.ColumnSpec{{Name: "note", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	seed, err := core.ParseSeed([]byte(`{"tables": [
+		{"name": "customers", "columns": [{"name": "name", "type": "text"}], "rows": [{"id": 5, "name": "Ada"}, {"name": "Bob"}]}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseSeed: %v", err)
+	}
+	var forgotten []string
+	forget := func(_ context.Context, tableName string) { forgotten = append(forgotten, tableName) }
+	for range 2 { // Repeated resets give the same contents
+		forgotten = nil
+		result, err := LoadSeed(ctx, store, seed, true, forget)
+		if err != nil {
+			t.Fatalf("LoadSeed: %v", err)
+		}
+		if len(result.Created) != 1 || result.Rows["customers"] != 2 || len(forgotten) != len(result.Dropped) {
+			t.Errorf("LoadSeed = %+v (forgot %v); want customers created with 2 rows", result, forgotten)
+		}
+		record, err := store.GetRecord(ctx, "customers", 6)
+		if err != nil || record["name"] != "Bob" {
+			t.Errorf("record 6 = %v, %v; want Bob after the fixed id 5", record, err)
+		}
+	}
+	if _, err := store.ColumnTypes(ctx, "stale"); !errors.Is(err, ErrTableNotFound) {
+		t.Errorf("table 'stale' after reset: %v; want ErrTableNotFound", err)
+	}
+
+	// Without reset, rows are checked against the existing table
+	more, err := core.ParseSeed([]byte(`{"tables": [{"name": "customers", "rows": [{"name": "Cy"}]}, {"name": "orders", "rows": [{"x": 1}]}]}`))
+	if err != nil {
+		t.Fatalf("ParseSeed: %v", err)
+	}
+	if _, err := LoadSeed(ctx, store, more, false, nil); !errors.Is(err, ErrInvalidSeed) {
+		t.Errorf("LoadSeed with an undeclared new table = %v; want ErrInvalidSeed", err)
+	}
+	more.Tables = more.Tables[:1]
+	if result, err := LoadSeed(ctx, store, more, false, nil); err != nil || result.Rows["customers"] != 1 || len(result.Created) != 0 {
+		t.Errorf("LoadSeed into the existing table = %+v, %v", result, err)
+	}
+}
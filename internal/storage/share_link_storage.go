@@ -0,0 +1,97 @@
+// internal/storage/share_link_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrShareLinkNotFound is returned when no share link has an ID.
+var ErrShareLinkNotFound = errors.New("share link not found")
+
+const shareLinkColumns = `share_id, database_id, table_name, query_name, description, expires_at, created_at`
+
+func scanShareLink(scan func(dest ...any) error) (*domain.ShareLink, error) {
+	var link domain.ShareLink
+	var expiresAt int64
+	if err := scan(&link.ID, &link.DatabaseID, &link.Table, &link.Query, &link.Description, &expiresAt, &link.CreatedAt); err != nil {
+		return nil, err
+	}
+	link.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+	return &link, nil
+}
+
+// ListShareLinks returns the share links of a database, oldest first.
+func (s *sqlMetadataStore) ListShareLinks(ctx context.Context, databaseId int64) ([]domain.ShareLink, error) {
+	rows, err := s.query(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE database_id = ? ORDER BY created_at, share_id`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list share links for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []domain.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading share links: %w", err)
+		}
+		links = append(links, *link)
+	}
+	return links, rows.Err()
+}
+
+// FindShareLink returns the share link of an ID, whatever its database, for opening
+// shared data.
+func (s *sqlMetadataStore) FindShareLink(ctx context.Context, shareId string) (*domain.ShareLink, error) {
+	link, err := scanShareLink(s.queryRow(ctx, `SELECT `+shareLinkColumns+` FROM share_links WHERE share_id = ?`, shareId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrShareLinkNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to find share link %s: %v", shareId, err)
+		return nil, fmt.Errorf("database error finding share link: %w", err)
+	}
+	return link, nil
+}
+
+// CreateShareLink stores a new share link of link.DatabaseID. The ID is chosen by the
+// caller.
+func (s *sqlMetadataStore) CreateShareLink(ctx context.Context, link domain.ShareLink) error {
+	_, err := s.exec(ctx, `INSERT INTO share_links (share_id, database_id, table_name, query_name, description, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		link.ID, link.DatabaseID, link.Table, link.Query, link.Description, link.ExpiresAt.Unix())
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create share link for DBID %d: %v", link.DatabaseID, err)
+		return fmt.Errorf("database error creating share link: %w", err)
+	}
+	return nil
+}
+
+// DeleteShareLink removes a share link, returning ErrShareLinkNotFound if it did not
+// exist. Its token stops working right away.
+func (s *sqlMetadataStore) DeleteShareLink(ctx context.Context, databaseId int64, shareId string) error {
+	result, err := s.exec(ctx, `DELETE FROM share_links WHERE database_id = ? AND share_id = ?`, databaseId, shareId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete share link %s for DBID %d: %v", shareId, databaseId, err)
+		return fmt.Errorf("database error deleting share link: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrShareLinkNotFound
+	}
+	return nil
+}
+
+// DeleteExpiredShareLinks removes the share links of a database that expired before
+// now.
+func (s *sqlMetadataStore) DeleteExpiredShareLinks(ctx context.Context, databaseId int64, now time.Time) error {
+	if _, err := s.exec(ctx, `DELETE FROM share_links WHERE database_id = ? AND expires_at <= ?`, databaseId, now.Unix()); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete expired share links for DBID %d: %v", databaseId, err)
+		return fmt.Errorf("database error deleting expired share links: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+// internal/storage/slow_queries.go
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+// SlowQuery is a statement shape that was slow in one database: the statement with its
+// values, filter values included, replaced by placeholders, so that every run of the same
+// query shares an entry.
+type SlowQuery struct {
+	Statement  string    `json:"statement"`
+	Table      string    `json:"table"`
+	Count      int64     `json:"count"`  // Slow runs captured
+	MaxMs      float64   `json:"max_ms"` // Duration of the slowest run
+	AvgMs      float64   `json:"avg_ms"` // Mean duration of the slow runs
+	Rows       int64     `json:"rows"`   // Rows read or written by the slowest run
+	LastSeenAt time.Time `json:"last_seen_at"`
+
+	totalMs float64
+}
+
+// slowQueryLog keeps the slowest statement shapes of each database, keyed by storage
+// location. It lives in memory: every server instance keeps the statements it ran, and
+// they are lost on restart.
+type slowQueryLog struct {
+	mu          sync.Mutex
+	threshold   time.Duration
+	perDatabase int // 0 disables capture
+	byLocation  map[string][]*SlowQuery
+}
+
+var slowQueries = &slowQueryLog{threshold: 200 * time.Millisecond, perDatabase: 20, byLocation: make(map[string][]*SlowQuery)}
+
+// ConfigureSlowQueries sets the duration from which record operations are captured and
+// how many statement shapes are kept per database (0 disables capture). Call once at
+// startup.
+func ConfigureSlowQueries(threshold time.Duration, perDatabase int) {
+	slowQueries.mu.Lock()
+	defer slowQueries.mu.Unlock()
+	slowQueries.threshold = max(threshold, 0)
+	slowQueries.perDatabase = max(perDatabase, 0)
+	slowQueries.byLocation = make(map[string][]*SlowQuery)
+}
+
+// SlowQueries returns the slow statement shapes captured for the database stored at
+// location, slowest first.
+func SlowQueries(location string) []SlowQuery {
+	slowQueries.mu.Lock()
+	defer slowQueries.mu.Unlock()
+	entries := slowQueries.byLocation[location]
+	result := make([]SlowQuery, len(entries))
+	for i, entry := range entries {
+		result[i] = *entry
+	}
+	return result
+}
+
+// ResetSlowQueries forgets the slow statements captured for the database stored at
+// location.
+func ResetSlowQueries(location string) {
+	slowQueries.mu.Lock()
+	defer slowQueries.mu.Unlock()
+	delete(slowQueries.byLocation, location)
+}
+
+// enabled reports whether statements are captured.
+func (l *slowQueryLog) enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.perDatabase > 0
+}
+
+// exceeds reports whether an operation that took duration is captured.
+func (l *slowQueryLog) exceeds(duration time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.perDatabase > 0 && duration >= l.threshold
+}
+
+// record captures one slow run of a statement. When the database already has the
+// maximum of shapes, a new one replaces the fastest if it is slower.
+func (l *slowQueryLog) record(location, table, statement string, duration time.Duration, rows int64, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perDatabase == 0 {
+		return
+	}
+	ms := float64(duration.Microseconds()) / 1000
+	entries := l.byLocation[location]
+	i := slices.IndexFunc(entries, func(e *SlowQuery) bool { return e.Statement == statement })
+	switch {
+	case i >= 0:
+		entry := entries[i]
+		entry.Count++
+		entry.totalMs += ms
+		entry.AvgMs = entry.totalMs / float64(entry.Count)
+		entry.LastSeenAt = now
+		if ms > entry.MaxMs {
+			entry.MaxMs, entry.Rows = ms, rows
+		}
+	case len(entries) < l.perDatabase:
+		entries = append(entries, &SlowQuery{Statement: statement, Table: table, Count: 1, MaxMs: ms, AvgMs: ms, Rows: rows, LastSeenAt: now, totalMs: ms})
+	case ms > entries[len(entries)-1].MaxMs:
+		entries[len(entries)-1] = &SlowQuery{Statement: statement, Table: table, Count: 1, MaxMs: ms, AvgMs: ms, Rows: rows, LastSeenAt: now, totalMs: ms}
+	default:
+		return
+	}
+	slices.SortStableFunc(entries, func(a, b *SlowQuery) int {
+		switch {
+		case a.MaxMs > b.MaxMs:
+			return -1
+		case a.MaxMs < b.MaxMs:
+			return 1
+		}
+		return 0
+	})
+	l.byLocation[location] = entries
+}
+
+// observedUserData captures the slow record operations of a UserDataStore under the
+// storage location of its database. Schema changes are not captured.
+type observedUserData struct {
+	UserDataStore
+	location string
+}
+
+// observe captures one operation on table that started at start. The statement is only
+// built when the operation was slow.
+func (s *observedUserData) observe(table string, start time.Time, rows int64, statement func() string) {
+	duration := time.Since(start)
+	if !slowQueries.exceeds(duration) {
+		return
+	}
+	slowQueries.record(s.location, table, statement(), duration, rows, time.Now())
+}
+
+func (s *observedUserData) InsertRecord(ctx context.Context, tableName string, columns []string, values []any) (int64, error) {
+	start := time.Now()
+	id, err := s.UserDataStore.InsertRecord(ctx, tableName, columns, values)
+	if err == nil {
+		s.observe(tableName, start, 1, func() string {
+			sorted := slices.Sorted(slices.Values(columns))
+			return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(sorted, ", "), placeholders(len(sorted)))
+		})
+	}
+	return id, err
+}
+
+func (s *observedUserData) CountRecords(ctx context.Context, tableName string, limit int64) (int64, error) {
+	start := time.Now()
+	count, err := s.UserDataStore.CountRecords(ctx, tableName, limit)
+	if err == nil {
+		s.observe(tableName, start, count, func() string {
+			return fmt.Sprintf("SELECT COUNT(*) FROM %s LIMIT ?", tableName)
+		})
+	}
+	return count, err
+}
+
+func (s *observedUserData) ListRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions) (*ListRecordsResult, error) {
+	start := time.Now()
+	result, err := s.UserDataStore.ListRecords(ctx, tableName, queryParams, opts)
+	if err == nil {
+		s.observe(tableName, start, int64(len(result.Records)), func() string {
+			return listStatement(tableName, queryParams, opts)
+		})
+	}
+	return result, err
+}
+
+func (s *observedUserData) StreamRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions,
+	startFn func(PaginationMeta) error, emit func(map[string]any) error) error {
+	start := time.Now()
+	var rows int64
+	err := s.UserDataStore.StreamRecords(ctx, tableName, queryParams, opts, startFn, func(record map[string]any) error {
+		rows++
+		return emit(record)
+	})
+	if err == nil {
+		s.observe(tableName, start, rows, func() string {
+			return listStatement(tableName, queryParams, opts)
+		})
+	}
+	return err
+}
+
+func (s *observedUserData) GetRecord(ctx context.Context, tableName string, recordID int64) (map[string]any, error) {
+	start := time.Now()
+	record, err := s.UserDataStore.GetRecord(ctx, tableName, recordID)
+	if err == nil {
+		s.observe(tableName, start, 1, func() string {
+			return fmt.Sprintf("SELECT * FROM %s WHERE id = ?", tableName)
+		})
+	}
+	return record, err
+}
+
+func (s *observedUserData) UpdateRecord(ctx context.Context, tableName string, recordID int64, columns []string, values []any) (int64, error) {
+	start := time.Now()
+	affected, err := s.UserDataStore.UpdateRecord(ctx, tableName, recordID, columns, values)
+	if err == nil {
+		s.observe(tableName, start, affected, func() string {
+			sets := slices.Sorted(slices.Values(columns))
+			for i, column := range sets {
+				sets[i] = column + " = ?"
+			}
+			return fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", tableName, strings.Join(sets, ", "))
+		})
+	}
+	return affected, err
+}
+
+func (s *observedUserData) DeleteRecord(ctx context.Context, tableName string, recordID int64) (int64, error) {
+	start := time.Now()
+	affected, err := s.UserDataStore.DeleteRecord(ctx, tableName, recordID)
+	if err == nil {
+		s.observe(tableName, start, affected, func() string {
+			return fmt.Sprintf("DELETE FROM %s WHERE id = ?", tableName)
+		})
+	}
+	return affected, err
+}
+
+func (s *observedUserData) SearchText(ctx context.Context, tableName string, columns []string, term string, limit int) ([]map[string]any, error) {
+	start := time.Now()
+	records, err := s.UserDataStore.SearchText(ctx, tableName, columns, term, limit)
+	if err == nil {
+		s.observe(tableName, start, int64(len(records)), func() string {
+			likes := slices.Sorted(slices.Values(columns))
+			for i, column := range likes {
+				likes[i] = column + " LIKE ?"
+			}
+			return fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT ?", tableName, strings.Join(likes, " OR "))
+		})
+	}
+	return records, err
+}
+
+func (s *observedUserData) FindDuplicates(ctx context.Context, tableName string, columns []string, limit int) ([]DuplicateGroup, error) {
+	start := time.Now()
+	groups, err := s.UserDataStore.FindDuplicates(ctx, tableName, columns, limit)
+	if err == nil {
+		s.observe(tableName, start, int64(len(groups)), func() string {
+			list := strings.Join(columns, ", ")
+			return fmt.Sprintf("SELECT %s, COUNT(*) FROM %s GROUP BY %s HAVING COUNT(*) > 1 LIMIT ?", list, tableName, list)
+		})
+	}
+	return groups, err
+}
+
+func (s *observedUserData) DeleteDuplicates(ctx context.Context, tableName string, columns []string, keepLast bool) ([]int64, error) {
+	start := time.Now()
+	deleted, err := s.UserDataStore.DeleteDuplicates(ctx, tableName, columns, keepLast)
+	if err == nil {
+		s.observe(tableName, start, int64(len(deleted)), func() string {
+			keep := "MIN"
+			if keepLast {
+				keep = "MAX"
+			}
+			return fmt.Sprintf("DELETE FROM %s WHERE id NOT IN (SELECT %s(id) FROM %s GROUP BY %s)", tableName, keep, tableName, strings.Join(columns, ", "))
+		})
+	}
+	return deleted, err
+}
+
+// listStatement returns the shape of the SELECT run by ListRecords: the filters of
+// queryParams, sorted, with their values replaced by placeholders.
+func listStatement(tableName string, queryParams url.Values, opts *core.ListQueryOptions) string {
+	fields := "*"
+	if len(opts.Fields) > 0 {
+		fields = strings.Join(opts.Fields, ", ")
+	}
+	statement := fmt.Sprintf("SELECT %s FROM %s", fields, tableName)
+
+	var filters []string
+	for key := range queryParams {
+		if core.IsReservedParam(key) {
+			continue
+		}
+		column, operator, err := core.ParseFilterKey(key)
+		if err != nil {
+			continue
+		}
+		switch operator {
+		case core.FilterIs:
+			filters = append(filters, column+" IS ?")
+		case core.FilterAfter:
+			filters = append(filters, column+" > ?")
+		case core.FilterBefore:
+			filters = append(filters, column+" < ?")
+		default:
+			filters = append(filters, column+" = ?")
+		}
+	}
+	if len(filters) > 0 {
+		slices.Sort(filters)
+		statement += " WHERE " + strings.Join(filters, " AND ")
+	}
+	if opts.SortBy != "" {
+		direction := "ASC"
+		if strings.EqualFold(opts.SortOrder, "desc") {
+			direction = "DESC"
+		}
+		statement += fmt.Sprintf(" ORDER BY %s %s", opts.SortBy, direction)
+	} else {
+		statement += " ORDER BY id ASC"
+	}
+	return statement + " LIMIT ? OFFSET ?"
+}
+
+// placeholders returns n comma-separated "?".
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestSlowQueryLogKeepsSlowestShapes(t *testing.T) {
+	log := &slowQueryLog{threshold: 10 * time.Millisecond, perDatabase: 2, byLocation: make(map[string][]*SlowQuery)}
+	now := time.Now()
+	if log.exceeds(5 * time.Millisecond) {
+		t.Error("exceeds(5ms) = true below the threshold")
+	}
+	log.record("a.db", "notes", "SELECT a", 20*time.Millisecond, 5, now)
+	log.record("a.db", "notes", "SELECT b", 30*time.Millisecond, 7, now)
+	log.record("a.db", "notes", "SELECT a", 40*time.Millisecond, 9, now)
+	log.record("a.db", "notes", "SELECT c", 35*time.Millisecond, 1, now) // Replaces b, the fastest
+	log.record("a.db", "notes", "SELECT d", 15*time.Millisecond, 1, now) // Faster than both
+
+	entries := log.byLocation["a.db"]
+	if len(entries) != 2 || entries[0].Statement != "SELECT a" || entries[1].Statement != "SELECT c" {
+		t.Fatalf("entries = %+v, want SELECT a then SELECT c", entries)
+	}
+	if a := entries[0]; a.Count != 2 || a.MaxMs != 40 || a.AvgMs != 30 || a.Rows != 9 {
+		t.Errorf("SELECT a = %+v, want 2 runs, max 40, avg 30, 9 rows", *a)
+	}
+	if len(log.byLocation["b.db"]) != 0 {
+		t.Error("statements of a.db captured for b.db")
+	}
+}
+
+func TestListStatementShape(t *testing.T) {
+	params := url.Values{"status": {"open"}, "due[before]": {"2025-01-01"}, "limit": {"5"}, "owner[is]": {"null"}}
+	opts := &core.ListQueryOptions{SortBy: "created_at", SortOrder: "desc"}
+	want := "SELECT * FROM tasks WHERE due < ? AND owner IS ? AND status = ? ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	if got := listStatement("tasks", params, opts); got != want {
+		t.Errorf("listStatement() = %q\nwant %q", got, want)
+	}
+}
+
+func TestObservedUserDataCapturesRecordOperations(t *testing.T) {
+	ConfigureSlowQueries(0, 10) // Capture everything
+	defer ConfigureSlowQueries(200*time.Millisecond, 20)
+
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := OpenUserData(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer userDB.Release()
+
+	if err := userDB.CreateTable(ctx, "notes", []core.ColumnSpec{{Name: "body", Type: "TEXT"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	for _, body := range []string{"first", "second"} {
+		if _, err := userDB.InsertRecord(ctx, "notes", []string{"body"}, []any{body}); err != nil {
+			t.Fatalf("InsertRecord: %v", err)
+		}
+	}
+	if _, err := userDB.ListRecords(ctx, "notes", url.Values{"body": {"first"}}, &core.ListQueryOptions{Limit: 10}); err != nil {
+		t.Fatalf("ListRecords: %v", err)
+	}
+
+	byStatement := make(map[string]SlowQuery)
+	for _, entry := range SlowQueries(dbPath) {
+		byStatement[entry.Statement] = entry
+	}
+	if entry := byStatement["INSERT INTO notes (body) VALUES (?)"]; entry.Count != 2 || entry.Table != "notes" {
+		t.Errorf("insert entry = %+v, want 2 runs on notes", entry)
+	}
+	if entry, ok := byStatement["SELECT * FROM notes WHERE body = ? ORDER BY id ASC LIMIT ? OFFSET ?"]; !ok || entry.Rows != 1 {
+		t.Errorf("list entry = %+v (found %t), want 1 row", entry, ok)
+	}
+	if len(byStatement) != 2 {
+		t.Errorf("captured %v, want the inserts and the list only", byStatement)
+	}
+
+	ResetSlowQueries(dbPath)
+	if entries := SlowQueries(dbPath); len(entries) != 0 {
+		t.Errorf("SlowQueries() after reset = %v, want none", entries)
+	}
+}
@@ -0,0 +1,98 @@
+// internal/storage/table_description_storage.go
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ListTableDescriptions returns the descriptions of the tables of a user database,
+// keyed by table name. Tables without any description are absent.
+func (s *sqlMetadataStore) ListTableDescriptions(ctx context.Context, databaseId int64) (map[string]domain.TableDescription, error) {
+	rows, err := s.query(ctx, `SELECT table_name, column_name, description FROM table_descriptions WHERE database_id = ?`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list table descriptions for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing table descriptions: %w", err)
+	}
+	defer rows.Close()
+
+	descriptions := make(map[string]domain.TableDescription)
+	for rows.Next() {
+		var tableName, columnName, text string
+		if err := rows.Scan(&tableName, &columnName, &text); err != nil {
+			return nil, fmt.Errorf("database error reading table descriptions: %w", err)
+		}
+		description := descriptions[tableName]
+		if columnName == "" {
+			description.Description = text
+		} else {
+			if description.Columns == nil {
+				description.Columns = make(map[string]string)
+			}
+			description.Columns[columnName] = text
+		}
+		descriptions[tableName] = description
+	}
+	return descriptions, rows.Err()
+}
+
+// GetTableDescription returns the descriptions of a user table and its columns, empty
+// if none were set.
+func (s *sqlMetadataStore) GetTableDescription(ctx context.Context, databaseId int64, tableName string) (domain.TableDescription, error) {
+	rows, err := s.query(ctx, `SELECT column_name, description FROM table_descriptions WHERE database_id = ? AND table_name = ?`,
+		databaseId, tableName)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get descriptions for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return domain.TableDescription{}, fmt.Errorf("database error getting table description: %w", err)
+	}
+	defer rows.Close()
+
+	description := domain.TableDescription{Columns: make(map[string]string)}
+	for rows.Next() {
+		var columnName, text string
+		if err := rows.Scan(&columnName, &text); err != nil {
+			return domain.TableDescription{}, fmt.Errorf("database error reading table description: %w", err)
+		}
+		if columnName == "" {
+			description.Description = text
+		} else {
+			description.Columns[columnName] = text
+		}
+	}
+	return description, rows.Err()
+}
+
+// ReplaceTableDescription replaces the descriptions of a user table and its columns;
+// empty descriptions are not stored, so the zero value removes them all. Column names
+// are expected to be validated by core.ValidateTableDescription.
+func (s *sqlMetadataStore) ReplaceTableDescription(ctx context.Context, databaseId int64, tableName string, description domain.TableDescription) error {
+	tx, err := s.beginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("database error replacing table description: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // No-op after Commit
+
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM table_descriptions WHERE database_id = ? AND table_name = ?`), databaseId, tableName); err != nil {
+		return fmt.Errorf("database error replacing table description: %w", err)
+	}
+	insertSQL := s.dialect.rebind(`INSERT INTO table_descriptions (database_id, table_name, column_name, description) VALUES (?, ?, ?, ?)`)
+	texts := map[string]string{"": description.Description}
+	for columnName, text := range description.Columns {
+		texts[columnName] = text
+	}
+	for columnName, text := range texts {
+		if text == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, insertSQL, databaseId, tableName, columnName, text); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to store description of DBID %d, Table '%s', column '%s': %v", databaseId, tableName, columnName, err)
+			return fmt.Errorf("database error storing table description: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database error replacing table description: %w", err)
+	}
+	return nil
+}
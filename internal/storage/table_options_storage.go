@@ -0,0 +1,45 @@
+// internal/storage/table_options_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// GetTableOptions returns the options of a user table, the defaults if none were set.
+func (s *sqlMetadataStore) GetTableOptions(ctx context.Context, databaseId int64, tableName string) (domain.TableOptions, error) {
+	var options domain.TableOptions
+	err := s.queryRow(ctx, `SELECT auto_columns, default_sort, default_order, max_rows FROM table_options WHERE database_id = ? AND table_name = ?`,
+		databaseId, tableName).Scan(&options.AutoColumns, &options.DefaultSort, &options.DefaultOrder, &options.MaxRows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get options for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return domain.TableOptions{}, fmt.Errorf("database error getting table options: %w", err)
+	}
+	return options, nil
+}
+
+// SetTableOptions stores the options of a user table, replacing the previous ones.
+func (s *sqlMetadataStore) SetTableOptions(ctx context.Context, databaseId int64, tableName string, options domain.TableOptions) error {
+	_, err := s.exec(ctx, `INSERT INTO table_options (database_id, table_name, auto_columns, default_sort, default_order, max_rows) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (database_id, table_name) DO UPDATE SET auto_columns = excluded.auto_columns,
+			default_sort = excluded.default_sort, default_order = excluded.default_order, max_rows = excluded.max_rows, updated_at = CURRENT_TIMESTAMP`,
+		databaseId, tableName, options.AutoColumns, options.DefaultSort, options.DefaultOrder, options.MaxRows)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store options for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error storing table options: %w", err)
+	}
+	return nil
+}
+
+// DeleteTableOptions resets the options of a user table to the defaults.
+func (s *sqlMetadataStore) DeleteTableOptions(ctx context.Context, databaseId int64, tableName string) error {
+	if _, err := s.exec(ctx, `DELETE FROM table_options WHERE database_id = ? AND table_name = ?`, databaseId, tableName); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete options for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error deleting table options: %w", err)
+	}
+	return nil
+}
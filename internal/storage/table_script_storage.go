@@ -0,0 +1,53 @@
+// internal/storage/table_script_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrTableScriptNotFound is returned when a user table has no script.
+var ErrTableScriptNotFound = errors.New("table has no script")
+
+// GetTableScript returns the script source of a user table.
+func (s *sqlMetadataStore) GetTableScript(ctx context.Context, databaseId int64, tableName string) (string, error) {
+	var source string
+	err := s.queryRow(ctx, `SELECT source FROM table_scripts WHERE database_id = ? AND table_name = ?`, databaseId, tableName).Scan(&source)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTableScriptNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get script for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return "", fmt.Errorf("database error getting script: %w", err)
+	}
+	return source, nil
+}
+
+// SetTableScript stores the script source of a user table, replacing any previous one.
+// The source is expected to compile with scripting.Compile.
+func (s *sqlMetadataStore) SetTableScript(ctx context.Context, databaseId int64, tableName, source string) error {
+	_, err := s.exec(ctx, `INSERT INTO table_scripts (database_id, table_name, source) VALUES (?, ?, ?)
+		ON CONFLICT (database_id, table_name) DO UPDATE SET source = excluded.source, updated_at = CURRENT_TIMESTAMP`,
+		databaseId, tableName, source)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store script for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error storing script: %w", err)
+	}
+	return nil
+}
+
+// DeleteTableScript removes the script of a user table, returning
+// ErrTableScriptNotFound if it had none.
+func (s *sqlMetadataStore) DeleteTableScript(ctx context.Context, databaseId int64, tableName string) error {
+	result, err := s.exec(ctx, `DELETE FROM table_scripts WHERE database_id = ? AND table_name = ?`, databaseId, tableName)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete script for DBID %d, Table '%s': %v", databaseId, tableName, err)
+		return fmt.Errorf("database error deleting script: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTableScriptNotFound
+	}
+	return nil
+}
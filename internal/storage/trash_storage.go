@@ -0,0 +1,163 @@
+// internal/storage/trash_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// ErrTrashItemNotFound is returned when a user has no trash item of an ID.
+var ErrTrashItemNotFound = errors.New("trash item not found")
+
+const trashColumns = `trash_id, owner_id, db_name, table_name, location, trash_location, deleted_at, expires_at`
+
+func scanTrashItem(scan func(dest ...any) error) (*domain.TrashItem, error) {
+	var item domain.TrashItem
+	var expiresAt int64
+	if err := scan(&item.TrashID, &item.UserID, &item.DBName, &item.TableName, &item.Location,
+		&item.TrashLocation, &item.DeletedAt, &expiresAt); err != nil {
+		return nil, err
+	}
+	item.ExpiresAt = time.Unix(expiresAt, 0).UTC()
+	return &item, nil
+}
+
+func (s *sqlMetadataStore) listTrashItems(ctx context.Context, query string, args ...any) ([]domain.TrashItem, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error listing trash: %w", err)
+	}
+	defer rows.Close()
+
+	items := []domain.TrashItem{}
+	for rows.Next() {
+		item, err := scanTrashItem(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading trash: %w", err)
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// AddTrashItem records a deleted database or dropped table and returns its ID.
+func (s *sqlMetadataStore) AddTrashItem(ctx context.Context, item domain.TrashItem) (int64, error) {
+	var id int64
+	err := s.queryRow(ctx, `INSERT INTO trash (owner_id, db_name, table_name, location, trash_location, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?) RETURNING trash_id`,
+		item.UserID, item.DBName, item.TableName, item.Location, item.TrashLocation, item.ExpiresAt.Unix()).Scan(&id)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to record trash item for DB '%s' of UserID %s: %v", item.DBName, item.UserID, err)
+		return 0, fmt.Errorf("database error recording trash item: %w", err)
+	}
+	return id, nil
+}
+
+// ListTrashItems returns a user's trash, most recently deleted first.
+func (s *sqlMetadataStore) ListTrashItems(ctx context.Context, userId string) ([]domain.TrashItem, error) {
+	items, err := s.listTrashItems(ctx, `SELECT `+trashColumns+` FROM trash WHERE owner_id = ? ORDER BY trash_id DESC`, userId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list trash of UserID %s: %v", userId, err)
+	}
+	return items, err
+}
+
+// ListExpiredTrashItems returns the trash items of every user that expired by now.
+func (s *sqlMetadataStore) ListExpiredTrashItems(ctx context.Context, now time.Time) ([]domain.TrashItem, error) {
+	return s.listTrashItems(ctx, `SELECT `+trashColumns+` FROM trash WHERE expires_at <= ? ORDER BY trash_id`, now.Unix())
+}
+
+// GetTrashItem returns one trash item of a user.
+func (s *sqlMetadataStore) GetTrashItem(ctx context.Context, userId string, trashId int64) (*domain.TrashItem, error) {
+	item, err := scanTrashItem(s.queryRow(ctx, `SELECT `+trashColumns+` FROM trash WHERE owner_id = ? AND trash_id = ?`, userId, trashId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTrashItemNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get trash item %d of UserID %s: %v", trashId, userId, err)
+		return nil, fmt.Errorf("database error getting trash item: %w", err)
+	}
+	return item, nil
+}
+
+// DeleteTrashItem removes a trash item's record, returning ErrTrashItemNotFound if it
+// did not exist. The trashed data must already be restored or purged.
+func (s *sqlMetadataStore) DeleteTrashItem(ctx context.Context, trashId int64) error {
+	result, err := s.exec(ctx, `DELETE FROM trash WHERE trash_id = ?`, trashId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete trash item %d: %v", trashId, err)
+		return fmt.Errorf("database error deleting trash item: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTrashItemNotFound
+	}
+	return nil
+}
+
+// PurgeTrashItem permanently deletes the data of a trash item and its record. A dropped
+// table whose database is no longer registered under its name went with the database.
+func PurgeTrashItem(ctx context.Context, store MetadataStore, item domain.TrashItem) error {
+	if item.TableName == "" {
+		if err := DeleteUserData(ctx, item.TrashLocation); err != nil {
+			return fmt.Errorf("failed to delete trashed database '%s': %w", item.DBName, err)
+		}
+		return store.DeleteTrashItem(ctx, item.TrashID)
+	}
+
+	location, err := store.FindDatabasePath(ctx, item.UserID, item.DBName)
+	switch {
+	case errors.Is(err, ErrDatabaseNotFound):
+	case err != nil:
+		return err
+	default:
+		userDB, err := OpenUserData(ctx, location)
+		if err != nil {
+			return err
+		}
+		err = userDB.DropTable(ctx, item.TrashLocation)
+		userDB.Release()
+		if err != nil {
+			return fmt.Errorf("failed to drop trashed table '%s': %w", item.TableName, err)
+		}
+	}
+	return store.DeleteTrashItem(ctx, item.TrashID)
+}
+
+// RunTrashPurger periodically purges expired trash items, until ctx is done.
+func RunTrashPurger(ctx context.Context, store MetadataStore, interval time.Duration) {
+	health.RegisterWorker("trash_purger", interval)
+	defer health.UnregisterWorker("trash_purger")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purgeExpiredTrash(ctx, store)
+			health.Beat("trash_purger")
+		}
+	}
+}
+
+// purgeExpiredTrash runs one pass of the trash purger.
+func purgeExpiredTrash(ctx context.Context, store MetadataStore) {
+	items, err := store.ListExpiredTrashItems(ctx, time.Now())
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Trash purge skipped: %v", err)
+		return
+	}
+	for _, item := range items {
+		if err := PurgeTrashItem(ctx, store, item); err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: Failed to purge trash item %d of UserID %s: %v", item.TrashID, item.UserID, err)
+			continue
+		}
+		customLog.Ctx(ctx).Printf("Storage: Purged expired trash item %d (DB '%s', table '%s')", item.TrashID, item.DBName, item.TableName)
+	}
+}
@@ -0,0 +1,160 @@
+// internal/storage/usage_storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// UsageDayLayout formats the UTC day of a usage bucket.
+const UsageDayLayout = "2006-01-02"
+
+// usageKey identifies the pending traffic of one database on one day. Databases are
+// addressed by name, so requests only resolve their ID when flushed.
+type usageKey struct {
+	userID string
+	dbName string
+	day    string
+}
+
+// usageCounter accumulates API traffic in memory between flushes to the metadata DB.
+type usageCounter struct {
+	mu      sync.Mutex
+	pending map[usageKey]*domain.DatabaseUsage
+}
+
+var databaseUsage = &usageCounter{pending: make(map[usageKey]*domain.DatabaseUsage)}
+
+// RecordDatabaseUsage counts one API request to the database dbName of userId.
+// Requests failing with a status of 400 or above also count as errors.
+func RecordDatabaseUsage(userId, dbName string, status int, bytesIn, bytesOut int64) {
+	key := usageKey{userID: userId, dbName: dbName, day: time.Now().UTC().Format(UsageDayLayout)}
+	databaseUsage.mu.Lock()
+	defer databaseUsage.mu.Unlock()
+	usage, ok := databaseUsage.pending[key]
+	if !ok {
+		usage = &domain.DatabaseUsage{Day: key.day}
+		databaseUsage.pending[key] = usage
+	}
+	usage.Requests++
+	if status >= 400 {
+		usage.Errors++
+	}
+	usage.BytesIn += bytesIn
+	usage.BytesOut += bytesOut
+}
+
+// FlushDatabaseUsage adds the traffic counted since the last flush to the metadata DB.
+// Traffic of databases that no longer exist is dropped; traffic that failed to be
+// stored is kept for the next flush.
+func FlushDatabaseUsage(ctx context.Context, store MetadataStore) error {
+	databaseUsage.mu.Lock()
+	pending := databaseUsage.pending
+	databaseUsage.pending = make(map[usageKey]*domain.DatabaseUsage)
+	databaseUsage.mu.Unlock()
+
+	var firstErr error
+	for key, usage := range pending {
+		databaseID, err := store.FindDatabaseIDByNameAndUser(ctx, key.userID, key.dbName)
+		if err == nil {
+			err = store.AddDatabaseUsage(ctx, databaseID, *usage)
+		}
+		if errors.Is(err, ErrDatabaseNotFound) {
+			continue
+		}
+		if err != nil {
+			databaseUsage.restore(key, usage)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// restore merges usage back into the pending traffic after a failed flush.
+func (u *usageCounter) restore(key usageKey, usage *domain.DatabaseUsage) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if current, ok := u.pending[key]; ok {
+		current.Requests += usage.Requests
+		current.Errors += usage.Errors
+		current.BytesIn += usage.BytesIn
+		current.BytesOut += usage.BytesOut
+		return
+	}
+	u.pending[key] = usage
+}
+
+// flushUsage stores the traffic counted per database and per API key.
+func flushUsage(ctx context.Context, store MetadataStore) error {
+	return errors.Join(FlushDatabaseUsage(ctx, store), FlushAPIKeyUsage(ctx, store))
+}
+
+// RunUsageFlusher periodically stores the counted API traffic, until ctx is done; what
+// was counted since the last flush is stored before returning.
+func RunUsageFlusher(ctx context.Context, store MetadataStore, interval time.Duration) {
+	health.RegisterWorker("usage_flusher", interval)
+	defer health.UnregisterWorker("usage_flusher")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The run context is cancelled, so store with a fresh one
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := flushUsage(flushCtx, store); err != nil {
+				customLog.Warnf("Storage: Final usage flush failed: %v", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := flushUsage(ctx, store); err != nil {
+				customLog.Ctx(ctx).Warnf("Storage: Usage flush failed: %v", err)
+			}
+			health.Beat("usage_flusher")
+		}
+	}
+}
+
+// AddDatabaseUsage adds traffic to the usage bucket of a user database for usage.Day.
+func (s *sqlMetadataStore) AddDatabaseUsage(ctx context.Context, databaseId int64, usage domain.DatabaseUsage) error {
+	_, err := s.exec(ctx, `INSERT INTO database_usage (database_id, day, requests, errors, bytes_in, bytes_out) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (database_id, day) DO UPDATE SET requests = database_usage.requests + excluded.requests,
+		errors = database_usage.errors + excluded.errors, bytes_in = database_usage.bytes_in + excluded.bytes_in,
+		bytes_out = database_usage.bytes_out + excluded.bytes_out`,
+		databaseId, usage.Day, usage.Requests, usage.Errors, usage.BytesIn, usage.BytesOut)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to store usage of DBID %d on %s: %v", databaseId, usage.Day, err)
+		return fmt.Errorf("database error storing database usage: %w", err)
+	}
+	return nil
+}
+
+// ListDatabaseUsage returns the daily usage buckets of a user database from day since
+// (YYYY-MM-DD) on, oldest first. Days without traffic are absent.
+func (s *sqlMetadataStore) ListDatabaseUsage(ctx context.Context, databaseId int64, since string) ([]domain.DatabaseUsage, error) {
+	rows, err := s.query(ctx, `SELECT day, requests, errors, bytes_in, bytes_out FROM database_usage
+		WHERE database_id = ? AND day >= ? ORDER BY day`, databaseId, since)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list usage of DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing database usage: %w", err)
+	}
+	defer rows.Close()
+
+	days := make([]domain.DatabaseUsage, 0)
+	for rows.Next() {
+		var usage domain.DatabaseUsage
+		if err := rows.Scan(&usage.Day, &usage.Requests, &usage.Errors, &usage.BytesIn, &usage.BytesOut); err != nil {
+			return nil, fmt.Errorf("database error reading database usage: %w", err)
+		}
+		days = append(days, usage)
+	}
+	return days, rows.Err()
+}
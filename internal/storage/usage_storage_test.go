@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+)
+
+func TestFlushDatabaseUsage(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "app", "app.db"); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, _ := store.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+
+	RecordDatabaseUsage("u1", "app", 200, 10, 100)
+	RecordDatabaseUsage("u1", "app", 404, 0, 50)
+	RecordDatabaseUsage("u1", "gone", 200, 0, 0) // Not registered: dropped
+	if err := FlushDatabaseUsage(ctx, store); err != nil {
+		t.Fatalf("FlushDatabaseUsage: %v", err)
+	}
+	RecordDatabaseUsage("u1", "app", 500, 5, 0)
+	if err := FlushDatabaseUsage(ctx, store); err != nil {
+		t.Fatalf("second FlushDatabaseUsage: %v", err)
+	}
+
+	today := time.Now().UTC().Format(UsageDayLayout)
+	usage, err := store.ListDatabaseUsage(ctx, databaseID, today)
+	if err != nil || len(usage) != 1 {
+		t.Fatalf("ListDatabaseUsage = %v, %v; want one day", usage, err)
+	}
+	if u := usage[0]; u.Day != today || u.Requests != 3 || u.Errors != 2 || u.BytesIn != 15 || u.BytesOut != 150 {
+		t.Errorf("usage = %+v; want 3 requests, 2 errors, 15 bytes in, 150 bytes out", u)
+	}
+	if len(databaseUsage.pending) != 0 {
+		t.Errorf("pending usage left after flush: %v", databaseUsage.pending)
+	}
+}
+
+func TestFlushAPIKeyUsage(t *testing.T) {
+	ctx := context.Background()
+	store, err := ConnectMetadataDB(&config.Config{MetadataDbDir: t.TempDir(), MetadataDbFile: "meta.db"})
+	if err != nil {
+		t.Fatalf("ConnectMetadataDB: %v", err)
+	}
+	defer store.Close()
+	if _, err := store.CreateUser(ctx, "u1", "user_one", "u1@example.com", "x"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := store.RegisterDatabase(ctx, "u1", "app", "app.db"); err != nil {
+		t.Fatalf("RegisterDatabase: %v", err)
+	}
+	databaseID, _ := store.FindDatabaseIDByNameAndUser(ctx, "u1", "app")
+	if _, err := store.StoreAPIKey(ctx, "u1", databaseID); err != nil {
+		t.Fatalf("StoreAPIKey: %v", err)
+	}
+	keyId, err := store.FindAPIKeyID(ctx, databaseID)
+	if err != nil {
+		t.Fatalf("FindAPIKeyID: %v", err)
+	}
+
+	RecordAPIKeyUsage(databaseID, "GET", "/api/v1/databases/:db_name/tables", "10.0.0.1", 200)
+	RecordAPIKeyUsage(databaseID, "GET", "/api/v1/databases/:db_name/tables", "10.0.0.2", 403)
+	RecordAPIKeyUsage(databaseID+1, "GET", "/api/v1/databases/:db_name/tables", "10.0.0.3", 200) // No key: dropped
+	if err := FlushAPIKeyUsage(ctx, store); err != nil {
+		t.Fatalf("FlushAPIKeyUsage: %v", err)
+	}
+	RecordAPIKeyUsage(databaseID, "GET", "/api/v1/databases/:db_name/tables", "10.0.0.1", 500)
+	if err := FlushAPIKeyUsage(ctx, store); err != nil {
+		t.Fatalf("second FlushAPIKeyUsage: %v", err)
+	}
+
+	today := time.Now().UTC().Format(UsageDayLayout)
+	usage, err := store.ListAPIKeyUsage(ctx, keyId, today)
+	if err != nil || len(usage) != 1 {
+		t.Fatalf("ListAPIKeyUsage = %v, %v; want one bucket", usage, err)
+	}
+	if u := usage[0]; u.Requests != 3 || u.Errors != 2 || u.Method != "GET" {
+		t.Errorf("usage = %+v; want 3 GET requests, 2 errors", u)
+	}
+	clients, err := store.ListAPIKeyClients(ctx, keyId, 10)
+	if err != nil || len(clients) != 2 {
+		t.Fatalf("ListAPIKeyClients = %v, %v; want two clients", clients, err)
+	}
+	if c := clients[0]; c.IP != "10.0.0.1" || c.Requests != 2 {
+		t.Errorf("last client = %+v; want 10.0.0.1 with 2 requests", c)
+	}
+}
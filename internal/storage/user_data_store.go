@@ -0,0 +1,347 @@
+// internal/storage/user_data_store.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/config"
+	"github.com/Annany2002/nebula-backend/internal/core"
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrUserDataUnsupported is returned for operations that only work on SQLite files
+// (backups, maintenance, dumps...) when tenant data lives in another backend.
+var ErrUserDataUnsupported = errors.New("operation not supported by the user data backend")
+
+// UserDataStore performs the schema and record operations of one tenant database.
+// Column types and errors are the same for every backend: types are the normalized
+// core.AllowedColumnTypes (plus TIMESTAMP for created_at) and failures map to the
+// sentinel errors of user_database_storage.go. Handles come from OpenUserData and
+// must be handed back with Release.
+type UserDataStore interface {
+	// Schema
+	ColumnTypes(ctx context.Context, tableName string) (map[string]string, error)
+	ListTables(ctx context.Context) ([]domain.TableMetadata, error)
+	CreateTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error
+	CreateStrictTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error
+	DropTable(ctx context.Context, tableName string) error
+	RenameTable(ctx context.Context, from, to string) error
+	AddColumns(ctx context.Context, tableName string, columns []core.ColumnSpec) error
+
+	// Records
+	InsertRecord(ctx context.Context, tableName string, columns []string, values []any) (int64, error)
+	CountRecords(ctx context.Context, tableName string, limit int64) (int64, error) // Counts up to limit
+	ListRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions) (*ListRecordsResult, error)
+	StreamRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions,
+		start func(PaginationMeta) error, emit func(map[string]any) error) error
+	GetRecord(ctx context.Context, tableName string, recordID int64) (map[string]any, error)
+	UpdateRecord(ctx context.Context, tableName string, recordID int64, columns []string, values []any) (int64, error)
+	DeleteRecord(ctx context.Context, tableName string, recordID int64) (int64, error)
+	SearchText(ctx context.Context, tableName string, columns []string, term string, limit int) ([]map[string]any, error)
+	FindDuplicates(ctx context.Context, tableName string, columns []string, limit int) ([]DuplicateGroup, error)
+	DeleteDuplicates(ctx context.Context, tableName string, columns []string, keepLast bool) ([]int64, error) // Returns the deleted IDs
+
+	Release()
+}
+
+// userData is the deployment's tenant data backend, set once by ConfigureUserData.
+var userData = struct {
+	backend string
+	pg      *sql.DB // Shared pool for the postgres backend
+}{backend: BackendSQLite}
+
+// ConfigureUserData selects where tenant databases live. SQLite (the default) keeps one
+// file per database under the data directory; Postgres keeps one schema per database in
+// a shared server. Call once at startup before serving requests.
+func ConfigureUserData(cfg *config.Config) error {
+	switch cfg.UserDataBackend {
+	case BackendSQLite, "":
+		userData.backend = BackendSQLite
+		return nil
+	case BackendPostgres:
+		db, err := sql.Open("postgres", cfg.UserDataPostgresDSN)
+		if err != nil {
+			return fmt.Errorf("failed to open user data database: %w", err)
+		}
+		db.SetMaxOpenConns(cfg.UserDataPostgresMaxConns)
+		db.SetMaxIdleConns(cfg.UserDataPostgresMaxConns)
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to connect to user data database: %w", err)
+		}
+		customLog.Printf("Storage: Tenant data stored in Postgres (schema per database)")
+		userData.backend = BackendPostgres
+		userData.pg = db
+		return nil
+	default:
+		return fmt.Errorf("unknown user data backend '%s'", cfg.UserDataBackend)
+	}
+}
+
+// UserDataBackend returns the configured tenant data backend.
+func UserDataBackend() string {
+	return userData.backend
+}
+
+// CloseUserData closes the shared user data connection pool, if any. Intended for shutdown.
+func CloseUserData() {
+	if userData.pg != nil {
+		if err := userData.pg.Close(); err != nil {
+			customLog.Warnf("Storage: Error closing user data database: %v", err)
+		}
+	}
+}
+
+// PingUserData verifies the shared user data connection. SQLite files have nothing to ping.
+func PingUserData(ctx context.Context) error {
+	if userData.pg == nil {
+		return nil
+	}
+	return userData.pg.PingContext(ctx)
+}
+
+// UserDataLocation returns where a new database is stored, to be saved with its
+// registration: a file path for SQLite, "postgres:<schema>" for Postgres.
+func UserDataLocation(dataDir, userId, dbName string) string {
+	if userData.backend == BackendPostgres {
+		return postgresLocationPrefix + tenantSchema(userId, dbName)
+	}
+	return filepath.Join(dataDir, userId, dbName+".db")
+}
+
+// PrepareUserData creates the storage for a newly registered database: the user's
+// directory for SQLite (the file itself appears on first use) or the tenant schema.
+func PrepareUserData(ctx context.Context, location string) error {
+	if userData.backend == BackendPostgres {
+		schema, err := postgresSchema(location)
+		if err != nil {
+			return err
+		}
+		return createTenantSchema(ctx, userData.pg, schema)
+	}
+	movedUserDBs.forget(location)
+	return os.MkdirAll(filepath.Dir(location), 0o750)
+}
+
+// DeleteUserData removes the storage of a database whose registration was deleted,
+// with the -wal and -shm files of SQLite databases. Missing storage is not an error.
+func DeleteUserData(ctx context.Context, location string) error {
+	if userData.backend == BackendPostgres {
+		schema, err := postgresSchema(location)
+		if err != nil {
+			return err
+		}
+		return dropTenantSchema(ctx, userData.pg, schema)
+	}
+	InvalidateUserDB(location)
+	ResetSlowQueries(location)
+	return removeUserDBFiles(ctx, location)
+}
+
+// TrashUserData moves the storage of a database being deleted out of the way and returns
+// where it went: a .trash directory next to the file for SQLite, a renamed schema for
+// Postgres. RestoreUserData moves it back; DeleteUserData purges it.
+func TrashUserData(ctx context.Context, location string) (string, error) {
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	if userData.backend == BackendPostgres {
+		schema, err := postgresSchema(location)
+		if err != nil {
+			return "", err
+		}
+		trashed := schema + core.TrashedTablePrefix + suffix
+		if err := renameTenantSchema(ctx, userData.pg, schema, trashed); err != nil {
+			return "", err
+		}
+		return postgresLocationPrefix + trashed, nil
+	}
+	trashed := filepath.Join(filepath.Dir(location), ".trash", strings.TrimSuffix(filepath.Base(location), ".db")+"."+suffix+".db")
+	if err := moveUserDBFile(location, trashed); err != nil {
+		return "", err
+	}
+	return trashed, nil
+}
+
+// RestoreUserData moves the storage of a trashed database back to location.
+func RestoreUserData(ctx context.Context, trashed, location string) error {
+	if userData.backend == BackendPostgres {
+		from, err := postgresSchema(trashed)
+		if err != nil {
+			return err
+		}
+		to, err := postgresSchema(location)
+		if err != nil {
+			return err
+		}
+		return renameTenantSchema(ctx, userData.pg, from, to)
+	}
+	if _, err := os.Stat(location); err == nil {
+		return fmt.Errorf("cannot restore database: '%s' already exists", location)
+	}
+	return moveUserDBFile(trashed, location)
+}
+
+// moveUserDBFile renames a SQLite file along with its -wal and -shm files. A file that
+// was never created (no table was ever added) is not an error.
+func moveUserDBFile(from, to string) error {
+	InvalidateUserDB(from)
+	ResetSlowQueries(from)
+	movedUserDBs.forget(to)
+	if err := os.MkdirAll(filepath.Dir(to), 0o750); err != nil {
+		return err
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(from+suffix, to+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenUserData returns a handle on the database stored at location, as returned by
+// UserDataLocation and saved with its registration.
+func OpenUserData(ctx context.Context, location string) (UserDataStore, error) {
+	if userData.backend == BackendPostgres {
+		schema, err := postgresSchema(location)
+		if err != nil {
+			return nil, err
+		}
+		return notifyUserData(observeUserData(&postgresUserData{db: userData.pg, schema: schema}, location), location), nil
+	}
+	userDB, err := ConnectUserDB(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	return notifyUserData(observeUserData(&sqliteUserData{db: userDB}, location), location), nil
+}
+
+// observeUserData wraps store to capture its slow record operations, unless capture is
+// disabled.
+func observeUserData(store UserDataStore, location string) UserDataStore {
+	if !slowQueries.enabled() {
+		return store
+	}
+	return &observedUserData{UserDataStore: store, location: location}
+}
+
+// sqliteUserData implements UserDataStore on a pooled SQLite file handle.
+type sqliteUserData struct {
+	db *sql.DB
+}
+
+func (s *sqliteUserData) ColumnTypes(ctx context.Context, tableName string) (map[string]string, error) {
+	return PragmaTableInfo(ctx, s.db, tableName)
+}
+
+func (s *sqliteUserData) ListTables(ctx context.Context) ([]domain.TableMetadata, error) {
+	return ListTables(ctx, s.db)
+}
+
+func (s *sqliteUserData) CreateTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	createSQL, err := core.BuildCreateTableSQL(tableName, columns)
+	if err != nil {
+		return err
+	}
+	return CreateTable(ctx, s.db, createSQL)
+}
+
+// CreateStrictTable creates a SQLite STRICT table (see core.BuildStrictCreateTableSQL).
+func (s *sqliteUserData) CreateStrictTable(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	createSQL, err := core.BuildStrictCreateTableSQL(tableName, columns)
+	if err != nil {
+		return err
+	}
+	return CreateTable(ctx, s.db, createSQL)
+}
+
+func (s *sqliteUserData) DropTable(ctx context.Context, tableName string) error {
+	return DropTable(ctx, s.db, tableName)
+}
+
+func (s *sqliteUserData) RenameTable(ctx context.Context, from, to string) error {
+	return RenameTable(ctx, s.db, from, to)
+}
+
+func (s *sqliteUserData) AddColumns(ctx context.Context, tableName string, columns []core.ColumnSpec) error {
+	normalized, err := core.ValidateTableDefinition(tableName, columns)
+	if err != nil {
+		return err
+	}
+	return AddColumns(ctx, s.db, tableName, normalized)
+}
+
+func (s *sqliteUserData) InsertRecord(ctx context.Context, tableName string, columns []string, values []any) (int64, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(columns, ", "), placeholders)
+	return InsertRecord(ctx, s.db, insertSQL, values...)
+}
+
+func (s *sqliteUserData) CountRecords(ctx context.Context, tableName string, limit int64) (int64, error) {
+	return CountRecords(ctx, s.db, tableName, limit)
+}
+
+func (s *sqliteUserData) ListRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions) (*ListRecordsResult, error) {
+	return ListRecords(ctx, s.db, tableName, queryParams, opts)
+}
+
+func (s *sqliteUserData) StreamRecords(ctx context.Context, tableName string, queryParams url.Values, opts *core.ListQueryOptions,
+	start func(PaginationMeta) error, emit func(map[string]any) error) error {
+	return StreamRecords(ctx, s.db, tableName, queryParams, opts, start, emit)
+}
+
+func (s *sqliteUserData) GetRecord(ctx context.Context, tableName string, recordID int64) (map[string]any, error) {
+	return GetRecord(ctx, s.db, fmt.Sprintf("SELECT * FROM %s WHERE id = ? LIMIT 1;", tableName), recordID)
+}
+
+func (s *sqliteUserData) UpdateRecord(ctx context.Context, tableName string, recordID int64, columns []string, values []any) (int64, error) {
+	setClauses := make([]string, len(columns))
+	for i, col := range columns {
+		setClauses[i] = col + " = ?"
+	}
+	updateSQL := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", tableName, strings.Join(setClauses, ", "))
+	return UpdateRecord(ctx, s.db, updateSQL, append(values, recordID)...)
+}
+
+func (s *sqliteUserData) DeleteRecord(ctx context.Context, tableName string, recordID int64) (int64, error) {
+	return DeleteRecord(ctx, s.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", tableName), recordID)
+}
+
+func (s *sqliteUserData) SearchText(ctx context.Context, tableName string, columns []string, term string, limit int) ([]map[string]any, error) {
+	query := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		return queryWithRetry(ctx, s.db, query, args...)
+	}
+	return searchText(ctx, sqliteDialect, query, tableName, columns, term, limit)
+}
+
+func (s *sqliteUserData) FindDuplicates(ctx context.Context, tableName string, columns []string, limit int) ([]DuplicateGroup, error) {
+	query := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		return queryWithRetry(ctx, s.db, query, args...)
+	}
+	return findDuplicates(ctx, sqliteDialect, query, tableName, columns, limit)
+}
+
+func (s *sqliteUserData) DeleteDuplicates(ctx context.Context, tableName string, columns []string, keepLast bool) ([]int64, error) {
+	db, unlock, err := lockUserDBWrites(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	defer invalidateReads(db)
+
+	query := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		return queryWithRetry(ctx, db, query, args...)
+	}
+	return deleteDuplicates(ctx, sqliteDialect, query, tableName, columns, keepLast)
+}
+
+func (s *sqliteUserData) Release() {
+	ReleaseUserDB(s.db)
+}
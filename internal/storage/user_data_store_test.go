@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/core"
+)
+
+func TestSQLiteUserDataStore(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	columns := []core.ColumnSpec{{Name: "title", Type: "text"}, {Name: "done", Type: "boolean"}, {Name: "owner", Type: "text", Default: "o'neil"}}
+	if err := store.CreateTable(ctx, "todos", columns); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	columnTypes, err := store.ColumnTypes(ctx, "todos")
+	if err != nil || columnTypes["title"] != "TEXT" || columnTypes["done"] != "BOOLEAN" {
+		t.Fatalf("ColumnTypes = %v, %v", columnTypes, err)
+	}
+
+	id, err := store.InsertRecord(ctx, "todos", []string{"title", "done"}, []any{"write tests", false})
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+	if _, err := store.UpdateRecord(ctx, "todos", id, []string{"done"}, []any{true}); err != nil {
+		t.Fatalf("UpdateRecord: %v", err)
+	}
+	record, err := store.GetRecord(ctx, "todos", id)
+	if err != nil || record["title"] != "write tests" || record["done"] != true || record["owner"] != "o'neil" {
+		t.Fatalf("GetRecord = %v, %v", record, err)
+	}
+
+	params := url.Values{"done": {"1"}}
+	opts, err := core.ParseListQueryOptions(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.ListRecords(ctx, "todos", params, opts)
+	if err != nil || result.Pagination.Total != 1 || len(result.Records) != 1 {
+		t.Fatalf("ListRecords = %+v, %v; want one record", result, err)
+	}
+
+	if _, err := store.DeleteRecord(ctx, "todos", id); err != nil {
+		t.Fatalf("DeleteRecord: %v", err)
+	}
+	if _, err := store.GetRecord(ctx, "todos", id); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("GetRecord after delete = %v, want ErrRecordNotFound", err)
+	}
+
+	if err := DeleteUserData(ctx, location); err != nil {
+		t.Fatalf("DeleteUserData: %v", err)
+	}
+	if err := DeleteUserData(ctx, location); err != nil {
+		t.Fatalf("DeleteUserData is not idempotent: %v", err)
+	}
+}
+
+func TestStrictTable(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	if err := store.CreateStrictTable(ctx, "flags", []core.ColumnSpec{{Name: "on", Type: "boolean"}}); err == nil {
+		t.Fatal("CreateStrictTable accepted a BOOLEAN column")
+	}
+	if err := store.CreateStrictTable(ctx, "items", []core.ColumnSpec{{Name: "qty", Type: "integer"}, {Name: "name", Type: "text"}}); err != nil {
+		t.Fatalf("CreateStrictTable: %v", err)
+	}
+	if _, err := store.InsertRecord(ctx, "items", []string{"qty"}, []any{"many"}); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("InsertRecord of text into INTEGER = %v, want ErrTypeMismatch", err)
+	}
+	id, err := store.InsertRecord(ctx, "items", []string{"qty", "name"}, []any{3, "bolt"})
+	if err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+	record, err := store.GetRecord(ctx, "items", id)
+	if err != nil || record["qty"] != int64(3) || len(record["created_at"].(string)) != len("2006-01-02T15:04:05Z") {
+		t.Fatalf("GetRecord = %v, %v", record, err)
+	}
+	if err := store.AddColumns(ctx, "items", []core.ColumnSpec{{Name: "done", Type: "boolean"}}); !errors.Is(err, ErrTypeMismatch) {
+		t.Fatalf("AddColumns of BOOLEAN to a strict table = %v, want ErrTypeMismatch", err)
+	}
+}
+
+func TestAddColumns(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+	if err := store.CreateTable(ctx, "events", []core.ColumnSpec{{Name: "kind", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if _, err := store.ColumnTypes(ctx, "events"); err != nil { // Cached until the schema changes
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	added := []core.ColumnSpec{{Name: "count", Type: "integer"}}
+	if err := store.AddColumns(ctx, "events", added); err != nil {
+		t.Fatalf("AddColumns: %v", err)
+	}
+	if err := store.AddColumns(ctx, "events", append(added, core.ColumnSpec{Name: "kind", Type: "text"})); err != nil {
+		t.Fatalf("AddColumns of existing columns: %v", err)
+	}
+	if columnTypes, err := store.ColumnTypes(ctx, "events"); err != nil || columnTypes["count"] != "INTEGER" {
+		t.Fatalf("ColumnTypes = %v, %v; want the added column", columnTypes, err)
+	}
+	if err := store.AddColumns(ctx, "missing", added); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("AddColumns to a missing table = %v, want ErrTableNotFound", err)
+	}
+}
+
+func TestTrashAndRestoreUserData(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	if err := store.CreateTable(ctx, "notes", []core.ColumnSpec{{Name: "body", Type: "text"}}); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if _, err := store.InsertRecord(ctx, "notes", []string{"body"}, []any{"keep me"}); err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	// A trashed table is hidden until renamed back
+	trashName := core.TrashedTablePrefix + "1"
+	if err := store.RenameTable(ctx, "notes", trashName); err != nil {
+		t.Fatalf("RenameTable: %v", err)
+	}
+	if tables, err := store.ListTables(ctx); err != nil || len(tables) != 0 {
+		t.Fatalf("ListTables = %v, %v; want the trashed table hidden", tables, err)
+	}
+	if err := store.RenameTable(ctx, "notes", trashName); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("RenameTable of missing table = %v, want ErrTableNotFound", err)
+	}
+	if err := store.RenameTable(ctx, trashName, "notes"); err != nil {
+		t.Fatalf("RenameTable back: %v", err)
+	}
+	store.Release()
+
+	trashed, err := TrashUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("TrashUserData: %v", err)
+	}
+	if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("database file still in place after TrashUserData: %v", err)
+	}
+	if err := RestoreUserData(ctx, trashed, location); err != nil {
+		t.Fatalf("RestoreUserData: %v", err)
+	}
+	store, err = OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData after restore: %v", err)
+	}
+	defer store.Release()
+	if record, err := store.GetRecord(ctx, "notes", 1); err != nil || record["body"] != "keep me" {
+		t.Fatalf("GetRecord after restore = %v, %v", record, err)
+	}
+}
+
+func TestPostgresLocation(t *testing.T) {
+	schema := tenantSchema("3f2a7c1e-0000-4000-8000-000000000001", "app")
+	if !core.IsValidIdentifier(schema) || len(schema) > 63 {
+		t.Fatalf("tenantSchema = %q, want a short valid identifier", schema)
+	}
+	if got, err := postgresSchema(postgresLocationPrefix + schema); err != nil || got != schema {
+		t.Fatalf("postgresSchema = %q, %v", got, err)
+	}
+	if _, err := postgresSchema(filepath.Join("data", "u1", "app.db")); !errors.Is(err, ErrUserDataUnsupported) {
+		t.Fatalf("postgresSchema(file path) = %v, want ErrUserDataUnsupported", err)
+	}
+}
+
+func TestListRecordsFilters(t *testing.T) {
+	ctx := context.Background()
+	location := UserDataLocation(t.TempDir(), "u1", "app")
+	if err := PrepareUserData(ctx, location); err != nil {
+		t.Fatalf("PrepareUserData: %v", err)
+	}
+	store, err := OpenUserData(ctx, location)
+	if err != nil {
+		t.Fatalf("OpenUserData: %v", err)
+	}
+	defer store.Release()
+
+	if err := store.CreateTable(ctx, "bad", []core.ColumnSpec{{Name: "n", Type: "integer", Collate: "nocase"}}); err == nil {
+		t.Fatal("CreateTable accepted a collation on an INTEGER column")
+	}
+	columns := []core.ColumnSpec{{Name: "email", Type: "text", Collate: "nocase"}, {Name: "name", Type: "text"}}
+	if err := store.CreateTable(ctx, "users", columns); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if _, err := store.InsertRecord(ctx, "users", []string{"email", "name"}, []any{"Ann@Example.com", "Ann"}); err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+
+	count := func(params url.Values) int {
+		t.Helper()
+		opts, err := core.ParseListQueryOptions(params)
+		if err != nil {
+			t.Fatalf("ParseListQueryOptions(%v): %v", params, err)
+		}
+		result, err := store.ListRecords(ctx, "users", params, opts)
+		if err != nil {
+			t.Fatalf("ListRecords(%v): %v", params, err)
+		}
+		return len(result.Records)
+	}
+	if n := count(url.Values{"email": {"ann@example.com"}}); n != 1 {
+		t.Errorf("filter on nocase column matched %d records, want 1", n)
+	}
+	if n := count(url.Values{"name": {"ANN"}}); n != 0 {
+		t.Errorf("case-sensitive filter matched %d records, want 0", n)
+	}
+	if n := count(url.Values{"name": {"ANN"}, "case_insensitive": {"true"}}); n != 1 {
+		t.Errorf("case_insensitive filter matched %d records, want 1", n)
+	}
+	if n := count(url.Values{"name[is]": {"null"}}); n != 0 {
+		t.Errorf("name[is]=null matched %d records, want 0", n)
+	}
+	if n := count(url.Values{"name[is]": {"not_null"}}); n != 1 {
+		t.Errorf("name[is]=not_null matched %d records, want 1", n)
+	}
+
+	hourAgo := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	if n := count(url.Values{"created_at[after]": {hourAgo}}); n != 1 {
+		t.Errorf("created_at[after] matched %d records, want 1", n)
+	}
+	if n := count(url.Values{"created_at[after]": {"2000-01-01"}, "created_at[before]": {hourAgo}}); n != 0 {
+		t.Errorf("created_at range before the insert matched %d records, want 0", n)
+	}
+	opts, _ := core.ParseListQueryOptions(nil)
+	if _, err := store.ListRecords(ctx, "users", url.Values{"name[after]": {"2024-01-01"}}, opts); !errors.Is(err, ErrInvalidFilterValue) {
+		t.Errorf("date filter on a TEXT column: error = %v, want ErrInvalidFilterValue", err)
+	}
+}
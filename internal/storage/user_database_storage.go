@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-sqlite3"
 
@@ -26,6 +27,7 @@ var (
 	ErrInvalidFilterValue  = errors.New("invalid value provided for filter") // New error
 	ErrInvalidSortColumn   = errors.New("invalid sort column")
 	ErrInvalidFieldColumn  = errors.New("invalid field column")
+	ErrTableExists         = errors.New("table already exists")
 )
 
 // ListRecordsResult contains records and pagination metadata
@@ -43,27 +45,41 @@ type PaginationMeta struct {
 
 // --- User DB Connection ---
 
-// ConnectUserDB opens and pings a connection to a specific user DB file.
-// The caller is responsible for closing the connection.
+// ConnectUserDB returns a pooled connection to a specific user DB file, opening it
+// on first use. The caller must hand it back with ReleaseUserDB instead of closing it.
 func ConnectUserDB(ctx context.Context, filePath string) (*sql.DB, error) {
-	customLog.Printf("Storage: Opening user DB: %s", filePath)
+	return userDBs.acquire(ctx, filePath)
+}
+
+// openUserDB opens and pings a new connection pool for a user DB file.
+func openUserDB(ctx context.Context, filePath string) (*sql.DB, error) {
+	customLog.Ctx(ctx).Printf("Storage: Opening user DB: %s", filePath)
 	// Ensured foreign keys, WAL mode and busy timeout for better concurrency
-	userDb, err := sql.Open("sqlite3", filePath+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000")
+	driverName, options := userDBDriver()
+	userDb, err := sql.Open(driverName, filePath+"?"+options)
 	if err != nil {
-		customLog.Warnf("Storage: Failed to open user DB file '%s': %v", filePath, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to open user DB file '%s': %v", filePath, err)
 		return nil, fmt.Errorf("failed to access user database storage: %w", err)
 	}
 
 	// Ping to verify connection
 	if err = userDb.PingContext(ctx); err != nil {
 		userDb.Close() // Close if ping fails
-		customLog.Warnf("Storage: Failed to ping user DB '%s': %v", filePath, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to ping user DB '%s': %v", filePath, err)
 		return nil, fmt.Errorf("failed to connect to user database storage: %w", err)
 	}
 
-	// Optional: Configure pool for user DB connections if kept open longer
-	// userDB.SetMaxOpenConns(5)
-	// userDB.SetMaxIdleConns(1)
+	// Encrypted files can only be switched to WAL once keyed; the mode is persistent
+	if encryptionEnabled() {
+		if _, err = userDb.ExecContext(ctx, "PRAGMA journal_mode=WAL;"); err != nil {
+			userDb.Close()
+			customLog.Ctx(ctx).Warnf("Storage: Failed to enable WAL on encrypted user DB '%s': %v", filePath, err)
+			return nil, fmt.Errorf("failed to connect to user database storage: %w", err)
+		}
+	}
+
+	// Handles stay open in the pool; let idle SQLite connections go after a while
+	userDb.SetConnMaxIdleTime(defaultPoolIdleTimeout)
 
 	return userDb, nil
 }
@@ -71,11 +87,22 @@ func ConnectUserDB(ctx context.Context, filePath string) (*sql.DB, error) {
 // --- User DB Schema Operations ---
 
 // PragmaTableInfo retrieves schema information for a table.
+// Results are cached per database file until the table is created, dropped or altered.
 func PragmaTableInfo(ctx context.Context, userDB *sql.DB, tableName string) (map[string]string, error) {
+	dbPath := userDBs.pathOf(userDB)
+	var generation uint64
+	if dbPath != "" {
+		cached, gen, ok := tableSchemas.get(dbPath, tableName)
+		if ok {
+			return cached, nil
+		}
+		generation = gen
+	}
+
 	pragmaSQL := fmt.Sprintf("PRAGMA table_info(%s);", tableName) // Assumes tableName is pre-validated
-	rows, err := userDB.QueryContext(ctx, pragmaSQL)
+	rows, err := queryWithRetry(ctx, userDB, pragmaSQL)
 	if err != nil {
-		customLog.Warnf("Storage: Failed PRAGMA for Table '%s': %v", tableName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed PRAGMA for Table '%s': %v", tableName, err)
 		// Check if error indicates table not found
 		if strings.Contains(err.Error(), "no such table") { // Brittle check
 			return nil, ErrTableNotFound
@@ -96,18 +123,21 @@ func PragmaTableInfo(ctx context.Context, userDB *sql.DB, tableName string) (map
 		var pk int
 
 		if err := rows.Scan(&cid, &name, &sqlType, &notnull, &dfltValue, &pk); err != nil {
-			customLog.Warnf("Storage: Failed scanning PRAGMA for Table '%s': %v", tableName, err)
+			customLog.Ctx(ctx).Warnf("Storage: Failed scanning PRAGMA for Table '%s': %v", tableName, err)
 			return nil, fmt.Errorf("failed to parse schema: %w", err)
 		}
 		columnTypes[strings.ToLower(name)] = strings.ToUpper(sqlType)
 	}
 	if err = rows.Err(); err != nil {
-		customLog.Warnf("Storage: Error iterating PRAGMA for Table '%s': %v", tableName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error iterating PRAGMA for Table '%s': %v", tableName, err)
 		return nil, fmt.Errorf("failed to read schema: %w", err)
 	}
 	if !foundColumns {
 		return nil, ErrTableNotFound // No rows means table doesn't exist
 	}
+	if dbPath != "" {
+		tableSchemas.put(dbPath, tableName, generation, columnTypes)
+	}
 	return columnTypes, nil
 }
 
@@ -117,10 +147,10 @@ func ListTables(ctx context.Context, userDB *sql.DB) ([]domain.TableMetadata, er
 	// Exclude sqlite internal tables
 	query := `SELECT * FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name;`
 
-	rows, err := userDB.QueryContext(ctx, query)
+	rows, err := queryWithRetry(ctx, userDB, query)
 
 	if err != nil {
-		customLog.Warnf("Storage: Error listing tables: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Error listing tables: %v", err)
 		return nil, fmt.Errorf("database error listing tables: %w", err)
 	}
 	defer rows.Close()
@@ -131,9 +161,12 @@ func ListTables(ctx context.Context, userDB *sql.DB) ([]domain.TableMetadata, er
 		var table domain.TableMetadata
 
 		if err := rows.Scan(&table.Type, &table.Name, &table.TableName, &table.RootPage, &table.Sql); err != nil {
-			customLog.Warnf("Storage: Error scanning table name: %v", err)
+			customLog.Ctx(ctx).Warnf("Storage: Error scanning table name: %v", err)
 			return nil, fmt.Errorf("failed processing table list: %w", err)
 		}
+		if strings.HasPrefix(table.Name, core.TrashedTablePrefix) {
+			continue // Dropped, waiting in the trash
+		}
 		// Get column information for the current table.
 		columnInfos, err := getColumnInfo(ctx, userDB, table.Name)
 		if err != nil {
@@ -144,7 +177,7 @@ func ListTables(ctx context.Context, userDB *sql.DB) ([]domain.TableMetadata, er
 		tables = append(tables, table)
 	}
 	if err = rows.Err(); err != nil {
-		customLog.Warnf("Storage: Error iterating table list: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Error iterating table list: %v", err)
 		return nil, fmt.Errorf("failed reading table list: %w", err)
 	}
 
@@ -154,29 +187,128 @@ func ListTables(ctx context.Context, userDB *sql.DB) ([]domain.TableMetadata, er
 	return tables, nil
 }
 
+// countUserTables returns the number of tables outside the trash in the user DB file,
+// from the schema cache when the schema did not change since the last count.
+func countUserTables(ctx context.Context, filePath string) (int64, error) {
+	count, generation, ok := tableSchemas.getTableCount(filePath)
+	if ok {
+		return count, nil
+	}
+
+	userDB, err := ConnectUserDB(ctx, filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer ReleaseUserDB(userDB)
+	if err := userDB.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name NOT GLOB ?;", core.TrashedTablePrefix+"*").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed counting tables: %w", err)
+	}
+	tableSchemas.putTableCount(filePath, generation, count)
+	return count, nil
+}
+
 // CreateTable executes a CREATE TABLE statement in the user DB.
 func CreateTable(ctx context.Context, userDB *sql.DB, createSQL string) error {
-	_, err := userDB.ExecContext(ctx, createSQL) // createSQL assumed pre-validated
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
+	_, err = execWithRetry(ctx, userDB, createSQL) // createSQL assumed pre-validated
 	if err != nil {
-		customLog.Warnf("Storage: Failed to execute CREATE TABLE: %v\nSQL: %s", err, createSQL)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to execute CREATE TABLE: %v\nSQL: %s", err, createSQL)
 		// Could try to parse error for specific issues (e.g., table exists if not using IF NOT EXISTS)
 		return fmt.Errorf("failed to create table: %w", err)
 	}
+	invalidateTableSchema(userDB, "") // Table name is embedded in createSQL
 	return nil
 }
 
 // DropTable executes a DROP TABLE statement in the user DB.
 // tableName should be pre-validated by the caller.
 func DropTable(ctx context.Context, userDB *sql.DB, tableName string) error {
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
 	// Use IF EXISTS to prevent error if table doesn't exist (makes operation idempotent)
 	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s;", tableName) // tableName is assumed validated
-	_, err := userDB.ExecContext(ctx, dropSQL)
+	_, err = execWithRetry(ctx, userDB, dropSQL)
 
 	if err != nil {
 		// This could indicate a more serious issue (permissions, locked db, etc.)
-		customLog.Warnf("Storage: Failed DROP TABLE for Table '%s': %v", tableName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed DROP TABLE for Table '%s': %v", tableName, err)
 		return fmt.Errorf("database error dropping table: %w", err)
 	}
+	invalidateTableSchema(userDB, tableName)
+	return nil
+}
+
+// RenameTable renames a table of the user DB. Both names should be pre-validated by the
+// caller. It returns ErrTableNotFound if from does not exist and ErrTableExists if to does.
+func RenameTable(ctx context.Context, userDB *sql.DB, from, to string) error {
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
+	renameSQL := fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", from, to) // Names are assumed validated
+	if _, err := execWithRetry(ctx, userDB, renameSQL); err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed RENAME of Table '%s' to '%s': %v", from, to, err)
+		switch {
+		case strings.Contains(err.Error(), "no such table"):
+			return ErrTableNotFound
+		case strings.Contains(err.Error(), "already another table"):
+			return ErrTableExists
+		}
+		return fmt.Errorf("database error renaming table: %w", err)
+	}
+	invalidateTableSchema(userDB, from)
+	invalidateTableSchema(userDB, to)
+	return nil
+}
+
+// AddColumns adds validated columns (see core.ValidateTableDefinition) to a table of the
+// user DB. Columns another writer added meanwhile are skipped, so concurrent requests
+// adding the same column both succeed.
+func AddColumns(ctx context.Context, userDB *sql.DB, tableName string, columns []core.ColumnSpec) error {
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+	defer invalidateTableSchema(userDB, tableName)
+
+	for _, col := range columns {
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, col.Name, col.Type) // Names are assumed validated
+		if col.Collate != "" {
+			alterSQL += " COLLATE " + col.Collate
+		}
+		if col.Default != nil {
+			literal, _ := core.DefaultLiteral(col.Default)
+			alterSQL += " DEFAULT " + literal
+		}
+		if _, err := execWithRetry(ctx, userDB, alterSQL); err != nil {
+			switch {
+			case strings.Contains(err.Error(), "duplicate column name"):
+				continue
+			case strings.Contains(err.Error(), "no such table"):
+				return ErrTableNotFound
+			case strings.Contains(err.Error(), "unknown datatype"):
+				return fmt.Errorf("%w: strict tables cannot have %s columns", ErrTypeMismatch, col.Type)
+			}
+			customLog.Ctx(ctx).Warnf("Storage: Failed to add column '%s' to Table '%s': %v", col.Name, tableName, err)
+			return fmt.Errorf("database error adding column: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -219,9 +351,16 @@ func ListUserTableSchema(ctx context.Context, userDB *sql.DB, tableName string)
 
 // InsertRecord executes an INSERT statement and returns the last insert ID.
 func InsertRecord(ctx context.Context, userDB *sql.DB, insertSQL string, values ...interface{}) (int64, error) {
-	result, err := userDB.ExecContext(ctx, insertSQL, values...)
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
+	result, err := execWithRetry(ctx, userDB, insertSQL, values...)
 	if err != nil {
-		customLog.Warnf("Storage: Failed INSERT: %v\nSQL: %s", err, insertSQL)
+		customLog.Ctx(ctx).Warnf("Storage: Failed INSERT: %v\nSQL: %s", err, insertSQL)
 		// Map common SQLite errors to specific storage errors
 		if strings.Contains(err.Error(), "no such table") {
 			return 0, ErrTableNotFound
@@ -229,7 +368,7 @@ func InsertRecord(ctx context.Context, userDB *sql.DB, insertSQL string, values
 		if strings.Contains(err.Error(), "has no column named") {
 			return 0, ErrColumnNotFound
 		}
-		if strings.Contains(err.Error(), "datatype mismatch") {
+		if strings.Contains(err.Error(), "datatype mismatch") || strings.Contains(err.Error(), "cannot store") { // The latter from STRICT tables
 			return 0, ErrTypeMismatch
 		}
 		var sqliteErr sqlite3.Error
@@ -240,7 +379,7 @@ func InsertRecord(ctx context.Context, userDB *sql.DB, insertSQL string, values
 	}
 	lastID, err := result.LastInsertId()
 	if err != nil {
-		customLog.Warnf("Storage: Failed to get LastInsertId after INSERT: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get LastInsertId after INSERT: %v", err)
 		return 0, fmt.Errorf("failed to retrieve ID after insert: %w", err)
 	}
 	return lastID, nil
@@ -248,18 +387,67 @@ func InsertRecord(ctx context.Context, userDB *sql.DB, insertSQL string, values
 
 // ListRecords retrieves records with support for filtering, pagination, sorting, and field selection.
 // Accepts tableName, query parameters, and parsed query options.
+// Results may come from the read cache and must be treated as read-only.
 func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryParams url.Values, opts *core.ListQueryOptions) (*ListRecordsResult, error) {
+	// The sort may come from table options rather than the query string
+	cached, store := recordReads.lookup(userDB, "list\x00"+tableName+"\x00"+queryParams.Encode()+"\x00"+opts.SortBy+"\x00"+opts.SortOrder)
+	if cached != nil {
+		return cached.(*ListRecordsResult), nil
+	}
+	if readReplicas.lagging(userDB) {
+		store = func(any) {}
+	}
+
+	result := &ListRecordsResult{Records: make([]map[string]any, 0)}
+	err := StreamRecords(ctx, userDB, tableName, queryParams, opts,
+		func(pagination PaginationMeta) error {
+			result.Pagination = pagination
+			return nil
+		},
+		func(record map[string]any) error {
+			result.Records = append(result.Records, record)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	store(result)
+	return result, nil
+}
+
+// StreamRecords runs the same query as ListRecords but hands records to emit one at a
+// time as they are scanned instead of collecting them. start is called with the
+// pagination metadata once the query has succeeded and before the first record, so
+// errors returned before start can still be reported as a normal response.
+func StreamRecords(ctx context.Context, userDB *sql.DB, tableName string, queryParams url.Values, opts *core.ListQueryOptions,
+	start func(PaginationMeta) error, emit func(map[string]any) error) error {
 
 	// 1. Fetch schema to validate filter keys, sort column, and field columns
 	columnTypes, err := PragmaTableInfo(ctx, userDB, tableName)
 	if err != nil {
-		return nil, err // Propagate ErrTableNotFound or other schema errors
+		return err // Propagate ErrTableNotFound or other schema errors
+	}
+
+	// Only the rows are read from a replica: the schema cache belongs to the primary
+	readDB, release := readReplicas.route(ctx, userDB)
+	defer release()
+	query := func(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+		return queryWithRetry(ctx, readDB, query, args...)
 	}
+	return streamRecords(ctx, sqliteDialect, query, tableName, columnTypes, queryParams, opts, start, emit)
+}
+
+// streamRecords validates the list options against columnTypes and streams the matching
+// rows of from (a table name, qualified if the backend needs it). Queries are written
+// with "?" placeholders and rebound for the backend's driver.
+func streamRecords(ctx context.Context, d dialect, query func(ctx context.Context, query string, args ...any) (*sql.Rows, error),
+	from string, columnTypes map[string]string, queryParams url.Values, opts *core.ListQueryOptions,
+	start func(PaginationMeta) error, emit func(map[string]any) error) error {
 
 	// 2. Validate sort column exists in schema (if specified)
 	if opts.SortBy != "" {
 		if _, exists := columnTypes[strings.ToLower(opts.SortBy)]; !exists {
-			return nil, fmt.Errorf("%w: '%s' not found in table schema", ErrInvalidSortColumn, opts.SortBy)
+			return fmt.Errorf("%w: '%s' not found in table schema", ErrInvalidSortColumn, opts.SortBy)
 		}
 	}
 
@@ -269,7 +457,7 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 		validatedFields := make([]string, 0, len(opts.Fields))
 		for _, field := range opts.Fields {
 			if _, exists := columnTypes[strings.ToLower(field)]; !exists {
-				return nil, fmt.Errorf("%w: '%s' not found in table schema", ErrInvalidFieldColumn, field)
+				return fmt.Errorf("%w: '%s' not found in table schema", ErrInvalidFieldColumn, field)
 			}
 			validatedFields = append(validatedFields, field)
 		}
@@ -292,19 +480,56 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 			continue
 		}
 		filterValueStr := values[0]
-		lowerKey := strings.ToLower(key)
 
 		// A. Validate filter key format
-		if !core.IsValidIdentifier(key) {
-			customLog.Warnf("Storage: ListRecords received invalid filter key format: %s", key)
-			return nil, fmt.Errorf("%w: invalid filter key format '%s'", ErrInvalidFilterValue, key)
+		column, operator, err := core.ParseFilterKey(key)
+		if err != nil {
+			customLog.Ctx(ctx).Warnf("Storage: ListRecords received invalid filter key: %s", key)
+			return fmt.Errorf("%w: %s", ErrInvalidFilterValue, err.Error())
 		}
 
 		// B. Validate filter key exists in schema
-		expectedType, exists := columnTypes[lowerKey]
+		expectedType, exists := columnTypes[strings.ToLower(column)]
 		if !exists {
-			customLog.Warnf("Storage: ListRecords received filter key not in schema: %s", key)
-			return nil, fmt.Errorf("%w: filter key '%s' not found in table schema", ErrInvalidFilterValue, key)
+			customLog.Ctx(ctx).Warnf("Storage: ListRecords received filter key not in schema: %s", key)
+			return fmt.Errorf("%w: filter key '%s' not found in table schema", ErrInvalidFilterValue, column)
+		}
+
+		// Null checks apply to columns of any type
+		if operator == core.FilterIs {
+			switch strings.ToLower(filterValueStr) {
+			case core.FilterNull:
+				whereClauses = append(whereClauses, column+" IS NULL")
+			case core.FilterNotNull:
+				whereClauses = append(whereClauses, column+" IS NOT NULL")
+			default:
+				return fmt.Errorf("%w: '%s' must be '%s' or '%s'", ErrInvalidFilterValue, key, core.FilterNull, core.FilterNotNull)
+			}
+			continue
+		}
+
+		// Date ranges apply to timestamp columns; created_at is TEXT in strict tables
+		if operator == core.FilterAfter || operator == core.FilterBefore {
+			if expectedType != "TIMESTAMP" && !strings.EqualFold(column, "created_at") {
+				return fmt.Errorf("%w: '%s' only applies to timestamp columns", ErrInvalidFilterValue, key)
+			}
+			t, err := core.ParseFilterTime(filterValueStr)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidFilterValue, err.Error())
+			}
+			comparison := ">"
+			if operator == core.FilterBefore {
+				comparison = "<"
+			}
+			if d.name == BackendPostgres {
+				whereClauses = append(whereClauses, fmt.Sprintf("%s %s ?", column, comparison))
+				args = append(args, t)
+			} else {
+				// SQLite timestamps are text in one of several formats; datetime() normalizes them
+				whereClauses = append(whereClauses, fmt.Sprintf("datetime(%s) %s ?", column, comparison))
+				args = append(args, t.Format(time.DateTime))
+			}
+			continue
 		}
 
 		// C. Attempt to convert filterValueStr to expected type
@@ -327,19 +552,23 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 		case "TEXT":
 			convertedValue = filterValueStr
 		case "BLOB":
-			customLog.Printf("Storage: ListRecords ignoring filter on BLOB column: %s", key)
+			customLog.Ctx(ctx).Printf("Storage: ListRecords ignoring filter on BLOB column: %s", key)
 			continue
 		default:
-			customLog.Printf("Storage: ListRecords ignoring filter on column '%s' with unhandled type '%s'", key, expectedType)
+			customLog.Ctx(ctx).Printf("Storage: ListRecords ignoring filter on column '%s' with unhandled type '%s'", key, expectedType)
 			continue
 		}
 
 		if conversionError != nil {
-			customLog.Printf("Storage: ListRecords conversion error for key '%s', value '%s': %v", key, filterValueStr, conversionError)
-			return nil, fmt.Errorf("%w: %s", ErrInvalidFilterValue, conversionError.Error())
+			customLog.Ctx(ctx).Printf("Storage: ListRecords conversion error for key '%s', value '%s': %v", key, filterValueStr, conversionError)
+			return fmt.Errorf("%w: %s", ErrInvalidFilterValue, conversionError.Error())
 		}
 
-		whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", key))
+		if expectedType == "TEXT" && opts.CaseInsensitive {
+			whereClauses = append(whereClauses, fmt.Sprintf("LOWER(%s) = LOWER(?)", column))
+		} else {
+			whereClauses = append(whereClauses, fmt.Sprintf("%s = ?", column))
+		}
 		args = append(args, convertedValue)
 	}
 
@@ -351,17 +580,16 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 
 	// 6. Get total count for pagination metadata
 	// nolint:gosec // tableName is validated by handler before reaching here
-	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", tableName, whereClause)
-	var totalCount int
-	err = userDB.QueryRowContext(ctx, countSQL, args...).Scan(&totalCount)
+	countSQL := d.rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s%s", from, whereClause))
+	totalCount, err := countRows(ctx, query, countSQL, args...)
 	if err != nil {
-		customLog.Warnf("Storage: Failed COUNT query: %v\nSQL: %s", err, countSQL)
-		return nil, fmt.Errorf("database error counting records: %w", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed COUNT query: %v\nSQL: %s", err, countSQL)
+		return fmt.Errorf("database error counting records: %w", err)
 	}
 
 	// 7. Construct final SELECT SQL with ORDER BY and LIMIT/OFFSET
 	// nolint:gosec // tableName and selectFields are validated
-	selectSQL := fmt.Sprintf("SELECT %s FROM %s%s", selectFields, tableName, whereClause)
+	selectSQL := fmt.Sprintf("SELECT %s FROM %s%s", selectFields, from, whereClause)
 
 	// Add ORDER BY clause
 	if opts.SortBy != "" {
@@ -380,23 +608,31 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 	// Add LIMIT and OFFSET
 	selectSQL += fmt.Sprintf(" LIMIT %d OFFSET %d", opts.Limit, opts.Offset)
 
-	customLog.Printf("Storage: Executing List Records SQL: %s | Args: %v", selectSQL, args)
+	customLog.Ctx(ctx).Printf("Storage: Executing List Records SQL: %s | Args: %v", selectSQL, args)
 
 	// 8. Execute query
-	rows, err := userDB.QueryContext(ctx, selectSQL, args...)
+	selectSQL = d.rebind(selectSQL)
+	rows, err := query(ctx, selectSQL, args...)
 	if err != nil {
-		customLog.Warnf("Storage: Failed SELECT: %v\nSQL: %s", err, selectSQL)
-		return nil, fmt.Errorf("database error listing records: %w", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed SELECT: %v\nSQL: %s", err, selectSQL)
+		return fmt.Errorf("database error listing records: %w", err)
 	}
 	defer rows.Close()
 
 	// 9. Process results
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("failed processing results: %w", err)
+		return fmt.Errorf("failed processing results: %w", err)
 	}
 	numColumns := len(columns)
-	records := make([]map[string]interface{}, 0)
+
+	if err := start(PaginationMeta{
+		Total:  totalCount,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}); err != nil {
+		return err
+	}
 
 	for rows.Next() {
 		scanArgs := make([]interface{}, numColumns)
@@ -405,7 +641,7 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 			scanArgs[i] = &values[i]
 		}
 		if err := rows.Scan(scanArgs...); err != nil {
-			return nil, fmt.Errorf("failed reading record data: %w", err)
+			return fmt.Errorf("failed reading record data: %w", err)
 		}
 
 		rowData := make(map[string]interface{})
@@ -417,27 +653,50 @@ func ListRecords(ctx context.Context, userDB *sql.DB, tableName string, queryPar
 				rowData[colName] = rawValue
 			}
 		}
-		records = append(records, rowData)
+		if err := emit(rowData); err != nil {
+			return err
+		}
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed processing all records: %w", err)
+		return fmt.Errorf("failed processing all records: %w", err)
 	}
 
-	return &ListRecordsResult{
-		Records: records,
-		Pagination: PaginationMeta{
-			Total:  totalCount,
-			Limit:  opts.Limit,
-			Offset: opts.Offset,
-		},
-	}, nil
+	return nil
+}
+
+// countRows runs a SELECT COUNT(*) query and returns the count.
+func countRows(ctx context.Context, query func(ctx context.Context, query string, args ...any) (*sql.Rows, error), countSQL string, args ...any) (int, error) {
+	rows, err := query(ctx, countSQL, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var count int
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
 }
 
 // GetRecord executes SELECT * WHERE id = ? and returns a single map or ErrRecordNotFound.
+// Results may come from the read cache and must be treated as read-only.
 func GetRecord(ctx context.Context, userDB *sql.DB, selectSQL string, recordID int64) (map[string]interface{}, error) {
-	rows, err := userDB.QueryContext(ctx, selectSQL, recordID) // selectSQL assumed safe with placeholder
+	cached, store := recordReads.lookup(userDB, fmt.Sprintf("get\x00%s\x00%d", selectSQL, recordID))
+	if cached != nil {
+		return cached.(map[string]interface{}), nil
+	}
+
+	if readReplicas.lagging(userDB) {
+		store = func(any) {}
+	}
+
+	readDB, release := readReplicas.route(ctx, userDB)
+	defer release()
+	rows, err := queryWithRetry(ctx, readDB, selectSQL, recordID) // selectSQL assumed safe with placeholder
 	if err != nil {
-		customLog.Warnf("Storage: Failed SELECT by ID: %v\nSQL: %s", err, selectSQL)
+		customLog.Ctx(ctx).Warnf("Storage: Failed SELECT by ID: %v\nSQL: %s", err, selectSQL)
 		if strings.Contains(err.Error(), "no such table") {
 			return nil, ErrTableNotFound
 		}
@@ -465,7 +724,7 @@ func GetRecord(ctx context.Context, userDB *sql.DB, selectSQL string, recordID i
 	}
 
 	if err := rows.Scan(scanArgs...); err != nil {
-		customLog.Warnf("Storage: Failed scanning row for SELECT by ID: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed scanning row for SELECT by ID: %v", err)
 		return nil, fmt.Errorf("failed reading record data: %w", err)
 	}
 
@@ -482,24 +741,32 @@ func GetRecord(ctx context.Context, userDB *sql.DB, selectSQL string, recordID i
 
 	// Ensure no more rows (optional check)
 	if rows.Next() {
-		customLog.Warnf("WARN: Found multiple rows for ID %d", recordID)
+		customLog.Ctx(ctx).Warnf("WARN: Found multiple rows for ID %d", recordID)
 	}
 
+	store(rowData)
 	return rowData, nil
 }
 
 // UpdateRecord executes an UPDATE statement and returns rows affected.
 func UpdateRecord(ctx context.Context, userDB *sql.DB, updateSQL string, values ...interface{}) (int64, error) {
-	result, err := userDB.ExecContext(ctx, updateSQL, values...)
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
 	if err != nil {
-		customLog.Warnf("Storage: Failed UPDATE: %v\nSQL: %s", err, updateSQL)
+		return 0, err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
+	result, err := execWithRetry(ctx, userDB, updateSQL, values...)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed UPDATE: %v\nSQL: %s", err, updateSQL)
 		if strings.Contains(err.Error(), "no such table") {
 			return 0, ErrTableNotFound
 		}
 		if strings.Contains(err.Error(), "no such column") {
 			return 0, ErrColumnNotFound
 		} // Less likely due to PRAGMA check
-		if strings.Contains(err.Error(), "datatype mismatch") {
+		if strings.Contains(err.Error(), "datatype mismatch") || strings.Contains(err.Error(), "cannot store") { // The latter from STRICT tables
 			return 0, ErrTypeMismatch
 		} // Less likely
 		var sqliteErr sqlite3.Error
@@ -510,7 +777,7 @@ func UpdateRecord(ctx context.Context, userDB *sql.DB, updateSQL string, values
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		customLog.Warnf("Storage: Failed getting RowsAffected after UPDATE: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed getting RowsAffected after UPDATE: %v", err)
 		return 0, fmt.Errorf("failed confirming update: %w", err)
 	}
 	if rowsAffected == 0 {
@@ -521,15 +788,22 @@ func UpdateRecord(ctx context.Context, userDB *sql.DB, updateSQL string, values
 
 // DeleteRecord executes a DELETE statement and returns rows affected.
 func DeleteRecord(ctx context.Context, userDB *sql.DB, deleteSQL string, recordID int64) (int64, error) {
-	result, err := userDB.ExecContext(ctx, deleteSQL, recordID) // deleteSQL assumed safe with placeholder
+	userDB, unlock, err := lockUserDBWrites(ctx, userDB)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	defer invalidateReads(userDB)
+
+	result, err := execWithRetry(ctx, userDB, deleteSQL, recordID) // deleteSQL assumed safe with placeholder
 	if err != nil {
-		customLog.Warnf("Storage: Failed DELETE: %v\nSQL: %s", err, deleteSQL)
+		customLog.Ctx(ctx).Warnf("Storage: Failed DELETE: %v\nSQL: %s", err, deleteSQL)
 		// Less likely to get specific errors here, maybe just connection issues
 		return 0, fmt.Errorf("database error during delete: %w", err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		customLog.Warnf("Storage: Failed getting RowsAffected after DELETE: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Failed getting RowsAffected after DELETE: %v", err)
 		return 0, fmt.Errorf("failed confirming delete: %w", err)
 	}
 	if rowsAffected == 0 {
@@ -541,9 +815,9 @@ func DeleteRecord(ctx context.Context, userDB *sql.DB, deleteSQL string, recordI
 // helper function to get column information
 func getColumnInfo(ctx context.Context, userDb *sql.DB, tableName string) ([]domain.ColumnInfo, error) {
 	query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
-	rows, err := userDb.QueryContext(ctx, query)
+	rows, err := queryWithRetry(ctx, userDb, query)
 	if err != nil {
-		customLog.Warnf("Storage: Error getting column info for table %s: %v", tableName, err)
+		customLog.Ctx(ctx).Warnf("Storage: Error getting column info for table %s: %v", tableName, err)
 		return nil, fmt.Errorf("database error getting column info: %w", err)
 	}
 	defer rows.Close()
@@ -552,13 +826,13 @@ func getColumnInfo(ctx context.Context, userDb *sql.DB, tableName string) ([]dom
 	for rows.Next() {
 		var colInfo domain.ColumnInfo
 		if err := rows.Scan(&colInfo.ColumnId, &colInfo.Name, &colInfo.Type, &colInfo.NotNull, &colInfo.Default, &colInfo.PK); err != nil {
-			customLog.Warnf("Storage: Error scanning column info: %v", err)
+			customLog.Ctx(ctx).Warnf("Storage: Error scanning column info: %v", err)
 			return nil, fmt.Errorf("failed processing column info: %w", err)
 		}
 		columnInfos = append(columnInfos, colInfo)
 	}
 	if err = rows.Err(); err != nil {
-		customLog.Warnf("Storage: Error iterating column info: %v", err)
+		customLog.Ctx(ctx).Warnf("Storage: Error iterating column info: %v", err)
 		return nil, fmt.Errorf("failed reading column info: %w", err)
 	}
 
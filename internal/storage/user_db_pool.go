@@ -0,0 +1,271 @@
+// internal/storage/user_db_pool.go
+package storage
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// Defaults for the shared user DB pool, overridable via ConfigureUserDBPool.
+const (
+	defaultPoolMaxOpen     = 128
+	defaultPoolIdleTimeout = 5 * time.Minute
+)
+
+// pooledUserDB is one cached *sql.DB handle for a user DB file.
+type pooledUserDB struct {
+	path     string
+	db       *sql.DB
+	refs     int       // Handlers currently holding the handle
+	lastUsed time.Time // Last acquire or release
+	stale    bool      // Invalidated; close once the last holder releases it
+	elem     *list.Element
+}
+
+// userDBPool keeps user DB handles open across requests, keyed by file path.
+// Idle handles are evicted least-recently-used first once maxOpen is exceeded or
+// after idleTimeout; handles still in use are never closed underneath a caller.
+type userDBPool struct {
+	mu          sync.Mutex
+	entries     map[string]*pooledUserDB
+	byHandle    map[*sql.DB]*pooledUserDB
+	lru         *list.List // Front = most recently used
+	maxOpen     int
+	idleTimeout time.Duration
+}
+
+var userDBs = newUserDBPool(defaultPoolMaxOpen, defaultPoolIdleTimeout)
+
+func newUserDBPool(maxOpen int, idleTimeout time.Duration) *userDBPool {
+	return &userDBPool{
+		entries:     make(map[string]*pooledUserDB),
+		byHandle:    make(map[*sql.DB]*pooledUserDB),
+		lru:         list.New(),
+		maxOpen:     maxOpen,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// ConfigureUserDBPool sets the maximum number of open user DB handles and how long an
+// unused handle stays open. Call once at startup before serving requests.
+func ConfigureUserDBPool(maxOpen int, idleTimeout time.Duration) {
+	userDBs.mu.Lock()
+	defer userDBs.mu.Unlock()
+	if maxOpen > 0 {
+		userDBs.maxOpen = maxOpen
+	}
+	if idleTimeout > 0 {
+		userDBs.idleTimeout = idleTimeout
+	}
+}
+
+// acquire returns the cached handle for path, opening it on first use.
+func (p *userDBPool) acquire(ctx context.Context, path string) (*sql.DB, error) {
+	if movedUserDBs.moved(path) {
+		return nil, ErrDatabaseMoved
+	}
+	p.mu.Lock()
+	if entry, ok := p.entries[path]; ok {
+		entry.refs++
+		entry.lastUsed = time.Now()
+		p.lru.MoveToFront(entry.elem)
+		p.mu.Unlock()
+		return entry.db, nil
+	}
+	p.mu.Unlock()
+
+	// Open outside the lock; opening and pinging touches the disk
+	db, err := openUserDB(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Another request may have opened the same file meanwhile; keep the first handle
+	if entry, ok := p.entries[path]; ok {
+		db.Close()
+		entry.refs++
+		entry.lastUsed = time.Now()
+		p.lru.MoveToFront(entry.elem)
+		return entry.db, nil
+	}
+
+	entry := &pooledUserDB{path: path, db: db, refs: 1, lastUsed: time.Now()}
+	entry.elem = p.lru.PushFront(entry)
+	p.entries[path] = entry
+	p.byHandle[db] = entry
+	p.evictLocked()
+	return db, nil
+}
+
+// release returns a handle obtained from acquire.
+func (p *userDBPool) release(db *sql.DB) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.byHandle[db]
+	if !ok {
+		// Not pooled (or already evicted and closed); closing again is harmless
+		db.Close()
+		return
+	}
+	if entry.refs > 0 {
+		entry.refs--
+	}
+	entry.lastUsed = time.Now()
+
+	if entry.stale && entry.refs == 0 {
+		p.closeLocked(entry)
+		return
+	}
+	p.evictLocked()
+}
+
+// invalidate drops the handle for path so the next acquire reopens the file.
+func (p *userDBPool) invalidate(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[path]
+	if !ok {
+		return
+	}
+	delete(p.entries, path)
+	p.lru.Remove(entry.elem)
+	entry.stale = true
+	if entry.refs == 0 {
+		p.closeLocked(entry)
+	}
+}
+
+// stale reports whether the pooled handle db was invalidated while still held.
+func (p *userDBPool) stale(db *sql.DB) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.byHandle[db]
+	return ok && entry.stale
+}
+
+// evictLocked closes idle handles that timed out or exceed maxOpen. Caller holds p.mu.
+func (p *userDBPool) evictLocked() {
+	now := time.Now()
+	for elem := p.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		entry := elem.Value.(*pooledUserDB)
+		overLimit := len(p.entries) > p.maxOpen
+		idleExpired := now.Sub(entry.lastUsed) > p.idleTimeout
+		if entry.refs == 0 && (overLimit || idleExpired) {
+			delete(p.entries, entry.path)
+			p.lru.Remove(elem)
+			p.closeLocked(entry)
+		}
+		elem = prev
+	}
+}
+
+// closeLocked closes an entry's handle. Caller holds p.mu and has unlinked the entry.
+func (p *userDBPool) closeLocked(entry *pooledUserDB) {
+	delete(p.byHandle, entry.db)
+	if err := entry.db.Close(); err != nil {
+		customLog.Warnf("Storage: Error closing pooled user DB '%s': %v", entry.path, err)
+	}
+}
+
+// pathOf returns the file path behind a pooled handle, or "" for handles not owned by the pool.
+func (p *userDBPool) pathOf(db *sql.DB) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.byHandle[db]; ok {
+		return entry.path
+	}
+	return ""
+}
+
+// openHandles returns the number of user DB handles currently open.
+func (p *userDBPool) openHandles() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.byHandle)
+}
+
+// handleCounts returns the number of open handles held by callers and idle.
+func (p *userDBPool) handleCounts() (inUse, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range p.byHandle {
+		if entry.refs > 0 {
+			inUse++
+		} else {
+			idle++
+		}
+	}
+	return inUse, idle
+}
+
+// isOpen reports whether the pool holds a handle for path.
+func (p *userDBPool) isOpen(path string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.entries[path]
+	return ok
+}
+
+// ReleaseUserDB hands a connection obtained from ConnectUserDB back to the pool.
+func ReleaseUserDB(userDB *sql.DB) {
+	userDBs.release(userDB)
+}
+
+// InvalidateUserDB closes the pooled handle for filePath (once no request is using it)
+// and forgets its cached table schemas and reads, so later requests reopen the file. Call after deleting or replacing a database file.
+// Other instances sharing the data directory are told to do the same.
+func InvalidateUserDB(filePath string) {
+	invalidateUserDB(filePath)
+	notifyInvalidation(Invalidation{Kind: InvalidateDatabase, Path: filePath})
+}
+
+func invalidateUserDB(filePath string) {
+	userDBs.invalidate(filePath)
+	tableSchemas.invalidate(filePath, "")
+	recordReads.invalidate(filePath)
+	readReplicas.primaryChanged(filePath, true)
+}
+
+// CloseAllUserDBs closes every pooled handle. Intended for shutdown.
+func CloseAllUserDBs() {
+	userDBs.mu.Lock()
+	paths := make([]string, 0, len(userDBs.entries))
+	for path := range userDBs.entries {
+		paths = append(paths, path)
+	}
+	userDBs.mu.Unlock()
+
+	for _, path := range paths {
+		userDBs.invalidate(path)
+	}
+}
+
+// RunUserDBPoolJanitor periodically closes idle user DB handles until ctx is done.
+func RunUserDBPoolJanitor(ctx context.Context, interval time.Duration) {
+	health.RegisterWorker("user_db_pool_janitor", interval)
+	defer health.UnregisterWorker("user_db_pool_janitor")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			userDBs.mu.Lock()
+			userDBs.evictLocked()
+			userDBs.mu.Unlock()
+			health.Beat("user_db_pool_janitor")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUserDBPoolReusesAndEvicts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	pool := newUserDBPool(1, time.Hour)
+
+	first, err := pool.acquire(ctx, filepath.Join(dir, "a.db"))
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	again, err := pool.acquire(ctx, filepath.Join(dir, "a.db"))
+	if err != nil {
+		t.Fatalf("acquire again: %v", err)
+	}
+	if first != again {
+		t.Error("acquire returned a new handle for an already open file")
+	}
+	pool.release(first)
+	pool.release(again)
+
+	// Opening a second file exceeds maxOpen and evicts the idle first handle
+	other, err := pool.acquire(ctx, filepath.Join(dir, "b.db"))
+	if err != nil {
+		t.Fatalf("acquire other: %v", err)
+	}
+	if got := pool.openHandles(); got != 1 {
+		t.Errorf("open handles = %d; want 1", got)
+	}
+	if inUse, idle := pool.handleCounts(); inUse != 1 || idle != 0 {
+		t.Errorf("handle counts = %d in use, %d idle; want 1 in use", inUse, idle)
+	}
+	if err := first.PingContext(ctx); err == nil {
+		t.Error("evicted handle is still open")
+	}
+
+	// An invalidated handle stays usable until its holder releases it
+	pool.invalidate(filepath.Join(dir, "b.db"))
+	if err := other.PingContext(ctx); err != nil {
+		t.Errorf("invalidated handle closed while in use: %v", err)
+	}
+	pool.release(other)
+	if got := pool.openHandles(); got != 0 {
+		t.Errorf("open handles after release = %d; want 0", got)
+	}
+}
+
+func TestPragmaTableInfoCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	columns, err := PragmaTableInfo(ctx, userDB, "notes")
+	if err != nil || columns["body"] != "TEXT" {
+		t.Fatalf("PragmaTableInfo = %v, %v; want body TEXT", columns, err)
+	}
+
+	// Recreate the table with a different layout through the storage helpers
+	if err := DropTable(ctx, userDB, "notes"); err != nil {
+		t.Fatalf("DropTable: %v", err)
+	}
+	if _, err := PragmaTableInfo(ctx, userDB, "notes"); err != ErrTableNotFound {
+		t.Fatalf("PragmaTableInfo after drop err = %v; want ErrTableNotFound", err)
+	}
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, title TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	columns, err = PragmaTableInfo(ctx, userDB, "notes")
+	if err != nil || columns["title"] != "TEXT" || columns["body"] != "" {
+		t.Errorf("PragmaTableInfo after recreate = %v, %v; want stale columns gone", columns, err)
+	}
+}
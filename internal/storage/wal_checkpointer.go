@@ -0,0 +1,81 @@
+// internal/storage/wal_checkpointer.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/health"
+)
+
+// pinnedUserDB is a pooled handle held on behalf of a background task.
+type pinnedUserDB struct {
+	path string
+	db   *sql.DB
+}
+
+// pinAll takes a reference on every open handle so none is closed while a background
+// task works on it. Each handle must be handed back with release.
+func (p *userDBPool) pinAll() []pinnedUserDB {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pinned := make([]pinnedUserDB, 0, len(p.entries))
+	for path, entry := range p.entries {
+		entry.refs++
+		pinned = append(pinned, pinnedUserDB{path: path, db: entry.db})
+	}
+	return pinned
+}
+
+// RunWALCheckpointer periodically truncates the WAL of every open user DB whose -wal
+// file has grown to at least thresholdBytes, until ctx is done. Files whose handles
+// are not cached are checkpointed by SQLite when their last connection closes.
+func RunWALCheckpointer(ctx context.Context, interval time.Duration, thresholdBytes int64) {
+	health.RegisterWorker("wal_checkpointer", interval)
+	defer health.UnregisterWorker("wal_checkpointer")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkpointLargeWALs(ctx, thresholdBytes)
+			health.Beat("wal_checkpointer")
+		}
+	}
+}
+
+// checkpointLargeWALs runs one pass of the WAL checkpointer.
+func checkpointLargeWALs(ctx context.Context, thresholdBytes int64) {
+	for _, pinned := range userDBs.pinAll() {
+		checkpointIfLarge(ctx, pinned, thresholdBytes)
+		userDBs.release(pinned.db)
+	}
+}
+
+func checkpointIfLarge(ctx context.Context, pinned pinnedUserDB, thresholdBytes int64) {
+	info, err := os.Stat(pinned.path + "-wal")
+	if err != nil || info.Size() < thresholdBytes {
+		return
+	}
+
+	// Writers would only refill the WAL mid-checkpoint; take our turn like any other write
+	unlock, err := userDBWriteLocks.lock(ctx, pinned.path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	res, err := CheckpointWAL(ctx, pinned.db)
+	if err != nil {
+		return // Already logged
+	}
+	if res.Busy != 0 {
+		customLog.Ctx(ctx).Warnf("Storage: WAL checkpoint of '%s' blocked by active readers (%d/%d frames copied)", pinned.path, res.Checkpointed, res.LogFrames)
+		return
+	}
+	customLog.Ctx(ctx).Printf("Storage: Checkpointed %d WAL frames of '%s' (WAL was %d bytes)", res.Checkpointed, pinned.path, info.Size())
+}
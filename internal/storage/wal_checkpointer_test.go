@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointLargeWALsTruncatesWAL(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if _, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "x"); err != nil {
+		t.Fatalf("InsertRecord: %v", err)
+	}
+	ReleaseUserDB(userDB)
+
+	if info, err := os.Stat(dbPath + "-wal"); err != nil || info.Size() == 0 {
+		t.Fatalf("expected a non-empty WAL before checkpointing (err %v)", err)
+	}
+
+	checkpointLargeWALs(ctx, 1)
+
+	info, err := os.Stat(dbPath + "-wal")
+	if err != nil {
+		t.Fatalf("stat WAL: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("WAL size after checkpoint = %d; want 0", info.Size())
+	}
+}
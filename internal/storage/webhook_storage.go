@@ -0,0 +1,139 @@
+// internal/storage/webhook_storage.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// ErrWebhookNotFound is returned when a database has no webhook of an ID.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const webhookColumns = `webhook_id, database_id, url, description, events, secret, enabled, last_status, last_error, last_delivery_at, created_at, updated_at`
+
+func scanWebhook(scan func(dest ...any) error) (*domain.Webhook, error) {
+	var hook domain.Webhook
+	var events string
+	var lastStatus sql.NullInt64
+	var lastDeliveryAt sql.NullTime
+	if err := scan(&hook.ID, &hook.DatabaseID, &hook.URL, &hook.Description, &events, &hook.Secret, &hook.Enabled,
+		&lastStatus, &hook.LastError, &lastDeliveryAt, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	hook.Events = []string{}
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	if lastStatus.Valid {
+		status := int(lastStatus.Int64)
+		hook.LastStatus = &status
+	}
+	if lastDeliveryAt.Valid {
+		hook.LastDeliveryAt = &lastDeliveryAt.Time
+	}
+	return &hook, nil
+}
+
+// ListWebhooks returns the webhooks of a database, oldest first.
+func (s *sqlMetadataStore) ListWebhooks(ctx context.Context, databaseId int64) ([]domain.Webhook, error) {
+	rows, err := s.query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE database_id = ? ORDER BY created_at, webhook_id`, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to list webhooks for DBID %d: %v", databaseId, err)
+		return nil, fmt.Errorf("database error listing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []domain.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("database error reading webhooks: %w", err)
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+// GetWebhook returns one webhook of a database.
+func (s *sqlMetadataStore) GetWebhook(ctx context.Context, databaseId int64, webhookId string) (*domain.Webhook, error) {
+	hook, err := scanWebhook(s.queryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE database_id = ? AND webhook_id = ?`, databaseId, webhookId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		customLog.Ctx(ctx).Warnf("Storage: Failed to get webhook %s for DBID %d: %v", webhookId, databaseId, err)
+		return nil, fmt.Errorf("database error getting webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// CreateWebhook stores a new webhook of hook.DatabaseID. ID and Secret are chosen by the
+// caller.
+func (s *sqlMetadataStore) CreateWebhook(ctx context.Context, hook domain.Webhook) error {
+	_, err := s.exec(ctx, `INSERT INTO webhooks (webhook_id, database_id, url, description, events, secret, enabled) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		hook.ID, hook.DatabaseID, hook.URL, hook.Description, strings.Join(hook.Events, ","), hook.Secret, hook.Enabled)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to create webhook for DBID %d: %v", hook.DatabaseID, err)
+		return fmt.Errorf("database error creating webhook: %w", err)
+	}
+	return nil
+}
+
+// UpdateWebhook stores the URL, description, events and enabled state of hook.
+func (s *sqlMetadataStore) UpdateWebhook(ctx context.Context, hook domain.Webhook) error {
+	result, err := s.exec(ctx, `UPDATE webhooks SET url = ?, description = ?, events = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE database_id = ? AND webhook_id = ?`,
+		hook.URL, hook.Description, strings.Join(hook.Events, ","), hook.Enabled, hook.DatabaseID, hook.ID)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to update webhook %s for DBID %d: %v", hook.ID, hook.DatabaseID, err)
+		return fmt.Errorf("database error updating webhook: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SetWebhookSecret replaces the signing secret of a webhook.
+func (s *sqlMetadataStore) SetWebhookSecret(ctx context.Context, databaseId int64, webhookId, secret string) error {
+	result, err := s.exec(ctx, `UPDATE webhooks SET secret = ?, updated_at = CURRENT_TIMESTAMP WHERE database_id = ? AND webhook_id = ?`,
+		secret, databaseId, webhookId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to rotate secret of webhook %s for DBID %d: %v", webhookId, databaseId, err)
+		return fmt.Errorf("database error rotating webhook secret: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook, returning ErrWebhookNotFound if it did not exist.
+func (s *sqlMetadataStore) DeleteWebhook(ctx context.Context, databaseId int64, webhookId string) error {
+	result, err := s.exec(ctx, `DELETE FROM webhooks WHERE database_id = ? AND webhook_id = ?`, databaseId, webhookId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Storage: Failed to delete webhook %s for DBID %d: %v", webhookId, databaseId, err)
+		return fmt.Errorf("database error deleting webhook: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RecordWebhookDelivery stores the outcome of the latest delivery to a webhook: the
+// HTTP status (0 when none was received) and the error, if it failed.
+func (s *sqlMetadataStore) RecordWebhookDelivery(ctx context.Context, webhookId string, status int, deliveryErr string, at time.Time) error {
+	_, err := s.exec(ctx, `UPDATE webhooks SET last_status = ?, last_error = ?, last_delivery_at = ? WHERE webhook_id = ?`,
+		status, deliveryErr, at.UTC(), webhookId)
+	if err != nil {
+		return fmt.Errorf("database error recording webhook delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// internal/storage/write_lock.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrWriteLockTimeout is returned when a write gives up waiting for its turn on a database.
+// It also matches ErrDatabaseBusy.
+var ErrWriteLockTimeout = errors.New("timed out waiting for database write lock")
+
+// writeLock admits one writer at a time to a user DB file. The channel is used as a
+// semaphore so waiting writers can give up when their request context ends.
+type writeLock struct {
+	sem     chan struct{}
+	holders int // Writers holding or waiting for the lock; the entry is dropped at zero
+}
+
+// writeLocks serializes writes per user DB file path so concurrent API writes queue up
+// in-process instead of racing for the SQLite file lock and failing with SQLITE_BUSY.
+// With file locks configured, the writer also holds the database's lock file.
+type writeLocks struct {
+	mu    sync.Mutex
+	locks map[string]*writeLock
+}
+
+var userDBWriteLocks = &writeLocks{locks: make(map[string]*writeLock)}
+
+// lock waits for exclusive write access to path and returns the matching unlock func.
+func (w *writeLocks) lock(ctx context.Context, path string) (func(), error) {
+	w.mu.Lock()
+	l, ok := w.locks[path]
+	if !ok {
+		l = &writeLock{sem: make(chan struct{}, 1)}
+		w.locks[path] = l
+	}
+	l.holders++
+	w.mu.Unlock()
+
+	done := func() {
+		w.mu.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(w.locks, path)
+		}
+		w.mu.Unlock()
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		// Other instances sharing the data directory take turns through the lock file
+		unlockFile, err := userDBFileLocks.lock(ctx, path)
+		if err != nil {
+			<-l.sem
+			done()
+			return nil, err
+		}
+		return func() {
+			unlockFile()
+			<-l.sem
+			done()
+		}, nil
+	case <-ctx.Done():
+		done()
+		customLog.Ctx(ctx).Warnf("Storage: Gave up waiting for write lock on '%s': %v", path, ctx.Err())
+		return nil, fmt.Errorf("%w (%w): %v", ErrWriteLockTimeout, ErrDatabaseBusy, ctx.Err())
+	}
+}
+
+// lockUserDBWrites serializes a write against the database behind userDB and returns
+// the handle to write through. Handles not owned by the pool have no known path and are
+// left to SQLite's own locking. A handle invalidated while the write waited for its
+// turn (the file was restored or replaced) still points at the old file, so the write
+// goes through a freshly opened handle instead, released by unlock.
+func lockUserDBWrites(ctx context.Context, userDB *sql.DB) (*sql.DB, func(), error) {
+	path := userDBs.pathOf(userDB)
+	if path == "" {
+		return userDB, func() {}, nil
+	}
+	unlock, err := userDBWriteLocks.lock(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The file may have been moved while this write waited for its turn
+	if movedUserDBs.moved(path) {
+		unlock()
+		return nil, nil, ErrDatabaseMoved
+	}
+	if !userDBs.stale(userDB) {
+		return userDB, unlock, nil
+	}
+
+	// Reopening a deleted file would create an empty database in its place
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		unlock()
+		return nil, nil, ErrDatabaseNotFound
+	}
+	current, err := userDBs.acquire(ctx, path)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return current, func() {
+		unlock()
+		userDBs.release(current)
+	}, nil
+}
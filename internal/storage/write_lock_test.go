@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentInsertsAreSerialized(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "app.db")
+	userDB, err := ConnectUserDB(ctx, dbPath)
+	if err != nil {
+		t.Fatalf("ConnectUserDB: %v", err)
+	}
+	defer InvalidateUserDB(dbPath)
+	defer ReleaseUserDB(userDB)
+
+	if err := CreateTable(ctx, userDB, "CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+
+	const writers = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := InsertRecord(ctx, userDB, "INSERT INTO notes (body) VALUES (?)", "x"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent insert failed: %v", err)
+	}
+}
+
+func TestWriteLockHonoursContext(t *testing.T) {
+	locks := &writeLocks{locks: make(map[string]*writeLock)}
+	unlock, err := locks.lock(context.Background(), "a.db")
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := locks.lock(ctx, "a.db"); err == nil {
+		t.Fatal("second writer acquired a held lock")
+	}
+
+	unlock()
+	if len(locks.locks) != 0 {
+		t.Errorf("lock entries left after release: %d", len(locks.locks))
+	}
+}
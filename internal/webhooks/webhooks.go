@@ -0,0 +1,341 @@
+// internal/webhooks/webhooks.go
+
+// Package webhooks notifies the webhooks registered for a user database of writes to
+// it. Each delivery is a JSON POST signed with the webhook's secret: the
+// X-Nebula-Signature header holds "t=<unix time>,v1=<hex HMAC-SHA256 of
+// "<unix time>.<body>">", so receivers can check both origin and freshness.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+	"github.com/Annany2002/nebula-backend/internal/logger"
+)
+
+var customLog = logger.NewLogger()
+
+// Events webhooks can subscribe to.
+const (
+	EventRecordCreated = "record.created"
+	EventRecordUpdated = "record.updated"
+	EventRecordDeleted = "record.deleted"
+)
+
+// EventTest is sent by test fires, whatever the webhook subscribes to.
+const EventTest = "webhook.test"
+
+// Events lists the events webhooks can subscribe to.
+var Events = []string{EventRecordCreated, EventRecordUpdated, EventRecordDeleted}
+
+// Headers of deliveries.
+const (
+	HeaderEvent     = "X-Nebula-Event"
+	HeaderDelivery  = "X-Nebula-Delivery"
+	HeaderSignature = "X-Nebula-Signature"
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultMaxAttempts = 3
+	maxErrorLength     = 500
+	secretPrefix       = "whsec_"
+)
+
+// ErrPrivateHost is returned for deliveries to loopback, private or link-local
+// addresses, unless Options.AllowPrivateHosts is set.
+var ErrPrivateHost = errors.New("webhook host resolves to a private address")
+
+// Store is the part of the metadata store webhooks need (storage.MetadataStore
+// satisfies it).
+type Store interface {
+	ListWebhooks(ctx context.Context, databaseId int64) ([]domain.Webhook, error)
+	RecordWebhookDelivery(ctx context.Context, webhookId string, status int, deliveryErr string, at time.Time) error
+}
+
+// Options configure deliveries.
+type Options struct {
+	Timeout           time.Duration // Bound of each delivery attempt; defaults to 10s
+	MaxAttempts       int           // Attempts of an event delivery before giving up; defaults to 3
+	AllowPrivateHosts bool          // Allow webhooks on loopback and private networks
+}
+
+// Event is the body of a delivery.
+type Event struct {
+	ID       string         `json:"id"` // Delivery ID, also sent as X-Nebula-Delivery
+	Event    string         `json:"event"`
+	DBName   string         `json:"db_name"`
+	Table    string         `json:"table,omitempty"`
+	RecordID int64          `json:"record_id,omitempty"`
+	Record   map[string]any `json:"record,omitempty"` // The stored record, for created and updated records
+	Time     time.Time      `json:"time"`
+}
+
+// Result is the outcome of one delivery attempt.
+type Result struct {
+	DeliveryID string `json:"delivery_id"`
+	Delivered  bool   `json:"delivered"`             // The webhook answered with a 2xx status
+	StatusCode int    `json:"status_code,omitempty"` // Absent when no response was received
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Notifier delivers events to webhooks.
+type Notifier struct {
+	store      Store
+	opts       Options
+	client     *http.Client
+	now        func() time.Time
+	retryDelay time.Duration // Before the second attempt, doubling after each
+}
+
+// NewNotifier returns a notifier that finds webhooks in, and records deliveries to,
+// store.
+func NewNotifier(store Store, opts Options) *Notifier {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateHosts {
+		dialer.Control = refusePrivate
+	}
+	transport := &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: opts.Timeout, MaxIdleConnsPerHost: 4}
+	return &Notifier{
+		store: store,
+		opts:  opts,
+		client: &http.Client{
+			Timeout:       opts.Timeout,
+			Transport:     transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		now:        time.Now,
+		retryDelay: 5 * time.Second,
+	}
+}
+
+// refusePrivate is a dial control that refuses connections to addresses on the local
+// host or network. Checking the dialed address also covers names that resolve to them.
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return ErrPrivateHost
+	}
+	return nil
+}
+
+var (
+	mu       sync.RWMutex
+	notifier *Notifier // nil until Configure; writes then notify no webhooks
+)
+
+// Configure enables webhook deliveries for the process.
+func Configure(store Store, opts Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifier = NewNotifier(store, opts)
+}
+
+func current() *Notifier {
+	mu.RLock()
+	defer mu.RUnlock()
+	return notifier
+}
+
+// Enabled reports whether webhooks are delivered.
+func Enabled() bool {
+	return current() != nil
+}
+
+// Subscribers returns the enabled webhooks of a database subscribed to event, so
+// callers only build events someone listens to.
+func Subscribers(ctx context.Context, databaseId int64, event string) []domain.Webhook {
+	n := current()
+	if n == nil {
+		return nil
+	}
+	hooks, err := n.store.ListWebhooks(ctx, databaseId)
+	if err != nil {
+		customLog.Ctx(ctx).Warnf("Webhooks: Failed to list webhooks of DBID %d: %v", databaseId, err)
+		return nil
+	}
+	subscribed := hooks[:0]
+	for _, hook := range hooks {
+		if hook.Enabled && Subscribes(hook, event) {
+			subscribed = append(subscribed, hook)
+		}
+	}
+	return subscribed
+}
+
+// Publish delivers event to hooks in the background, retrying failed deliveries.
+func Publish(ctx context.Context, hooks []domain.Webhook, event Event) {
+	n := current()
+	if n == nil || len(hooks) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = n.now().UTC()
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, hook := range hooks {
+		delivery := event
+		delivery.ID = uuid.New().String() // Kept across retries so receivers can dedupe
+		go n.deliverWithRetries(ctx, hook, delivery)
+	}
+}
+
+// Test sends a test event to hook right away, even when it is disabled, and returns
+// the outcome. Without Configure the default options apply.
+func Test(ctx context.Context, store Store, hook domain.Webhook, dbName string) Result {
+	n := current()
+	if n == nil {
+		n = NewNotifier(store, Options{})
+	}
+	return n.deliver(ctx, hook, Event{Event: EventTest, DBName: dbName, Time: n.now().UTC()})
+}
+
+func (n *Notifier) deliverWithRetries(ctx context.Context, hook domain.Webhook, event Event) {
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		result := n.deliver(ctx, hook, event)
+		if result.Delivered {
+			return
+		}
+		if attempt >= n.opts.MaxAttempts {
+			customLog.Ctx(ctx).Warnf("Webhooks: Giving up on %s delivery %s to webhook %s: %s", event.Event, result.DeliveryID, hook.ID, result.Error)
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// deliver makes one delivery attempt of event to hook and records its outcome.
+func (n *Notifier) deliver(ctx context.Context, hook domain.Webhook, event Event) Result {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	result := Result{DeliveryID: event.ID}
+	start := n.now()
+	status, err := n.post(ctx, hook, event)
+	result.DurationMs = n.now().Sub(start).Milliseconds()
+	result.StatusCode = status
+	if err == nil && (status < 200 || status >= 300) {
+		err = fmt.Errorf("webhook answered with status %d", status)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		if len(result.Error) > maxErrorLength {
+			result.Error = result.Error[:maxErrorLength]
+		}
+	} else {
+		result.Delivered = true
+	}
+	if err := n.store.RecordWebhookDelivery(ctx, hook.ID, status, result.Error, n.now()); err != nil {
+		customLog.Ctx(ctx).Warnf("Webhooks: Failed to record delivery to webhook %s: %v", hook.ID, err)
+	}
+	return result
+}
+
+func (n *Notifier) post(ctx context.Context, hook domain.Webhook, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("cannot encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Nebula-Webhooks/1")
+	req.Header.Set(HeaderEvent, event.Event)
+	req.Header.Set(HeaderDelivery, event.ID)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, n.now().Unix(), body))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Lets the connection be reused
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Nebula-Signature header value of body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates a webhook signing secret.
+func NewSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ValidateURL checks that rawURL is an absolute http or https URL.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL '%s' (use an http or https URL)", rawURL)
+	}
+	if u.User != nil {
+		return errors.New("webhook URLs cannot contain credentials; verify deliveries with the signature instead")
+	}
+	return nil
+}
+
+// NormalizeEvents checks that events can be subscribed to and returns them sorted and
+// without duplicates. An empty list subscribes to every event.
+func NormalizeEvents(events []string) ([]string, error) {
+	normalized := []string{}
+	for _, event := range events {
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("unknown webhook event '%s' (use one of %v)", event, Events)
+		}
+		if !slices.Contains(normalized, event) {
+			normalized = append(normalized, event)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// Subscribes reports whether hook is subscribed to event.
+func Subscribes(hook domain.Webhook, event string) bool {
+	return len(hook.Events) == 0 || slices.Contains(hook.Events, event)
+}
@@ -0,0 +1,137 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Annany2002/nebula-backend/internal/domain"
+)
+
+// fakeStore records deliveries in memory.
+type fakeStore struct {
+	mu         sync.Mutex
+	hooks      []domain.Webhook
+	deliveries []int
+}
+
+func (s *fakeStore) ListWebhooks(context.Context, int64) ([]domain.Webhook, error) {
+	return append([]domain.Webhook(nil), s.hooks...), nil
+}
+
+func (s *fakeStore) RecordWebhookDelivery(_ context.Context, _ string, status int, _ string, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, status)
+	return nil
+}
+
+func TestDeliverySignedAndRetried(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var lastBody []byte
+	var lastSignature, lastDelivery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		lastBody, lastSignature, lastDelivery = body, r.Header.Get(HeaderSignature), r.Header.Get(HeaderDelivery)
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	n := NewNotifier(store, Options{AllowPrivateHosts: true, MaxAttempts: 2})
+	n.retryDelay = time.Millisecond
+	hook := domain.Webhook{ID: "w1", URL: server.URL, Secret: "whsec_test", Enabled: true}
+	n.deliverWithRetries(context.Background(), hook, Event{ID: "d1", Event: EventRecordCreated, DBName: "app", RecordID: 7})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 || len(store.deliveries) != 2 || store.deliveries[1] != http.StatusOK {
+		t.Fatalf("calls = %d, recorded deliveries = %v; want a failed then a successful attempt", calls, store.deliveries)
+	}
+	if lastDelivery != "d1" {
+		t.Errorf("delivery ID = %q, want it kept across retries", lastDelivery)
+	}
+	var event Event
+	if err := json.Unmarshal(lastBody, &event); err != nil || event.RecordID != 7 || event.Event != EventRecordCreated {
+		t.Errorf("body = %s (%v)", lastBody, err)
+	}
+	ts, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(lastSignature, ",")[0], "t="), 10, 64)
+	if want := Sign("whsec_test", ts, lastBody); lastSignature != want {
+		t.Errorf("signature = %q, want %q", lastSignature, want)
+	}
+}
+
+func TestPrivateHostsRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("delivered to a loopback address")
+	}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	result := NewNotifier(store, Options{}).deliver(context.Background(), domain.Webhook{ID: "w1", URL: server.URL}, Event{Event: EventTest})
+	if result.Delivered || !strings.Contains(result.Error, ErrPrivateHost.Error()) {
+		t.Errorf("result = %+v, want the private host refused", result)
+	}
+	if len(store.deliveries) != 1 || store.deliveries[0] != 0 {
+		t.Errorf("recorded deliveries = %v, want one without status", store.deliveries)
+	}
+}
+
+func TestEventFilters(t *testing.T) {
+	events, err := NormalizeEvents([]string{EventRecordUpdated, EventRecordCreated, EventRecordUpdated})
+	if err != nil || strings.Join(events, ",") != "record.created,record.updated" {
+		t.Errorf("NormalizeEvents = %v, %v", events, err)
+	}
+	if _, err := NormalizeEvents([]string{"table.dropped"}); err == nil {
+		t.Error("NormalizeEvents accepted an unknown event")
+	}
+
+	store := &fakeStore{hooks: []domain.Webhook{
+		{ID: "all", Enabled: true},
+		{ID: "created", Enabled: true, Events: []string{EventRecordCreated}},
+		{ID: "disabled", Enabled: false},
+	}}
+	Configure(store, Options{})
+	defer func() { mu.Lock(); notifier = nil; mu.Unlock() }()
+
+	ids := func(hooks []domain.Webhook) string {
+		var out []string
+		for _, hook := range hooks {
+			out = append(out, hook.ID)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := ids(Subscribers(context.Background(), 1, EventRecordCreated)); got != "all,created" {
+		t.Errorf("subscribers of %s = %s", EventRecordCreated, got)
+	}
+	if got := ids(Subscribers(context.Background(), 1, EventRecordDeleted)); got != "all" {
+		t.Errorf("subscribers of %s = %s", EventRecordDeleted, got)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://example.com/hook":      true,
+		"http://example.com:8080/":      true,
+		"ftp://example.com/":            false,
+		"/relative":                     false,
+		"https://user:pw@example.com/x": false,
+	} {
+		if err := ValidateURL(raw); (err == nil) != ok {
+			t.Errorf("ValidateURL(%q) = %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// internal/xlsx/writer.go
+// Package xlsx writes Excel workbooks (Office Open XML spreadsheets) for table exports.
+// Sheets are streamed one after the other into the zip container, so exports do not
+// need to hold their rows in memory. Cells hold numbers, booleans or inline strings;
+// the first row of each sheet is a bold, frozen header.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of .xlsx workbooks.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+const (
+	maxSheetName = 31    // Excel's limit on sheet names
+	maxCellText  = 32767 // Excel's limit on the characters of a cell
+	maxRows      = 1048576
+)
+
+// ErrTooManyRows is returned when a sheet would exceed Excel's row limit.
+var ErrTooManyRows = errors.New("xlsx: sheet exceeds 1048576 rows")
+
+// Writer writes one workbook to an io.Writer. Call AddSheet before writing rows, and
+// Close to complete the file; the underlying writer is not closed.
+type Writer struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer // Current sheet, nil before the first AddSheet
+	names  []string
+	row    int
+	closed bool
+}
+
+// NewWriter starts a workbook on w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{zw: zip.NewWriter(w)}
+}
+
+// AddSheet ends the current sheet and starts a new one whose first row is header.
+// The name is shortened and made unique as Excel requires.
+func (x *Writer) AddSheet(name string, header []string) error {
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+	entry, err := x.zw.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(x.names)+1))
+	if err != nil {
+		return err
+	}
+	x.names = append(x.names, x.uniqueName(name))
+	x.sheet = bufio.NewWriter(entry)
+	x.row = 0
+	x.sheet.WriteString(xml.Header)
+	x.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(header) > 0 {
+		x.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	x.sheet.WriteString(`<sheetData>`)
+	if len(header) == 0 {
+		return nil
+	}
+	values := make([]any, len(header))
+	for i, h := range header {
+		values[i] = h
+	}
+	return x.writeRow(values, ` s="1"`)
+}
+
+// WriteRow adds a row to the current sheet. Values may be nil (an empty cell), any Go
+// integer or float, bool, string, []byte (written as base64) or time.Time.
+func (x *Writer) WriteRow(values []any) error {
+	if x.sheet == nil {
+		return errors.New("xlsx: WriteRow before AddSheet")
+	}
+	return x.writeRow(values, "")
+}
+
+func (x *Writer) writeRow(values []any, style string) error {
+	if x.row >= maxRows {
+		return ErrTooManyRows
+	}
+	x.row++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, v := range values {
+		ref := columnName(i) + strconv.Itoa(x.row)
+		switch v := v.(type) {
+		case nil:
+			continue
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(x.sheet, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, style, b)
+		case int64, int, int32, uint32:
+			fmt.Fprintf(x.sheet, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				x.writeText(ref, style, strconv.FormatFloat(v, 'g', -1, 64))
+				continue
+			}
+			fmt.Fprintf(x.sheet, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'g', -1, 64))
+		case string:
+			x.writeText(ref, style, v)
+		case []byte:
+			x.writeText(ref, style, base64.StdEncoding.EncodeToString(v))
+		case time.Time:
+			x.writeText(ref, style, v.UTC().Format("2006-01-02 15:04:05"))
+		default:
+			x.writeText(ref, style, fmt.Sprint(v))
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *Writer) writeText(ref, style, text string) {
+	fmt.Fprintf(x.sheet, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+	_ = xml.EscapeText(x.sheet, []byte(cellText(text)))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+func (x *Writer) endSheet() error {
+	if x.sheet == nil {
+		return nil
+	}
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	err := x.sheet.Flush()
+	x.sheet = nil
+	return err
+}
+
+// Close ends the last sheet and writes the workbook parts. A workbook without sheets
+// gets an empty one, since Excel rejects files that have none.
+func (x *Writer) Close() error {
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	if len(x.names) == 0 {
+		if err := x.AddSheet("Sheet1", nil); err != nil {
+			return err
+		}
+	}
+	if err := x.endSheet(); err != nil {
+		return err
+	}
+
+	var workbook, workbookRels, contentTypes strings.Builder
+	workbook.WriteString(xml.Header)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	workbookRels.WriteString(xml.Header)
+	workbookRels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	contentTypes.WriteString(xml.Header)
+	contentTypes.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i, name := range x.names {
+		n := i + 1
+		workbook.WriteString(`<sheet name="`)
+		_ = xml.EscapeText(&workbook, []byte(name))
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+	}
+	workbook.WriteString(`</sheets></workbook>`)
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`, len(x.names)+1)
+	contentTypes.WriteString(`</Types>`)
+
+	parts := []struct{ name, body string }{
+		{"xl/workbook.xml", workbook.String()},
+		{"xl/_rels/workbook.xml.rels", workbookRels.String()},
+		{"xl/styles.xml", stylesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"[Content_Types].xml", contentTypes.String()},
+	}
+	for _, part := range parts {
+		entry, err := x.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, part.body); err != nil {
+			return err
+		}
+	}
+	return x.zw.Close()
+}
+
+// uniqueName shortens name to Excel's limit, replaces the characters Excel forbids and
+// appends a counter when another sheet already has the name (compared case-insensitively).
+func (x *Writer) uniqueName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "Sheet"
+	}
+	candidate := truncate(name, maxSheetName)
+	for n := 2; x.hasName(candidate); n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate = truncate(name, maxSheetName-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func (x *Writer) hasName(name string) bool {
+	for _, existing := range x.names {
+		if strings.EqualFold(existing, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cellText drops characters XML 1.0 cannot carry and truncates to Excel's cell limit.
+func cellText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != utf8.RuneError && r != 0xFFFE && r != 0xFFFF) {
+			return r
+		}
+		return -1
+	}, s)
+	return truncate(s, maxCellText)
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// columnName returns the spreadsheet column letters of the zero-based index i.
+func columnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// stylesXML defines the default cell format (0) and a bold one for headers (1).
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`